@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/prxssh/echo/pkg/echo"
+)
+
+// client calls the daemon's API.
+type client struct {
+	base  string
+	token string
+	http  *http.Client
+}
+
+type apiError struct {
+	Error string `json:"error"`
+}
+
+// do sends in, if not nil, as JSON and decodes the answer into out, if
+// not nil. An error status is returned as an error with the daemon's
+// message.
+func (c *client) do(
+	ctx context.Context,
+	method, path string,
+	in, out any,
+) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.base+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		var e apiError
+		err := json.NewDecoder(resp.Body).Decode(&e)
+		if err == nil && e.Error != "" {
+			return errors.New(e.Error)
+		}
+		return fmt.Errorf("%s %s: %s", method, path, resp.Status)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func (c *client) list(ctx context.Context) ([]echo.TorrentStatus, error) {
+	var list []echo.TorrentStatus
+	err := c.do(ctx, "GET", "/api/v1/torrents", nil, &list)
+	return list, err
+}
+
+// resolve expands an info-hash prefix to the one torrent it starts, so
+// that hashes can be given as list prints them.
+func (c *client) resolve(ctx context.Context, prefix string) (string, error) {
+	prefix = strings.ToLower(prefix)
+	list, err := c.list(ctx)
+	if err != nil {
+		return "", err
+	}
+	var found []string
+	for _, st := range list {
+		if strings.HasPrefix(st.InfoHash, prefix) {
+			found = append(found, st.InfoHash)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return "", fmt.Errorf("no torrent %s", prefix)
+	case 1:
+		return found[0], nil
+	}
+	return "", fmt.Errorf("%s names %d torrents", prefix, len(found))
+}
+
+// torrentPath returns the API path of the torrent prefix names, with
+// suffix appended.
+func (c *client) torrentPath(
+	ctx context.Context,
+	prefix, suffix string,
+) (string, error) {
+	hash, err := c.resolve(ctx, prefix)
+	if err != nil {
+		return "", err
+	}
+	return "/api/v1/torrents/" + url.PathEscape(hash) + suffix, nil
+}
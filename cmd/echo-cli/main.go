@@ -0,0 +1,355 @@
+// Command echo-cli controls a running echod over its API, for scripts and
+// machines reached only over SSH.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/prxssh/echo/internal/api"
+	"github.com/prxssh/echo/internal/config"
+	"github.com/prxssh/echo/pkg/echo"
+)
+
+const usage = `usage: echo-cli [-server url] [-token token] [-profile name]
+                [-portable] <command> [args]
+
+commands:
+  add [-category c] [-dir d] [-paused] <file|magnet|url>...
+  list [-json]
+  pause <hash>...
+  resume <hash>...
+  rm [-delete] <hash>...
+  stats [-json]
+  search [-limit n] [-json] <query>
+  port [-json]
+
+Hashes may be shortened to any prefix naming one torrent.
+`
+
+type command func(ctx context.Context, c *client, args []string) error
+
+var commands = map[string]command{
+	"add":    add,
+	"list":   list,
+	"pause":  each("POST", "/pause"),
+	"resume": each("POST", "/resume"),
+	"rm":     rm,
+	"stats":  stats,
+	"search": search,
+	"port":   port,
+}
+
+func main() {
+	flag.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	server := flag.String(
+		"server",
+		envOr("ECHO_SERVER", "http://127.0.0.1:9080"),
+		"daemon URL",
+	)
+	token := flag.String("token", os.Getenv("ECHOD_TOKEN"), "API token")
+	openProfile := config.ProfileFlags(flag.CommandLine)
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	cmd, ok := commands[flag.Arg(0)]
+	if !ok {
+		fmt.Fprintf(
+			os.Stderr,
+			"echo-cli: unknown command %q\n",
+			flag.Arg(0),
+		)
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if *token == "" {
+		profile, err := openProfile()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "echo-cli:", err)
+			os.Exit(2)
+		}
+		*token = savedToken(profile)
+	}
+	c := &client{
+		base:  strings.TrimRight(*server, "/"),
+		token: *token,
+		http:  &http.Client{},
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if err := cmd(ctx, c, flag.Args()[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, "echo-cli:", err)
+		os.Exit(1)
+	}
+}
+
+func envOr(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
+
+// savedToken returns the token echod saved in the profile's directory,
+// for a daemon running on this machine as this user.
+func savedToken(profile config.Profile) string {
+	data, err := os.ReadFile(profile.TokenPath())
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+func add(ctx context.Context, c *client, args []string) error {
+	fs := flag.NewFlagSet("add", flag.ExitOnError)
+	category := fs.String("category", "", "category to file under")
+	dir := fs.String("dir", "", "directory to download to")
+	paused := fs.Bool("paused", false, "add without starting")
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		return fmt.Errorf("add: nothing to add")
+	}
+
+	opts := echo.AddOptions{
+		Category: *category,
+		SavePath: *dir,
+		Paused:   *paused,
+	}
+	var failed int
+	for _, arg := range fs.Args() {
+		req := echo.AddRequest{Options: opts}
+		switch {
+		case strings.HasPrefix(arg, "magnet:"):
+			req.Magnet = arg
+		case strings.HasPrefix(arg, "http://"),
+			strings.HasPrefix(arg, "https://"):
+			req.URL = arg
+		default:
+			// The daemon may be on another machine, so the file
+			// is sent rather than its path.
+			data, err := os.ReadFile(arg)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "echo-cli:", err)
+				failed++
+				continue
+			}
+			req.Data = data
+		}
+
+		var added struct {
+			InfoHash string `json:"infoHash"`
+			Name     string `json:"name"`
+		}
+		err := c.do(ctx, "POST", "/api/v1/torrents", req, &added)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "echo-cli: %s: %v\n", arg, err)
+			failed++
+			continue
+		}
+		fmt.Printf("%s  %s\n", added.InfoHash, added.Name)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d not added", failed, fs.NArg())
+	}
+	return nil
+}
+
+func list(ctx context.Context, c *client, args []string) error {
+	fs := flag.NewFlagSet("list", flag.ExitOnError)
+	asJSON := fs.Bool("json", false, "print JSON")
+	fs.Parse(args)
+
+	torrents, err := c.list(ctx)
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		return printJSON(torrents)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "HASH\tSTATE\tDONE\tSIZE\tDOWN\tUP\tRATIO\tNAME")
+	for _, t := range torrents {
+		fmt.Fprintf(
+			w,
+			"%s\t%s\t%.1f%%\t%s\t%s\t%s\t%.2f\t%s\n",
+			t.InfoHash[:8],
+			t.State,
+			t.Progress,
+			formatBytes(float64(t.Size)),
+			formatRate(t.DownloadRate),
+			formatRate(t.UploadRate),
+			t.Ratio,
+			t.Name,
+		)
+	}
+	return w.Flush()
+}
+
+// each returns the command calling method on suffix of each torrent
+// named.
+func each(method, suffix string) command {
+	return func(ctx context.Context, c *client, args []string) error {
+		if len(args) == 0 {
+			return fmt.Errorf("no torrents named")
+		}
+		for _, prefix := range args {
+			path, err := c.torrentPath(ctx, prefix, suffix)
+			if err != nil {
+				return err
+			}
+			err = c.do(ctx, method, path, nil, nil)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+func rm(ctx context.Context, c *client, args []string) error {
+	fs := flag.NewFlagSet("rm", flag.ExitOnError)
+	deleteFiles := fs.Bool("delete", false, "delete the files too")
+	fs.Parse(args)
+
+	suffix := ""
+	if *deleteFiles {
+		suffix = "?deleteFiles=true"
+	}
+	return each("DELETE", suffix)(ctx, c, fs.Args())
+}
+
+func stats(ctx context.Context, c *client, args []string) error {
+	fs := flag.NewFlagSet("stats", flag.ExitOnError)
+	asJSON := fs.Bool("json", false, "print JSON")
+	fs.Parse(args)
+
+	var s api.Stats
+	if err := c.do(ctx, "GET", "/api/v1/stats", nil, &s); err != nil {
+		return err
+	}
+	if *asJSON {
+		return printJSON(s)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "torrents\t%d\n", s.Torrents)
+	fmt.Fprintf(
+		w,
+		"session\t%s down, %s up\n",
+		formatBytes(float64(s.Session.Session.Downloaded)),
+		formatBytes(float64(s.Session.Session.Uploaded)),
+	)
+	fmt.Fprintf(
+		w,
+		"all time\t%s down, %s up, ratio %.2f\n",
+		formatBytes(float64(s.Session.AllTime.Downloaded)),
+		formatBytes(float64(s.Session.AllTime.Uploaded)),
+		s.Session.Ratio,
+	)
+	fmt.Fprintf(w, "uptime\t%s\n", s.Session.Uptime.Round(time.Second))
+	fmt.Fprintf(w, "dht nodes\t%d\n", s.DHT.Nodes)
+	return w.Flush()
+}
+
+func search(ctx context.Context, c *client, args []string) error {
+	fs := flag.NewFlagSet("search", flag.ExitOnError)
+	limit := fs.Int("limit", 20, "results to print; 0 prints every one")
+	asJSON := fs.Bool("json", false, "print JSON")
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		return fmt.Errorf("search: nothing to search for")
+	}
+
+	q := echo.SearchQuery{Text: strings.Join(fs.Args(), " ")}
+	var results []echo.SearchResult
+	if err := c.do(ctx, "POST", "/api/v1/search", q, &results); err != nil {
+		return err
+	}
+	if *limit > 0 && len(results) > *limit {
+		results = results[:*limit]
+	}
+	if *asJSON {
+		return printJSON(results)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "SEEDS\tPEERS\tSIZE\tTITLE\tLINK")
+	for _, r := range results {
+		link := r.Magnet
+		if link == "" {
+			link = r.Link
+		}
+		fmt.Fprintf(
+			w,
+			"%d\t%d\t%s\t%s\t%s\n",
+			r.Seeders,
+			r.Leechers,
+			formatBytes(float64(r.Size)),
+			r.Title,
+			link,
+		)
+	}
+	return w.Flush()
+}
+
+func port(ctx context.Context, c *client, args []string) error {
+	fs := flag.NewFlagSet("port", flag.ExitOnError)
+	asJSON := fs.Bool("json", false, "print JSON")
+	fs.Parse(args)
+
+	var check echo.PortCheck
+	err := c.do(ctx, "POST", "/api/v1/port/check", nil, &check)
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		return printJSON(check)
+	}
+	fmt.Printf(
+		"%d/%s %s (%s)\n",
+		check.Port,
+		check.Protocol,
+		check.Status,
+		check.Method,
+	)
+	return nil
+}
+
+func printJSON(v any) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func formatBytes(n float64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%.0f B", n)
+	}
+	exp := 0
+	for n >= unit*unit && exp < 4 {
+		n /= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", n/unit, "KMGTP"[exp])
+}
+
+func formatRate(bytesPerSec float64) string {
+	if bytesPerSec < 1 {
+		return "-"
+	}
+	return formatBytes(bytesPerSec) + "/s"
+}
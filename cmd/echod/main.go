@@ -0,0 +1,129 @@
+// Command echod runs the engine without a window, serving the session
+// over an HTTP JSON API for seedboxes and servers.
+package main
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/prxssh/echo/internal/api"
+	"github.com/prxssh/echo/internal/config"
+	"github.com/prxssh/echo/internal/engine"
+)
+
+// defaultListen is where the API listens unless told otherwise.
+const defaultListen = "127.0.0.1:9080"
+
+func main() {
+	openProfile := config.ProfileFlags(flag.CommandLine)
+	path := flag.String(
+		"config",
+		"",
+		"configuration file; defaults to the profile's config.yaml",
+	)
+	listen := flag.String(
+		"listen",
+		"",
+		"address the API listens on; defaults to the config's, else "+
+			defaultListen,
+	)
+	token := flag.String(
+		"token",
+		os.Getenv("ECHOD_TOKEN"),
+		"API token; defaults to $ECHOD_TOKEN, the config's, else one "+
+			"saved in the profile's directory",
+	)
+	flag.Parse()
+
+	profile, err := openProfile()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "echod:", err)
+		os.Exit(2)
+	}
+	eng, err := engine.Open(profile, engine.Options{
+		ConfigPath: *path,
+		Level:      slog.LevelInfo,
+		Strict:     true,
+	})
+	if err != nil {
+		slog.Error(
+			"opening engine failed",
+			slog.String("error", err.Error()),
+		)
+		os.Exit(1)
+	}
+	*listen = cmp.Or(*listen, eng.File.API.Listen, defaultListen)
+	*token = cmp.Or(*token, eng.File.API.Token)
+	if *token == "" {
+		if err := loadToken(profile, token); err != nil {
+			slog.Error(
+				"loading api token failed",
+				slog.String("error", err.Error()),
+			)
+			os.Exit(1)
+		}
+	}
+
+	eng.Start(context.Background())
+	ctx, stop := signal.NotifyContext(
+		context.Background(),
+		os.Interrupt,
+		syscall.SIGTERM,
+	)
+
+	srv := &http.Server{
+		Addr:              *listen,
+		Handler:           api.New(eng.Client, *token),
+		ReadHeaderTimeout: 10 * time.Second,
+		// Event streams end as the signal comes, rather than hold up
+		// the shutdown.
+		BaseContext: func(net.Listener) context.Context { return ctx },
+	}
+	go func() {
+		slog.Info("serving api", slog.String("addr", *listen))
+		err := srv.ListenAndServe()
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error(
+				"api failed",
+				slog.String("error", err.Error()),
+			)
+			os.Exit(1)
+		}
+	}()
+
+	<-ctx.Done()
+	stop()
+	slog.Info("quitting")
+
+	shutdown, cancel := context.WithTimeout(
+		context.Background(),
+		30*time.Second,
+	)
+	defer cancel()
+	if err := srv.Shutdown(shutdown); err != nil {
+		slog.Warn("api shutdown", slog.String("error", err.Error()))
+	}
+	eng.Close(shutdown)
+}
+
+// loadToken sets token to the one saved in the profile's directory,
+// which is made up on the first run.
+func loadToken(profile config.Profile, token *string) error {
+	path := profile.TokenPath()
+	var err error
+	*token, err = api.LoadToken(path)
+	if err == nil {
+		slog.Info("api token loaded", slog.String("path", path))
+	}
+	return err
+}
@@ -2,16 +2,61 @@ import React, { useCallback, useEffect, useMemo, useState } from 'react';
 import './App.css';
 import TorrentUploader from './components/TorrentUploader';
 import Toolbar from './components/Toolbar';
+import useDHTStats from './hooks/useDHTStats';
+import useSessionStats from './hooks/useSessionStats';
+import useSuspended from './hooks/useSuspended';
+import usePower from './hooks/usePower';
+import useNotifications from './hooks/useNotifications';
+import usePendingMagnets from './hooks/usePendingMagnets';
+import useDrops from './hooks/useDrops';
 import TorrentTable, { SortDir, SortKey } from './components/TorrentTable';
 import { toRow, formatBytes } from './utils/torrent';
 import Pager from './components/Pager';
+import PendingMagnets from './components/PendingMagnets';
 import DetailsPanel from './components/DetailsPanel';
-import { AddTorrent, RemoveTorrent } from '../wailsjs/go/ui/UI';
+import {
+    AddMagnet,
+    AddTorrentFromURL,
+    ChooseTorrentFiles,
+    GetFullState,
+    Torrents,
+    PauseTorrent,
+    RemoveTorrent,
+    ResumeTorrent,
+} from '../wailsjs/go/ui/UI';
+import Input from './components/primitives/Input';
+import Button from './components/primitives/Button';
 import {
     TrackerStatsProvider,
     useTrackerStats,
 } from './providers/TrackerStatsProvider';
-import { torrent as Models } from '../wailsjs/go/models';
+import {
+    TorrentStateProvider,
+    isActive,
+    useTorrentStates,
+} from './providers/TorrentStateProvider';
+import {
+    echo,
+    search,
+    torrent as Models,
+    ui,
+} from '../wailsjs/go/models';
+import useLabels from './hooks/useLabels';
+import CategoryManager from './components/CategoryManager';
+import WatchFolderManager from './components/WatchFolderManager';
+import FeedManager from './components/FeedManager';
+import IndexerManager from './components/IndexerManager';
+import SearchDialog from './components/SearchDialog';
+import PauseScheduleEditor from './components/PauseScheduleEditor';
+import PowerPolicyEditor from './components/PowerPolicyEditor';
+import QuotaEditor from './components/QuotaEditor';
+import HistoryDialog from './components/HistoryDialog';
+import ImportDialog from './components/ImportDialog';
+import SettingsEditor from './components/SettingsEditor';
+import LogViewer from './components/LogViewer';
+import CloseDialog from './components/CloseDialog';
+import CreateTorrentDialog from './components/CreateTorrentDialog';
+import AddDialog, { PendingAdd } from './components/AddDialog';
 import useResponsivePageSize from './hooks/useResponsivePageSize';
 import useFilterSort from './hooks/useFilterSort';
 
@@ -24,6 +69,51 @@ function App() {
     const [query, setQuery] = useState('');
     const [busy, setBusy] = useState(false);
     const [error, setError] = useState<string | null>(null);
+    const [magnet, setMagnet] = useState('');
+    const [addCategory, setAddCategory] = useState('');
+    const [filterCategory, setFilterCategory] = useState('');
+    const [filterTag, setFilterTag] = useState('');
+    const [managingCategories, setManagingCategories] = useState(false);
+    const [managingWatch, setManagingWatch] = useState(false);
+    const [managingFeeds, setManagingFeeds] = useState(false);
+    const [managingIndexers, setManagingIndexers] = useState(false);
+    const [searching, setSearching] = useState(false);
+    const [importing, setImporting] = useState(false);
+    const [editingSchedule, setEditingSchedule] = useState(false);
+    const [editingPower, setEditingPower] = useState(false);
+    const [editingQuotas, setEditingQuotas] = useState(false);
+    const [showingHistory, setShowingHistory] = useState(false);
+    const [editingSettings, setEditingSettings] = useState(false);
+    const [viewingLogs, setViewingLogs] = useState(false);
+    const [creating, setCreating] = useState(false);
+    const [adding, setAdding] = useState(false);
+    const [pendingAdds, setPendingAdds] = useState<PendingAdd[]>([]);
+    const {
+        categories,
+        labels,
+        refresh: refreshLabels,
+    } = useLabels();
+    const dht = useDHTStats();
+    const dhtLabel = dht ? ` • DHT: ${dht.nodes} nodes` : '';
+    const session = useSessionStats();
+    const sessionLabel = session
+        ? ` • ↓ ${formatBytes(session.session.downloaded)} ↑ ${formatBytes(session.session.uploaded)} this session • ratio ${session.ratio.toFixed(2)} all-time`
+        : '';
+    const suspended = useSuspended();
+    const power = usePower();
+    const { notifications, dismiss } = useNotifications();
+    const powerReason = [
+        power?.onBattery && 'on battery',
+        power?.metered && 'metered',
+    ]
+        .filter(Boolean)
+        .join(', ');
+    let suspendedLabel = '';
+    if (power?.action === 'pause')
+        suspendedLabel = ` • paused (${powerReason})`;
+    else if (suspended) suspendedLabel = ' • paused by schedule';
+    if (power?.action === 'throttle')
+        suspendedLabel += ` • throttled (${powerReason})`;
     const [page, setPage] = useState<number>(1);
     const pageSize = useResponsivePageSize(5, 10, 1200);
     const totalSize = useMemo(
@@ -33,6 +123,7 @@ function App() {
 
     // Tracker stats via provider
     const { stats: trackerStats } = useTrackerStats();
+    const { states } = useTorrentStates();
 
     const infoHashHex = (t: Models.Torrent): string => {
         const arr = t.metainfo?.info?.infoHash as number[] | undefined;
@@ -43,56 +134,240 @@ function App() {
         return s;
     };
 
+    // Torrents come back from the last session, and magnet links restored
+    // with it show up once their metadata arrives.
+    const refresh = useCallback(() => {
+        Torrents()
+            .then((list) =>
+                setItems((prev) => {
+                    const known = new Set(prev.map((i) => infoHashHex(i)));
+                    const fresh = (list || []).filter(
+                        (t) => !known.has(infoHashHex(t))
+                    );
+                    return fresh.length > 0 ? [...fresh, ...prev] : prev;
+                })
+            )
+            .then(refreshLabels)
+            .catch(() => {});
+    }, [refreshLabels]);
+
+    // A reloaded window starts over from a full snapshot rather than what
+    // it had before, which may be stale.
+    useEffect(() => {
+        GetFullState()
+            .then((s) => setItems(s.torrents || []))
+            .catch(() => {});
+    }, []);
+
+    const { magnets, track: trackMagnet } = usePendingMagnets(
+        useCallback(
+            (m: echo.MagnetStatus) => {
+                if (m.error) setError(`${m.name || m.infoHash}: ${m.error}`);
+                else refresh();
+            },
+            [refresh]
+        )
+    );
+
+    useEffect(() => {
+        const known = new Set(items.map((i) => infoHashHex(i)));
+        if (Object.keys(states).some((h) => !known.has(h))) refresh();
+    }, [states, items, refresh]);
+
+    const addParsed = useCallback(
+        (parsed: Models.Torrent[]) => {
+            // Enforce uniqueness by infohash against current list and within batch
+            const existing = new Set(items.map((i) => infoHashHex(i)));
+            const seen = new Set<string>();
+            const unique = parsed.filter((p) => {
+                const id = infoHashHex(p);
+                if (!id || existing.has(id) || seen.has(id)) return false;
+                seen.add(id);
+                return true;
+            });
+
+            const skipped = parsed.length - unique.length;
+            if (skipped > 0) {
+                setError(
+                    `Skipped ${skipped} duplicate${skipped > 1 ? 's' : ''}.`
+                );
+            }
+
+            if (unique.length > 0) {
+                setItems((prev) => [...unique, ...prev]);
+                setPage(1);
+                // Auto-open details for the first newly added torrent
+                const firstId = infoHashHex(unique[0]);
+                if (firstId) setSelectedId(firstId);
+                // Ensure DetailsPanel defaults to General after uploads
+                setActiveTab('general');
+            }
+        },
+        [items]
+    );
+
+    const newRequest = useCallback(
+        (source: Partial<echo.AddRequest>) =>
+            echo.AddRequest.createFrom({
+                ...source,
+                options: echo.AddOptions.createFrom({
+                    category: addCategory,
+                    tags: [],
+                }),
+            }),
+        [addCategory]
+    );
+
+    // Selected files are added from the dialog, each with its own options.
     const handleSelect = useCallback(
         async (files: File[]) => {
-            setBusy(true);
-            setError(null);
-            try {
-                const parsed: Models.Torrent[] = [];
-                for (const f of files) {
-                    const buf = new Uint8Array(await f.arrayBuffer());
-                    const info = await AddTorrent(Array.from(buf));
-                    parsed.push(info as Models.Torrent);
-                }
+            const pending = await Promise.all(
+                files.map(async (f) => ({
+                    label: f.name,
+                    request: newRequest({
+                        data: Array.from(new Uint8Array(await f.arrayBuffer())),
+                    }),
+                }))
+            );
+            if (pending.length === 0) return;
+            setPendingAdds(pending);
+            setAdding(true);
+        },
+        [newRequest]
+    );
 
-                // Enforce uniqueness by infohash against current list and within batch
-                const existing = new Set(items.map((i) => infoHashHex(i)));
-                const seen = new Set<string>();
-                const unique = parsed.filter((p) => {
-                    const id = infoHashHex(p);
-                    if (!id || existing.has(id) || seen.has(id)) return false;
-                    seen.add(id);
-                    return true;
-                });
+    // The native dialog hands back paths, which the backend reads itself.
+    const handleBrowse = useCallback(async () => {
+        try {
+            const paths = (await ChooseTorrentFiles()) || [];
+            if (paths.length === 0) return;
+            setPendingAdds(
+                paths.map((path) => ({
+                    label: path.split(/[\\/]/).pop() || path,
+                    request: newRequest({ path }),
+                }))
+            );
+            setAdding(true);
+        } catch (e: any) {
+            setError(e?.message ?? String(e));
+        }
+    }, [newRequest]);
 
-                const skipped = parsed.length - unique.length;
-                if (skipped > 0) {
+    // Dropped files and magnet links go through the same dialog, once the
+    // backend has checked them; those it turned down are reported.
+    useDrops(
+        useCallback(
+            (dropped: ui.DroppedItem[]) => {
+                const rejected = dropped.filter((d) => d.error);
+                if (rejected.length > 0) {
                     setError(
-                        `Skipped ${skipped} duplicate${skipped > 1 ? 's' : ''}.`
+                        rejected.map((d) => `${d.label}: ${d.error}`).join('; ')
                     );
                 }
+                const pending = dropped
+                    .filter((d) => !d.error)
+                    .map((d) => ({
+                        label: d.label,
+                        request: newRequest(d.request),
+                    }));
+                if (pending.length === 0) return;
+                setPendingAdds(pending);
+                setAdding(true);
+            },
+            [newRequest]
+        )
+    );
+
+    // A search result goes through the add dialog like any other torrent;
+    // a .torrent link is preferred since it needs no metadata fetch.
+    const handleSearchResult = useCallback(
+        (r: search.Result) => {
+            // Merged results download from their best-seeded source.
+            const best = r.sources?.find((s) => s.link || s.magnet) || r;
+            setPendingAdds([
+                {
+                    label: r.title,
+                    request: newRequest(
+                        best.link ? { url: best.link } : { magnet: best.magnet }
+                    ),
+                },
+            ]);
+            setAdding(true);
+        },
+        [newRequest]
+    );
+
+    const handleAdded = useCallback(
+        (added: Models.Torrent[], duplicates: number) => {
+            addParsed(added);
+            refreshLabels();
+            if (duplicates > 0) {
+                setError(
+                    `Skipped ${duplicates} duplicate${duplicates > 1 ? 's' : ''}.`
+                );
+            }
+        },
+        [addParsed, refreshLabels]
+    );
 
-                if (unique.length > 0) {
-                    setItems((prev) => [...unique, ...prev]);
-                    setPage(1);
-                    // Auto-open details for the first newly added torrent
-                    const firstId = infoHashHex(unique[0]);
-                    if (firstId) setSelectedId(firstId);
-                    // Ensure DetailsPanel defaults to General after uploads
-                    setActiveTab('general');
+    const handleMagnet = useCallback(
+        async (e: React.FormEvent) => {
+            e.preventDefault();
+            const uri = magnet.trim();
+            if (!uri) return;
+            setBusy(true);
+            setError(null);
+            try {
+                const opts = echo.AddOptions.createFrom({
+                    category: addCategory,
+                    tags: [],
+                });
+                // A URL resolves once the .torrent has been downloaded; a
+                // magnet straight away, its torrent following when its
+                // metadata has been fetched from peers.
+                if (/^https?:\/\//i.test(uri)) {
+                    addParsed([await AddTorrentFromURL(uri, opts)]);
+                    refreshLabels();
+                } else {
+                    trackMagnet(await AddMagnet(uri, opts));
                 }
+                setMagnet('');
             } catch (e: any) {
-                setError(e?.message ?? 'Failed to parse torrent');
+                setError(e?.message ?? String(e));
             } finally {
                 setBusy(false);
             }
         },
-        [items]
+        [magnet, addParsed, addCategory, refreshLabels, trackMagnet]
     );
 
     const [sortKey, setSortKey] = useState<SortKey>('name');
     const [sortDir, setSortDir] = useState<SortDir>('asc');
-    const { filtered, sorted } = useFilterSort(items, query, sortKey, sortDir);
+    const tags = useMemo(
+        () =>
+            Array.from(
+                new Set(Object.values(labels).flatMap((l) => l.tags || []))
+            ).sort(),
+        [labels]
+    );
+    const labelled = useMemo(
+        () =>
+            items.filter((t) => {
+                const l = labels[infoHashHex(t)];
+                if (filterCategory && l?.category !== filterCategory)
+                    return false;
+                if (filterTag && !(l?.tags || []).includes(filterTag))
+                    return false;
+                return true;
+            }),
+        [items, labels, filterCategory, filterTag]
+    );
+    const { filtered, sorted } = useFilterSort(
+        labelled,
+        query,
+        sortKey,
+        sortDir
+    );
 
     // Clear selection if it no longer exists in the filtered list
     useEffect(() => {
@@ -127,12 +402,204 @@ function App() {
                 <div className="card ui-card">
                     <h2 className="card-title">Add Torrents</h2>
                     <p className="card-desc">
-                        Select or drop .torrent files to get started.
+                        Select or drop .torrent files, or paste a magnet link or
+                        a .torrent URL, to get started.
                     </p>
                     <TorrentUploader
                         onSelect={handleSelect}
                         hint={busy ? 'Parsing…' : undefined}
                     />
+                    <form
+                        className="ui-stack"
+                        style={{ marginTop: 12, alignItems: 'flex-end' }}
+                        onSubmit={handleMagnet}
+                    >
+                        <div style={{ flex: 1 }}>
+                            <Input
+                                placeholder="magnet:?xt=urn:btih:… or https://…/file.torrent"
+                                aria-label="Magnet link or .torrent URL"
+                                value={magnet}
+                                onChange={(e) => setMagnet(e.target.value)}
+                            />
+                        </div>
+                        <Button type="submit" loading={busy}>
+                            Add
+                        </Button>
+                        <Button
+                            type="button"
+                            variant="ghost"
+                            disabled={busy}
+                            onClick={handleBrowse}
+                        >
+                            Browse…
+                        </Button>
+                    </form>
+                    <div
+                        className="ui-stack"
+                        style={{ marginTop: 8, alignItems: 'center' }}
+                    >
+                        <label className="label" htmlFor="add-category">
+                            Add to category
+                        </label>
+                        <select
+                            id="add-category"
+                            className="ui-input control"
+                            value={addCategory}
+                            onChange={(e) => setAddCategory(e.target.value)}
+                        >
+                            <option value="">None</option>
+                            {categories.map((c) => (
+                                <option key={c.name} value={c.name}>
+                                    {c.name}
+                                </option>
+                            ))}
+                        </select>
+                        <Button
+                            variant="ghost"
+                            onClick={() => setManagingCategories(true)}
+                        >
+                            Manage categories
+                        </Button>
+                        <Button
+                            variant="ghost"
+                            onClick={() => setManagingWatch(true)}
+                        >
+                            Watch folders
+                        </Button>
+                        <Button
+                            variant="ghost"
+                            onClick={() => setManagingFeeds(true)}
+                        >
+                            RSS feeds
+                        </Button>
+                        <Button
+                            variant="ghost"
+                            onClick={() => setSearching(true)}
+                        >
+                            Search
+                        </Button>
+                        <Button
+                            variant="ghost"
+                            onClick={() => setManagingIndexers(true)}
+                        >
+                            Indexers
+                        </Button>
+                        <Button
+                            variant="ghost"
+                            onClick={() => setEditingSchedule(true)}
+                        >
+                            Schedule
+                        </Button>
+                        <Button
+                            variant="ghost"
+                            onClick={() => setEditingPower(true)}
+                        >
+                            Power
+                        </Button>
+                        <Button
+                            variant="ghost"
+                            onClick={() => setEditingQuotas(true)}
+                        >
+                            Quotas
+                        </Button>
+                        <Button
+                            variant="ghost"
+                            onClick={() => setShowingHistory(true)}
+                        >
+                            History
+                        </Button>
+                        <Button
+                            variant="ghost"
+                            onClick={() => setEditingSettings(true)}
+                        >
+                            Settings
+                        </Button>
+                        <Button
+                            variant="ghost"
+                            onClick={() => setViewingLogs(true)}
+                        >
+                            Logs
+                        </Button>
+                        <Button
+                            variant="ghost"
+                            onClick={() => setImporting(true)}
+                        >
+                            Import…
+                        </Button>
+                        <Button
+                            variant="ghost"
+                            onClick={() => setCreating(true)}
+                        >
+                            Create…
+                        </Button>
+                    </div>
+                    <WatchFolderManager
+                        open={managingWatch}
+                        onOpenChange={setManagingWatch}
+                        categories={categories}
+                    />
+                    <FeedManager
+                        open={managingFeeds}
+                        onOpenChange={setManagingFeeds}
+                        categories={categories}
+                    />
+                    <IndexerManager
+                        open={managingIndexers}
+                        onOpenChange={setManagingIndexers}
+                    />
+                    <SearchDialog
+                        open={searching}
+                        onOpenChange={setSearching}
+                        onDownload={handleSearchResult}
+                    />
+                    <PauseScheduleEditor
+                        open={editingSchedule}
+                        onOpenChange={setEditingSchedule}
+                    />
+                    <PowerPolicyEditor
+                        open={editingPower}
+                        onOpenChange={setEditingPower}
+                    />
+                    <QuotaEditor
+                        open={editingQuotas}
+                        onOpenChange={setEditingQuotas}
+                    />
+                    <HistoryDialog
+                        open={showingHistory}
+                        onOpenChange={setShowingHistory}
+                    />
+                    <AddDialog
+                        open={adding}
+                        onOpenChange={setAdding}
+                        items={pendingAdds}
+                        categories={categories}
+                        onAdded={handleAdded}
+                    />
+                    <SettingsEditor
+                        open={editingSettings}
+                        onOpenChange={setEditingSettings}
+                    />
+                    <LogViewer
+                        open={viewingLogs}
+                        onOpenChange={setViewingLogs}
+                    />
+                    <CloseDialog />
+                    <CreateTorrentDialog
+                        open={creating}
+                        onOpenChange={setCreating}
+                        onSeeded={refresh}
+                    />
+                    <ImportDialog
+                        open={importing}
+                        onOpenChange={setImporting}
+                        onImported={refresh}
+                    />
+                    <CategoryManager
+                        open={managingCategories}
+                        onOpenChange={setManagingCategories}
+                        categories={categories}
+                        onChange={refreshLabels}
+                    />
                     {error && (
                         <div
                             className="uploader-error"
@@ -145,12 +612,48 @@ function App() {
                     )}
                 </div>
 
+                <PendingMagnets magnets={magnets} />
+
                 {items.length > 0 && (
                     <div className="card ui-card" style={{ marginTop: 16 }}>
                         <Toolbar
-                            totalLabel={`${items.length} total • ${formatBytes(totalSize)}`}
+                            totalLabel={`${items.length} total • ${formatBytes(totalSize)}${dhtLabel}${sessionLabel}${suspendedLabel}`}
                             query={query}
                             onQueryChange={setQuery}
+                            filters={
+                                <>
+                                    <select
+                                        className="ui-input control"
+                                        aria-label="Filter by category"
+                                        value={filterCategory}
+                                        onChange={(e) =>
+                                            setFilterCategory(e.target.value)
+                                        }
+                                    >
+                                        <option value="">All categories</option>
+                                        {categories.map((c) => (
+                                            <option key={c.name} value={c.name}>
+                                                {c.name}
+                                            </option>
+                                        ))}
+                                    </select>
+                                    <select
+                                        className="ui-input control"
+                                        aria-label="Filter by tag"
+                                        value={filterTag}
+                                        onChange={(e) =>
+                                            setFilterTag(e.target.value)
+                                        }
+                                    >
+                                        <option value="">All tags</option>
+                                        {tags.map((t) => (
+                                            <option key={t} value={t}>
+                                                {t}
+                                            </option>
+                                        ))}
+                                    </select>
+                                </>
+                            }
                             onClearAll={() => {
                                 setItems([]);
                                 setSelectedId(null);
@@ -259,7 +762,27 @@ function App() {
                                 torrent={sel}
                                 activeTab={activeTab}
                                 onTabChange={setActiveTab}
-                                trackerStats={trackerStats}
+                                trackerStats={trackerStats[selectedId]}
+                                status={states[selectedId]}
+                                labels={labels[selectedId]}
+                                categories={categories}
+                                onLabelsChange={refreshLabels}
+                                onTogglePause={async () => {
+                                    const hash = sel.metainfo?.info
+                                        ?.infoHash as number[];
+                                    const active = isActive(
+                                        states[selectedId]?.state
+                                    );
+                                    try {
+                                        if (active) {
+                                            await PauseTorrent(hash);
+                                        } else {
+                                            await ResumeTorrent(hash);
+                                        }
+                                    } catch (e: any) {
+                                        setError(e?.message ?? String(e));
+                                    }
+                                }}
                             />
                         );
                     })()}
@@ -267,6 +790,19 @@ function App() {
                 {/* Inline details handled per row via selectedId */}
             </main>
 
+            <div className="notifications" role="status" aria-live="polite">
+                {notifications.map((n, i) => (
+                    <div
+                        key={`${n.time}-${i}`}
+                        className={`notification ui-card ${n.kind}`}
+                        onClick={() => dismiss(n)}
+                    >
+                        <div>{n.title}</div>
+                        <div className="muted">{n.message}</div>
+                    </div>
+                ))}
+            </div>
+
             <footer className="footer">
                 <span className="muted">v0.1.0</span>
             </footer>
@@ -279,9 +815,11 @@ import { PeersProvider } from './providers/PeersProvider';
 export default function AppWithProviders() {
     return (
         <TrackerStatsProvider>
-            <PeersProvider>
-                <App />
-            </PeersProvider>
+            <TorrentStateProvider>
+                <PeersProvider>
+                    <App />
+                </PeersProvider>
+            </TorrentStateProvider>
         </TrackerStatsProvider>
     );
 }
@@ -0,0 +1,218 @@
+import React, { useEffect, useState } from 'react';
+import Button from './primitives/Button';
+import Input from './primitives/Input';
+import Modal from './primitives/Modal';
+import { AddTorrents } from '../../wailsjs/go/ui/UI';
+import { echo, torrent as Models } from '../../wailsjs/go/models';
+
+export type PendingAdd = {
+    label: string;
+    request: echo.AddRequest;
+};
+
+type Props = {
+    open: boolean;
+    onOpenChange: (open: boolean) => void;
+    items: PendingAdd[];
+    categories: echo.Category[];
+    onAdded: (added: Models.Torrent[], duplicates: number) => void;
+};
+
+type Row = PendingAdd & { error?: string };
+
+// Blank means every file.
+const toFiles = (files?: number[]) => (files || []).join(', ');
+const fromFiles = (s: string): number[] | null => {
+    const parts = s
+        .split(/[\s,]+/)
+        .map((p) => p.trim())
+        .filter(Boolean);
+    if (parts.length === 0) return null;
+    return parts.map(Number).filter((n) => Number.isInteger(n) && n >= 0);
+};
+
+export const AddDialog: React.FC<Props> = ({
+    open,
+    onOpenChange,
+    items,
+    categories,
+    onAdded,
+}) => {
+    const [rows, setRows] = useState<Row[]>([]);
+    const [files, setFiles] = useState<string[]>([]);
+    const [adding, setAdding] = useState(false);
+
+    useEffect(() => {
+        if (!open) return;
+        setRows(items);
+        setFiles(items.map((i) => toFiles(i.request.options?.files)));
+    }, [open, items]);
+
+    const update = (i: number, change: Partial<echo.AddOptions>) =>
+        setRows((prev) =>
+            prev.map((r, j) =>
+                j !== i
+                    ? r
+                    : {
+                          ...r,
+                          request: echo.AddRequest.createFrom({
+                              ...r.request,
+                              options: { ...r.request.options, ...change },
+                          }),
+                      }
+            )
+        );
+
+    const add = () => {
+        const reqs = rows.map((r, i) =>
+            echo.AddRequest.createFrom({
+                ...r.request,
+                options: { ...r.request.options, files: fromFiles(files[i]) },
+            })
+        );
+        setAdding(true);
+        AddTorrents(reqs)
+            .then((results) => {
+                const added: Models.Torrent[] = [];
+                const failed: Row[] = [];
+                const failedFiles: string[] = [];
+                let duplicates = 0;
+                (results || []).forEach((res, i) => {
+                    if (res.duplicate) duplicates++;
+                    else if (res.error) {
+                        failed.push({ ...rows[i], error: res.error });
+                        failedFiles.push(files[i]);
+                    } else if (res.torrent) added.push(res.torrent);
+                });
+                onAdded(added, duplicates);
+                // Only the torrents that failed are left to retry.
+                setRows(failed);
+                setFiles(failedFiles);
+                if (failed.length === 0) onOpenChange(false);
+            })
+            .catch((e) =>
+                setRows((prev) => prev.map((r) => ({ ...r, error: String(e) })))
+            )
+            .finally(() => setAdding(false));
+    };
+
+    return (
+        <Modal open={open} onOpenChange={onOpenChange} title="Add torrents">
+            <div className="muted" style={{ marginBottom: 8 }}>
+                Each torrent keeps its own options. A blank save path uses the
+                category's; blank files downloads every file.
+            </div>
+            {rows.map((r, i) => {
+                const opts = r.request.options;
+                return (
+                    <div
+                        key={`${r.label}-${i}`}
+                        style={{
+                            padding: '8px 0',
+                            borderTop: i > 0 ? '1px solid #333' : undefined,
+                        }}
+                    >
+                        <div className="mono">{r.label}</div>
+                        <div
+                            className="ui-stack"
+                            style={{ marginTop: 6, alignItems: 'center' }}
+                        >
+                            <select
+                                className="ui-input control"
+                                aria-label="Category"
+                                value={opts?.category || ''}
+                                onChange={(e) =>
+                                    update(i, { category: e.target.value })
+                                }
+                            >
+                                <option value="">No category</option>
+                                {categories.map((c) => (
+                                    <option key={c.name} value={c.name}>
+                                        {c.name}
+                                    </option>
+                                ))}
+                            </select>
+                            <div style={{ flex: 1 }}>
+                                <Input
+                                    placeholder="Save path"
+                                    aria-label="Save path"
+                                    value={opts?.savePath || ''}
+                                    onChange={(e) =>
+                                        update(i, { savePath: e.target.value })
+                                    }
+                                />
+                            </div>
+                        </div>
+                        <div
+                            className="ui-stack"
+                            style={{ marginTop: 6, alignItems: 'center' }}
+                        >
+                            <label className="label">
+                                <input
+                                    type="checkbox"
+                                    checked={!!opts?.paused}
+                                    onChange={(e) =>
+                                        update(i, { paused: e.target.checked })
+                                    }
+                                />{' '}
+                                Start paused
+                            </label>
+                            <label className="label">
+                                <input
+                                    type="checkbox"
+                                    checked={!!opts?.sequential}
+                                    onChange={(e) =>
+                                        update(i, {
+                                            sequential: e.target.checked,
+                                        })
+                                    }
+                                />{' '}
+                                Sequential
+                            </label>
+                            <div style={{ flex: 1 }}>
+                                <Input
+                                    placeholder="Files, e.g. 0, 2, 3"
+                                    aria-label="File indices"
+                                    value={files[i] || ''}
+                                    onChange={(e) =>
+                                        setFiles((prev) =>
+                                            prev.map((f, j) =>
+                                                j === i ? e.target.value : f
+                                            )
+                                        )
+                                    }
+                                />
+                            </div>
+                        </div>
+                        {r.error && (
+                            <div
+                                role="alert"
+                                style={{ marginTop: 4, color: '#ff6b6b' }}
+                            >
+                                {r.error}
+                            </div>
+                        )}
+                    </div>
+                );
+            })}
+            <div
+                className="ui-stack"
+                style={{ justifyContent: 'flex-end', marginTop: 12 }}
+            >
+                <Button variant="ghost" onClick={() => onOpenChange(false)}>
+                    Cancel
+                </Button>
+                <Button
+                    variant="primary"
+                    loading={adding}
+                    disabled={rows.length === 0}
+                    onClick={add}
+                >
+                    Add {rows.length > 1 ? `${rows.length} torrents` : ''}
+                </Button>
+            </div>
+        </Modal>
+    );
+};
+
+export default AddDialog;
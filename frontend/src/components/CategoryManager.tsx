@@ -0,0 +1,126 @@
+import React, { useState } from 'react';
+import Button from './primitives/Button';
+import Input from './primitives/Input';
+import Modal from './primitives/Modal';
+import { AddCategory, RemoveCategory } from '../../wailsjs/go/ui/UI';
+import { echo } from '../../wailsjs/go/models';
+import { formatBytes } from '../utils/torrent';
+
+// Quotas are edited in GiB and stored in bytes.
+const GiB = 1024 ** 3;
+
+type Props = {
+    open: boolean;
+    onOpenChange: (open: boolean) => void;
+    categories: echo.Category[];
+    onChange: () => void;
+};
+
+export const CategoryManager: React.FC<Props> = ({
+    open,
+    onOpenChange,
+    categories,
+    onChange,
+}) => {
+    const [name, setName] = useState('');
+    const [savePath, setSavePath] = useState('');
+    const [quota, setQuota] = useState('');
+    const [error, setError] = useState('');
+
+    const add = (e: React.FormEvent) => {
+        e.preventDefault();
+        setError('');
+        AddCategory(
+            echo.Category.createFrom({
+                name,
+                savePath,
+                quota: Math.max(0, Math.round(Number(quota) * GiB)),
+            })
+        )
+            .then(() => {
+                setName('');
+                setSavePath('');
+                setQuota('');
+                onChange();
+            })
+            .catch((e) => setError(String(e)));
+    };
+
+    return (
+        <Modal open={open} onOpenChange={onOpenChange} title="Categories">
+            {categories.length === 0 && (
+                <div className="muted">No categories yet.</div>
+            )}
+            {categories.map((c) => (
+                <div
+                    key={c.name}
+                    className="ui-stack"
+                    style={{ justifyContent: 'space-between' }}
+                >
+                    <div>
+                        <div>{c.name}</div>
+                        <div className="muted mono">
+                            {c.savePath || 'Default download folder'}
+                        </div>
+                        {c.quota > 0 && (
+                            <div className="muted">
+                                Quota {formatBytes(c.quota)}
+                            </div>
+                        )}
+                    </div>
+                    <Button
+                        variant="ghost"
+                        onClick={() =>
+                            RemoveCategory(c.name)
+                                .then(onChange)
+                                .catch(() => {})
+                        }
+                    >
+                        Remove
+                    </Button>
+                </div>
+            ))}
+            <form onSubmit={add} style={{ marginTop: 12 }}>
+                <Input
+                    label="Name"
+                    value={name}
+                    onChange={(e) => setName(e.target.value)}
+                />
+                <div style={{ marginTop: 8 }}>
+                    <Input
+                        label="Save path (blank for the default)"
+                        value={savePath}
+                        onChange={(e) => setSavePath(e.target.value)}
+                    />
+                </div>
+                <div style={{ marginTop: 8 }}>
+                    <Input
+                        label="Disk quota (GiB, blank for none)"
+                        type="number"
+                        min={0}
+                        value={quota}
+                        onChange={(e) => setQuota(e.target.value)}
+                    />
+                </div>
+                {error && (
+                    <div
+                        role="alert"
+                        style={{ marginTop: 4, color: '#ff6b6b' }}
+                    >
+                        {error}
+                    </div>
+                )}
+                <div
+                    className="ui-stack"
+                    style={{ justifyContent: 'flex-end', marginTop: 12 }}
+                >
+                    <Button type="submit" variant="primary">
+                        Add or update
+                    </Button>
+                </div>
+            </form>
+        </Modal>
+    );
+};
+
+export default CategoryManager;
@@ -0,0 +1,97 @@
+import React, { useEffect, useState } from 'react';
+import Button from './primitives/Button';
+import Modal from './primitives/Modal';
+import { Quit, RunInBackground, SetCloseAction } from '../../wailsjs/go/ui/UI';
+import { EventsOn } from '../../wailsjs/runtime';
+
+export type CloseAction = 'ask' | 'quit' | 'background';
+
+const storageKey = 'app.closeAction';
+
+export const savedCloseAction = (): CloseAction => {
+    try {
+        const v = localStorage.getItem(storageKey);
+        if (v === 'quit' || v === 'background') return v;
+    } catch {}
+    return 'ask';
+};
+
+// saveCloseAction remembers what closing the window does and tells the
+// backend, which decides before the frontend hears about a close.
+export const saveCloseAction = (action: CloseAction) => {
+    try {
+        localStorage.setItem(storageKey, action);
+    } catch {}
+    return SetCloseAction(action);
+};
+
+type Request = { downloading: number; seeding: number };
+
+// CloseDialog asks what to do when the window is closed while torrents
+// are still transferring.
+export const CloseDialog: React.FC = () => {
+    const [request, setRequest] = useState<Request | null>(null);
+    const [remember, setRemember] = useState(false);
+
+    useEffect(() => {
+        saveCloseAction(savedCloseAction()).catch(() => {});
+        const off = EventsOn('app:close-requested', (payload: any) => {
+            if (!payload) return;
+            setRemember(false);
+            setRequest(payload as Request);
+        });
+        return () => {
+            if (typeof off === 'function') off();
+        };
+    }, []);
+
+    const choose = (action: CloseAction) => {
+        setRequest(null);
+        if (remember) saveCloseAction(action).catch(() => {});
+        if (action === 'quit') Quit();
+        else RunInBackground();
+    };
+
+    const parts = [
+        request?.downloading && `${request.downloading} downloading`,
+        request?.seeding && `${request.seeding} seeding`,
+    ].filter(Boolean);
+
+    return (
+        <Modal
+            open={request !== null}
+            onOpenChange={(open) => !open && setRequest(null)}
+            title="Quit Echo?"
+        >
+            <div>
+                Torrents are still transferring ({parts.join(', ')}). Quitting
+                stops them; in the background they keep going with the window
+                minimised.
+            </div>
+            <label className="label" style={{ display: 'block', marginTop: 8 }}>
+                <input
+                    type="checkbox"
+                    checked={remember}
+                    onChange={(e) => setRemember(e.target.checked)}
+                />{' '}
+                Don't ask again
+            </label>
+            <div
+                className="ui-stack"
+                style={{ justifyContent: 'flex-end', marginTop: 12 }}
+            >
+                <Button variant="ghost" onClick={() => setRequest(null)}>
+                    Cancel
+                </Button>
+                <Button variant="ghost" onClick={() => choose('background')}>
+                    Keep running
+                </Button>
+                <Button variant="primary" onClick={() => choose('quit')}>
+                    Quit
+                </Button>
+            </div>
+        </Modal>
+    );
+};
+
+export default CloseDialog;
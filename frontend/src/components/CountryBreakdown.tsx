@@ -0,0 +1,53 @@
+import React from 'react';
+import usePeerCountries from '../hooks/usePeerCountries';
+import { peer } from '../../wailsjs/go/models';
+import { formatBytes } from '../utils/torrent';
+
+type Props = {
+    infoHash: number[];
+    hex: string;
+};
+
+// CountryBreakdown shows where a torrent's connected peers are, a bar per
+// country sized by its share of the peers.
+export const CountryBreakdown: React.FC<Props> = ({ infoHash, hex }) => {
+    const countries = usePeerCountries(infoHash, hex);
+    if (countries.length === 0) return null;
+
+    const total = countries.reduce((n, c) => n + c.peers, 0);
+    const exchanged = (c: peer.CountryStats) =>
+        `↓ ${formatBytes(c.downloaded)} ↑ ${formatBytes(c.uploaded)}`;
+    return (
+        <div className="section-block">
+            <div className="label" style={{ marginBottom: 6 }}>
+                Peers by country
+            </div>
+            {countries.map((c) => (
+                <div
+                    key={c.isoCode || '?'}
+                    className="ui-stack"
+                    style={{ alignItems: 'center', marginBottom: 4 }}
+                    title={exchanged(c)}
+                >
+                    <span style={{ width: 24 }}>{c.flag || '-'}</span>
+                    <span style={{ width: 160 }}>{c.country || 'Unknown'}</span>
+                    <div style={{ flex: 1 }}>
+                        <div
+                            style={{
+                                width: `${(c.peers / total) * 100}%`,
+                                height: 8,
+                                borderRadius: 4,
+                                background: '#4a90d9',
+                            }}
+                        />
+                    </div>
+                    <span className="mono" style={{ width: 40 }}>
+                        {c.peers}
+                    </span>
+                </div>
+            ))}
+        </div>
+    );
+};
+
+export default CountryBreakdown;
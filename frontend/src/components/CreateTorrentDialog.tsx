@@ -0,0 +1,245 @@
+import React, { useEffect, useState } from 'react';
+import Button from './primitives/Button';
+import Input from './primitives/Input';
+import Modal from './primitives/Modal';
+import {
+    CancelCreate,
+    ChooseCreateSource,
+    CreateTorrent,
+    SaveCreatedTorrent,
+    SeedCreatedTorrent,
+} from '../../wailsjs/go/ui/UI';
+import { EventsOn } from '../../wailsjs/runtime';
+import { echo, ui } from '../../wailsjs/go/models';
+import { formatBytes } from '../utils/torrent';
+
+type Props = {
+    open: boolean;
+    onOpenChange: (open: boolean) => void;
+    onSeeded: () => void;
+};
+
+// Powers of two from 16 KiB to 16 MiB; zero lets the engine pick.
+const pieceLengths = [0, ...Array.from({ length: 11 }, (_, i) => 16384 << i)];
+
+const lines = (text: string) =>
+    text
+        .split('\n')
+        .map((l) => l.trim())
+        .filter(Boolean);
+
+export const CreateTorrentDialog: React.FC<Props> = ({
+    open,
+    onOpenChange,
+    onSeeded,
+}) => {
+    const [path, setPath] = useState('');
+    const [pieceLength, setPieceLength] = useState(0);
+    const [trackers, setTrackers] = useState('');
+    const [webSeeds, setWebSeeds] = useState('');
+    const [comment, setComment] = useState('');
+    const [priv, setPriv] = useState(false);
+    const [source, setSource] = useState('');
+    const [progress, setProgress] = useState<[number, number] | null>(null);
+    const [created, setCreated] = useState<ui.CreatedTorrent | null>(null);
+    const [error, setError] = useState('');
+    const [busy, setBusy] = useState(false);
+
+    useEffect(() => {
+        if (!open) return;
+        setCreated(null);
+        setProgress(null);
+        setError('');
+        const off = EventsOn('create:progress', (payload: any) => {
+            if (payload) setProgress([payload.hashed, payload.total]);
+        });
+        return () => {
+            if (typeof off === 'function') off();
+        };
+    }, [open]);
+
+    const choose = (folder: boolean) =>
+        ChooseCreateSource(folder)
+            .then((p) => p && setPath(p))
+            .catch((e) => setError(String(e)));
+
+    const create = () => {
+        setError('');
+        setCreated(null);
+        setProgress([0, 0]);
+        CreateTorrent(
+            echo.CreateOptions.createFrom({
+                path: path.trim(),
+                pieceLength,
+                trackers: lines(trackers),
+                webSeeds: lines(webSeeds),
+                comment: comment.trim(),
+                private: priv,
+                source: source.trim(),
+            })
+        )
+            .then(setCreated)
+            .catch((e) => setError(String(e)))
+            .finally(() => setProgress(null));
+    };
+
+    const close = (open: boolean) => {
+        if (!open && progress) CancelCreate();
+        onOpenChange(open);
+    };
+
+    const save = () => {
+        setError('');
+        SaveCreatedTorrent().catch((e) => setError(String(e)));
+    };
+
+    const seed = () => {
+        setBusy(true);
+        setError('');
+        SeedCreatedTorrent(echo.AddOptions.createFrom({ tags: [] }))
+            .then(() => {
+                onSeeded();
+                onOpenChange(false);
+            })
+            .catch((e) => setError(String(e)))
+            .finally(() => setBusy(false));
+    };
+
+    return (
+        <Modal open={open} onOpenChange={close} title="Create torrent">
+            <div className="ui-stack" style={{ alignItems: 'flex-end' }}>
+                <div style={{ flex: 1 }}>
+                    <Input
+                        label="File or folder to share"
+                        value={path}
+                        onChange={(e) => setPath(e.target.value)}
+                    />
+                </div>
+                <Button variant="ghost" onClick={() => choose(false)}>
+                    File…
+                </Button>
+                <Button variant="ghost" onClick={() => choose(true)}>
+                    Folder…
+                </Button>
+            </div>
+            <label
+                className="label"
+                htmlFor="create-piece-length"
+                style={{ display: 'block', margin: '8px 0 6px' }}
+            >
+                Piece size
+            </label>
+            <select
+                id="create-piece-length"
+                className="ui-input"
+                value={pieceLength}
+                onChange={(e) => setPieceLength(Number(e.target.value))}
+            >
+                {pieceLengths.map((n) => (
+                    <option key={n} value={n}>
+                        {n === 0 ? 'Automatic' : formatBytes(n, 0)}
+                    </option>
+                ))}
+            </select>
+            <label
+                className="label"
+                htmlFor="create-trackers"
+                style={{ display: 'block', margin: '8px 0 6px' }}
+            >
+                Trackers, one per line
+            </label>
+            <textarea
+                id="create-trackers"
+                className="ui-input"
+                rows={4}
+                style={{ width: '100%', fontFamily: 'inherit' }}
+                value={trackers}
+                onChange={(e) => setTrackers(e.target.value)}
+            />
+            <label
+                className="label"
+                htmlFor="create-web-seeds"
+                style={{ display: 'block', margin: '8px 0 6px' }}
+            >
+                Web seeds, one per line
+            </label>
+            <textarea
+                id="create-web-seeds"
+                className="ui-input"
+                rows={2}
+                style={{ width: '100%', fontFamily: 'inherit' }}
+                value={webSeeds}
+                onChange={(e) => setWebSeeds(e.target.value)}
+            />
+            <div className="ui-stack" style={{ marginTop: 8 }}>
+                <Input
+                    label="Comment"
+                    value={comment}
+                    onChange={(e) => setComment(e.target.value)}
+                />
+                <Input
+                    label="Source"
+                    placeholder="Tracker tag"
+                    value={source}
+                    onChange={(e) => setSource(e.target.value)}
+                />
+            </div>
+            <label className="label" style={{ display: 'block', marginTop: 8 }}>
+                <input
+                    type="checkbox"
+                    checked={priv}
+                    onChange={(e) => setPriv(e.target.checked)}
+                />{' '}
+                Private (no DHT or peer exchange)
+            </label>
+            {progress && (
+                <progress
+                    max={progress[1] || 1}
+                    value={progress[0]}
+                    style={{ width: '100%', marginTop: 8 }}
+                />
+            )}
+            {created && (
+                <div className="muted" style={{ marginTop: 8 }}>
+                    {created.name}: {formatBytes(created.size)} in{' '}
+                    {created.pieces} pieces of{' '}
+                    {formatBytes(created.pieceLength, 0)}
+                </div>
+            )}
+            {error && (
+                <div role="alert" style={{ marginTop: 4, color: '#ff6b6b' }}>
+                    {error}
+                </div>
+            )}
+            <div
+                className="ui-stack"
+                style={{ justifyContent: 'flex-end', marginTop: 12 }}
+            >
+                <Button variant="ghost" onClick={() => close(false)}>
+                    {progress ? 'Cancel' : 'Close'}
+                </Button>
+                {created ? (
+                    <>
+                        <Button variant="ghost" onClick={save}>
+                            Save .torrent…
+                        </Button>
+                        <Button variant="primary" loading={busy} onClick={seed}>
+                            Add and seed
+                        </Button>
+                    </>
+                ) : (
+                    <Button
+                        variant="primary"
+                        loading={!!progress}
+                        disabled={!path.trim()}
+                        onClick={create}
+                    >
+                        Create
+                    </Button>
+                )}
+            </div>
+        </Modal>
+    );
+};
+
+export default CreateTorrentDialog;
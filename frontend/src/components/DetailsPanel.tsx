@@ -1,12 +1,45 @@
-import React, { useState } from 'react';
+import React, { useEffect, useState } from 'react';
 import Button from './primitives/Button';
 import { torrent as Models } from '../../wailsjs/go/models';
 import { infoHashHex } from '../utils/torrent';
 import FileTree from './FileTree';
-import { formatBytes } from '../utils/torrent';
+import {
+    formatBytes,
+    formatETA,
+    formatLimit,
+    formatRate,
+} from '../utils/torrent';
 import TrackersList from './TrackersList';
+import TrackerEditor from './TrackerEditor';
+import ShareLimitsEditor from './ShareLimitsEditor';
+import TorrentOptionsEditor from './TorrentOptionsEditor';
 import Tabs from './primitives/Tabs';
 import PeersList from './PeersList';
+import CountryBreakdown from './CountryBreakdown';
+import PieceBar from './PieceBar';
+import SpeedGraph from './SpeedGraph';
+import {
+    ExportTorrent,
+    ForceReannounce,
+    ForceRecheck,
+    MagnetURI,
+    MoveDown,
+    MoveToBottom,
+    MoveToTop,
+    MoveUp,
+    OpenTorrentFile,
+    ReannounceTracker,
+    RevealTorrent,
+    SetCategory,
+    SetFilePriority,
+    SetFilesWanted,
+    SetTags,
+} from '../../wailsjs/go/ui/UI';
+import { echo } from '../../wailsjs/go/models';
+import { TorrentStatus, isActive } from '../providers/TorrentStateProvider';
+import useTorrentDetails from '../hooks/useTorrentDetails';
+import usePieceMap from '../hooks/usePieceMap';
+import useSpeedHistory, { SpeedRange } from '../hooks/useSpeedHistory';
 
 type Props = {
     torrent: Models.Torrent;
@@ -23,6 +56,11 @@ type Props = {
             at: number;
         }
     >;
+    status?: TorrentStatus;
+    labels?: echo.Labels;
+    categories?: echo.Category[];
+    onLabelsChange?: () => void;
+    onTogglePause?: () => void;
 };
 
 export const DetailsPanel: React.FC<Props> = ({
@@ -30,12 +68,28 @@ export const DetailsPanel: React.FC<Props> = ({
     activeTab,
     onTabChange,
     trackerStats,
+    status,
+    labels,
+    categories = [],
+    onLabelsChange,
+    onTogglePause,
 }) => {
     const [copied, setCopied] = useState(false);
+    const [magnetCopied, setMagnetCopied] = useState(false);
+    const [editingTrackers, setEditingTrackers] = useState(false);
+    const [editingLimits, setEditingLimits] = useState(false);
+    const [editingOptions, setEditingOptions] = useState(false);
+    const [tagText, setTagText] = useState('');
+    const [moveFiles, setMoveFiles] = useState(false);
+    const [categoryError, setCategoryError] = useState('');
+    const [filesError, setFilesError] = useState('');
+    const [actionError, setActionError] = useState('');
+    const [trackerError, setTrackerError] = useState('');
+    const [rechecking, setRechecking] = useState(false);
     const id = infoHashHex(t);
     const name = t.metainfo?.info?.name || 'Unnamed torrent';
     const sizeStr = formatBytes(t.metainfo?.size || 0);
-    const pieces = t.metainfo?.info?.pieces?.length || 0;
+    const pieces = t.metainfo?.info?.numPieces || 0;
     const pieceLenStr = formatBytes(t.metainfo?.info?.pieceLength || 0);
     const created = t.metainfo?.creationDate
         ? new Date(t.metainfo.creationDate as any).toLocaleString()
@@ -44,6 +98,95 @@ export const DetailsPanel: React.FC<Props> = ({
     const trackers = t.metainfo?.announceUrls?.length || 0;
     const files = t.metainfo?.info?.files?.length || 0;
     const isPrivate = !!t.metainfo?.info?.private;
+    const hash = t.metainfo?.info?.infoHash as number[];
+    const { details, reload: reloadDetails } = useTorrentDetails(hash);
+    const fileProgress: Record<string, number> = {};
+    const filePriority: Record<string, string> = {};
+    const fileIndex: Record<string, number> = {};
+    for (const f of details?.files || []) {
+        const key = f.path.join('/');
+        fileProgress[key] = f.progress;
+        filePriority[key] = f.priority;
+        fileIndex[key] = f.index;
+    }
+    const summary = details?.pieces;
+    const pieceMap = usePieceMap(hash, id);
+    const [speedRange, setSpeedRange] = useState<SpeedRange>('recent');
+    const speeds = useSpeedHistory(hash, speedRange);
+    const failing = (details?.trackers || []).filter((tr) => tr.error);
+
+    useEffect(() => {
+        setTagText((labels?.tags || []).join(', '));
+    }, [id, labels]);
+
+    useEffect(() => {
+        setCategoryError('');
+        setFilesError('');
+        setActionError('');
+        setTrackerError('');
+    }, [id]);
+
+    const changeCategory = (category: string) => {
+        setCategoryError('');
+        SetCategory(hash, category, moveFiles)
+            .then(onLabelsChange)
+            .catch((e) => setCategoryError(String(e)));
+    };
+
+    // Priority changes reach the picker at once; reload to show them.
+    const changePriority = (path: string[], priority: string) => {
+        setFilesError('');
+        SetFilePriority(hash, fileIndex[path.join('/')], priority)
+            .then(reloadDetails)
+            .catch((e) => setFilesError(String(e)));
+    };
+
+    const changeWanted = (paths: string[][], wanted: boolean) => {
+        setFilesError('');
+        const indices = paths.map((p) => fileIndex[p.join('/')]);
+        SetFilesWanted(hash, indices, wanted)
+            .then(reloadDetails)
+            .catch((e) => setFilesError(String(e)));
+    };
+
+    const openFile = (path: string[]) => {
+        setFilesError('');
+        OpenTorrentFile(hash, fileIndex[path.join('/')]).catch((e) =>
+            setFilesError(String(e))
+        );
+    };
+
+    // Announces fail at once if the torrent is not running.
+    const reannounce = (url?: string) => {
+        setTrackerError('');
+        (url ? ReannounceTracker(hash, url) : ForceReannounce(hash)).catch(
+            (e) => setTrackerError(String(e))
+        );
+    };
+
+    const recheck = () => {
+        setActionError('');
+        setRechecking(true);
+        ForceRecheck(hash)
+            .then(reloadDetails)
+            .catch((e) => setActionError(String(e)))
+            .finally(() => setRechecking(false));
+    };
+
+    const saveTags = () => {
+        const tags = tagText
+            .split(',')
+            .map((s) => s.trim())
+            .filter(Boolean);
+        SetTags(hash, tags)
+            .then(onLabelsChange)
+            .catch(() => {});
+    };
+
+    // The new position arrives with the next status snapshot.
+    const queuePos = status?.queuePosition;
+    const moveInQueue = (move: (hash: number[]) => Promise<void>) =>
+        move(hash).catch(() => {});
 
     return (
         <div className="card ui-card" style={{ marginTop: 12 }}>
@@ -123,9 +266,330 @@ export const DetailsPanel: React.FC<Props> = ({
                                 </Button>
                             </div>
                             {/* Removed separate toast; the button label reflects Copied state */}
+                            <div className="id-row">
+                                <Button
+                                    variant="ghost"
+                                    className="btn-copy"
+                                    title="Copy magnet link"
+                                    aria-label={
+                                        magnetCopied
+                                            ? 'Copied'
+                                            : 'Copy magnet link'
+                                    }
+                                    onClick={() => {
+                                        const hash = t.metainfo?.info
+                                            ?.infoHash as number[];
+                                        MagnetURI(hash)
+                                            .then((uri) =>
+                                                navigator.clipboard.writeText(
+                                                    uri
+                                                )
+                                            )
+                                            .then(() => {
+                                                setMagnetCopied(true);
+                                                setTimeout(
+                                                    () =>
+                                                        setMagnetCopied(false),
+                                                    1500
+                                                );
+                                            })
+                                            .catch(() => {});
+                                    }}
+                                >
+                                    {magnetCopied
+                                        ? 'Copied'
+                                        : 'Copy magnet link'}
+                                </Button>
+                                <Button
+                                    variant="ghost"
+                                    className="btn-copy"
+                                    title="Save a .torrent file"
+                                    onClick={() => {
+                                        const hash = t.metainfo?.info
+                                            ?.infoHash as number[];
+                                        ExportTorrent(hash).catch(() => {});
+                                    }}
+                                >
+                                    Export .torrent
+                                </Button>
+                                <Button
+                                    variant="ghost"
+                                    className="btn-copy"
+                                    title="Open the file manager at the torrent"
+                                    onClick={() => {
+                                        setActionError('');
+                                        RevealTorrent(hash).catch((e) =>
+                                            setActionError(String(e))
+                                        );
+                                    }}
+                                >
+                                    Show in folder
+                                </Button>
+                                <Button
+                                    variant="ghost"
+                                    className="btn-copy"
+                                    title="Verify the data on disk again"
+                                    loading={rechecking}
+                                    onClick={recheck}
+                                >
+                                    Force recheck
+                                </Button>
+                                {onTogglePause && (
+                                    <Button
+                                        variant="ghost"
+                                        className="btn-copy"
+                                        onClick={onTogglePause}
+                                    >
+                                        {isActive(status?.state)
+                                            ? 'Pause'
+                                            : 'Resume'}
+                                    </Button>
+                                )}
+                                <Button
+                                    variant="ghost"
+                                    className="btn-copy"
+                                    title="Stop seeding at a ratio or time"
+                                    onClick={() => setEditingLimits(true)}
+                                >
+                                    Share limits
+                                </Button>
+                                <ShareLimitsEditor
+                                    open={editingLimits}
+                                    onOpenChange={setEditingLimits}
+                                    infoHash={hash}
+                                />
+                                <Button
+                                    variant="ghost"
+                                    className="btn-copy"
+                                    title="Limits and options for this torrent"
+                                    onClick={() => setEditingOptions(true)}
+                                >
+                                    Options
+                                </Button>
+                                <TorrentOptionsEditor
+                                    open={editingOptions}
+                                    onOpenChange={setEditingOptions}
+                                    infoHash={hash}
+                                />
+                            </div>
+                            {actionError && (
+                                <div
+                                    role="alert"
+                                    style={{ marginTop: 4, color: '#ff6b6b' }}
+                                >
+                                    {actionError}
+                                </div>
+                            )}
                         </div>
 
                         <div className="kv-grid">
+                            {status && (
+                                <div className="kv">
+                                    <div className="label">State</div>
+                                    <div
+                                        className="value"
+                                        title={status.error}
+                                    >
+                                        {status.state}
+                                    </div>
+                                </div>
+                            )}
+                            {status?.error && (
+                                <div className="kv">
+                                    <div className="label">Error</div>
+                                    <div
+                                        className="value"
+                                        style={{ color: '#ff6b6b' }}
+                                    >
+                                        {status.error}
+                                        {status.retryIn
+                                            ? ` (retrying in ${formatETA(
+                                                  Math.max(1e9, status.retryIn)
+                                              )})`
+                                            : ''}
+                                    </div>
+                                </div>
+                            )}
+                            {status && (
+                                <div className="kv">
+                                    <div className="label">Speed</div>
+                                    <div className="value">
+                                        ↓ {formatRate(status.downloadRate)} ↑{' '}
+                                        {formatRate(status.uploadRate)}
+                                    </div>
+                                </div>
+                            )}
+                            {status && (
+                                <div className="kv">
+                                    <div className="label">Limits</div>
+                                    <div className="value">
+                                        ↓ {formatLimit(status.downloadLimit)} ↑{' '}
+                                        {formatLimit(status.uploadLimit)}
+                                    </div>
+                                </div>
+                            )}
+                            {status && (
+                                <div className="kv">
+                                    <div className="label">Progress</div>
+                                    <div className="value">
+                                        {(status.progress ?? 0).toFixed(1)}% •{' '}
+                                        {status.peers ?? 0} peers (
+                                        {status.seeds ?? 0} seeds) • ratio{' '}
+                                        {(status.ratio ?? 0).toFixed(2)}
+                                    </div>
+                                </div>
+                            )}
+                            {summary && (
+                                <div className="kv">
+                                    <div className="label">Availability</div>
+                                    <div className="value">
+                                        {summary.have}/{summary.count} pieces
+                                        • {summary.availability.toFixed(2)}{' '}
+                                        copies among peers
+                                        {summary.unavailable > 0 &&
+                                            ` • ${summary.unavailable} missing`}
+                                    </div>
+                                </div>
+                            )}
+                            <div className="kv">
+                                <div className="label">Speed graph</div>
+                                <div className="value">
+                                    <select
+                                        className="ui-input"
+                                        aria-label="Speed graph range"
+                                        value={speedRange}
+                                        onChange={(e) =>
+                                            setSpeedRange(
+                                                e.target.value as SpeedRange
+                                            )
+                                        }
+                                    >
+                                        <option value="recent">
+                                            Last 10 minutes
+                                        </option>
+                                        <option value="day">
+                                            Last 24 hours
+                                        </option>
+                                    </select>
+                                    <SpeedGraph samples={speeds} />
+                                </div>
+                            </div>
+                            {pieceMap && (
+                                <div className="kv">
+                                    <div className="label">Pieces</div>
+                                    <div className="value">
+                                        <PieceBar map={pieceMap} />
+                                    </div>
+                                </div>
+                            )}
+                            {status && (
+                                <div className="kv">
+                                    <div className="label">ETA</div>
+                                    <div className="value">
+                                        {formatETA(status.eta)}
+                                    </div>
+                                </div>
+                            )}
+                            <div className="kv">
+                                <div className="label">Category</div>
+                                <div className="value ui-stack">
+                                    <select
+                                        className="ui-input"
+                                        aria-label="Category"
+                                        value={labels?.category || ''}
+                                        onChange={(e) =>
+                                            changeCategory(e.target.value)
+                                        }
+                                    >
+                                        <option value="">None</option>
+                                        {categories.map((c) => (
+                                            <option key={c.name} value={c.name}>
+                                                {c.name}
+                                            </option>
+                                        ))}
+                                    </select>
+                                    <label>
+                                        <input
+                                            type="checkbox"
+                                            checked={moveFiles}
+                                            onChange={(e) =>
+                                                setMoveFiles(e.target.checked)
+                                            }
+                                        />{' '}
+                                        Move files
+                                    </label>
+                                </div>
+                            </div>
+                            {categoryError && (
+                                <div
+                                    role="alert"
+                                    style={{ marginTop: 4, color: '#ff6b6b' }}
+                                >
+                                    {categoryError}
+                                </div>
+                            )}
+                            <div className="kv">
+                                <div className="label">Tags</div>
+                                <input
+                                    className="ui-input value"
+                                    aria-label="Tags, comma separated"
+                                    placeholder="tag, another tag"
+                                    value={tagText}
+                                    onChange={(e) => setTagText(e.target.value)}
+                                    onBlur={saveTags}
+                                    onKeyDown={(e) => {
+                                        if (e.key === 'Enter') saveTags();
+                                    }}
+                                />
+                            </div>
+                            {queuePos !== undefined && (
+                                <div className="kv">
+                                    <div className="label">Queue</div>
+                                    <div className="value ui-stack">
+                                        #{queuePos + 1}
+                                        <Button
+                                            variant="ghost"
+                                            className="btn-copy"
+                                            title="Move to the top"
+                                            disabled={queuePos === 0}
+                                            onClick={() =>
+                                                moveInQueue(MoveToTop)
+                                            }
+                                        >
+                                            Top
+                                        </Button>
+                                        <Button
+                                            variant="ghost"
+                                            className="btn-copy"
+                                            title="Move up in the queue"
+                                            disabled={queuePos === 0}
+                                            onClick={() => moveInQueue(MoveUp)}
+                                        >
+                                            Up
+                                        </Button>
+                                        <Button
+                                            variant="ghost"
+                                            className="btn-copy"
+                                            title="Move down in the queue"
+                                            onClick={() =>
+                                                moveInQueue(MoveDown)
+                                            }
+                                        >
+                                            Down
+                                        </Button>
+                                        <Button
+                                            variant="ghost"
+                                            className="btn-copy"
+                                            title="Move to the bottom"
+                                            onClick={() =>
+                                                moveInQueue(MoveToBottom)
+                                            }
+                                        >
+                                            Bottom
+                                        </Button>
+                                    </div>
+                                </div>
+                            )}
                             {created && (
                                 <div className="kv">
                                     <div className="label">Created</div>
@@ -163,26 +627,88 @@ export const DetailsPanel: React.FC<Props> = ({
                                     files={
                                         (t.metainfo?.info?.files as any) || []
                                     }
+                                    progress={fileProgress}
+                                    priorities={filePriority}
+                                    onPriorityChange={changePriority}
+                                    onWantedChange={changeWanted}
+                                    onOpen={openFile}
                                 />
+                                {filesError && (
+                                    <div
+                                        role="alert"
+                                        style={{
+                                            marginTop: 4,
+                                            color: '#ff6b6b',
+                                        }}
+                                    >
+                                        {filesError}
+                                    </div>
+                                )}
                             </div>
                         )}
                     </div>
                 </Tabs.Panel>
 
                 <Tabs.Panel value="trackers">
+                    <div className="id-row">
+                        <Button
+                            variant="ghost"
+                            onClick={() => setEditingTrackers(true)}
+                        >
+                            Edit trackers &amp; save .torrent
+                        </Button>
+                        <Button
+                            variant="ghost"
+                            title="Announce to every tracker now"
+                            disabled={!isActive(status?.state)}
+                            onClick={() => reannounce()}
+                        >
+                            Reannounce all
+                        </Button>
+                    </div>
+                    {trackerError && (
+                        <div
+                            role="alert"
+                            style={{ marginTop: 4, color: '#ff6b6b' }}
+                        >
+                            {trackerError}
+                        </div>
+                    )}
+                    <TrackerEditor
+                        open={editingTrackers}
+                        onOpenChange={setEditingTrackers}
+                        infoHash={t.metainfo?.info?.infoHash as number[]}
+                        urls={t.metainfo?.announceUrls || []}
+                    />
                     {(t.metainfo?.announceUrls?.length || 0) > 0 && (
                         <div className="section-block">
                             <TrackersList
                                 urls={t.metainfo?.announceUrls || []}
                                 stats={trackerStats || {}}
+                                onReannounce={
+                                    isActive(status?.state)
+                                        ? reannounce
+                                        : undefined
+                                }
                             />
                         </div>
                     )}
+                    {failing.map((tr) => (
+                        <div
+                            key={tr.url}
+                            role="alert"
+                            className="wrap"
+                            style={{ marginTop: 4, color: '#ff6b6b' }}
+                        >
+                            {tr.url}: {tr.error}
+                        </div>
+                    ))}
                 </Tabs.Panel>
                 <Tabs.Panel value="peers">
                     <div className="section-block">
-                        <PeersList />
+                        <PeersList infoHash={id} />
                     </div>
+                    <CountryBreakdown infoHash={hash} hex={id} />
                 </Tabs.Panel>
             </Tabs.Root>
         </div>
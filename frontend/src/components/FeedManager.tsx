@@ -0,0 +1,223 @@
+import React, { useEffect, useState } from 'react';
+import Button from './primitives/Button';
+import Input from './primitives/Input';
+import Modal from './primitives/Modal';
+import { Feeds, SetFeeds } from '../../wailsjs/go/ui/UI';
+import { echo, rss } from '../../wailsjs/go/models';
+
+type Props = {
+    open: boolean;
+    onOpenChange: (open: boolean) => void;
+    categories: echo.Category[];
+};
+
+const minute = 60 * 1e9;
+
+export const FeedManager: React.FC<Props> = ({
+    open,
+    onOpenChange,
+    categories,
+}) => {
+    const [feeds, setFeeds] = useState<echo.Feed[]>([]);
+    const [url, setUrl] = useState('');
+    const [intervalMins, setIntervalMins] = useState('');
+    const [name, setName] = useState('');
+    const [match, setMatch] = useState('');
+    const [exclude, setExclude] = useState('');
+    const [episodes, setEpisodes] = useState('');
+    const [category, setCategory] = useState('');
+    const [error, setError] = useState('');
+
+    useEffect(() => {
+        if (!open) return;
+        setError('');
+        Feeds()
+            .then((f) => setFeeds(f || []))
+            .catch((e) => setError(String(e)));
+    }, [open]);
+
+    const save = (next: echo.Feed[]) => {
+        setError('');
+        SetFeeds(next)
+            .then(() => setFeeds(next))
+            .catch((e) => setError(String(e)));
+    };
+
+    const withRules = (f: echo.Feed, rules: rss.Rule[]) =>
+        echo.Feed.createFrom({ ...f, rules });
+
+    // Adds the rule to the feed at url, subscribing to the feed first if
+    // need be.
+    const add = (e: React.FormEvent) => {
+        e.preventDefault();
+        const u = url.trim();
+        if (!u) return;
+        const rule = rss.Rule.createFrom({
+            name: name.trim() || match.trim() || 'Everything',
+            match: match.trim(),
+            exclude: exclude.trim(),
+            episodes: episodes.trim(),
+            category,
+            disabled: false,
+        });
+        const mins = parseFloat(intervalMins);
+        const existing = feeds.find((f) => f.url === u);
+        const feed = echo.Feed.createFrom({
+            url: u,
+            interval: mins > 0 ? mins * minute : existing?.interval || 0,
+            rules: [...(existing?.rules || []), rule],
+        });
+        save(
+            existing
+                ? feeds.map((f) => (f.url === u ? feed : f))
+                : [...feeds, feed]
+        );
+        setName('');
+        setMatch('');
+        setExclude('');
+        setEpisodes('');
+    };
+
+    return (
+        <Modal open={open} onOpenChange={onOpenChange} title="RSS feeds">
+            <div className="muted" style={{ marginBottom: 8 }}>
+                Items matching a feed's rules are added automatically, once
+                each.
+            </div>
+            {feeds.map((f) => (
+                <div key={f.url} style={{ marginBottom: 8 }}>
+                    <div
+                        className="ui-stack"
+                        style={{ justifyContent: 'space-between' }}
+                    >
+                        <div>
+                            <div className="mono">{f.url}</div>
+                            <div className="muted">
+                                {f.interval > 0
+                                    ? `every ${Math.round(f.interval / minute)} min`
+                                    : 'default interval'}
+                            </div>
+                        </div>
+                        <Button
+                            variant="ghost"
+                            onClick={() =>
+                                save(feeds.filter((x) => x.url !== f.url))
+                            }
+                        >
+                            Unsubscribe
+                        </Button>
+                    </div>
+                    {(f.rules || []).map((r, i) => (
+                        <div
+                            key={i}
+                            className="ui-stack"
+                            style={{
+                                justifyContent: 'space-between',
+                                paddingLeft: 12,
+                            }}
+                        >
+                            <div className="muted">
+                                {r.name}
+                                {r.match && ` • /${r.match}/`}
+                                {r.exclude && ` • not /${r.exclude}/`}
+                                {r.episodes && ` • ${r.episodes}`}
+                                {r.category && ` • ${r.category}`}
+                            </div>
+                            <Button
+                                variant="ghost"
+                                onClick={() =>
+                                    save(
+                                        feeds.map((x) =>
+                                            x.url === f.url
+                                                ? withRules(
+                                                      x,
+                                                      x.rules.filter(
+                                                          (_, j) => j !== i
+                                                      )
+                                                  )
+                                                : x
+                                        )
+                                    )
+                                }
+                            >
+                                Remove
+                            </Button>
+                        </div>
+                    ))}
+                </div>
+            ))}
+            <form onSubmit={add} style={{ marginTop: 12 }}>
+                <Input
+                    label="Feed URL"
+                    value={url}
+                    onChange={(e) => setUrl(e.target.value)}
+                />
+                <Input
+                    label="Interval (minutes, blank for default)"
+                    type="number"
+                    min={1}
+                    value={intervalMins}
+                    onChange={(e) => setIntervalMins(e.target.value)}
+                />
+                <Input
+                    label="Rule name"
+                    value={name}
+                    onChange={(e) => setName(e.target.value)}
+                />
+                <Input
+                    label="Title must match (regex)"
+                    value={match}
+                    onChange={(e) => setMatch(e.target.value)}
+                />
+                <Input
+                    label="Title must not match (regex)"
+                    value={exclude}
+                    onChange={(e) => setExclude(e.target.value)}
+                />
+                <Input
+                    label="Episodes, e.g. 1x5-; 2x"
+                    value={episodes}
+                    onChange={(e) => setEpisodes(e.target.value)}
+                />
+                <label
+                    className="label"
+                    htmlFor="feed-category"
+                    style={{ display: 'block', margin: '8px 0 6px' }}
+                >
+                    Category
+                </label>
+                <select
+                    id="feed-category"
+                    className="ui-input"
+                    value={category}
+                    onChange={(e) => setCategory(e.target.value)}
+                >
+                    <option value="">None</option>
+                    {categories.map((c) => (
+                        <option key={c.name} value={c.name}>
+                            {c.name}
+                        </option>
+                    ))}
+                </select>
+                {error && (
+                    <div
+                        role="alert"
+                        style={{ marginTop: 4, color: '#ff6b6b' }}
+                    >
+                        {error}
+                    </div>
+                )}
+                <div
+                    className="ui-stack"
+                    style={{ justifyContent: 'flex-end', marginTop: 12 }}
+                >
+                    <Button type="submit" variant="primary">
+                        Add rule
+                    </Button>
+                </div>
+            </form>
+        </Modal>
+    );
+};
+
+export default FeedManager;
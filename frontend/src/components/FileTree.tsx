@@ -1,4 +1,5 @@
 import React from 'react';
+import Button from './primitives/Button';
 
 export type FileEntry = { length: number; path: string[] };
 
@@ -48,9 +49,35 @@ function formatSize(bytes: number): string {
     return bytes + ' B';
 }
 
-type Props = { files: FileEntry[] };
+type Props = {
+    files: FileEntry[];
+    // Percent complete, keyed by the file's path joined with '/'.
+    progress?: Record<string, number>;
+    // Download priority, keyed the same way; 'skip' leaves a file out.
+    priorities?: Record<string, string>;
+    onPriorityChange?: (path: string[], priority: string) => void;
+    // Adds every file below a folder to the download or leaves them out.
+    onWantedChange?: (paths: string[][], wanted: boolean) => void;
+    // Launches a completed file with its default application.
+    onOpen?: (path: string[]) => void;
+};
+
+const PRIORITIES = ['high', 'normal', 'low', 'skip'];
+
+// filesBelow lists the paths of every file in a directory's subtree.
+function filesBelow(n: Node): string[][] {
+    if (!n.children) return n.path ? [n.path] : [];
+    return Object.values(n.children).flatMap(filesBelow);
+}
 
-export const FileTree: React.FC<Props> = ({ files }) => {
+export const FileTree: React.FC<Props> = ({
+    files,
+    progress,
+    priorities,
+    onPriorityChange,
+    onWantedChange,
+    onOpen,
+}) => {
     const tree = React.useMemo(() => buildTree(files), [files]);
     const [expanded, setExpanded] = React.useState<Set<string>>(
         () => new Set<string>()
@@ -72,16 +99,51 @@ export const FileTree: React.FC<Props> = ({ files }) => {
     ): React.ReactNode => {
         if (!node.children) {
             // file
+            const key = node.path!.join('/');
+            const pct = progress?.[key];
+            const priority = priorities?.[key];
             return (
                 <li
                     className="filetree-file"
-                    key={node.path!.join('/')}
+                    key={key}
                     style={{ paddingLeft: depth * 14 }}
                 >
-                    <span className="wrap">{node.name}</span>
+                    <span
+                        className={`wrap ${priority === 'skip' ? 'muted' : ''}`}
+                    >
+                        {node.name}
+                    </span>
                     <span className="muted">
                         {formatSize(node.length || 0)}
+                        {pct !== undefined && ` • ${pct.toFixed(1)}%`}
                     </span>
+                    {priority && onPriorityChange && (
+                        <select
+                            className="ui-input control"
+                            aria-label={`Priority of ${node.name}`}
+                            value={priority}
+                            onChange={(e) =>
+                                onPriorityChange(node.path!, e.target.value)
+                            }
+                        >
+                            {PRIORITIES.map((p) => (
+                                <option key={p} value={p}>
+                                    {p}
+                                </option>
+                            ))}
+                        </select>
+                    )}
+                    {onOpen && pct === 100 && (
+                        <Button
+                            type="button"
+                            variant="ghost"
+                            size="sm"
+                            aria-label={`Open ${node.name}`}
+                            onClick={() => onOpen(node.path!)}
+                        >
+                            Open
+                        </Button>
+                    )}
                 </li>
             );
         }
@@ -100,6 +162,10 @@ export const FileTree: React.FC<Props> = ({ files }) => {
             }
             const key = basePath.concat(child.name).join('/');
             const isOpen = expanded.has(key);
+            const below = filesBelow(child);
+            const wanted = below.some(
+                (p) => priorities?.[p.join('/')] !== 'skip'
+            );
             return (
                 <li className="filetree-dir" key={`dir-${key}`}>
                     <div
@@ -115,6 +181,16 @@ export const FileTree: React.FC<Props> = ({ files }) => {
                         >
                             {isOpen ? '▾' : '▸'}
                         </button>
+                        {priorities && onWantedChange && (
+                            <input
+                                type="checkbox"
+                                aria-label={`Download ${child.name}`}
+                                checked={wanted}
+                                onChange={(e) =>
+                                    onWantedChange(below, e.target.checked)
+                                }
+                            />
+                        )}
                         <span className="wrap">{child.name}</span>
                         <span className="muted" style={{ marginLeft: 8 }}>
                             {formatSize(child.total || 0)}
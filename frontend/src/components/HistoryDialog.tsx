@@ -0,0 +1,98 @@
+import React, { useEffect, useState } from 'react';
+import Button from './primitives/Button';
+import Modal from './primitives/Modal';
+import { ClearHistory, History } from '../../wailsjs/go/ui/UI';
+import { EventsOn } from '../../wailsjs/runtime';
+import { echo } from '../../wailsjs/go/models';
+import { formatBytes } from '../utils/torrent';
+
+type Props = {
+    open: boolean;
+    onOpenChange: (open: boolean) => void;
+};
+
+export const HistoryDialog: React.FC<Props> = ({ open, onOpenChange }) => {
+    const [entries, setEntries] = useState<echo.HistoryEntry[]>([]);
+    const [query, setQuery] = useState('');
+    const [error, setError] = useState('');
+
+    useEffect(() => {
+        if (!open) return;
+        setError('');
+        History()
+            .then((h) => setEntries(h || []))
+            .catch((e) => setError(String(e)));
+        const off = EventsOn('history:added', (payload: any) => {
+            if (payload)
+                setEntries((prev) => [payload as echo.HistoryEntry, ...prev]);
+        });
+        return () => {
+            if (typeof off === 'function') off();
+        };
+    }, [open]);
+
+    const clear = () => {
+        setError('');
+        ClearHistory()
+            .then(() => setEntries([]))
+            .catch((e) => setError(String(e)));
+    };
+
+    const q = query.trim().toLowerCase();
+    const shown = q
+        ? entries.filter((e) => e.name.toLowerCase().includes(q))
+        : entries;
+
+    return (
+        <Modal open={open} onOpenChange={onOpenChange} title="History">
+            <input
+                className="ui-input"
+                placeholder="Search completed downloads"
+                value={query}
+                onChange={(e) => setQuery(e.target.value)}
+                style={{ width: '100%', marginBottom: 8 }}
+            />
+            {shown.length === 0 && (
+                <div className="muted">Nothing has finished downloading.</div>
+            )}
+            <div style={{ maxHeight: 360, overflowY: 'auto' }}>
+                {shown.map((e) => (
+                    <div
+                        key={`${e.infoHash}-${e.completed}`}
+                        style={{ marginBottom: 8 }}
+                    >
+                        <div>{e.name}</div>
+                        <div className="muted">
+                            {formatBytes(e.size)} •{' '}
+                            {new Date(e.completed).toLocaleString()} • ratio{' '}
+                            {e.ratio.toFixed(2)}
+                            {e.category ? ` • ${e.category}` : ''}
+                        </div>
+                    </div>
+                ))}
+            </div>
+            {error && (
+                <div role="alert" style={{ marginTop: 4, color: '#ff6b6b' }}>
+                    {error}
+                </div>
+            )}
+            <div
+                className="ui-stack"
+                style={{ justifyContent: 'flex-end', marginTop: 12 }}
+            >
+                <Button
+                    variant="ghost"
+                    disabled={entries.length === 0}
+                    onClick={clear}
+                >
+                    Clear history
+                </Button>
+                <Button variant="primary" onClick={() => onOpenChange(false)}>
+                    Close
+                </Button>
+            </div>
+        </Modal>
+    );
+};
+
+export default HistoryDialog;
@@ -0,0 +1,101 @@
+import React, { useEffect, useState } from 'react';
+import Button from './primitives/Button';
+import Modal from './primitives/Modal';
+import { Import } from '../../wailsjs/go/ui/UI';
+import { echo } from '../../wailsjs/go/models';
+
+type Props = {
+    open: boolean;
+    onOpenChange: (open: boolean) => void;
+    onImported: () => void;
+};
+
+export const ImportDialog: React.FC<Props> = ({
+    open,
+    onOpenChange,
+    onImported,
+}) => {
+    const [source, setSource] = useState('qbittorrent');
+    const [result, setResult] = useState<echo.ImportResult | null>(null);
+    const [error, setError] = useState('');
+    const [importing, setImporting] = useState(false);
+
+    useEffect(() => {
+        if (!open) return;
+        setResult(null);
+        setError('');
+    }, [open]);
+
+    const run = () => {
+        setImporting(true);
+        setError('');
+        Import(source)
+            .then((res) => {
+                if (!res.added && !res.skipped && !res.errors?.length) return;
+                setResult(res);
+                onImported();
+            })
+            .catch((e) => setError(String(e)))
+            .finally(() => setImporting(false));
+    };
+
+    return (
+        <Modal
+            open={open}
+            onOpenChange={onOpenChange}
+            title="Import from another client"
+        >
+            <div className="muted" style={{ marginBottom: 8 }}>
+                Torrents keep their save paths, trackers and labels. Their
+                files are checked before they start.
+            </div>
+            <label
+                className="label"
+                htmlFor="import-source"
+                style={{ display: 'block', marginBottom: 6 }}
+            >
+                Client
+            </label>
+            <select
+                id="import-source"
+                className="ui-input"
+                value={source}
+                onChange={(e) => setSource(e.target.value)}
+            >
+                <option value="qbittorrent">qBittorrent (BT_backup)</option>
+                <option value="transmission">Transmission</option>
+            </select>
+            {result && (
+                <div style={{ marginTop: 8 }}>
+                    <div>
+                        Imported {result.added}, skipped {result.skipped}{' '}
+                        already added.
+                    </div>
+                    {(result.errors || []).map((e) => (
+                        <div key={e} className="muted mono">
+                            {e}
+                        </div>
+                    ))}
+                </div>
+            )}
+            {error && (
+                <div role="alert" style={{ marginTop: 4, color: '#ff6b6b' }}>
+                    {error}
+                </div>
+            )}
+            <div
+                className="ui-stack"
+                style={{ justifyContent: 'flex-end', marginTop: 12 }}
+            >
+                <Button variant="ghost" onClick={() => onOpenChange(false)}>
+                    Close
+                </Button>
+                <Button variant="primary" loading={importing} onClick={run}>
+                    Choose folder…
+                </Button>
+            </div>
+        </Modal>
+    );
+};
+
+export default ImportDialog;
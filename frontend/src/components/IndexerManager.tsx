@@ -0,0 +1,247 @@
+import React, { useEffect, useState } from 'react';
+import Button from './primitives/Button';
+import Input from './primitives/Input';
+import Modal from './primitives/Modal';
+import {
+    IndexerHealth,
+    Indexers,
+    SetIndexerEnabled,
+    SetIndexers,
+    TestIndexer,
+} from '../../wailsjs/go/ui/UI';
+import { echo, search } from '../../wailsjs/go/models';
+
+// Latencies are Go durations, in nanoseconds.
+const ms = (ns: number) => `${Math.round(ns / 1e6)} ms`;
+
+const healthLine = (h?: search.Health) => {
+    if (!h || !h.searches) return 'Not searched yet';
+    const line = `${h.searches} searches, ${h.failures} failed, ${ms(
+        h.latency
+    )} average`;
+    return h.lastError ? `${line} • last error: ${h.lastError}` : line;
+};
+
+type Props = {
+    open: boolean;
+    onOpenChange: (open: boolean) => void;
+};
+
+export const IndexerManager: React.FC<Props> = ({ open, onOpenChange }) => {
+    const [indexers, setIndexers] = useState<echo.Indexer[]>([]);
+    const [name, setName] = useState('');
+    const [kind, setKind] = useState('torznab');
+    const [url, setUrl] = useState('');
+    const [apiKey, setApiKey] = useState('');
+    const [health, setHealth] = useState<Record<string, search.Health>>({});
+    // Test outcomes by indexer name; '' is the indexer being added.
+    const [tests, setTests] = useState<Record<string, string>>({});
+    const [error, setError] = useState('');
+
+    useEffect(() => {
+        if (!open) return;
+        setError('');
+        setTests({});
+        Indexers()
+            .then((list) => setIndexers(list || []))
+            .catch((e) => setError(String(e)));
+        IndexerHealth()
+            .then((list) =>
+                setHealth(
+                    Object.fromEntries((list || []).map((h) => [h.name, h]))
+                )
+            )
+            .catch(() => {});
+    }, [open]);
+
+    const test = (key: string, indexer: echo.Indexer) => {
+        setTests((prev) => ({ ...prev, [key]: 'Testing…' }));
+        TestIndexer(indexer)
+            .then((ns) => `Working, answered in ${ms(ns)}`)
+            .catch((e) => `Failed: ${e}`)
+            .then((result) =>
+                setTests((prev) => ({ ...prev, [key]: result }))
+            );
+    };
+
+    const setEnabled = (name: string, enabled: boolean) => {
+        setError('');
+        SetIndexerEnabled(name, enabled)
+            .then(() =>
+                setIndexers((prev) =>
+                    prev.map((i) =>
+                        i.name === name
+                            ? echo.Indexer.createFrom({
+                                  ...i,
+                                  disabled: !enabled,
+                              })
+                            : i
+                    )
+                )
+            )
+            .catch((e) => setError(String(e)));
+    };
+
+    const draft = () =>
+        echo.Indexer.createFrom({
+            name: name.trim(),
+            kind,
+            url: url.trim(),
+            apiKey: kind === 'rss' ? '' : apiKey.trim(),
+        });
+
+    const save = (next: echo.Indexer[]) => {
+        setError('');
+        return SetIndexers(next)
+            .then(() => setIndexers(next))
+            .catch((e) => {
+                setError(String(e));
+                throw e;
+            });
+    };
+
+    const add = (e: React.FormEvent) => {
+        e.preventDefault();
+        if (!name.trim() || !url.trim()) return;
+        const indexer = draft();
+        save([...indexers.filter((i) => i.name !== indexer.name), indexer])
+            .then(() => {
+                setName('');
+                setUrl('');
+                setApiKey('');
+            })
+            .catch(() => {});
+    };
+
+    return (
+        <Modal open={open} onOpenChange={onOpenChange} title="Search indexers">
+            <div className="muted" style={{ marginBottom: 8 }}>
+                Torznab or Newznab endpoints, such as those of Jackett or
+                Prowlarr, and torrent RSS feeds, searched for torrents.
+            </div>
+            {indexers.map((i) => (
+                <div
+                    key={i.name}
+                    className="ui-stack"
+                    style={{ justifyContent: 'space-between' }}
+                >
+                    <div>
+                        <div>
+                            {i.name}
+                            {i.disabled && (
+                                <span className="muted"> (disabled)</span>
+                            )}
+                        </div>
+                        <div className="muted mono">
+                            {i.kind === 'rss' ? 'RSS: ' : ''}
+                            {i.url}
+                        </div>
+                        <div className="muted">
+                            {tests[i.name] || healthLine(health[i.name])}
+                        </div>
+                    </div>
+                    <div className="ui-stack">
+                        <label className="label">
+                            <input
+                                type="checkbox"
+                                checked={!i.disabled}
+                                onChange={(e) =>
+                                    setEnabled(i.name, e.target.checked)
+                                }
+                            />{' '}
+                            Enabled
+                        </label>
+                        <Button variant="ghost" onClick={() => test(i.name, i)}>
+                            Test
+                        </Button>
+                        <Button
+                            variant="ghost"
+                            onClick={() =>
+                                save(
+                                    indexers.filter((x) => x.name !== i.name)
+                                ).catch(() => {})
+                            }
+                        >
+                            Remove
+                        </Button>
+                    </div>
+                </div>
+            ))}
+            <form onSubmit={add} style={{ marginTop: 12 }}>
+                <Input
+                    label="Name"
+                    value={name}
+                    onChange={(e) => setName(e.target.value)}
+                />
+                <label
+                    className="label"
+                    htmlFor="indexer-kind"
+                    style={{ display: 'block', margin: '8px 0 6px' }}
+                >
+                    Kind
+                </label>
+                <select
+                    id="indexer-kind"
+                    className="ui-input"
+                    value={kind}
+                    onChange={(e) => setKind(e.target.value)}
+                >
+                    <option value="torznab">Torznab / Newznab API</option>
+                    <option value="rss">RSS or Atom feed</option>
+                </select>
+                {kind === 'rss' ? (
+                    <Input
+                        label="Feed URL"
+                        value={url}
+                        onChange={(e) => setUrl(e.target.value)}
+                    />
+                ) : (
+                    <>
+                        <Input
+                            label="Torznab URL"
+                            placeholder="http://localhost:9117/api/v2.0/indexers/all/results/torznab"
+                            value={url}
+                            onChange={(e) => setUrl(e.target.value)}
+                        />
+                        <Input
+                            label="API key"
+                            type="password"
+                            value={apiKey}
+                            onChange={(e) => setApiKey(e.target.value)}
+                        />
+                    </>
+                )}
+                {tests[''] && (
+                    <div className="muted" style={{ marginTop: 4 }}>
+                        {tests['']}
+                    </div>
+                )}
+                {error && (
+                    <div
+                        role="alert"
+                        style={{ marginTop: 4, color: '#ff6b6b' }}
+                    >
+                        {error}
+                    </div>
+                )}
+                <div
+                    className="ui-stack"
+                    style={{ justifyContent: 'flex-end', marginTop: 12 }}
+                >
+                    <Button
+                        variant="ghost"
+                        disabled={!name.trim() || !url.trim()}
+                        onClick={() => test('', draft())}
+                    >
+                        Test
+                    </Button>
+                    <Button type="submit" variant="primary">
+                        Add indexer
+                    </Button>
+                </div>
+            </form>
+        </Modal>
+    );
+};
+
+export default IndexerManager;
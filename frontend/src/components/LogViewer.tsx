@@ -0,0 +1,204 @@
+import React, { useEffect, useState } from 'react';
+import Button from './primitives/Button';
+import Modal from './primitives/Modal';
+import {
+    GetSettings,
+    LogModules,
+    QueryLogs,
+    UpdateSettings,
+} from '../../wailsjs/go/ui/UI';
+import { EventsOn } from '../../wailsjs/runtime';
+import { echo, logging } from '../../wailsjs/go/models';
+
+type Props = {
+    open: boolean;
+    onOpenChange: (open: boolean) => void;
+};
+
+const levels = ['debug', 'info', 'warn', 'error'];
+
+// The packages that log, whose records can be quieted on their own.
+const modules = [
+    'echo',
+    'torrent',
+    'peer',
+    'tracker',
+    'dht',
+    'webseed',
+    'storage',
+    'ui',
+];
+const limit = 500;
+
+const levelColor: Record<string, string> = {
+    warn: '#f0c674',
+    error: '#ff6b6b',
+};
+
+const atLeast = (level: string, min: string) =>
+    levels.indexOf(level.toLowerCase()) >= levels.indexOf(min);
+
+// Mirrors logging.Query's matching for the entries that arrive live.
+const matches = (e: logging.Entry, module: string, text: string) => {
+    if (module && e.module !== module) return false;
+    const t = text.toLowerCase();
+    return (
+        !t ||
+        e.message.toLowerCase().includes(t) ||
+        Object.values(e.attrs || {}).some((v) => v.toLowerCase().includes(t))
+    );
+};
+
+export const LogViewer: React.FC<Props> = ({ open, onOpenChange }) => {
+    const [entries, setEntries] = useState<logging.Entry[]>([]);
+    const [level, setLevel] = useState('warn');
+    const [module, setModule] = useState('');
+    const [text, setText] = useState('');
+    const [seenModules, setSeenModules] = useState<string[]>([]);
+    const [error, setError] = useState('');
+    const [moduleLevels, setModuleLevels] = useState<Record<string, string>>(
+        {}
+    );
+    const [showModules, setShowModules] = useState(false);
+
+    useEffect(() => {
+        if (!open) return;
+        GetSettings()
+            .then((s) => setModuleLevels(s.logLevels || {}))
+            .catch((e) => setError(String(e)));
+    }, [open]);
+
+    // An empty level leaves the module to the overall level.
+    const setModuleLevel = (module: string, level: string) => {
+        const next = { ...moduleLevels };
+        if (level) next[module] = level;
+        else delete next[module];
+        UpdateSettings(echo.SettingsPatch.createFrom({ logLevels: next }))
+            .then((s) => setModuleLevels(s.logLevels || {}))
+            .catch((e) => setError(String(e)));
+    };
+
+    useEffect(() => {
+        if (!open) return;
+        setError('');
+        QueryLogs(level, module ? [module] : [], text.trim(), limit)
+            .then((l) => setEntries(l || []))
+            .catch((e) => setError(String(e)));
+        LogModules()
+            .then((m) => setSeenModules(m || []))
+            .catch((e) => setError(String(e)));
+        const off = EventsOn('logs:new', (payload: any) => {
+            const e = payload as logging.Entry;
+            if (!e || !atLeast(e.level, level)) return;
+            if (!matches(e, module, text.trim())) return;
+            setEntries((prev) => [...prev.slice(1 - limit), e]);
+        });
+        return () => {
+            if (typeof off === 'function') off();
+        };
+    }, [open, level, module, text]);
+
+    return (
+        <Modal open={open} onOpenChange={onOpenChange} title="Logs">
+            <select
+                className="ui-input"
+                aria-label="Level"
+                value={level}
+                onChange={(e) => setLevel(e.target.value)}
+                style={{ marginBottom: 8 }}
+            >
+                <option value="debug">Debug and above</option>
+                <option value="info">Info and above</option>
+                <option value="warn">Warnings and errors</option>
+                <option value="error">Errors</option>
+            </select>{' '}
+            <select
+                className="ui-input"
+                aria-label="Module"
+                value={module}
+                onChange={(e) => setModule(e.target.value)}
+            >
+                <option value="">All modules</option>
+                {seenModules.map((m) => (
+                    <option key={m} value={m}>
+                        {m}
+                    </option>
+                ))}
+            </select>{' '}
+            <input
+                className="ui-input"
+                placeholder="Filter"
+                value={text}
+                onChange={(e) => setText(e.target.value)}
+            />{' '}
+            <Button variant="ghost" onClick={() => setShowModules((v) => !v)}>
+                Module levels
+            </Button>
+            {showModules && (
+                <div className="ui-stack" style={{ flexWrap: 'wrap' }}>
+                    {modules.map((m) => (
+                        <label key={m} className="label">
+                            {m}{' '}
+                            <select
+                                className="ui-input"
+                                value={(moduleLevels[m] || '').toLowerCase()}
+                                onChange={(e) =>
+                                    setModuleLevel(m, e.target.value)
+                                }
+                            >
+                                <option value="">Default</option>
+                                {levels.map((l) => (
+                                    <option key={l} value={l}>
+                                        {l}
+                                    </option>
+                                ))}
+                            </select>
+                        </label>
+                    ))}
+                </div>
+            )}
+            {entries.length === 0 && (
+                <div className="muted">Nothing has been logged.</div>
+            )}
+            <div className="mono" style={{ maxHeight: 360, overflowY: 'auto' }}>
+                {entries.map((e, i) => (
+                    <div key={i} style={{ marginBottom: 4 }}>
+                        <span className="muted">
+                            {new Date(e.time).toLocaleTimeString()}
+                        </span>{' '}
+                        <span
+                            style={{ color: levelColor[e.level.toLowerCase()] }}
+                        >
+                            {e.level}
+                        </span>{' '}
+                        {e.module && (
+                            <span className="muted">[{e.module}] </span>
+                        )}
+                        {e.message}
+                        {Object.entries(e.attrs || {}).map(([k, v]) => (
+                            <span key={k} className="muted">
+                                {' '}
+                                {k}={v}
+                            </span>
+                        ))}
+                    </div>
+                ))}
+            </div>
+            {error && (
+                <div role="alert" style={{ marginTop: 4, color: '#ff6b6b' }}>
+                    {error}
+                </div>
+            )}
+            <div
+                className="ui-stack"
+                style={{ justifyContent: 'flex-end', marginTop: 12 }}
+            >
+                <Button variant="ghost" onClick={() => onOpenChange(false)}>
+                    Close
+                </Button>
+            </div>
+        </Modal>
+    );
+};
+
+export default LogViewer;
@@ -0,0 +1,158 @@
+import React, { useEffect, useState } from 'react';
+import Button from './primitives/Button';
+import Modal from './primitives/Modal';
+import { PauseWindows, SetPauseWindows } from '../../wailsjs/go/ui/UI';
+import { echo } from '../../wailsjs/go/models';
+
+type Props = {
+    open: boolean;
+    onOpenChange: (open: boolean) => void;
+};
+
+const DAYS = ['Sun', 'Mon', 'Tue', 'Wed', 'Thu', 'Fri', 'Sat'];
+
+// Windows are kept in minutes after midnight; time inputs use "HH:MM".
+const toClock = (mins: number) =>
+    `${String(Math.floor(mins / 60)).padStart(2, '0')}:${String(
+        mins % 60
+    ).padStart(2, '0')}`;
+
+const fromClock = (clock: string) => {
+    const [h, m] = clock.split(':').map(Number);
+    return (h || 0) * 60 + (m || 0);
+};
+
+const describe = (w: echo.PauseWindow) => {
+    const days =
+        !w.days || w.days.length === 0
+            ? 'Every day'
+            : w.days.map((d) => DAYS[d]).join(', ');
+    return `${days}, ${toClock(w.from)} – ${toClock(w.to)}`;
+};
+
+export const PauseScheduleEditor: React.FC<Props> = ({
+    open,
+    onOpenChange,
+}) => {
+    const [windows, setWindows] = useState<echo.PauseWindow[]>([]);
+    const [days, setDays] = useState<number[]>([]);
+    const [from, setFrom] = useState('22:00');
+    const [to, setTo] = useState('06:00');
+    const [error, setError] = useState('');
+
+    useEffect(() => {
+        if (!open) return;
+        setError('');
+        PauseWindows()
+            .then((w) => setWindows(w || []))
+            .catch((e) => setError(String(e)));
+    }, [open]);
+
+    const save = (next: echo.PauseWindow[]) => {
+        setError('');
+        SetPauseWindows(next)
+            .then(() => setWindows(next))
+            .catch((e) => setError(String(e)));
+    };
+
+    const toggleDay = (d: number) =>
+        setDays((prev) =>
+            prev.includes(d)
+                ? prev.filter((x) => x !== d)
+                : [...prev, d].sort((a, b) => a - b)
+        );
+
+    const add = (e: React.FormEvent) => {
+        e.preventDefault();
+        const w = echo.PauseWindow.createFrom({
+            days,
+            from: fromClock(from),
+            to: fromClock(to),
+        });
+        save([...windows, w]);
+    };
+
+    return (
+        <Modal open={open} onOpenChange={onOpenChange} title="Pause schedule">
+            <div className="muted" style={{ marginBottom: 8 }}>
+                Every transfer pauses during these times and resumes when
+                they end. A window ending before it starts runs past
+                midnight.
+            </div>
+            {windows.map((w, i) => (
+                <div
+                    key={i}
+                    className="ui-stack"
+                    style={{ justifyContent: 'space-between' }}
+                >
+                    <div>{describe(w)}</div>
+                    <Button
+                        variant="ghost"
+                        onClick={() =>
+                            save(windows.filter((_, j) => j !== i))
+                        }
+                    >
+                        Remove
+                    </Button>
+                </div>
+            ))}
+            <form onSubmit={add} style={{ marginTop: 12 }}>
+                <div className="label" style={{ marginBottom: 6 }}>
+                    Days (none for every day)
+                </div>
+                <div className="ui-stack">
+                    {DAYS.map((name, d) => (
+                        <label key={name}>
+                            <input
+                                type="checkbox"
+                                checked={days.includes(d)}
+                                onChange={() => toggleDay(d)}
+                            />{' '}
+                            {name}
+                        </label>
+                    ))}
+                </div>
+                <div className="ui-stack" style={{ marginTop: 8 }}>
+                    <label className="label" htmlFor="pause-from">
+                        From
+                    </label>
+                    <input
+                        id="pause-from"
+                        type="time"
+                        className="ui-input"
+                        value={from}
+                        onChange={(e) => setFrom(e.target.value)}
+                    />
+                    <label className="label" htmlFor="pause-to">
+                        To
+                    </label>
+                    <input
+                        id="pause-to"
+                        type="time"
+                        className="ui-input"
+                        value={to}
+                        onChange={(e) => setTo(e.target.value)}
+                    />
+                </div>
+                {error && (
+                    <div
+                        role="alert"
+                        style={{ marginTop: 4, color: '#ff6b6b' }}
+                    >
+                        {error}
+                    </div>
+                )}
+                <div
+                    className="ui-stack"
+                    style={{ justifyContent: 'flex-end', marginTop: 12 }}
+                >
+                    <Button type="submit" variant="primary">
+                        Add window
+                    </Button>
+                </div>
+            </form>
+        </Modal>
+    );
+};
+
+export default PauseScheduleEditor;
@@ -1,6 +1,7 @@
 import React, { useEffect, useMemo, useRef, useState } from 'react';
 import { usePeers } from '../providers/PeersProvider';
 import Pager from './Pager';
+import { formatRate } from '../utils/torrent';
 
 function fmtSince(ts: number): string {
     const sec = Math.max(0, Math.floor((Date.now() - ts) / 1000));
@@ -21,9 +22,42 @@ function flagEmoji(cc?: string): string | undefined {
     return String.fromCodePoint(first) + String.fromCodePoint(second);
 }
 
-export default function PeersList() {
+const columns = [
+    'Flag',
+    'Address',
+    'Client',
+    'Network',
+    'Progress',
+    'Down',
+    'Up',
+    'Flags',
+    'Message',
+];
+
+// The connection state letters, as other clients show them.
+function network(p: { asn?: number; org?: string }): string | undefined {
+    if (!p.asn) return p.org;
+    return p.org ? `AS${p.asn} ${p.org}` : `AS${p.asn}`;
+}
+
+const flagsHelp =
+    'D downloading, d interested but choked, U uploading, ' +
+    'u peer interested but choked, K peer unchoked us, ? we unchoked peer';
+
+type Props = {
+    // Only peers of this torrent are listed, by hex info-hash.
+    infoHash?: string;
+};
+
+export default function PeersList({ infoHash }: Props) {
     const { peers } = usePeers();
-    const items = useMemo(() => Object.values(peers), [peers]);
+    const items = useMemo(
+        () =>
+            Object.values(peers).filter(
+                (p) => !infoHash || p.infoHash === infoHash
+            ),
+        [peers, infoHash]
+    );
 
     // Resizable columns (like trackers)
     const headerRef = useRef<HTMLDivElement | null>(null);
@@ -49,7 +83,7 @@ export default function PeersList() {
     const persistWidths = () => {
         try {
             const widths: Record<string, number> = {};
-            for (let i = 1; i <= 3; i++) {
+            for (let i = 1; i <= columns.length; i++) {
                 const v = getVarPx(`--peers-col-${i}`, 0);
                 if (v > 0) widths[i] = v;
             }
@@ -139,27 +173,19 @@ export default function PeersList() {
     return (
         <div className="tracker-table-wrap">
             <div className="peers-grid peers-grid-header" ref={headerRef}>
-                <div className="grid-resizable">
-                    Flag
-                    <div
-                        className="col-resizer"
-                        onMouseDown={(e) => startResize(1, e)}
-                    />
-                </div>
-                <div className="grid-resizable">
-                    Address
+                {columns.map((name, i) => (
                     <div
-                        className="col-resizer"
-                        onMouseDown={(e) => startResize(2, e)}
-                    />
-                </div>
-                <div className="grid-resizable">
-                    Message
-                    <div
-                        className="col-resizer"
-                        onMouseDown={(e) => startResize(3, e)}
-                    />
-                </div>
+                        key={name}
+                        className="grid-resizable"
+                        title={name === 'Flags' ? flagsHelp : undefined}
+                    >
+                        {name}
+                        <div
+                            className="col-resizer"
+                            onMouseDown={(e) => startResize(i + 1, e)}
+                        />
+                    </div>
+                ))}
             </div>
             {/* Tick to re-render so pulse can fade */}
             {pageItems.map((p) => {
@@ -178,6 +204,22 @@ export default function PeersList() {
                         <div className="wrap" title={p.addr}>
                             {hostFor(p.addr)}
                         </div>
+                        <div className="wrap" title={p.client || ''}>
+                            {p.client || '-'}
+                        </div>
+                        <div className="wrap" title={network(p) || ''}>
+                            {p.org || (p.asn ? `AS${p.asn}` : '-')}
+                        </div>
+                        <div className="num">
+                            {p.seed
+                                ? 'seed'
+                                : `${(p.progress || 0).toFixed(1)}%`}
+                        </div>
+                        <div className="num">{formatRate(p.downRate)}</div>
+                        <div className="num">{formatRate(p.upRate)}</div>
+                        <div className="mono" title={flagsHelp}>
+                            {p.flags || '-'}
+                        </div>
                         <div className="wrap">{p.lastMsg || '-'}</div>
                     </div>
                 );
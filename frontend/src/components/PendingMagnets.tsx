@@ -0,0 +1,36 @@
+import React from 'react';
+import { echo } from '../../wailsjs/go/models';
+
+type Props = {
+    magnets: echo.MagnetStatus[];
+};
+
+// PendingMagnets lists the magnet links still waiting for their metadata,
+// until they turn into rows of the torrent table.
+export const PendingMagnets: React.FC<Props> = ({ magnets }) => {
+    if (magnets.length === 0) return null;
+    return (
+        <div className="card ui-card" style={{ marginTop: 16 }}>
+            {magnets.map((m) => {
+                const pct = m.total > 0 ? (m.received / m.total) * 100 : 0;
+                return (
+                    <div key={m.infoHash} style={{ marginBottom: 8 }}>
+                        <div>{m.name || m.infoHash}</div>
+                        <div className="muted">
+                            {m.total > 0
+                                ? `Fetching metadata: ${m.received} of ${m.total} pieces`
+                                : 'Looking for peers with the metadata…'}
+                        </div>
+                        <progress
+                            max={100}
+                            value={pct}
+                            style={{ width: '100%' }}
+                        />
+                    </div>
+                );
+            })}
+        </div>
+    );
+};
+
+export default PendingMagnets;
@@ -0,0 +1,62 @@
+import React, { useEffect, useRef } from 'react';
+
+type Props = {
+    // One digit per piece, as GetPieceMap returns it.
+    map: string;
+    height?: number;
+};
+
+const colors: Record<string, string> = {
+    '0': '#2a2a2a',
+    '1': '#b38b00',
+    '2': '#4a90d9',
+    '3': '#3fb950',
+};
+
+// PieceBar draws the classic piece bar. With more pieces than pixels, each
+// column shows the least advanced piece it covers, so gaps stay visible.
+export const PieceBar: React.FC<Props> = ({ map, height = 14 }) => {
+    const ref = useRef<HTMLCanvasElement | null>(null);
+
+    useEffect(() => {
+        const canvas = ref.current;
+        if (!canvas) return;
+        const width = Math.max(1, Math.floor(canvas.clientWidth));
+        canvas.width = width;
+        canvas.height = height;
+        const ctx = canvas.getContext('2d');
+        if (!ctx || map.length === 0) return;
+
+        const cols = Math.min(width, map.length);
+        const colWidth = width / cols;
+        for (let c = 0; c < cols; c++) {
+            const from = Math.floor((c * map.length) / cols);
+            const to = Math.max(
+                from + 1,
+                Math.floor(((c + 1) * map.length) / cols)
+            );
+            let state = '3';
+            for (let i = from; i < to; i++) {
+                if (map[i] < state) state = map[i];
+            }
+            ctx.fillStyle = colors[state] || colors['0'];
+            ctx.fillRect(
+                Math.floor(c * colWidth),
+                0,
+                Math.ceil(colWidth),
+                height
+            );
+        }
+    }, [map, height]);
+
+    return (
+        <canvas
+            ref={ref}
+            role="img"
+            aria-label="Piece map"
+            style={{ width: '100%', height, display: 'block' }}
+        />
+    );
+};
+
+export default PieceBar;
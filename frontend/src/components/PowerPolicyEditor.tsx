@@ -0,0 +1,135 @@
+import React, { useEffect, useState } from 'react';
+import Button from './primitives/Button';
+import Input from './primitives/Input';
+import Modal from './primitives/Modal';
+import { PowerPolicy, SetPowerPolicy } from '../../wailsjs/go/ui/UI';
+import { echo } from '../../wailsjs/go/models';
+
+type Props = {
+    open: boolean;
+    onOpenChange: (open: boolean) => void;
+};
+
+// Throttle limits are edited in KiB/s and stored in bytes per second.
+const toKiB = (bytes: number) => (bytes > 0 ? String(bytes / 1024) : '');
+const fromKiB = (text: string) => Math.max(0, Math.round(Number(text) * 1024));
+
+const ActionSelect: React.FC<{
+    id: string;
+    label: string;
+    value: string;
+    onChange: (v: string) => void;
+}> = ({ id, label, value, onChange }) => (
+    <>
+        <label
+            className="label"
+            htmlFor={id}
+            style={{ display: 'block', margin: '8px 0 6px' }}
+        >
+            {label}
+        </label>
+        <select
+            id={id}
+            className="ui-input"
+            value={value}
+            onChange={(e) => onChange(e.target.value)}
+        >
+            <option value="">Keep going</option>
+            <option value="throttle">Throttle</option>
+            <option value="pause">Pause everything</option>
+        </select>
+    </>
+);
+
+export const PowerPolicyEditor: React.FC<Props> = ({ open, onOpenChange }) => {
+    const [onBattery, setOnBattery] = useState('');
+    const [onMetered, setOnMetered] = useState('');
+    const [down, setDown] = useState('');
+    const [up, setUp] = useState('');
+    const [error, setError] = useState('');
+    const [saving, setSaving] = useState(false);
+
+    useEffect(() => {
+        if (!open) return;
+        setError('');
+        PowerPolicy()
+            .then((p) => {
+                setOnBattery(p.onBattery || '');
+                setOnMetered(p.onMetered || '');
+                setDown(toKiB(p.throttleDownload));
+                setUp(toKiB(p.throttleUpload));
+            })
+            .catch((e) => setError(String(e)));
+    }, [open]);
+
+    const save = () => {
+        setSaving(true);
+        setError('');
+        SetPowerPolicy(
+            echo.PowerPolicy.createFrom({
+                onBattery,
+                onMetered,
+                throttleDownload: fromKiB(down),
+                throttleUpload: fromKiB(up),
+            })
+        )
+            .then(() => onOpenChange(false))
+            .catch((e) => setError(String(e)))
+            .finally(() => setSaving(false));
+    };
+
+    return (
+        <Modal
+            open={open}
+            onOpenChange={onOpenChange}
+            title="Battery & metered networks"
+        >
+            <ActionSelect
+                id="power-battery"
+                label="On battery power"
+                value={onBattery}
+                onChange={setOnBattery}
+            />
+            <ActionSelect
+                id="power-metered"
+                label="On a metered connection"
+                value={onMetered}
+                onChange={setOnMetered}
+            />
+            <div className="ui-stack" style={{ marginTop: 8 }}>
+                <Input
+                    label="Throttled download (KiB/s)"
+                    type="number"
+                    min={0}
+                    value={down}
+                    onChange={(e) => setDown(e.target.value)}
+                />
+                <Input
+                    label="Throttled upload (KiB/s)"
+                    type="number"
+                    min={0}
+                    value={up}
+                    onChange={(e) => setUp(e.target.value)}
+                />
+            </div>
+            {error && (
+                <div role="alert" style={{ marginTop: 4, color: '#ff6b6b' }}>
+                    {error}
+                </div>
+            )}
+            <div
+                className="ui-stack"
+                style={{ justifyContent: 'flex-end', marginTop: 12 }}
+            >
+                <Button variant="ghost" onClick={() => onOpenChange(false)}>
+                    Cancel
+                </Button>
+                <Button variant="primary" loading={saving} onClick={save}>
+                    Save
+                </Button>
+            </div>
+        </Modal>
+    );
+};
+
+export default PowerPolicyEditor;
@@ -0,0 +1,121 @@
+import React, { useEffect, useState } from 'react';
+import Button from './primitives/Button';
+import Input from './primitives/Input';
+import Modal from './primitives/Modal';
+import { QuotaStatus, SetQuotas } from '../../wailsjs/go/ui/UI';
+import { echo } from '../../wailsjs/go/models';
+import { formatBytes } from '../utils/torrent';
+
+type Props = {
+    open: boolean;
+    onOpenChange: (open: boolean) => void;
+};
+
+// Quotas are edited in GiB and stored in bytes.
+const GiB = 1024 ** 3;
+const toGiB = (bytes: number) => (bytes > 0 ? String(bytes / GiB) : '');
+const fromGiB = (text: string) => Math.max(0, Math.round(Number(text) * GiB));
+
+export const QuotaEditor: React.FC<Props> = ({ open, onOpenChange }) => {
+    const [status, setStatus] = useState<echo.QuotaStatus>();
+    const [disk, setDisk] = useState('');
+    const [monthly, setMonthly] = useState('');
+    const [resetDay, setResetDay] = useState('1');
+    const [error, setError] = useState('');
+    const [saving, setSaving] = useState(false);
+
+    useEffect(() => {
+        if (!open) return;
+        setError('');
+        QuotaStatus()
+            .then((s) => {
+                setStatus(s);
+                setDisk(toGiB(s.quotas.disk));
+                setMonthly(toGiB(s.quotas.monthly));
+                setResetDay(String(s.quotas.resetDay || 1));
+            })
+            .catch((e) => setError(String(e)));
+    }, [open]);
+
+    const save = () => {
+        setSaving(true);
+        setError('');
+        SetQuotas(
+            echo.Quotas.createFrom({
+                disk: fromGiB(disk),
+                monthly: fromGiB(monthly),
+                resetDay: Number(resetDay) || 1,
+            })
+        )
+            .then(() => onOpenChange(false))
+            .catch((e) => setError(String(e)))
+            .finally(() => setSaving(false));
+    };
+
+    const month = status ? status.month.uploaded + status.month.downloaded : 0;
+
+    return (
+        <Modal open={open} onOpenChange={onOpenChange} title="Quotas">
+            {status && (
+                <div className="muted">
+                    <div>
+                        On disk: {formatBytes(status.diskUsed)}
+                        {status.diskFull && ' — downloads held'}
+                    </div>
+                    <div>
+                        This month: {formatBytes(month)}
+                        {status.capReached && ' — cap reached, paused'}
+                    </div>
+                    {status.fullCategories.length > 0 && (
+                        <div>
+                            Full categories:{' '}
+                            {status.fullCategories.join(', ')}
+                        </div>
+                    )}
+                </div>
+            )}
+            <div className="ui-stack" style={{ marginTop: 8 }}>
+                <Input
+                    label="Disk quota (GiB)"
+                    type="number"
+                    min={0}
+                    value={disk}
+                    onChange={(e) => setDisk(e.target.value)}
+                />
+                <Input
+                    label="Monthly cap (GiB)"
+                    type="number"
+                    min={0}
+                    value={monthly}
+                    onChange={(e) => setMonthly(e.target.value)}
+                />
+                <Input
+                    label="Resets on day"
+                    type="number"
+                    min={1}
+                    max={28}
+                    value={resetDay}
+                    onChange={(e) => setResetDay(e.target.value)}
+                />
+            </div>
+            {error && (
+                <div role="alert" style={{ marginTop: 4, color: '#ff6b6b' }}>
+                    {error}
+                </div>
+            )}
+            <div
+                className="ui-stack"
+                style={{ justifyContent: 'flex-end', marginTop: 12 }}
+            >
+                <Button variant="ghost" onClick={() => onOpenChange(false)}>
+                    Cancel
+                </Button>
+                <Button variant="primary" loading={saving} onClick={save}>
+                    Save
+                </Button>
+            </div>
+        </Modal>
+    );
+};
+
+export default QuotaEditor;
@@ -0,0 +1,470 @@
+import React, { useEffect, useRef, useState } from 'react';
+import Button from './primitives/Button';
+import Input from './primitives/Input';
+import Modal from './primitives/Modal';
+import {
+    CancelPreview,
+    CancelSearch,
+    IndexedTorrents,
+    Indexers,
+    PreviewResult,
+    SavedSearches,
+    Search,
+    SetSavedSearches,
+} from '../../wailsjs/go/ui/UI';
+import { EventsOn } from '../../wailsjs/runtime';
+import { echo, search } from '../../wailsjs/go/models';
+import { formatBytes } from '../utils/torrent';
+
+type Props = {
+    open: boolean;
+    onOpenChange: (open: boolean) => void;
+    onDownload: (result: search.Result) => void;
+};
+
+// Must match echo.LocalIndexName.
+const localIndex = 'DHT index';
+
+// Newznab's top-level categories.
+const categories: [number, string][] = [
+    [0, 'All categories'],
+    [2000, 'Movies'],
+    [5000, 'TV'],
+    [3000, 'Audio'],
+    [4000, 'PC'],
+    [7000, 'Books'],
+    [8000, 'Other'],
+];
+
+const day = 24 * 60 * 60 * 1e9;
+
+// Maximum ages offered, in nanoseconds as Go's time.Duration.
+const ages: [number, string][] = [
+    [0, 'Any age'],
+    [day, 'Past day'],
+    [7 * day, 'Past week'],
+    [30 * day, 'Past month'],
+    [365 * day, 'Past year'],
+];
+
+// Sizes are entered in GiB; blank doesn't filter.
+const fromGiB = (text: string) =>
+    Math.max(0, Math.round(Number(text) * 1024 ** 3) || 0);
+
+const resultKey = (r: search.Result) => r.infoHash || r.link || r.magnet;
+
+// The metadata of the result being previewed, once it has arrived.
+type Preview = {
+    key: string;
+    data?: echo.ResultPreview;
+    error?: string;
+};
+
+export const SearchDialog: React.FC<Props> = ({
+    open,
+    onOpenChange,
+    onDownload,
+}) => {
+    const [indexers, setIndexers] = useState<echo.Indexer[]>([]);
+    const [selected, setSelected] = useState<string[]>([]);
+    const [text, setText] = useState('');
+    const [category, setCategory] = useState(0);
+    const [minSeeders, setMinSeeders] = useState('');
+    const [minSize, setMinSize] = useState('');
+    const [maxSize, setMaxSize] = useState('');
+    const [maxAge, setMaxAge] = useState(0);
+    const [results, setResults] = useState<search.Result[]>([]);
+    const [pending, setPending] = useState(0);
+    const [checking, setChecking] = useState(false);
+    const [errors, setErrors] = useState<string[]>([]);
+    const [saved, setSaved] = useState<echo.SavedSearch[]>([]);
+    const [autoAdd, setAutoAdd] = useState(false);
+    const [preview, setPreview] = useState<Preview | null>(null);
+    const [error, setError] = useState('');
+    // Updates from an earlier search are told apart by their query.
+    const current = useRef('');
+
+    useEffect(() => {
+        if (!open) return;
+        setError('');
+        Promise.all([Indexers(), IndexedTorrents()])
+            .then(([list, indexed]) => {
+                // The local index is searched like one more indexer.
+                const local = indexed
+                    ? [echo.Indexer.createFrom({ name: localIndex })]
+                    : [];
+                const enabled = (list || []).filter((i) => !i.disabled);
+                setIndexers([...local, ...enabled]);
+            })
+            .catch((e) => setError(String(e)));
+        SavedSearches()
+            .then((list) => setSaved(list || []))
+            .catch((e) => setError(String(e)));
+        const off = EventsOn('search:results', (payload: any) => {
+            if (!payload || payload.query !== current.current) return;
+            setResults(payload.results || []);
+            setPending(payload.pending);
+            setChecking(payload.checking);
+            if (payload.error)
+                setErrors((prev) => [
+                    ...prev,
+                    `${payload.indexer}: ${payload.error}`,
+                ]);
+        });
+        return () => {
+            if (typeof off === 'function') off();
+            CancelSearch();
+            CancelPreview();
+            setPreview(null);
+        };
+    }, [open]);
+
+    const filter = () =>
+        search.Filter.createFrom({
+            minSeeders: Math.max(0, Math.round(Number(minSeeders)) || 0),
+            minSize: fromGiB(minSize),
+            maxSize: fromGiB(maxSize),
+            maxAge,
+        });
+
+    const run = (e: React.FormEvent) => {
+        e.preventDefault();
+        const q = text.trim();
+        if (!q) return;
+        current.current = q;
+        setResults([]);
+        setErrors([]);
+        setError('');
+        setPending(selected.length || indexers.length);
+        Search(q, category ? [category] : [], selected, filter())
+            .then((r) => {
+                if (current.current === q) setResults(r || []);
+            })
+            .catch((e) => {
+                if (current.current === q) setError(String(e));
+            })
+            .finally(() => {
+                if (current.current !== q) return;
+                setPending(0);
+                setChecking(false);
+            });
+    };
+
+    const storeSaved = (next: echo.SavedSearch[]) =>
+        SetSavedSearches(next)
+            .then(() => setSaved(next))
+            .catch((e) => setError(String(e)));
+
+    // A search is saved under its text, replacing one saved before.
+    const save = () => {
+        const q = text.trim();
+        if (!q) return;
+        const entry = echo.SavedSearch.createFrom({
+            name: q,
+            query: echo.SearchQuery.createFrom({
+                text: q,
+                categories: category ? [category] : [],
+                indexers: selected,
+                filter: filter(),
+            }),
+            interval: 0,
+            autoAdd,
+        });
+        storeSaved([...saved.filter((s) => s.name !== q), entry]);
+    };
+
+    const load = (s: echo.SavedSearch) => {
+        const f = s.query.filter;
+        setText(s.query.text);
+        setCategory(s.query.categories?.[0] || 0);
+        setSelected(s.query.indexers || []);
+        setMinSeeders(f?.minSeeders ? String(f.minSeeders) : '');
+        setMinSize(f?.minSize ? String(f.minSize / 1024 ** 3) : '');
+        setMaxSize(f?.maxSize ? String(f.maxSize / 1024 ** 3) : '');
+        setMaxAge(f?.maxAge || 0);
+        setAutoAdd(s.autoAdd);
+    };
+
+    // Showing a result's files again hides them.
+    const togglePreview = (r: search.Result) => {
+        const key = resultKey(r);
+        if (preview?.key === key) {
+            CancelPreview();
+            setPreview(null);
+            return;
+        }
+        setPreview({ key });
+        PreviewResult(r)
+            .then((data) =>
+                setPreview((p) => (p?.key === key ? { key, data } : p))
+            )
+            .catch((e) =>
+                setPreview((p) =>
+                    p?.key === key ? { key, error: String(e) } : p
+                )
+            );
+    };
+
+    // None selected searches every indexer.
+    const toggle = (name: string) =>
+        setSelected((prev) => {
+            const from = prev.length ? prev : indexers.map((i) => i.name);
+            const next = from.includes(name)
+                ? from.filter((n) => n !== name)
+                : [...from, name];
+            return next.length === indexers.length ? [] : next;
+        });
+
+    return (
+        <Modal open={open} onOpenChange={onOpenChange} title="Search">
+            <form className="ui-stack" onSubmit={run}>
+                <input
+                    className="ui-input"
+                    placeholder="Search your indexers"
+                    value={text}
+                    onChange={(e) => setText(e.target.value)}
+                    style={{ flex: 1 }}
+                />
+                <select
+                    className="ui-input"
+                    aria-label="Category"
+                    value={category}
+                    onChange={(e) => setCategory(Number(e.target.value))}
+                >
+                    {categories.map(([id, name]) => (
+                        <option key={id} value={id}>
+                            {name}
+                        </option>
+                    ))}
+                </select>
+                <Button
+                    type="submit"
+                    variant="primary"
+                    loading={pending > 0}
+                    disabled={indexers.length === 0}
+                >
+                    Search
+                </Button>
+            </form>
+            <div className="ui-stack" style={{ marginTop: 8 }}>
+                <Input
+                    label="Min seeders"
+                    type="number"
+                    min={0}
+                    value={minSeeders}
+                    onChange={(e) => setMinSeeders(e.target.value)}
+                />
+                <Input
+                    label="Min size (GiB)"
+                    type="number"
+                    min={0}
+                    step="any"
+                    value={minSize}
+                    onChange={(e) => setMinSize(e.target.value)}
+                />
+                <Input
+                    label="Max size (GiB)"
+                    type="number"
+                    min={0}
+                    step="any"
+                    value={maxSize}
+                    onChange={(e) => setMaxSize(e.target.value)}
+                />
+                <select
+                    className="ui-input"
+                    aria-label="Age"
+                    value={maxAge}
+                    onChange={(e) => setMaxAge(Number(e.target.value))}
+                    style={{ alignSelf: 'flex-end' }}
+                >
+                    {ages.map(([age, name]) => (
+                        <option key={age} value={age}>
+                            {name}
+                        </option>
+                    ))}
+                </select>
+            </div>
+            {indexers.length === 0 ? (
+                <div className="muted" style={{ marginTop: 8 }}>
+                    Add an indexer to search first.
+                </div>
+            ) : (
+                <div className="ui-stack" style={{ marginTop: 8 }}>
+                    {indexers.map((i) => (
+                        <label key={i.name} className="label">
+                            <input
+                                type="checkbox"
+                                checked={
+                                    selected.length === 0 ||
+                                    selected.includes(i.name)
+                                }
+                                onChange={() => toggle(i.name)}
+                            />{' '}
+                            {i.name}
+                        </label>
+                    ))}
+                </div>
+            )}
+            <div className="ui-stack" style={{ marginTop: 8 }}>
+                <label className="label">
+                    <input
+                        type="checkbox"
+                        checked={autoAdd}
+                        onChange={(e) => setAutoAdd(e.target.checked)}
+                    />{' '}
+                    Add new results automatically
+                </label>
+                <Button variant="ghost" disabled={!text.trim()} onClick={save}>
+                    Save search
+                </Button>
+            </div>
+            {saved.length > 0 && (
+                <div style={{ marginTop: 8 }}>
+                    <div className="label">
+                        Saved searches, checked for new results
+                    </div>
+                    {saved.map((s) => (
+                        <div
+                            key={s.name}
+                            className="ui-stack"
+                            style={{ justifyContent: 'space-between' }}
+                        >
+                            <span>
+                                {s.name}
+                                {s.autoAdd && (
+                                    <span className="muted"> • auto-add</span>
+                                )}
+                            </span>
+                            <span className="ui-stack">
+                                <Button variant="ghost" onClick={() => load(s)}>
+                                    Load
+                                </Button>
+                                <Button
+                                    variant="ghost"
+                                    onClick={() =>
+                                        storeSaved(
+                                            saved.filter((t) => t !== s)
+                                        )
+                                    }
+                                >
+                                    Remove
+                                </Button>
+                            </span>
+                        </div>
+                    ))}
+                </div>
+            )}
+            <div style={{ maxHeight: 400, overflowY: 'auto', marginTop: 8 }}>
+                {results.map((r) => (
+                    <div key={resultKey(r)} style={{ marginBottom: 8 }}>
+                        <div
+                            className="ui-stack"
+                            style={{ justifyContent: 'space-between' }}
+                        >
+                            <div>
+                                <div>{r.title}</div>
+                                <div className="muted">
+                                    {formatBytes(r.size)} •{' '}
+                                    <span
+                                        title={
+                                            r.verified
+                                                ? 'Scraped from trackers'
+                                                : 'As the indexer reported'
+                                        }
+                                    >
+                                        {r.seeders} seeders •{' '}
+                                        {r.leechers} leechers
+                                        {r.verified && ' ✓'}
+                                    </span>
+                                    {r.dhtPeers
+                                        ? ` • ${r.dhtPeers} DHT peers`
+                                        : ''}{' '}
+                                    • <Sources result={r} />
+                                </div>
+                            </div>
+                            <span className="ui-stack">
+                                <Button
+                                    variant="ghost"
+                                    onClick={() => togglePreview(r)}
+                                >
+                                    Files
+                                </Button>
+                                <Button
+                                    variant="ghost"
+                                    onClick={() => onDownload(r)}
+                                >
+                                    Download
+                                </Button>
+                            </span>
+                        </div>
+                        {preview?.key === resultKey(r) && (
+                            <PreviewFiles preview={preview} />
+                        )}
+                    </div>
+                ))}
+                {checking && (
+                    <div className="muted">Checking swarms…</div>
+                )}
+                {pending === 0 && results.length === 0 && current.current && (
+                    <div className="muted">Nothing found.</div>
+                )}
+            </div>
+            {[...errors, error].filter(Boolean).map((e) => (
+                <div
+                    key={e}
+                    role="alert"
+                    style={{ marginTop: 4, color: '#ff6b6b' }}
+                >
+                    {e}
+                </div>
+            ))}
+        </Modal>
+    );
+};
+
+// A result merged from several providers gets a badge for each, the one
+// downloaded from first.
+const Sources: React.FC<{ result: search.Result }> = ({ result }) => {
+    const sources = result.sources || [];
+    if (sources.length < 2) return <>{result.indexer || result.provider}</>;
+    return (
+        <>
+            {sources.map((s, i) => (
+                <span
+                    key={i}
+                    className="count-badge"
+                    title={`${s.title}: ${s.seeders} seeders`}
+                    style={{ marginRight: 4 }}
+                >
+                    {s.indexer || s.provider}
+                </span>
+            ))}
+        </>
+    );
+};
+
+const PreviewFiles: React.FC<{ preview: Preview }> = ({ preview }) => {
+    const { data, error } = preview;
+    if (error)
+        return (
+            <div role="alert" style={{ marginTop: 4, color: '#ff6b6b' }}>
+                {error}
+            </div>
+        );
+    if (!data) return <div className="muted">Fetching metadata…</div>;
+    return (
+        <div className="muted" style={{ marginTop: 4, marginLeft: 12 }}>
+            <div>
+                {data.files.length} files, {formatBytes(data.size)}
+                {data.private && ' • private'}
+            </div>
+            {data.files.map((f) => (
+                <div key={f.path} className="mono">
+                    {f.path} ({formatBytes(f.length)})
+                </div>
+            ))}
+        </div>
+    );
+};
+
+export default SearchDialog;
@@ -0,0 +1,397 @@
+import React, { useEffect, useState } from 'react';
+import Button from './primitives/Button';
+import Input from './primitives/Input';
+import Modal from './primitives/Modal';
+import {
+    CloseAction,
+    saveCloseAction,
+    savedCloseAction,
+} from './CloseDialog';
+import {
+    CheckPort,
+    FingerprintPresets,
+    GetSettings,
+    UpdateSettings,
+} from '../../wailsjs/go/ui/UI';
+import { echo, torrent } from '../../wailsjs/go/models';
+
+type Props = {
+    open: boolean;
+    onOpenChange: (open: boolean) => void;
+};
+
+// Rate limits are edited in KiB/s and stored in bytes per second; blank is
+// unlimited.
+const toKiB = (bytes: number) => (bytes > 0 ? String(bytes / 1024) : '');
+const fromKiB = (text: string) => Math.max(0, Math.round(Number(text) * 1024));
+const toCount = (n: number) => (n > 0 ? String(n) : '');
+const fromCount = (text: string) => Math.max(0, Math.round(Number(text) || 0));
+
+const sameFingerprint = (a: torrent.Fingerprint, b: torrent.Fingerprint) =>
+    a.peerIdPrefix === b.peerIdPrefix &&
+    a.version === b.version &&
+    a.userAgent === b.userAgent;
+
+const notifyOptions: [keyof echo.NotifySettings, string][] = [
+    ['completed', 'Downloads finishing'],
+    ['errored', 'Torrents stopping on an error'],
+    ['tracker', 'Trackers refusing a torrent'],
+    ['search', 'Saved searches finding new results'],
+    ['native', 'Show as system notifications'],
+];
+
+export const SettingsEditor: React.FC<Props> = ({ open, onOpenChange }) => {
+    const [downloadDir, setDownloadDir] = useState('');
+    const [torrentsDir, setTorrentsDir] = useState('');
+    const [dhtPort, setDhtPort] = useState('');
+    const [savedPort, setSavedPort] = useState(0);
+    const [listenPort, setListenPort] = useState('');
+    const [savedListenPort, setSavedListenPort] = useState(0);
+    const [readOnly, setReadOnly] = useState(false);
+    const [savedReadOnly, setSavedReadOnly] = useState(false);
+    const [down, setDown] = useState('');
+    const [up, setUp] = useState('');
+    const [connections, setConnections] = useState('');
+    const [downloads, setDownloads] = useState('');
+    const [seeds, setSeeds] = useState('');
+    const [proxy, setProxy] = useState('');
+    const [encryption, setEncryption] = useState('prefer');
+    const [notify, setNotify] = useState(new echo.NotifySettings());
+    const [crawl, setCrawl] = useState(false);
+    const [reconnect, setReconnect] = useState(false);
+    const [debugAddr, setDebugAddr] = useState('');
+    const [presets, setPresets] = useState<torrent.FingerprintPreset[]>([]);
+    const [fingerprint, setFingerprint] = useState(new torrent.Fingerprint());
+    const [closeAction, setCloseAction] = useState<CloseAction>('ask');
+    const [portCheck, setPortCheck] = useState('');
+    const [checking, setChecking] = useState(false);
+    const [error, setError] = useState('');
+    const [saving, setSaving] = useState(false);
+
+    useEffect(() => {
+        if (!open) return;
+        setError('');
+        setPortCheck('');
+        setCloseAction(savedCloseAction());
+        GetSettings()
+            .then((s) => {
+                setDownloadDir(s.downloadDir);
+                setTorrentsDir(s.torrentsDir);
+                setDhtPort(String(s.dhtPort));
+                setSavedPort(s.dhtPort);
+                setListenPort(String(s.listenPort));
+                setSavedListenPort(s.listenPort);
+                setReadOnly(s.dhtReadOnly);
+                setSavedReadOnly(s.dhtReadOnly);
+                setDown(toKiB(s.downloadLimit));
+                setUp(toKiB(s.uploadLimit));
+                setConnections(toCount(s.maxConnections));
+                setDownloads(toCount(s.maxActiveDownloads));
+                setSeeds(toCount(s.maxActiveSeeds));
+                setProxy(s.proxy);
+                setEncryption(s.encryption || 'prefer');
+                setNotify(s.notifications || new echo.NotifySettings());
+                setCrawl(s.crawlDHT);
+                setReconnect(s.reconnectOnIpChange);
+                setDebugAddr(s.debugAddr);
+                setFingerprint(s.fingerprint || new torrent.Fingerprint());
+            })
+            .catch((e) => setError(String(e)));
+        FingerprintPresets()
+            .then(setPresets)
+            .catch(() => setPresets([]));
+    }, [open]);
+
+    // An empty fingerprint is Echo's, the first preset.
+    const empty = !fingerprint.peerIdPrefix && !fingerprint.version;
+    const selectedPreset =
+        presets.find(
+            (p, i) =>
+                (empty && i === 0) ||
+                sameFingerprint(p.fingerprint, fingerprint)
+        )?.name ?? '';
+
+    const checkPort = () => {
+        setChecking(true);
+        setPortCheck('Testing, which can take a minute…');
+        CheckPort()
+            .then((c) =>
+                setPortCheck(
+                    (c.status === 'unknown'
+                        ? `Port ${c.port}/${c.protocol} has not been reached ` +
+                          'yet; test again once Echo has run a while.'
+                        : `Port ${c.port}/${c.protocol} is ${c.status}.`) +
+                        (c.dhtReachable ? ' The DHT port is reachable.' : '')
+                )
+            )
+            .catch((e) => setPortCheck(String(e)))
+            .finally(() => setChecking(false));
+    };
+
+    const save = () => {
+        setSaving(true);
+        setError('');
+        saveCloseAction(closeAction).catch(() => {});
+        UpdateSettings(
+            echo.SettingsPatch.createFrom({
+                downloadDir: downloadDir.trim(),
+                torrentsDir: torrentsDir.trim(),
+                dhtPort: fromCount(dhtPort),
+                listenPort: fromCount(listenPort),
+                dhtReadOnly: readOnly,
+                downloadLimit: fromKiB(down),
+                uploadLimit: fromKiB(up),
+                maxConnections: fromCount(connections),
+                maxActiveDownloads: fromCount(downloads),
+                maxActiveSeeds: fromCount(seeds),
+                proxy: proxy.trim(),
+                encryption,
+                notifications: notify,
+                crawlDHT: crawl,
+                reconnectOnIpChange: reconnect,
+                debugAddr: debugAddr.trim(),
+                fingerprint,
+            })
+        )
+            .then(() => onOpenChange(false))
+            .catch((e) => setError(String(e)))
+            .finally(() => setSaving(false));
+    };
+
+    return (
+        <Modal open={open} onOpenChange={onOpenChange} title="Settings">
+            <Input
+                label="Download folder"
+                value={downloadDir}
+                onChange={(e) => setDownloadDir(e.target.value)}
+            />
+            <div style={{ marginTop: 8 }}>
+                <Input
+                    label="Folder for fetched .torrent files"
+                    value={torrentsDir}
+                    onChange={(e) => setTorrentsDir(e.target.value)}
+                />
+            </div>
+            <div className="ui-stack" style={{ marginTop: 8 }}>
+                <Input
+                    label="Download limit (KiB/s)"
+                    type="number"
+                    min={0}
+                    placeholder="Unlimited"
+                    value={down}
+                    onChange={(e) => setDown(e.target.value)}
+                />
+                <Input
+                    label="Upload limit (KiB/s)"
+                    type="number"
+                    min={0}
+                    placeholder="Unlimited"
+                    value={up}
+                    onChange={(e) => setUp(e.target.value)}
+                />
+            </div>
+            <div className="ui-stack" style={{ marginTop: 8 }}>
+                <Input
+                    label="Max connections"
+                    type="number"
+                    min={0}
+                    placeholder="Unlimited"
+                    value={connections}
+                    onChange={(e) => setConnections(e.target.value)}
+                />
+                <Input
+                    label="Active downloads"
+                    type="number"
+                    min={0}
+                    placeholder="Unlimited"
+                    value={downloads}
+                    onChange={(e) => setDownloads(e.target.value)}
+                />
+                <Input
+                    label="Active seeds"
+                    type="number"
+                    min={0}
+                    placeholder="Unlimited"
+                    value={seeds}
+                    onChange={(e) => setSeeds(e.target.value)}
+                />
+            </div>
+            <div className="ui-stack" style={{ marginTop: 8 }}>
+                <Input
+                    label="DHT port"
+                    type="number"
+                    min={1}
+                    max={65535}
+                    value={dhtPort}
+                    onChange={(e) => setDhtPort(e.target.value)}
+                />
+                <Input
+                    label="Listen port"
+                    type="number"
+                    min={0}
+                    max={65535}
+                    value={listenPort}
+                    onChange={(e) => setListenPort(e.target.value)}
+                />
+                <Input
+                    label="Proxy"
+                    placeholder="socks5://host:1080"
+                    value={proxy}
+                    onChange={(e) => setProxy(e.target.value)}
+                />
+            </div>
+            {(fromCount(dhtPort) !== savedPort ||
+                fromCount(listenPort) !== savedListenPort) && (
+                <div className="muted" style={{ marginTop: 4 }}>
+                    The new port is used after a restart.
+                </div>
+            )}
+            <label className="label" style={{ display: 'block', marginTop: 8 }}>
+                <input
+                    type="checkbox"
+                    checked={readOnly}
+                    onChange={(e) => setReadOnly(e.target.checked)}
+                />{' '}
+                Only query the DHT, without answering other nodes
+            </label>
+            {readOnly !== savedReadOnly && (
+                <div className="muted" style={{ marginTop: 4 }}>
+                    The DHT changes mode after a restart.
+                </div>
+            )}
+            <div className="ui-stack" style={{ marginTop: 8 }}>
+                <Button variant="ghost" loading={checking} onClick={checkPort}>
+                    Test port
+                </Button>
+                {portCheck && <span className="muted">{portCheck}</span>}
+            </div>
+            <label
+                className="label"
+                htmlFor="settings-encryption"
+                style={{ display: 'block', margin: '8px 0 6px' }}
+            >
+                Peer encryption
+            </label>
+            <select
+                id="settings-encryption"
+                className="ui-input"
+                value={encryption}
+                onChange={(e) => setEncryption(e.target.value)}
+            >
+                <option value="prefer">Prefer encrypted</option>
+                <option value="require">Require encrypted</option>
+                <option value="disable">Disable</option>
+            </select>
+            <label
+                className="label"
+                htmlFor="settings-close"
+                style={{ display: 'block', margin: '8px 0 6px' }}
+            >
+                Closing the window while transferring
+            </label>
+            <select
+                id="settings-close"
+                className="ui-input"
+                value={closeAction}
+                onChange={(e) => setCloseAction(e.target.value as CloseAction)}
+            >
+                <option value="ask">Ask</option>
+                <option value="background">
+                    Keep running in the background
+                </option>
+                <option value="quit">Quit</option>
+            </select>
+            <div className="label" style={{ margin: '8px 0 6px' }}>
+                Notify about
+            </div>
+            {notifyOptions.map(([key, label]) => (
+                <label key={key} className="label" style={{ display: 'block' }}>
+                    <input
+                        type="checkbox"
+                        checked={!!notify[key]}
+                        onChange={(e) =>
+                            setNotify(
+                                echo.NotifySettings.createFrom({
+                                    ...notify,
+                                    [key]: e.target.checked,
+                                })
+                            )
+                        }
+                    />{' '}
+                    {label}
+                </label>
+            ))}
+            <label className="label" style={{ display: 'block', marginTop: 8 }}>
+                <input
+                    type="checkbox"
+                    checked={crawl}
+                    onChange={(e) => setCrawl(e.target.checked)}
+                />{' '}
+                Crawl the DHT to build a local search index
+            </label>
+            <label className="label" style={{ display: 'block' }}>
+                <input
+                    type="checkbox"
+                    checked={reconnect}
+                    onChange={(e) => setReconnect(e.target.checked)}
+                />{' '}
+                Reconnect to peers when the IP address changes
+            </label>
+            <label
+                className="label"
+                htmlFor="settings-fingerprint"
+                style={{ display: 'block', margin: '8px 0 6px' }}
+            >
+                Introduce torrents added from now on as
+            </label>
+            <select
+                id="settings-fingerprint"
+                className="ui-input"
+                value={selectedPreset}
+                onChange={(e) => {
+                    const preset = presets.find(
+                        (p) => p.name === e.target.value
+                    );
+                    if (preset) setFingerprint(preset.fingerprint);
+                }}
+            >
+                {selectedPreset === '' && (
+                    <option value="">
+                        Custom ({fingerprint.peerIdPrefix})
+                    </option>
+                )}
+                {presets.map((p) => (
+                    <option key={p.name} value={p.name}>
+                        {p.name}
+                    </option>
+                ))}
+            </select>
+            <div style={{ marginTop: 8 }}>
+                <Input
+                    label="Debug listener (pprof and engine internals)"
+                    placeholder="127.0.0.1:6060"
+                    value={debugAddr}
+                    onChange={(e) => setDebugAddr(e.target.value)}
+                />
+            </div>
+            {error && (
+                <div role="alert" style={{ marginTop: 4, color: '#ff6b6b' }}>
+                    {error}
+                </div>
+            )}
+            <div
+                className="ui-stack"
+                style={{ justifyContent: 'flex-end', marginTop: 12 }}
+            >
+                <Button variant="ghost" onClick={() => onOpenChange(false)}>
+                    Cancel
+                </Button>
+                <Button variant="primary" loading={saving} onClick={save}>
+                    Save
+                </Button>
+            </div>
+        </Modal>
+    );
+};
+
+export default SettingsEditor;
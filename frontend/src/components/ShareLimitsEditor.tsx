@@ -0,0 +1,116 @@
+import React, { useEffect, useState } from 'react';
+import Button from './primitives/Button';
+import Input from './primitives/Input';
+import Modal from './primitives/Modal';
+import { SetShareLimits, ShareLimits } from '../../wailsjs/go/ui/UI';
+import { echo } from '../../wailsjs/go/models';
+
+type Props = {
+    open: boolean;
+    onOpenChange: (open: boolean) => void;
+    infoHash: number[];
+};
+
+// Durations cross the bridge as nanoseconds.
+const NS_PER_MIN = 60e9;
+
+export const ShareLimitsEditor: React.FC<Props> = ({
+    open,
+    onOpenChange,
+    infoHash,
+}) => {
+    const [ratio, setRatio] = useState('');
+    const [minutes, setMinutes] = useState('');
+    const [action, setAction] = useState('pause');
+    const [error, setError] = useState('');
+    const [saving, setSaving] = useState(false);
+
+    useEffect(() => {
+        if (!open) return;
+        setError('');
+        ShareLimits(infoHash)
+            .then((l) => {
+                const mins = Math.round(l.seedTime / NS_PER_MIN);
+                setRatio(l.ratio ? String(l.ratio) : '');
+                setMinutes(mins ? String(mins) : '');
+                setAction(l.action || 'pause');
+            })
+            .catch((e) => setError(String(e)));
+    }, [open, infoHash]);
+
+    const save = (limits: echo.ShareLimits | null) => {
+        setSaving(true);
+        setError('');
+        SetShareLimits(infoHash, limits as echo.ShareLimits)
+            .then(() => onOpenChange(false))
+            .catch((e) => setError(String(e)))
+            .finally(() => setSaving(false));
+    };
+
+    const limits = () =>
+        echo.ShareLimits.createFrom({
+            ratio: Number(ratio) || 0,
+            seedTime: (Number(minutes) || 0) * NS_PER_MIN,
+            action,
+        });
+
+    return (
+        <Modal open={open} onOpenChange={onOpenChange} title="Share limits">
+            <Input
+                label="Ratio (blank for none)"
+                type="number"
+                min={0}
+                step={0.1}
+                value={ratio}
+                onChange={(e) => setRatio(e.target.value)}
+            />
+            <div style={{ marginTop: 8 }}>
+                <Input
+                    label="Seeding time in minutes (blank for none)"
+                    type="number"
+                    min={0}
+                    value={minutes}
+                    onChange={(e) => setMinutes(e.target.value)}
+                />
+            </div>
+            <label
+                className="label"
+                htmlFor="share-limits-action"
+                style={{ display: 'block', margin: '8px 0 6px' }}
+            >
+                When reached
+            </label>
+            <select
+                id="share-limits-action"
+                className="ui-input"
+                value={action}
+                onChange={(e) => setAction(e.target.value)}
+            >
+                <option value="pause">Pause the torrent</option>
+                <option value="remove">Remove the torrent</option>
+            </select>
+            {error && (
+                <div role="alert" style={{ marginTop: 4, color: '#ff6b6b' }}>
+                    {error}
+                </div>
+            )}
+            <div
+                className="ui-stack"
+                style={{ justifyContent: 'flex-end', marginTop: 12 }}
+            >
+                <Button variant="ghost" onClick={() => save(null)}>
+                    Use global limits
+                </Button>
+                <Button
+                    variant="primary"
+                    loading={saving}
+                    onClick={() => save(limits())}
+                >
+                    Save
+                </Button>
+            </div>
+        </Modal>
+    );
+};
+
+export default ShareLimitsEditor;
@@ -0,0 +1,68 @@
+import React from 'react';
+import { echo } from '../../wailsjs/go/models';
+import { formatRate } from '../utils/torrent';
+
+type Props = {
+    samples: echo.SpeedSample[];
+    height?: number;
+};
+
+const WIDTH = 600;
+
+function line(values: number[], top: number, height: number): string {
+    if (values.length < 2) return '';
+    const step = WIDTH / (values.length - 1);
+    return values
+        .map((v, i) => {
+            const x = (i * step).toFixed(1);
+            const y = (height - (v / top) * height).toFixed(1);
+            return `${x},${y}`;
+        })
+        .join(' ');
+}
+
+// SpeedGraph plots download and upload rates on a shared scale, the peak
+// labelled in the corner.
+export const SpeedGraph: React.FC<Props> = ({ samples, height = 80 }) => {
+    const down = samples.map((s) => s.download);
+    const up = samples.map((s) => s.upload);
+    const top = Math.max(1, ...down, ...up);
+
+    if (samples.length < 2) {
+        return <div className="muted">Not enough samples yet.</div>;
+    }
+    return (
+        <div style={{ position: 'relative' }}>
+            <svg
+                viewBox={`0 0 ${WIDTH} ${height}`}
+                preserveAspectRatio="none"
+                role="img"
+                aria-label="Transfer rates"
+                style={{ width: '100%', height, display: 'block' }}
+            >
+                <polyline
+                    points={line(down, top, height)}
+                    fill="none"
+                    stroke="#4a90d9"
+                    strokeWidth={1.5}
+                    vectorEffect="non-scaling-stroke"
+                />
+                <polyline
+                    points={line(up, top, height)}
+                    fill="none"
+                    stroke="#3fb950"
+                    strokeWidth={1.5}
+                    vectorEffect="non-scaling-stroke"
+                />
+            </svg>
+            <div
+                className="muted"
+                style={{ position: 'absolute', top: 0, right: 4 }}
+            >
+                {formatRate(top)}
+            </div>
+        </div>
+    );
+};
+
+export default SpeedGraph;
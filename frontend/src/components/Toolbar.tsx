@@ -8,6 +8,8 @@ type Props = {
     query: string;
     onQueryChange: (v: string) => void;
     onClearAll?: () => void;
+    // filters are extra controls shown before the search box.
+    filters?: React.ReactNode;
 };
 
 export const Toolbar: React.FC<Props> = ({
@@ -16,6 +18,7 @@ export const Toolbar: React.FC<Props> = ({
     query,
     onQueryChange,
     onClearAll,
+    filters,
 }) => {
     return (
         <div className="toolbar">
@@ -29,6 +32,7 @@ export const Toolbar: React.FC<Props> = ({
                 {totalLabel && <div className="muted">{totalLabel}</div>}
             </div>
             <div className="toolbar-right">
+                {filters}
                 <Input
                     className="control"
                     placeholder="Search name or hash…"
@@ -0,0 +1,172 @@
+import React, { useEffect, useState } from 'react';
+import Button from './primitives/Button';
+import Input from './primitives/Input';
+import Modal from './primitives/Modal';
+import { Overrides, SetOverrides } from '../../wailsjs/go/ui/UI';
+import { echo } from '../../wailsjs/go/models';
+
+type Props = {
+    open: boolean;
+    onOpenChange: (open: boolean) => void;
+    infoHash: number[];
+};
+
+// Blank fields fall back to the global settings.
+const toField = (v?: number, scale = 1) =>
+    v === undefined || v === null ? '' : String(Math.round(v / scale));
+const fromField = (s: string, scale = 1) =>
+    s.trim() === '' ? undefined : Math.max(0, Number(s) || 0) * scale;
+
+const KIB = 1024;
+
+export const TorrentOptionsEditor: React.FC<Props> = ({
+    open,
+    onOpenChange,
+    infoHash,
+}) => {
+    const [current, setCurrent] = useState<echo.Overrides | null>(null);
+    const [maxPeers, setMaxPeers] = useState('');
+    const [down, setDown] = useState('');
+    const [up, setUp] = useState('');
+    const [numWant, setNumWant] = useState('');
+    const [sequential, setSequential] = useState<'' | 'on' | 'off'>('');
+    const [priority, setPriority] = useState('normal');
+    const [error, setError] = useState('');
+    const [saving, setSaving] = useState(false);
+
+    useEffect(() => {
+        if (!open) return;
+        setError('');
+        Overrides(infoHash)
+            .then((o) => {
+                setCurrent(o);
+                setMaxPeers(toField(o.maxPeers));
+                setDown(toField(o.downloadLimit, KIB));
+                setUp(toField(o.uploadLimit, KIB));
+                setNumWant(toField(o.numWant));
+                setSequential(
+                    o.sequential === undefined || o.sequential === null
+                        ? ''
+                        : o.sequential
+                          ? 'on'
+                          : 'off'
+                );
+                setPriority(o.priority || 'normal');
+            })
+            .catch((e) => setError(String(e)));
+    }, [open, infoHash]);
+
+    const save = () => {
+        setSaving(true);
+        setError('');
+        const o = echo.Overrides.createFrom({
+            maxPeers: fromField(maxPeers),
+            downloadLimit: fromField(down, KIB),
+            uploadLimit: fromField(up, KIB),
+            numWant: fromField(numWant),
+            sequential: sequential === '' ? undefined : sequential === 'on',
+            shareLimits: current?.shareLimits,
+            priority: priority === 'normal' ? undefined : priority,
+        });
+        SetOverrides(infoHash, o)
+            .then(() => onOpenChange(false))
+            .catch((e) => setError(String(e)))
+            .finally(() => setSaving(false));
+    };
+
+    return (
+        <Modal open={open} onOpenChange={onOpenChange} title="Torrent options">
+            <div className="muted" style={{ marginBottom: 8 }}>
+                Leave a field blank to use the global setting.
+            </div>
+            <Input
+                label="Max peers"
+                type="number"
+                min={0}
+                value={maxPeers}
+                onChange={(e) => setMaxPeers(e.target.value)}
+            />
+            <div style={{ marginTop: 8 }}>
+                <Input
+                    label="Download limit (KiB/s, 0 for unlimited)"
+                    type="number"
+                    min={0}
+                    value={down}
+                    onChange={(e) => setDown(e.target.value)}
+                />
+            </div>
+            <div style={{ marginTop: 8 }}>
+                <Input
+                    label="Upload limit (KiB/s, 0 for unlimited)"
+                    type="number"
+                    min={0}
+                    value={up}
+                    onChange={(e) => setUp(e.target.value)}
+                />
+            </div>
+            <div style={{ marginTop: 8 }}>
+                <Input
+                    label="Peers to ask trackers for"
+                    type="number"
+                    min={0}
+                    value={numWant}
+                    onChange={(e) => setNumWant(e.target.value)}
+                />
+            </div>
+            <label
+                className="label"
+                htmlFor="torrent-options-sequential"
+                style={{ display: 'block', margin: '8px 0 6px' }}
+            >
+                Sequential download
+            </label>
+            <select
+                id="torrent-options-sequential"
+                className="ui-input"
+                value={sequential}
+                onChange={(e) =>
+                    setSequential(e.target.value as '' | 'on' | 'off')
+                }
+            >
+                <option value="">Global setting</option>
+                <option value="on">On</option>
+                <option value="off">Off</option>
+            </select>
+            <label
+                className="label"
+                htmlFor="torrent-options-priority"
+                style={{ display: 'block', margin: '8px 0 6px' }}
+            >
+                Bandwidth priority
+            </label>
+            <select
+                id="torrent-options-priority"
+                className="ui-input"
+                value={priority}
+                onChange={(e) => setPriority(e.target.value)}
+            >
+                <option value="low">Low</option>
+                <option value="normal">Normal</option>
+                <option value="high">High</option>
+            </select>
+            {error && (
+                <div role="alert" style={{ marginTop: 4, color: '#ff6b6b' }}>
+                    {error}
+                </div>
+            )}
+            <div
+                className="ui-stack"
+                style={{ justifyContent: 'flex-end', marginTop: 12 }}
+            >
+                <Button variant="ghost" onClick={() => onOpenChange(false)}>
+                    Cancel
+                </Button>
+                <Button variant="primary" loading={saving} onClick={save}>
+                    Save
+                </Button>
+            </div>
+        </Modal>
+    );
+};
+
+export default TorrentOptionsEditor;
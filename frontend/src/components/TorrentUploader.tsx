@@ -45,14 +45,12 @@ export const TorrentUploader: React.FC<Props> = ({
         [onSelect]
     );
 
-    const onDrop = useCallback(
-        (e: React.DragEvent<HTMLDivElement>) => {
-            e.preventDefault();
-            setIsDragging(false);
-            handleFiles(e.dataTransfer.files);
-        },
-        [handleFiles]
-    );
+    // Dropped files reach the backend through Wails' native file drop,
+    // which hands them back as a "drop:received" event; see useDrops.
+    const onDrop = useCallback((e: React.DragEvent<HTMLDivElement>) => {
+        e.preventDefault();
+        setIsDragging(false);
+    }, []);
 
     const onDragOver = useCallback((e: React.DragEvent<HTMLDivElement>) => {
         e.preventDefault();
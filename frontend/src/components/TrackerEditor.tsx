@@ -0,0 +1,91 @@
+import React, { useEffect, useState } from 'react';
+import Button from './primitives/Button';
+import Modal from './primitives/Modal';
+import { SaveTorrentFile } from '../../wailsjs/go/ui/UI';
+
+type Props = {
+    open: boolean;
+    onOpenChange: (open: boolean) => void;
+    infoHash: number[];
+    urls: string[];
+};
+
+// One tracker per line; a blank line starts a new tier.
+const toText = (urls: string[]) => urls.join('\n\n');
+
+const toTiers = (text: string): string[][] =>
+    text
+        .split(/\n\s*\n/)
+        .map((tier) =>
+            tier
+                .split('\n')
+                .map((u) => u.trim())
+                .filter(Boolean)
+        )
+        .filter((tier) => tier.length > 0);
+
+export const TrackerEditor: React.FC<Props> = ({
+    open,
+    onOpenChange,
+    infoHash,
+    urls,
+}) => {
+    const [text, setText] = useState(toText(urls));
+    const [error, setError] = useState('');
+    const [saving, setSaving] = useState(false);
+
+    useEffect(() => {
+        if (!open) return;
+        setText(toText(urls));
+        setError('');
+    }, [open, urls]);
+
+    const save = () => {
+        setSaving(true);
+        setError('');
+        SaveTorrentFile(infoHash, toTiers(text))
+            .then((path) => {
+                if (path) onOpenChange(false);
+            })
+            .catch((e) => setError(String(e)))
+            .finally(() => setSaving(false));
+    };
+
+    return (
+        <Modal
+            open={open}
+            onOpenChange={onOpenChange}
+            title="Edit trackers & save .torrent"
+        >
+            <label className="label" htmlFor="tracker-editor-text">
+                One tracker per line, blank line between tiers
+            </label>
+            <textarea
+                id="tracker-editor-text"
+                className="ui-input"
+                rows={10}
+                style={{ width: '100%', marginTop: 6, fontFamily: 'inherit' }}
+                value={text}
+                onChange={(e) => setText(e.target.value)}
+            />
+            {error && (
+                <div role="alert" style={{ marginTop: 4, color: '#ff6b6b' }}>
+                    {error}
+                </div>
+            )}
+            <div
+                className="ui-stack"
+                style={{ justifyContent: 'flex-end', marginTop: 12 }}
+            >
+                <Button variant="ghost" onClick={() => onOpenChange(false)}>
+                    Cancel
+                </Button>
+                <Button variant="primary" loading={saving} onClick={save}>
+                    Save .torrent
+                </Button>
+            </div>
+        </Modal>
+    );
+};
+
+export default TrackerEditor;
@@ -3,6 +3,9 @@ import React, { useEffect, useMemo, useRef, useState } from 'react';
 type Props = {
     urls: string[];
     stats?: Record<string, Stat>;
+    // Announces to one tracker now; leave unset when the torrent is not
+    // running.
+    onReannounce?: (url: string) => void;
 };
 
 type Stat = {
@@ -14,7 +17,11 @@ type Stat = {
     at: number; // timestamp ms
 };
 
-export const TrackersList: React.FC<Props> = ({ urls, stats = {} }) => {
+export const TrackersList: React.FC<Props> = ({
+    urls,
+    stats = {},
+    onReannounce,
+}) => {
     // Column width management for tracker grid (independent of torrent table)
     const headerRef = useRef<HTMLDivElement | null>(null);
     const dragging = useRef<{
@@ -194,7 +201,21 @@ export const TrackersList: React.FC<Props> = ({ urls, stats = {} }) => {
                 </div>
                 {rows.map(({ url, data }) => (
                     <div key={url} className="tracker-grid tracker-grid-row">
-                        <div aria-hidden="true"></div>
+                        {onReannounce ? (
+                            <div>
+                                <button
+                                    type="button"
+                                    className="tree-toggle"
+                                    title="Announce now"
+                                    aria-label={`Announce to ${url} now`}
+                                    onClick={() => onReannounce(url)}
+                                >
+                                    ↻
+                                </button>
+                            </div>
+                        ) : (
+                            <div aria-hidden="true"></div>
+                        )}
                         <div className="wrap" title={url}>
                             {url}
                         </div>
@@ -0,0 +1,146 @@
+import React, { useEffect, useState } from 'react';
+import Button from './primitives/Button';
+import Input from './primitives/Input';
+import Modal from './primitives/Modal';
+import { SetWatchFolders, WatchFolders } from '../../wailsjs/go/ui/UI';
+import { echo } from '../../wailsjs/go/models';
+
+type Props = {
+    open: boolean;
+    onOpenChange: (open: boolean) => void;
+    categories: echo.Category[];
+};
+
+export const WatchFolderManager: React.FC<Props> = ({
+    open,
+    onOpenChange,
+    categories,
+}) => {
+    const [folders, setFolders] = useState<echo.WatchFolder[]>([]);
+    const [dir, setDir] = useState('');
+    const [category, setCategory] = useState('');
+    const [afterAdd, setAfterAdd] = useState('rename');
+    const [error, setError] = useState('');
+
+    useEffect(() => {
+        if (!open) return;
+        setError('');
+        WatchFolders()
+            .then((f) => setFolders(f || []))
+            .catch((e) => setError(String(e)));
+    }, [open]);
+
+    const save = (next: echo.WatchFolder[]) => {
+        setError('');
+        SetWatchFolders(next)
+            .then(() => setFolders(next))
+            .catch((e) => setError(String(e)));
+    };
+
+    const add = (e: React.FormEvent) => {
+        e.preventDefault();
+        if (!dir.trim()) return;
+        const folder = echo.WatchFolder.createFrom({
+            dir: dir.trim(),
+            category,
+            afterAdd,
+        });
+        save([...folders.filter((f) => f.dir !== folder.dir), folder]);
+        setDir('');
+    };
+
+    return (
+        <Modal open={open} onOpenChange={onOpenChange} title="Watch folders">
+            <div className="muted" style={{ marginBottom: 8 }}>
+                .torrent and .magnet files dropped into these folders are added
+                automatically.
+            </div>
+            {folders.map((f) => (
+                <div
+                    key={f.dir}
+                    className="ui-stack"
+                    style={{ justifyContent: 'space-between' }}
+                >
+                    <div>
+                        <div className="mono">{f.dir}</div>
+                        <div className="muted">
+                            {f.category || 'No category'} •{' '}
+                            {f.afterAdd === 'delete'
+                                ? 'delete after adding'
+                                : 'rename after adding'}
+                        </div>
+                    </div>
+                    <Button
+                        variant="ghost"
+                        onClick={() =>
+                            save(folders.filter((x) => x.dir !== f.dir))
+                        }
+                    >
+                        Remove
+                    </Button>
+                </div>
+            ))}
+            <form onSubmit={add} style={{ marginTop: 12 }}>
+                <Input
+                    label="Folder"
+                    value={dir}
+                    onChange={(e) => setDir(e.target.value)}
+                />
+                <label
+                    className="label"
+                    htmlFor="watch-category"
+                    style={{ display: 'block', margin: '8px 0 6px' }}
+                >
+                    Category
+                </label>
+                <select
+                    id="watch-category"
+                    className="ui-input"
+                    value={category}
+                    onChange={(e) => setCategory(e.target.value)}
+                >
+                    <option value="">None</option>
+                    {categories.map((c) => (
+                        <option key={c.name} value={c.name}>
+                            {c.name}
+                        </option>
+                    ))}
+                </select>
+                <label
+                    className="label"
+                    htmlFor="watch-after-add"
+                    style={{ display: 'block', margin: '8px 0 6px' }}
+                >
+                    After adding
+                </label>
+                <select
+                    id="watch-after-add"
+                    className="ui-input"
+                    value={afterAdd}
+                    onChange={(e) => setAfterAdd(e.target.value)}
+                >
+                    <option value="rename">Rename to .added</option>
+                    <option value="delete">Delete the file</option>
+                </select>
+                {error && (
+                    <div
+                        role="alert"
+                        style={{ marginTop: 4, color: '#ff6b6b' }}
+                    >
+                        {error}
+                    </div>
+                )}
+                <div
+                    className="ui-stack"
+                    style={{ justifyContent: 'flex-end', marginTop: 12 }}
+                >
+                    <Button type="submit" variant="primary">
+                        Watch folder
+                    </Button>
+                </div>
+            </form>
+        </Modal>
+    );
+};
+
+export default WatchFolderManager;
@@ -0,0 +1,32 @@
+import { useEffect, useState } from 'react';
+import { EventsOn } from '../../wailsjs/runtime';
+
+export type DHTStats = {
+    nodes: number;
+    goodNodes: number;
+    buckets: number;
+    fullBuckets: number;
+    queries: number;
+    responses: number;
+    timeouts: number;
+    announces: number;
+    storedPeers: number;
+    externalIp: string;
+};
+
+export function useDHTStats() {
+    const [stats, setStats] = useState<DHTStats | null>(null);
+
+    useEffect(() => {
+        const off = EventsOn('dht:stats', (payload: any) => {
+            if (payload) setStats(payload as DHTStats);
+        });
+        return () => {
+            if (typeof off === 'function') off();
+        };
+    }, []);
+
+    return stats;
+}
+
+export default useDHTStats;
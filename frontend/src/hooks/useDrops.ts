@@ -0,0 +1,38 @@
+import { useEffect } from 'react';
+import { EventsOn } from '../../wailsjs/runtime';
+import { DropText } from '../../wailsjs/go/ui/UI';
+import { ui } from '../../wailsjs/go/models';
+
+// useDrops hands onDrop the .torrent files and magnet links dropped on the
+// window once the backend has checked them. Files arrive through Wails'
+// native file drop; dropped text, such as a magnet link dragged from a
+// browser, is passed to the backend here.
+export function useDrops(onDrop: (items: ui.DroppedItem[]) => void) {
+    useEffect(() => {
+        const off = EventsOn('drop:received', (payload: any) => {
+            if (Array.isArray(payload) && payload.length > 0)
+                onDrop(payload.map((p) => ui.DroppedItem.createFrom(p)));
+        });
+        const over = (e: DragEvent) => {
+            if (e.dataTransfer?.types.includes('text/plain'))
+                e.preventDefault();
+        };
+        const drop = (e: DragEvent) => {
+            const dt = e.dataTransfer;
+            if (!dt || dt.files.length > 0) return;
+            const text = dt.getData('text/plain');
+            if (!text) return;
+            e.preventDefault();
+            DropText(text).catch(() => {});
+        };
+        window.addEventListener('dragover', over);
+        window.addEventListener('drop', drop);
+        return () => {
+            if (typeof off === 'function') off();
+            window.removeEventListener('dragover', over);
+            window.removeEventListener('drop', drop);
+        };
+    }, [onDrop]);
+}
+
+export default useDrops;
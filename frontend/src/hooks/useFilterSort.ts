@@ -27,13 +27,13 @@ export function useFilterSort(
             const ra = {
                 name: a.metainfo?.info?.name || '',
                 sizeBytes: a.metainfo?.size || 0,
-                pieces: a.metainfo?.info?.pieces?.length || 0,
+                pieces: a.metainfo?.info?.numPieces || 0,
                 pieceLenBytes: a.metainfo?.info?.pieceLength || 0,
             };
             const rb = {
                 name: b.metainfo?.info?.name || '',
                 sizeBytes: b.metainfo?.size || 0,
-                pieces: b.metainfo?.info?.pieces?.length || 0,
+                pieces: b.metainfo?.info?.numPieces || 0,
                 pieceLenBytes: b.metainfo?.info?.pieceLength || 0,
             };
             let cmp = 0;
@@ -0,0 +1,27 @@
+import { useCallback, useEffect, useState } from 'react';
+import { Categories, TorrentLabels } from '../../wailsjs/go/ui/UI';
+import { echo } from '../../wailsjs/go/models';
+
+// useLabels loads the categories and every torrent's labels, keyed by hex
+// info-hash. Call refresh after changing either.
+export function useLabels() {
+    const [categories, setCategories] = useState<echo.Category[]>([]);
+    const [labels, setLabels] = useState<Record<string, echo.Labels>>({});
+
+    const refresh = useCallback(() => {
+        Categories()
+            .then((c) => setCategories(c || []))
+            .catch(() => {});
+        TorrentLabels()
+            .then((l) => setLabels(l || {}))
+            .catch(() => {});
+    }, []);
+
+    useEffect(() => {
+        refresh();
+    }, [refresh]);
+
+    return { categories, labels, refresh };
+}
+
+export default useLabels;
@@ -0,0 +1,42 @@
+import { useCallback, useEffect, useState } from 'react';
+import { EventsOn } from '../../wailsjs/runtime';
+
+export type Notification = {
+    kind: 'completed' | 'errored' | 'tracker';
+    infoHash: string;
+    name: string;
+    title: string;
+    message: string;
+    time: string;
+};
+
+// How long a notification stays up unless it is dismissed first.
+const shownFor = 8000;
+
+// useNotifications collects the "notification" events the engine sends,
+// dropping each after a while.
+export function useNotifications() {
+    const [shown, setShown] = useState<Notification[]>([]);
+
+    const dismiss = useCallback((n: Notification) => {
+        setShown((prev) => prev.filter((m) => m !== n));
+    }, []);
+
+    useEffect(() => {
+        const timers: ReturnType<typeof setTimeout>[] = [];
+        const off = EventsOn('notification', (payload: any) => {
+            if (!payload) return;
+            const n = payload as Notification;
+            setShown((prev) => [...prev, n]);
+            timers.push(setTimeout(() => dismiss(n), shownFor));
+        });
+        return () => {
+            if (typeof off === 'function') off();
+            timers.forEach(clearTimeout);
+        };
+    }, [dismiss]);
+
+    return { notifications: shown, dismiss };
+}
+
+export default useNotifications;
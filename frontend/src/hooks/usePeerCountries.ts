@@ -0,0 +1,38 @@
+import { useEffect, useState } from 'react';
+import { EventsOn } from '../../wailsjs/runtime';
+import { GetPeerCountries } from '../../wailsjs/go/ui/UI';
+import { peer } from '../../wailsjs/go/models';
+
+// usePeerCountries keeps a torrent's connected peers broken down by
+// country, most peers first. "torrent:countries" events list only the
+// torrents with peers, so one missing from them has none.
+export function usePeerCountries(infoHash?: number[], hex?: string) {
+    const [countries, setCountries] = useState<peer.CountryStats[]>([]);
+    const key = (infoHash || []).join(',');
+
+    useEffect(() => {
+        setCountries([]);
+        if (!infoHash || infoHash.length === 0) return;
+
+        let cancelled = false;
+        GetPeerCountries(infoHash)
+            .then((list) => {
+                if (!cancelled) setCountries(list || []);
+            })
+            .catch(() => {});
+
+        const off = EventsOn('torrent:countries', (payload: any) => {
+            if (!Array.isArray(payload)) return;
+            const entry = payload.find((p: any) => p?.infoHash === hex);
+            setCountries(entry?.countries || []);
+        });
+        return () => {
+            cancelled = true;
+            if (typeof off === 'function') off();
+        };
+    }, [key, hex]);
+
+    return countries;
+}
+
+export default usePeerCountries;
@@ -0,0 +1,51 @@
+import { useCallback, useEffect, useState } from 'react';
+import { EventsOn } from '../../wailsjs/runtime';
+import { PendingMagnets } from '../../wailsjs/go/ui/UI';
+import { echo } from '../../wailsjs/go/models';
+
+// usePendingMagnets follows the magnet links whose metadata is being
+// fetched. onDone is told when one is added as a torrent, or fails.
+export function usePendingMagnets(onDone: (m: echo.MagnetStatus) => void) {
+    const [magnets, setMagnets] = useState<echo.MagnetStatus[]>([]);
+
+    const track = useCallback((m: echo.MagnetStatus) => {
+        setMagnets((prev) =>
+            prev.some((p) => p.infoHash === m.infoHash) ? prev : [...prev, m]
+        );
+    }, []);
+
+    useEffect(() => {
+        PendingMagnets()
+            .then((list) => (list || []).forEach(track))
+            .catch(() => {});
+        const offProgress = EventsOn('metadata:progress', (payload: any) => {
+            if (!payload) return;
+            setMagnets((prev) =>
+                prev.map((m) =>
+                    m.infoHash === payload.infoHash
+                        ? echo.MagnetStatus.createFrom({
+                              ...m,
+                              received: payload.received,
+                              total: payload.total,
+                          })
+                        : m
+                )
+            );
+        });
+        const offDone = EventsOn('magnet:done', (payload: any) => {
+            if (!payload) return;
+            setMagnets((prev) =>
+                prev.filter((m) => m.infoHash !== payload.infoHash)
+            );
+            onDone(payload as echo.MagnetStatus);
+        });
+        return () => {
+            if (typeof offProgress === 'function') offProgress();
+            if (typeof offDone === 'function') offDone();
+        };
+    }, [track, onDone]);
+
+    return { magnets, track };
+}
+
+export default usePendingMagnets;
@@ -0,0 +1,52 @@
+import { useEffect, useState } from 'react';
+import { EventsOn } from '../../wailsjs/runtime';
+import { GetPieceMap } from '../../wailsjs/go/ui/UI';
+
+// usePieceMap keeps a torrent's piece states current: one digit per piece,
+// 0 missing, 1 requested, 2 downloaded, 3 verified. The whole map is
+// fetched once and after every state change, which is when a check may
+// have touched it; "pieces:delta" events patch it in between.
+export function usePieceMap(infoHash?: number[], hex?: string) {
+    const [map, setMap] = useState('');
+    const key = (infoHash || []).join(',');
+
+    useEffect(() => {
+        setMap('');
+        if (!infoHash || infoHash.length === 0) return;
+
+        let cancelled = false;
+        const load = () =>
+            GetPieceMap(infoHash)
+                .then((m) => {
+                    if (!cancelled) setMap(m);
+                })
+                .catch(() => {});
+        load();
+
+        const offState = EventsOn('torrent:state', (payload: any) => {
+            if (payload?.infoHash === hex) load();
+        });
+        const offDelta = EventsOn('pieces:delta', (payload: any) => {
+            if (payload?.infoHash !== hex) return;
+            const indices: number[] = payload?.indices || [];
+            const states: string = payload?.states || '';
+            setMap((prev) => {
+                if (!prev) return prev;
+                const next = prev.split('');
+                indices.forEach((i, j) => {
+                    if (i < next.length) next[i] = states[j] || next[i];
+                });
+                return next.join('');
+            });
+        });
+        return () => {
+            cancelled = true;
+            if (typeof offState === 'function') offState();
+            if (typeof offDelta === 'function') offDelta();
+        };
+    }, [key, hex]);
+
+    return map;
+}
+
+export default usePieceMap;
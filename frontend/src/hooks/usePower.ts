@@ -0,0 +1,24 @@
+import { useEffect, useState } from 'react';
+import { EventsOn } from '../../wailsjs/runtime';
+import { Power } from '../../wailsjs/go/ui/UI';
+import { echo } from '../../wailsjs/go/models';
+
+export function usePower() {
+    const [status, setStatus] = useState<echo.PowerStatus | null>(null);
+
+    useEffect(() => {
+        Power()
+            .then(setStatus)
+            .catch(() => {});
+        const off = EventsOn('session:power', (payload: any) => {
+            if (payload) setStatus(payload as echo.PowerStatus);
+        });
+        return () => {
+            if (typeof off === 'function') off();
+        };
+    }, []);
+
+    return status;
+}
+
+export default usePower;
@@ -0,0 +1,24 @@
+import { useEffect, useState } from 'react';
+import { EventsOn } from '../../wailsjs/runtime';
+import { SessionStats } from '../../wailsjs/go/ui/UI';
+import { echo } from '../../wailsjs/go/models';
+
+export function useSessionStats() {
+    const [stats, setStats] = useState<echo.SessionStats | null>(null);
+
+    useEffect(() => {
+        SessionStats()
+            .then(setStats)
+            .catch(() => {});
+        const off = EventsOn('session:stats', (payload: any) => {
+            if (payload) setStats(payload as echo.SessionStats);
+        });
+        return () => {
+            if (typeof off === 'function') off();
+        };
+    }, []);
+
+    return stats;
+}
+
+export default useSessionStats;
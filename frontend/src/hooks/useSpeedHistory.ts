@@ -0,0 +1,43 @@
+import { useEffect, useState } from 'react';
+import { GetSessionSpeeds, GetTorrentSpeeds } from '../../wailsjs/go/ui/UI';
+import { echo } from '../../wailsjs/go/models';
+
+export type SpeedRange = 'recent' | 'day';
+
+// The backend keeps the history, so a reloaded window picks the graph up
+// where it was; poll it rather than collecting samples here.
+const POLL_MS = 2000;
+
+// useSpeedHistory returns a torrent's rate history, or the session's when
+// no info-hash is given.
+export function useSpeedHistory(
+    infoHash?: number[],
+    range: SpeedRange = 'recent'
+) {
+    const [samples, setSamples] = useState<echo.SpeedSample[]>([]);
+    const key = (infoHash || []).join(',');
+
+    useEffect(() => {
+        let cancelled = false;
+        const load = () =>
+            (infoHash && infoHash.length > 0
+                ? GetTorrentSpeeds(infoHash, range)
+                : GetSessionSpeeds(range)
+            )
+                .then((s) => {
+                    if (!cancelled) setSamples(s || []);
+                })
+                .catch(() => {});
+        setSamples([]);
+        load();
+        const id = window.setInterval(load, POLL_MS);
+        return () => {
+            cancelled = true;
+            window.clearInterval(id);
+        };
+    }, [key, range]);
+
+    return samples;
+}
+
+export default useSpeedHistory;
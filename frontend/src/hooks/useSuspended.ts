@@ -0,0 +1,24 @@
+import { useEffect, useState } from 'react';
+import { EventsOn } from '../../wailsjs/runtime';
+import { Suspended } from '../../wailsjs/go/ui/UI';
+
+// useSuspended reports whether a pause window is holding every transfer.
+export function useSuspended() {
+    const [suspended, setSuspended] = useState(false);
+
+    useEffect(() => {
+        Suspended()
+            .then(setSuspended)
+            .catch(() => {});
+        const off = EventsOn('session:suspended', (payload: any) => {
+            setSuspended(Boolean(payload));
+        });
+        return () => {
+            if (typeof off === 'function') off();
+        };
+    }, []);
+
+    return suspended;
+}
+
+export default useSuspended;
@@ -0,0 +1,43 @@
+import { useCallback, useEffect, useState } from 'react';
+import { GetTorrentDetails } from '../../wailsjs/go/ui/UI';
+import { torrent } from '../../wailsjs/go/models';
+
+// Per-file progress and peer lists change too often to push; poll while
+// the details pane is open.
+const POLL_MS = 2000;
+
+export function useTorrentDetails(infoHash?: number[]) {
+    const [details, setDetails] = useState<torrent.Details | null>(null);
+    const key = (infoHash || []).join(',');
+
+    const [reloads, setReloads] = useState(0);
+
+    useEffect(() => {
+        setDetails(null);
+    }, [key]);
+
+    useEffect(() => {
+        if (!infoHash || infoHash.length === 0) return;
+
+        let cancelled = false;
+        const load = () =>
+            GetTorrentDetails(infoHash)
+                .then((d) => {
+                    if (!cancelled) setDetails(d);
+                })
+                .catch(() => {});
+        load();
+        const id = window.setInterval(load, POLL_MS);
+        return () => {
+            cancelled = true;
+            window.clearInterval(id);
+        };
+    }, [key, reloads]);
+
+    // reload fetches the details again straight away, as after a change.
+    const reload = useCallback(() => setReloads((n) => n + 1), []);
+
+    return { details, reload };
+}
+
+export default useTorrentDetails;
@@ -9,12 +9,20 @@ import { EventsOn } from '../../wailsjs/runtime';
 
 export type Peer = {
     id?: string;
+    infoHash?: string; // hex info-hash of the torrent the peer is in
     addr: string;
     client?: string;
     at: number; // connected timestamp
     cc?: string; // ISO 3166-1 alpha-2, if known
     flag?: string; // emoji flag if provided
     country?: string; // localized country name if provided
+    asn?: number; // autonomous system the peer's network is, if known
+    org?: string; // and the organization running it
+    progress?: number; // percentage of the torrent the peer has
+    seed?: boolean;
+    downRate?: number; // bytes per second
+    upRate?: number;
+    flags?: string; // D/d/U/u/K/? connection state letters
     lastMsg?: string; // last message type
     lastMsgAt?: number;
     removing?: boolean;
@@ -36,6 +44,11 @@ function deriveId(payload: any): string {
     return id;
 }
 
+// The same address may be a peer in several torrents.
+function peerKey(payload: any, id: string): string {
+    return `${payload?.infoHash ?? ''}/${id}`;
+}
+
 function deriveAddr(payload: any): string {
     if (typeof payload === 'string') return payload;
     const addr =
@@ -84,118 +97,145 @@ export function PeersProvider({ children }: { children: React.ReactNode }) {
     const [peers, setPeers] = useState<Record<string, Peer>>({});
     const timersRef = React.useRef<Map<string, number>>(new Map());
     const addTimersRef = React.useRef<Map<string, number>>(new Map());
+    // peersRef mirrors the state so handlers can work out timers from the
+    // peers as they are, outside a state updater.
+    const peersRef = React.useRef<Record<string, Peer>>({});
+    const update = (
+        fn: (prev: Record<string, Peer>) => Record<string, Peer>
+    ) => {
+        peersRef.current = fn(peersRef.current);
+        setPeers(peersRef.current);
+    };
 
     useEffect(() => {
-        const offStart = EventsOn('peers:started', (payload: any) => {
+        // Each torrent reports all its peers about once a second; a peer
+        // missing from its torrent's latest snapshot has disconnected.
+        const offSnapshot = EventsOn('peers:snapshot', (payload: any) => {
             try {
-                const id = deriveId(payload);
-                const addr = deriveAddr(payload);
-                if (!addr) return;
-                const key = id || addr;
-                // Cancel any pending removal if peer re-starts
-                const t = timersRef.current.get(key);
-                if (t) {
-                    clearTimeout(t);
-                    timersRef.current.delete(key);
+                const infoHash = (payload?.infoHash || '').toString();
+                const list: any[] = Array.isArray(payload?.peers)
+                    ? payload.peers
+                    : [];
+                const seen = new Set<string>();
+                const added: string[] = [];
+                update((prev) => {
+                    const next = { ...prev };
+                    for (const p of list) {
+                        const addr = deriveAddr(p);
+                        if (!addr) continue;
+                        const key = peerKey({ infoHash }, addr);
+                        seen.add(key);
+                        // Cancel any pending removal if the peer is back
+                        const t = timersRef.current.get(key);
+                        if (t) {
+                            clearTimeout(t);
+                            timersRef.current.delete(key);
+                        }
+                        const old = prev[key];
+                        if (!old || old.removing) added.push(key);
+                        const cc = deriveCC(p, addr);
+                        next[key] = {
+                            ...old,
+                            infoHash: infoHash || undefined,
+                            addr,
+                            client: deriveClient(p),
+                            at: old && !old.removing ? old.at : Date.now(),
+                            cc,
+                            flag: deriveFlag(p, cc),
+                            country:
+                                (p?.country || '').toString() || undefined,
+                            asn: Number(p?.asn) || undefined,
+                            org: (p?.org || '').toString() || undefined,
+                            progress: Number(p?.progress) || 0,
+                            seed: !!p?.seed,
+                            downRate: Number(p?.downRate) || 0,
+                            upRate: Number(p?.upRate) || 0,
+                            flags: (p?.flags || '').toString(),
+                            removing: false,
+                            adding: old?.adding || !old || old.removing,
+                        };
+                    }
+                    for (const key of Object.keys(prev)) {
+                        const peer = prev[key]!;
+                        if (peer.infoHash !== (infoHash || undefined)) {
+                            continue;
+                        }
+                        if (seen.has(key) || peer.removing) continue;
+                        // Pulse as removing, then drop after a delay
+                        next[key] = { ...peer, removing: true };
+                        const tid = window.setTimeout(() => {
+                            update((cur) => {
+                                const rest = { ...cur };
+                                delete rest[key];
+                                return rest;
+                            });
+                            timersRef.current.delete(key);
+                        }, 3200);
+                        timersRef.current.set(key, tid);
+                    }
+                    return next;
+                });
+                // Clear the added pulse shortly after
+                for (const key of added) {
+                    const oldAdd = addTimersRef.current.get(key);
+                    if (oldAdd) clearTimeout(oldAdd);
+                    const addTid = window.setTimeout(() => {
+                        update((prev) => ({
+                            ...prev,
+                            [key]: prev[key]
+                                ? { ...prev[key]!, adding: false }
+                                : prev[key],
+                        }));
+                        addTimersRef.current.delete(key);
+                    }, 900);
+                    addTimersRef.current.set(key, addTid);
                 }
-                setPeers((prev) => ({
-                    ...prev,
-                    [key]: {
-                        id: id || undefined,
-                        addr,
-                        client: deriveClient(payload),
-                        at: Date.now(),
-                        cc: deriveCC(payload, addr),
-                        flag: deriveFlag(payload, deriveCC(payload, addr)),
-                        country:
-                            (payload?.country || '').toString() || undefined,
-                        removing: false,
-                        lastMsg: prev[key]?.lastMsg,
-                        lastMsgAt: prev[key]?.lastMsgAt,
-                        // mark as added for a brief pulse
-                        ...(prev[key]?.adding ? {} : { adding: true }),
-                    },
-                }));
-                // Clear any previous add timer, then set a new one to remove the added pulse
-                const oldAdd = addTimersRef.current.get(key);
-                if (oldAdd) clearTimeout(oldAdd);
-                const addTid = window.setTimeout(() => {
-                    setPeers((prev) => ({
-                        ...prev,
-                        [key]: prev[key]
-                            ? { ...prev[key]!, adding: false }
-                            : prev[key],
-                    }));
-                    addTimersRef.current.delete(key);
-                }, 900);
-                addTimersRef.current.set(key, addTid);
             } catch {}
         });
-        const offStop = EventsOn('peers:stopped', (payload: any) => {
+        // Messages arrive batched a few times a second, at most one entry
+        // per peer with the last message type and per-type counts.
+        const offMsg = EventsOn('peer:msgs', (batch: any) => {
             try {
-                const addr = deriveAddr(payload);
-                const id = deriveId(payload);
-                const key = id || addr;
-                if (!key) return;
-                // Mark as removing with a pulse, then remove after delay
-                setPeers((prev) => ({
-                    ...prev,
-                    [key]: prev[key]
-                        ? { ...prev[key]!, removing: true }
-                        : { addr, at: Date.now(), removing: true },
-                }));
-                const tid = window.setTimeout(() => {
-                    setPeers((prev) => {
-                        const next = { ...prev } as Record<string, Peer>;
-                        delete next[key];
-                        return next;
-                    });
-                    timersRef.current.delete(key);
-                }, 3200);
-                const old = timersRef.current.get(key);
-                if (old) clearTimeout(old);
-                timersRef.current.set(key, tid);
-            } catch {}
-        });
-        const offMsg = EventsOn('peer:msg', (payload: any) => {
-            try {
-                const addr = deriveAddr(payload);
-                const id = deriveId(payload);
-                const key = id || addr;
-                const typ = (payload?.type || '').toString();
-                if (!key || !typ) return;
-                setPeers((prev) => {
-                    const peek = prev[key];
-                    const base: Peer = peek || {
-                        id: id || undefined,
-                        addr,
-                        at: Date.now(),
-                    };
-                    return {
-                        ...prev,
-                        [key]: {
+                const list: any[] = Array.isArray(batch) ? batch : [];
+                if (list.length === 0) return;
+                update((prev) => {
+                    const next = { ...prev };
+                    for (const payload of list) {
+                        const addr = deriveAddr(payload);
+                        const id = deriveId(payload);
+                        const key = peerKey(payload, id || addr);
+                        const typ = (payload?.type || '').toString();
+                        if (!key || !typ) continue;
+                        const base: Peer = next[key] || {
+                            id: id || undefined,
+                            infoHash: payload?.infoHash || undefined,
+                            addr,
+                            at: Date.now(),
+                        };
+                        const cc = base.cc || deriveCC(payload, addr);
+                        next[key] = {
                             ...base,
-                            cc: base.cc || deriveCC(payload, addr),
-                            flag:
-                                base.flag ||
-                                deriveFlag(
-                                    payload,
-                                    base.cc || deriveCC(payload, addr)
-                                ),
+                            cc,
+                            flag: base.flag || deriveFlag(payload, cc),
                             country:
                                 base.country ||
                                 (payload?.country || '').toString() ||
                                 undefined,
+                            asn: base.asn || Number(payload?.asn) || undefined,
+                            org:
+                                base.org ||
+                                (payload?.org || '').toString() ||
+                                undefined,
                             lastMsg: typ,
                             lastMsgAt: Date.now(),
-                        },
-                    };
+                        };
+                    }
+                    return next;
                 });
             } catch {}
         });
         return () => {
-            if (typeof offStart === 'function') offStart();
-            if (typeof offStop === 'function') offStop();
+            if (typeof offSnapshot === 'function') offSnapshot();
             if (typeof offMsg === 'function') offMsg();
             // Clear any pending timers
             timersRef.current.forEach((id) => clearTimeout(id));
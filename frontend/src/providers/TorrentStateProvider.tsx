@@ -0,0 +1,154 @@
+import React, {
+    createContext,
+    useContext,
+    useEffect,
+    useMemo,
+    useState,
+} from 'react';
+import { EventsOn } from '../../wailsjs/runtime';
+import { ListTorrents } from '../../wailsjs/go/ui/UI';
+
+export type TorrentState =
+    | 'checking'
+    | 'downloading'
+    | 'seeding'
+    | 'paused'
+    | 'queued'
+    | 'errored'
+    | 'moving';
+
+export type TorrentStatus = {
+    state: TorrentState;
+    error?: string;
+    // Percent complete.
+    progress?: number;
+    // Smoothed by the engine, in bytes per second.
+    downloadRate?: number;
+    uploadRate?: number;
+    // Nanoseconds; -1 when unknown.
+    eta?: number;
+    // Zero-based place in the queue.
+    queuePosition?: number;
+    // Nanoseconds until an errored torrent is retried; unset when no
+    // retry is planned.
+    retryIn?: number;
+    peers?: number;
+    seeds?: number;
+    ratio?: number;
+    // Rate limits in effect, in bytes per second; zero is unlimited.
+    downloadLimit?: number;
+    uploadLimit?: number;
+};
+
+type StatusPayload = {
+    infoHash: string;
+    state: TorrentState;
+    error?: string;
+    progress: number;
+    downloadRate: number;
+    uploadRate: number;
+    eta: number;
+    queuePosition: number;
+    retryIn?: number;
+    peers: number;
+    seeds: number;
+    ratio: number;
+    downloadLimit: number;
+    uploadLimit: number;
+};
+
+const fromPayload = (s: StatusPayload): TorrentStatus => ({
+    state: s.state,
+    error: s.error || undefined,
+    progress: s.progress,
+    downloadRate: s.downloadRate,
+    uploadRate: s.uploadRate,
+    eta: s.eta,
+    queuePosition: s.queuePosition,
+    retryIn: s.retryIn || undefined,
+    peers: s.peers,
+    seeds: s.seeds,
+    ratio: s.ratio,
+    downloadLimit: s.downloadLimit,
+    uploadLimit: s.uploadLimit,
+});
+
+// States in which the torrent is connected to peers.
+export const isActive = (s?: TorrentState) =>
+    s === 'downloading' || s === 'seeding';
+
+type Ctx = {
+    states: Record<string, TorrentStatus>;
+};
+
+const TorrentStateCtx = createContext<Ctx | null>(null);
+
+export function TorrentStateProvider({
+    children,
+}: {
+    children: React.ReactNode;
+}) {
+    const [states, setStates] = useState<Record<string, TorrentStatus>>({});
+
+    useEffect(() => {
+        // Torrents restored from the last session changed state before we
+        // subscribed; start from a snapshot.
+        ListTorrents()
+            .then((snapshot) =>
+                setStates((prev) => {
+                    const next = { ...prev };
+                    for (const s of snapshot || []) {
+                        if (!next[s.infoHash])
+                            next[s.infoHash] = fromPayload(
+                                s as StatusPayload
+                            );
+                    }
+                    return next;
+                })
+            )
+            .catch(() => {});
+
+        const off = EventsOn('torrent:state', (payload: any) => {
+            const hash = String(payload?.infoHash ?? '');
+            if (!hash) return;
+            setStates((prev) => ({
+                ...prev,
+                [hash]: {
+                    ...prev[hash],
+                    state: payload?.to as TorrentState,
+                    error: payload?.error || undefined,
+                },
+            }));
+        });
+        // Periodic snapshots carry the rates and ETA.
+        const offStatus = EventsOn('torrent:status', (payload: any) => {
+            if (!Array.isArray(payload)) return;
+            setStates((prev) => {
+                const next = { ...prev };
+                for (const s of payload as StatusPayload[])
+                    next[s.infoHash] = fromPayload(s);
+                return next;
+            });
+        });
+        return () => {
+            if (typeof off === 'function') off();
+            if (typeof offStatus === 'function') offStatus();
+        };
+    }, []);
+
+    const value = useMemo<Ctx>(() => ({ states }), [states]);
+    return (
+        <TorrentStateCtx.Provider value={value}>
+            {children}
+        </TorrentStateCtx.Provider>
+    );
+}
+
+export function useTorrentStates() {
+    const ctx = useContext(TorrentStateCtx);
+    if (!ctx)
+        throw new Error(
+            'useTorrentStates must be used within TorrentStateProvider'
+        );
+    return ctx;
+}
@@ -17,7 +17,8 @@ export type TrackerStat = {
 };
 
 type Ctx = {
-    stats: Record<string, TrackerStat>;
+    // Keyed by the torrent's hex info-hash, then the normalized URL.
+    stats: Record<string, Record<string, TrackerStat>>;
     normalize: (u: string) => string;
 };
 
@@ -41,28 +42,37 @@ export function TrackerStatsProvider({
 }: {
     children: React.ReactNode;
 }) {
-    const [stats, setStats] = useState<Record<string, TrackerStat>>({});
+    const [stats, setStats] = useState<
+        Record<string, Record<string, TrackerStat>>
+    >({});
 
     useEffect(() => {
         const off = EventsOn('tracker:announce', (payload: any) => {
             try {
                 const url = String(payload?.tracker ?? '');
                 if (!url) return;
+                const hash = String(payload?.infoHash ?? '');
                 const key = normalize(url);
                 const intervalNs = Number(payload?.interval ?? 0);
                 const minIntervalNs = Number(payload?.minInterval ?? 0);
                 setStats((prev) => ({
                     ...prev,
-                    [key]: {
-                        seeders: Number(payload?.seeders ?? 0),
-                        leechers: Number(payload?.leechers ?? 0),
-                        peersCount: Number(payload?.peersCount ?? 0),
-                        intervalSec: Math.max(0, Math.round(intervalNs / 1e9)),
-                        minIntervalSec: Math.max(
-                            0,
-                            Math.round(minIntervalNs / 1e9)
-                        ),
-                        at: Date.now(),
+                    [hash]: {
+                        ...prev[hash],
+                        [key]: {
+                            seeders: Number(payload?.seeders ?? 0),
+                            leechers: Number(payload?.leechers ?? 0),
+                            peersCount: Number(payload?.peersCount ?? 0),
+                            intervalSec: Math.max(
+                                0,
+                                Math.round(intervalNs / 1e9)
+                            ),
+                            minIntervalSec: Math.max(
+                                0,
+                                Math.round(minIntervalNs / 1e9)
+                            ),
+                            at: Date.now(),
+                        },
                     },
                 }));
             } catch {}
@@ -26,7 +26,7 @@ export function toRow(t: Models.Torrent): TorrentRow {
     const name = t.metainfo?.info?.name || 'Unnamed torrent';
     const sizeBytes = t.metainfo?.size || 0;
     const size = formatBytes(sizeBytes);
-    const pieces = t.metainfo?.info?.pieces?.length || 0;
+    const pieces = t.metainfo?.info?.numPieces || 0;
     const pieceLenBytes = t.metainfo?.info?.pieceLength || 0;
     const pieceLen = formatBytes(pieceLenBytes);
     const trackers = t.metainfo?.announceUrls?.length || 0;
@@ -55,3 +55,25 @@ export function formatBytes(bytes: number, decimals = 2): string {
     const fixed = i === 0 ? 0 : dm; // no decimals for bytes
     return `${value.toFixed(fixed)} ${sizes[i]}`;
 }
+
+export function formatRate(bytesPerSec?: number): string {
+    return `${formatBytes(Math.round(bytesPerSec || 0))}/s`;
+}
+
+// formatETA renders a duration in nanoseconds, as the engine reports it.
+// formatLimit shows a rate limit, where zero or unset is unlimited.
+export function formatLimit(bytesPerSec?: number): string {
+    return bytesPerSec ? formatRate(bytesPerSec) : '∞';
+}
+
+export function formatETA(ns?: number): string {
+    if (ns === undefined || ns < 0) return '∞';
+    const secs = Math.round(ns / 1e9);
+    if (secs === 0) return 'done';
+    const h = Math.floor(secs / 3600);
+    const m = Math.floor((secs % 3600) / 60);
+    const s = secs % 60;
+    if (h > 0) return `${h}h ${m}m`;
+    if (m > 0) return `${m}m ${s}s`;
+    return `${s}s`;
+}
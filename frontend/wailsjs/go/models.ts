@@ -1,4 +1,1289 @@
+export namespace echo {
+    export class AddOptions {
+        category: string;
+        tags: string[];
+        savePath: string;
+        paused: boolean;
+        sequential: boolean;
+        files: number[];
+
+        static createFrom(source: any = {}) {
+            return new AddOptions(source);
+        }
+
+        constructor(source: any = {}) {
+            if ('string' === typeof source) source = JSON.parse(source);
+            this.category = source['category'];
+            this.tags = source['tags'];
+            this.savePath = source['savePath'];
+            this.paused = source['paused'];
+            this.sequential = source['sequential'];
+            this.files = source['files'];
+        }
+    }
+    export class AddRequest {
+        data: number[];
+        path: string;
+        url: string;
+        magnet: string;
+        options: AddOptions;
+
+        static createFrom(source: any = {}) {
+            return new AddRequest(source);
+        }
+
+        constructor(source: any = {}) {
+            if ('string' === typeof source) source = JSON.parse(source);
+            this.data = source['data'];
+            this.path = source['path'];
+            this.url = source['url'];
+            this.magnet = source['magnet'];
+            this.options = this.convertValues(source['options'], AddOptions);
+        }
+
+        convertValues(a: any, classs: any, asMap: boolean = false): any {
+            if (!a) {
+                return a;
+            }
+            if (a.slice && a.map) {
+                return (a as any[]).map((elem) =>
+                    this.convertValues(elem, classs)
+                );
+            } else if ('object' === typeof a) {
+                if (asMap) {
+                    for (const key of Object.keys(a)) {
+                        a[key] = new classs(a[key]);
+                    }
+                    return a;
+                }
+                return new classs(a);
+            }
+            return a;
+        }
+    }
+    export class AddResult {
+        torrent: torrent.Torrent;
+        error: string;
+        duplicate: boolean;
+
+        static createFrom(source: any = {}) {
+            return new AddResult(source);
+        }
+
+        constructor(source: any = {}) {
+            if ('string' === typeof source) source = JSON.parse(source);
+            this.torrent = this.convertValues(
+                source['torrent'],
+                torrent.Torrent
+            );
+            this.error = source['error'];
+            this.duplicate = source['duplicate'];
+        }
+
+        convertValues(a: any, classs: any, asMap: boolean = false): any {
+            if (!a) {
+                return a;
+            }
+            if (a.slice && a.map) {
+                return (a as any[]).map((elem) =>
+                    this.convertValues(elem, classs)
+                );
+            } else if ('object' === typeof a) {
+                if (asMap) {
+                    for (const key of Object.keys(a)) {
+                        a[key] = new classs(a[key]);
+                    }
+                    return a;
+                }
+                return new classs(a);
+            }
+            return a;
+        }
+    }
+    export class Addresses {
+        local: string[];
+        external: string;
+
+        static createFrom(source: any = {}) {
+            return new Addresses(source);
+        }
+
+        constructor(source: any = {}) {
+            if ('string' === typeof source) source = JSON.parse(source);
+            this.local = source['local'];
+            this.external = source['external'];
+        }
+    }
+    export class Category {
+        name: string;
+        savePath: string;
+        quota: number;
+
+        static createFrom(source: any = {}) {
+            return new Category(source);
+        }
+
+        constructor(source: any = {}) {
+            if ('string' === typeof source) source = JSON.parse(source);
+            this.name = source['name'];
+            this.savePath = source['savePath'];
+            this.quota = source['quota'];
+        }
+    }
+    export class CreateOptions {
+        path: string;
+        pieceLength: number;
+        trackers: string[];
+        webSeeds: string[];
+        comment: string;
+        private: boolean;
+        source: string;
+
+        static createFrom(source: any = {}) {
+            return new CreateOptions(source);
+        }
+
+        constructor(source: any = {}) {
+            if ('string' === typeof source) source = JSON.parse(source);
+            this.path = source['path'];
+            this.pieceLength = source['pieceLength'];
+            this.trackers = source['trackers'];
+            this.webSeeds = source['webSeeds'];
+            this.comment = source['comment'];
+            this.private = source['private'];
+            this.source = source['source'];
+        }
+    }
+    export class Feed {
+        url: string;
+        interval: number;
+        rules: rss.Rule[];
+
+        static createFrom(source: any = {}) {
+            return new Feed(source);
+        }
+
+        constructor(source: any = {}) {
+            if ('string' === typeof source) source = JSON.parse(source);
+            this.url = source['url'];
+            this.interval = source['interval'];
+            this.rules = this.convertValues(source['rules'], rss.Rule);
+        }
+
+        convertValues(a: any, classs: any, asMap: boolean = false): any {
+            if (!a) {
+                return a;
+            }
+            if (a.slice && a.map) {
+                return (a as any[]).map((elem) =>
+                    this.convertValues(elem, classs)
+                );
+            } else if ('object' === typeof a) {
+                if (asMap) {
+                    for (const key of Object.keys(a)) {
+                        a[key] = new classs(a[key]);
+                    }
+                    return a;
+                }
+                return new classs(a);
+            }
+            return a;
+        }
+    }
+    export class GlobalSettings {
+        downloadDir: string;
+        torrentsDir: string;
+        dhtPort: number;
+        listenPort: number;
+        downloadLimit: number;
+        uploadLimit: number;
+        maxConnections: number;
+        maxActiveDownloads: number;
+        maxActiveSeeds: number;
+        proxy: string;
+        encryption: string;
+        notifications: NotifySettings;
+        crawlDHT: boolean;
+        logLevels: Record<string, string>;
+        debugAddr: string;
+        fingerprint: torrent.Fingerprint;
+        reconnectOnIpChange: boolean;
+        dhtReadOnly: boolean;
+
+        static createFrom(source: any = {}) {
+            return new GlobalSettings(source);
+        }
+
+        constructor(source: any = {}) {
+            if ('string' === typeof source) source = JSON.parse(source);
+            this.downloadDir = source['downloadDir'];
+            this.torrentsDir = source['torrentsDir'];
+            this.dhtPort = source['dhtPort'];
+            this.listenPort = source['listenPort'];
+            this.downloadLimit = source['downloadLimit'];
+            this.uploadLimit = source['uploadLimit'];
+            this.maxConnections = source['maxConnections'];
+            this.maxActiveDownloads = source['maxActiveDownloads'];
+            this.maxActiveSeeds = source['maxActiveSeeds'];
+            this.proxy = source['proxy'];
+            this.encryption = source['encryption'];
+            this.notifications = this.convertValues(
+                source['notifications'],
+                NotifySettings
+            );
+            this.crawlDHT = source['crawlDHT'];
+            this.logLevels = source['logLevels'];
+            this.debugAddr = source['debugAddr'];
+            this.fingerprint = this.convertValues(
+                source['fingerprint'],
+                torrent.Fingerprint
+            );
+            this.reconnectOnIpChange = source['reconnectOnIpChange'];
+            this.dhtReadOnly = source['dhtReadOnly'];
+        }
+
+        convertValues(a: any, classs: any, asMap: boolean = false): any {
+            if (!a) {
+                return a;
+            }
+            if (a.slice && a.map) {
+                return (a as any[]).map((elem) =>
+                    this.convertValues(elem, classs)
+                );
+            } else if ('object' === typeof a) {
+                if (asMap) {
+                    for (const key of Object.keys(a)) {
+                        a[key] = new classs(a[key]);
+                    }
+                    return a;
+                }
+                return new classs(a);
+            }
+            return a;
+        }
+    }
+    export class HistoryEntry {
+        infoHash: string;
+        name: string;
+        size: number;
+        category?: string;
+        // Go type: time
+        completed: any;
+        ratio: number;
+
+        static createFrom(source: any = {}) {
+            return new HistoryEntry(source);
+        }
+
+        constructor(source: any = {}) {
+            if ('string' === typeof source) source = JSON.parse(source);
+            this.infoHash = source['infoHash'];
+            this.name = source['name'];
+            this.size = source['size'];
+            this.category = source['category'];
+            this.completed = this.convertValues(source['completed'], null);
+            this.ratio = source['ratio'];
+        }
+
+        convertValues(a: any, classs: any, asMap: boolean = false): any {
+            if (!a) {
+                return a;
+            }
+            if (a.slice && a.map) {
+                return (a as any[]).map((elem) =>
+                    this.convertValues(elem, classs)
+                );
+            } else if ('object' === typeof a) {
+                if (asMap) {
+                    for (const key of Object.keys(a)) {
+                        a[key] = new classs(a[key]);
+                    }
+                    return a;
+                }
+                return new classs(a);
+            }
+            return a;
+        }
+    }
+    export class ImportResult {
+        added: number;
+        skipped: number;
+        errors: string[];
+
+        static createFrom(source: any = {}) {
+            return new ImportResult(source);
+        }
+
+        constructor(source: any = {}) {
+            if ('string' === typeof source) source = JSON.parse(source);
+            this.added = source['added'];
+            this.skipped = source['skipped'];
+            this.errors = source['errors'];
+        }
+    }
+    export class Indexer {
+        name: string;
+        kind?: string;
+        url: string;
+        apiKey: string;
+        disabled?: boolean;
+
+        static createFrom(source: any = {}) {
+            return new Indexer(source);
+        }
+
+        constructor(source: any = {}) {
+            if ('string' === typeof source) source = JSON.parse(source);
+            this.name = source['name'];
+            this.kind = source['kind'];
+            this.url = source['url'];
+            this.apiKey = source['apiKey'];
+            this.disabled = source['disabled'];
+        }
+    }
+    export class Labels {
+        category: string;
+        tags: string[];
+
+        static createFrom(source: any = {}) {
+            return new Labels(source);
+        }
+
+        constructor(source: any = {}) {
+            if ('string' === typeof source) source = JSON.parse(source);
+            this.category = source['category'];
+            this.tags = source['tags'];
+        }
+    }
+    export class MagnetStatus {
+        infoHash: string;
+        name: string;
+        magnet: string;
+        received: number;
+        total: number;
+        error?: string;
+
+        static createFrom(source: any = {}) {
+            return new MagnetStatus(source);
+        }
+
+        constructor(source: any = {}) {
+            if ('string' === typeof source) source = JSON.parse(source);
+            this.infoHash = source['infoHash'];
+            this.name = source['name'];
+            this.magnet = source['magnet'];
+            this.received = source['received'];
+            this.total = source['total'];
+            this.error = source['error'];
+        }
+    }
+    export class NotifySettings {
+        completed: boolean;
+        errored: boolean;
+        tracker: boolean;
+        search: boolean;
+        native: boolean;
+
+        static createFrom(source: any = {}) {
+            return new NotifySettings(source);
+        }
+
+        constructor(source: any = {}) {
+            if ('string' === typeof source) source = JSON.parse(source);
+            this.completed = source['completed'];
+            this.errored = source['errored'];
+            this.tracker = source['tracker'];
+            this.search = source['search'];
+            this.native = source['native'];
+        }
+    }
+    export class Overrides {
+        maxPeers?: number;
+        downloadLimit?: number;
+        uploadLimit?: number;
+        numWant?: number;
+        sequential?: boolean;
+        shareLimits?: ShareLimits;
+        priority?: string;
+
+        static createFrom(source: any = {}) {
+            return new Overrides(source);
+        }
+
+        constructor(source: any = {}) {
+            if ('string' === typeof source) source = JSON.parse(source);
+            this.maxPeers = source['maxPeers'];
+            this.downloadLimit = source['downloadLimit'];
+            this.uploadLimit = source['uploadLimit'];
+            this.numWant = source['numWant'];
+            this.sequential = source['sequential'];
+            this.shareLimits = this.convertValues(
+                source['shareLimits'],
+                ShareLimits
+            );
+            this.priority = source['priority'];
+        }
+
+        convertValues(a: any, classs: any, asMap: boolean = false): any {
+            if (!a) {
+                return a;
+            }
+            if (a.slice && a.map) {
+                return (a as any[]).map((elem) =>
+                    this.convertValues(elem, classs)
+                );
+            } else if ('object' === typeof a) {
+                if (asMap) {
+                    for (const key of Object.keys(a)) {
+                        a[key] = new classs(a[key]);
+                    }
+                    return a;
+                }
+                return new classs(a);
+            }
+            return a;
+        }
+    }
+    export class PauseWindow {
+        days: number[];
+        from: number;
+        to: number;
+
+        static createFrom(source: any = {}) {
+            return new PauseWindow(source);
+        }
+
+        constructor(source: any = {}) {
+            if ('string' === typeof source) source = JSON.parse(source);
+            this.days = source['days'];
+            this.from = source['from'];
+            this.to = source['to'];
+        }
+    }
+    export class PortCheck {
+        port: number;
+        protocol: string;
+        status: string;
+        method: string;
+        externalIp?: string;
+        dhtReachable: boolean;
+        // Go type: time
+        checkedAt: any;
+
+        static createFrom(source: any = {}) {
+            return new PortCheck(source);
+        }
+
+        constructor(source: any = {}) {
+            if ('string' === typeof source) source = JSON.parse(source);
+            this.port = source['port'];
+            this.protocol = source['protocol'];
+            this.status = source['status'];
+            this.method = source['method'];
+            this.externalIp = source['externalIp'];
+            this.dhtReachable = source['dhtReachable'];
+            this.checkedAt = this.convertValues(source['checkedAt'], null);
+        }
+
+        convertValues(a: any, classs: any, asMap: boolean = false): any {
+            if (!a) {
+                return a;
+            }
+            if (a.slice && a.map) {
+                return (a as any[]).map((elem) =>
+                    this.convertValues(elem, classs)
+                );
+            } else if ('object' === typeof a) {
+                if (asMap) {
+                    for (const key of Object.keys(a)) {
+                        a[key] = new classs(a[key]);
+                    }
+                    return a;
+                }
+                return new classs(a);
+            }
+            return a;
+        }
+    }
+    export class PowerPolicy {
+        onBattery: string;
+        onMetered: string;
+        throttleDownload: number;
+        throttleUpload: number;
+
+        static createFrom(source: any = {}) {
+            return new PowerPolicy(source);
+        }
+
+        constructor(source: any = {}) {
+            if ('string' === typeof source) source = JSON.parse(source);
+            this.onBattery = source['onBattery'];
+            this.onMetered = source['onMetered'];
+            this.throttleDownload = source['throttleDownload'];
+            this.throttleUpload = source['throttleUpload'];
+        }
+    }
+    export class PowerStatus {
+        onBattery: boolean;
+        metered: boolean;
+        action: string;
+
+        static createFrom(source: any = {}) {
+            return new PowerStatus(source);
+        }
+
+        constructor(source: any = {}) {
+            if ('string' === typeof source) source = JSON.parse(source);
+            this.onBattery = source['onBattery'];
+            this.metered = source['metered'];
+            this.action = source['action'];
+        }
+    }
+    export class PreviewFile {
+        path: string;
+        length: number;
+
+        static createFrom(source: any = {}) {
+            return new PreviewFile(source);
+        }
+
+        constructor(source: any = {}) {
+            if ('string' === typeof source) source = JSON.parse(source);
+            this.path = source['path'];
+            this.length = source['length'];
+        }
+    }
+    export class QuotaStatus {
+        quotas: Quotas;
+        diskUsed: number;
+        categoryUsed: Record<string, number>;
+        diskFull: boolean;
+        fullCategories: string[];
+        month: Transfer;
+        // Go type: time
+        periodStart: any;
+        // Go type: time
+        periodEnd: any;
+        capReached: boolean;
+
+        static createFrom(source: any = {}) {
+            return new QuotaStatus(source);
+        }
+
+        constructor(source: any = {}) {
+            if ('string' === typeof source) source = JSON.parse(source);
+            this.quotas = this.convertValues(source['quotas'], Quotas);
+            this.diskUsed = source['diskUsed'];
+            this.categoryUsed = source['categoryUsed'];
+            this.diskFull = source['diskFull'];
+            this.fullCategories = source['fullCategories'];
+            this.month = this.convertValues(source['month'], Transfer);
+            this.periodStart = this.convertValues(source['periodStart'], null);
+            this.periodEnd = this.convertValues(source['periodEnd'], null);
+            this.capReached = source['capReached'];
+        }
+
+        convertValues(a: any, classs: any, asMap: boolean = false): any {
+            if (!a) {
+                return a;
+            }
+            if (a.slice && a.map) {
+                return (a as any[]).map((elem) =>
+                    this.convertValues(elem, classs)
+                );
+            } else if ('object' === typeof a) {
+                if (asMap) {
+                    for (const key of Object.keys(a)) {
+                        a[key] = new classs(a[key]);
+                    }
+                    return a;
+                }
+                return new classs(a);
+            }
+            return a;
+        }
+    }
+    export class Quotas {
+        disk: number;
+        monthly: number;
+        resetDay: number;
+
+        static createFrom(source: any = {}) {
+            return new Quotas(source);
+        }
+
+        constructor(source: any = {}) {
+            if ('string' === typeof source) source = JSON.parse(source);
+            this.disk = source['disk'];
+            this.monthly = source['monthly'];
+            this.resetDay = source['resetDay'];
+        }
+    }
+    export class RateLimits {
+        download: number;
+        upload: number;
+
+        static createFrom(source: any = {}) {
+            return new RateLimits(source);
+        }
+
+        constructor(source: any = {}) {
+            if ('string' === typeof source) source = JSON.parse(source);
+            this.download = source['download'];
+            this.upload = source['upload'];
+        }
+    }
+    export class ResultPreview {
+        name: string;
+        infoHash: string;
+        size: number;
+        files: PreviewFile[];
+        private: boolean;
+
+        static createFrom(source: any = {}) {
+            return new ResultPreview(source);
+        }
+
+        constructor(source: any = {}) {
+            if ('string' === typeof source) source = JSON.parse(source);
+            this.name = source['name'];
+            this.infoHash = source['infoHash'];
+            this.size = source['size'];
+            this.files = this.convertValues(source['files'], PreviewFile);
+            this.private = source['private'];
+        }
+
+        convertValues(a: any, classs: any, asMap: boolean = false): any {
+            if (!a) {
+                return a;
+            }
+            if (a.slice && a.map) {
+                return (a as any[]).map((elem) =>
+                    this.convertValues(elem, classs)
+                );
+            } else if ('object' === typeof a) {
+                if (asMap) {
+                    for (const key of Object.keys(a)) {
+                        a[key] = new classs(a[key]);
+                    }
+                    return a;
+                }
+                return new classs(a);
+            }
+            return a;
+        }
+    }
+    export class SavedSearch {
+        name: string;
+        query: SearchQuery;
+        interval: number;
+        autoAdd: boolean;
+        category?: string;
+
+        static createFrom(source: any = {}) {
+            return new SavedSearch(source);
+        }
+
+        constructor(source: any = {}) {
+            if ('string' === typeof source) source = JSON.parse(source);
+            this.name = source['name'];
+            this.query = this.convertValues(source['query'], SearchQuery);
+            this.interval = source['interval'];
+            this.autoAdd = source['autoAdd'];
+            this.category = source['category'];
+        }
+
+        convertValues(a: any, classs: any, asMap: boolean = false): any {
+            if (!a) {
+                return a;
+            }
+            if (a.slice && a.map) {
+                return (a as any[]).map((elem) =>
+                    this.convertValues(elem, classs)
+                );
+            } else if ('object' === typeof a) {
+                if (asMap) {
+                    for (const key of Object.keys(a)) {
+                        a[key] = new classs(a[key]);
+                    }
+                    return a;
+                }
+                return new classs(a);
+            }
+            return a;
+        }
+    }
+    export class SearchQuery {
+        text: string;
+        categories: number[];
+        indexers: string[];
+        filter: search.Filter;
+
+        static createFrom(source: any = {}) {
+            return new SearchQuery(source);
+        }
+
+        constructor(source: any = {}) {
+            if ('string' === typeof source) source = JSON.parse(source);
+            this.text = source['text'];
+            this.categories = source['categories'];
+            this.indexers = source['indexers'];
+            this.filter = this.convertValues(source['filter'], search.Filter);
+        }
+
+        convertValues(a: any, classs: any, asMap: boolean = false): any {
+            if (!a) {
+                return a;
+            }
+            if (a.slice && a.map) {
+                return (a as any[]).map((elem) =>
+                    this.convertValues(elem, classs)
+                );
+            } else if ('object' === typeof a) {
+                if (asMap) {
+                    for (const key of Object.keys(a)) {
+                        a[key] = new classs(a[key]);
+                    }
+                    return a;
+                }
+                return new classs(a);
+            }
+            return a;
+        }
+    }
+    export class SessionStats {
+        session: Transfer;
+        allTime: Transfer;
+        ratio: number;
+        uptime: number;
+        totalUptime: number;
+
+        static createFrom(source: any = {}) {
+            return new SessionStats(source);
+        }
+
+        constructor(source: any = {}) {
+            if ('string' === typeof source) source = JSON.parse(source);
+            this.session = this.convertValues(source['session'], Transfer);
+            this.allTime = this.convertValues(source['allTime'], Transfer);
+            this.ratio = source['ratio'];
+            this.uptime = source['uptime'];
+            this.totalUptime = source['totalUptime'];
+        }
+
+        convertValues(a: any, classs: any, asMap: boolean = false): any {
+            if (!a) {
+                return a;
+            }
+            if (a.slice && a.map) {
+                return (a as any[]).map((elem) =>
+                    this.convertValues(elem, classs)
+                );
+            } else if ('object' === typeof a) {
+                if (asMap) {
+                    for (const key of Object.keys(a)) {
+                        a[key] = new classs(a[key]);
+                    }
+                    return a;
+                }
+                return new classs(a);
+            }
+            return a;
+        }
+    }
+    export class SettingsPatch {
+        downloadDir?: string;
+        torrentsDir?: string;
+        dhtPort?: number;
+        listenPort?: number;
+        downloadLimit?: number;
+        uploadLimit?: number;
+        maxConnections?: number;
+        maxActiveDownloads?: number;
+        maxActiveSeeds?: number;
+        proxy?: string;
+        encryption?: string;
+        notifications?: NotifySettings;
+        crawlDHT?: boolean;
+        logLevels?: Record<string, string>;
+        debugAddr?: string;
+        fingerprint?: torrent.Fingerprint;
+        reconnectOnIpChange?: boolean;
+        dhtReadOnly?: boolean;
+
+        static createFrom(source: any = {}) {
+            return new SettingsPatch(source);
+        }
+
+        constructor(source: any = {}) {
+            if ('string' === typeof source) source = JSON.parse(source);
+            this.downloadDir = source['downloadDir'];
+            this.torrentsDir = source['torrentsDir'];
+            this.dhtPort = source['dhtPort'];
+            this.listenPort = source['listenPort'];
+            this.downloadLimit = source['downloadLimit'];
+            this.uploadLimit = source['uploadLimit'];
+            this.maxConnections = source['maxConnections'];
+            this.maxActiveDownloads = source['maxActiveDownloads'];
+            this.maxActiveSeeds = source['maxActiveSeeds'];
+            this.proxy = source['proxy'];
+            this.encryption = source['encryption'];
+            this.notifications = this.convertValues(
+                source['notifications'],
+                NotifySettings
+            );
+            this.crawlDHT = source['crawlDHT'];
+            this.logLevels = source['logLevels'];
+            this.debugAddr = source['debugAddr'];
+            this.fingerprint = this.convertValues(
+                source['fingerprint'],
+                torrent.Fingerprint
+            );
+            this.reconnectOnIpChange = source['reconnectOnIpChange'];
+            this.dhtReadOnly = source['dhtReadOnly'];
+        }
+
+        convertValues(a: any, classs: any, asMap: boolean = false): any {
+            if (!a) {
+                return a;
+            }
+            if (a.slice && a.map) {
+                return (a as any[]).map((elem) =>
+                    this.convertValues(elem, classs)
+                );
+            } else if ('object' === typeof a) {
+                if (asMap) {
+                    for (const key of Object.keys(a)) {
+                        a[key] = new classs(a[key]);
+                    }
+                    return a;
+                }
+                return new classs(a);
+            }
+            return a;
+        }
+    }
+    export class ShareLimits {
+        ratio: number;
+        seedTime: number;
+        action: string;
+
+        static createFrom(source: any = {}) {
+            return new ShareLimits(source);
+        }
+
+        constructor(source: any = {}) {
+            if ('string' === typeof source) source = JSON.parse(source);
+            this.ratio = source['ratio'];
+            this.seedTime = source['seedTime'];
+            this.action = source['action'];
+        }
+    }
+    export class SpeedSample {
+        // Go type: time
+        at: any;
+        download: number;
+        upload: number;
+
+        static createFrom(source: any = {}) {
+            return new SpeedSample(source);
+        }
+
+        constructor(source: any = {}) {
+            if ('string' === typeof source) source = JSON.parse(source);
+            this.at = this.convertValues(source['at'], null);
+            this.download = source['download'];
+            this.upload = source['upload'];
+        }
+
+        convertValues(a: any, classs: any, asMap: boolean = false): any {
+            if (!a) {
+                return a;
+            }
+            if (a.slice && a.map) {
+                return (a as any[]).map((elem) =>
+                    this.convertValues(elem, classs)
+                );
+            } else if ('object' === typeof a) {
+                if (asMap) {
+                    for (const key of Object.keys(a)) {
+                        a[key] = new classs(a[key]);
+                    }
+                    return a;
+                }
+                return new classs(a);
+            }
+            return a;
+        }
+    }
+    export class Transfer {
+        uploaded: number;
+        downloaded: number;
+
+        static createFrom(source: any = {}) {
+            return new Transfer(source);
+        }
+
+        constructor(source: any = {}) {
+            if ('string' === typeof source) source = JSON.parse(source);
+            this.uploaded = source['uploaded'];
+            this.downloaded = source['downloaded'];
+        }
+    }
+    export class WatchFolder {
+        dir: string;
+        category: string;
+        afterAdd: string;
+
+        static createFrom(source: any = {}) {
+            return new WatchFolder(source);
+        }
+
+        constructor(source: any = {}) {
+            if ('string' === typeof source) source = JSON.parse(source);
+            this.dir = source['dir'];
+            this.category = source['category'];
+            this.afterAdd = source['afterAdd'];
+        }
+    }
+}
+
+export namespace logging {
+    export class Entry {
+        // Go type: time
+        time: any;
+        level: string;
+        module?: string;
+        message: string;
+        attrs?: Record<string, string>;
+
+        static createFrom(source: any = {}) {
+            return new Entry(source);
+        }
+
+        constructor(source: any = {}) {
+            if ('string' === typeof source) source = JSON.parse(source);
+            this.time = this.convertValues(source['time'], null);
+            this.level = source['level'];
+            this.module = source['module'];
+            this.message = source['message'];
+            this.attrs = source['attrs'];
+        }
+
+        convertValues(a: any, classs: any, asMap: boolean = false): any {
+            if (!a) {
+                return a;
+            }
+            if (a.slice && a.map) {
+                return (a as any[]).map((elem) =>
+                    this.convertValues(elem, classs)
+                );
+            } else if ('object' === typeof a) {
+                if (asMap) {
+                    for (const key of Object.keys(a)) {
+                        a[key] = new classs(a[key]);
+                    }
+                    return a;
+                }
+                return new classs(a);
+            }
+            return a;
+        }
+    }
+}
+
+export namespace peer {
+    export class CountryStats {
+        isoCode: string;
+        country: string;
+        flag: string;
+        peers: number;
+        downloaded: number;
+        uploaded: number;
+
+        static createFrom(source: any = {}) {
+            return new CountryStats(source);
+        }
+
+        constructor(source: any = {}) {
+            if ('string' === typeof source) source = JSON.parse(source);
+            this.isoCode = source['isoCode'];
+            this.country = source['country'];
+            this.flag = source['flag'];
+            this.peers = source['peers'];
+            this.downloaded = source['downloaded'];
+            this.uploaded = source['uploaded'];
+        }
+    }
+    export class Info {
+        addr: string;
+        client?: string;
+        isoCode?: string;
+        country?: string;
+        flag?: string;
+        asn?: number;
+        org?: string;
+        progress: number;
+        seed: boolean;
+        downloaded: number;
+        uploaded: number;
+        downRate: number;
+        upRate: number;
+        amChoking: boolean;
+        amInterested: boolean;
+        peerChoking: boolean;
+        peerInterested: boolean;
+        flags: string;
+
+        static createFrom(source: any = {}) {
+            return new Info(source);
+        }
+
+        constructor(source: any = {}) {
+            if ('string' === typeof source) source = JSON.parse(source);
+            this.addr = source['addr'];
+            this.client = source['client'];
+            this.isoCode = source['isoCode'];
+            this.country = source['country'];
+            this.flag = source['flag'];
+            this.asn = source['asn'];
+            this.org = source['org'];
+            this.progress = source['progress'];
+            this.seed = source['seed'];
+            this.downloaded = source['downloaded'];
+            this.uploaded = source['uploaded'];
+            this.downRate = source['downRate'];
+            this.upRate = source['upRate'];
+            this.amChoking = source['amChoking'];
+            this.amInterested = source['amInterested'];
+            this.peerChoking = source['peerChoking'];
+            this.peerInterested = source['peerInterested'];
+            this.flags = source['flags'];
+        }
+    }
+}
+
+export namespace rss {
+    export class Rule {
+        name: string;
+        match: string;
+        exclude: string;
+        episodes: string;
+        category: string;
+        disabled: boolean;
+
+        static createFrom(source: any = {}) {
+            return new Rule(source);
+        }
+
+        constructor(source: any = {}) {
+            if ('string' === typeof source) source = JSON.parse(source);
+            this.name = source['name'];
+            this.match = source['match'];
+            this.exclude = source['exclude'];
+            this.episodes = source['episodes'];
+            this.category = source['category'];
+            this.disabled = source['disabled'];
+        }
+    }
+}
+
+export namespace search {
+    export class Filter {
+        minSeeders: number;
+        minSize: number;
+        maxSize: number;
+        maxAge: number;
+
+        static createFrom(source: any = {}) {
+            return new Filter(source);
+        }
+
+        constructor(source: any = {}) {
+            if ('string' === typeof source) source = JSON.parse(source);
+            this.minSeeders = source['minSeeders'];
+            this.minSize = source['minSize'];
+            this.maxSize = source['maxSize'];
+            this.maxAge = source['maxAge'];
+        }
+    }
+    export class Health {
+        name: string;
+        searches: number;
+        failures: number;
+        latency: number;
+        lastError?: string;
+        // Go type: time
+        lastErrorAt: any;
+        // Go type: time
+        lastSuccess: any;
+
+        static createFrom(source: any = {}) {
+            return new Health(source);
+        }
+
+        constructor(source: any = {}) {
+            if ('string' === typeof source) source = JSON.parse(source);
+            this.name = source['name'];
+            this.searches = source['searches'];
+            this.failures = source['failures'];
+            this.latency = source['latency'];
+            this.lastError = source['lastError'];
+            this.lastErrorAt = this.convertValues(source['lastErrorAt'], null);
+            this.lastSuccess = this.convertValues(source['lastSuccess'], null);
+        }
+
+        convertValues(a: any, classs: any, asMap: boolean = false): any {
+            if (!a) {
+                return a;
+            }
+            if (a.slice && a.map) {
+                return (a as any[]).map((elem) =>
+                    this.convertValues(elem, classs)
+                );
+            } else if ('object' === typeof a) {
+                if (asMap) {
+                    for (const key of Object.keys(a)) {
+                        a[key] = new classs(a[key]);
+                    }
+                    return a;
+                }
+                return new classs(a);
+            }
+            return a;
+        }
+    }
+    export class Result {
+        title: string;
+        link?: string;
+        magnet?: string;
+        infoHash?: string;
+        size: number;
+        seeders: number;
+        leechers: number;
+        verified: boolean;
+        dhtPeers?: number;
+        // Go type: time
+        published: any;
+        categories?: number[];
+        details?: string;
+        indexer?: string;
+        provider: string;
+        sources?: Source[];
+
+        static createFrom(source: any = {}) {
+            return new Result(source);
+        }
+
+        constructor(source: any = {}) {
+            if ('string' === typeof source) source = JSON.parse(source);
+            this.title = source['title'];
+            this.link = source['link'];
+            this.magnet = source['magnet'];
+            this.infoHash = source['infoHash'];
+            this.size = source['size'];
+            this.seeders = source['seeders'];
+            this.leechers = source['leechers'];
+            this.verified = source['verified'];
+            this.dhtPeers = source['dhtPeers'];
+            this.published = this.convertValues(source['published'], null);
+            this.categories = source['categories'];
+            this.details = source['details'];
+            this.indexer = source['indexer'];
+            this.provider = source['provider'];
+            this.sources = this.convertValues(source['sources'], Source);
+        }
+
+        convertValues(a: any, classs: any, asMap: boolean = false): any {
+            if (!a) {
+                return a;
+            }
+            if (a.slice && a.map) {
+                return (a as any[]).map((elem) =>
+                    this.convertValues(elem, classs)
+                );
+            } else if ('object' === typeof a) {
+                if (asMap) {
+                    for (const key of Object.keys(a)) {
+                        a[key] = new classs(a[key]);
+                    }
+                    return a;
+                }
+                return new classs(a);
+            }
+            return a;
+        }
+    }
+    export class Source {
+        provider: string;
+        indexer?: string;
+        title: string;
+        link?: string;
+        magnet?: string;
+        details?: string;
+        seeders: number;
+        leechers: number;
+
+        static createFrom(source: any = {}) {
+            return new Source(source);
+        }
+
+        constructor(source: any = {}) {
+            if ('string' === typeof source) source = JSON.parse(source);
+            this.provider = source['provider'];
+            this.indexer = source['indexer'];
+            this.title = source['title'];
+            this.link = source['link'];
+            this.magnet = source['magnet'];
+            this.details = source['details'];
+            this.seeders = source['seeders'];
+            this.leechers = source['leechers'];
+        }
+    }
+}
+
 export namespace torrent {
+    export class Details {
+        files: FileStatus[];
+        pieces: PieceSummary;
+        trackers: tracker.Status[];
+        peers: peer.Info[];
+
+        static createFrom(source: any = {}) {
+            return new Details(source);
+        }
+
+        constructor(source: any = {}) {
+            if ('string' === typeof source) source = JSON.parse(source);
+            this.files = this.convertValues(source['files'], FileStatus);
+            this.pieces = this.convertValues(source['pieces'], PieceSummary);
+            this.trackers = this.convertValues(
+                source['trackers'],
+                tracker.Status
+            );
+            this.peers = this.convertValues(source['peers'], peer.Info);
+        }
+
+        convertValues(a: any, classs: any, asMap: boolean = false): any {
+            if (!a) {
+                return a;
+            }
+            if (a.slice && a.map) {
+                return (a as any[]).map((elem) =>
+                    this.convertValues(elem, classs)
+                );
+            } else if ('object' === typeof a) {
+                if (asMap) {
+                    for (const key of Object.keys(a)) {
+                        a[key] = new classs(a[key]);
+                    }
+                    return a;
+                }
+                return new classs(a);
+            }
+            return a;
+        }
+    }
     export class File {
         length: number;
         path: string[];
@@ -13,12 +1298,87 @@ export namespace torrent {
             this.path = source['path'];
         }
     }
+    export class FileStatus {
+        path: string[];
+        size: number;
+        progress: number;
+        index: number;
+        wanted: boolean;
+        priority: string;
+
+        static createFrom(source: any = {}) {
+            return new FileStatus(source);
+        }
+
+        constructor(source: any = {}) {
+            if ('string' === typeof source) source = JSON.parse(source);
+            this.path = source['path'];
+            this.size = source['size'];
+            this.progress = source['progress'];
+            this.index = source['index'];
+            this.wanted = source['wanted'];
+            this.priority = source['priority'];
+        }
+    }
+    export class Fingerprint {
+        peerIdPrefix: string;
+        version: string;
+        userAgent: string;
+
+        static createFrom(source: any = {}) {
+            return new Fingerprint(source);
+        }
+
+        constructor(source: any = {}) {
+            if ('string' === typeof source) source = JSON.parse(source);
+            this.peerIdPrefix = source['peerIdPrefix'];
+            this.version = source['version'];
+            this.userAgent = source['userAgent'];
+        }
+    }
+    export class FingerprintPreset {
+        name: string;
+        fingerprint: Fingerprint;
+
+        static createFrom(source: any = {}) {
+            return new FingerprintPreset(source);
+        }
+
+        constructor(source: any = {}) {
+            if ('string' === typeof source) source = JSON.parse(source);
+            this.name = source['name'];
+            this.fingerprint = this.convertValues(
+                source['fingerprint'],
+                Fingerprint
+            );
+        }
+
+        convertValues(a: any, classs: any, asMap: boolean = false): any {
+            if (!a) {
+                return a;
+            }
+            if (a.slice && a.map) {
+                return (a as any[]).map((elem) =>
+                    this.convertValues(elem, classs)
+                );
+            } else if ('object' === typeof a) {
+                if (asMap) {
+                    for (const key of Object.keys(a)) {
+                        a[key] = new classs(a[key]);
+                    }
+                    return a;
+                }
+                return new classs(a);
+            }
+            return a;
+        }
+    }
     export class Info {
         infoHash: number[];
         name: string;
         files?: File[];
         pieceLength: number;
-        pieces: number[][];
+        numPieces: number;
         private: boolean;
 
         static createFrom(source: any = {}) {
@@ -31,7 +1391,7 @@ export namespace torrent {
             this.name = source['name'];
             this.files = this.convertValues(source['files'], File);
             this.pieceLength = source['pieceLength'];
-            this.pieces = source['pieces'];
+            this.numPieces = source['numPieces'];
             this.private = source['private'];
         }
 
@@ -101,6 +1461,74 @@ export namespace torrent {
             return a;
         }
     }
+    export class PieceSummary {
+        count: number;
+        length: number;
+        have: number;
+        unavailable: number;
+        availability: number;
+
+        static createFrom(source: any = {}) {
+            return new PieceSummary(source);
+        }
+
+        constructor(source: any = {}) {
+            if ('string' === typeof source) source = JSON.parse(source);
+            this.count = source['count'];
+            this.length = source['length'];
+            this.have = source['have'];
+            this.unavailable = source['unavailable'];
+            this.availability = source['availability'];
+        }
+    }
+    export class Status {
+        infoHash: string;
+        name: string;
+        size: number;
+        state: string;
+        error?: string;
+        uploaded: number;
+        downloaded: number;
+        left: number;
+        progress: number;
+        downloadRate: number;
+        uploadRate: number;
+        eta: number;
+        queuePosition: number;
+        retryIn?: number;
+        peers: number;
+        seeds: number;
+        ratio: number;
+        downloadLimit: number;
+        uploadLimit: number;
+
+        static createFrom(source: any = {}) {
+            return new Status(source);
+        }
+
+        constructor(source: any = {}) {
+            if ('string' === typeof source) source = JSON.parse(source);
+            this.infoHash = source['infoHash'];
+            this.name = source['name'];
+            this.size = source['size'];
+            this.state = source['state'];
+            this.error = source['error'];
+            this.uploaded = source['uploaded'];
+            this.downloaded = source['downloaded'];
+            this.left = source['left'];
+            this.progress = source['progress'];
+            this.downloadRate = source['downloadRate'];
+            this.uploadRate = source['uploadRate'];
+            this.eta = source['eta'];
+            this.queuePosition = source['queuePosition'];
+            this.retryIn = source['retryIn'];
+            this.peers = source['peers'];
+            this.seeds = source['seeds'];
+            this.ratio = source['ratio'];
+            this.downloadLimit = source['downloadLimit'];
+            this.uploadLimit = source['uploadLimit'];
+        }
+    }
     export class Torrent {
         metainfo?: Metainfo;
         uploaded: number;
@@ -140,3 +1568,189 @@ export namespace torrent {
         }
     }
 }
+
+export namespace tracker {
+    export class Status {
+        url: string;
+        working: boolean;
+        error?: string;
+        seeders: number;
+        leechers: number;
+        peers: number;
+        // Go type: time
+        lastAnnounce: any;
+        // Go type: time
+        nextAnnounce: any;
+
+        static createFrom(source: any = {}) {
+            return new Status(source);
+        }
+
+        constructor(source: any = {}) {
+            if ('string' === typeof source) source = JSON.parse(source);
+            this.url = source['url'];
+            this.working = source['working'];
+            this.error = source['error'];
+            this.seeders = source['seeders'];
+            this.leechers = source['leechers'];
+            this.peers = source['peers'];
+            this.lastAnnounce = this.convertValues(
+                source['lastAnnounce'],
+                null
+            );
+            this.nextAnnounce = this.convertValues(
+                source['nextAnnounce'],
+                null
+            );
+        }
+
+        convertValues(a: any, classs: any, asMap: boolean = false): any {
+            if (!a) {
+                return a;
+            }
+            if (a.slice && a.map) {
+                return (a as any[]).map((elem) =>
+                    this.convertValues(elem, classs)
+                );
+            } else if ('object' === typeof a) {
+                if (asMap) {
+                    for (const key of Object.keys(a)) {
+                        a[key] = new classs(a[key]);
+                    }
+                    return a;
+                }
+                return new classs(a);
+            }
+            return a;
+        }
+    }
+}
+
+export namespace ui {
+    export class CreatedTorrent {
+        name: string;
+        infoHash: string;
+        size: number;
+        pieceLength: number;
+        pieces: number;
+
+        static createFrom(source: any = {}) {
+            return new CreatedTorrent(source);
+        }
+
+        constructor(source: any = {}) {
+            if ('string' === typeof source) source = JSON.parse(source);
+            this.name = source['name'];
+            this.infoHash = source['infoHash'];
+            this.size = source['size'];
+            this.pieceLength = source['pieceLength'];
+            this.pieces = source['pieces'];
+        }
+    }
+    export class DroppedItem {
+        label: string;
+        request: echo.AddRequest;
+        error?: string;
+
+        static createFrom(source: any = {}) {
+            return new DroppedItem(source);
+        }
+
+        constructor(source: any = {}) {
+            if ('string' === typeof source) source = JSON.parse(source);
+            this.label = source['label'];
+            this.request = this.convertValues(
+                source['request'],
+                echo.AddRequest
+            );
+            this.error = source['error'];
+        }
+
+        convertValues(a: any, classs: any, asMap: boolean = false): any {
+            if (!a) {
+                return a;
+            }
+            if (a.slice && a.map) {
+                return (a as any[]).map((elem) =>
+                    this.convertValues(elem, classs)
+                );
+            } else if ('object' === typeof a) {
+                if (asMap) {
+                    for (const key of Object.keys(a)) {
+                        a[key] = new classs(a[key]);
+                    }
+                    return a;
+                }
+                return new classs(a);
+            }
+            return a;
+        }
+    }
+    export class FullState {
+        torrents: torrent.Torrent[];
+        statuses: torrent.Status[];
+        labels: { [key: string]: echo.Labels };
+        categories: echo.Category[];
+        magnets: echo.MagnetStatus[];
+        settings: echo.GlobalSettings;
+        stats: echo.SessionStats;
+        power: echo.PowerStatus;
+        suspended: boolean;
+
+        static createFrom(source: any = {}) {
+            return new FullState(source);
+        }
+
+        constructor(source: any = {}) {
+            if ('string' === typeof source) source = JSON.parse(source);
+            this.torrents = this.convertValues(
+                source['torrents'],
+                torrent.Torrent
+            );
+            this.statuses = this.convertValues(
+                source['statuses'],
+                torrent.Status
+            );
+            this.labels = this.convertValues(
+                source['labels'],
+                echo.Labels,
+                true
+            );
+            this.categories = this.convertValues(
+                source['categories'],
+                echo.Category
+            );
+            this.magnets = this.convertValues(
+                source['magnets'],
+                echo.MagnetStatus
+            );
+            this.settings = this.convertValues(
+                source['settings'],
+                echo.GlobalSettings
+            );
+            this.stats = this.convertValues(source['stats'], echo.SessionStats);
+            this.power = this.convertValues(source['power'], echo.PowerStatus);
+            this.suspended = source['suspended'];
+        }
+
+        convertValues(a: any, classs: any, asMap: boolean = false): any {
+            if (!a) {
+                return a;
+            }
+            if (a.slice && a.map) {
+                return (a as any[]).map((elem) =>
+                    this.convertValues(elem, classs)
+                );
+            } else if ('object' === typeof a) {
+                if (asMap) {
+                    for (const key of Object.keys(a)) {
+                        a[key] = new classs(a[key]);
+                    }
+                    return a;
+                }
+                return new classs(a);
+            }
+            return a;
+        }
+    }
+}
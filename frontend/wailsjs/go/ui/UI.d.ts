@@ -1,10 +1,199 @@
 // Cynhyrchwyd y ffeil hon yn awtomatig. PEIDIWCH Â MODIWL
 // This file is automatically generated. DO NOT EDIT
 import { torrent } from '../models';
+import { echo } from '../models';
 import { context } from '../models';
+import { logging } from '../models';
+import { ui } from '../models';
+import { peer } from '../models';
+import { search } from '../models';
 
-export function AddTorrent(arg1: Array<number>): Promise<torrent.Torrent>;
+export function AddCategory(arg1: echo.Category): Promise<void>;
+
+export function AddMagnet(arg1: string, arg2: echo.AddOptions): Promise<echo.MagnetStatus>;
+
+export function AddTorrent(arg1: Array<number>, arg2: echo.AddOptions): Promise<torrent.Torrent>;
+
+export function AddTorrentFromPath(arg1: string, arg2: echo.AddOptions): Promise<torrent.Torrent>;
+
+export function AddTorrentFromURL(arg1: string, arg2: echo.AddOptions): Promise<torrent.Torrent>;
+
+export function AddTorrents(arg1: Array<echo.AddRequest>): Promise<Array<echo.AddResult>>;
+
+export function Addresses(): Promise<echo.Addresses>;
+
+export function BeforeClose(): Promise<boolean>;
+
+export function CancelCreate(): Promise<void>;
+
+export function CancelPreview(): Promise<void>;
+
+export function CancelSearch(): Promise<void>;
+
+export function Categories(): Promise<Array<echo.Category>>;
+
+export function CheckPort(): Promise<echo.PortCheck>;
+
+export function ChooseCreateSource(arg1: boolean): Promise<string>;
+
+export function ChooseTorrentFiles(): Promise<Array<string>>;
+
+export function ClearHistory(): Promise<void>;
+
+export function CreateTorrent(arg1: echo.CreateOptions): Promise<ui.CreatedTorrent>;
+
+export function DropText(arg1: string): Promise<void>;
+
+export function ExportTorrent(arg1: Array<number>): Promise<string>;
+
+export function Feeds(): Promise<Array<echo.Feed>>;
+
+export function FingerprintPresets(): Promise<Array<torrent.FingerprintPreset>>;
+
+export function ForceReannounce(arg1: Array<number>): Promise<void>;
+
+export function ForceRecheck(arg1: Array<number>): Promise<void>;
+
+export function GetFullState(): Promise<ui.FullState>;
+
+export function GetPeerCountries(arg1: Array<number>): Promise<Array<peer.CountryStats>>;
+
+export function GetPieceMap(arg1: Array<number>): Promise<string>;
+
+export function GetRecentLogs(arg1: string, arg2: number): Promise<Array<logging.Entry>>;
+
+export function GetSessionSpeeds(arg1: string): Promise<Array<echo.SpeedSample>>;
+
+export function GetSettings(): Promise<echo.GlobalSettings>;
+
+export function GetTorrentDetails(arg1: Array<number>): Promise<torrent.Details>;
+
+export function GetTorrentSpeeds(arg1: Array<number>, arg2: string): Promise<Array<echo.SpeedSample>>;
+
+export function History(): Promise<Array<echo.HistoryEntry>>;
+
+export function Import(arg1: string): Promise<echo.ImportResult>;
+
+export function IndexedTorrents(): Promise<number>;
+
+export function IndexerHealth(): Promise<Array<search.Health>>;
+
+export function Indexers(): Promise<Array<echo.Indexer>>;
+
+export function ListTorrents(): Promise<Array<torrent.Status>>;
+
+export function LogModules(): Promise<Array<string>>;
+
+export function MagnetURI(arg1: Array<number>): Promise<string>;
+
+export function MoveDown(arg1: Array<number>): Promise<void>;
+
+export function MoveToBottom(arg1: Array<number>): Promise<void>;
+
+export function MoveToTop(arg1: Array<number>): Promise<void>;
+
+export function MoveUp(arg1: Array<number>): Promise<void>;
+
+export function OpenTorrentFile(arg1: Array<number>, arg2: number): Promise<void>;
+
+export function Overrides(arg1: Array<number>): Promise<echo.Overrides>;
+
+export function PauseTorrent(arg1: Array<number>): Promise<void>;
+
+export function PauseWindows(): Promise<Array<echo.PauseWindow>>;
+
+export function PendingMagnets(): Promise<Array<echo.MagnetStatus>>;
+
+export function Power(): Promise<echo.PowerStatus>;
+
+export function PowerPolicy(): Promise<echo.PowerPolicy>;
+
+export function PreviewResult(arg1: search.Result): Promise<echo.ResultPreview>;
+
+export function QueryLogs(arg1: string, arg2: Array<string>, arg3: string, arg4: number): Promise<Array<logging.Entry>>;
+
+export function QueuePosition(arg1: Array<number>): Promise<number>;
+
+export function Quit(): Promise<void>;
+
+export function QuotaStatus(): Promise<echo.QuotaStatus>;
+
+export function Quotas(): Promise<echo.Quotas>;
+
+export function ReannounceTracker(arg1: Array<number>, arg2: string): Promise<void>;
+
+export function RemoveCategory(arg1: string): Promise<void>;
 
 export function RemoveTorrent(arg1: any): Promise<void>;
 
+export function ResumeTorrent(arg1: Array<number>): Promise<void>;
+
+export function RevealTorrent(arg1: Array<number>): Promise<void>;
+
+export function RunInBackground(): Promise<void>;
+
+export function SaveCreatedTorrent(): Promise<string>;
+
+export function SaveTorrentFile(arg1: Array<number>, arg2: Array<Array<string>>): Promise<string>;
+
+export function SavedSearches(): Promise<Array<echo.SavedSearch>>;
+
+export function Search(arg1: string, arg2: Array<number>, arg3: Array<string>, arg4: search.Filter): Promise<Array<search.Result>>;
+
+export function SeedCreatedTorrent(arg1: echo.AddOptions): Promise<torrent.Torrent>;
+
+export function SessionStats(): Promise<echo.SessionStats>;
+
+export function SetCategory(arg1: Array<number>, arg2: string, arg3: boolean): Promise<void>;
+
+export function SetCloseAction(arg1: string): Promise<void>;
+
+export function SetFeeds(arg1: Array<echo.Feed>): Promise<void>;
+
+export function SetFilePriority(arg1: Array<number>, arg2: number, arg3: string): Promise<void>;
+
+export function SetFilesWanted(arg1: Array<number>, arg2: Array<number>, arg3: boolean): Promise<void>;
+
+export function SetGlobalRateLimits(arg1: echo.RateLimits): Promise<void>;
+
+export function SetIndexerEnabled(arg1: string, arg2: boolean): Promise<void>;
+
+export function SetIndexers(arg1: Array<echo.Indexer>): Promise<void>;
+
+export function SetOverrides(arg1: Array<number>, arg2: echo.Overrides): Promise<void>;
+
+export function SetPauseWindows(arg1: Array<echo.PauseWindow>): Promise<void>;
+
+export function SetPowerPolicy(arg1: echo.PowerPolicy): Promise<void>;
+
+export function SetQueuePosition(arg1: Array<number>, arg2: number): Promise<void>;
+
+export function SetQuotas(arg1: echo.Quotas): Promise<void>;
+
+export function SetSavedSearches(arg1: Array<echo.SavedSearch>): Promise<void>;
+
+export function SetShareLimits(arg1: Array<number>, arg2: echo.ShareLimits): Promise<void>;
+
+export function SetTags(arg1: Array<number>, arg2: Array<string>): Promise<void>;
+
+export function SetTorrentRateLimits(arg1: Array<number>, arg2: echo.RateLimits): Promise<void>;
+
+export function SetWatchFolders(arg1: Array<echo.WatchFolder>): Promise<void>;
+
+export function ShareLimits(arg1: Array<number>): Promise<echo.ShareLimits>;
+
 export function Startup(arg1: context.Context): Promise<void>;
+
+export function Suspended(): Promise<boolean>;
+
+export function TestIndexer(arg1: echo.Indexer): Promise<number>;
+
+export function TorrentLabels(): Promise<Record<string, echo.Labels>>;
+
+export function TorrentStates(): Promise<Record<string, string>>;
+
+export function Torrents(): Promise<Array<torrent.Torrent>>;
+
+export function UpdateSettings(arg1: echo.SettingsPatch): Promise<echo.GlobalSettings>;
+
+export function WatchFolders(): Promise<Array<echo.WatchFolder>>;
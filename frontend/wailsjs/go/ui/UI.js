@@ -2,14 +2,382 @@
 // Cynhyrchwyd y ffeil hon yn awtomatig. PEIDIWCH Â MODIWL
 // This file is automatically generated. DO NOT EDIT
 
-export function AddTorrent(arg1) {
-    return window['go']['ui']['UI']['AddTorrent'](arg1);
+export function AddCategory(arg1) {
+    return window['go']['ui']['UI']['AddCategory'](arg1);
+}
+
+export function AddMagnet(arg1, arg2) {
+    return window['go']['ui']['UI']['AddMagnet'](arg1, arg2);
+}
+
+export function AddTorrent(arg1, arg2) {
+    return window['go']['ui']['UI']['AddTorrent'](arg1, arg2);
+}
+
+export function AddTorrentFromPath(arg1, arg2) {
+    return window['go']['ui']['UI']['AddTorrentFromPath'](arg1, arg2);
+}
+
+export function AddTorrentFromURL(arg1, arg2) {
+    return window['go']['ui']['UI']['AddTorrentFromURL'](arg1, arg2);
+}
+
+export function AddTorrents(arg1) {
+    return window['go']['ui']['UI']['AddTorrents'](arg1);
+}
+
+export function Addresses() {
+    return window['go']['ui']['UI']['Addresses']();
+}
+
+export function BeforeClose() {
+    return window['go']['ui']['UI']['BeforeClose']();
+}
+
+export function CancelCreate() {
+    return window['go']['ui']['UI']['CancelCreate']();
+}
+
+export function CancelPreview() {
+    return window['go']['ui']['UI']['CancelPreview']();
+}
+
+export function CancelSearch() {
+    return window['go']['ui']['UI']['CancelSearch']();
+}
+
+export function Categories() {
+    return window['go']['ui']['UI']['Categories']();
+}
+
+export function CheckPort() {
+    return window['go']['ui']['UI']['CheckPort']();
+}
+
+export function ChooseCreateSource(arg1) {
+    return window['go']['ui']['UI']['ChooseCreateSource'](arg1);
+}
+
+export function ChooseTorrentFiles() {
+    return window['go']['ui']['UI']['ChooseTorrentFiles']();
+}
+
+export function ClearHistory() {
+    return window['go']['ui']['UI']['ClearHistory']();
+}
+
+export function CreateTorrent(arg1) {
+    return window['go']['ui']['UI']['CreateTorrent'](arg1);
+}
+
+export function DropText(arg1) {
+    return window['go']['ui']['UI']['DropText'](arg1);
+}
+
+export function ExportTorrent(arg1) {
+    return window['go']['ui']['UI']['ExportTorrent'](arg1);
+}
+
+export function Feeds() {
+    return window['go']['ui']['UI']['Feeds']();
+}
+
+export function FingerprintPresets() {
+    return window['go']['ui']['UI']['FingerprintPresets']();
+}
+
+export function ForceReannounce(arg1) {
+    return window['go']['ui']['UI']['ForceReannounce'](arg1);
+}
+
+export function ForceRecheck(arg1) {
+    return window['go']['ui']['UI']['ForceRecheck'](arg1);
+}
+
+export function GetFullState() {
+    return window['go']['ui']['UI']['GetFullState']();
+}
+
+export function GetPeerCountries(arg1) {
+    return window['go']['ui']['UI']['GetPeerCountries'](arg1);
+}
+
+export function GetPieceMap(arg1) {
+    return window['go']['ui']['UI']['GetPieceMap'](arg1);
+}
+
+export function GetRecentLogs(arg1, arg2) {
+    return window['go']['ui']['UI']['GetRecentLogs'](arg1, arg2);
+}
+
+export function GetSessionSpeeds(arg1) {
+    return window['go']['ui']['UI']['GetSessionSpeeds'](arg1);
+}
+
+export function GetSettings() {
+    return window['go']['ui']['UI']['GetSettings']();
+}
+
+export function GetTorrentDetails(arg1) {
+    return window['go']['ui']['UI']['GetTorrentDetails'](arg1);
+}
+
+export function GetTorrentSpeeds(arg1, arg2) {
+    return window['go']['ui']['UI']['GetTorrentSpeeds'](arg1, arg2);
+}
+
+export function History() {
+    return window['go']['ui']['UI']['History']();
+}
+
+export function Import(arg1) {
+    return window['go']['ui']['UI']['Import'](arg1);
+}
+
+export function IndexedTorrents() {
+    return window['go']['ui']['UI']['IndexedTorrents']();
+}
+
+export function IndexerHealth() {
+    return window['go']['ui']['UI']['IndexerHealth']();
+}
+
+export function Indexers() {
+    return window['go']['ui']['UI']['Indexers']();
+}
+
+export function ListTorrents() {
+    return window['go']['ui']['UI']['ListTorrents']();
+}
+
+export function LogModules() {
+    return window['go']['ui']['UI']['LogModules']();
+}
+
+export function MagnetURI(arg1) {
+    return window['go']['ui']['UI']['MagnetURI'](arg1);
+}
+
+export function MoveDown(arg1) {
+    return window['go']['ui']['UI']['MoveDown'](arg1);
+}
+
+export function MoveToBottom(arg1) {
+    return window['go']['ui']['UI']['MoveToBottom'](arg1);
+}
+
+export function MoveToTop(arg1) {
+    return window['go']['ui']['UI']['MoveToTop'](arg1);
+}
+
+export function MoveUp(arg1) {
+    return window['go']['ui']['UI']['MoveUp'](arg1);
+}
+
+export function OpenTorrentFile(arg1, arg2) {
+    return window['go']['ui']['UI']['OpenTorrentFile'](arg1, arg2);
+}
+
+export function Overrides(arg1) {
+    return window['go']['ui']['UI']['Overrides'](arg1);
+}
+
+export function PauseTorrent(arg1) {
+    return window['go']['ui']['UI']['PauseTorrent'](arg1);
+}
+
+export function PauseWindows() {
+    return window['go']['ui']['UI']['PauseWindows']();
+}
+
+export function PendingMagnets() {
+    return window['go']['ui']['UI']['PendingMagnets']();
+}
+
+export function Power() {
+    return window['go']['ui']['UI']['Power']();
+}
+
+export function PowerPolicy() {
+    return window['go']['ui']['UI']['PowerPolicy']();
+}
+
+export function PreviewResult(arg1) {
+    return window['go']['ui']['UI']['PreviewResult'](arg1);
+}
+
+export function QueryLogs(arg1, arg2, arg3, arg4) {
+    return window['go']['ui']['UI']['QueryLogs'](arg1, arg2, arg3, arg4);
+}
+
+export function QueuePosition(arg1) {
+    return window['go']['ui']['UI']['QueuePosition'](arg1);
+}
+
+export function Quit() {
+    return window['go']['ui']['UI']['Quit']();
+}
+
+export function QuotaStatus() {
+    return window['go']['ui']['UI']['QuotaStatus']();
+}
+
+export function Quotas() {
+    return window['go']['ui']['UI']['Quotas']();
+}
+
+export function ReannounceTracker(arg1, arg2) {
+    return window['go']['ui']['UI']['ReannounceTracker'](arg1, arg2);
+}
+
+export function RemoveCategory(arg1) {
+    return window['go']['ui']['UI']['RemoveCategory'](arg1);
 }
 
 export function RemoveTorrent(arg1) {
     return window['go']['ui']['UI']['RemoveTorrent'](arg1);
 }
 
+export function ResumeTorrent(arg1) {
+    return window['go']['ui']['UI']['ResumeTorrent'](arg1);
+}
+
+export function RevealTorrent(arg1) {
+    return window['go']['ui']['UI']['RevealTorrent'](arg1);
+}
+
+export function RunInBackground() {
+    return window['go']['ui']['UI']['RunInBackground']();
+}
+
+export function SaveCreatedTorrent() {
+    return window['go']['ui']['UI']['SaveCreatedTorrent']();
+}
+
+export function SaveTorrentFile(arg1, arg2) {
+    return window['go']['ui']['UI']['SaveTorrentFile'](arg1, arg2);
+}
+
+export function SavedSearches() {
+    return window['go']['ui']['UI']['SavedSearches']();
+}
+
+export function Search(arg1, arg2, arg3, arg4) {
+    return window['go']['ui']['UI']['Search'](arg1, arg2, arg3, arg4);
+}
+
+export function SeedCreatedTorrent(arg1) {
+    return window['go']['ui']['UI']['SeedCreatedTorrent'](arg1);
+}
+
+export function SessionStats() {
+    return window['go']['ui']['UI']['SessionStats']();
+}
+
+export function SetCategory(arg1, arg2, arg3) {
+    return window['go']['ui']['UI']['SetCategory'](arg1, arg2, arg3);
+}
+
+export function SetCloseAction(arg1) {
+    return window['go']['ui']['UI']['SetCloseAction'](arg1);
+}
+
+export function SetFeeds(arg1) {
+    return window['go']['ui']['UI']['SetFeeds'](arg1);
+}
+
+export function SetFilePriority(arg1, arg2, arg3) {
+    return window['go']['ui']['UI']['SetFilePriority'](arg1, arg2, arg3);
+}
+
+export function SetFilesWanted(arg1, arg2, arg3) {
+    return window['go']['ui']['UI']['SetFilesWanted'](arg1, arg2, arg3);
+}
+
+export function SetGlobalRateLimits(arg1) {
+    return window['go']['ui']['UI']['SetGlobalRateLimits'](arg1);
+}
+
+export function SetIndexerEnabled(arg1, arg2) {
+    return window['go']['ui']['UI']['SetIndexerEnabled'](arg1, arg2);
+}
+
+export function SetIndexers(arg1) {
+    return window['go']['ui']['UI']['SetIndexers'](arg1);
+}
+
+export function SetOverrides(arg1, arg2) {
+    return window['go']['ui']['UI']['SetOverrides'](arg1, arg2);
+}
+
+export function SetPauseWindows(arg1) {
+    return window['go']['ui']['UI']['SetPauseWindows'](arg1);
+}
+
+export function SetPowerPolicy(arg1) {
+    return window['go']['ui']['UI']['SetPowerPolicy'](arg1);
+}
+
+export function SetQueuePosition(arg1, arg2) {
+    return window['go']['ui']['UI']['SetQueuePosition'](arg1, arg2);
+}
+
+export function SetQuotas(arg1) {
+    return window['go']['ui']['UI']['SetQuotas'](arg1);
+}
+
+export function SetSavedSearches(arg1) {
+    return window['go']['ui']['UI']['SetSavedSearches'](arg1);
+}
+
+export function SetShareLimits(arg1, arg2) {
+    return window['go']['ui']['UI']['SetShareLimits'](arg1, arg2);
+}
+
+export function SetTags(arg1, arg2) {
+    return window['go']['ui']['UI']['SetTags'](arg1, arg2);
+}
+
+export function SetTorrentRateLimits(arg1, arg2) {
+    return window['go']['ui']['UI']['SetTorrentRateLimits'](arg1, arg2);
+}
+
+export function SetWatchFolders(arg1) {
+    return window['go']['ui']['UI']['SetWatchFolders'](arg1);
+}
+
+export function ShareLimits(arg1) {
+    return window['go']['ui']['UI']['ShareLimits'](arg1);
+}
+
 export function Startup(arg1) {
     return window['go']['ui']['UI']['Startup'](arg1);
 }
+
+export function Suspended() {
+    return window['go']['ui']['UI']['Suspended']();
+}
+
+export function TestIndexer(arg1) {
+    return window['go']['ui']['UI']['TestIndexer'](arg1);
+}
+
+export function TorrentLabels() {
+    return window['go']['ui']['UI']['TorrentLabels']();
+}
+
+export function TorrentStates() {
+    return window['go']['ui']['UI']['TorrentStates']();
+}
+
+export function Torrents() {
+    return window['go']['ui']['UI']['Torrents']();
+}
+
+export function UpdateSettings(arg1) {
+    return window['go']['ui']['UI']['UpdateSettings'](arg1);
+}
+
+export function WatchFolders() {
+    return window['go']['ui']['UI']['WatchFolders']();
+}
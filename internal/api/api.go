@@ -0,0 +1,458 @@
+// Package api serves a session over HTTP as JSON, for running the engine
+// without a window, as on a seedbox or server.
+package api
+
+import (
+	"cmp"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prxssh/echo/pkg/echo"
+	"github.com/prxssh/echo/pkg/logging"
+)
+
+// maxBody bounds a request body, a .torrent given inline included.
+const maxBody = 32 << 20
+
+// keepAliveInterval is how often an idle event stream is written to, so
+// that proxies do not take it for a dead connection.
+const keepAliveInterval = 30 * time.Second
+
+// Server routes the API's requests to a client. Every request must carry
+// the token as "Authorization: Bearer <token>", or as the password of
+// basic authentication for clients, such as Transmission's, that only
+// know that, or the cookie of a login to qBittorrent's API.
+type Server struct {
+	client *echo.Client
+	token  string
+	mux    *http.ServeMux
+	tr     *transmission
+	qb     *qbit
+}
+
+// New returns the API of client, guarded by token, which must not be
+// empty.
+func New(client *echo.Client, token string) *Server {
+	s := &Server{
+		client: client,
+		token:  token,
+		mux:    http.NewServeMux(),
+		tr:     newTransmission(),
+		qb:     newQbit(),
+	}
+	s.mux.HandleFunc("GET /api/v1/torrents", s.listTorrents)
+	s.mux.HandleFunc("POST /api/v1/torrents", s.addTorrent)
+	s.mux.HandleFunc("GET /api/v1/torrents/{hash}", s.torrentDetails)
+	s.mux.HandleFunc("DELETE /api/v1/torrents/{hash}", s.removeTorrent)
+	s.mux.HandleFunc("POST /api/v1/torrents/{hash}/pause", s.pauseTorrent)
+	s.mux.HandleFunc(
+		"POST /api/v1/torrents/{hash}/resume",
+		s.resumeTorrent,
+	)
+	s.mux.HandleFunc("GET /api/v1/settings", s.settings)
+	s.mux.HandleFunc("PATCH /api/v1/settings", s.updateSettings)
+	s.mux.HandleFunc("GET /api/v1/stats", s.stats)
+	s.mux.HandleFunc("POST /api/v1/search", s.search)
+	s.mux.HandleFunc("POST /api/v1/port/check", s.checkPort)
+	s.mux.HandleFunc("GET /api/v1/events", s.streamEvents)
+	s.mux.HandleFunc("/transmission/rpc", s.transmissionRPC)
+	s.routeQbit()
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != qbLogin && !s.authorized(r) {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="echo"`)
+		err := errors.New("api: unauthorized")
+		writeError(w, http.StatusUnauthorized, err)
+		return
+	}
+	r = r.WithContext(logging.WithRequestID(r.Context()))
+	r.Body = http.MaxBytesReader(w, r.Body, maxBody)
+	s.mux.ServeHTTP(w, r)
+}
+
+func (s *Server) authorized(r *http.Request) bool {
+	got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		_, got, ok = r.BasicAuth()
+	}
+	if !ok {
+		return s.qb.valid(r)
+	}
+	if s.token == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(got), []byte(s.token)) == 1
+}
+
+func (s *Server) listTorrents(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, s.client.Statuses())
+}
+
+// addTorrent adds the torrent given by exactly one of the request's
+// fields. A magnet link is added without waiting for its metadata, and
+// answered with its progress in fetching it rather than the torrent's
+// status.
+func (s *Server) addTorrent(w http.ResponseWriter, r *http.Request) {
+	var req echo.AddRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	var (
+		t   *echo.Torrent
+		err error
+	)
+	switch {
+	case req.Magnet != "":
+		m, err := s.client.AddMagnetAsync(req.Magnet, req.Options)
+		if err != nil {
+			writeError(w, addStatus(err), err)
+			return
+		}
+		writeJSON(w, http.StatusAccepted, m)
+		return
+	case req.Data != nil:
+		t, err = s.client.AddTorrent(req.Data, req.Options)
+	case req.Path != "":
+		t, err = s.client.AddTorrentFile(req.Path, req.Options)
+	case req.URL != "":
+		t, err = s.client.AddTorrentURL(
+			r.Context(),
+			req.URL,
+			req.Options,
+		)
+	default:
+		err = errors.New("api: nothing to add")
+	}
+	if err != nil {
+		writeError(w, addStatus(err), err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, t.Status())
+}
+
+func addStatus(err error) int {
+	if errors.Is(err, echo.ErrDuplicate) {
+		return http.StatusConflict
+	}
+	return http.StatusBadRequest
+}
+
+func (s *Server) torrentDetails(w http.ResponseWriter, r *http.Request) {
+	hash, ok := s.lookup(w, r)
+	if !ok {
+		return
+	}
+	details, err := s.client.Details(hash)
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, details)
+}
+
+// removeTorrent drops the torrent from the session, and its files too if
+// the deleteFiles parameter is "true".
+func (s *Server) removeTorrent(w http.ResponseWriter, r *http.Request) {
+	hash, ok := s.lookup(w, r)
+	if !ok {
+		return
+	}
+	deleteFiles := r.URL.Query().Get("deleteFiles") == "true"
+	if err := s.remove(hash, deleteFiles); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// remove drops the torrent from the session, and with deleteFiles set,
+// deletes its files once it has stopped.
+func (s *Server) remove(hash [sha1.Size]byte, deleteFiles bool) error {
+	t, ok := s.client.Get(hash)
+	if !ok {
+		return nil
+	}
+	var paths []string
+	if deleteFiles {
+		for _, f := range t.Details().Files {
+			path, _, err := s.client.FilePath(hash, f.Index)
+			if err == nil {
+				paths = append(paths, path)
+			}
+		}
+	}
+	s.client.Remove(hash)
+	return removeFiles(t.DownloadDir(), paths)
+}
+
+// removeFiles deletes paths and then the directories under root they
+// leave empty.
+func removeFiles(root string, paths []string) error {
+	var errs []error
+	for _, path := range paths {
+		err := os.Remove(path)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			errs = append(errs, err)
+			continue
+		}
+		for dir := filepath.Dir(path); ; dir = filepath.Dir(dir) {
+			rel, err := filepath.Rel(root, dir)
+			inside := err == nil && rel != "." &&
+				!strings.HasPrefix(rel, "..")
+			if !inside {
+				break
+			}
+			if os.Remove(dir) != nil {
+				break
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (s *Server) pauseTorrent(w http.ResponseWriter, r *http.Request) {
+	hash, ok := s.lookup(w, r)
+	if !ok {
+		return
+	}
+	if err := s.client.Pause(hash); err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) resumeTorrent(w http.ResponseWriter, r *http.Request) {
+	hash, ok := s.lookup(w, r)
+	if !ok {
+		return
+	}
+	if err := s.client.Resume(hash); err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// lookup returns the info-hash the request's path names, answering the
+// request itself if it is malformed or the session has no such torrent.
+func (s *Server) lookup(
+	w http.ResponseWriter,
+	r *http.Request,
+) ([sha1.Size]byte, bool) {
+	hash, err := ParseInfoHash(r.PathValue("hash"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return hash, false
+	}
+	if _, ok := s.client.Get(hash); !ok {
+		err := fmt.Errorf("api: unknown torrent %x", hash)
+		writeError(w, http.StatusNotFound, err)
+		return hash, false
+	}
+	return hash, true
+}
+
+func (s *Server) settings(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, s.client.GlobalSettings())
+}
+
+func (s *Server) updateSettings(w http.ResponseWriter, r *http.Request) {
+	var patch echo.SettingsPatch
+	if err := json.NewDecoder(r.Body).Decode(&patch); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	settings, err := s.client.UpdateSettings(patch)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, settings)
+}
+
+// Stats is the session's summary along with its transfer totals.
+type Stats struct {
+	echo.Stats
+	Session echo.SessionStats `json:"session"`
+}
+
+func (s *Server) stats(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, Stats{
+		Stats:   s.client.Stats(),
+		Session: s.client.SessionStats(),
+	})
+}
+
+// search runs the query on the indexers, answering once they all have.
+func (s *Server) search(w http.ResponseWriter, r *http.Request) {
+	var q echo.SearchQuery
+	if err := json.NewDecoder(r.Body).Decode(&q); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	results, err := s.client.Search(r.Context(), q)
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, results)
+}
+
+func (s *Server) checkPort(w http.ResponseWriter, r *http.Request) {
+	check, err := s.client.CheckPort(r.Context())
+	if err != nil {
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, check)
+}
+
+// streamEvents serves the session's events as server-sent events, each
+// with its Seq as the ID. A client reconnecting with the ID of the last
+// event it read, as Last-Event-ID or ?since=, first gets those it missed.
+func (s *Server) streamEvents(w http.ResponseWriter, r *http.Request) {
+	since, err := lastEventID(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	rc := http.NewResponseController(w)
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	ctx := r.Context()
+	keepAlive := time.NewTicker(keepAliveInterval)
+	defer keepAlive.Stop()
+	for ctx.Err() == nil {
+		// The engine cuts a stream off when it falls behind; the next
+		// picks up after the last event sent.
+		events := s.client.Stream(ctx, since)
+	stream:
+		for {
+			select {
+			case e, ok := <-events:
+				if !ok {
+					break stream
+				}
+				since = e.Seq
+				err = writeEvent(w, e)
+			case <-keepAlive.C:
+				_, err = io.WriteString(w, ": keep-alive\n\n")
+			}
+			if err == nil {
+				err = rc.Flush()
+			}
+			if err != nil {
+				return
+			}
+		}
+	}
+}
+
+// lastEventID returns the ID of the last event a client read, zero for a
+// client that has read none.
+func lastEventID(r *http.Request) (uint64, error) {
+	id := cmp.Or(r.Header.Get("Last-Event-ID"), r.FormValue("since"))
+	if id == "" {
+		return 0, nil
+	}
+	since, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("api: bad event id %q", id)
+	}
+	return since, nil
+}
+
+func writeEvent(w io.Writer, e echo.Event) error {
+	data, err := json.Marshal(e.Data)
+	if err != nil {
+		slog.Debug(
+			"encoding event failed",
+			slog.String("event", e.Name),
+			slog.String("error", err.Error()),
+		)
+		return nil
+	}
+	_, err = fmt.Fprintf(
+		w,
+		"id: %d\nevent: %s\ndata: %s\n\n",
+		e.Seq,
+		e.Name,
+		data,
+	)
+	return err
+}
+
+// ParseInfoHash parses an info-hash written in hex.
+func ParseInfoHash(s string) ([sha1.Size]byte, error) {
+	var hash [sha1.Size]byte
+	raw, err := hex.DecodeString(s)
+	if err != nil || len(raw) != sha1.Size {
+		return hash, fmt.Errorf("api: bad info-hash %q", s)
+	}
+	return [sha1.Size]byte(raw), nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.Debug(
+			"writing api response failed",
+			slog.String("error", err.Error()),
+		)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+// LoadToken returns the token saved at path, first saving a new random
+// one, readable only by its owner, if there is none.
+func LoadToken(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err == nil {
+		if token := strings.TrimSpace(string(data)); token != "" {
+			return token, nil
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return "", err
+	}
+
+	var raw [32]byte
+	if _, err := rand.Read(raw[:]); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(raw[:])
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return "", err
+	}
+	err = os.WriteFile(path, []byte(token+"\n"), 0o600)
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
@@ -0,0 +1,288 @@
+package api
+
+import (
+	"bufio"
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prxssh/echo/pkg/echo"
+)
+
+const testToken = "secret"
+
+// testServer serves a client without the DHT, listening for peers on a
+// free port, after applying opts to its config.
+func testServer(
+	t *testing.T,
+	opts ...func(*echo.Config),
+) *httptest.Server {
+	t.Helper()
+	dir := t.TempDir()
+	cfg := echo.DefaultConfig()
+	cfg.DisableDHT = true
+	cfg.ListenPort = 0
+	cfg.DownloadDir = filepath.Join(dir, "downloads")
+	cfg.TorrentsDir = filepath.Join(dir, "torrents")
+	cfg.SessionPath = filepath.Join(dir, "session.json")
+	cfg.HistoryPath = ""
+	cfg.JournalDir = ""
+	cfg.SettingsPath = filepath.Join(dir, "settings.json")
+	cfg.IndexPath = ""
+	cfg.CredentialsPath = ""
+	cfg.GeoIP = echo.GeoIPConfig{}
+	// Stats pushed often keep events coming for the event stream.
+	cfg.StatsInterval = 20 * time.Millisecond
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	client := echo.New(&cfg)
+	if err := client.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Close(context.Background()) })
+
+	srv := httptest.NewServer(New(client, testToken))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func do(
+	t *testing.T,
+	srv *httptest.Server,
+	method, path, token, body string,
+) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(
+		method,
+		srv.URL+path,
+		strings.NewReader(body),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func TestAuth(t *testing.T) {
+	srv := testServer(t)
+	for _, token := range []string{"", "wrong"} {
+		resp := do(t, srv, "GET", "/api/v1/torrents", token, "")
+		if resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("token %q: status %d", token, resp.StatusCode)
+		}
+	}
+	resp := do(t, srv, "GET", "/api/v1/torrents", testToken, "")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status %d", resp.StatusCode)
+	}
+	var list []echo.TorrentStatus
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 0 {
+		t.Errorf("%d torrents in a new session", len(list))
+	}
+}
+
+func TestTorrentErrors(t *testing.T) {
+	srv := testServer(t)
+	unknown := strings.Repeat("ab", 20)
+	for _, tc := range []struct {
+		method, path, body string
+		status             int
+	}{
+		{"GET", "/api/v1/torrents/xyz", "", http.StatusBadRequest},
+		{"GET", "/api/v1/torrents/" + unknown, "", http.StatusNotFound},
+		{
+			"POST",
+			"/api/v1/torrents/" + unknown + "/pause",
+			"",
+			http.StatusNotFound,
+		},
+		{
+			"DELETE",
+			"/api/v1/torrents/" + unknown,
+			"",
+			http.StatusNotFound,
+		},
+		{"POST", "/api/v1/torrents", "{}", http.StatusBadRequest},
+		{
+			"POST",
+			"/api/v1/torrents",
+			`{"data":"bm90IGEgdG9ycmVudA=="}`,
+			http.StatusBadRequest,
+		},
+	} {
+		resp := do(t, srv, tc.method, tc.path, testToken, tc.body)
+		if resp.StatusCode != tc.status {
+			t.Errorf(
+				"%s %s: status %d, want %d",
+				tc.method,
+				tc.path,
+				resp.StatusCode,
+				tc.status,
+			)
+		}
+	}
+}
+
+// TestPortCheck checks that the port asked about is the TCP one peers
+// connect to.
+func TestPortCheck(t *testing.T) {
+	asked := make(chan string, 1)
+	service := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			asked <- r.URL.Query().Get("port")
+			fmt.Fprint(w, "open")
+		},
+	))
+	defer service.Close()
+	srv := testServer(t, func(cfg *echo.Config) {
+		cfg.PortCheckURL = service.URL + "/?port={port}"
+	})
+
+	resp := do(t, srv, "POST", "/api/v1/port/check", testToken, "")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status %d", resp.StatusCode)
+	}
+	var check echo.PortCheck
+	if err := json.NewDecoder(resp.Body).Decode(&check); err != nil {
+		t.Fatal(err)
+	}
+	if check.Protocol != "tcp" || check.Status != echo.PortOpen {
+		t.Errorf("check = %+v, want an open tcp port", check)
+	}
+	if port := <-asked; check.Port == 0 ||
+		port != strconv.Itoa(int(check.Port)) {
+		t.Errorf(
+			"service asked about port %s, check has %d",
+			port,
+			check.Port,
+		)
+	}
+}
+
+func TestUpdateSettings(t *testing.T) {
+	srv := testServer(t)
+	resp := do(
+		t,
+		srv,
+		"PATCH",
+		"/api/v1/settings",
+		testToken,
+		`{"uploadLimit":1024,"dhtReadOnly":true}`,
+	)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status %d", resp.StatusCode)
+	}
+
+	resp = do(t, srv, "GET", "/api/v1/settings", testToken, "")
+	var s echo.GlobalSettings
+	if err := json.NewDecoder(resp.Body).Decode(&s); err != nil {
+		t.Fatal(err)
+	}
+	if s.UploadLimit != 1024 {
+		t.Errorf("upload limit %d after the update", s.UploadLimit)
+	}
+	if !s.DHTReadOnly {
+		t.Error("dht not read-only after the update")
+	}
+}
+
+// readEventIDs returns the IDs of the first n events on the stream at
+// path, and the name of the first.
+func readEventIDs(
+	t *testing.T,
+	srv *httptest.Server,
+	path string,
+	n int,
+) ([]uint64, string) {
+	t.Helper()
+	resp := do(t, srv, "GET", path, testToken, "")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("%s: status %d", path, resp.StatusCode)
+	}
+	var (
+		ids   []uint64
+		first string
+	)
+	lines := bufio.NewScanner(resp.Body)
+	for lines.Scan() {
+		line := lines.Text()
+		if line == "" && len(ids) == n {
+			break
+		}
+		if name, ok := strings.CutPrefix(line, "event: "); ok {
+			first = cmp.Or(first, name)
+		}
+		if id, ok := strings.CutPrefix(line, "id: "); ok {
+			seq, err := strconv.ParseUint(id, 10, 64)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ids = append(ids, seq)
+		}
+	}
+	if len(ids) < n {
+		t.Fatalf("%s: %d events, want %d", path, len(ids), n)
+	}
+	return ids, first
+}
+
+func TestEventStream(t *testing.T) {
+	srv := testServer(t)
+	ids, _ := readEventIDs(t, srv, "/api/v1/events", 3)
+	for i := 1; i < len(ids); i++ {
+		if ids[i] != ids[i-1]+1 {
+			t.Fatalf("ids %v are not consecutive", ids)
+		}
+	}
+
+	// Reconnecting after the first event replays those that followed.
+	path := fmt.Sprintf("/api/v1/events?since=%d", ids[0])
+	again, _ := readEventIDs(t, srv, path, 2)
+	if again[0] != ids[1] || again[1] != ids[2] {
+		t.Errorf("ids %v after %d, want %v", again, ids[0], ids[1:])
+	}
+
+	// An ID the session has not reached starts over.
+	_, first := readEventIDs(t, srv, "/api/v1/events?since=999999999", 1)
+	if first != echo.StreamReset {
+		t.Errorf("first event %q, want %q", first, echo.StreamReset)
+	}
+
+	resp := do(t, srv, "GET", "/api/v1/events?since=x", testToken, "")
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("bad id: status %d", resp.StatusCode)
+	}
+}
+
+func TestLoadToken(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "echo", "api-token")
+	first, err := LoadToken(path)
+	if err != nil || first == "" {
+		t.Fatalf("token %q, %v", first, err)
+	}
+	again, err := LoadToken(path)
+	if err != nil || again != first {
+		t.Fatalf("token %q then %q, %v", first, again, err)
+	}
+}
@@ -0,0 +1,573 @@
+package api
+
+import (
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prxssh/echo/internal/torrent"
+	"github.com/prxssh/echo/pkg/echo"
+)
+
+// qBittorrent's WebUI API v2, as far as the *arr apps and other automation
+// use it. See https://github.com/qbittorrent/qBittorrent/wiki.
+const (
+	qbVersion    = "v4.6.0"
+	qbAPIVersion = "2.9.3"
+	// qbLogin is the one path served without authentication.
+	qbLogin = "/api/v2/auth/login"
+	// sidCookie names the cookie a login hands out.
+	sidCookie = "SID"
+	// sidTTL is how long a login lasts without being used.
+	sidTTL = time.Hour
+	// qbNoETA is what qBittorrent gives as the ETA of a torrent that
+	// isn't downloading.
+	qbNoETA = 8640000
+)
+
+// qbit holds the sessions logged in to qBittorrent's API, by their SID,
+// with when each expires.
+type qbit struct {
+	mu   sync.Mutex
+	sids map[string]time.Time
+}
+
+func newQbit() *qbit {
+	return &qbit{sids: make(map[string]time.Time)}
+}
+
+func (q *qbit) login() string {
+	var raw [16]byte
+	_, _ = rand.Read(raw[:])
+	sid := hex.EncodeToString(raw[:])
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := time.Now()
+	for s, expires := range q.sids {
+		if now.After(expires) {
+			delete(q.sids, s)
+		}
+	}
+	q.sids[sid] = now.Add(sidTTL)
+	return sid
+}
+
+// valid reports whether the request carries the SID of a live login, and
+// keeps that login alive.
+func (q *qbit) valid(r *http.Request) bool {
+	cookie, err := r.Cookie(sidCookie)
+	if err != nil {
+		return false
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	expires, ok := q.sids[cookie.Value]
+	if !ok || time.Now().After(expires) {
+		return false
+	}
+	q.sids[cookie.Value] = time.Now().Add(sidTTL)
+	return true
+}
+
+func (q *qbit) logout(r *http.Request) {
+	if cookie, err := r.Cookie(sidCookie); err == nil {
+		q.mu.Lock()
+		delete(q.sids, cookie.Value)
+		q.mu.Unlock()
+	}
+}
+
+// routeQbit serves qBittorrent's API under /api/v2. Its actions are all
+// POSTs of forms.
+func (s *Server) routeQbit() {
+	get := func(path string, h http.HandlerFunc) {
+		s.mux.HandleFunc("GET /api/v2/"+path, h)
+	}
+	post := func(path string, h http.HandlerFunc) {
+		s.mux.HandleFunc("POST /api/v2/"+path, h)
+	}
+	post("auth/login", s.qbLogin)
+	post("auth/logout", s.qbLogout)
+	get("app/version", s.qbVersion)
+	get("app/webapiVersion", s.qbAPIVersion)
+	get("app/preferences", s.qbPreferences)
+	get("transfer/info", s.qbTransferInfo)
+	get("torrents/info", s.qbTorrentsInfo)
+	get("torrents/properties", s.qbProperties)
+	get("torrents/categories", s.qbCategories)
+	post("torrents/add", s.qbAdd)
+	post("torrents/delete", s.qbDelete)
+	post("torrents/pause", s.qbEach(s.client.Pause))
+	post("torrents/stop", s.qbEach(s.client.Pause))
+	post("torrents/resume", s.qbEach(s.client.Resume))
+	post("torrents/start", s.qbEach(s.client.Resume))
+	post("torrents/recheck", s.qbEach(s.client.Recheck))
+	post("torrents/reannounce", s.qbEach(s.client.Reannounce))
+	post("torrents/topPrio", s.qbEach(s.client.MoveToTop))
+	post("torrents/bottomPrio", s.qbEach(s.client.MoveToBottom))
+	post("torrents/setCategory", s.qbSetCategory)
+	post("torrents/createCategory", s.qbCreateCategory)
+	post("torrents/addTags", s.qbTags(true))
+	post("torrents/removeTags", s.qbTags(false))
+	post("torrents/increasePrio", s.qbEach(s.client.MoveUp))
+	post("torrents/decreasePrio", s.qbEach(s.client.MoveDown))
+	post("torrents/setForceStart", s.qbEach(s.client.Resume))
+	post("torrents/setShareLimits", s.qbIgnore)
+	post("torrents/setSuperSeeding", s.qbIgnore)
+}
+
+// qbLogin hands out a session cookie for the token given as the password;
+// the username is ignored. As qBittorrent does, it answers "Fails." with
+// 200 OK to a wrong password.
+func (s *Server) qbLogin(w http.ResponseWriter, r *http.Request) {
+	password := []byte(r.FormValue("password"))
+	if s.token == "" ||
+		subtle.ConstantTimeCompare(password, []byte(s.token)) != 1 {
+		writeText(w, http.StatusOK, "Fails.")
+		return
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     sidCookie,
+		Value:    s.qb.login(),
+		Path:     "/",
+		HttpOnly: true,
+		SameSite: http.SameSiteStrictMode,
+	})
+	writeText(w, http.StatusOK, "Ok.")
+}
+
+func (s *Server) qbLogout(w http.ResponseWriter, r *http.Request) {
+	s.qb.logout(r)
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) qbVersion(w http.ResponseWriter, _ *http.Request) {
+	writeText(w, http.StatusOK, qbVersion)
+}
+
+func (s *Server) qbAPIVersion(w http.ResponseWriter, _ *http.Request) {
+	writeText(w, http.StatusOK, qbAPIVersion)
+}
+
+func (s *Server) qbPreferences(w http.ResponseWriter, _ *http.Request) {
+	g := s.client.GlobalSettings()
+	writeJSON(w, http.StatusOK, map[string]any{
+		"save_path":                g.DownloadDir,
+		"dl_limit":                 g.DownloadLimit,
+		"up_limit":                 g.UploadLimit,
+		"max_connec":               g.MaxConnections,
+		"queueing_enabled":         g.MaxActiveDownloads > 0,
+		"max_active_downloads":     g.MaxActiveDownloads,
+		"max_active_uploads":       g.MaxActiveSeeds,
+		"max_ratio_enabled":        false,
+		"max_ratio":                -1,
+		"max_seeding_time_enabled": false,
+		"max_seeding_time":         -1,
+		"dht":                      true,
+	})
+}
+
+func (s *Server) qbTransferInfo(w http.ResponseWriter, _ *http.Request) {
+	g := s.client.GlobalSettings()
+	stats := s.client.SessionStats()
+	var down, up float64
+	for _, st := range s.client.Statuses() {
+		down += st.DownloadRate
+		up += st.UploadRate
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"dl_info_speed":     int64(down),
+		"dl_info_data":      stats.Session.Downloaded,
+		"up_info_speed":     int64(up),
+		"up_info_data":      stats.Session.Uploaded,
+		"dl_rate_limit":     g.DownloadLimit,
+		"up_rate_limit":     g.UploadLimit,
+		"dht_nodes":         s.client.Stats().DHT.Nodes,
+		"connection_status": "connected",
+	})
+}
+
+// qbTorrentsInfo lists the torrents the filter, category, tag and hashes
+// parameters pick, in queue order.
+func (s *Server) qbTorrentsInfo(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	hashes := qbHashes(q.Get("hashes"))
+	category, byCategory := q["category"]
+	tag := q.Get("tag")
+
+	list := []map[string]any{}
+	for _, st := range s.client.Statuses() {
+		hash, err := ParseInfoHash(st.InfoHash)
+		if err != nil {
+			continue
+		}
+		if hashes != nil && !slices.Contains(hashes, hash) {
+			continue
+		}
+		t, ok := s.client.Get(hash)
+		if !ok || !qbFilter(q.Get("filter"), st) {
+			continue
+		}
+		labels, _ := s.client.Labels(hash)
+		if byCategory && labels.Category != category[0] {
+			continue
+		}
+		if tag != "" && !slices.Contains(labels.Tags, tag) {
+			continue
+		}
+		list = append(list, qbTorrent(t, st, labels))
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+func qbTorrent(
+	t *echo.Torrent,
+	st echo.TorrentStatus,
+	labels echo.Labels,
+) map[string]any {
+	dir := t.DownloadDir()
+	return map[string]any{
+		"hash":             st.InfoHash,
+		"name":             st.Name,
+		"size":             st.Size,
+		"total_size":       st.Size,
+		"progress":         st.Progress / 100,
+		"dlspeed":          int64(st.DownloadRate),
+		"upspeed":          int64(st.UploadRate),
+		"priority":         st.QueuePosition + 1,
+		"num_seeds":        st.Seeds,
+		"num_leechs":       st.Peers - st.Seeds,
+		"ratio":            st.Ratio,
+		"eta":              qbETA(st),
+		"state":            qbState(st),
+		"category":         labels.Category,
+		"tags":             strings.Join(labels.Tags, ", "),
+		"save_path":        dir,
+		"content_path":     filepath.Join(dir, st.Name),
+		"downloaded":       st.Downloaded,
+		"uploaded":         st.Uploaded,
+		"amount_left":      st.Left,
+		"completed":        st.Size - min(st.Left, st.Size),
+		"dl_limit":         st.DownloadLimit,
+		"up_limit":         st.UploadLimit,
+		"seq_dl":           false,
+		"force_start":      false,
+		"super_seeding":    false,
+		"auto_tmm":         false,
+		"max_ratio":        -1,
+		"max_seeding_time": -1,
+		"magnet_uri":       t.Metainfo.MagnetURI(),
+	}
+}
+
+func qbETA(st echo.TorrentStatus) int64 {
+	if st.ETA < 0 {
+		return qbNoETA
+	}
+	return int64(st.ETA.Seconds())
+}
+
+// qbState gives a torrent's state as qBittorrent names it, telling apart
+// downloads from seeds, and transfers from stalled ones.
+func qbState(st echo.TorrentStatus) string {
+	done := st.Left == 0
+	suffix := "DL"
+	if done {
+		suffix = "UP"
+	}
+	switch st.State {
+	case torrent.StatePaused:
+		return "paused" + suffix
+	case torrent.StateQueued:
+		return "queued" + suffix
+	case torrent.StateChecking:
+		return "checking" + suffix
+	case torrent.StateErrored:
+		return "error"
+	case torrent.StateMoving:
+		return "moving"
+	case torrent.StateSeeding:
+		if st.UploadRate > 0 {
+			return "uploading"
+		}
+		return "stalledUP"
+	}
+	if done {
+		return "stalledUP"
+	}
+	if st.DownloadRate > 0 {
+		return "downloading"
+	}
+	return "stalledDL"
+}
+
+func qbFilter(filter string, st echo.TorrentStatus) bool {
+	switch filter {
+	case "downloading":
+		return st.Left > 0 && st.State != torrent.StatePaused
+	case "seeding":
+		return st.State == torrent.StateSeeding
+	case "completed":
+		return st.Left == 0
+	case "paused", "stopped":
+		return st.State == torrent.StatePaused
+	case "resumed", "running":
+		return st.State != torrent.StatePaused
+	case "active":
+		return st.DownloadRate > 0 || st.UploadRate > 0
+	case "inactive":
+		return st.DownloadRate == 0 && st.UploadRate == 0
+	case "stalled":
+		return st.State.Active() &&
+			st.DownloadRate == 0 && st.UploadRate == 0
+	case "errored":
+		return st.State == torrent.StateErrored
+	case "checking":
+		return st.State == torrent.StateChecking
+	case "moving":
+		return st.State == torrent.StateMoving
+	}
+	return true
+}
+
+func (s *Server) qbProperties(w http.ResponseWriter, r *http.Request) {
+	hash, err := ParseInfoHash(r.URL.Query().Get("hash"))
+	if err != nil {
+		writeText(w, http.StatusNotFound, "Torrent hash was not found")
+		return
+	}
+	t, ok := s.client.Get(hash)
+	if !ok {
+		writeText(w, http.StatusNotFound, "Torrent hash was not found")
+		return
+	}
+	st := t.Status()
+	writeJSON(w, http.StatusOK, map[string]any{
+		"save_path":            t.DownloadDir(),
+		"total_size":           st.Size,
+		"total_downloaded":     st.Downloaded,
+		"total_uploaded":       st.Uploaded,
+		"share_ratio":          st.Ratio,
+		"dl_speed":             int64(st.DownloadRate),
+		"up_speed":             int64(st.UploadRate),
+		"dl_limit":             st.DownloadLimit,
+		"up_limit":             st.UploadLimit,
+		"peers":                st.Peers - st.Seeds,
+		"seeds":                st.Seeds,
+		"pieces_num":           t.Metainfo.Info.NumPieces,
+		"piece_size":           t.Metainfo.Info.PieceLength,
+		"eta":                  qbETA(st),
+		"comment":              t.Metainfo.Comment,
+		"seeding_time":         0,
+		"time_elapsed":         0,
+		"addition_date":        -1,
+		"completion_date":      -1,
+		"creation_date":        t.Metainfo.CreationDate.Unix(),
+		"nb_connections":       st.Peers,
+		"nb_connections_limit": -1,
+	})
+}
+
+func (s *Server) qbCategories(w http.ResponseWriter, _ *http.Request) {
+	cats := make(map[string]any)
+	for _, c := range s.client.Categories() {
+		cats[c.Name] = map[string]string{
+			"name":     c.Name,
+			"savePath": c.SavePath,
+		}
+	}
+	writeJSON(w, http.StatusOK, cats)
+}
+
+func (s *Server) qbCreateCategory(w http.ResponseWriter, r *http.Request) {
+	err := s.client.AddCategory(echo.Category{
+		Name:     r.FormValue("category"),
+		SavePath: r.FormValue("savePath"),
+	})
+	if err != nil {
+		writeText(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) qbSetCategory(w http.ResponseWriter, r *http.Request) {
+	name := r.FormValue("category")
+	s.qbEach(func(hash [sha1.Size]byte) error {
+		return s.client.SetCategory(hash, name, false)
+	})(w, r)
+}
+
+// qbTags returns the handler adding the tags given to each torrent named,
+// or with add false, removing them.
+func (s *Server) qbTags(add bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		tags := splitList(r.FormValue("tags"), ",")
+		s.qbEach(func(hash [sha1.Size]byte) error {
+			labels, err := s.client.Labels(hash)
+			if err != nil {
+				return err
+			}
+			named := func(t string) bool {
+				return slices.Contains(tags, t)
+			}
+			kept := slices.Clone(labels.Tags)
+			kept = slices.DeleteFunc(kept, named)
+			if add {
+				kept = append(kept, tags...)
+			}
+			return s.client.SetTags(hash, kept)
+		})(w, r)
+	}
+}
+
+// qbAdd adds each torrent uploaded as a file and each magnet link or URL
+// given, one per line, in urls. Magnet links are added without waiting
+// for their metadata. As qBittorrent does, it answers "Fails." with 415
+// Unsupported Media Type if none could be added.
+func (s *Server) qbAdd(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseMultipartForm(maxBody); err != nil &&
+		!errors.Is(err, http.ErrNotMultipart) {
+		writeText(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	opts := echo.AddOptions{
+		Category: r.FormValue("category"),
+		Tags:     splitList(r.FormValue("tags"), ","),
+		SavePath: r.FormValue("savepath"),
+		Paused: r.FormValue("paused") == "true" ||
+			r.FormValue("stopped") == "true",
+		Sequential: r.FormValue("sequentialDownload") == "true",
+	}
+
+	added, tried := 0, 0
+	note := func(err error) {
+		tried++
+		if err == nil || errors.Is(err, echo.ErrDuplicate) {
+			added++
+		}
+	}
+	for _, u := range splitList(r.FormValue("urls"), "\n") {
+		if strings.HasPrefix(u, "magnet:") {
+			_, err := s.client.AddMagnetAsync(u, opts)
+			note(err)
+			continue
+		}
+		_, err := s.client.AddTorrentURL(r.Context(), u, opts)
+		note(err)
+	}
+	if r.MultipartForm != nil {
+		for _, fh := range r.MultipartForm.File["torrents"] {
+			note(s.qbAddFile(fh, opts))
+		}
+	}
+
+	if tried == 0 || added == 0 {
+		writeText(w, http.StatusUnsupportedMediaType, "Fails.")
+		return
+	}
+	writeText(w, http.StatusOK, "Ok.")
+}
+
+func (s *Server) qbAddFile(
+	fh *multipart.FileHeader,
+	opts echo.AddOptions,
+) error {
+	f, err := fh.Open()
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return err
+	}
+	_, err = s.client.AddTorrent(data, opts)
+	return err
+}
+
+// qbDelete removes the torrents named and, if deleteFiles is "true",
+// their files.
+func (s *Server) qbDelete(w http.ResponseWriter, r *http.Request) {
+	deleteFiles := r.FormValue("deleteFiles") == "true"
+	s.qbEach(func(hash [sha1.Size]byte) error {
+		return s.remove(hash, deleteFiles)
+	})(w, r)
+}
+
+// qbIgnore accepts a request for a setting the engine doesn't have, so
+// that tools making it as a matter of course carry on.
+func (s *Server) qbIgnore(w http.ResponseWriter, _ *http.Request) {
+	w.WriteHeader(http.StatusOK)
+}
+
+// qbEach returns the handler calling fn for each torrent the hashes form
+// value names, "all" naming every one.
+func (s *Server) qbEach(fn func([sha1.Size]byte) error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		hashes := qbHashes(r.FormValue("hashes"))
+		if hashes == nil {
+			for _, t := range s.client.List() {
+				hashes = append(hashes, t.Metainfo.Info.Hash)
+			}
+		}
+		var errs []error
+		for _, hash := range hashes {
+			if _, ok := s.client.Get(hash); ok {
+				errs = append(errs, fn(hash))
+			}
+		}
+		if err := errors.Join(errs...); err != nil {
+			writeText(w, http.StatusConflict, err.Error())
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// qbHashes parses qBittorrent's list of info-hashes separated by "|".
+// Nil, for none or "all", means every torrent.
+func qbHashes(s string) [][sha1.Size]byte {
+	if s == "" || s == "all" {
+		return nil
+	}
+	hashes := [][sha1.Size]byte{}
+	for _, h := range strings.Split(s, "|") {
+		if hash, err := ParseInfoHash(h); err == nil {
+			hashes = append(hashes, hash)
+		}
+	}
+	return hashes
+}
+
+// splitList splits s at sep, dropping blank items.
+func splitList(s, sep string) []string {
+	var items []string
+	for _, item := range strings.Split(s, sep) {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func writeText(w http.ResponseWriter, status int, text string) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(status)
+	_, _ = io.WriteString(w, text)
+}
@@ -0,0 +1,113 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"testing"
+
+	"github.com/prxssh/echo/internal/torrent"
+	"github.com/prxssh/echo/pkg/echo"
+)
+
+func TestQbitLogin(t *testing.T) {
+	srv := testServer(t)
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := srv.Client()
+	client.Jar = jar
+
+	resp, err := client.Get(srv.URL + "/api/v2/torrents/info")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("status %d before logging in", resp.StatusCode)
+	}
+
+	login := func(password string) string {
+		resp, err := client.PostForm(
+			srv.URL+qbLogin,
+			url.Values{
+				"username": {"admin"},
+				"password": {password},
+			},
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return string(body)
+	}
+	if got := login("wrong"); got != "Fails." {
+		t.Fatalf("wrong password: %q", got)
+	}
+	if got := login(testToken); got != "Ok." {
+		t.Fatalf("login: %q", got)
+	}
+
+	resp, err = client.Get(srv.URL + "/api/v2/torrents/info")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var list []map[string]any
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		t.Fatal(err)
+	}
+	if list == nil || len(list) != 0 {
+		t.Errorf("torrents %v in a new session", list)
+	}
+
+	resp, err = client.PostForm(
+		srv.URL+"/api/v2/torrents/add",
+		url.Values{"urls": {""}},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnsupportedMediaType {
+		t.Errorf("adding nothing: status %d", resp.StatusCode)
+	}
+}
+
+func TestQbitState(t *testing.T) {
+	for _, tc := range []struct {
+		state    torrent.State
+		left     uint64
+		down, up float64
+		want     string
+	}{
+		{torrent.StatePaused, 1, 0, 0, "pausedDL"},
+		{torrent.StatePaused, 0, 0, 0, "pausedUP"},
+		{torrent.StateQueued, 1, 0, 0, "queuedDL"},
+		{torrent.StateSeeding, 0, 0, 0, "stalledUP"},
+		{torrent.StateSeeding, 0, 0, 1, "uploading"},
+		{torrent.StateDownloading, 1, 0, 0, "stalledDL"},
+		{torrent.StateDownloading, 1, 1, 0, "downloading"},
+		{torrent.StateErrored, 1, 0, 0, "error"},
+	} {
+		st := echo.TorrentStatus{
+			State:        tc.state,
+			Left:         tc.left,
+			DownloadRate: tc.down,
+			UploadRate:   tc.up,
+		}
+		if got := qbState(st); got != tc.want {
+			t.Errorf(
+				"%s with %d left: %s, want %s",
+				tc.state,
+				tc.left,
+				got,
+				tc.want,
+			)
+		}
+	}
+}
@@ -0,0 +1,671 @@
+package api
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prxssh/echo/internal/torrent"
+	"github.com/prxssh/echo/pkg/echo"
+)
+
+// Transmission's RPC, as far as remote GUIs and the *arr apps use it. See
+// https://github.com/transmission/transmission/blob/main/docs/rpc-spec.md.
+const (
+	rpcVersion    = 17
+	rpcVersionMin = 14
+	// sessionHeader carries the token a client must echo back, against
+	// cross-site request forgery.
+	sessionHeader = "X-Transmission-Session-Id"
+	// kilo is Transmission's unit for rates, which it gives in kB/s.
+	kilo = 1000
+)
+
+// Transmission's torrent statuses.
+const (
+	trStopped = iota
+	trCheckWait
+	trCheck
+	trDownloadWait
+	trDownload
+	trSeedWait
+	trSeed
+)
+
+// transmission holds what Transmission's RPC keeps that the engine does
+// not: its session ID and the numeric IDs it gives torrents.
+type transmission struct {
+	sessionID string
+
+	mu     sync.Mutex
+	ids    map[[sha1.Size]byte]int
+	hashes map[int][sha1.Size]byte
+	nextID int
+}
+
+func newTransmission() *transmission {
+	var raw [16]byte
+	_, _ = rand.Read(raw[:])
+	return &transmission{
+		sessionID: hex.EncodeToString(raw[:]),
+		ids:       make(map[[sha1.Size]byte]int),
+		hashes:    make(map[int][sha1.Size]byte),
+		nextID:    1,
+	}
+}
+
+// id returns the torrent's numeric ID, giving it the next one the first
+// time it is asked for. IDs are not reused within a run.
+func (tr *transmission) id(hash [sha1.Size]byte) int {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+
+	id, ok := tr.ids[hash]
+	if !ok {
+		id = tr.nextID
+		tr.nextID++
+		tr.ids[hash] = id
+		tr.hashes[id] = hash
+	}
+	return id
+}
+
+func (tr *transmission) hash(id int) ([sha1.Size]byte, bool) {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+
+	hash, ok := tr.hashes[id]
+	return hash, ok
+}
+
+type rpcRequest struct {
+	Method    string          `json:"method"`
+	Arguments json.RawMessage `json:"arguments"`
+	Tag       *int            `json:"tag,omitempty"`
+}
+
+type rpcResponse struct {
+	Result    string `json:"result"`
+	Arguments any    `json:"arguments"`
+	Tag       *int   `json:"tag,omitempty"`
+}
+
+// transmissionRPC serves Transmission's RPC. As Transmission does, it
+// turns away a request without the current session ID with 409 Conflict
+// and the ID to retry with.
+func (s *Server) transmissionRPC(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set(sessionHeader, s.tr.sessionID)
+	if r.Header.Get(sessionHeader) != s.tr.sessionID {
+		w.WriteHeader(http.StatusConflict)
+		return
+	}
+
+	var req rpcRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if len(req.Arguments) == 0 {
+		req.Arguments = json.RawMessage("{}")
+	}
+
+	args, err := s.rpc(r, req.Method, req.Arguments)
+	resp := rpcResponse{Result: "success", Arguments: args, Tag: req.Tag}
+	if err != nil {
+		resp.Result = err.Error()
+	}
+	if resp.Arguments == nil {
+		resp.Arguments = struct{}{}
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) rpc(
+	r *http.Request,
+	method string,
+	raw json.RawMessage,
+) (any, error) {
+	switch method {
+	case "session-get":
+		return s.trSessionGet(), nil
+	case "session-set":
+		return nil, s.trSessionSet(raw)
+	case "session-stats":
+		return s.trSessionStats(), nil
+	case "port-test":
+		check, err := s.client.CheckPort(r.Context())
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{
+			"port-is-open": check.Status == echo.PortOpen,
+		}, nil
+	case "torrent-get":
+		return s.trTorrentGet(raw)
+	case "torrent-add":
+		return s.trTorrentAdd(r, raw)
+	case "torrent-remove":
+		return nil, s.trTorrentRemove(raw)
+	case "torrent-set":
+		return nil, s.trTorrentSet(raw)
+	case "torrent-start", "torrent-start-now":
+		return nil, s.trEach(raw, s.client.Resume)
+	case "torrent-stop":
+		return nil, s.trEach(raw, s.client.Pause)
+	case "torrent-verify":
+		return nil, s.trEach(raw, s.client.Recheck)
+	case "torrent-reannounce":
+		return nil, s.trEach(raw, s.client.Reannounce)
+	case "queue-move-top":
+		return nil, s.trEach(raw, s.client.MoveToTop)
+	case "queue-move-up":
+		return nil, s.trEach(raw, s.client.MoveUp)
+	case "queue-move-down":
+		return nil, s.trEach(raw, s.client.MoveDown)
+	case "queue-move-bottom":
+		return nil, s.trEach(raw, s.client.MoveToBottom)
+	}
+	return nil, errors.New("method name not recognized")
+}
+
+func (s *Server) trSessionGet() map[string]any {
+	g := s.client.GlobalSettings()
+	encryption := "preferred"
+	switch g.Encryption {
+	case echo.EncryptionRequire:
+		encryption = "required"
+	case echo.EncryptionDisable:
+		encryption = "tolerated"
+	}
+	return map[string]any{
+		"version":                  "4.0.0 (echo)",
+		"rpc-version":              rpcVersion,
+		"rpc-version-minimum":      rpcVersionMin,
+		"session-id":               s.tr.sessionID,
+		"download-dir":             g.DownloadDir,
+		"speed-limit-down":         g.DownloadLimit / kilo,
+		"speed-limit-down-enabled": g.DownloadLimit > 0,
+		"speed-limit-up":           g.UploadLimit / kilo,
+		"speed-limit-up-enabled":   g.UploadLimit > 0,
+		"peer-limit-global":        g.MaxConnections,
+		"download-queue-size":      g.MaxActiveDownloads,
+		"download-queue-enabled":   g.MaxActiveDownloads > 0,
+		"seed-queue-size":          g.MaxActiveSeeds,
+		"seed-queue-enabled":       g.MaxActiveSeeds > 0,
+		"dht-enabled":              true,
+		"encryption":               encryption,
+		"units": map[string]any{
+			"speed-units": []string{
+				"kB/s",
+				"MB/s",
+				"GB/s",
+				"TB/s",
+			},
+			"speed-bytes":  kilo,
+			"size-units":   []string{"kB", "MB", "GB", "TB"},
+			"size-bytes":   kilo,
+			"memory-units": []string{"KiB", "MiB", "GiB", "TiB"},
+			"memory-bytes": 1024,
+		},
+	}
+}
+
+// trSessionSet applies the keys of session-set the engine has settings
+// for; the rest are ignored.
+func (s *Server) trSessionSet(raw json.RawMessage) error {
+	var args struct {
+		DownloadDir     *string `json:"download-dir"`
+		DownLimit       *int64  `json:"speed-limit-down"`
+		DownLimited     *bool   `json:"speed-limit-down-enabled"`
+		UpLimit         *int64  `json:"speed-limit-up"`
+		UpLimited       *bool   `json:"speed-limit-up-enabled"`
+		PeerLimit       *int    `json:"peer-limit-global"`
+		DownloadQueue   *int    `json:"download-queue-size"`
+		DownloadQueued  *bool   `json:"download-queue-enabled"`
+		SeedQueue       *int    `json:"seed-queue-size"`
+		SeedQueued      *bool   `json:"seed-queue-enabled"`
+		EncryptionLevel *string `json:"encryption"`
+	}
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return err
+	}
+
+	// A limit switched on without a value keeps the one in effect.
+	patch := echo.SettingsPatch{
+		DownloadDir:    args.DownloadDir,
+		MaxConnections: args.PeerLimit,
+		DownloadLimit:  limit(args.DownLimit, args.DownLimited, kilo),
+		UploadLimit:    limit(args.UpLimit, args.UpLimited, kilo),
+		MaxActiveDownloads: limit(
+			args.DownloadQueue,
+			args.DownloadQueued,
+			1,
+		),
+		MaxActiveSeeds: limit(args.SeedQueue, args.SeedQueued, 1),
+	}
+	if args.EncryptionLevel != nil {
+		e := echo.EncryptionPrefer
+		switch *args.EncryptionLevel {
+		case "required":
+			e = echo.EncryptionRequire
+		case "tolerated":
+			e = echo.EncryptionDisable
+		}
+		patch.Encryption = &e
+	}
+	_, err := s.client.UpdateSettings(patch)
+	return err
+}
+
+// limit turns one of Transmission's limits, a value in units and whether
+// it is enabled, into the engine's, where zero means none.
+func limit[T int | int64](value *T, enabled *bool, units T) *T {
+	if enabled != nil && !*enabled {
+		var zero T
+		return &zero
+	}
+	if value == nil {
+		return nil
+	}
+	v := *value * units
+	return &v
+}
+
+func (s *Server) trSessionStats() map[string]any {
+	statuses := s.client.Statuses()
+	stats := s.client.SessionStats()
+	var active, paused int
+	var down, up float64
+	for _, st := range statuses {
+		switch {
+		case st.State == torrent.StatePaused:
+			paused++
+		case st.State.Active():
+			active++
+		}
+		down += st.DownloadRate
+		up += st.UploadRate
+	}
+	return map[string]any{
+		"activeTorrentCount": active,
+		"pausedTorrentCount": paused,
+		"torrentCount":       len(statuses),
+		"downloadSpeed":      int64(down),
+		"uploadSpeed":        int64(up),
+		"current-stats": map[string]any{
+			"uploadedBytes":   stats.Session.Uploaded,
+			"downloadedBytes": stats.Session.Downloaded,
+			"secondsActive":   int64(stats.Uptime.Seconds()),
+			"sessionCount":    1,
+		},
+		"cumulative-stats": map[string]any{
+			"uploadedBytes":   stats.AllTime.Uploaded,
+			"downloadedBytes": stats.AllTime.Downloaded,
+			"secondsActive":   int64(stats.TotalUptime.Seconds()),
+		},
+	}
+}
+
+func (s *Server) trTorrentGet(raw json.RawMessage) (any, error) {
+	var args struct {
+		IDs    json.RawMessage `json:"ids"`
+		Fields []string        `json:"fields"`
+	}
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return nil, err
+	}
+	want, err := s.trIDs(args.IDs)
+	if err != nil {
+		return nil, err
+	}
+
+	torrents := []map[string]any{}
+	for _, st := range s.client.Statuses() {
+		hash, err := ParseInfoHash(st.InfoHash)
+		if err != nil || (want != nil && !slices.Contains(want, hash)) {
+			continue
+		}
+		t, ok := s.client.Get(hash)
+		if !ok {
+			continue
+		}
+		torrents = append(torrents, s.trTorrent(t, st, args.Fields))
+	}
+	return map[string]any{"torrents": torrents}, nil
+}
+
+// trTorrent returns the fields of t a torrent-get asked for, out of those
+// the engine has.
+func (s *Server) trTorrent(
+	t *echo.Torrent,
+	st echo.TorrentStatus,
+	fields []string,
+) map[string]any {
+	hash := t.Metainfo.Info.Hash
+	errCode := 0
+	if st.Error != "" {
+		errCode = 3
+	}
+	eta := int64(-1)
+	if st.ETA >= 0 {
+		eta = int64(st.ETA / time.Second)
+	}
+	labels := []string{}
+	if l, err := s.client.Labels(hash); err == nil {
+		labels = append(labels, l.Tags...)
+		if l.Category != "" {
+			labels = append([]string{l.Category}, labels...)
+		}
+	}
+
+	all := map[string]any{
+		"id":             s.tr.id(hash),
+		"hashString":     st.InfoHash,
+		"name":           st.Name,
+		"status":         trStatus(st),
+		"error":          errCode,
+		"errorString":    st.Error,
+		"totalSize":      st.Size,
+		"sizeWhenDone":   st.Size,
+		"leftUntilDone":  st.Left,
+		"percentDone":    st.Progress / 100,
+		"downloadedEver": st.Downloaded,
+		"uploadedEver":   st.Uploaded,
+		"rateDownload":   int64(st.DownloadRate),
+		"rateUpload":     int64(st.UploadRate),
+		"uploadRatio":    st.Ratio,
+		"eta":            eta,
+		"peersConnected": st.Peers,
+		"queuePosition":  st.QueuePosition,
+		"downloadDir":    t.DownloadDir(),
+		"isFinished":     st.Left == 0,
+		"labels":         labels,
+	}
+	if slices.Contains(fields, "files") ||
+		slices.Contains(fields, "fileStats") {
+		var files, stats []map[string]any
+		for _, f := range t.Details().Files {
+			done := uint64(float64(f.Size) * f.Progress / 100)
+			files = append(files, map[string]any{
+				"name":           filepath.Join(f.Path...),
+				"length":         f.Size,
+				"bytesCompleted": done,
+			})
+			stats = append(stats, map[string]any{
+				"bytesCompleted": done,
+				"wanted":         f.Wanted,
+				"priority":       0,
+			})
+		}
+		all["files"], all["fileStats"] = files, stats
+	}
+
+	if len(fields) == 0 {
+		return all
+	}
+	out := make(map[string]any, len(fields))
+	for _, f := range fields {
+		if v, ok := all[f]; ok {
+			out[f] = v
+		}
+	}
+	return out
+}
+
+func trStatus(st echo.TorrentStatus) int {
+	switch st.State {
+	case torrent.StateChecking:
+		return trCheck
+	case torrent.StateQueued:
+		if st.Left == 0 {
+			return trSeedWait
+		}
+		return trDownloadWait
+	case torrent.StateSeeding:
+		return trSeed
+	case torrent.StateDownloading, torrent.StateMoving:
+		if st.Left == 0 {
+			return trSeed
+		}
+		return trDownload
+	}
+	return trStopped
+}
+
+// trTorrentAdd adds the torrent given by metainfo, its contents in
+// base64, or by filename: a magnet link, a URL or a path on the server.
+func (s *Server) trTorrentAdd(
+	r *http.Request,
+	raw json.RawMessage,
+) (any, error) {
+	var args struct {
+		Filename    string   `json:"filename"`
+		Metainfo    string   `json:"metainfo"`
+		DownloadDir string   `json:"download-dir"`
+		Paused      bool     `json:"paused"`
+		Labels      []string `json:"labels"`
+	}
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return nil, err
+	}
+	opts := echo.AddOptions{
+		SavePath: args.DownloadDir,
+		Paused:   args.Paused,
+		Tags:     args.Labels,
+	}
+
+	var (
+		t    *echo.Torrent
+		data []byte
+		err  error
+	)
+	switch {
+	case args.Metainfo != "":
+		data, err = base64.StdEncoding.DecodeString(args.Metainfo)
+		if err == nil {
+			t, err = s.client.AddTorrent(data, opts)
+		}
+	case strings.HasPrefix(args.Filename, "magnet:"):
+		m, err := s.client.AddMagnetAsync(args.Filename, opts)
+		if err != nil {
+			return s.trAdded(err, args.Filename, nil)
+		}
+		hash, _ := ParseInfoHash(m.InfoHash)
+		return map[string]any{"torrent-added": map[string]any{
+			"id":         s.tr.id(hash),
+			"hashString": m.InfoHash,
+			"name":       m.Name,
+		}}, nil
+	case strings.HasPrefix(args.Filename, "http://"),
+		strings.HasPrefix(args.Filename, "https://"):
+		t, err = s.client.AddTorrentURL(
+			r.Context(),
+			args.Filename,
+			opts,
+		)
+	case args.Filename != "":
+		t, err = s.client.AddTorrentFile(args.Filename, opts)
+	default:
+		err = errors.New("no filename or metainfo")
+	}
+	if err != nil {
+		return s.trAdded(err, args.Filename, data)
+	}
+	hash := t.Metainfo.Info.Hash
+	return map[string]any{"torrent-added": map[string]any{
+		"id":         s.tr.id(hash),
+		"hashString": hex.EncodeToString(hash[:]),
+		"name":       t.Metainfo.Info.Name,
+	}}, nil
+}
+
+// trAdded answers a torrent-add that failed, which for a torrent the
+// session already has is a success naming that torrent, if it was given
+// by a magnet link or metainfo.
+func (s *Server) trAdded(
+	err error,
+	filename string,
+	data []byte,
+) (any, error) {
+	if !errors.Is(err, echo.ErrDuplicate) {
+		return nil, err
+	}
+	var hash [sha1.Size]byte
+	if data != nil {
+		m, perr := torrent.ParseMetainfo(bytes.NewReader(data))
+		if perr == nil {
+			hash = m.Info.Hash
+		}
+	} else if m, perr := torrent.ParseMagnet(filename); perr == nil {
+		hash = m.InfoHash
+	}
+	dup := map[string]any{}
+	if t, ok := s.client.Get(hash); ok {
+		dup["id"] = s.tr.id(hash)
+		dup["hashString"] = hex.EncodeToString(hash[:])
+		dup["name"] = t.Metainfo.Info.Name
+	}
+	return map[string]any{"torrent-duplicate": dup}, nil
+}
+
+// trTorrentRemove drops torrents from the session and, if asked, deletes
+// their files.
+func (s *Server) trTorrentRemove(raw json.RawMessage) error {
+	var args struct {
+		IDs    json.RawMessage `json:"ids"`
+		Delete bool            `json:"delete-local-data"`
+	}
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return err
+	}
+	hashes, err := s.trTargets(args.IDs)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, hash := range hashes {
+		errs = append(errs, s.remove(hash, args.Delete))
+	}
+	return errors.Join(errs...)
+}
+
+// trTorrentSet applies the keys of torrent-set the engine has settings
+// for; the rest are ignored.
+func (s *Server) trTorrentSet(raw json.RawMessage) error {
+	var args struct {
+		IDs           json.RawMessage `json:"ids"`
+		Labels        *[]string       `json:"labels"`
+		QueuePosition *int            `json:"queuePosition"`
+	}
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return err
+	}
+	hashes, err := s.trTargets(args.IDs)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, hash := range hashes {
+		if tags := args.Labels; tags != nil {
+			errs = append(errs, s.client.SetTags(hash, *tags))
+		}
+		if pos := args.QueuePosition; pos != nil {
+			err := s.client.SetQueuePosition(hash, *pos)
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// trEach calls fn for each torrent the request's ids name.
+func (s *Server) trEach(
+	raw json.RawMessage,
+	fn func([sha1.Size]byte) error,
+) error {
+	var args struct {
+		IDs json.RawMessage `json:"ids"`
+	}
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return err
+	}
+	hashes, err := s.trTargets(args.IDs)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, hash := range hashes {
+		errs = append(errs, fn(hash))
+	}
+	return errors.Join(errs...)
+}
+
+// trTargets returns the torrents ids names, every torrent if none.
+func (s *Server) trTargets(ids json.RawMessage) ([][sha1.Size]byte, error) {
+	hashes, err := s.trIDs(ids)
+	if err != nil || hashes != nil {
+		return hashes, err
+	}
+	for _, st := range s.client.Statuses() {
+		if hash, err := ParseInfoHash(st.InfoHash); err == nil {
+			hashes = append(hashes, hash)
+		}
+	}
+	return hashes, nil
+}
+
+// trIDs parses Transmission's ids argument: an ID, an info-hash, or a
+// list of either. Nil, for none or "recently-active", means every
+// torrent.
+func (s *Server) trIDs(raw json.RawMessage) ([][sha1.Size]byte, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+	var list []any
+	if raw[0] == '[' {
+		if err := json.Unmarshal(raw, &list); err != nil {
+			return nil, err
+		}
+	} else {
+		var one any
+		if err := json.Unmarshal(raw, &one); err != nil {
+			return nil, err
+		}
+		if one == "recently-active" {
+			return nil, nil
+		}
+		list = []any{one}
+	}
+
+	hashes := make([][sha1.Size]byte, 0, len(list))
+	for _, v := range list {
+		switch v := v.(type) {
+		case float64:
+			if hash, ok := s.tr.hash(int(v)); ok {
+				hashes = append(hashes, hash)
+			}
+		case string:
+			hash, err := ParseInfoHash(v)
+			if err != nil {
+				return nil, err
+			}
+			hashes = append(hashes, hash)
+		default:
+			return nil, fmt.Errorf("api: bad torrent id %v", v)
+		}
+	}
+	return hashes, nil
+}
@@ -0,0 +1,149 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// rpc makes a Transmission RPC call as a remote GUI would: with basic
+// authentication, retrying once with the session ID a 409 hands out.
+func rpc(
+	t *testing.T,
+	srv *httptest.Server,
+	body string,
+) (rpcResponse, json.RawMessage) {
+	t.Helper()
+	var sessionID string
+	for range 2 {
+		req, err := http.NewRequest(
+			"POST",
+			srv.URL+"/transmission/rpc",
+			strings.NewReader(body),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.SetBasicAuth("admin", testToken)
+		req.Header.Set(sessionHeader, sessionID)
+		resp, err := srv.Client().Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode == http.StatusConflict {
+			sessionID = resp.Header.Get(sessionHeader)
+			continue
+		}
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("status %d", resp.StatusCode)
+		}
+
+		var out struct {
+			rpcResponse
+			Arguments json.RawMessage `json:"arguments"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+			t.Fatal(err)
+		}
+		return out.rpcResponse, out.Arguments
+	}
+	t.Fatal("session ID refused")
+	return rpcResponse{}, nil
+}
+
+func TestTransmissionSession(t *testing.T) {
+	srv := testServer(t)
+	resp, args := rpc(t, srv, `{"method":"session-get","tag":7}`)
+	if resp.Result != "success" || resp.Tag == nil || *resp.Tag != 7 {
+		t.Fatalf("result %q, tag %v", resp.Result, resp.Tag)
+	}
+	var session map[string]any
+	if err := json.Unmarshal(args, &session); err != nil {
+		t.Fatal(err)
+	}
+	if session["rpc-version"] != float64(rpcVersion) {
+		t.Errorf("rpc-version %v", session["rpc-version"])
+	}
+
+	resp, _ = rpc(
+		t,
+		srv,
+		`{"method":"session-set","arguments":{"speed-limit-up":50,`+
+			`"speed-limit-up-enabled":true}}`,
+	)
+	if resp.Result != "success" {
+		t.Fatalf("session-set: %s", resp.Result)
+	}
+	_, args = rpc(t, srv, `{"method":"session-get"}`)
+	if err := json.Unmarshal(args, &session); err != nil {
+		t.Fatal(err)
+	}
+	if session["speed-limit-up"] != float64(50) {
+		t.Errorf("speed-limit-up %v", session["speed-limit-up"])
+	}
+}
+
+func TestTransmissionTorrents(t *testing.T) {
+	srv := testServer(t)
+	resp, args := rpc(
+		t,
+		srv,
+		`{"method":"torrent-get","arguments":{"fields":["id","name"]}}`,
+	)
+	if resp.Result != "success" {
+		t.Fatalf("torrent-get: %s", resp.Result)
+	}
+	var got struct {
+		Torrents []map[string]any `json:"torrents"`
+	}
+	if err := json.Unmarshal(args, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Torrents == nil || len(got.Torrents) != 0 {
+		t.Errorf("torrents %v in a new session", got.Torrents)
+	}
+
+	resp, _ = rpc(
+		t,
+		srv,
+		`{"method":"torrent-add","arguments":{"metainfo":"bm90"}}`,
+	)
+	if resp.Result == "success" {
+		t.Error("garbage metainfo added")
+	}
+	resp, _ = rpc(t, srv, `{"method":"no-such-method"}`)
+	if resp.Result == "success" {
+		t.Error("unknown method succeeded")
+	}
+}
+
+func TestTransmissionIDs(t *testing.T) {
+	tr := newTransmission()
+	s := &Server{tr: tr}
+	var hash [20]byte
+	hash[0] = 1
+	id := tr.id(hash)
+	if tr.id(hash) != id {
+		t.Fatal("ID changed")
+	}
+
+	hex := `"01` + strings.Repeat("00", 19) + `"`
+	for _, raw := range []string{"1", "[1]", hex, "[" + hex + ", 1]"} {
+		hashes, err := s.trIDs(json.RawMessage(raw))
+		if err != nil || len(hashes) == 0 || hashes[0] != hash {
+			t.Errorf("ids %s: %x, %v", raw, hashes, err)
+		}
+	}
+	for _, raw := range []string{"", "null", `"recently-active"`} {
+		hashes, err := s.trIDs(json.RawMessage(raw))
+		if err != nil || hashes != nil {
+			t.Errorf("ids %q: %x, %v", raw, hashes, err)
+		}
+	}
+	if hashes, _ := s.trIDs(json.RawMessage("[]")); hashes == nil {
+		t.Error("empty list taken for every torrent")
+	}
+}
@@ -0,0 +1,93 @@
+// Package bandwidth shares a global transfer limit and connection budget
+// among torrents by weight.
+package bandwidth
+
+// Claim is one torrent's stake in a shared rate limit.
+type Claim struct {
+	// Weight sets the torrent's share relative to the others; values
+	// below one count as one.
+	Weight int
+	// Demand is the rate in bytes per second the torrent could use, or
+	// negative if it could use any amount.
+	Demand int64
+}
+
+// Allocate splits total bytes per second among the claims in proportion to
+// their weights. Claims demanding less than their share get their demand
+// and the rest is split again among the others, so bandwidth an idle
+// torrent leaves unused goes to the busy ones. Whatever is left once every
+// demand is met is spread by weight too, leaving room for rates to grow.
+func Allocate(total int64, claims []Claim) []int64 {
+	shares := make([]int64, len(claims))
+	if total <= 0 || len(claims) == 0 {
+		return shares
+	}
+
+	open := make([]int, 0, len(claims))
+	for i := range claims {
+		open = append(open, i)
+	}
+	remaining := total
+	for len(open) > 0 && remaining > 0 {
+		sum := weightSum(claims, open)
+
+		var met int64
+		next := open[:0:0]
+		for _, i := range open {
+			fair := remaining * weight(claims[i]) / sum
+			if d := claims[i].Demand; d >= 0 && d <= fair {
+				shares[i] = d
+				met += d
+				continue
+			}
+			next = append(next, i)
+		}
+
+		if len(next) == len(open) {
+			for _, i := range open {
+				shares[i] = remaining * weight(claims[i]) / sum
+			}
+			return shares
+		}
+		open = next
+		remaining -= met
+	}
+
+	if remaining > 0 {
+		all := make([]int, len(claims))
+		for i := range all {
+			all[i] = i
+		}
+		sum := weightSum(claims, all)
+		for i := range claims {
+			shares[i] += remaining * weight(claims[i]) / sum
+		}
+	}
+	return shares
+}
+
+// Slots splits total connections among torrents of the given weights, in
+// proportion to them. Every torrent gets at least one.
+func Slots(total int, weights []int) []int {
+	slots := make([]int, len(weights))
+	sum := 0
+	for _, w := range weights {
+		sum += max(w, 1)
+	}
+	for i, w := range weights {
+		slots[i] = max(total*max(w, 1)/sum, 1)
+	}
+	return slots
+}
+
+func weight(c Claim) int64 {
+	return int64(max(c.Weight, 1))
+}
+
+func weightSum(claims []Claim, idx []int) int64 {
+	var sum int64
+	for _, i := range idx {
+		sum += weight(claims[i])
+	}
+	return sum
+}
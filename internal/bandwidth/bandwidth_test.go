@@ -0,0 +1,79 @@
+package bandwidth
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestAllocate(t *testing.T) {
+	tests := []struct {
+		name   string
+		total  int64
+		claims []Claim
+		want   []int64
+	}{
+		{
+			name:  "weights",
+			total: 700,
+			claims: []Claim{
+				{Weight: 1, Demand: -1},
+				{Weight: 2, Demand: -1},
+				{Weight: 4, Demand: -1},
+			},
+			want: []int64{100, 200, 400},
+		},
+		{
+			name:  "unused share goes to the others",
+			total: 1000,
+			claims: []Claim{
+				{Weight: 4, Demand: 100},
+				{Weight: 1, Demand: -1},
+			},
+			want: []int64{100, 900},
+		},
+		{
+			name:  "leftover spread by weight",
+			total: 1000,
+			claims: []Claim{
+				{Weight: 1, Demand: 100},
+				{Weight: 1, Demand: 200},
+			},
+			want: []int64{450, 550},
+		},
+		{
+			name:  "zero weight counts as one",
+			total: 100,
+			claims: []Claim{
+				{Demand: -1},
+				{Weight: 1, Demand: -1},
+			},
+			want: []int64{50, 50},
+		},
+		{
+			name:   "unlimited",
+			total:  0,
+			claims: []Claim{{Weight: 1, Demand: -1}},
+			want:   []int64{0},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Allocate(tt.total, tt.claims)
+			if !slices.Equal(got, tt.want) {
+				t.Fatalf("got %v; want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSlots(t *testing.T) {
+	got := Slots(100, []int{1, 2, 4, 0})
+	want := []int{12, 25, 50, 12}
+	if !slices.Equal(got, want) {
+		t.Fatalf("Slots() = %v; want %v", got, want)
+	}
+	got = Slots(2, []int{1, 1, 1})
+	if !slices.Equal(got, []int{1, 1, 1}) {
+		t.Fatalf("Slots() = %v; want at least one each", got)
+	}
+}
@@ -0,0 +1,38 @@
+package bencode
+
+import (
+	"bytes"
+	"io"
+)
+
+// Canonicalize re-encodes data in canonical form: dictionary keys sorted and
+// numbers without leading zeros. The input must hold exactly one well-formed
+// value with no repeated keys, so equal content always yields equal bytes and
+// hashes over the result are deterministic.
+func Canonicalize(data []byte) ([]byte, error) {
+	d := NewDecoder(bytes.NewReader(data))
+	d.UseBytes()
+	d.uniqueKeys = true
+
+	v, err := d.Decode()
+	if err == io.EOF {
+		return nil, d.errorAt(0, ErrUnexpectedEOF)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if d.off != int64(len(data)) {
+		return nil, &DecodeError{
+			Offset: d.off,
+			Err:    ErrUnexpectedByte,
+			Detail: "trailing data after value",
+		}
+	}
+
+	var buf bytes.Buffer
+	buf.Grow(len(data))
+	if err := NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
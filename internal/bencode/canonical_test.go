@@ -0,0 +1,90 @@
+package bencode
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestCanonicalize(t *testing.T) {
+	cases := map[string]string{
+		"i03e":                 "i3e",
+		"i-0e":                 "i0e",
+		"04:spam":              "4:spam",
+		"d1:bi1e1:ai2ee":       "d1:ai2e1:bi1ee",
+		"ld1:z0:1:a0:ei7ee":    "ld1:a0:1:z0:ei7ee",
+		"d3:bin2:\xff\x00e":    "d3:bin2:\xff\x00e",
+		"d4:infod1:y0:1:x0:ee": "d4:infod1:x0:1:y0:ee",
+	}
+	for in, want := range cases {
+		got, err := Canonicalize([]byte(in))
+		if err != nil {
+			t.Errorf("Canonicalize(%q) error = %v", in, err)
+			continue
+		}
+		if string(got) != want {
+			t.Errorf(
+				"Canonicalize(%q) = %q; want %q",
+				in,
+				got,
+				want,
+			)
+		}
+	}
+}
+
+func TestCanonicalizeErrors(t *testing.T) {
+	cases := map[string]error{
+		"":               ErrUnexpectedEOF,
+		"i1ei2e":         ErrUnexpectedByte,
+		"d1:ai1e1:ai2ee": ErrDuplicateKey,
+		"l":              ErrUnexpectedEOF,
+		"99999999999:x":  ErrUnexpectedEOF,
+	}
+	for in, want := range cases {
+		_, err := Canonicalize([]byte(in))
+		if !errors.Is(err, want) {
+			t.Errorf(
+				"Canonicalize(%q) = %v; want %v",
+				in,
+				err,
+				want,
+			)
+		}
+	}
+}
+
+func FuzzCanonicalize(f *testing.F) {
+	for _, seed := range []string{
+		"i42e",
+		"4:spam",
+		"d1:bi1e1:ai2ee",
+		"l4:spami-3ee",
+		"d4:infod6:lengthi1e4:name1:a12:piece lengthi16384eee",
+	} {
+		f.Add([]byte(seed))
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		out, err := Canonicalize(data)
+		if err != nil {
+			return
+		}
+		if err := Validate(bytes.NewReader(out)); err != nil {
+			t.Fatalf(
+				"Canonicalize(%q) = %q, invalid: %v",
+				data,
+				out,
+				err,
+			)
+		}
+
+		again, err := Canonicalize(out)
+		if err != nil {
+			t.Fatalf("Canonicalize(%q) error = %v", out, err)
+		}
+		if !bytes.Equal(out, again) {
+			t.Fatalf("not idempotent: %q -> %q", out, again)
+		}
+	})
+}
@@ -2,15 +2,41 @@ package bencode
 
 import (
 	"bufio"
+	"bytes"
 	"errors"
+	"fmt"
 	"io"
 	"strconv"
+	"strings"
 )
 
 type Decoder struct {
-	r *bufio.Reader
+	r        *bufio.Reader
+	off      int64
+	useBytes bool
+	strict   bool
+
+	// uniqueKeys rejects repeated dictionary keys, which strict mode
+	// implies.
+	uniqueKeys bool
+
+	streams map[string]io.Writer
+
+	// nesting counts the lists and dictionaries decode is inside.
+	nesting int
+
+	// Token state: open containers and unread bytes of the last string.
+	depth   int
+	pending int64
 }
 
+const maxPrealloc = 1 << 20
+
+// MaxDepth is how deeply lists and dictionaries may nest in what Decode and
+// Unmarshal accept. Both recurse into them, so input from a peer could
+// otherwise nest deep enough to overflow the stack.
+const MaxDepth = 256
+
 type bType byte
 
 const (
@@ -24,74 +50,183 @@ func NewDecoder(r io.Reader) *Decoder {
 	return &Decoder{r: bufio.NewReader(r)}
 }
 
+// UseBytes makes Decode return string values as []byte. Dictionary keys stay
+// strings. Binary fields such as piece hashes and compact peer lists then
+// avoid a round trip through Go strings.
+func (d *Decoder) UseBytes() {
+	d.useBytes = true
+}
+
+// Strict makes Decode reject input that is not in canonical form: integers
+// and lengths with leading zeros or a sign other than a single '-', "i-0e",
+// and dictionaries whose keys are unsorted or repeated.
+func (d *Decoder) Strict() {
+	d.strict = true
+	d.uniqueKeys = true
+}
+
+// Streamed stands in the decoded dictionary for a string value that was
+// copied to a StreamKey writer; it holds the number of bytes written.
+type Streamed int64
+
+// StreamKey makes Decode copy the string value of every dictionary entry
+// named key to w in chunks instead of holding it in memory, leaving a
+// Streamed in its place. Values of other types decode as usual.
+func (d *Decoder) StreamKey(key string, w io.Writer) {
+	if d.streams == nil {
+		d.streams = make(map[string]io.Writer)
+	}
+	d.streams[key] = w
+}
+
+// InputOffset returns the number of bytes consumed so far.
+func (d *Decoder) InputOffset() int64 {
+	return d.off
+}
+
+// Decode reads the next value. It returns io.EOF if the input ends cleanly
+// before a value starts; any other failure is a *DecodeError.
 func (d *Decoder) Decode() (any, error) {
-	btype, err := d.r.ReadByte()
-	if err != nil {
+	if err := d.skipPending(); err != nil {
 		return nil, err
 	}
+	if _, err := d.r.Peek(1); err == io.EOF {
+		return nil, io.EOF
+	}
+	return d.decode()
+}
 
-	var val any
+func (d *Decoder) decode() (any, error) {
+	start := d.off
+	btype, err := d.readByte()
+	if err != nil {
+		return nil, err
+	}
 
 	switch btype {
 	case byte(bInteger):
-		val, err = d.decodeInteger()
-	case byte(bList):
-		val, err = d.decodeList()
-	case byte(bDict):
-		val, err = d.decodeDict()
-	default:
-		if err := d.r.UnreadByte(); err != nil {
-			return nil, err
+		return d.decodeInteger()
+	case byte(bList), byte(bDict):
+		if d.nesting >= MaxDepth {
+			return nil, d.errorAt(start, ErrTooDeep)
 		}
+		d.nesting++
+		defer func() { d.nesting-- }()
+		if btype == byte(bList) {
+			return d.decodeList()
+		}
+		return d.decodeDict()
+	}
+	// A leading '-' is let through so a negative length is reported as such.
+	if (btype < '0' || btype > '9') && btype != '-' {
+		return nil, &DecodeError{
+			Offset: start,
+			Err:    ErrUnexpectedByte,
+			Detail: fmt.Sprintf("unexpected %q", btype),
+		}
+	}
 
-		val, err = d.decodeString()
+	if err := d.unreadByte(); err != nil {
+		return nil, err
+	}
+	if d.useBytes {
+		return d.decodeBytes()
 	}
+	return d.decodeString()
+}
+
+func (d *Decoder) errorAt(off int64, err error) error {
+	return &DecodeError{Offset: off, Err: err}
+}
 
+func (d *Decoder) readByte() (byte, error) {
+	b, err := d.r.ReadByte()
 	if err != nil {
-		return nil, err
+		return 0, d.ioError(err)
+	}
+	d.off++
+	return b, nil
+}
+
+func (d *Decoder) unreadByte() error {
+	if err := d.r.UnreadByte(); err != nil {
+		return err
+	}
+	d.off--
+	return nil
+}
+
+func (d *Decoder) peekByte() (byte, error) {
+	b, err := d.r.Peek(1)
+	if err != nil {
+		return 0, d.ioError(err)
 	}
-	return val, nil
+	return b[0], nil
+}
+
+// ioError turns a read failure into a DecodeError at the current offset;
+// running out of input mid-value is always unexpected.
+func (d *Decoder) ioError(err error) error {
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return d.errorAt(d.off, ErrUnexpectedEOF)
+	}
+	return err
 }
 
 func (d *Decoder) decodeInteger() (int64, error) {
-	return d.readInteger(bDelim)
+	return d.readInteger(bDelim, ErrInvalidInteger)
 }
 
 func (d *Decoder) decodeString() (string, error) {
-	size, err := d.readInteger(':')
+	b, err := d.decodeBytes()
+	return string(b), err
+}
+
+func (d *Decoder) decodeBytes() ([]byte, error) {
+	start := d.off
+	size, err := d.readInteger(':', ErrInvalidLength)
 	if err != nil {
-		return "", err
-	}
-	if size == 0 {
-		return "", nil
+		return nil, err
 	}
 	if size < 0 {
-		return "", errors.New(
-			"bencode: invalid string, length can't be negative",
-		)
+		return nil, d.errorAt(start, ErrNegativeLength)
+	}
+
+	// A claimed length is not trusted with an allocation of that size;
+	// beyond maxPrealloc the buffer only grows as data actually arrives.
+	if size > maxPrealloc {
+		var buf bytes.Buffer
+		n, err := io.CopyN(&buf, d.r, size)
+		d.off += n
+		if err != nil {
+			return nil, d.ioError(err)
+		}
+		return buf.Bytes(), nil
 	}
 
 	buf := make([]byte, size)
-	if _, err := io.ReadFull(d.r, buf); err != nil {
-		return "", err
+	n, err := io.ReadFull(d.r, buf)
+	d.off += int64(n)
+	if err != nil {
+		return nil, d.ioError(err)
 	}
-	return string(buf), nil
+	return buf, nil
 }
 
 func (d *Decoder) decodeList() ([]any, error) {
 	list := make([]any, 0)
 
 	for {
-		peek, err := d.r.Peek(1)
+		c, err := d.peekByte()
 		if err != nil {
 			return nil, err
 		}
-		if peek[0] == byte(bDelim) {
-			d.r.ReadByte()
+		if c == byte(bDelim) {
+			_, _ = d.readByte()
 			break
 		}
 
-		v, err := d.Decode()
+		v, err := d.decode()
 		if err != nil {
 			return nil, err
 		}
@@ -104,21 +239,45 @@ func (d *Decoder) decodeList() ([]any, error) {
 func (d *Decoder) decodeDict() (map[string]any, error) {
 	dict := make(map[string]any)
 
-	for {
-		peek, err := d.r.Peek(1)
+	var prev string
+	for i := 0; ; i++ {
+		c, err := d.peekByte()
 		if err != nil {
 			return nil, err
 		}
-		if peek[0] == byte(bDelim) {
-			d.r.ReadByte()
+		if c == byte(bDelim) {
+			_, _ = d.readByte()
 			break
 		}
+		if c < '0' || c > '9' {
+			return nil, d.errorAt(d.off, ErrKeyNotString)
+		}
 
+		start := d.off
 		key, err := d.decodeString()
 		if err != nil {
 			return nil, err
 		}
-		val, err := d.Decode()
+		if d.strict && i > 0 && key <= prev {
+			kind := ErrUnsortedKeys
+			if key == prev {
+				kind = ErrDuplicateKey
+			}
+			return nil, &DecodeError{
+				Offset: start,
+				Err:    kind,
+				Detail: fmt.Sprintf("%s %q", kind, key),
+			}
+		}
+		prev = key
+		if _, dup := dict[key]; dup && d.uniqueKeys {
+			return nil, &DecodeError{
+				Offset: start,
+				Err:    ErrDuplicateKey,
+				Detail: fmt.Sprintf("duplicate key %q", key),
+			}
+		}
+		val, err := d.decodeValue(key)
 		if err != nil {
 			return nil, err
 		}
@@ -129,12 +288,98 @@ func (d *Decoder) decodeDict() (map[string]any, error) {
 	return dict, nil
 }
 
-func (d *Decoder) readInteger(delim bType) (int64, error) {
+// decodeValue decodes the value of the dictionary entry key, streaming it if
+// it is a string registered with StreamKey.
+func (d *Decoder) decodeValue(key string) (any, error) {
+	w, ok := d.streams[key]
+	if !ok {
+		return d.decode()
+	}
+	c, err := d.peekByte()
+	if err != nil {
+		return nil, err
+	}
+	if (c < '0' || c > '9') && c != '-' {
+		return d.decode()
+	}
+
+	start := d.off
+	size, err := d.readInteger(':', ErrInvalidLength)
+	if err != nil {
+		return nil, err
+	}
+	if size < 0 {
+		return nil, d.errorAt(start, ErrNegativeLength)
+	}
+
+	n, err := io.CopyN(w, d.r, size)
+	d.off += n
+	if err != nil {
+		return nil, d.ioError(err)
+	}
+	return Streamed(size), nil
+}
+
+func (d *Decoder) readInteger(delim bType, invalid error) (int64, error) {
+	start := d.off
 	read, err := d.r.ReadBytes(byte(delim))
+	d.off += int64(len(read))
 	if err != nil {
-		return 0, err
+		return 0, d.ioError(err)
 	}
 
 	sint := string(read[:len(read)-1])
-	return strconv.ParseInt(sint, 10, 64)
+	n, err := strconv.ParseInt(sint, 10, 64)
+	if err == nil && d.strict && !canonicalInt(sint) {
+		return 0, &DecodeError{
+			Offset: start,
+			Err:    ErrNotCanonical,
+			Detail: fmt.Sprintf("non-canonical number %q", sint),
+		}
+	}
+	if err != nil {
+		return 0, &DecodeError{
+			Offset: start,
+			Err:    invalid,
+			Detail: fmt.Sprintf("%s %q", invalid, sint),
+		}
+	}
+	return n, nil
+}
+
+// canonicalInt reports whether s is the shortest decimal form of its value.
+func canonicalInt(s string) bool {
+	digits := strings.TrimPrefix(s, "-")
+	if digits == "" || digits[0] < '0' || digits[0] > '9' {
+		return false
+	}
+	if digits[0] == '0' {
+		return s == "0"
+	}
+	return true
+}
+
+// Validate reports whether r holds exactly one value in canonical form, as
+// required of metadata whose hash is checked against an info-hash.
+func Validate(r io.Reader) error {
+	d := NewDecoder(r)
+	d.Strict()
+
+	if _, err := d.Decode(); err != nil {
+		if err == io.EOF {
+			return d.errorAt(0, ErrUnexpectedEOF)
+		}
+		return err
+	}
+	if _, err := d.r.Peek(1); err != io.EOF {
+		if err != nil {
+			return err
+		}
+		return &DecodeError{
+			Offset: d.off,
+			Err:    ErrUnexpectedByte,
+			Detail: "trailing data after value",
+		}
+	}
+	return nil
 }
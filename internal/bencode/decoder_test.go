@@ -1,6 +1,9 @@
 package bencode
 
 import (
+	"bytes"
+	"errors"
+	"io"
 	"reflect"
 	"strings"
 	"testing"
@@ -118,7 +121,7 @@ func TestDecodeErrors(t *testing.T) {
 	// Negative string length
 	if _, err := NewDecoder(strings.NewReader("-1:")).Decode(); err == nil {
 		t.Fatalf("expected error for negative string length")
-	} else if err.Error() != "bencode: invalid string, length can't be negative" {
+	} else if !errors.Is(err, ErrNegativeLength) {
 		t.Fatalf("unexpected error: %v", err)
 	}
 
@@ -158,3 +161,189 @@ func TestDecodeErrors(t *testing.T) {
 		t.Fatalf("expected error when dict key is not a string")
 	}
 }
+
+func TestDecodeTooDeep(t *testing.T) {
+	// As a peer could send in place of an info dict.
+	deep := strings.Repeat("l", 16<<20)
+	_, err := NewDecoder(strings.NewReader(deep)).Decode()
+	if !errors.Is(err, ErrTooDeep) {
+		t.Fatalf("Decode = %v; want %v", err, ErrTooDeep)
+	}
+	err = Validate(strings.NewReader(deep))
+	if !errors.Is(err, ErrTooDeep) {
+		t.Errorf("Validate = %v; want %v", err, ErrTooDeep)
+	}
+
+	limit := strings.Repeat("l", MaxDepth-1) + "d1:ai1ee" +
+		strings.Repeat("e", MaxDepth-1)
+	if _, err := NewDecoder(strings.NewReader(limit)).Decode(); err != nil {
+		t.Errorf("%d levels: %v", MaxDepth, err)
+	}
+	over := "l" + limit + "e"
+	_, err = NewDecoder(strings.NewReader(over)).Decode()
+	if !errors.Is(err, ErrTooDeep) {
+		t.Errorf("%d levels: %v; want %v", MaxDepth+1, err, ErrTooDeep)
+	}
+}
+
+func TestDecodeErrorOffsets(t *testing.T) {
+	cases := []struct {
+		in     string
+		want   error
+		offset int64
+	}{
+		{"l4:spami4x2ee", ErrInvalidInteger, 8},
+		{"d3:fooi1ei2ee", ErrKeyNotString, 9},
+		{"l4:spamx", ErrUnexpectedByte, 7},
+		{"d3:foo10:abce", ErrUnexpectedEOF, 13},
+		{"li1e", ErrUnexpectedEOF, 4},
+	}
+
+	for _, tc := range cases {
+		_, err := NewDecoder(strings.NewReader(tc.in)).Decode()
+
+		var de *DecodeError
+		if !errors.As(err, &de) || !errors.Is(err, tc.want) {
+			t.Fatalf(
+				"Decode(%q) error = %v; want %v",
+				tc.in,
+				err,
+				tc.want,
+			)
+		}
+		if de.Offset != tc.offset {
+			t.Fatalf(
+				"Decode(%q) offset = %d; want %d",
+				tc.in,
+				de.Offset,
+				tc.offset,
+			)
+		}
+	}
+
+	if _, err := NewDecoder(strings.NewReader("")).Decode(); err != io.EOF {
+		t.Fatalf("Decode(\"\") error = %v; want io.EOF", err)
+	}
+}
+
+func TestDecodeUseBytes(t *testing.T) {
+	in := "d5:peers6:\x01\x02\x03\x04\x1a\xe14:tagsl1:aee"
+
+	dec := NewDecoder(strings.NewReader(in))
+	dec.UseBytes()
+	v, err := dec.Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]any{
+		"peers": []byte{1, 2, 3, 4, 0x1a, 0xe1},
+		"tags":  []any{[]byte("a")},
+	}
+	if !reflect.DeepEqual(v, want) {
+		t.Fatalf("Decode = %#v; want %#v", v, want)
+	}
+
+	var buf strings.Builder
+	if err := NewEncoder(&buf).Encode(v); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != in {
+		t.Fatalf("round trip = %q; want %q", buf.String(), in)
+	}
+}
+
+func TestDecodeStrict(t *testing.T) {
+	rejected := map[string]error{
+		"i03e":           ErrNotCanonical,
+		"i-0e":           ErrNotCanonical,
+		"i+4e":           ErrNotCanonical,
+		"04:spam":        ErrNotCanonical,
+		"d1:bi1e1:ai2ee": ErrUnsortedKeys,
+		"d1:ai1e1:ai2ee": ErrDuplicateKey,
+	}
+	for in, want := range rejected {
+		dec := NewDecoder(strings.NewReader(in))
+		dec.Strict()
+		_, err := dec.Decode()
+		if !errors.Is(err, want) {
+			t.Errorf("Decode(%q) = %v; want %v", in, err, want)
+		}
+		lenient := NewDecoder(strings.NewReader(in))
+		if _, err := lenient.Decode(); err != nil {
+			t.Errorf("lenient Decode(%q) error = %v", in, err)
+		}
+	}
+
+	accepted := []string{"i0e", "i-7e", "0:", "d1:ai1e1:bi2ee", "le"}
+	for _, in := range accepted {
+		dec := NewDecoder(strings.NewReader(in))
+		dec.Strict()
+		if _, err := dec.Decode(); err != nil {
+			t.Errorf("Decode(%q) error = %v", in, err)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if err := Validate(strings.NewReader("d4:infod1:ai1eee")); err != nil {
+		t.Fatalf("Validate error = %v", err)
+	}
+
+	cases := map[string]error{
+		"":          ErrUnexpectedEOF,
+		"i1ei2e":    ErrUnexpectedByte,
+		"d1:ai01ee": ErrNotCanonical,
+	}
+	for in, want := range cases {
+		err := Validate(strings.NewReader(in))
+		if !errors.Is(err, want) {
+			t.Errorf("Validate(%q) = %v; want %v", in, err, want)
+		}
+	}
+}
+
+func TestDecodeStreamKey(t *testing.T) {
+	pieces := strings.Repeat("\xab", 3*20)
+	in := "d4:infod6:lengthi3e6:pieces60:" + pieces + "e6:pieces1:xe"
+
+	var sink bytes.Buffer
+	dec := NewDecoder(strings.NewReader(in))
+	dec.StreamKey("pieces", &sink)
+	v, err := dec.Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]any{
+		"info": map[string]any{
+			"length": int64(3),
+			"pieces": Streamed(60),
+		},
+		"pieces": Streamed(1),
+	}
+	if !reflect.DeepEqual(v, want) {
+		t.Fatalf("Decode = %#v; want %#v", v, want)
+	}
+	if sink.String() != pieces+"x" {
+		t.Fatalf("streamed %q; want %q", sink.String(), pieces+"x")
+	}
+	if dec.InputOffset() != int64(len(in)) {
+		t.Fatalf("offset = %d; want %d", dec.InputOffset(), len(in))
+	}
+}
+
+func TestDecodeStreamKeyNotString(t *testing.T) {
+	var sink bytes.Buffer
+	dec := NewDecoder(strings.NewReader("d6:piecesli1eee"))
+	dec.StreamKey("pieces", &sink)
+	v, err := dec.Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]any{"pieces": []any{int64(1)}}
+	if !reflect.DeepEqual(v, want) || sink.Len() != 0 {
+		t.Fatalf("Decode = %#v, streamed %q", v, sink.String())
+	}
+}
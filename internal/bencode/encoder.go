@@ -1,8 +1,8 @@
 package bencode
 
 import (
-	"fmt"
 	"io"
+	"reflect"
 	"sort"
 	"strconv"
 )
@@ -15,18 +15,27 @@ func NewEncoder(w io.Writer) *Encoder {
 	return &Encoder{w: w}
 }
 
+// Encode writes the bencoding of v. The types Decode produces are written
+// directly; anything else goes through the same reflection rules as Marshal.
 func (e *Encoder) Encode(v any) error {
 	switch vt := v.(type) {
 	case string:
 		return e.encodeString(vt)
+	case []byte:
+		return e.encodeBytes(vt)
 	case []any:
 		return e.encodeList(vt)
 	case map[string]any:
 		return e.encodeDict(vt)
 	case int64:
 		return e.encodeInteger(vt)
+	case int:
+		return e.encodeInteger(int64(vt))
+	case RawMessage:
+		_, err := e.w.Write(vt)
+		return err
 	default:
-		return fmt.Errorf("bencode: unsupported type '%T'", vt)
+		return e.encodeReflect(reflect.ValueOf(v))
 	}
 }
 
@@ -48,6 +57,15 @@ func (e *Encoder) encodeString(v string) error {
 	return err
 }
 
+func (e *Encoder) encodeBytes(v []byte) error {
+	buf := []byte(strconv.Itoa(len(v)))
+	buf = append(buf, byte(':'))
+	buf = append(buf, v...)
+
+	_, err := e.w.Write(buf)
+	return err
+}
+
 func (e *Encoder) encodeList(list []any) error {
 	if _, err := e.w.Write([]byte{byte(bList)}); err != nil {
 		return err
@@ -141,7 +141,7 @@ func TestEncodeUnsupportedType(t *testing.T) {
 	var buf bytes.Buffer
 	enc := NewEncoder(&buf)
 
-	cases := []any{1, true, nil}
+	cases := []any{1.5, nil, map[int]string{1: "a"}, make(chan int)}
 	for _, c := range cases {
 		if err := enc.Encode(c); err == nil {
 			t.Fatalf("Encode(%T) expected error, got nil", c)
@@ -149,6 +149,39 @@ func TestEncodeUnsupportedType(t *testing.T) {
 	}
 }
 
+func TestEncodeGoTypes(t *testing.T) {
+	cases := []struct {
+		in   any
+		want string
+	}{
+		{7, "i7e"},
+		{uint16(80), "i80e"},
+		{true, "i1e"},
+		{false, "i0e"},
+		{[]string{"a", "bc"}, "l1:a2:bce"},
+		{[]int{1, 2}, "li1ei2ee"},
+		{testFile{3, []string{"x"}}, "d6:lengthi3e4:pathl1:xee"},
+		{
+			map[string]any{"f": []testFile{{1, nil}}},
+			"d1:fld6:lengthi1e4:pathleeee",
+		},
+	}
+	for _, c := range cases {
+		var buf bytes.Buffer
+		if err := NewEncoder(&buf).Encode(c.in); err != nil {
+			t.Fatalf("Encode(%#v) error = %v", c.in, err)
+		}
+		if buf.String() != c.want {
+			t.Errorf(
+				"Encode(%#v) = %q; want %q",
+				c.in,
+				buf.String(),
+				c.want,
+			)
+		}
+	}
+}
+
 func TestEncodeWriterError(t *testing.T) {
 	ew := errWriter{err: errors.New("write failed")}
 	enc := NewEncoder(ew)
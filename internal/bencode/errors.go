@@ -0,0 +1,43 @@
+package bencode
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrUnexpectedEOF  = errors.New("unexpected end of input")
+	ErrUnexpectedByte = errors.New("unexpected byte")
+	ErrInvalidInteger = errors.New("invalid integer")
+	ErrInvalidLength  = errors.New("invalid string length")
+	ErrNegativeLength = errors.New(
+		"invalid string, length can't be negative",
+	)
+	ErrKeyNotString = errors.New("dictionary key is not a string")
+	ErrTypeMismatch = errors.New("type mismatch")
+	ErrOverflow     = errors.New("value out of range")
+	ErrNotCanonical = errors.New("number not in canonical form")
+	ErrUnsortedKeys = errors.New("dictionary keys not sorted")
+	ErrDuplicateKey = errors.New("duplicate dictionary key")
+	ErrTooDeep      = errors.New("lists and dictionaries nested too deep")
+)
+
+// DecodeError reports why and where decoding failed. Err is one of the
+// sentinel errors above, so callers can match with errors.Is.
+type DecodeError struct {
+	Offset int64
+	Err    error
+	Detail string
+}
+
+func (e *DecodeError) Error() string {
+	msg := e.Err.Error()
+	if e.Detail != "" {
+		msg = e.Detail
+	}
+	return fmt.Sprintf("bencode: %s at offset %d", msg, e.Offset)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
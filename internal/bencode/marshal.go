@@ -0,0 +1,196 @@
+package bencode
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Marshal returns the bencoding of v. It handles every integer kind, bool (as
+// 0 or 1), strings, []byte, byte arrays, slices, arrays, maps with string
+// keys, pointers and structs.
+//
+// Struct fields are encoded as dictionary entries keyed by the field name or
+// the name given in a `bencode:"name"` tag. The ",omitempty" option skips zero
+// values and a tag of "-" skips the field. Nil pointers and interfaces are
+// always skipped, since bencode has no null.
+func Marshal(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	err := NewEncoder(&buf).encodeReflect(reflect.ValueOf(v))
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+type field struct {
+	name      string
+	index     int
+	omitEmpty bool
+}
+
+func structFields(t reflect.Type) []field {
+	fields := make([]field, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+
+		name, opts, _ := strings.Cut(sf.Tag.Get("bencode"), ",")
+		if name == "-" && opts == "" {
+			continue
+		}
+		if name == "" {
+			name = sf.Name
+		}
+		fields = append(fields, field{
+			name:      name,
+			index:     i,
+			omitEmpty: opts == "omitempty",
+		})
+	}
+
+	sort.Slice(fields, func(i, j int) bool {
+		return fields[i].name < fields[j].name
+	})
+	return fields
+}
+
+func isByteSequence(t reflect.Type) bool {
+	return (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) &&
+		t.Elem().Kind() == reflect.Uint8
+}
+
+func (e *Encoder) encodeReflect(v reflect.Value) error {
+	if !v.IsValid() {
+		return errors.New("bencode: cannot encode nil")
+	}
+	if v.Type() == rawMessageType {
+		_, err := e.w.Write(v.Bytes())
+		return err
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return errors.New("bencode: cannot encode nil")
+		}
+		return e.encodeReflect(v.Elem())
+	case reflect.String:
+		return e.encodeString(v.String())
+	case reflect.Bool:
+		if v.Bool() {
+			return e.encodeInteger(1)
+		}
+		return e.encodeInteger(0)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Int64:
+		return e.encodeInteger(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32,
+		reflect.Uint64, reflect.Uintptr:
+		u := v.Uint()
+		if u > math.MaxInt64 {
+			return fmt.Errorf("bencode: %d overflows int64", u)
+		}
+		return e.encodeInteger(int64(u))
+	case reflect.Slice, reflect.Array:
+		if isByteSequence(v.Type()) {
+			if v.Kind() == reflect.Array {
+				b := make([]byte, v.Len())
+				reflect.Copy(reflect.ValueOf(b), v)
+				return e.encodeBytes(b)
+			}
+			return e.encodeBytes(v.Bytes())
+		}
+		return e.encodeReflectList(v)
+	case reflect.Map:
+		return e.encodeReflectMap(v)
+	case reflect.Struct:
+		return e.encodeReflectStruct(v)
+	default:
+		return fmt.Errorf("bencode: unsupported type '%s'", v.Type())
+	}
+}
+
+func (e *Encoder) encodeReflectList(v reflect.Value) error {
+	if _, err := e.w.Write([]byte{byte(bList)}); err != nil {
+		return err
+	}
+
+	for i := 0; i < v.Len(); i++ {
+		if err := e.encodeReflect(v.Index(i)); err != nil {
+			return err
+		}
+	}
+
+	_, err := e.w.Write([]byte{byte(bDelim)})
+	return err
+}
+
+func (e *Encoder) encodeReflectMap(v reflect.Value) error {
+	if v.Type().Key().Kind() != reflect.String {
+		return fmt.Errorf(
+			"bencode: unsupported map key type '%s'",
+			v.Type().Key(),
+		)
+	}
+	if _, err := e.w.Write([]byte{byte(bDict)}); err != nil {
+		return err
+	}
+
+	keys := v.MapKeys()
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].String() < keys[j].String()
+	})
+	for _, k := range keys {
+		val := v.MapIndex(k)
+		if isNil(val) {
+			continue
+		}
+		if err := e.encodeString(k.String()); err != nil {
+			return err
+		}
+		if err := e.encodeReflect(val); err != nil {
+			return err
+		}
+	}
+
+	_, err := e.w.Write([]byte{byte(bDelim)})
+	return err
+}
+
+func (e *Encoder) encodeReflectStruct(v reflect.Value) error {
+	if _, err := e.w.Write([]byte{byte(bDict)}); err != nil {
+		return err
+	}
+
+	for _, f := range structFields(v.Type()) {
+		val := v.Field(f.index)
+		if isNil(val) || f.omitEmpty && val.IsZero() {
+			continue
+		}
+		if err := e.encodeString(f.name); err != nil {
+			return err
+		}
+		if err := e.encodeReflect(val); err != nil {
+			return err
+		}
+	}
+
+	_, err := e.w.Write([]byte{byte(bDelim)})
+	return err
+}
+
+func isNil(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		return v.IsNil()
+	}
+	return false
+}
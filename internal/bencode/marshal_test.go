@@ -0,0 +1,193 @@
+package bencode
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type testFile struct {
+	Length int64    `bencode:"length"`
+	Path   []string `bencode:"path"`
+}
+
+type testInfo struct {
+	Name        string     `bencode:"name"`
+	PieceLength uint32     `bencode:"piece length"`
+	Pieces      []byte     `bencode:"pieces"`
+	Private     int8       `bencode:"private,omitempty"`
+	Files       []testFile `bencode:"files,omitempty"`
+	Skipped     string     `bencode:"-"`
+}
+
+type testMeta struct {
+	Announce string    `bencode:"announce"`
+	Info     *testInfo `bencode:"info"`
+	Hash     [4]byte   `bencode:"hash"`
+	Extra    any       `bencode:"extra,omitempty"`
+}
+
+func TestMarshalStruct(t *testing.T) {
+	m := testMeta{
+		Announce: "http://t",
+		Info: &testInfo{
+			Name:        "a",
+			PieceLength: 16384,
+			Pieces:      []byte{0, 1},
+			Files: []testFile{
+				{Length: 3, Path: []string{"x"}},
+			},
+			Skipped: "nope",
+		},
+		Hash: [4]byte{'a', 'b', 'c', 'd'},
+	}
+
+	got, err := Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "d8:announce8:http://t4:hash4:abcd" +
+		"4:infod5:filesld6:lengthi3e4:pathl1:xeee4:name1:a" +
+		"12:piece lengthi16384e6:pieces2:\x00\x01ee"
+	if string(got) != want {
+		t.Fatalf("Marshal = %q; want %q", got, want)
+	}
+
+	var back testMeta
+	if err := Unmarshal(got, &back); err != nil {
+		t.Fatal(err)
+	}
+	m.Info.Skipped = ""
+	if !reflect.DeepEqual(back, m) {
+		t.Fatalf("round trip = %+v; want %+v", back, m)
+	}
+}
+
+func TestUnmarshalMapsAndAny(t *testing.T) {
+	var m map[string]any
+	if err := Unmarshal([]byte("d1:ai1e1:bl1:cee"), &m); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{"a": int64(1), "b": []any{"c"}}
+	if !reflect.DeepEqual(m, want) {
+		t.Fatalf("got %#v; want %#v", m, want)
+	}
+
+	var counts map[string]uint16
+	if err := Unmarshal([]byte("d1:xi7ee"), &counts); err != nil {
+		t.Fatal(err)
+	}
+	if counts["x"] != 7 {
+		t.Fatalf("got %v", counts)
+	}
+}
+
+func TestUnmarshalErrors(t *testing.T) {
+	cases := []struct {
+		in  string
+		dst any
+	}{
+		{"i300e", new(uint8)},
+		{"i-1e", new(uint32)},
+		{"4:spam", new(int)},
+		{"i1e", new(string)},
+		{"3:abc", new([4]byte)},
+		{"d4:namei1ee", new(testInfo)},
+	}
+
+	for _, tc := range cases {
+		if err := Unmarshal([]byte(tc.in), tc.dst); err == nil {
+			t.Fatalf("Unmarshal(%q, %T) succeeded", tc.in, tc.dst)
+		}
+	}
+
+	var s string
+	if err := Unmarshal([]byte("4:spam"), s); err == nil {
+		t.Fatal("Unmarshal into non-pointer succeeded")
+	}
+}
+
+func TestUnmarshalTooDeep(t *testing.T) {
+	deep := []byte(strings.Repeat("l", 16<<20))
+	skipped := append([]byte("d4:deep"), deep...)
+	cases := []struct {
+		in  []byte
+		dst any
+	}{
+		{deep, new(any)},
+		{deep, new([]any)},
+		{deep, new(RawMessage)},
+		{skipped, new(testInfo)},
+		{skipped, new(map[string]RawMessage)},
+	}
+	for _, tc := range cases {
+		err := Unmarshal(tc.in, tc.dst)
+		if !errors.Is(err, ErrTooDeep) {
+			t.Errorf("Unmarshal into %T = %v", tc.dst, err)
+		}
+	}
+
+	limit := strings.Repeat("l", MaxDepth) + strings.Repeat("e", MaxDepth)
+	var v any
+	if err := Unmarshal([]byte(limit), &v); err != nil {
+		t.Errorf("%d levels: %v", MaxDepth, err)
+	}
+}
+
+func TestRawMessageKeepsOriginalBytes(t *testing.T) {
+	// "b" before "a" is not canonical; re-encoding would reorder it.
+	in := "d4:infod1:bi1e1:ai2ee4:name1:xe"
+
+	var top struct {
+		Info RawMessage `bencode:"info"`
+		Name string     `bencode:"name"`
+	}
+	if err := Unmarshal([]byte(in), &top); err != nil {
+		t.Fatal(err)
+	}
+	if got := string(top.Info); got != "d1:bi1e1:ai2ee" {
+		t.Fatalf("Info = %q", got)
+	}
+	if top.Name != "x" {
+		t.Fatalf("Name = %q", top.Name)
+	}
+
+	out, err := Marshal(top)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != in {
+		t.Fatalf("Marshal = %q; want %q", out, in)
+	}
+}
+
+func TestUnmarshalErrorOffset(t *testing.T) {
+	var info testInfo
+	err := Unmarshal([]byte("d4:name1:x12:piece length1:xe"), &info)
+
+	var de *DecodeError
+	if !errors.As(err, &de) || !errors.Is(err, ErrTypeMismatch) {
+		t.Fatalf("Unmarshal error = %v; want type mismatch", err)
+	}
+	if de.Offset != 25 {
+		t.Fatalf("offset = %d; want 25", de.Offset)
+	}
+}
+
+func TestUnmarshalBool(t *testing.T) {
+	var v struct {
+		Private bool `bencode:"private"`
+	}
+	if err := Unmarshal([]byte("d7:privatei1ee"), &v); err != nil {
+		t.Fatal(err)
+	}
+	if !v.Private {
+		t.Fatal("private = false; want true")
+	}
+
+	err := Unmarshal([]byte("d7:privatei2ee"), &v)
+	if !errors.Is(err, ErrTypeMismatch) {
+		t.Fatalf("Unmarshal(i2e) error = %v; want ErrTypeMismatch", err)
+	}
+}
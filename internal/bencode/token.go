@@ -0,0 +1,139 @@
+package bencode
+
+import "io"
+
+type TokenKind uint8
+
+const (
+	TokenInteger TokenKind = iota + 1
+	TokenString
+	TokenList
+	TokenDict
+	TokenEnd
+)
+
+func (k TokenKind) String() string {
+	switch k {
+	case TokenInteger:
+		return "integer"
+	case TokenString:
+		return "string"
+	case TokenList:
+		return "list"
+	case TokenDict:
+		return "dict"
+	case TokenEnd:
+		return "end"
+	default:
+		return "unknown"
+	}
+}
+
+// Token is one step of the streaming API. Integers carry their value; strings
+// only their length, with the contents left in the input for StringReader.
+type Token struct {
+	Kind   TokenKind
+	Int    int64
+	Len    int64
+	Offset int64
+}
+
+// Next returns the next token in the input. Lists and dicts are opened by a
+// TokenList or TokenDict and closed by TokenEnd; dict keys and values simply
+// alternate. Any unread part of the previous string is skipped. Next returns
+// io.EOF once the input ends between top-level values.
+func (d *Decoder) Next() (Token, error) {
+	if err := d.skipPending(); err != nil {
+		return Token{}, err
+	}
+	if _, err := d.r.Peek(1); err == io.EOF && d.depth == 0 {
+		return Token{}, io.EOF
+	}
+
+	start := d.off
+	c, err := d.readByte()
+	if err != nil {
+		return Token{}, err
+	}
+
+	switch c {
+	case byte(bInteger):
+		n, err := d.decodeInteger()
+		if err != nil {
+			return Token{}, err
+		}
+		return Token{Kind: TokenInteger, Int: n, Offset: start}, nil
+	case byte(bList), byte(bDict):
+		d.depth++
+		kind := TokenList
+		if c == byte(bDict) {
+			kind = TokenDict
+		}
+		return Token{Kind: kind, Offset: start}, nil
+	case byte(bDelim):
+		if d.depth == 0 {
+			return Token{}, d.errorAt(start, ErrUnexpectedByte)
+		}
+		d.depth--
+		return Token{Kind: TokenEnd, Offset: start}, nil
+	}
+
+	if (c < '0' || c > '9') && c != '-' {
+		return Token{}, d.errorAt(start, ErrUnexpectedByte)
+	}
+	if err := d.unreadByte(); err != nil {
+		return Token{}, err
+	}
+	size, err := d.readInteger(':', ErrInvalidLength)
+	if err != nil {
+		return Token{}, err
+	}
+	if size < 0 {
+		return Token{}, d.errorAt(start, ErrNegativeLength)
+	}
+
+	d.pending = size
+	return Token{Kind: TokenString, Len: size, Offset: start}, nil
+}
+
+// StringReader reads the contents of the string token Next just returned,
+// without buffering it all in memory.
+func (d *Decoder) StringReader() io.Reader {
+	return stringReader{d}
+}
+
+type stringReader struct {
+	d *Decoder
+}
+
+func (s stringReader) Read(p []byte) (int, error) {
+	d := s.d
+	if d.pending == 0 {
+		return 0, io.EOF
+	}
+	if int64(len(p)) > d.pending {
+		p = p[:d.pending]
+	}
+
+	n, err := d.r.Read(p)
+	d.off += int64(n)
+	d.pending -= int64(n)
+	if err != nil {
+		return n, d.ioError(err)
+	}
+	return n, nil
+}
+
+func (d *Decoder) skipPending() error {
+	if d.pending == 0 {
+		return nil
+	}
+
+	n, err := d.r.Discard(int(d.pending))
+	d.off += int64(n)
+	d.pending -= int64(n)
+	if err != nil {
+		return d.ioError(err)
+	}
+	return nil
+}
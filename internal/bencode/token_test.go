@@ -0,0 +1,104 @@
+package bencode
+
+import (
+	"crypto/sha1"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestNextTokens(t *testing.T) {
+	d := NewDecoder(strings.NewReader("d3:cowi3e4:spaml1:ai-2eee"))
+
+	want := []Token{
+		{Kind: TokenDict, Offset: 0},
+		{Kind: TokenString, Len: 3, Offset: 1},
+		{Kind: TokenInteger, Int: 3, Offset: 6},
+		{Kind: TokenString, Len: 4, Offset: 9},
+		{Kind: TokenList, Offset: 15},
+		{Kind: TokenString, Len: 1, Offset: 16},
+		{Kind: TokenInteger, Int: -2, Offset: 19},
+		{Kind: TokenEnd, Offset: 23},
+		{Kind: TokenEnd, Offset: 24},
+	}
+	for i, w := range want {
+		got, err := d.Next()
+		if err != nil {
+			t.Fatalf("token %d: error = %v", i, err)
+		}
+		if got != w {
+			t.Fatalf("token %d = %+v, want %+v", i, got, w)
+		}
+	}
+
+	if _, err := d.Next(); err != io.EOF {
+		t.Fatalf("final Next error = %v, want io.EOF", err)
+	}
+}
+
+func TestNextStreamsStrings(t *testing.T) {
+	pieces := strings.Repeat("x", 100_000)
+	input := "d6:lengthi5e6:pieces" + "100000:" + pieces + "e"
+	d := NewDecoder(strings.NewReader(input))
+
+	var hashed []byte
+	for {
+		tok, err := d.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Next error = %v", err)
+		}
+		if tok.Kind != TokenString || tok.Len != 6 {
+			continue
+		}
+
+		key, err := io.ReadAll(d.StringReader())
+		if err != nil {
+			t.Fatalf("read key: %v", err)
+		}
+		if string(key) != "pieces" {
+			continue
+		}
+		if _, err := d.Next(); err != nil {
+			t.Fatalf("Next value error = %v", err)
+		}
+		h := sha1.New()
+		if _, err := io.Copy(h, d.StringReader()); err != nil {
+			t.Fatalf("stream pieces: %v", err)
+		}
+		hashed = h.Sum(nil)
+	}
+
+	sum := sha1.Sum([]byte(pieces))
+	if string(hashed) != string(sum[:]) {
+		t.Fatal("streamed pieces hash mismatch")
+	}
+	if d.InputOffset() != int64(len(input)) {
+		t.Fatalf("offset = %d, want %d", d.InputOffset(), len(input))
+	}
+}
+
+func TestNextErrors(t *testing.T) {
+	cases := map[string]error{
+		"e":      ErrUnexpectedByte,
+		"x":      ErrUnexpectedByte,
+		"l":      ErrUnexpectedEOF,
+		"5:ab":   ErrUnexpectedEOF,
+		"-1:":    ErrNegativeLength,
+		"i1x2e":  ErrInvalidInteger,
+		"d3:key": ErrUnexpectedEOF,
+	}
+	for input, want := range cases {
+		d := NewDecoder(strings.NewReader(input))
+		var err error
+		for err == nil {
+			_, err = d.Next()
+		}
+		if !errors.Is(err, want) {
+			t.Errorf("%q: error = %v, want %v", input, err, want)
+		}
+	}
+}
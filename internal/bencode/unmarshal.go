@@ -0,0 +1,423 @@
+package bencode
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+)
+
+// RawMessage is a raw encoded bencode value. Unmarshal fills it with the exact
+// bytes of the value in the input, which is what hashes such as the info-hash
+// must be computed over; Marshal and Encode write it out verbatim.
+type RawMessage []byte
+
+var rawMessageType = reflect.TypeFor[RawMessage]()
+
+// Unmarshal decodes data and stores the result in the value pointed to by v.
+// Dictionaries decode into structs (matched by tag or field name) or maps
+// with string keys, lists into slices or arrays, strings into string, []byte
+// or byte arrays of the exact length, and integers into any integer kind that
+// can hold them or into bool when 0 or 1. Empty interfaces receive the same
+// values Decode returns. Unknown dictionary keys are ignored.
+func Unmarshal(data []byte, v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return errors.New("bencode: Unmarshal needs a non-nil pointer")
+	}
+
+	u := &unmarshaler{data: data}
+	return u.value(rv.Elem())
+}
+
+// unmarshaler walks the input in place, so values can be assigned without an
+// intermediate map and raw spans can be sliced out directly.
+type unmarshaler struct {
+	data []byte
+	pos  int
+	// depth counts the lists and dictionaries being walked.
+	depth int
+}
+
+// enter goes into a list or dictionary, failing past MaxDepth; leave must
+// follow once it is done with.
+func (u *unmarshaler) enter() error {
+	if u.depth >= MaxDepth {
+		return u.fail(ErrTooDeep, "")
+	}
+	u.depth++
+	return nil
+}
+
+func (u *unmarshaler) leave() {
+	u.depth--
+}
+
+// fail reports err at the current position, with an optional more specific
+// message.
+func (u *unmarshaler) fail(err error, format string, args ...any) error {
+	e := &DecodeError{Offset: int64(u.pos), Err: err}
+	if format != "" {
+		e.Detail = fmt.Sprintf(format, args...)
+	}
+	return e
+}
+
+func (u *unmarshaler) peek() (byte, error) {
+	if u.pos >= len(u.data) {
+		return 0, u.fail(ErrUnexpectedEOF, "")
+	}
+	return u.data[u.pos], nil
+}
+
+func (u *unmarshaler) value(dst reflect.Value) error {
+	if dst.Type() == rawMessageType {
+		start := u.pos
+		if err := u.skip(); err != nil {
+			return err
+		}
+		dst.SetBytes(bytes.Clone(u.data[start:u.pos]))
+		return nil
+	}
+
+	switch dst.Kind() {
+	case reflect.Pointer:
+		if dst.IsNil() {
+			dst.Set(reflect.New(dst.Type().Elem()))
+		}
+		return u.value(dst.Elem())
+	case reflect.Interface:
+		if dst.NumMethod() != 0 {
+			break
+		}
+		start := u.pos
+		if err := u.skip(); err != nil {
+			return err
+		}
+		raw := u.data[start:u.pos]
+		v, err := NewDecoder(bytes.NewReader(raw)).Decode()
+		if err != nil {
+			return err
+		}
+		dst.Set(reflect.ValueOf(v))
+		return nil
+	}
+
+	c, err := u.peek()
+	if err != nil {
+		return err
+	}
+	switch {
+	case c == byte(bInteger):
+		return u.integer(dst)
+	case c == byte(bList):
+		return u.list(dst)
+	case c == byte(bDict):
+		return u.dict(dst)
+	case c >= '0' && c <= '9':
+		return u.str(dst)
+	default:
+		return u.fail(ErrUnexpectedByte, "unexpected %q", c)
+	}
+}
+
+func (u *unmarshaler) readInt() (int64, error) {
+	u.pos++ // 'i'
+	end := bytes.IndexByte(u.data[u.pos:], byte(bDelim))
+	if end < 0 {
+		return 0, u.fail(ErrUnexpectedEOF, "unterminated integer")
+	}
+
+	n, err := strconv.ParseInt(string(u.data[u.pos:u.pos+end]), 10, 64)
+	if err != nil {
+		return 0, u.fail(ErrInvalidInteger, "")
+	}
+	u.pos += end + 1
+	return n, nil
+}
+
+func (u *unmarshaler) readString() ([]byte, error) {
+	colon := bytes.IndexByte(u.data[u.pos:], ':')
+	if colon < 0 {
+		return nil, u.fail(ErrUnexpectedEOF, "missing ':' after length")
+	}
+
+	size, err := strconv.Atoi(string(u.data[u.pos : u.pos+colon]))
+	if err != nil {
+		return nil, u.fail(ErrInvalidLength, "")
+	}
+	if size < 0 {
+		return nil, u.fail(ErrNegativeLength, "")
+	}
+	start := u.pos + colon + 1
+	if size > len(u.data)-start {
+		return nil, u.fail(
+			ErrUnexpectedEOF,
+			"string length %d exceeds input",
+			size,
+		)
+	}
+
+	u.pos = start + size
+	return u.data[start:u.pos], nil
+}
+
+func (u *unmarshaler) integer(dst reflect.Value) error {
+	start := u.pos
+	n, err := u.readInt()
+	if err != nil {
+		return err
+	}
+
+	switch dst.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Int64:
+		if dst.OverflowInt(n) {
+			u.pos = start
+			return u.overflow(n, dst.Type())
+		}
+		dst.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32,
+		reflect.Uint64, reflect.Uintptr:
+		if n < 0 || dst.OverflowUint(uint64(n)) {
+			u.pos = start
+			return u.overflow(n, dst.Type())
+		}
+		dst.SetUint(uint64(n))
+	case reflect.Bool:
+		if n != 0 && n != 1 {
+			u.pos = start
+			return u.fail(ErrTypeMismatch, "%d is not a boolean", n)
+		}
+		dst.SetBool(n == 1)
+	default:
+		u.pos = start
+		return u.typeError("integer", dst.Type())
+	}
+
+	return nil
+}
+
+func (u *unmarshaler) str(dst reflect.Value) error {
+	start := u.pos
+	b, err := u.readString()
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case dst.Kind() == reflect.String:
+		dst.SetString(string(b))
+	case dst.Kind() == reflect.Slice && isByteSequence(dst.Type()):
+		dst.SetBytes(bytes.Clone(b))
+	case dst.Kind() == reflect.Array && isByteSequence(dst.Type()):
+		if len(b) != dst.Len() {
+			u.pos = start
+			return u.fail(
+				ErrTypeMismatch,
+				"%d-byte string into '%s'",
+				len(b),
+				dst.Type(),
+			)
+		}
+		reflect.Copy(dst, reflect.ValueOf(b))
+	default:
+		u.pos = start
+		return u.typeError("string", dst.Type())
+	}
+
+	return nil
+}
+
+func (u *unmarshaler) list(dst reflect.Value) error {
+	if err := u.enter(); err != nil {
+		return err
+	}
+	defer u.leave()
+
+	switch dst.Kind() {
+	case reflect.Slice:
+		u.pos++ // 'l'
+		out := reflect.MakeSlice(dst.Type(), 0, 0)
+		for {
+			c, err := u.peek()
+			if err != nil {
+				return err
+			}
+			if c == byte(bDelim) {
+				u.pos++
+				break
+			}
+
+			elem := reflect.New(dst.Type().Elem()).Elem()
+			if err := u.value(elem); err != nil {
+				return err
+			}
+			out = reflect.Append(out, elem)
+		}
+		dst.Set(out)
+	case reflect.Array:
+		u.pos++ // 'l'
+		for i := 0; ; i++ {
+			c, err := u.peek()
+			if err != nil {
+				return err
+			}
+			if c == byte(bDelim) {
+				if i != dst.Len() {
+					return u.fail(
+						ErrTypeMismatch,
+						"%d-item list into '%s'",
+						i,
+						dst.Type(),
+					)
+				}
+				u.pos++
+				break
+			}
+			if i >= dst.Len() {
+				return u.fail(
+					ErrTypeMismatch,
+					"list too long for '%s'",
+					dst.Type(),
+				)
+			}
+			if err := u.value(dst.Index(i)); err != nil {
+				return err
+			}
+		}
+	default:
+		return u.typeError("list", dst.Type())
+	}
+
+	return nil
+}
+
+func (u *unmarshaler) dict(dst reflect.Value) error {
+	var fields map[string]field
+	switch dst.Kind() {
+	case reflect.Map:
+		if dst.Type().Key().Kind() != reflect.String {
+			return u.typeError("dictionary", dst.Type())
+		}
+		if dst.IsNil() {
+			dst.Set(reflect.MakeMap(dst.Type()))
+		}
+	case reflect.Struct:
+		fields = make(map[string]field)
+		for _, f := range structFields(dst.Type()) {
+			fields[f.name] = f
+		}
+	default:
+		return u.typeError("dictionary", dst.Type())
+	}
+	if err := u.enter(); err != nil {
+		return err
+	}
+	defer u.leave()
+
+	u.pos++ // 'd'
+	for {
+		c, err := u.peek()
+		if err != nil {
+			return err
+		}
+		if c == byte(bDelim) {
+			u.pos++
+			return nil
+		}
+		if c < '0' || c > '9' {
+			return u.errKeyNotString()
+		}
+		key, err := u.readString()
+		if err != nil {
+			return err
+		}
+
+		if dst.Kind() == reflect.Map {
+			elem := reflect.New(dst.Type().Elem()).Elem()
+			if err := u.value(elem); err != nil {
+				return err
+			}
+			k := reflect.ValueOf(string(key))
+			dst.SetMapIndex(k.Convert(dst.Type().Key()), elem)
+			continue
+		}
+
+		f, ok := fields[string(key)]
+		if !ok {
+			if err := u.skip(); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := u.value(dst.Field(f.index)); err != nil {
+			return fmt.Errorf("%w (field %q)", err, f.name)
+		}
+	}
+}
+
+// skip advances past one complete value without decoding it.
+func (u *unmarshaler) skip() error {
+	c, err := u.peek()
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case c == byte(bInteger):
+		_, err := u.readInt()
+		return err
+	case c >= '0' && c <= '9':
+		_, err := u.readString()
+		return err
+	case c == byte(bList) || c == byte(bDict):
+		if err := u.enter(); err != nil {
+			return err
+		}
+		defer u.leave()
+		dict := c == byte(bDict)
+		u.pos++
+		for {
+			c, err := u.peek()
+			if err != nil {
+				return err
+			}
+			if c == byte(bDelim) {
+				u.pos++
+				return nil
+			}
+			if dict {
+				if c < '0' || c > '9' {
+					return u.errKeyNotString()
+				}
+				if _, err := u.readString(); err != nil {
+					return err
+				}
+			}
+			if err := u.skip(); err != nil {
+				return err
+			}
+		}
+	default:
+		return u.fail(ErrUnexpectedByte, "unexpected %q", c)
+	}
+}
+
+func (u *unmarshaler) overflow(n int64, t reflect.Type) error {
+	return u.fail(ErrOverflow, "%d overflows '%s'", n, t)
+}
+
+func (u *unmarshaler) errKeyNotString() error {
+	return u.fail(ErrKeyNotString, "")
+}
+
+func (u *unmarshaler) typeError(kind string, t reflect.Type) error {
+	return u.fail(
+		ErrTypeMismatch,
+		"cannot unmarshal %s into '%s'",
+		kind,
+		t,
+	)
+}
@@ -2,67 +2,203 @@ package bitfield
 
 import (
 	"bytes"
+	"errors"
+	"fmt"
+	"iter"
 	"math/bits"
 )
 
-type Bitfield []byte
+// ErrSpareBits rejects a bitfield with bits set past its last piece, which
+// the protocol requires to be clear.
+var ErrSpareBits = errors.New("bitfield: spare bits set")
+
+// Bitfield is a set of piece indices, packed high bit first as the peer
+// protocol sends it. It knows how many bits it holds, so that the spare
+// bits padding the last byte are never taken for pieces.
+type Bitfield struct {
+	bits []byte
+	n    int
+}
 
 func New(n int) Bitfield {
-	size := (n + 7) / 8
-	if size < 0 {
-		size = 0
-	}
-	return make(Bitfield, size)
+	n = max(n, 0)
+	return Bitfield{bits: make([]byte, (n+7)/8), n: n}
 }
 
-func FromBytes(b []byte) Bitfield {
-	bf := make(Bitfield, len(b))
-	copy(bf, b)
+// FromBytes returns a bitfield of n bits read from a copy of b, as a peer
+// sent or the session saved it. b is taken as it is; Validate checks it.
+func FromBytes(b []byte, n int) Bitfield {
+	bf := Bitfield{bits: make([]byte, len(b)), n: max(n, 0)}
+	copy(bf.bits, b)
 	return bf
 }
 
+// Clone returns a copy of bf that shares nothing with it.
+func (bf Bitfield) Clone() Bitfield {
+	return FromBytes(bf.bits, bf.n)
+}
+
 func (bf Bitfield) ToBytes() []byte {
-	out := make([]byte, len(bf))
-	copy(out, bf)
+	out := make([]byte, len(bf.bits))
+	copy(out, bf.bits)
 	return out
 }
 
+// Validate checks a bitfield, such as a peer's, against the torrent's
+// numPieces: it must be exactly as long as they need, with the spare bits
+// left clear.
+func (bf Bitfield) Validate(numPieces int) error {
+	if bf.n != numPieces || len(bf.bits) != (numPieces+7)/8 {
+		return fmt.Errorf(
+			"bitfield: %d bytes for %d pieces",
+			len(bf.bits),
+			numPieces,
+		)
+	}
+	spare := bf.n % 8
+	if spare != 0 && bf.bits[len(bf.bits)-1]<<spare != 0 {
+		return ErrSpareBits
+	}
+	return nil
+}
+
 func (bf Bitfield) Has(index int) bool {
-	byteIndex, offset := index/8, index%8
-	if byteIndex < 0 || byteIndex >= len(bf) {
+	if index < 0 || index >= bf.n || index/8 >= len(bf.bits) {
 		return false
 	}
-	return bf[byteIndex]>>(7-offset)&1 != 0
+	return bf.bits[index/8]>>(7-index%8)&1 != 0
 }
 
 func (bf Bitfield) Set(index int) {
-	byteIndex, offset := index/8, index%8
-	if byteIndex < 0 || byteIndex >= len(bf) {
+	if index < 0 || index >= bf.n || index/8 >= len(bf.bits) {
 		return
 	}
-	bf[byteIndex] |= 1 << (7 - offset)
+	bf.bits[index/8] |= 1 << (7 - index%8)
 }
 
 func (bf Bitfield) Clear(index int) {
-	byteIndex, offset := index/8, index%8
-	if byteIndex < 0 || byteIndex >= len(bf) {
+	if index < 0 || index >= bf.n || index/8 >= len(bf.bits) {
 		return
 	}
-	bf[byteIndex] &^= 1 << (7 - offset)
+	bf.bits[index/8] &^= 1 << (7 - index%8)
+}
+
+// SetAll sets every bit, leaving the spare ones clear.
+func (bf Bitfield) SetAll() {
+	for i := range bf.bits {
+		bf.bits[i] = 0xff
+	}
+	if spare := bf.n % 8; spare != 0 && len(bf.bits) == (bf.n+7)/8 {
+		bf.bits[len(bf.bits)-1] = 0xff << (8 - spare)
+	}
+}
+
+// ClearAll clears every bit.
+func (bf Bitfield) ClearAll() {
+	clear(bf.bits)
 }
 
-func (bf Bitfield) Len() int { return len(bf) * 8 }
+// Len returns the number of bits, which the bytes may pad.
+func (bf Bitfield) Len() int { return bf.n }
 
 func (bf Bitfield) Count() int {
 	c := 0
-	for _, b := range bf {
+	for _, b := range bf.bits {
 		c += bits.OnesCount8(b)
 	}
 	return c
 }
 
+// And returns the bits set in both bf and other, as long as bf. The set
+// operations take bits past the end of other as clear.
+func (bf Bitfield) And(other Bitfield) Bitfield {
+	return bf.combine(other, func(a, b byte) byte { return a & b })
+}
+
+// Or returns the bits set in either bf or other.
+func (bf Bitfield) Or(other Bitfield) Bitfield {
+	return bf.combine(other, func(a, b byte) byte { return a | b })
+}
+
+// AndNot returns the bits set in bf but not in other, such as the pieces a
+// peer has that we lack.
+func (bf Bitfield) AndNot(other Bitfield) Bitfield {
+	return bf.combine(other, func(a, b byte) byte { return a &^ b })
+}
+
+// Xor returns the bits set in one of bf and other but not both.
+func (bf Bitfield) Xor(other Bitfield) Bitfield {
+	return bf.combine(other, func(a, b byte) byte { return a ^ b })
+}
+
+func (bf Bitfield) combine(
+	other Bitfield,
+	op func(a, b byte) byte,
+) Bitfield {
+	out := New(bf.n)
+	for i := range out.bits {
+		out.bits[i] = op(bf.byteAt(i), other.byteAt(i))
+	}
+	if spare := bf.n % 8; spare != 0 {
+		out.bits[len(out.bits)-1] &= 0xff << (8 - spare)
+	}
+	return out
+}
+
+// byteAt returns the i-th byte, zero past the end.
+func (bf Bitfield) byteAt(i int) byte {
+	if i < len(bf.bits) {
+		return bf.bits[i]
+	}
+	return 0
+}
+
+// NextSet returns the index of the first set bit at or after from, or -1
+// if there is none.
+func (bf Bitfield) NextSet(from int) int {
+	from = max(from, 0)
+	for i := from / 8; i < len(bf.bits); i++ {
+		b := bf.bits[i]
+		if i == from/8 {
+			b &= 0xff >> (from % 8)
+		}
+		if b != 0 {
+			if index := i*8 + bits.LeadingZeros8(b); index < bf.n {
+				return index
+			}
+			return -1
+		}
+	}
+	return -1
+}
+
+// FirstUnset returns the index of the first clear bit, or -1 if every bit
+// is set.
+func (bf Bitfield) FirstUnset() int {
+	for i := range (bf.n + 7) / 8 {
+		if b := bf.byteAt(i); b != 0xff {
+			if index := i*8 + bits.LeadingZeros8(^b); index < bf.n {
+				return index
+			}
+			return -1
+		}
+	}
+	return -1
+}
+
+// Indices yields the index of every set bit, in order.
+func (bf Bitfield) Indices() iter.Seq[int] {
+	return func(yield func(int) bool) {
+		for i := bf.NextSet(0); i >= 0; i = bf.NextSet(i + 1) {
+			if !yield(i) {
+				return
+			}
+		}
+	}
+}
+
 func (bf Bitfield) Equals(other Bitfield) bool {
-	return bytes.Equal(bf, other)
+	return bf.n == other.n && bytes.Equal(bf.bits, other.bits)
 }
 
 func (bf Bitfield) String() string {
@@ -1,10 +1,13 @@
 package bitfield
 
-import "testing"
+import (
+	"errors"
+	"testing"
+)
 
 func TestNewSizeRounding(t *testing.T) {
 	cases := []struct {
-		nBits     int64
+		nBits     int
 		wantBytes int
 	}{
 		{0, 0},
@@ -18,7 +21,7 @@ func TestNewSizeRounding(t *testing.T) {
 
 	for _, tc := range cases {
 		bf := New(tc.nBits)
-		if got := len(bf); got != tc.wantBytes {
+		if got := len(bf.bits); got != tc.wantBytes {
 			t.Fatalf(
 				"New(%d) bytes = %d; want %d",
 				tc.nBits,
@@ -32,32 +35,32 @@ func TestNewSizeRounding(t *testing.T) {
 func TestSetHasClearAndBounds(t *testing.T) {
 	bf := New(10) // 2 bytes
 
-	if bf.HasBit(-1) || bf.HasBit(100) {
-		t.Fatalf("HasBit out-of-range should be false")
+	if bf.Has(-1) || bf.Has(100) {
+		t.Fatalf("Has out-of-range should be false")
 	}
 
 	// Set bits at 0,7,8,9
 	idxs := []int{0, 7, 8, 9}
 	for _, i := range idxs {
-		bf.SetBit(i)
+		bf.Set(i)
 	}
 	for _, i := range idxs {
-		if !bf.HasBit(i) {
+		if !bf.Has(i) {
 			t.Fatalf("bit %d should be set", i)
 		}
 	}
 
 	// Clear one and verify
-	bf.ClearBit(7)
-	if bf.HasBit(7) {
+	bf.Clear(7)
+	if bf.Has(7) {
 		t.Fatalf("bit 7 should be cleared")
 	}
 
 	// Out-of-range operations must not panic or affect valid bits
-	bf.SetBit(100)
-	bf.ClearBit(-42)
+	bf.Set(100)
+	bf.Clear(-42)
 	for _, i := range []int{0, 8, 9} {
-		if !bf.HasBit(i) {
+		if !bf.Has(i) {
 			t.Fatalf("bit %d unexpectedly cleared by OOB ops", i)
 		}
 	}
@@ -65,23 +68,23 @@ func TestSetHasClearAndBounds(t *testing.T) {
 
 func TestFromBytesAndToBytesIndependence(t *testing.T) {
 	src := []byte{0xFF, 0x00}
-	bf := FromBytes(src)
+	bf := FromBytes(src, 16)
 
 	// mutate src; bf should be unchanged
 	src[0] = 0x00
-	if !bf.Equals(Bitfield{0xFF, 0x00}) {
+	if !bf.Equals(FromBytes([]byte{0xFF, 0x00}, 16)) {
 		t.Fatalf("FromBytes must copy input")
 	}
 
 	out := bf.ToBytes()
 	out[1] = 0xAA
-	if bf[1] != 0x00 {
+	if bf.bits[1] != 0x00 {
 		t.Fatalf("ToBytes must return a copy, not alias")
 	}
 }
 
 func TestStringRepresentation(t *testing.T) {
-	bf := FromBytes([]byte{0xA5, 0x01}) // 1010 0101 0000 0001
+	bf := FromBytes([]byte{0xA5, 0x01}, 16) // 1010 0101 0000 0001
 	got := bf.String()
 	want := "1010010100000001"
 	if got != want {
@@ -91,23 +94,140 @@ func TestStringRepresentation(t *testing.T) {
 
 func TestCountAndEquals(t *testing.T) {
 	bf := New(10)
-	bf.SetBit(0)
-	bf.SetBit(2)
-	bf.SetBit(3)
-	bf.SetBit(8)
+	bf.Set(0)
+	bf.Set(2)
+	bf.Set(3)
+	bf.Set(8)
 
 	if got := bf.Count(); got != 4 {
 		t.Fatalf("Count() = %d; want %d", got, 4)
 	}
 
-	same := FromBytes(bf.ToBytes())
+	same := FromBytes(bf.ToBytes(), bf.Len())
 	if !bf.Equals(same) {
 		t.Fatalf("Equals should report identical contents")
 	}
 
-	diff := FromBytes(bf.ToBytes())
-	diff.SetBit(9)
+	diff := FromBytes(bf.ToBytes(), bf.Len())
+	diff.Set(9)
 	if bf.Equals(diff) {
 		t.Fatalf("Equals should detect difference")
 	}
 }
+
+func TestSetOperations(t *testing.T) {
+	a := FromBytes([]byte{0xF0, 0x0F}, 16) // 1111 0000 0000 1111
+	b := FromBytes([]byte{0x3C}, 8)        // 0011 1100, shorter than a
+
+	cases := []struct {
+		name string
+		got  Bitfield
+		want string
+	}{
+		{"And", a.And(b), "0011000000000000"},
+		{"Or", a.Or(b), "1111110000001111"},
+		{"AndNot", a.AndNot(b), "1100000000001111"},
+		{"Xor", a.Xor(b), "1100110000001111"},
+	}
+	for _, tc := range cases {
+		if got := tc.got.String(); got != tc.want {
+			t.Errorf("%s = %s; want %s", tc.name, got, tc.want)
+		}
+	}
+	if a.String() != "1111000000001111" {
+		t.Fatalf("set operations must not modify their operands")
+	}
+}
+
+func TestNextSetFirstUnsetAndIndices(t *testing.T) {
+	bf := FromBytes([]byte{0x41, 0x00, 0x80}, 24) // bits 1, 7 and 16
+
+	for _, tc := range []struct{ from, want int }{
+		{-5, 1}, {0, 1}, {2, 7}, {8, 16}, {16, 16}, {17, -1}, {99, -1},
+	} {
+		if got := bf.NextSet(tc.from); got != tc.want {
+			t.Errorf(
+				"NextSet(%d) = %d; want %d",
+				tc.from,
+				got,
+				tc.want,
+			)
+		}
+	}
+
+	var got []int
+	for i := range bf.Indices() {
+		got = append(got, i)
+	}
+	if len(got) != 3 || got[0] != 1 || got[1] != 7 || got[2] != 16 {
+		t.Errorf("Indices() = %v; want [1 7 16]", got)
+	}
+
+	if got := bf.FirstUnset(); got != 0 {
+		t.Errorf("FirstUnset() = %d; want 0", got)
+	}
+	bf.SetAll()
+	if got := bf.FirstUnset(); got != -1 {
+		t.Errorf("FirstUnset() after SetAll = %d; want -1", got)
+	}
+	bf.Clear(12)
+	if got := bf.FirstUnset(); got != 12 {
+		t.Errorf("FirstUnset() = %d; want 12", got)
+	}
+	bf.ClearAll()
+	if bf.Count() != 0 || bf.NextSet(0) != -1 {
+		t.Errorf("bits set after ClearAll: %s", bf)
+	}
+}
+
+func TestSpareBits(t *testing.T) {
+	bf := New(10)
+	bf.SetAll()
+	if got := bf.String(); got != "1111111111" {
+		t.Fatalf("SetAll() = %s; want ten bits set", got)
+	}
+	if got := bf.ToBytes(); got[1] != 0xC0 {
+		t.Fatalf("SetAll() set spare bits: %08b", got[1])
+	}
+	if got := bf.FirstUnset(); got != -1 {
+		t.Fatalf("FirstUnset() = %d; spare bits are not pieces", got)
+	}
+	bf.Set(12)
+	if bf.Has(12) || bf.Count() != 10 {
+		t.Fatalf("Set past the last bit changed the bitfield")
+	}
+	if got := bf.Xor(New(10)).Count(); got != 10 {
+		t.Fatalf("Xor set %d bits; want 10", got)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	cases := []struct {
+		name    string
+		payload []byte
+		pieces  int
+		spare   bool
+		ok      bool
+	}{
+		{"exact", []byte{0xFF, 0xC0}, 10, false, true},
+		{"whole bytes", []byte{0xFF}, 8, false, true},
+		{"spare bit set", []byte{0xFF, 0xE0}, 10, true, false},
+		{"last spare bit set", []byte{0x00, 0x01}, 10, true, false},
+		{"too short", []byte{0xFF}, 10, false, false},
+		{"too long", []byte{0xFF, 0x00, 0x00}, 10, false, false},
+		{"empty", nil, 0, false, true},
+	}
+	for _, tc := range cases {
+		err := FromBytes(tc.payload, tc.pieces).Validate(tc.pieces)
+		if (err == nil) != tc.ok {
+			t.Errorf("%s: Validate() = %v", tc.name, err)
+		}
+		if got := errors.Is(err, ErrSpareBits); got != tc.spare {
+			t.Errorf("%s: spare bits error %v", tc.name, err)
+		}
+	}
+
+	if err := New(16).Validate(10); err == nil {
+		t.Errorf("Validate() took 16 bits for 10 pieces")
+	}
+}
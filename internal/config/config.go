@@ -0,0 +1,382 @@
+// Package config loads the YAML configuration file, with environment
+// variables overriding its keys, and watches it for changes.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"time"
+
+	"github.com/prxssh/echo/internal/geoip"
+	"github.com/prxssh/echo/pkg/echo"
+	"gopkg.in/yaml.v3"
+)
+
+// File is the configuration file. Keys left out keep the defaults, or for
+// settings also changed from the app, the values last saved.
+//
+// Every key can be overridden by an environment variable named after its
+// section and key, as ECHO_LIMITS_DOWNLOAD for download under limits.
+type File struct {
+	Paths    Paths    `yaml:"paths"`
+	Network  Network  `yaml:"network"`
+	Limits   Limits   `yaml:"limits"`
+	Log      Log      `yaml:"log"`
+	GeoIP    GeoIP    `yaml:"geoip"`
+	Search   Search   `yaml:"search"`
+	API      API      `yaml:"api"`
+	Debug    Debug    `yaml:"debug"`
+	Identity Identity `yaml:"identity"`
+	Peer     Peer     `yaml:"peer"`
+}
+
+// Paths are where downloads and state are kept.
+type Paths struct {
+	Download string `yaml:"download"`
+	Torrents string `yaml:"torrents"`
+	Session  string `yaml:"session"`
+	Journal  string `yaml:"journal"`
+	History  string `yaml:"history"`
+	Settings string `yaml:"settings"`
+	GeoIP    string `yaml:"geoip"`
+}
+
+type Network struct {
+	DHTPort    *uint16 `yaml:"dht_port"`
+	ListenPort *uint16 `yaml:"listen_port"`
+	DisableDHT *bool   `yaml:"disable_dht"`
+	// DHTReadOnly is as GlobalSettings.DHTReadOnly has it.
+	DHTReadOnly *bool `yaml:"dht_read_only"`
+	// SeparateUDP keeps UDP trackers off the DHT's port.
+	SeparateUDP *bool `yaml:"separate_udp"`
+	// Proxy is as GlobalSettings.Proxy has it; Encryption is "prefer",
+	// "require" or "disable".
+	Proxy      *string `yaml:"proxy"`
+	Encryption *string `yaml:"encryption"`
+	// PortCheckURL is the service asked whether the port is open; see
+	// echo.Config's.
+	PortCheckURL string `yaml:"port_check_url"`
+	// ReconnectOnIPChange drops every peer when the address changes.
+	ReconnectOnIPChange *bool `yaml:"reconnect_on_ip_change"`
+}
+
+// Limits are rates in bytes per second and counts, zero being unlimited.
+type Limits struct {
+	Download        *int64 `yaml:"download"`
+	Upload          *int64 `yaml:"upload"`
+	Connections     *int   `yaml:"connections"`
+	ActiveDownloads *int   `yaml:"active_downloads"`
+	ActiveSeeds     *int   `yaml:"active_seeds"`
+}
+
+type Log struct {
+	// Format is "pretty" or "json"; File, if set, also gets info and
+	// above as JSON lines; Privacy hides IP addresses.
+	Format  string `yaml:"format"`
+	File    string `yaml:"file"`
+	Privacy *bool  `yaml:"privacy"`
+	// Levels sets modules' levels, such as "debug" for "peer".
+	Levels map[string]string `yaml:"levels"`
+}
+
+type GeoIP struct {
+	Refresh *time.Duration `yaml:"refresh"`
+	// LicenseKey, if set, downloads MaxMind's GeoLite2 country database
+	// rather than DB-IP's.
+	LicenseKey string `yaml:"license_key"`
+}
+
+type Search struct {
+	Timeout  *time.Duration `yaml:"timeout"`
+	Indexers []Indexer      `yaml:"indexers"`
+}
+
+type Indexer struct {
+	Name     string `yaml:"name"`
+	Kind     string `yaml:"kind"`
+	URL      string `yaml:"url"`
+	APIKey   string `yaml:"api_key"`
+	Disabled bool   `yaml:"disabled"`
+}
+
+// Debug configures the localhost listener serving pprof and the engine's
+// internals, which can be turned on and off while the client runs.
+type Debug struct {
+	Listen *string `yaml:"listen"`
+}
+
+// Identity is how torrents added from now on introduce themselves to
+// peers and trackers: as the client Preset names, such as "qbittorrent",
+// with the fields given taking the place of its.
+type Identity struct {
+	Preset       string `yaml:"preset"`
+	PeerIDPrefix string `yaml:"peer_id_prefix"`
+	Version      string `yaml:"version"`
+	UserAgent    string `yaml:"user_agent"`
+}
+
+// Peer tunes the connections to peers of torrents added from now on.
+// MaxPeers is the limit of those without one of their own.
+type Peer struct {
+	MaxPeers         *uint32        `yaml:"max_peers"`
+	DialWorkers      *int           `yaml:"dial_workers"`
+	HandshakeTimeout *time.Duration `yaml:"handshake_timeout"`
+	ReadTimeout      *time.Duration `yaml:"read_timeout"`
+	WriteTimeout     *time.Duration `yaml:"write_timeout"`
+	KeepAlive        *time.Duration `yaml:"keep_alive"`
+	IdleTimeout      *time.Duration `yaml:"idle_timeout"`
+	DrainTimeout     *time.Duration `yaml:"drain_timeout"`
+	MaxMessageLength *uint32        `yaml:"max_message_length"`
+	MaxRequests      *int           `yaml:"max_requests"`
+	BlockQueue       *int           `yaml:"block_queue"`
+	UploadSlots      *int           `yaml:"upload_slots"`
+	ChokeInterval    *time.Duration `yaml:"choke_interval"`
+}
+
+// API configures echod's.
+type API struct {
+	Listen string `yaml:"listen"`
+	Token  string `yaml:"token"`
+}
+
+// Load reads the file at path, which need not exist, and applies the
+// environment's overrides. Relative paths are taken as relative to the
+// file's directory, so a portable install's can move with it.
+func Load(path string) (*File, error) {
+	f := &File{}
+	if path != "" {
+		data, err := os.ReadFile(path)
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+		case err != nil:
+			return nil, err
+		default:
+			err := yaml.Unmarshal(data, f)
+			if err != nil {
+				return nil, fmt.Errorf(
+					"config: %s: %w",
+					path,
+					err,
+				)
+			}
+		}
+	}
+	if err := applyEnv(f, os.LookupEnv); err != nil {
+		return nil, err
+	}
+	if path != "" {
+		f.resolve(filepath.Dir(path))
+	}
+	return f, nil
+}
+
+func (f *File) resolve(dir string) {
+	for _, p := range []*string{
+		&f.Paths.Download,
+		&f.Paths.Torrents,
+		&f.Paths.Session,
+		&f.Paths.Journal,
+		&f.Paths.History,
+		&f.Paths.Settings,
+		&f.Paths.GeoIP,
+		&f.Log.File,
+	} {
+		if *p != "" && !filepath.IsAbs(*p) {
+			*p = filepath.Join(dir, *p)
+		}
+	}
+}
+
+// Apply sets what the file has in cfg, before the client is created.
+func (f *File) Apply(cfg *echo.Config) error {
+	setString(&cfg.DownloadDir, f.Paths.Download)
+	setString(&cfg.TorrentsDir, f.Paths.Torrents)
+	setString(&cfg.SessionPath, f.Paths.Session)
+	setString(&cfg.JournalDir, f.Paths.Journal)
+	setString(&cfg.HistoryPath, f.Paths.History)
+	setString(&cfg.SettingsPath, f.Paths.Settings)
+	setString(&cfg.GeoIP.Dir, f.Paths.GeoIP)
+	setString(&cfg.PortCheckURL, f.Network.PortCheckURL)
+	if f.Network.DisableDHT != nil {
+		cfg.DisableDHT = *f.Network.DisableDHT
+	}
+	if f.Network.SeparateUDP != nil {
+		cfg.SeparateUDP = *f.Network.SeparateUDP
+	}
+	if f.GeoIP.Refresh != nil {
+		cfg.GeoIP.Refresh = *f.GeoIP.Refresh
+	}
+	if f.GeoIP.LicenseKey != "" {
+		cfg.GeoIP.Sources = []geoip.Source{
+			geoip.GeoLiteCountry(f.GeoIP.LicenseKey),
+			geoip.DBIPASN,
+		}
+	}
+	if f.Search.Timeout != nil {
+		cfg.SearchTimeout = *f.Search.Timeout
+	}
+	f.Peer.apply(&cfg.Peer)
+
+	patch, err := f.Patch()
+	if err != nil {
+		return err
+	}
+	if patch.DHTPort != nil {
+		cfg.DHT.Port = *patch.DHTPort
+	}
+	if patch.ListenPort != nil {
+		cfg.ListenPort = *patch.ListenPort
+	}
+	if patch.DHTReadOnly != nil {
+		cfg.DHT.ReadOnly = *patch.DHTReadOnly
+	}
+	if patch.LogLevels != nil {
+		cfg.LogLevels = patch.LogLevels
+	}
+	return nil
+}
+
+// Patch returns the settings the file sets, which are those that can be
+// changed while the client runs.
+func (f *File) Patch() (echo.SettingsPatch, error) {
+	p := echo.SettingsPatch{
+		DHTPort:             f.Network.DHTPort,
+		ListenPort:          f.Network.ListenPort,
+		Proxy:               f.Network.Proxy,
+		DownloadLimit:       f.Limits.Download,
+		UploadLimit:         f.Limits.Upload,
+		MaxConnections:      f.Limits.Connections,
+		MaxActiveDownloads:  f.Limits.ActiveDownloads,
+		MaxActiveSeeds:      f.Limits.ActiveSeeds,
+		DebugAddr:           f.Debug.Listen,
+		ReconnectOnIPChange: f.Network.ReconnectOnIPChange,
+		DHTReadOnly:         f.Network.DHTReadOnly,
+	}
+	if f.Paths.Download != "" {
+		p.DownloadDir = &f.Paths.Download
+	}
+	if f.Paths.Torrents != "" {
+		p.TorrentsDir = &f.Paths.Torrents
+	}
+	if f.Network.Encryption != nil {
+		e := echo.Encryption(*f.Network.Encryption)
+		p.Encryption = &e
+	}
+	if f.Identity != (Identity{}) {
+		fp, err := f.Identity.fingerprint()
+		if err != nil {
+			return p, err
+		}
+		p.Fingerprint = &fp
+	}
+	if f.Log.Levels != nil {
+		p.LogLevels = make(map[string]slog.Level, len(f.Log.Levels))
+		for module, name := range f.Log.Levels {
+			var level slog.Level
+			err := level.UnmarshalText([]byte(name))
+			if err != nil {
+				return p, fmt.Errorf("config: level %q", name)
+			}
+			p.LogLevels[module] = level
+		}
+	}
+	return p, nil
+}
+
+func (p Peer) apply(cfg *echo.PeerConfig) {
+	set(&cfg.MaxPeers, p.MaxPeers)
+	set(&cfg.DialWorkers, p.DialWorkers)
+	set(&cfg.HandshakeTimeout, p.HandshakeTimeout)
+	set(&cfg.ReadTimeout, p.ReadTimeout)
+	set(&cfg.WriteTimeout, p.WriteTimeout)
+	set(&cfg.KeepAlive, p.KeepAlive)
+	set(&cfg.IdleTimeout, p.IdleTimeout)
+	set(&cfg.DrainTimeout, p.DrainTimeout)
+	set(&cfg.MaxMessageLength, p.MaxMessageLength)
+	set(&cfg.MaxRequests, p.MaxRequests)
+	set(&cfg.BlockQueue, p.BlockQueue)
+	set(&cfg.UploadSlots, p.UploadSlots)
+	set(&cfg.ChokeInterval, p.ChokeInterval)
+}
+
+func (id Identity) fingerprint() (echo.Fingerprint, error) {
+	var fp echo.Fingerprint
+	if id.Preset != "" {
+		var ok bool
+		fp, ok = echo.LookupFingerprint(id.Preset)
+		if !ok {
+			return fp, fmt.Errorf("config: no client %q", id.Preset)
+		}
+	}
+	setString(&fp.PeerIDPrefix, id.PeerIDPrefix)
+	setString(&fp.Version, id.Version)
+	setString(&fp.UserAgent, id.UserAgent)
+	return fp, nil
+}
+
+// Live puts what the file sets into effect in a running client, taking
+// the place of the settings saved from the app.
+func (f *File) Live(c *echo.Client) error {
+	patch, err := f.Patch()
+	if err != nil {
+		return err
+	}
+	if _, err := c.UpdateSettings(patch); err != nil {
+		return err
+	}
+	if f.Search.Indexers == nil {
+		return nil
+	}
+	indexers := make([]echo.Indexer, len(f.Search.Indexers))
+	for i, ix := range f.Search.Indexers {
+		indexers[i] = echo.Indexer{
+			Name:     ix.Name,
+			Kind:     echo.IndexerKind(ix.Kind),
+			URL:      ix.URL,
+			APIKey:   ix.APIKey,
+			Disabled: ix.Disabled,
+		}
+	}
+	return c.SetIndexers(indexers)
+}
+
+// NeedsRestart reports whether f changes, from prev, keys that only take
+// effect when the client is started.
+func (f *File) NeedsRestart(prev *File) bool {
+	static := func(f *File) []any {
+		return []any{
+			f.Paths.Session,
+			f.Paths.Journal,
+			f.Paths.History,
+			f.Paths.Settings,
+			f.Paths.GeoIP,
+			f.Network.DisableDHT,
+			f.Network.SeparateUDP,
+			f.Network.PortCheckURL,
+			f.Log.Format,
+			f.Log.File,
+			f.Log.Privacy,
+			f.GeoIP,
+			f.Search.Timeout,
+			f.API,
+			f.Peer,
+		}
+	}
+	return !reflect.DeepEqual(static(f), static(prev))
+}
+
+func setString(dst *string, v string) {
+	if v != "" {
+		*dst = v
+	}
+}
+
+func set[T any](dst *T, v *T) {
+	if v != nil {
+		*dst = *v
+	}
+}
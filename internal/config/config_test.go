@@ -0,0 +1,333 @@
+package config
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/prxssh/echo/internal/torrent"
+	"github.com/prxssh/echo/pkg/echo"
+)
+
+const testYAML = `
+paths:
+  download: /data/downloads
+network:
+  dht_port: 6881
+  listen_port: 51413
+  dht_read_only: true
+  encryption: require
+  reconnect_on_ip_change: true
+limits:
+  download: 1048576
+  active_seeds: 3
+log:
+  levels:
+    peer: debug
+geoip:
+  refresh: 48h
+search:
+  indexers:
+    - name: local
+      kind: torznab
+      url: http://localhost:9117
+peer:
+  idle_timeout: 7m
+  max_requests: 64
+`
+
+func writeConfig(t *testing.T, data string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	f, err := Load(writeConfig(t, testYAML))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Paths.Download != "/data/downloads" {
+		t.Errorf("download = %q", f.Paths.Download)
+	}
+	if f.Limits.Download == nil || *f.Limits.Download != 1<<20 {
+		t.Errorf("download limit = %v", f.Limits.Download)
+	}
+	if f.Limits.Upload != nil {
+		t.Errorf("upload limit = %d, want unset", *f.Limits.Upload)
+	}
+	if f.GeoIP.Refresh == nil || *f.GeoIP.Refresh != 48*time.Hour {
+		t.Errorf("refresh = %v", f.GeoIP.Refresh)
+	}
+	ix := f.Search.Indexers
+	if len(ix) != 1 || ix[0].Kind != "torznab" {
+		t.Errorf("indexers = %+v", ix)
+	}
+
+	_, err = Load(filepath.Join(t.TempDir(), "missing.yaml"))
+	if err != nil {
+		t.Errorf("missing file: %v", err)
+	}
+	if _, err := Load(writeConfig(t, "limits: [")); err == nil {
+		t.Error("malformed file loaded")
+	}
+}
+
+func TestEnv(t *testing.T) {
+	env := map[string]string{
+		"ECHO_LIMITS_DOWNLOAD":         "2048",
+		"ECHO_LOG_PRIVACY":             "yes",
+		"ECHO_LOG_FORMAT":              "json",
+		"ECHO_GEOIP_REFRESH":           "1h",
+		"ECHO_NETWORK_PROXY":           "socks5://127.0.0.1:1080",
+		"ECHO_PEER_MAX_MESSAGE_LENGTH": "65536",
+	}
+	lookup := func(k string) (string, bool) {
+		v, ok := env[k]
+		return v, ok
+	}
+
+	f := &File{}
+	if err := applyEnv(f, lookup); err != nil {
+		t.Fatal(err)
+	}
+	if f.Limits.Download == nil || *f.Limits.Download != 2048 {
+		t.Errorf("download limit = %v", f.Limits.Download)
+	}
+	if f.Log.Privacy == nil || !*f.Log.Privacy {
+		t.Errorf("privacy = %v", f.Log.Privacy)
+	}
+	if f.Log.Format != "json" {
+		t.Errorf("format = %q", f.Log.Format)
+	}
+	if f.GeoIP.Refresh == nil || *f.GeoIP.Refresh != time.Hour {
+		t.Errorf("refresh = %v", f.GeoIP.Refresh)
+	}
+	length := f.Peer.MaxMessageLength
+	if length == nil || *length != 65536 {
+		t.Errorf("max message length = %v", length)
+	}
+	proxy := env["ECHO_NETWORK_PROXY"]
+	if f.Network.Proxy == nil || *f.Network.Proxy != proxy {
+		t.Errorf("proxy = %v", f.Network.Proxy)
+	}
+
+	env = map[string]string{"ECHO_LIMITS_UPLOAD": "fast"}
+	if err := applyEnv(&File{}, lookup); err == nil {
+		t.Error("bad number accepted")
+	}
+}
+
+func TestApply(t *testing.T) {
+	f, err := Load(writeConfig(t, testYAML))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := echo.DefaultConfig()
+	torrents := cfg.TorrentsDir
+	if err := f.Apply(&cfg); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.DownloadDir != "/data/downloads" {
+		t.Errorf("download dir = %q", cfg.DownloadDir)
+	}
+	if cfg.TorrentsDir != torrents {
+		t.Errorf("torrents dir = %q, want default", cfg.TorrentsDir)
+	}
+	if cfg.DHT.Port != 6881 {
+		t.Errorf("dht port = %d", cfg.DHT.Port)
+	}
+	if cfg.ListenPort != 51413 {
+		t.Errorf("listen port = %d", cfg.ListenPort)
+	}
+	if !cfg.DHT.ReadOnly {
+		t.Error("dht not read-only")
+	}
+	if cfg.GeoIP.Refresh != 48*time.Hour {
+		t.Errorf("refresh = %v", cfg.GeoIP.Refresh)
+	}
+	if cfg.LogLevels["peer"] != slog.LevelDebug {
+		t.Errorf("levels = %v", cfg.LogLevels)
+	}
+	if cfg.Peer.IdleTimeout != 7*time.Minute {
+		t.Errorf("idle timeout = %v", cfg.Peer.IdleTimeout)
+	}
+	if cfg.Peer.MaxRequests != 64 {
+		t.Errorf("max requests = %d", cfg.Peer.MaxRequests)
+	}
+	if d := echo.DefaultConfig().Peer; cfg.Peer.BlockQueue != d.BlockQueue {
+		t.Errorf("block queue = %d, want default", cfg.Peer.BlockQueue)
+	}
+
+	p, err := f.Patch()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Encryption == nil || *p.Encryption != "require" {
+		t.Errorf("encryption = %v", p.Encryption)
+	}
+	if p.MaxActiveSeeds == nil || *p.MaxActiveSeeds != 3 {
+		t.Errorf("active seeds = %v", p.MaxActiveSeeds)
+	}
+	if p.UploadLimit != nil {
+		t.Errorf("upload limit = %d, want unset", *p.UploadLimit)
+	}
+	if p.ReconnectOnIPChange == nil || !*p.ReconnectOnIPChange {
+		t.Errorf("reconnect = %v", p.ReconnectOnIPChange)
+	}
+	if p.DHTReadOnly == nil || !*p.DHTReadOnly {
+		t.Errorf("dht read-only = %v", p.DHTReadOnly)
+	}
+
+	bad := &File{Log: Log{Levels: map[string]string{"peer": "loud"}}}
+	if _, err := bad.Patch(); err == nil {
+		t.Error("bad level accepted")
+	}
+}
+
+// TestPeerConfig checks that the peer section reaches the peer manager of
+// a torrent added to a client started with it.
+func TestPeerConfig(t *testing.T) {
+	f, err := Load(writeConfig(t, testYAML))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	cfg := echo.DefaultConfig()
+	if err := f.Apply(&cfg); err != nil {
+		t.Fatal(err)
+	}
+	cfg.DisableDHT = true
+	cfg.ListenPort = 0
+	cfg.DownloadDir = filepath.Join(dir, "downloads")
+	cfg.TorrentsDir = filepath.Join(dir, "torrents")
+	cfg.SessionPath = filepath.Join(dir, "session.json")
+	cfg.HistoryPath = ""
+	cfg.JournalDir = ""
+	cfg.SettingsPath = filepath.Join(dir, "settings.json")
+	cfg.IndexPath = ""
+	cfg.CredentialsPath = ""
+	cfg.GeoIP = echo.GeoIPConfig{}
+
+	client := echo.New(&cfg)
+	if err := client.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close(context.Background())
+
+	path := filepath.Join(dir, "data")
+	if err := os.WriteFile(path, []byte("data"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	data, err := torrent.Create(torrent.CreateOpts{Path: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tor, err := client.AddTorrent(data, echo.AddOptions{Paused: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := tor.PeerManager.Config()
+	if got.IdleTimeout != 7*time.Minute || got.MaxRequests != 64 {
+		t.Errorf(
+			"manager runs with idle timeout %v, max requests %d",
+			got.IdleTimeout,
+			got.MaxRequests,
+		)
+	}
+}
+
+func TestNeedsRestart(t *testing.T) {
+	prev, err := Load(writeConfig(t, testYAML))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	live, _ := Load(writeConfig(t, testYAML))
+	live.Limits.Download = nil
+	live.Paths.Download = "/srv"
+	if live.NeedsRestart(prev) {
+		t.Error("live change needs restart")
+	}
+
+	static, _ := Load(writeConfig(t, testYAML))
+	static.API.Listen = ":9091"
+	if !static.NeedsRestart(prev) {
+		t.Error("api change takes effect live")
+	}
+	static, _ = Load(writeConfig(t, testYAML))
+	static.Peer.IdleTimeout = nil
+	if !static.NeedsRestart(prev) {
+		t.Error("peer change takes effect live")
+	}
+}
+
+func TestProfile(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("ECHO_CONFIG", "")
+	def, err := OpenProfile("", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	work, err := OpenProfile("work", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if work.Dir != filepath.Join(def.Dir, "profiles", "work") {
+		t.Errorf("dir = %q", work.Dir)
+	}
+
+	var cfg echo.Config
+	work.Apply(&cfg)
+	if filepath.Dir(cfg.SessionPath) != work.Dir {
+		t.Errorf("session = %q", cfg.SessionPath)
+	}
+	if work.ConfigPath() == def.ConfigPath() {
+		t.Error("profiles share a config file")
+	}
+
+	for _, name := range []string{"..", "a/b", ` a`} {
+		if _, err := OpenProfile(name, false); err == nil {
+			t.Errorf("profile %q opened", name)
+		}
+	}
+}
+
+func TestLoadRelative(t *testing.T) {
+	path := writeConfig(t, "paths:\n  session: state/session.json\n")
+	f, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := filepath.Join(filepath.Dir(path), "state", "session.json")
+	if f.Paths.Session != want {
+		t.Errorf("session = %q, want %q", f.Paths.Session, want)
+	}
+}
+
+func TestIdentity(t *testing.T) {
+	f := &File{Identity: Identity{
+		Preset:    "transmission",
+		UserAgent: "Transmission/4.0.5",
+	}}
+	p, err := f.Patch()
+	if err != nil {
+		t.Fatal(err)
+	}
+	fp := p.Fingerprint
+	if fp == nil || fp.PeerIDPrefix != "-TR4060-" ||
+		fp.UserAgent != "Transmission/4.0.5" {
+		t.Errorf("fingerprint = %+v", fp)
+	}
+
+	f.Identity.Preset = "azureus"
+	if _, err := f.Patch(); err == nil {
+		t.Error("unknown client accepted")
+	}
+}
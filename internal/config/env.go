@@ -0,0 +1,83 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var durationType = reflect.TypeFor[time.Duration]()
+
+// applyEnv overrides f's keys with the environment variables named after
+// them, ECHO_<SECTION>_<KEY>. Maps and lists can only be set in the file.
+func applyEnv(f *File, lookup func(string) (string, bool)) error {
+	sections := reflect.ValueOf(f).Elem()
+	for i := range sections.NumField() {
+		section := sections.Field(i)
+		prefix := "ECHO_" + yamlName(sections.Type().Field(i)) + "_"
+		for j := range section.NumField() {
+			name := prefix + yamlName(section.Type().Field(j))
+			v, ok := lookup(name)
+			if !ok {
+				continue
+			}
+			if err := setField(section.Field(j), v); err != nil {
+				return fmt.Errorf("config: $%s: %w", name, err)
+			}
+		}
+	}
+	return nil
+}
+
+func yamlName(f reflect.StructField) string {
+	name, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+	return strings.ToUpper(name)
+}
+
+func setField(field reflect.Value, s string) error {
+	if field.Kind() == reflect.Pointer {
+		v := reflect.New(field.Type().Elem())
+		if err := setValue(v.Elem(), s); err != nil {
+			return err
+		}
+		field.Set(v)
+		return nil
+	}
+	return setValue(field, s)
+}
+
+func setValue(v reflect.Value, s string) error {
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		// Set to anything but a false value, as with ECHO_LOG_PRIVACY=1
+		// or =yes, turns a switch on.
+		b, err := strconv.ParseBool(s)
+		v.SetBool(b || (err != nil && s != ""))
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint16, reflect.Uint32:
+		n, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/prxssh/echo/pkg/echo"
+)
+
+// portableDir is the directory next to the executable that keeps a
+// portable install's state. Its being there turns portable mode on.
+const portableDir = "echo-data"
+
+// Profile is the directory an instance keeps its config file, session,
+// resume data and logs in. Instances run with different profiles share
+// nothing, so private and public trackers' torrents can be kept apart.
+type Profile struct {
+	// Name is empty for the default profile.
+	Name string
+	Dir  string
+	// Portable keeps the directory next to the executable rather than
+	// in the user's config directory.
+	Portable bool
+}
+
+// ProfileFlags defines -profile and -portable on fs, and returns what
+// opens the profile they name once fs has been parsed.
+func ProfileFlags(fs *flag.FlagSet) func() (Profile, error) {
+	name := fs.String(
+		"profile",
+		os.Getenv("ECHO_PROFILE"),
+		"profile to run, each keeping its own config and state",
+	)
+	portable := fs.Bool(
+		"portable",
+		false,
+		"keep config and state next to the executable, in "+portableDir,
+	)
+	return func() (Profile, error) {
+		return OpenProfile(*name, *portable)
+	}
+}
+
+// OpenProfile returns the profile named, the default one if name is
+// empty. Portable mode is also on if the executable has a portableDir
+// next to it.
+func OpenProfile(name string, portable bool) (Profile, error) {
+	if name != "" && !validName(name) {
+		return Profile{}, fmt.Errorf("config: bad profile %q", name)
+	}
+
+	var root string
+	exe, err := os.Executable()
+	if err == nil {
+		dir := filepath.Join(filepath.Dir(exe), portableDir)
+		if info, err := os.Stat(dir); err == nil && info.IsDir() {
+			portable = true
+		}
+		if portable {
+			root = dir
+		}
+	}
+	if root == "" {
+		if portable {
+			return Profile{}, fmt.Errorf("config: %w", err)
+		}
+		dir, err := os.UserConfigDir()
+		if err != nil {
+			return Profile{}, fmt.Errorf("config: %w", err)
+		}
+		root = filepath.Join(dir, "echo")
+	}
+
+	p := Profile{Name: name, Dir: root, Portable: portable}
+	if name != "" {
+		p.Dir = filepath.Join(root, "profiles", name)
+	}
+	return p, nil
+}
+
+// validName reports whether name can be a directory's, and only one's.
+func validName(name string) bool {
+	if name == "." || name == ".." {
+		return false
+	}
+	return !strings.ContainsAny(name, `/\:`) && name == strings.TrimSpace(name)
+}
+
+// ConfigPath is $ECHO_CONFIG, or config.yaml in the profile's directory.
+func (p Profile) ConfigPath() string {
+	if path := os.Getenv("ECHO_CONFIG"); path != "" {
+		return path
+	}
+	return filepath.Join(p.Dir, "config.yaml")
+}
+
+// TokenPath is where echod keeps its API token.
+func (p Profile) TokenPath() string {
+	return filepath.Join(p.Dir, "api-token")
+}
+
+// Apply keeps cfg's state in the profile's directory.
+func (p Profile) Apply(cfg *echo.Config) {
+	cfg.SetStateDir(p.Dir)
+}
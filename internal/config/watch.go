@@ -0,0 +1,77 @@
+package config
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/prxssh/echo/pkg/echo"
+)
+
+// WatchInterval is how often Watch checks the file for changes.
+const WatchInterval = 5 * time.Second
+
+// Watch checks the file at path every WatchInterval until ctx is done,
+// and calls fn with it, loaded again, each time it changes. A file that
+// no longer loads is logged and skipped until it is fixed.
+func Watch(ctx context.Context, path string, fn func(*File)) {
+	if path == "" {
+		return
+	}
+	last := modified(path)
+	ticker := time.NewTicker(WatchInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		mod := modified(path)
+		if mod.Equal(last) {
+			continue
+		}
+		last = mod
+		f, err := Load(path)
+		if err != nil {
+			slog.Warn(
+				"reloading config failed",
+				slog.String("path", path),
+				slog.String("error", err.Error()),
+			)
+			continue
+		}
+		slog.Info("config reloaded", slog.String("path", path))
+		fn(f)
+	}
+}
+
+// Reload is Watch's fn for a running client: it puts the file's settings
+// into effect, and warns of changes needing a restart.
+func Reload(c *echo.Client, prev *File) func(*File) {
+	return func(f *File) {
+		if err := f.Live(c); err != nil {
+			slog.Warn(
+				"applying config failed",
+				slog.String("error", err.Error()),
+			)
+		}
+		if f.NeedsRestart(prev) {
+			slog.Warn(
+				"some config changes need a restart",
+			)
+		}
+		prev = f
+	}
+}
+
+func modified(path string) time.Time {
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
@@ -0,0 +1,47 @@
+// Package desktop hands files to the operating system, opening them with
+// their default application or showing them in the file manager, and
+// shows system notifications.
+package desktop
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+// Open launches the file or directory at path with the application the
+// system associates with it.
+func Open(path string) error {
+	if _, err := os.Stat(path); err != nil {
+		return fmt.Errorf("desktop: %w", err)
+	}
+	return start(openCommand(path))
+}
+
+// Reveal shows the file or directory at path in the file manager, selected
+// where the platform allows.
+func Reveal(path string) error {
+	if _, err := os.Stat(path); err != nil {
+		return fmt.Errorf("desktop: %w", err)
+	}
+	return start(revealCommand(path))
+}
+
+// Notify shows a system notification with the given title and body.
+func Notify(title, body string) error {
+	return start(notifyCommand(title, body))
+}
+
+// start runs cmd without waiting for it, since file managers and
+// applications may stay open long after they are launched.
+func start(cmd *exec.Cmd) error {
+	if cmd == nil {
+		return errors.New("desktop: not supported on this platform")
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("desktop: %w", err)
+	}
+	go func() { _ = cmd.Wait() }()
+	return nil
+}
@@ -0,0 +1,20 @@
+package desktop
+
+import "os/exec"
+
+func openCommand(path string) *exec.Cmd {
+	return exec.Command("open", path)
+}
+
+func revealCommand(path string) *exec.Cmd {
+	return exec.Command("open", "-R", path)
+}
+
+// notifyCommand passes the text as arguments to the script, so nothing in
+// it needs quoting for AppleScript.
+func notifyCommand(title, body string) *exec.Cmd {
+	const script = `on run argv
+	display notification (item 2 of argv) with title (item 1 of argv)
+end run`
+	return exec.Command("osascript", "-e", script, title, body)
+}
@@ -0,0 +1,20 @@
+package desktop
+
+import (
+	"os/exec"
+	"path/filepath"
+)
+
+func openCommand(path string) *exec.Cmd {
+	return exec.Command("xdg-open", path)
+}
+
+// revealCommand opens the containing directory: there is no portable way
+// to have a Linux file manager select a file.
+func revealCommand(path string) *exec.Cmd {
+	return exec.Command("xdg-open", filepath.Dir(path))
+}
+
+func notifyCommand(title, body string) *exec.Cmd {
+	return exec.Command("notify-send", "--app-name=Echo", "--", title, body)
+}
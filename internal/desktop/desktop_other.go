@@ -0,0 +1,17 @@
+//go:build !linux && !darwin && !windows
+
+package desktop
+
+import "os/exec"
+
+func openCommand(string) *exec.Cmd {
+	return nil
+}
+
+func revealCommand(string) *exec.Cmd {
+	return nil
+}
+
+func notifyCommand(string, string) *exec.Cmd {
+	return nil
+}
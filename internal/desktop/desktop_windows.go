@@ -0,0 +1,30 @@
+package desktop
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// Explorer opens a path it is given with the default application, and
+// quits with a non-zero status even when it succeeds; start does not wait
+// for it.
+func openCommand(path string) *exec.Cmd {
+	return exec.Command("explorer", path)
+}
+
+// revealCommand writes the command line itself: Explorer only understands
+// /select when the quotes surround the path alone, not the whole argument
+// as exec would quote it.
+func revealCommand(path string) *exec.Cmd {
+	cmd := exec.Command("explorer")
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		CmdLine: `explorer /select,"` + path + `"`,
+	}
+	return cmd
+}
+
+// notifyCommand returns nil: Windows has no command that shows a toast
+// without registering the app first.
+func notifyCommand(string, string) *exec.Cmd {
+	return nil
+}
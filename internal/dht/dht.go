@@ -0,0 +1,573 @@
+package dht
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/netip"
+	"sync"
+	"time"
+)
+
+// PacketConn is the socket the DHT talks over, *net.UDPConn or its share
+// of one multiplexed with other protocols.
+type PacketConn interface {
+	ReadFromUDPAddrPort(b []byte) (int, netip.AddrPort, error)
+	WriteToUDPAddrPort(b []byte, addr netip.AddrPort) (int, error)
+	LocalAddr() net.Addr
+	Close() error
+}
+
+type Config struct {
+	Port              uint16
+	BootstrapNodes    []string
+	StatePath         string
+	BucketSize        int
+	Alpha             int
+	QueryTimeout      time.Duration
+	QuestionableAfter time.Duration
+	MaxNodeFailures   int
+	RefreshInterval   time.Duration
+	SaveInterval      time.Duration
+	StatsInterval     time.Duration
+	// ReadOnly makes us a query-only node (BEP 43): we flag our queries
+	// so others keep us out of their routing tables and we never answer
+	// incoming queries. Useful behind NATs that drop unsolicited packets.
+	ReadOnly bool
+	// EnforceNodeIDs keeps nodes whose ID does not match their IP (BEP 42)
+	// out of the routing table. When false they are only deprioritized.
+	EnforceNodeIDs bool
+	// Conn, when set, is talked over in place of a socket of our own on
+	// Port.
+	Conn PacketConn
+}
+
+func DefaultConfig() Config {
+	return Config{
+		Port: 6881,
+		BootstrapNodes: []string{
+			"router.bittorrent.com:6881",
+			"router.utorrent.com:6881",
+			"dht.transmissionbt.com:6881",
+			"dht.libtorrent.org:25401",
+		},
+		BucketSize:        8,
+		Alpha:             3,
+		QueryTimeout:      5 * time.Second,
+		QuestionableAfter: 15 * time.Minute,
+		MaxNodeFailures:   2,
+		RefreshInterval:   15 * time.Minute,
+		SaveInterval:      10 * time.Minute,
+		StatsInterval:     5 * time.Second,
+	}
+}
+
+const maxPacketSize = 4096
+
+var errQueryTimeout = errors.New("dht: query timed out")
+
+type pendingQuery struct {
+	addr netip.AddrPort
+	ch   chan *message
+}
+
+type DHT struct {
+	cfg   Config
+	idMu  sync.RWMutex
+	id    ID
+	table *table
+	conn  PacketConn
+	voter *ipVoter
+
+	tokens    *tokenManager
+	peerStore *peerStore
+	items     *itemStore
+	counters  counters
+	reach     *reachability
+
+	txMu    sync.Mutex
+	txSeq   uint16
+	pending map[string]*pendingQuery
+
+	done     chan struct{}
+	stopOnce sync.Once
+	workers  sync.WaitGroup
+}
+
+func New(cfg *Config) (*DHT, error) {
+	d := &DHT{
+		cfg:       DefaultConfig(),
+		pending:   make(map[string]*pendingQuery),
+		tokens:    newTokenManager(),
+		peerStore: newPeerStore(),
+		items:     newItemStore(),
+		voter:     newIPVoter(),
+		reach:     newReachability(),
+		done:      make(chan struct{}),
+	}
+	if cfg != nil {
+		d.cfg = *cfg
+	}
+
+	st, err := loadState(d.cfg.StatePath)
+	if err != nil {
+		slog.Warn(
+			"dht state load failed",
+			slog.String("path", d.cfg.StatePath),
+			slog.String("error", err.Error()),
+		)
+	}
+	if st != nil {
+		d.id = st.id
+	} else if d.id, err = RandomID(); err != nil {
+		return nil, err
+	}
+	d.table = newTable(d.id, d.cfg)
+
+	if st != nil {
+		for _, n := range st.nodes {
+			d.table.insert(n.ID, n.Addr)
+		}
+	}
+
+	return d, nil
+}
+
+func (d *DHT) ID() ID {
+	d.idMu.RLock()
+	defer d.idMu.RUnlock()
+
+	return d.id
+}
+
+func (d *DHT) idString() string {
+	id := d.ID()
+	return string(id[:])
+}
+
+// ExternalIP returns our public address as agreed on by the nodes we talk to,
+// or the zero Addr if there is no consensus yet.
+func (d *DHT) ExternalIP() netip.Addr {
+	return d.voter.get()
+}
+
+// observeExternalIP records the address a node says it sees us at. Once the
+// DHT agrees on a new external IP that our ID is not valid for, we switch to
+// a BEP 42 compliant ID so that enforcing nodes keep us in their tables.
+func (d *DHT) observeExternalIP(from netip.AddrPort, compact string) {
+	addr, ok := decodeCompactAddr([]byte(compact))
+	if !ok {
+		return
+	}
+	ip, changed := d.voter.vote(from.Addr(), addr.Addr())
+	if !changed || ValidID(d.ID(), ip) {
+		return
+	}
+
+	id, err := SecureID(ip)
+	if err != nil {
+		return
+	}
+	d.idMu.Lock()
+	d.id = id
+	d.idMu.Unlock()
+	d.table.rebase(id)
+
+	slog.Info(
+		"dht node id changed",
+		slog.String("external_ip", ip.String()),
+		slog.String("id", id.String()),
+	)
+}
+
+// Renew takes a new random node ID after our address changed, as it does
+// when a VPN reconnects or a DHCP lease runs out. The external IP agreed
+// on is forgotten and the new ID looked up, so nodes vote on the new one;
+// once they agree, observeExternalIP swaps in a BEP 42 compliant ID.
+func (d *DHT) Renew(ctx context.Context) error {
+	id, err := RandomID()
+	if err != nil {
+		return err
+	}
+	d.voter.reset()
+	d.idMu.Lock()
+	d.id = id
+	d.idMu.Unlock()
+	d.table.rebase(id)
+
+	slog.Info("dht node id renewed", slog.String("id", id.String()))
+	d.lookup(ctx, id, methodFindNode, nil)
+	return nil
+}
+
+// Port returns the UDP port the DHT is bound to, which is what we advertise
+// to peers in PORT messages.
+func (d *DHT) Port() uint16 {
+	if d.conn == nil {
+		return d.cfg.Port
+	}
+	return uint16(d.conn.LocalAddr().(*net.UDPAddr).Port)
+}
+
+func (d *DHT) Start(ctx context.Context) error {
+	d.conn = d.cfg.Conn
+	if d.conn == nil {
+		conn, err := net.ListenUDP(
+			"udp",
+			&net.UDPAddr{Port: int(d.cfg.Port)},
+		)
+		if err != nil {
+			return fmt.Errorf("dht: listen: %w", err)
+		}
+		d.conn = conn
+	}
+
+	d.workers.Go(func() { d.readLoop() })
+	d.workers.Go(func() { d.maintain(ctx) })
+
+	slog.Info(
+		"dht started",
+		slog.String("id", d.ID().String()),
+		slog.Int("port", int(d.Port())),
+		slog.Int("nodes", d.table.len()),
+	)
+
+	return nil
+}
+
+func (d *DHT) Stop() {
+	d.stopOnce.Do(func() {
+		close(d.done)
+		if d.conn != nil {
+			_ = d.conn.Close()
+		}
+		d.workers.Wait()
+
+		if err := d.saveState(); err != nil {
+			slog.Warn(
+				"dht state save failed",
+				slog.String("error", err.Error()),
+			)
+		}
+	})
+}
+
+// AddNode pings addr and adds it to the routing table if it answers. It is
+// used for nodes learned out of band: PORT messages and .torrent files.
+func (d *DHT) AddNode(ctx context.Context, addr netip.AddrPort) {
+	go func() {
+		_, _ = d.query(ctx, addr, methodPing, map[string]any{
+			"id": d.idString(),
+		})
+	}()
+}
+
+// Bootstrap seeds the routing table from the given host:port pairs and then
+// looks up our own ID to fill the buckets near us.
+func (d *DHT) Bootstrap(ctx context.Context, hosts []string) {
+	var wg sync.WaitGroup
+	for _, host := range hosts {
+		wg.Go(func() {
+			udpAddr, err := net.ResolveUDPAddr("udp", host)
+			if err != nil {
+				slog.Debug(
+					"dht bootstrap resolve failed",
+					slog.String("host", host),
+					slog.String("error", err.Error()),
+				)
+				return
+			}
+
+			_, _ = d.findNode(ctx, udpAddr.AddrPort(), d.ID())
+		})
+	}
+	wg.Wait()
+
+	d.lookup(ctx, d.ID(), methodFindNode, nil)
+	slog.Info("dht bootstrapped", slog.Int("nodes", d.table.len()))
+}
+
+func (d *DHT) maintain(ctx context.Context) {
+	seeds := d.cfg.BootstrapNodes
+	if d.table.len() > 0 {
+		// A persisted table lets us rejoin without the public routers.
+		d.lookup(ctx, d.ID(), methodFindNode, nil)
+	}
+	if d.table.len() < d.cfg.BucketSize {
+		d.Bootstrap(ctx, seeds)
+	}
+
+	refresh := time.NewTicker(d.cfg.RefreshInterval / 3)
+	defer refresh.Stop()
+	save := time.NewTicker(d.cfg.SaveInterval)
+	defer save.Stop()
+	stats := time.NewTicker(d.cfg.StatsInterval)
+	defer stats.Stop()
+
+	for {
+		select {
+		case <-d.done:
+			return
+		case <-ctx.Done():
+			return
+		case <-stats.C:
+			d.emitStats(ctx)
+		case <-save.C:
+			if err := d.saveState(); err != nil {
+				slog.Warn(
+					"dht state save failed",
+					slog.String("error", err.Error()),
+				)
+			}
+		case now := <-refresh.C:
+			d.peerStore.expire(now)
+			d.items.expire(now)
+			for _, n := range d.table.questionable(now) {
+				d.AddNode(ctx, n.Addr)
+			}
+			for _, target := range d.table.staleBuckets(
+				now,
+				d.cfg.RefreshInterval,
+			) {
+				d.lookup(ctx, target, methodFindNode, nil)
+			}
+			if d.table.len() == 0 {
+				d.Bootstrap(ctx, seeds)
+			}
+		}
+	}
+}
+
+func (d *DHT) readLoop() {
+	buf := make([]byte, maxPacketSize)
+	for {
+		n, from, err := d.conn.ReadFromUDPAddrPort(buf)
+		if err != nil {
+			select {
+			case <-d.done:
+				return
+			default:
+			}
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			continue
+		}
+
+		msg, err := decodeMessage(buf[:n])
+		if err != nil {
+			continue
+		}
+		from = netip.AddrPortFrom(from.Addr().Unmap(), from.Port())
+
+		switch msg.Y {
+		case typeQuery:
+			d.reach.queried(from.Addr(), time.Now())
+			if d.cfg.ReadOnly {
+				continue
+			}
+			d.handleQuery(from, msg)
+		default:
+			d.deliver(from, msg)
+		}
+	}
+}
+
+func (d *DHT) deliver(from netip.AddrPort, msg *message) {
+	d.txMu.Lock()
+	pq, ok := d.pending[msg.T]
+	if ok && pq.addr == from {
+		delete(d.pending, msg.T)
+	}
+	d.txMu.Unlock()
+
+	if ok && pq.addr == from {
+		pq.ch <- msg
+	}
+}
+
+func (d *DHT) send(addr netip.AddrPort, msg *message) error {
+	b, err := msg.encode()
+	if err != nil {
+		return err
+	}
+
+	d.reach.sent(addr.Addr(), time.Now())
+	_, err = d.conn.WriteToUDPAddrPort(b, addr)
+	return err
+}
+
+// query sends a KRPC query and waits for the matching response. Responding
+// nodes are added to the routing table; silent ones are marked as failing.
+func (d *DHT) query(
+	ctx context.Context,
+	addr netip.AddrPort,
+	method string,
+	args map[string]any,
+) (*message, error) {
+	if d.conn == nil {
+		return nil, errors.New("dht: not started")
+	}
+	addr = netip.AddrPortFrom(addr.Addr().Unmap(), addr.Port())
+
+	ch := make(chan *message, 1)
+	d.txMu.Lock()
+	d.txSeq++
+	var t [2]byte
+	binary.BigEndian.PutUint16(t[:], d.txSeq)
+	tid := string(t[:])
+	d.pending[tid] = &pendingQuery{addr: addr, ch: ch}
+	d.txMu.Unlock()
+
+	cleanup := func() {
+		d.txMu.Lock()
+		delete(d.pending, tid)
+		d.txMu.Unlock()
+	}
+
+	msg := queryMessage(method, args)
+	msg.T = tid
+	msg.RO = d.cfg.ReadOnly
+	if err := d.send(addr, msg); err != nil {
+		cleanup()
+		return nil, err
+	}
+	d.counters.queries.Add(1)
+
+	timer := time.NewTimer(d.cfg.QueryTimeout)
+	defer timer.Stop()
+
+	select {
+	case resp := <-ch:
+		d.counters.responses.Add(1)
+		if resp.Y == typeError {
+			return nil, fmt.Errorf("dht: %s", resp.errorString())
+		}
+		if id, ok := resp.senderID(); ok {
+			d.table.insert(id, addr)
+		}
+		if resp.IP != "" {
+			d.observeExternalIP(addr, resp.IP)
+		}
+		return resp, nil
+	case <-timer.C:
+		cleanup()
+		d.counters.timeouts.Add(1)
+		d.markFailed(addr)
+		return nil, errQueryTimeout
+	case <-ctx.Done():
+		cleanup()
+		return nil, ctx.Err()
+	case <-d.done:
+		cleanup()
+		return nil, net.ErrClosed
+	}
+}
+
+func (d *DHT) markFailed(addr netip.AddrPort) {
+	for _, n := range d.table.nodes() {
+		if n.Addr == addr {
+			d.table.failed(n.ID)
+			return
+		}
+	}
+}
+
+func (d *DHT) findNode(
+	ctx context.Context,
+	addr netip.AddrPort,
+	target ID,
+) ([]*Node, error) {
+	resp, err := d.query(ctx, addr, methodFindNode, map[string]any{
+		"id":     d.idString(),
+		"target": string(target[:]),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return responseNodes(resp.R), nil
+}
+
+func responseNodes(r map[string]any) []*Node {
+	var nodes []*Node
+	if s, ok := r["nodes"].(string); ok {
+		nodes = append(nodes, decodeCompactNodes(s, false)...)
+	}
+	if s, ok := r["nodes6"].(string); ok {
+		nodes = append(nodes, decodeCompactNodes(s, true)...)
+	}
+	return nodes
+}
+
+func (d *DHT) handleQuery(from netip.AddrPort, msg *message) {
+	sender, ok := msg.senderID()
+	if !ok {
+		_ = d.send(
+			from,
+			errorMessage(msg.T, errCodeProtocol, "invalid id"),
+		)
+		return
+	}
+	// Read-only nodes cannot be queried, so they are useless in the table.
+	if !msg.RO {
+		d.table.insert(sender, from)
+	}
+
+	var resp *message
+	switch msg.Q {
+	case methodPing:
+		resp = responseMessage(msg.T, map[string]any{
+			"id": d.idString(),
+		})
+	case methodFindNode:
+		target, ok := msg.A["target"].(string)
+		if !ok || len(target) != len(ID{}) {
+			resp = errorMessage(
+				msg.T,
+				errCodeProtocol,
+				"invalid target",
+			)
+			break
+		}
+		resp = responseMessage(msg.T, d.nodesBody(ID([]byte(target))))
+	case methodGetPeers:
+		resp = d.handleGetPeers(from, msg)
+	case methodAnnouncePeer:
+		resp = d.handleAnnouncePeer(from, msg)
+	case methodGet:
+		resp = d.handleGet(from, msg)
+	case methodPut:
+		resp = d.handlePut(from, msg)
+	case methodSampleInfohashes:
+		resp = d.handleSampleInfohashes(msg)
+	default:
+		resp = errorMessage(
+			msg.T,
+			errCodeMethodUnknown,
+			"method unknown",
+		)
+	}
+
+	if resp.Y == typeResponse {
+		resp.IP = string(encodeCompactAddr(from))
+	}
+	_ = d.send(from, resp)
+}
+
+// nodesBody builds a response body carrying the nodes closest to target.
+func (d *DHT) nodesBody(target ID) map[string]any {
+	closest := d.table.closest(target, d.cfg.BucketSize)
+	body := map[string]any{
+		"id":    d.idString(),
+		"nodes": encodeCompactNodes(closest, false),
+	}
+	if v6 := encodeCompactNodes(closest, true); v6 != "" {
+		body["nodes6"] = v6
+	}
+
+	return body
+}
@@ -0,0 +1,432 @@
+package dht
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha1"
+	"errors"
+	"fmt"
+	"net/netip"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/prxssh/echo/internal/bencode"
+)
+
+// BEP 44 storage of arbitrary data. Immutable items are addressed by the
+// SHA-1 of their bencoded value; mutable items by the SHA-1 of the publisher's
+// ed25519 key and an optional salt, and carry a signed sequence number.
+
+const (
+	maxItemSize = 1000
+	maxSaltSize = 64
+	itemExpiry  = 2 * time.Hour
+	maxItems    = 1000
+)
+
+var (
+	ErrItemNotFound     = errors.New("dht: item not found")
+	ErrItemNotStored    = errors.New("dht: no node accepted the item")
+	errInvalidSignature = errors.New("dht: invalid item signature")
+)
+
+type Item struct {
+	V    any
+	K    ed25519.PublicKey
+	Salt []byte
+	Seq  int64
+	Sig  []byte
+}
+
+func (it *Item) mutable() bool {
+	return it.K != nil
+}
+
+func ImmutableTarget(v any) (ID, error) {
+	raw, err := encodeValue(v)
+	if err != nil {
+		return ID{}, err
+	}
+	return sha1.Sum(raw), nil
+}
+
+func MutableTarget(pub ed25519.PublicKey, salt []byte) ID {
+	h := sha1.New()
+	h.Write(pub)
+	h.Write(salt)
+
+	var id ID
+	copy(id[:], h.Sum(nil))
+	return id
+}
+
+// encodeValue bencodes an item's value in canonical form, the bytes its
+// target is hashed from and its signature covers, so that every node gets
+// the same bytes for the same value however it was built.
+func encodeValue(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := bencode.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return bencode.Canonicalize(buf.Bytes())
+}
+
+// signBuffer is the message a mutable item's signature covers.
+func signBuffer(salt []byte, seq int64, raw []byte) []byte {
+	var b bytes.Buffer
+	if len(salt) > 0 {
+		fmt.Fprintf(&b, "4:salt%d:%s", len(salt), salt)
+	}
+	fmt.Fprintf(&b, "3:seqi%de1:v", seq)
+	b.Write(raw)
+
+	return b.Bytes()
+}
+
+func verifyItem(it *Item, raw []byte) bool {
+	return len(it.K) == ed25519.PublicKeySize &&
+		len(it.Sig) == ed25519.SignatureSize &&
+		ed25519.Verify(it.K, signBuffer(it.Salt, it.Seq, raw), it.Sig)
+}
+
+type storedItem struct {
+	item   Item
+	raw    []byte
+	stored time.Time
+}
+
+type itemStore struct {
+	mu    sync.Mutex
+	items map[ID]*storedItem
+}
+
+func newItemStore() *itemStore {
+	return &itemStore{items: make(map[ID]*storedItem)}
+}
+
+func (s *itemStore) get(target ID) (*storedItem, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	si, ok := s.items[target]
+	if !ok || time.Since(si.stored) > itemExpiry {
+		return nil, false
+	}
+	return si, true
+}
+
+// put stores si under target. It returns a KRPC error code when the item is
+// rejected, or 0.
+func (s *itemStore) put(target ID, si *storedItem, cas int64, hasCAS bool) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, ok := s.items[target]
+	if ok && si.item.mutable() {
+		if hasCAS && existing.item.Seq != cas {
+			return errCodeCASMismatch
+		}
+		if si.item.Seq < existing.item.Seq ||
+			si.item.Seq == existing.item.Seq &&
+				!bytes.Equal(si.raw, existing.raw) {
+			return errCodeSeqTooLow
+		}
+	}
+	if !ok && len(s.items) >= maxItems {
+		return errCodeServer
+	}
+
+	s.items[target] = si
+	return 0
+}
+
+func (s *itemStore) expire(now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for target, si := range s.items {
+		if now.Sub(si.stored) > itemExpiry {
+			delete(s.items, target)
+		}
+	}
+}
+
+// GetImmutable looks up the immutable item stored under target.
+func (d *DHT) GetImmutable(ctx context.Context, target ID) (any, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var mu sync.Mutex
+	var found any
+	d.lookup(ctx, target, methodGet, func(_ *Node, r map[string]any) {
+		v, ok := r["v"]
+		if !ok {
+			return
+		}
+		raw, err := encodeValue(v)
+		if err != nil || sha1.Sum(raw) != target {
+			return
+		}
+
+		mu.Lock()
+		found = v
+		mu.Unlock()
+		cancel()
+	})
+
+	if found == nil {
+		return nil, ErrItemNotFound
+	}
+	return found, nil
+}
+
+// GetMutable looks up the newest correctly signed item published under pub and
+// salt.
+func (d *DHT) GetMutable(
+	ctx context.Context,
+	pub ed25519.PublicKey,
+	salt []byte,
+) (*Item, error) {
+	var mu sync.Mutex
+	var best *Item
+	d.lookup(
+		ctx,
+		MutableTarget(pub, salt),
+		methodGet,
+		func(_ *Node, r map[string]any) {
+			it, err := mutableFromResponse(r, pub, salt)
+			if err != nil {
+				return
+			}
+
+			mu.Lock()
+			if best == nil || it.Seq > best.Seq {
+				best = it
+			}
+			mu.Unlock()
+		},
+	)
+
+	if best == nil {
+		return nil, ErrItemNotFound
+	}
+	return best, nil
+}
+
+func mutableFromResponse(
+	r map[string]any,
+	pub ed25519.PublicKey,
+	salt []byte,
+) (*Item, error) {
+	v, ok := r["v"]
+	if !ok {
+		return nil, ErrItemNotFound
+	}
+	k, _ := r["k"].(string)
+	sig, _ := r["sig"].(string)
+	seq, _ := r["seq"].(int64)
+	if !bytes.Equal([]byte(k), pub) {
+		return nil, errInvalidSignature
+	}
+
+	raw, err := encodeValue(v)
+	if err != nil {
+		return nil, err
+	}
+	it := &Item{V: v, K: pub, Salt: salt, Seq: seq, Sig: []byte(sig)}
+	if !verifyItem(it, raw) {
+		return nil, errInvalidSignature
+	}
+
+	return it, nil
+}
+
+// PutImmutable stores v on the nodes closest to its hash and returns the
+// target it can be fetched with.
+func (d *DHT) PutImmutable(ctx context.Context, v any) (ID, error) {
+	raw, err := encodeValue(v)
+	if err != nil {
+		return ID{}, err
+	}
+	if len(raw) > maxItemSize {
+		return ID{}, fmt.Errorf("dht: item is %d bytes", len(raw))
+	}
+	target := ID(sha1.Sum(raw))
+
+	return target, d.putItem(ctx, target, map[string]any{"v": v})
+}
+
+// PutMutable signs v with priv under salt and sequence number seq and stores
+// it on the nodes closest to the resulting target.
+func (d *DHT) PutMutable(
+	ctx context.Context,
+	priv ed25519.PrivateKey,
+	salt []byte,
+	v any,
+	seq int64,
+) error {
+	raw, err := encodeValue(v)
+	if err != nil {
+		return err
+	}
+	if len(raw) > maxItemSize {
+		return fmt.Errorf("dht: item is %d bytes", len(raw))
+	}
+	if len(salt) > maxSaltSize {
+		return fmt.Errorf("dht: salt is %d bytes", len(salt))
+	}
+
+	pub := priv.Public().(ed25519.PublicKey)
+	args := map[string]any{
+		"v":   v,
+		"k":   string(pub),
+		"seq": seq,
+		"sig": string(ed25519.Sign(priv, signBuffer(salt, seq, raw))),
+	}
+	if len(salt) > 0 {
+		args["salt"] = string(salt)
+	}
+
+	return d.putItem(ctx, MutableTarget(pub, salt), args)
+}
+
+func (d *DHT) putItem(
+	ctx context.Context,
+	target ID,
+	args map[string]any,
+) error {
+	var mu sync.Mutex
+	tokens := make(map[netip.AddrPort]string)
+	closest := d.lookup(
+		ctx,
+		target,
+		methodGet,
+		func(node *Node, r map[string]any) {
+			if token, ok := r["token"].(string); ok {
+				mu.Lock()
+				tokens[node.Addr] = token
+				mu.Unlock()
+			}
+		},
+	)
+
+	var wg sync.WaitGroup
+	var stored atomic.Int32
+	for _, n := range closest {
+		token, ok := tokens[n.Addr]
+		if !ok {
+			continue
+		}
+
+		a := map[string]any{"id": d.idString(), "token": token}
+		for k, v := range args {
+			a[k] = v
+		}
+		wg.Go(func() {
+			_, err := d.query(ctx, n.Addr, methodPut, a)
+			if err == nil {
+				stored.Add(1)
+			}
+		})
+	}
+	wg.Wait()
+
+	if stored.Load() == 0 {
+		return ErrItemNotStored
+	}
+	return nil
+}
+
+func (d *DHT) handleGet(from netip.AddrPort, msg *message) *message {
+	s, ok := msg.A["target"].(string)
+	if !ok || len(s) != len(ID{}) {
+		return errorMessage(msg.T, errCodeProtocol, "invalid target")
+	}
+	target := ID([]byte(s))
+
+	body := d.nodesBody(target)
+	body["token"] = d.tokens.issue(from.Addr())
+
+	si, ok := d.items.get(target)
+	if !ok {
+		return responseMessage(msg.T, body)
+	}
+	if !si.item.mutable() {
+		body["v"] = si.item.V
+		return responseMessage(msg.T, body)
+	}
+
+	body["k"] = string(si.item.K)
+	body["seq"] = si.item.Seq
+	body["sig"] = string(si.item.Sig)
+	// A requester that already has this sequence number gets no value.
+	if seq, ok := msg.A["seq"].(int64); !ok || si.item.Seq > seq {
+		body["v"] = si.item.V
+	}
+
+	return responseMessage(msg.T, body)
+}
+
+func (d *DHT) handlePut(from netip.AddrPort, msg *message) *message {
+	token, _ := msg.A["token"].(string)
+	if !d.tokens.valid(token, from.Addr()) {
+		return errorMessage(msg.T, errCodeProtocol, "bad token")
+	}
+	v, ok := msg.A["v"]
+	if !ok {
+		return errorMessage(msg.T, errCodeProtocol, "missing v")
+	}
+	raw, err := encodeValue(v)
+	if err != nil {
+		return errorMessage(msg.T, errCodeProtocol, "invalid v")
+	}
+	if len(raw) > maxItemSize {
+		return errorMessage(msg.T, errCodeMessageTooBig, "v too big")
+	}
+
+	si := &storedItem{item: Item{V: v}, raw: raw, stored: time.Now()}
+	k, isMutable := msg.A["k"].(string)
+	if !isMutable {
+		code := d.items.put(sha1.Sum(raw), si, 0, false)
+		return d.putResponse(msg, code)
+	}
+
+	salt, _ := msg.A["salt"].(string)
+	if len(salt) > maxSaltSize {
+		return errorMessage(msg.T, errCodeSaltTooBig, "salt too big")
+	}
+	sig, _ := msg.A["sig"].(string)
+	seq, _ := msg.A["seq"].(int64)
+	si.item.K = ed25519.PublicKey(k)
+	si.item.Salt = []byte(salt)
+	si.item.Seq = seq
+	si.item.Sig = []byte(sig)
+	if !verifyItem(&si.item, raw) {
+		return errorMessage(
+			msg.T,
+			errCodeInvalidSignature,
+			"invalid signature",
+		)
+	}
+
+	cas, hasCAS := msg.A["cas"].(int64)
+	target := MutableTarget(si.item.K, si.item.Salt)
+	return d.putResponse(msg, d.items.put(target, si, cas, hasCAS))
+}
+
+func (d *DHT) putResponse(msg *message, code int) *message {
+	switch code {
+	case 0:
+		return responseMessage(msg.T, map[string]any{
+			"id": d.idString(),
+		})
+	case errCodeCASMismatch:
+		return errorMessage(msg.T, int64(code), "cas mismatch")
+	case errCodeSeqTooLow:
+		return errorMessage(msg.T, int64(code), "sequence too low")
+	default:
+		return errorMessage(msg.T, int64(code), "item store full")
+	}
+}
@@ -0,0 +1,138 @@
+package dht
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"net/netip"
+	"testing"
+)
+
+const bep44PublicKey = "77ff84905a91936367c01360803104f92432fcd904a43511876df5cdf3e7e548"
+
+func TestItemTargetsVectors(t *testing.T) {
+	// Examples from BEP 44.
+	imm, err := ImmutableTarget("Hello World!")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "e5f96f6f38320f0f33959cb4d3d656452117aadb"
+	if got := imm.String(); got != want {
+		t.Fatalf("immutable target = %s; want %s", got, want)
+	}
+
+	pub, _ := hex.DecodeString(bep44PublicKey)
+	cases := []struct {
+		salt string
+		want string
+	}{
+		{"", "4a533d47ec9c7d95b1ad75f576cffc641853b750"},
+		{"foobar", "411eba73b6f087ca51a3795d9c8c938d365e32c1"},
+	}
+	for _, tc := range cases {
+		got := MutableTarget(pub, []byte(tc.salt)).String()
+		if got != tc.want {
+			t.Fatalf("mutable target (salt %q) = %s", tc.salt, got)
+		}
+	}
+}
+
+func TestImmutableTargetCanonical(t *testing.T) {
+	// A struct whose fields are out of key order hashes as the dictionary
+	// it encodes to, since targets are taken over the canonical form.
+	type value struct {
+		Name string `bencode:"n"`
+		Size int64  `bencode:"l"`
+	}
+	fromStruct, err := ImmutableTarget(value{Name: "x", Size: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromMap, err := ImmutableTarget(map[string]any{"l": 1, "n": "x"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fromStruct != fromMap {
+		t.Errorf(
+			"struct target %s != map target %s",
+			fromStruct,
+			fromMap,
+		)
+	}
+}
+
+func TestSignBuffer(t *testing.T) {
+	raw := []byte("12:Hello World!")
+	got := string(signBuffer([]byte("foobar"), 1, raw))
+	want := "4:salt6:foobar3:seqi1e1:v12:Hello World!"
+	if got != want {
+		t.Fatalf("signBuffer = %q; want %q", got, want)
+	}
+}
+
+func TestPutGetMutableHandlers(t *testing.T) {
+	d, err := New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	from := netip.MustParseAddrPort("203.0.113.7:6881")
+	token := d.tokens.issue(from.Addr())
+
+	put := func(seq int64, v string) *message {
+		raw, _ := encodeValue(v)
+		sig := ed25519.Sign(priv, signBuffer(nil, seq, raw))
+		return d.handlePut(from, queryMessage(methodPut, map[string]any{
+			"token": token,
+			"v":     v,
+			"k":     string(pub),
+			"seq":   seq,
+			"sig":   string(sig),
+		}))
+	}
+
+	if resp := put(2, "second"); resp.Y != typeResponse {
+		t.Fatalf("put = %s", resp.errorString())
+	}
+	if resp := put(1, "first"); resp.Y != typeError {
+		t.Fatal("accepted a lower sequence number")
+	}
+
+	target := MutableTarget(pub, nil)
+	resp := d.handleGet(from, queryMessage(methodGet, map[string]any{
+		"target": string(target[:]),
+	}))
+	it, err := mutableFromResponse(resp.R, pub, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if it.V != "second" || it.Seq != 2 {
+		t.Fatalf("get = %v seq %d", it.V, it.Seq)
+	}
+
+	bad := queryMessage(methodPut, map[string]any{
+		"token": token,
+		"v":     "forged",
+		"k":     string(pub),
+		"seq":   int64(4),
+		"sig":   string(make([]byte, ed25519.SignatureSize)),
+	})
+	if resp := d.handlePut(from, bad); resp.Y != typeError {
+		t.Fatal("accepted a forged signature")
+	}
+}
+
+func TestParseMutableMagnet(t *testing.T) {
+	pub, salt, err := ParseMutableMagnet(
+		"magnet:?xs=urn:btpk:" + bep44PublicKey + "&s=666f6f626172",
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hex.EncodeToString(pub) != bep44PublicKey ||
+		string(salt) != "foobar" {
+		t.Fatalf("got %x, %q", pub, salt)
+	}
+}
@@ -0,0 +1,161 @@
+package dht
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/prxssh/echo/internal/bencode"
+)
+
+const (
+	typeQuery    = "q"
+	typeResponse = "r"
+	typeError    = "e"
+)
+
+const (
+	methodPing         = "ping"
+	methodFindNode     = "find_node"
+	methodGetPeers     = "get_peers"
+	methodAnnouncePeer = "announce_peer"
+	methodGet          = "get"
+	methodPut          = "put"
+	// methodSampleInfohashes is BEP 51's.
+	methodSampleInfohashes = "sample_infohashes"
+)
+
+const (
+	errCodeGeneric       = 201
+	errCodeServer        = 202
+	errCodeProtocol      = 203
+	errCodeMethodUnknown = 204
+
+	errCodeMessageTooBig    = 205
+	errCodeInvalidSignature = 206
+	errCodeSaltTooBig       = 207
+	errCodeCASMismatch      = 301
+	errCodeSeqTooLow        = 302
+)
+
+// message is a single KRPC packet. Only the fields relevant to its type are
+// populated. IP is the compact address of the querying node, echoed back in
+// responses (BEP 42). RO marks queries from read-only nodes (BEP 43).
+type message struct {
+	T  string
+	Y  string
+	Q  string
+	A  map[string]any
+	R  map[string]any
+	E  []any
+	IP string
+	RO bool
+}
+
+var errMalformedMessage = errors.New("dht: malformed krpc message")
+
+func (m *message) encode() ([]byte, error) {
+	dict := map[string]any{"t": m.T, "y": m.Y}
+	switch m.Y {
+	case typeQuery:
+		dict["q"] = m.Q
+		dict["a"] = m.A
+	case typeResponse:
+		dict["r"] = m.R
+	case typeError:
+		dict["e"] = m.E
+	}
+	if m.IP != "" {
+		dict["ip"] = m.IP
+	}
+	if m.RO {
+		dict["ro"] = int64(1)
+	}
+
+	var buf bytes.Buffer
+	if err := bencode.NewEncoder(&buf).Encode(dict); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func decodeMessage(b []byte) (*message, error) {
+	decoded, err := bencode.NewDecoder(bytes.NewReader(b)).Decode()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errMalformedMessage, err)
+	}
+	dict, ok := decoded.(map[string]any)
+	if !ok {
+		return nil, errMalformedMessage
+	}
+
+	m := &message{}
+	m.T, _ = dict["t"].(string)
+	m.Y, _ = dict["y"].(string)
+	m.IP, _ = dict["ip"].(string)
+	ro, _ := dict["ro"].(int64)
+	m.RO = ro == 1
+	if m.T == "" || m.Y == "" {
+		return nil, errMalformedMessage
+	}
+
+	switch m.Y {
+	case typeQuery:
+		m.Q, _ = dict["q"].(string)
+		m.A, ok = dict["a"].(map[string]any)
+		if !ok || m.Q == "" {
+			return nil, errMalformedMessage
+		}
+	case typeResponse:
+		m.R, ok = dict["r"].(map[string]any)
+		if !ok {
+			return nil, errMalformedMessage
+		}
+	case typeError:
+		m.E, _ = dict["e"].([]any)
+	default:
+		return nil, errMalformedMessage
+	}
+
+	return m, nil
+}
+
+// senderID extracts the "id" of the node that sent m.
+func (m *message) senderID() (ID, bool) {
+	body := m.A
+	if m.Y == typeResponse {
+		body = m.R
+	}
+
+	var id ID
+	s, ok := body["id"].(string)
+	if !ok || len(s) != len(id) {
+		return id, false
+	}
+	copy(id[:], s)
+
+	return id, true
+}
+
+func (m *message) errorString() string {
+	if len(m.E) < 2 {
+		return "unknown error"
+	}
+	code, _ := m.E[0].(int64)
+	msg, _ := m.E[1].(string)
+
+	return fmt.Sprintf("%d %s", code, msg)
+}
+
+func queryMessage(method string, args map[string]any) *message {
+	return &message{Y: typeQuery, Q: method, A: args}
+}
+
+func responseMessage(t string, body map[string]any) *message {
+	return &message{T: t, Y: typeResponse, R: body}
+}
+
+func errorMessage(t string, code int64, msg string) *message {
+	return &message{T: t, Y: typeError, E: []any{code, msg}}
+}
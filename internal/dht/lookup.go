@@ -0,0 +1,128 @@
+package dht
+
+import (
+	"context"
+	"net/netip"
+	"sort"
+	"sync"
+)
+
+// onResponseFunc is invoked for every node that answers during a lookup,
+// with the raw response body.
+type onResponseFunc func(node *Node, r map[string]any)
+
+type lookupEntry struct {
+	node      *Node
+	queried   bool
+	responded bool
+}
+
+// lookup runs an iterative Kademlia search for target using method (find_node
+// or get_peers). It keeps querying the alpha closest unqueried nodes until the
+// k closest known nodes have all been asked, and returns the closest nodes
+// that responded.
+func (d *DHT) lookup(
+	ctx context.Context,
+	target ID,
+	method string,
+	onResponse onResponseFunc,
+) []*Node {
+	k, alpha := d.cfg.BucketSize, d.cfg.Alpha
+	self := d.ID()
+
+	var mu sync.Mutex
+	seen := make(map[netip.AddrPort]*lookupEntry)
+	var shortlist []*lookupEntry
+
+	add := func(nodes []*Node) {
+		for _, n := range nodes {
+			if n.ID == self || !n.Addr.IsValid() {
+				continue
+			}
+			if _, ok := seen[n.Addr]; ok {
+				continue
+			}
+			e := &lookupEntry{node: n}
+			seen[n.Addr] = e
+			shortlist = append(shortlist, e)
+		}
+		sort.Slice(shortlist, func(i, j int) bool {
+			a, b := shortlist[i].node, shortlist[j].node
+			return closer(target, a.ID, b.ID)
+		})
+	}
+	add(d.table.closest(target, k))
+
+	args := map[string]any{"id": string(self[:])}
+	switch method {
+	case methodFindNode, methodGet, methodSampleInfohashes:
+		args["target"] = string(target[:])
+	default:
+		args["info_hash"] = string(target[:])
+	}
+
+	for {
+		if ctx.Err() != nil {
+			break
+		}
+
+		mu.Lock()
+		var batch []*lookupEntry
+		for i, e := range shortlist {
+			if i >= k || len(batch) >= alpha {
+				break
+			}
+			if !e.queried {
+				e.queried = true
+				batch = append(batch, e)
+			}
+		}
+		mu.Unlock()
+		if len(batch) == 0 {
+			break
+		}
+
+		var wg sync.WaitGroup
+		for _, e := range batch {
+			wg.Go(func() {
+				resp, err := d.query(
+					ctx,
+					e.node.Addr,
+					method,
+					args,
+				)
+				if err != nil {
+					return
+				}
+
+				mu.Lock()
+				if id, ok := resp.senderID(); ok {
+					e.node.ID = id
+				}
+				e.responded = true
+				add(responseNodes(resp.R))
+				mu.Unlock()
+
+				if onResponse != nil {
+					onResponse(e.node, resp.R)
+				}
+			})
+		}
+		wg.Wait()
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	var out []*Node
+	for _, e := range shortlist {
+		if e.responded {
+			out = append(out, e.node)
+		}
+		if len(out) == k {
+			break
+		}
+	}
+
+	return out
+}
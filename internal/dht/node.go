@@ -0,0 +1,141 @@
+package dht
+
+import (
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"math/bits"
+	"net/netip"
+	"time"
+)
+
+type ID [sha1.Size]byte
+
+func RandomID() (ID, error) {
+	var id ID
+	_, err := rand.Read(id[:])
+	return id, err
+}
+
+func ParseID(s string) (ID, error) {
+	var id ID
+
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return id, err
+	}
+	if len(b) != len(id) {
+		return id, errors.New("dht: node id must be 20 bytes")
+	}
+	copy(id[:], b)
+
+	return id, nil
+}
+
+func (id ID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+func (id ID) xor(other ID) ID {
+	var d ID
+	for i := range id {
+		d[i] = id[i] ^ other[i]
+	}
+	return d
+}
+
+// commonPrefixLen returns the number of leading bits id shares with other,
+// which is also the index of the bucket other belongs to in id's table.
+func (id ID) commonPrefixLen(other ID) int {
+	for i := range id {
+		if x := id[i] ^ other[i]; x != 0 {
+			return i*8 + bits.LeadingZeros8(x)
+		}
+	}
+	return len(id) * 8
+}
+
+// closer reports whether a is closer to target than b in the XOR metric.
+func closer(target, a, b ID) bool {
+	da, db := target.xor(a), target.xor(b)
+	for i := range da {
+		if da[i] != db[i] {
+			return da[i] < db[i]
+		}
+	}
+	return false
+}
+
+type Node struct {
+	ID       ID
+	Addr     netip.AddrPort
+	LastSeen time.Time
+	failures int
+	secure   bool
+}
+
+func (n *Node) good(now time.Time, questionableAfter time.Duration) bool {
+	return n.failures == 0 && now.Sub(n.LastSeen) < questionableAfter
+}
+
+const (
+	compactNodeV4 = sha1.Size + 6
+	compactNodeV6 = sha1.Size + 18
+)
+
+func encodeCompactAddr(addr netip.AddrPort) []byte {
+	ip := addr.Addr().Unmap()
+	b := ip.AsSlice()
+	b = binary.BigEndian.AppendUint16(b, addr.Port())
+	return b
+}
+
+func decodeCompactAddr(b []byte) (netip.AddrPort, bool) {
+	if len(b) != 6 && len(b) != 18 {
+		return netip.AddrPort{}, false
+	}
+
+	ip, ok := netip.AddrFromSlice(b[:len(b)-2])
+	if !ok {
+		return netip.AddrPort{}, false
+	}
+	port := binary.BigEndian.Uint16(b[len(b)-2:])
+
+	return netip.AddrPortFrom(ip, port), port != 0
+}
+
+func encodeCompactNodes(nodes []*Node, ipv6 bool) string {
+	var out []byte
+	for _, n := range nodes {
+		if n.Addr.Addr().Unmap().Is4() == ipv6 {
+			continue
+		}
+		out = append(out, n.ID[:]...)
+		out = append(out, encodeCompactAddr(n.Addr)...)
+	}
+	return string(out)
+}
+
+func decodeCompactNodes(s string, ipv6 bool) []*Node {
+	stride := compactNodeV4
+	if ipv6 {
+		stride = compactNodeV6
+	}
+
+	b := []byte(s)
+	nodes := make([]*Node, 0, len(b)/stride)
+	for i := 0; i+stride <= len(b); i += stride {
+		addr, ok := decodeCompactAddr(b[i+sha1.Size : i+stride])
+		if !ok {
+			continue
+		}
+
+		var id ID
+		copy(id[:], b[i:i+sha1.Size])
+		nodes = append(nodes, &Node{ID: id, Addr: addr})
+	}
+
+	return nodes
+}
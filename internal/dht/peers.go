@@ -0,0 +1,295 @@
+package dht
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"net/netip"
+	"sync"
+	"time"
+)
+
+const (
+	tokenRotation  = 5 * time.Minute
+	peerExpiry     = 30 * time.Minute
+	maxPeersPerKey = 100
+)
+
+// tokenManager issues announce tokens bound to the requester's IP. Secrets
+// rotate every few minutes and the previous secret stays valid, so a token
+// is accepted for up to two rotation periods.
+type tokenManager struct {
+	mu       sync.Mutex
+	current  []byte
+	previous []byte
+	rotated  time.Time
+}
+
+func newTokenManager() *tokenManager {
+	tm := &tokenManager{}
+	tm.rotate(time.Now())
+	return tm
+}
+
+func (tm *tokenManager) rotate(now time.Time) {
+	secret := make([]byte, 16)
+	_, _ = rand.Read(secret)
+
+	tm.previous, tm.current = tm.current, secret
+	tm.rotated = now
+}
+
+func (tm *tokenManager) issue(addr netip.Addr) string {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	if now := time.Now(); now.Sub(tm.rotated) > tokenRotation {
+		tm.rotate(now)
+	}
+	return tokenFor(tm.current, addr)
+}
+
+func (tm *tokenManager) valid(token string, addr netip.Addr) bool {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	for _, secret := range [][]byte{tm.current, tm.previous} {
+		if secret == nil {
+			continue
+		}
+		if hmac.Equal([]byte(token), []byte(tokenFor(secret, addr))) {
+			return true
+		}
+	}
+	return false
+}
+
+func tokenFor(secret []byte, addr netip.Addr) string {
+	mac := hmac.New(sha1.New, secret)
+	mac.Write(addr.AsSlice())
+	return string(mac.Sum(nil)[:8])
+}
+
+// peerStore keeps the peers other nodes announced to us.
+type peerStore struct {
+	mu    sync.Mutex
+	peers map[ID]map[netip.AddrPort]time.Time
+}
+
+func newPeerStore() *peerStore {
+	return &peerStore{peers: make(map[ID]map[netip.AddrPort]time.Time)}
+}
+
+func (ps *peerStore) add(infoHash ID, addr netip.AddrPort) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	set, ok := ps.peers[infoHash]
+	if !ok {
+		set = make(map[netip.AddrPort]time.Time)
+		ps.peers[infoHash] = set
+	}
+	if _, exists := set[addr]; !exists && len(set) >= maxPeersPerKey {
+		return
+	}
+	set[addr] = time.Now()
+}
+
+func (ps *peerStore) get(infoHash ID, n int) []netip.AddrPort {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	now := time.Now()
+	var out []netip.AddrPort
+	for addr, seen := range ps.peers[infoHash] {
+		if now.Sub(seen) > peerExpiry {
+			delete(ps.peers[infoHash], addr)
+			continue
+		}
+		if len(out) < n {
+			out = append(out, addr)
+		}
+	}
+
+	return out
+}
+
+// sample returns up to n of the info-hashes held, picked at random by map
+// order, and how many are held in all.
+func (ps *peerStore) sample(n int) ([]ID, int) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	out := make([]ID, 0, min(n, len(ps.peers)))
+	for infoHash := range ps.peers {
+		if len(out) == n {
+			break
+		}
+		out = append(out, infoHash)
+	}
+	return out, len(ps.peers)
+}
+
+func (ps *peerStore) len() int {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	n := 0
+	for _, set := range ps.peers {
+		n += len(set)
+	}
+	return n
+}
+
+func (ps *peerStore) expire(now time.Time) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	for infoHash, set := range ps.peers {
+		for addr, seen := range set {
+			if now.Sub(seen) > peerExpiry {
+				delete(set, addr)
+			}
+		}
+		if len(set) == 0 {
+			delete(ps.peers, infoHash)
+		}
+	}
+}
+
+// GetPeers looks infoHash up in the DHT and returns the peers found. If port
+// is non-zero we also announce ourselves on that port to the closest nodes
+// that handed us a token.
+func (d *DHT) GetPeers(
+	ctx context.Context,
+	infoHash [sha1.Size]byte,
+	port uint16,
+) []netip.AddrPort {
+	var mu sync.Mutex
+	seen := make(map[netip.AddrPort]struct{})
+	var peers []netip.AddrPort
+	tokens := make(map[netip.AddrPort]string)
+
+	closest := d.lookup(
+		ctx,
+		ID(infoHash),
+		methodGetPeers,
+		func(node *Node, r map[string]any) {
+			mu.Lock()
+			defer mu.Unlock()
+
+			if token, ok := r["token"].(string); ok {
+				tokens[node.Addr] = token
+			}
+			values, _ := r["values"].([]any)
+			for _, v := range values {
+				s, ok := v.(string)
+				if !ok {
+					continue
+				}
+				addr, ok := decodeCompactAddr([]byte(s))
+				if !ok {
+					continue
+				}
+				if _, dup := seen[addr]; dup {
+					continue
+				}
+				seen[addr] = struct{}{}
+				peers = append(peers, addr)
+			}
+		},
+	)
+
+	if port != 0 {
+		d.announce(ctx, infoHash, port, closest, tokens)
+	}
+
+	return peers
+}
+
+func (d *DHT) announce(
+	ctx context.Context,
+	infoHash [sha1.Size]byte,
+	port uint16,
+	nodes []*Node,
+	tokens map[netip.AddrPort]string,
+) {
+	var wg sync.WaitGroup
+	for _, n := range nodes {
+		token, ok := tokens[n.Addr]
+		if !ok {
+			continue
+		}
+
+		args := map[string]any{
+			"id":           d.idString(),
+			"info_hash":    string(infoHash[:]),
+			"port":         int64(port),
+			"implied_port": int64(0),
+			"token":        token,
+		}
+		wg.Go(func() {
+			_, err := d.query(ctx, n.Addr, methodAnnouncePeer, args)
+			if err == nil {
+				d.counters.announces.Add(1)
+			}
+		})
+	}
+	wg.Wait()
+}
+
+func (d *DHT) handleGetPeers(from netip.AddrPort, msg *message) *message {
+	infoHash, ok := msg.A["info_hash"].(string)
+	if !ok || len(infoHash) != len(ID{}) {
+		return errorMessage(msg.T, errCodeProtocol, "invalid info_hash")
+	}
+	target := ID([]byte(infoHash))
+
+	body := d.nodesBody(target)
+	body["token"] = d.tokens.issue(from.Addr())
+
+	if peers := d.peerStore.get(target, 50); len(peers) > 0 {
+		values := make([]any, 0, len(peers))
+		for _, p := range peers {
+			values = append(values, string(encodeCompactAddr(p)))
+		}
+		body["values"] = values
+	}
+
+	return responseMessage(msg.T, body)
+}
+
+func (d *DHT) handleAnnouncePeer(
+	from netip.AddrPort,
+	msg *message,
+) *message {
+	infoHash, ok := msg.A["info_hash"].(string)
+	if !ok || len(infoHash) != len(ID{}) {
+		return errorMessage(msg.T, errCodeProtocol, "invalid info_hash")
+	}
+	token, _ := msg.A["token"].(string)
+	if !d.tokens.valid(token, from.Addr()) {
+		return errorMessage(msg.T, errCodeProtocol, "bad token")
+	}
+
+	port := from.Port()
+	if implied, _ := msg.A["implied_port"].(int64); implied == 0 {
+		p, ok := msg.A["port"].(int64)
+		if !ok || p <= 0 || p > 65535 {
+			return errorMessage(
+				msg.T,
+				errCodeProtocol,
+				"invalid port",
+			)
+		}
+		port = uint16(p)
+	}
+
+	d.peerStore.add(
+		ID([]byte(infoHash)),
+		netip.AddrPortFrom(from.Addr(), port),
+	)
+
+	return responseMessage(msg.T, map[string]any{"id": d.idString()})
+}
@@ -0,0 +1,101 @@
+package dht
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// state is the on-disk form of our identity and the good part of the routing
+// table, so that a restart can rejoin the DHT from known nodes instead of
+// going through the public bootstrap routers.
+type state struct {
+	id    ID
+	nodes []*Node
+}
+
+type stateFile struct {
+	ID    string          `json:"id"`
+	Nodes []stateFileNode `json:"nodes"`
+}
+
+type stateFileNode struct {
+	ID   string `json:"id"`
+	Addr string `json:"addr"`
+}
+
+func loadState(path string) (*state, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var f stateFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, err
+	}
+
+	id, err := ParseID(f.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	st := &state{id: id}
+	for _, n := range f.Nodes {
+		nid, err := ParseID(n.ID)
+		if err != nil {
+			continue
+		}
+		addr, err := netip.ParseAddrPort(n.Addr)
+		if err != nil {
+			continue
+		}
+		st.nodes = append(st.nodes, &Node{ID: nid, Addr: addr})
+	}
+
+	return st, nil
+}
+
+func (d *DHT) saveState() error {
+	if d.cfg.StatePath == "" {
+		return nil
+	}
+
+	f := stateFile{ID: d.ID().String()}
+	now := time.Now()
+	for _, n := range d.table.nodes() {
+		if !n.good(now, d.cfg.QuestionableAfter) {
+			continue
+		}
+		f.Nodes = append(f.Nodes, stateFileNode{
+			ID:   n.ID.String(),
+			Addr: n.Addr.String(),
+		})
+	}
+
+	data, err := json.Marshal(f)
+	if err != nil {
+		return err
+	}
+	dir := filepath.Dir(d.cfg.StatePath)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	tmp := d.cfg.StatePath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, d.cfg.StatePath)
+}
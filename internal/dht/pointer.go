@@ -0,0 +1,116 @@
+package dht
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// BEP 46 torrent pointers: a mutable item whose value is {"ih": <info hash>},
+// letting a publisher move followers to new versions of a torrent.
+
+const btpkPrefix = "urn:btpk:"
+
+var errInvalidPointer = errors.New("dht: invalid torrent pointer")
+
+// ParseMutableMagnet extracts the public key and salt from a
+// magnet:?xs=urn:btpk:<key>&s=<salt> link.
+func ParseMutableMagnet(uri string) (ed25519.PublicKey, []byte, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil, nil, err
+	}
+	if u.Scheme != "magnet" {
+		return nil, nil, fmt.Errorf("dht: not a magnet link: %q", uri)
+	}
+
+	q := u.Query()
+	xs := q.Get("xs")
+	if !strings.HasPrefix(xs, btpkPrefix) {
+		return nil, nil, fmt.Errorf("dht: magnet has no btpk: %q", uri)
+	}
+	pub, err := hex.DecodeString(strings.TrimPrefix(xs, btpkPrefix))
+	if err != nil || len(pub) != ed25519.PublicKeySize {
+		return nil, nil, fmt.Errorf("dht: invalid btpk in %q", uri)
+	}
+	salt, err := hex.DecodeString(q.Get("s"))
+	if err != nil {
+		return nil, nil, fmt.Errorf("dht: invalid salt in %q", uri)
+	}
+
+	return ed25519.PublicKey(pub), salt, nil
+}
+
+// ResolveTorrentPointer returns the info hash the newest pointer published
+// under pub and salt refers to, along with its sequence number.
+func (d *DHT) ResolveTorrentPointer(
+	ctx context.Context,
+	pub ed25519.PublicKey,
+	salt []byte,
+) ([sha1.Size]byte, int64, error) {
+	var ih [sha1.Size]byte
+
+	it, err := d.GetMutable(ctx, pub, salt)
+	if err != nil {
+		return ih, 0, err
+	}
+	v, ok := it.V.(map[string]any)
+	if !ok {
+		return ih, 0, errInvalidPointer
+	}
+	s, ok := v["ih"].(string)
+	if !ok || len(s) != sha1.Size {
+		return ih, 0, errInvalidPointer
+	}
+	copy(ih[:], s)
+
+	return ih, it.Seq, nil
+}
+
+// FollowTorrentPointer polls the pointer every interval and calls onUpdate
+// whenever it moves to a newer sequence number, until ctx is done.
+func (d *DHT) FollowTorrentPointer(
+	ctx context.Context,
+	pub ed25519.PublicKey,
+	salt []byte,
+	interval time.Duration,
+	onUpdate func(infoHash [sha1.Size]byte, seq int64),
+) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	last := int64(-1)
+	for {
+		ih, seq, err := d.ResolveTorrentPointer(ctx, pub, salt)
+		if err == nil && seq > last {
+			last = seq
+			onUpdate(ih, seq)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-d.done:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// PublishTorrentPointer points pub/salt at infoHash with sequence number seq.
+func (d *DHT) PublishTorrentPointer(
+	ctx context.Context,
+	priv ed25519.PrivateKey,
+	salt []byte,
+	infoHash [sha1.Size]byte,
+	seq int64,
+) error {
+	v := map[string]any{"ih": string(infoHash[:])}
+	return d.PutMutable(ctx, priv, salt, v, seq)
+}
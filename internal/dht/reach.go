@@ -0,0 +1,81 @@
+package dht
+
+import (
+	"maps"
+	"net/netip"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	// contactedTTL outlasts the time NATs keep a mapping open for the
+	// answers of an address written to.
+	contactedTTL = 10 * time.Minute
+	// maxContacted bounds the addresses remembered; past it the oldest
+	// are forgotten.
+	maxContacted = 16384
+)
+
+// reachability notes queries from addresses the node has not written to
+// lately. Only a port reachable from outside gets those: a NAT lets in
+// just the answers of the addresses written to.
+type reachability struct {
+	mu          sync.Mutex
+	contacted   map[netip.Addr]time.Time
+	unsolicited time.Time
+}
+
+func newReachability() *reachability {
+	return &reachability{contacted: make(map[netip.Addr]time.Time)}
+}
+
+func (r *reachability) sent(addr netip.Addr, now time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.contacted) >= maxContacted {
+		for a, at := range r.contacted {
+			if now.Sub(at) > contactedTTL {
+				delete(r.contacted, a)
+			}
+		}
+	}
+	if len(r.contacted) >= maxContacted {
+		// Forgetting everyone lets a stray answer pass for a query
+		// made unasked, so the oldest half is dropped instead.
+		r.dropOldest(len(r.contacted) / 2)
+	}
+	r.contacted[addr] = now
+}
+
+func (r *reachability) dropOldest(n int) {
+	times := slices.Collect(maps.Values(r.contacted))
+	slices.SortFunc(times, time.Time.Compare)
+	cutoff := times[n]
+	for a, at := range r.contacted {
+		if at.Before(cutoff) {
+			delete(r.contacted, a)
+		}
+	}
+}
+
+func (r *reachability) queried(addr netip.Addr, now time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	at, ok := r.contacted[addr]
+	if !ok || now.Sub(at) > contactedTTL {
+		r.unsolicited = now
+	}
+}
+
+// LastUnsolicited returns when a node the DHT had not written to last
+// queried it, which shows its port reachable from outside; zero if none
+// has.
+func (d *DHT) LastUnsolicited() time.Time {
+	d.reach.mu.Lock()
+	defer d.reach.mu.Unlock()
+
+	return d.reach.unsolicited
+}
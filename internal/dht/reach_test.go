@@ -0,0 +1,49 @@
+package dht
+
+import (
+	"net/netip"
+	"testing"
+	"time"
+)
+
+func TestReachability(t *testing.T) {
+	r := newReachability()
+	now := time.Now()
+	known := netip.MustParseAddr("192.0.2.1")
+
+	r.sent(known, now)
+	r.queried(known, now.Add(time.Minute))
+	if !r.unsolicited.IsZero() {
+		t.Fatal("answer to a query taken as unsolicited")
+	}
+
+	later := now.Add(contactedTTL + time.Minute)
+	r.queried(known, later)
+	if !r.unsolicited.Equal(later) {
+		t.Errorf("unsolicited = %v, want %v", r.unsolicited, later)
+	}
+
+	r.queried(netip.MustParseAddr("192.0.2.2"), later.Add(time.Second))
+	if !r.unsolicited.Equal(later.Add(time.Second)) {
+		t.Error("query from a stranger not noted")
+	}
+}
+
+func TestReachabilityBounded(t *testing.T) {
+	r := newReachability()
+	now := time.Now()
+	for i := range maxContacted + 1 {
+		addr := netip.AddrFrom4([4]byte{10, 0, byte(i >> 8), byte(i)})
+		r.sent(addr, now.Add(time.Duration(i)*time.Millisecond))
+	}
+	if len(r.contacted) > maxContacted {
+		t.Fatalf("%d addresses remembered", len(r.contacted))
+	}
+	newest := netip.AddrFrom4([4]byte{10, 0, 64, 0})
+	if _, ok := r.contacted[newest]; !ok {
+		t.Error("newest address forgotten")
+	}
+	if _, ok := r.contacted[netip.AddrFrom4([4]byte{10, 0, 0, 0})]; ok {
+		t.Error("oldest address kept")
+	}
+}
@@ -0,0 +1,74 @@
+package dht
+
+import (
+	"context"
+	"crypto/sha1"
+	"sync"
+)
+
+const (
+	// maxSamples is how many info-hashes we hand out per
+	// sample_infohashes answer, which keeps it in one packet.
+	maxSamples = 20
+	// sampleInterval is how long, in seconds, we ask others to wait
+	// before sampling us again.
+	sampleInterval = 6 * 60 * 60
+)
+
+// SampleInfoHashes looks target up with BEP 51's sample_infohashes and
+// returns the info-hashes the nodes along the way sampled from their
+// storage. A random target samples a random corner of the DHT, which is how
+// it is crawled for torrents.
+func (d *DHT) SampleInfoHashes(
+	ctx context.Context,
+	target ID,
+) [][sha1.Size]byte {
+	var mu sync.Mutex
+	seen := make(map[[sha1.Size]byte]struct{})
+	var hashes [][sha1.Size]byte
+
+	d.lookup(
+		ctx,
+		target,
+		methodSampleInfohashes,
+		func(_ *Node, r map[string]any) {
+			samples, _ := r["samples"].(string)
+			mu.Lock()
+			defer mu.Unlock()
+
+			for len(samples) >= sha1.Size {
+				var h [sha1.Size]byte
+				copy(h[:], samples)
+				samples = samples[sha1.Size:]
+				if _, dup := seen[h]; dup {
+					continue
+				}
+				seen[h] = struct{}{}
+				hashes = append(hashes, h)
+			}
+		},
+	)
+
+	return hashes
+}
+
+// handleSampleInfohashes answers with some of the info-hashes peers have
+// announced to us, along with the nodes closest to the target.
+func (d *DHT) handleSampleInfohashes(msg *message) *message {
+	target, ok := msg.A["target"].(string)
+	if !ok || len(target) != len(ID{}) {
+		return errorMessage(msg.T, errCodeProtocol, "invalid target")
+	}
+
+	keys, total := d.peerStore.sample(maxSamples)
+	samples := make([]byte, 0, len(keys)*sha1.Size)
+	for _, k := range keys {
+		samples = append(samples, k[:]...)
+	}
+
+	body := d.nodesBody(ID([]byte(target)))
+	body["samples"] = string(samples)
+	body["num"] = int64(total)
+	body["interval"] = int64(sampleInterval)
+	return responseMessage(msg.T, body)
+}
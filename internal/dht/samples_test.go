@@ -0,0 +1,38 @@
+package dht
+
+import (
+	"net/netip"
+	"testing"
+)
+
+func TestHandleSampleInfohashes(t *testing.T) {
+	d, err := New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := netip.MustParseAddrPort("10.0.0.1:6881")
+	for i := range maxSamples + 5 {
+		d.peerStore.add(ID{byte(i), 1}, addr)
+	}
+
+	var target ID
+	resp := d.handleSampleInfohashes(&message{
+		T: "aa",
+		A: map[string]any{"target": string(target[:])},
+	})
+	if resp.Y != typeResponse {
+		t.Fatalf("got %+v, want a response", resp)
+	}
+	samples, _ := resp.R["samples"].(string)
+	if len(samples) != maxSamples*len(ID{}) {
+		t.Errorf("samples = %d bytes", len(samples))
+	}
+	if num := resp.R["num"]; num != int64(maxSamples+5) {
+		t.Errorf("num = %v", num)
+	}
+
+	resp = d.handleSampleInfohashes(&message{T: "ab", A: map[string]any{}})
+	if resp.Y != typeError {
+		t.Errorf("missing target answered with %+v", resp)
+	}
+}
@@ -0,0 +1,127 @@
+package dht
+
+import (
+	"crypto/rand"
+	"hash/crc32"
+	"net/netip"
+	"sync"
+)
+
+// BEP 42 ties a node ID to the node's external IP: the first 21 bits are a
+// CRC32-C of the masked address and the last byte holds the random salt.
+
+var (
+	castagnoli = crc32.MakeTable(crc32.Castagnoli)
+	v4IDMask   = []byte{0x03, 0x0f, 0x3f, 0xff}
+	v6IDMask   = []byte{0x01, 0x03, 0x07, 0x0f, 0x1f, 0x3f, 0x7f, 0xff}
+)
+
+// externalIPVotes is how many distinct nodes must report the same external
+// address before we trust it.
+const (
+	externalIPVotes = 8
+	maxIPVoters     = 64
+)
+
+func idPrefix(ip netip.Addr, r byte) uint32 {
+	ip = ip.Unmap()
+	b, mask := ip.AsSlice(), v4IDMask
+	if ip.Is6() {
+		b, mask = b[:len(v6IDMask)], v6IDMask
+	}
+	for i := range b {
+		b[i] &= mask[i]
+	}
+	b[0] |= (r & 0x07) << 5
+
+	return crc32.Checksum(b, castagnoli)
+}
+
+// SecureID returns a random node ID that is valid for ip under BEP 42.
+func SecureID(ip netip.Addr) (ID, error) {
+	var id ID
+	if _, err := rand.Read(id[:]); err != nil {
+		return id, err
+	}
+
+	crc := idPrefix(ip, id[19])
+	id[0] = byte(crc >> 24)
+	id[1] = byte(crc >> 16)
+	id[2] = byte(crc>>8)&0xf8 | id[2]&0x07
+
+	return id, nil
+}
+
+// ValidID reports whether id is a BEP 42 compliant ID for ip. Local and
+// private addresses are exempt.
+func ValidID(id ID, ip netip.Addr) bool {
+	ip = ip.Unmap()
+	if !ip.IsValid() || ip.IsLoopback() || ip.IsPrivate() ||
+		ip.IsLinkLocalUnicast() {
+		return true
+	}
+
+	crc := idPrefix(ip, id[19])
+	return id[0] == byte(crc>>24) && id[1] == byte(crc>>16) &&
+		id[2]&0xf8 == byte(crc>>8)&0xf8
+}
+
+// ipVoter tallies the external address other nodes see us at, one vote per
+// node. Only IPv4 votes count, since our ID can be tied to a single address
+// and IPv4 is what most of the DHT speaks.
+type ipVoter struct {
+	mu       sync.Mutex
+	votes    map[netip.Addr]netip.Addr
+	external netip.Addr
+}
+
+func newIPVoter() *ipVoter {
+	return &ipVoter{votes: make(map[netip.Addr]netip.Addr)}
+}
+
+// vote records that voter saw us at ip and returns the new external address
+// once it changes by consensus.
+func (v *ipVoter) vote(voter, ip netip.Addr) (netip.Addr, bool) {
+	ip = ip.Unmap()
+	if !ip.Is4() || ip.IsPrivate() || ip.IsLoopback() {
+		return netip.Addr{}, false
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if len(v.votes) >= maxIPVoters {
+		clear(v.votes)
+	}
+	v.votes[voter.Unmap()] = ip
+	count := 0
+	for _, claimed := range v.votes {
+		if claimed == ip {
+			count++
+		}
+	}
+	if count < externalIPVotes || ip == v.external {
+		return netip.Addr{}, false
+	}
+
+	v.external = ip
+	clear(v.votes)
+	return ip, true
+}
+
+// reset forgets the votes and the external address agreed on, so the next
+// consensus counts as a change even if it lands on the same address.
+func (v *ipVoter) reset() {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	clear(v.votes)
+	v.external = netip.Addr{}
+}
+
+func (v *ipVoter) get() netip.Addr {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	return v.external
+}
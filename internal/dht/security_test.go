@@ -0,0 +1,97 @@
+package dht
+
+import (
+	"net/netip"
+	"testing"
+)
+
+func TestValidIDVectors(t *testing.T) {
+	// Examples from BEP 42.
+	cases := []struct {
+		ip string
+		id string
+	}{
+		{"124.31.75.21", "5fbfbff10c5d6a4ec8a88e4c6ab4c28b95eee401"},
+		{"21.75.31.124", "5a3ce9c14e7a08645677bbd1cfe7d8f956d53256"},
+		{"65.23.51.170", "a5d43220bc8f112a3d426c84764f8c2a1150e616"},
+		{"84.124.73.14", "1b0321dd1bb1fe518101ceef99462b947a01ff41"},
+		{"43.213.53.83", "e56f6cbf5b7c4be0237986d5243b87aa6d51305a"},
+	}
+
+	for _, tc := range cases {
+		id, err := ParseID(tc.id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ip := netip.MustParseAddr(tc.ip)
+		if !ValidID(id, ip) {
+			t.Fatalf("ValidID(%s, %s) = false", tc.id, tc.ip)
+		}
+
+		id[0] ^= 0xff
+		if ValidID(id, ip) {
+			t.Fatalf("ValidID accepted tampered id for %s", tc.ip)
+		}
+	}
+}
+
+func TestSecureIDIsValid(t *testing.T) {
+	for _, s := range []string{"203.0.113.7", "2001:db8::42"} {
+		ip := netip.MustParseAddr(s)
+		id, err := SecureID(ip)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !ValidID(id, ip) {
+			t.Fatalf("SecureID(%s) = %s is not valid", s, id)
+		}
+	}
+}
+
+func TestIPVoterNeedsConsensus(t *testing.T) {
+	v := newIPVoter()
+	ext := netip.MustParseAddr("203.0.113.7")
+
+	for i := 1; i < externalIPVotes; i++ {
+		voter := netip.AddrFrom4([4]byte{198, 51, 100, byte(i)})
+		if _, changed := v.vote(voter, ext); changed {
+			t.Fatalf("consensus after %d votes", i)
+		}
+	}
+	// A repeated vote from the same node does not count twice.
+	if _, changed := v.vote(
+		netip.AddrFrom4([4]byte{198, 51, 100, 1}),
+		ext,
+	); changed {
+		t.Fatal("duplicate voter reached consensus")
+	}
+
+	got, changed := v.vote(netip.AddrFrom4([4]byte{198, 51, 100, 99}), ext)
+	if !changed || got != ext || v.get() != ext {
+		t.Fatalf("vote = %s, %v; want %s, true", got, changed, ext)
+	}
+}
+
+func TestIPVoterReset(t *testing.T) {
+	v := newIPVoter()
+	ext := netip.MustParseAddr("203.0.113.7")
+	vote := func() bool {
+		var changed bool
+		for i := range externalIPVotes {
+			voter := netip.AddrFrom4([4]byte{198, 51, 100, byte(i)})
+			_, changed = v.vote(voter, ext)
+		}
+		return changed
+	}
+
+	if !vote() {
+		t.Fatal("no consensus")
+	}
+	v.reset()
+	if v.get().IsValid() {
+		t.Fatalf("external = %s after reset", v.get())
+	}
+	if !vote() {
+		t.Fatal("same address after reset is not a change")
+	}
+}
@@ -0,0 +1,58 @@
+package dht
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/prxssh/echo/internal/events"
+)
+
+type counters struct {
+	queries   atomic.Uint64
+	responses atomic.Uint64
+	timeouts  atomic.Uint64
+	announces atomic.Uint64
+}
+
+// TopicStats carries the DHT's Stats now and then.
+const TopicStats events.Topic[Stats] = "dht:stats"
+
+// Stats is a snapshot of DHT health for the UI.
+type Stats struct {
+	ID          string `json:"id"`
+	ExternalIP  string `json:"externalIp"`
+	ReadOnly    bool   `json:"readOnly"`
+	Nodes       int    `json:"nodes"`
+	GoodNodes   int    `json:"goodNodes"`
+	Buckets     int    `json:"buckets"`
+	FullBuckets int    `json:"fullBuckets"`
+	Queries     uint64 `json:"queries"`
+	Responses   uint64 `json:"responses"`
+	Timeouts    uint64 `json:"timeouts"`
+	Announces   uint64 `json:"announces"`
+	StoredPeers int    `json:"storedPeers"`
+}
+
+func (d *DHT) Stats() Stats {
+	s := Stats{
+		ID:          d.ID().String(),
+		ReadOnly:    d.cfg.ReadOnly,
+		Queries:     d.counters.queries.Load(),
+		Responses:   d.counters.responses.Load(),
+		Timeouts:    d.counters.timeouts.Load(),
+		Announces:   d.counters.announces.Load(),
+		StoredPeers: d.peerStore.len(),
+	}
+	if ip := d.ExternalIP(); ip.IsValid() {
+		s.ExternalIP = ip.String()
+	}
+
+	d.table.fillStats(&s, time.Now())
+
+	return s
+}
+
+func (d *DHT) emitStats(ctx context.Context) {
+	TopicStats.Emit(ctx, d.Stats())
+}
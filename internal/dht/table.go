@@ -0,0 +1,274 @@
+package dht
+
+import (
+	"net/netip"
+	"sort"
+	"sync"
+	"time"
+)
+
+// table is a Kademlia routing table split into one bucket per shared-prefix
+// length with our own ID. Buckets hold at most k nodes; when a bucket is
+// full, a new node only gets in by replacing one that stopped responding.
+type table struct {
+	self ID
+	k    int
+
+	questionableAfter time.Duration
+	maxFailures       int
+	enforceIDs        bool
+
+	mu      sync.RWMutex
+	buckets [len(ID{}) * 8]bucket
+}
+
+type bucket struct {
+	nodes       []*Node
+	lastChanged time.Time
+}
+
+func newTable(self ID, cfg Config) *table {
+	return &table{
+		self:              self,
+		k:                 cfg.BucketSize,
+		questionableAfter: cfg.QuestionableAfter,
+		maxFailures:       cfg.MaxNodeFailures,
+		enforceIDs:        cfg.EnforceNodeIDs,
+	}
+}
+
+func (t *table) bucketIndex(id ID) int {
+	i := t.self.commonPrefixLen(id)
+	if i >= len(t.buckets) {
+		i = len(t.buckets) - 1
+	}
+	return i
+}
+
+// insert records that id answered from addr. It returns false if the node
+// could not be placed because its bucket is full of good nodes, or because
+// its ID is not valid for its address and IDs are enforced.
+func (t *table) insert(id ID, addr netip.AddrPort) bool {
+	if !addr.IsValid() {
+		return false
+	}
+	secure := ValidID(id, addr.Addr())
+	if !secure && t.enforceIDs {
+		return false
+	}
+	now := time.Now()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if id == t.self {
+		return false
+	}
+
+	b := &t.buckets[t.bucketIndex(id)]
+	for _, n := range b.nodes {
+		if n.ID == id {
+			n.Addr = addr
+			n.LastSeen = now
+			n.failures = 0
+			b.lastChanged = now
+			return true
+		}
+	}
+
+	node := &Node{ID: id, Addr: addr, LastSeen: now, secure: secure}
+	if len(b.nodes) < t.k {
+		b.nodes = append(b.nodes, node)
+		b.lastChanged = now
+		return true
+	}
+
+	for i, n := range b.nodes {
+		if n.failures >= t.maxFailures {
+			b.nodes[i] = node
+			b.lastChanged = now
+			return true
+		}
+	}
+	// Nodes violating BEP 42 are the first to go when a compliant one
+	// needs the slot.
+	if secure {
+		for i, n := range b.nodes {
+			if !n.secure {
+				b.nodes[i] = node
+				b.lastChanged = now
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// rebase moves the table onto a new own ID, re-bucketing every known node.
+// Nodes that no longer fit are dropped.
+func (t *table) rebase(self ID) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var all []*Node
+	for i := range t.buckets {
+		all = append(all, t.buckets[i].nodes...)
+		t.buckets[i] = bucket{}
+	}
+
+	t.self = self
+	now := time.Now()
+	for _, n := range all {
+		if n.ID == self {
+			continue
+		}
+		b := &t.buckets[t.bucketIndex(n.ID)]
+		if len(b.nodes) < t.k {
+			b.nodes = append(b.nodes, n)
+			b.lastChanged = now
+		}
+	}
+}
+
+func (t *table) failed(id ID) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	b := &t.buckets[t.bucketIndex(id)]
+	for i, n := range b.nodes {
+		if n.ID != id {
+			continue
+		}
+
+		n.failures++
+		if n.failures >= t.maxFailures && len(b.nodes) > 0 {
+			b.nodes = append(b.nodes[:i], b.nodes[i+1:]...)
+		}
+		return
+	}
+}
+
+// closest returns up to n known nodes ordered by distance to target.
+func (t *table) closest(target ID, n int) []*Node {
+	t.mu.RLock()
+	all := make([]*Node, 0, n*2)
+	for i := range t.buckets {
+		for _, node := range t.buckets[i].nodes {
+			cp := *node
+			all = append(all, &cp)
+		}
+	}
+	t.mu.RUnlock()
+
+	sort.Slice(all, func(i, j int) bool {
+		return closer(target, all[i].ID, all[j].ID)
+	})
+	if len(all) > n {
+		all = all[:n]
+	}
+
+	return all
+}
+
+func (t *table) nodes() []*Node {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	var out []*Node
+	for i := range t.buckets {
+		for _, node := range t.buckets[i].nodes {
+			cp := *node
+			out = append(out, &cp)
+		}
+	}
+
+	return out
+}
+
+func (t *table) len() int {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	n := 0
+	for i := range t.buckets {
+		n += len(t.buckets[i].nodes)
+	}
+	return n
+}
+
+func (t *table) fillStats(s *Stats, now time.Time) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	for i := range t.buckets {
+		nodes := t.buckets[i].nodes
+		if len(nodes) == 0 {
+			continue
+		}
+		s.Buckets++
+		if len(nodes) >= t.k {
+			s.FullBuckets++
+		}
+		for _, n := range nodes {
+			s.Nodes++
+			if n.good(now, t.questionableAfter) {
+				s.GoodNodes++
+			}
+		}
+	}
+}
+
+// questionable returns nodes we have not heard from recently, which the
+// refresher pings to find out whether they are still alive.
+func (t *table) questionable(now time.Time) []*Node {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	var out []*Node
+	for i := range t.buckets {
+		for _, node := range t.buckets[i].nodes {
+			if !node.good(now, t.questionableAfter) {
+				cp := *node
+				out = append(out, &cp)
+			}
+		}
+	}
+
+	return out
+}
+
+// staleBuckets returns a random target inside every non-empty-prefix bucket
+// that has not changed within the refresh interval.
+func (t *table) staleBuckets(now time.Time, after time.Duration) []ID {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	var targets []ID
+	for i := range t.buckets {
+		b := &t.buckets[i]
+		if len(b.nodes) == 0 || now.Sub(b.lastChanged) < after {
+			continue
+		}
+		targets = append(targets, t.randomIDInBucket(i))
+	}
+
+	return targets
+}
+
+func (t *table) randomIDInBucket(i int) ID {
+	id, _ := RandomID()
+
+	// Keep the first i bits of our own ID and flip bit i, so the result
+	// shares exactly i prefix bits with us.
+	for bit := 0; bit <= i && bit < len(id)*8; bit++ {
+		mask := byte(0x80 >> (bit % 8))
+		own := t.self[bit/8] & mask
+		if bit == i {
+			own ^= mask
+		}
+		id[bit/8] = id[bit/8]&^mask | own
+	}
+
+	return id
+}
@@ -0,0 +1,120 @@
+package dht
+
+import (
+	"net/netip"
+	"testing"
+	"time"
+)
+
+func idWithPrefix(b ...byte) ID {
+	var id ID
+	copy(id[:], b)
+	return id
+}
+
+func TestCommonPrefixLen(t *testing.T) {
+	cases := []struct {
+		a, b ID
+		want int
+	}{
+		{idWithPrefix(0x00), idWithPrefix(0x80), 0},
+		{idWithPrefix(0x00), idWithPrefix(0x01), 7},
+		{idWithPrefix(0xFF, 0x00), idWithPrefix(0xFF, 0x40), 9},
+		{idWithPrefix(0x12), idWithPrefix(0x12), 160},
+	}
+
+	for _, tc := range cases {
+		if got := tc.a.commonPrefixLen(tc.b); got != tc.want {
+			t.Fatalf(
+				"commonPrefixLen(%s, %s) = %d; want %d",
+				tc.a,
+				tc.b,
+				got,
+				tc.want,
+			)
+		}
+	}
+}
+
+func TestCompactNodesRoundTrip(t *testing.T) {
+	nodes := []*Node{
+		{
+			ID:   idWithPrefix(1),
+			Addr: netip.MustParseAddrPort("1.2.3.4:6881"),
+		},
+		{
+			ID:   idWithPrefix(2),
+			Addr: netip.MustParseAddrPort("[2001:db8::1]:51413"),
+		},
+	}
+
+	v4 := decodeCompactNodes(encodeCompactNodes(nodes, false), false)
+	if len(v4) != 1 || v4[0].ID != nodes[0].ID ||
+		v4[0].Addr != nodes[0].Addr {
+		t.Fatalf("v4 round trip = %+v", v4)
+	}
+
+	v6 := decodeCompactNodes(encodeCompactNodes(nodes, true), true)
+	if len(v6) != 1 || v6[0].ID != nodes[1].ID ||
+		v6[0].Addr != nodes[1].Addr {
+		t.Fatalf("v6 round trip = %+v", v6)
+	}
+}
+
+func TestTableBucketCapacityAndClosest(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.BucketSize = 2
+	tbl := newTable(ID{}, cfg)
+
+	addr := netip.MustParseAddrPort("10.0.0.1:1")
+	// All three share zero prefix bits with the all-zero self ID.
+	a, b, c := idWithPrefix(0x80), idWithPrefix(0x81), idWithPrefix(0x82)
+	if !tbl.insert(a, addr) || !tbl.insert(b, addr) {
+		t.Fatalf("insert into empty bucket failed")
+	}
+	if tbl.insert(c, addr) {
+		t.Fatalf("insert into full bucket of good nodes should fail")
+	}
+
+	for i := 0; i < cfg.MaxNodeFailures-1; i++ {
+		tbl.failed(a)
+	}
+	if tbl.insert(c, addr) {
+		t.Fatalf("node below failure threshold must not be replaced")
+	}
+	tbl.failed(a)
+	if !tbl.insert(c, addr) {
+		t.Fatalf("insert should succeed once a node was evicted")
+	}
+
+	closest := tbl.closest(idWithPrefix(0x82), 1)
+	if len(closest) != 1 || closest[0].ID != c {
+		t.Fatalf("closest = %+v; want %s", closest, c)
+	}
+}
+
+func TestRandomIDInBucket(t *testing.T) {
+	self := idWithPrefix(0xAB, 0xCD)
+	tbl := newTable(self, DefaultConfig())
+
+	for _, i := range []int{0, 5, 13, 159} {
+		id := tbl.randomIDInBucket(i)
+		if got := self.commonPrefixLen(id); got != i {
+			t.Fatalf("bucket %d: prefix len = %d", i, got)
+		}
+	}
+}
+
+func TestQuestionableNodes(t *testing.T) {
+	cfg := DefaultConfig()
+	tbl := newTable(ID{}, cfg)
+	tbl.insert(idWithPrefix(0x80), netip.MustParseAddrPort("10.0.0.1:1"))
+
+	if n := len(tbl.questionable(time.Now())); n != 0 {
+		t.Fatalf("fresh node reported questionable")
+	}
+	later := time.Now().Add(cfg.QuestionableAfter + time.Minute)
+	if n := len(tbl.questionable(later)); n != 1 {
+		t.Fatalf("stale node not reported questionable")
+	}
+}
@@ -0,0 +1,200 @@
+// Package engine brings up what every frontend of a session shares: the
+// profile, its config file, the logger and the client. The desktop app and
+// echod are thin layers over it that read the client's event Stream, as a
+// web UI or remote control would.
+package engine
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/prxssh/echo/internal/config"
+	"github.com/prxssh/echo/internal/events"
+	"github.com/prxssh/echo/internal/geoip"
+	"github.com/prxssh/echo/pkg/echo"
+	"github.com/prxssh/echo/pkg/logging"
+)
+
+// Options say how a frontend wants the engine brought up.
+type Options struct {
+	// ConfigPath is the config file read instead of the profile's
+	// config.yaml.
+	ConfigPath string
+	// Level is the lowest level logged to stdout.
+	Level slog.Level
+	// KeepLogs keeps the recent log entries for a log viewer, sampling
+	// those repeated on hot paths.
+	KeepLogs bool
+	// Strict fails on a config file that does not load or apply, rather
+	// than logging it and going on with the defaults.
+	Strict bool
+	// GeoIP are the country databases used until fresher ones have been
+	// downloaded.
+	GeoIP []geoip.Builtin
+}
+
+// Engine is a session run for its frontends.
+type Engine struct {
+	Profile config.Profile
+	// Path is the config file, which File was read from.
+	Path   string
+	File   *config.File
+	Client *echo.Client
+	// Logs holds the recent log entries if KeepLogs was set.
+	Logs *logging.RingHandler
+
+	cancel context.CancelFunc
+}
+
+// Open reads profile's config file, sets up logging as it says and creates
+// the client, which Start starts.
+func Open(profile config.Profile, opts Options) (*Engine, error) {
+	e := &Engine{
+		Profile: profile,
+		Path:    cmp.Or(opts.ConfigPath, profile.ConfigPath()),
+	}
+	file, loadErr := config.Load(e.Path)
+	if loadErr != nil {
+		file = &config.File{}
+	}
+	e.File = file
+	e.Logs = setupLogger(file.Log, opts)
+	if loadErr != nil {
+		if opts.Strict {
+			err := fmt.Errorf("engine: load config: %w", loadErr)
+			return nil, err
+		}
+		slog.Warn(
+			"loading config failed",
+			slog.String("path", e.Path),
+			slog.String("error", loadErr.Error()),
+		)
+	}
+
+	slog.Info(
+		"profile opened",
+		slog.String("name", profile.Name),
+		slog.String("dir", profile.Dir),
+		slog.Bool("portable", profile.Portable),
+	)
+
+	cfg := echo.DefaultConfig()
+	profile.Apply(&cfg)
+	cfg.GeoIP.Builtin = opts.GeoIP
+	if err := file.Apply(&cfg); err != nil {
+		if opts.Strict {
+			return nil, fmt.Errorf("engine: apply config: %w", err)
+		}
+		slog.Warn(
+			"applying config failed",
+			slog.String("error", err.Error()),
+		)
+	}
+	e.Client = echo.New(&cfg)
+	return e, nil
+}
+
+// Start starts the client, whose torrents run until Close or until ctx is
+// done. The file's settings then take the place of those saved from a
+// frontend, now and whenever it changes.
+func (e *Engine) Start(ctx context.Context) {
+	ctx, e.cancel = context.WithCancel(ctx)
+	if err := e.Client.Start(ctx); err != nil {
+		slog.Error(
+			"dht start failed",
+			slog.String("error", err.Error()),
+		)
+	}
+	reload := config.Reload(e.Client, e.File)
+	reload(e.File)
+	go config.Watch(ctx, e.Path, reload)
+}
+
+// Close saves the session and stops its torrents, giving up on those
+// still stopping once ctx is done.
+func (e *Engine) Close(ctx context.Context) {
+	if err := e.Client.Close(ctx); err != nil {
+		slog.Warn(
+			"shutdown incomplete",
+			slog.String("error", err.Error()),
+		)
+	}
+	if e.cancel != nil {
+		e.cancel()
+	}
+}
+
+// setupLogger logs to stdout, as JSON lines if the format is "json", and
+// with KeepLogs keeps the recent entries for a log viewer. If a file is
+// given, info and above are also appended to it as JSON lines. Secrets are
+// never logged, nor, in privacy mode, IP addresses. Records logged under a
+// torrent's context name its info-hash.
+func setupLogger(log config.Log, o Options) *logging.RingHandler {
+	opts := &logging.PrettyHandlerOptions{
+		SlogOpts: slog.HandlerOptions{
+			Level:     o.Level,
+			AddSource: true,
+		},
+		UseColor:          true,
+		ShowSource:        true,
+		TimeFormat:        time.RFC3339,
+		LevelWidth:        7,
+		FieldSeparator:    " | ",
+		DisableHTMLEscape: true,
+	}
+	var handler slog.Handler = logging.NewPrettyHandler(os.Stdout, opts)
+	if log.Format == "json" {
+		handler = logging.NewJSONHandler(os.Stdout, opts)
+	}
+	if path := log.File; path != "" {
+		f, err := os.OpenFile(
+			path,
+			os.O_CREATE|os.O_WRONLY|os.O_APPEND,
+			0o644,
+		)
+		if err == nil {
+			file := logging.NewLevelHandler(
+				slog.LevelInfo,
+				logging.NewJSONHandler(f, opts),
+			)
+			handler = logging.NewMultiHandler(handler, file)
+		} else {
+			defer slog.Warn(
+				"opening log file failed",
+				slog.String("path", path),
+				slog.String("error", err.Error()),
+			)
+		}
+	}
+	var logs *logging.RingHandler
+	if o.KeepLogs {
+		logs = logging.NewRingHandler(nil, nil)
+		// Debug and info records repeated on hot paths, such as those
+		// for each peer message, are sampled.
+		handler = logging.NewSamplingHandler(
+			logging.NewMultiHandler(handler, logs),
+			nil,
+		)
+	}
+	handler = logging.NewRedactHandler(
+		handler,
+		&logging.RedactOptions{
+			PeerAddrs: log.Privacy != nil && *log.Privacy,
+		},
+	)
+	handler = logging.NewContextHandler(handler, torrentAttrs)
+	slog.SetDefault(slog.New(handler))
+	return logs
+}
+
+func torrentAttrs(ctx context.Context) []slog.Attr {
+	t, ok := events.TorrentOf(ctx)
+	if !ok {
+		return nil
+	}
+	return []slog.Attr{slog.String("info_hash", t.InfoHash)}
+}
@@ -0,0 +1,67 @@
+// Package events carries notifications from the engine to whatever embeds
+// it, without the engine knowing whether that is the GUI or a daemon.
+package events
+
+import "context"
+
+// Sink receives every event emitted under a context that carries it.
+type Sink func(name string, data any)
+
+// Torrent names the torrent an event is about. Payloads of such events
+// carry it, so listeners can tell torrents apart.
+type Torrent struct {
+	InfoHash string `json:"infoHash"`
+	Name     string `json:"name"`
+}
+
+type (
+	sinkKey    struct{}
+	torrentKey struct{}
+)
+
+// WithSink returns a copy of ctx whose events go to sink.
+func WithSink(ctx context.Context, sink Sink) context.Context {
+	return context.WithValue(ctx, sinkKey{}, sink)
+}
+
+// WithTorrent returns a copy of ctx whose events are about t. Besides its
+// own name, each of them is emitted as "<name>:<info-hash>" for listeners
+// following a single torrent.
+func WithTorrent(ctx context.Context, t Torrent) context.Context {
+	return context.WithValue(ctx, torrentKey{}, t)
+}
+
+// TorrentOf returns the torrent the events emitted under ctx are about.
+func TorrentOf(ctx context.Context) (Torrent, bool) {
+	t, ok := ctx.Value(torrentKey{}).(Torrent)
+	return t, ok
+}
+
+// Topic is the name of an event typed with what it carries, so that those
+// emitting it and those listening agree on the payload. Each package
+// declares the topics it emits next to their payload types.
+type Topic[T any] string
+
+// Emit hands data to the sink in ctx under the topic's name.
+func (t Topic[T]) Emit(ctx context.Context, data T) {
+	Emit(ctx, string(t), data)
+}
+
+// Match returns the payload of the event named name, if it is one of the
+// topic's rather than another or one of its per-torrent copies.
+func (t Topic[T]) Match(name string, data any) (T, bool) {
+	v, ok := data.(T)
+	return v, ok && name == string(t)
+}
+
+// Emit hands an event to the sink in ctx, if there is one.
+func Emit(ctx context.Context, name string, data any) {
+	sink, ok := ctx.Value(sinkKey{}).(Sink)
+	if !ok {
+		return
+	}
+	sink(name, data)
+	if t, ok := TorrentOf(ctx); ok {
+		sink(name+":"+t.InfoHash, data)
+	}
+}
@@ -0,0 +1,489 @@
+// Package geoip keeps the country databases peers are looked up in up to
+// date: it downloads them to a data directory, checks them, downloads them
+// again once a month and swaps them into the running resolver.
+package geoip
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/oschwald/maxminddb-golang"
+	"github.com/prxssh/echo/internal/utils"
+)
+
+// maxDatabaseSize bounds how much of a download is read.
+const maxDatabaseSize = 256 << 20
+
+// Family says which addresses a country database covers. FamilyAuto
+// finds out by looking addresses of each family up in it, so separate
+// IPv4 and IPv6 databases and a single dual-stack one all work.
+type Family string
+
+const (
+	FamilyAuto Family = ""
+	FamilyBoth Family = "both"
+	FamilyIPv4 Family = "ipv4"
+	FamilyIPv6 Family = "ipv6"
+)
+
+// probes are well-known addresses of each family that every country
+// database places.
+var (
+	probes4 = []net.IP{net.IPv4(1, 1, 1, 1), net.IPv4(8, 8, 8, 8)}
+	probes6 = []net.IP{
+		net.ParseIP("2606:4700:4700::1111"),
+		net.ParseIP("2001:4860:4860::8888"),
+	}
+)
+
+// covers reports which families db, said to be of family f, has
+// addresses of.
+func covers(db *maxminddb.Reader, f Family) (v4, v6 bool) {
+	switch f {
+	case FamilyBoth:
+		return true, true
+	case FamilyIPv4:
+		return true, false
+	case FamilyIPv6:
+		return false, true
+	}
+	if db.Metadata.IPVersion == 4 {
+		return true, false
+	}
+	return places(db, probes4), places(db, probes6)
+}
+
+func places(db *maxminddb.Reader, ips []net.IP) bool {
+	for _, ip := range ips {
+		var record any
+		_, ok, err := db.LookupNetwork(ip, &record)
+		if err == nil && ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Kind says what a database maps addresses to.
+type Kind string
+
+const (
+	KindCountry Kind = ""
+	KindASN     Kind = "asn"
+)
+
+// Source is a database and where to download it.
+type Source struct {
+	// Name is the file the database is kept in, without ".mmdb".
+	Name string
+	// URL is where the database is downloaded from, with "{year}" and
+	// "{month}" standing for the current month. If that month's is not
+	// out yet, the last month's is downloaded.
+	URL string
+	// Archive is "gz" for a gzipped database or "tar.gz" for a tarball
+	// holding one. Empty goes by URL's extension.
+	Archive string
+	// ChecksumURL, if set, is where the download's SHA-256 is, as
+	// sha256sum prints it.
+	ChecksumURL string
+	Kind        Kind
+	// Family is only for country databases; ASN ones cover both.
+	Family Family
+}
+
+// DBIPCountry is DB-IP's free country database, covering IPv4 and IPv6.
+var DBIPCountry = Source{
+	Name: "dbip-country-lite",
+	URL: "https://download.db-ip.com/free/" +
+		"dbip-country-lite-{year}-{month}.mmdb.gz",
+}
+
+// DBIPASN is DB-IP's free ASN database, naming the network of peers.
+var DBIPASN = Source{
+	Name: "dbip-asn-lite",
+	URL: "https://download.db-ip.com/free/" +
+		"dbip-asn-lite-{year}-{month}.mmdb.gz",
+	Kind: KindASN,
+}
+
+// GeoLiteCountry is MaxMind's GeoLite2 country database, which needs the
+// license key of a free account.
+func GeoLiteCountry(licenseKey string) Source {
+	base := "https://download.maxmind.com/app/geoip_download" +
+		"?edition_id=GeoLite2-Country&license_key=" +
+		url.QueryEscape(licenseKey)
+	return Source{
+		Name:        "GeoLite2-Country",
+		URL:         base + "&suffix=tar.gz",
+		Archive:     "tar.gz",
+		ChecksumURL: base + "&suffix=tar.gz.sha256",
+	}
+}
+
+// Builtin is a country database built into the program.
+type Builtin struct {
+	Data   []byte
+	Family Family
+}
+
+type Config struct {
+	// Dir is where the databases are kept between runs.
+	Dir     string
+	Sources []Source
+	// Builtin are used until, or unless, a country database has been
+	// downloaded, so peers show their country on the first run and
+	// offline.
+	Builtin []Builtin
+	// Refresh is how old a database gets before it is downloaded again,
+	// and Retry how long to wait after a download fails.
+	Refresh time.Duration
+	Retry   time.Duration
+}
+
+func DefaultConfig() Config {
+	return Config{
+		Sources: []Source{DBIPCountry, DBIPASN},
+		Refresh: 30 * 24 * time.Hour,
+		Retry:   time.Hour,
+	}
+}
+
+// Manager downloads a Config's databases and swaps them into a resolver.
+type Manager struct {
+	cfg      Config
+	client   *http.Client
+	resolver *utils.IP2CountryResolver
+}
+
+func New(
+	cfg Config,
+	client *http.Client,
+	resolver *utils.IP2CountryResolver,
+) *Manager {
+	return &Manager{cfg: cfg, client: client, resolver: resolver}
+}
+
+// Run loads the databases already downloaded, then keeps them fresh until
+// ctx is done.
+func (m *Manager) Run(ctx context.Context) {
+	m.load()
+	if m.cfg.Dir == "" || len(m.cfg.Sources) == 0 {
+		return
+	}
+
+	for {
+		timer := time.NewTimer(m.Refresh(ctx))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// Refresh downloads the databases that are missing or older than
+// Config.Refresh, swaps them in, and returns how long until one is due
+// again.
+func (m *Manager) Refresh(ctx context.Context) time.Duration {
+	next := m.cfg.Refresh
+	updated := false
+	for _, src := range m.cfg.Sources {
+		age, ok := m.age(src)
+		if ok && age < m.cfg.Refresh {
+			next = min(next, m.cfg.Refresh-age)
+			continue
+		}
+		if err := m.Download(ctx, src); err != nil {
+			slog.Warn(
+				"geoip download failed",
+				slog.String("name", src.Name),
+				slog.String("error", err.Error()),
+			)
+			next = min(next, m.cfg.Retry)
+			continue
+		}
+		slog.Info(
+			"geoip database updated",
+			slog.String("name", src.Name),
+		)
+		updated = true
+	}
+	if updated {
+		m.load()
+	}
+	return next
+}
+
+func (m *Manager) path(src Source) string {
+	return filepath.Join(m.cfg.Dir, src.Name+".mmdb")
+}
+
+func (m *Manager) age(src Source) (time.Duration, bool) {
+	info, err := os.Stat(m.path(src))
+	if err != nil {
+		return 0, false
+	}
+	return time.Since(info.ModTime()), true
+}
+
+// load swaps the databases on disk into the resolver, keeping the ones it
+// has for any that can't be read.
+func (m *Manager) load() {
+	var v4, v6, asn *maxminddb.Reader
+	for _, src := range m.cfg.Sources {
+		if m.cfg.Dir == "" {
+			break
+		}
+		data, err := os.ReadFile(m.path(src))
+		if err != nil {
+			continue
+		}
+		db, err := maxminddb.FromBytes(data)
+		if err != nil {
+			slog.Warn(
+				"geoip database unreadable",
+				slog.String("name", src.Name),
+				slog.String("error", err.Error()),
+			)
+			continue
+		}
+		if src.Kind == KindASN {
+			if asn == nil {
+				asn = db
+			}
+			continue
+		}
+		has4, has6 := covers(db, src.Family)
+		if has4 && v4 == nil {
+			v4 = db
+		}
+		if has6 && v6 == nil {
+			v6 = db
+		}
+	}
+	if v4 == nil && v6 == nil {
+		v4, v6 = m.builtin()
+	}
+	if asn != nil {
+		m.resolver.SwapASN(asn)
+	}
+	if v4 != nil || v6 != nil {
+		m.resolver.Swap(v4, v6)
+	}
+}
+
+func (m *Manager) builtin() (v4, v6 *maxminddb.Reader) {
+	for _, b := range m.cfg.Builtin {
+		db, err := maxminddb.FromBytes(b.Data)
+		if err != nil {
+			slog.Warn(
+				"built-in geoip database unreadable",
+				slog.String("error", err.Error()),
+			)
+			continue
+		}
+		has4, has6 := covers(db, b.Family)
+		if has4 && v4 == nil {
+			v4 = db
+		}
+		if has6 && v6 == nil {
+			v6 = db
+		}
+	}
+	return v4, v6
+}
+
+// Download fetches src, checks it and replaces the copy on disk.
+func (m *Manager) Download(ctx context.Context, src Source) error {
+	now := time.Now().UTC()
+	data, err := m.fetch(ctx, expand(src.URL, now))
+	var status statusError
+	if errors.As(err, &status) && status == http.StatusNotFound &&
+		strings.Contains(src.URL, "{month}") {
+		data, err = m.fetch(ctx, expand(src.URL, now.AddDate(0, -1, 0)))
+	}
+	if err != nil {
+		return err
+	}
+
+	if src.ChecksumURL != "" {
+		if err := m.verifySum(ctx, src.ChecksumURL, data); err != nil {
+			return err
+		}
+	}
+	if data, err = unpack(src, data); err != nil {
+		return err
+	}
+	db, err := maxminddb.FromBytes(data)
+	if err != nil {
+		return fmt.Errorf("geoip: %s: %w", src.Name, err)
+	}
+	if err := check(db, src.Kind); err != nil {
+		return fmt.Errorf("geoip: %s: %w", src.Name, err)
+	}
+
+	if err := os.MkdirAll(m.cfg.Dir, 0o755); err != nil {
+		return err
+	}
+	tmp := m.path(src) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, m.path(src))
+}
+
+// check makes sure db is a database of the kind expected that addresses
+// can be looked up in.
+func check(db *maxminddb.Reader, kind Kind) error {
+	want := "country"
+	if kind == KindASN {
+		want = "asn"
+	}
+	typ := strings.ToLower(db.Metadata.DatabaseType)
+	if !strings.Contains(typ, want) {
+		return fmt.Errorf("not a %s database: %q", want, typ)
+	}
+	var record any
+	return db.Lookup(net.IPv4(1, 1, 1, 1), &record)
+}
+
+type statusError int
+
+func (e statusError) Error() string {
+	return "geoip: " + http.StatusText(int(e))
+}
+
+func (m *Manager) fetch(ctx context.Context, rawURL string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(
+		ctx,
+		http.MethodGet,
+		rawURL,
+		nil,
+	)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, statusError(resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxDatabaseSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxDatabaseSize {
+		return nil, errors.New("geoip: database too large")
+	}
+	return data, nil
+}
+
+func (m *Manager) verifySum(
+	ctx context.Context,
+	rawURL string,
+	data []byte,
+) error {
+	body, err := m.fetch(ctx, rawURL)
+	if err != nil {
+		return err
+	}
+	fields := strings.Fields(string(body))
+	if len(fields) == 0 {
+		return errors.New("geoip: empty checksum")
+	}
+	want, err := hex.DecodeString(fields[0])
+	if err != nil {
+		return fmt.Errorf("geoip: bad checksum: %w", err)
+	}
+	if sum := sha256.Sum256(data); !bytes.Equal(sum[:], want) {
+		return errors.New("geoip: checksum mismatch")
+	}
+	return nil
+}
+
+func expand(rawURL string, t time.Time) string {
+	return strings.NewReplacer(
+		"{year}", t.Format("2006"),
+		"{month}", t.Format("01"),
+	).Replace(rawURL)
+}
+
+// unpack returns the database in a download.
+func unpack(src Source, data []byte) ([]byte, error) {
+	archive := src.Archive
+	if archive == "" {
+		if u, err := url.Parse(src.URL); err == nil {
+			switch p := u.Path; {
+			case strings.HasSuffix(p, ".tar.gz"),
+				strings.HasSuffix(p, ".tgz"):
+				archive = "tar.gz"
+			case path.Ext(p) == ".gz":
+				archive = "gz"
+			}
+		}
+	}
+	if archive == "" {
+		return data, nil
+	}
+
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("geoip: %s: %w", src.Name, err)
+	}
+	defer zr.Close()
+
+	var r io.Reader = zr
+	if archive == "tar.gz" {
+		tr := tar.NewReader(zr)
+		for {
+			hdr, err := tr.Next()
+			if err == io.EOF {
+				return nil, fmt.Errorf(
+					"geoip: %s: no database in archive",
+					src.Name,
+				)
+			}
+			if err != nil {
+				return nil, fmt.Errorf(
+					"geoip: %s: %w",
+					src.Name,
+					err,
+				)
+			}
+			if hdr.Typeflag == tar.TypeReg &&
+				strings.HasSuffix(hdr.Name, ".mmdb") {
+				break
+			}
+		}
+		r = tr
+	}
+	data, err = io.ReadAll(io.LimitReader(r, maxDatabaseSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("geoip: %s: %w", src.Name, err)
+	}
+	if len(data) > maxDatabaseSize {
+		return nil, errors.New("geoip: database too large")
+	}
+	return data, nil
+}
@@ -0,0 +1,209 @@
+package geoip
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/oschwald/maxminddb-golang"
+	"github.com/prxssh/echo/internal/utils"
+)
+
+func testDatabase(t *testing.T) []byte {
+	t.Helper()
+	data, err := os.ReadFile("../../data/dbip-country-ipv4.mmdb")
+	if err != nil {
+		t.Skip("no test database:", err)
+	}
+	return data
+}
+
+func gzipped(t *testing.T, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func tarball(t *testing.T, name string, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, f := range []struct {
+		name string
+		data []byte
+	}{
+		{"GeoLite2-Country_20250101/README.txt", []byte("readme")},
+		{name, data},
+	} {
+		err := tw.WriteHeader(&tar.Header{
+			Name:     f.name,
+			Typeflag: tar.TypeReg,
+			Mode:     0o644,
+			Size:     int64(len(f.data)),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write(f.data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return gzipped(t, buf.Bytes())
+}
+
+func TestRefreshDownloadsAndSwaps(t *testing.T) {
+	db := testDatabase(t)
+	last := time.Now().UTC().AddDate(0, -1, 0).Format("2006-01")
+	mux := http.NewServeMux()
+	// This month's isn't out yet, so last month's is used.
+	mux.HandleFunc(
+		"/country-"+last+".mmdb.gz",
+		func(w http.ResponseWriter, _ *http.Request) {
+			w.Write(gzipped(t, db))
+		},
+	)
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	cfg := DefaultConfig()
+	cfg.Dir = t.TempDir()
+	cfg.Sources = []Source{{
+		Name:   "country",
+		URL:    srv.URL + "/country-{year}-{month}.mmdb.gz",
+		Family: FamilyIPv4,
+	}}
+	resolver := utils.NewIP2CountryResolver()
+	m := New(cfg, srv.Client(), resolver)
+
+	if next := m.Refresh(context.Background()); next <= cfg.Retry {
+		t.Fatalf("next refresh in %v after a download", next)
+	}
+	if code, _, _ := resolver.CountryCode("8.8.8.8"); code == "" {
+		t.Fatal("no country after the database was swapped in")
+	}
+	got, err := os.ReadFile(filepath.Join(cfg.Dir, "country.mmdb"))
+	if err != nil || !bytes.Equal(got, db) {
+		t.Fatalf("database not saved: %v", err)
+	}
+}
+
+func TestDownloadChecksTarball(t *testing.T) {
+	db := testDatabase(t)
+	archive := tarball(t, "GeoLite2-Country_20250101/country.mmdb", db)
+	sum := sha256.Sum256(archive)
+	checksum := hex.EncodeToString(sum[:]) + "  country.tar.gz\n"
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/db", func(w http.ResponseWriter, _ *http.Request) {
+		w.Write(archive)
+	})
+	mux.HandleFunc("/sum", func(w http.ResponseWriter, _ *http.Request) {
+		w.Write([]byte(checksum))
+	})
+	mux.HandleFunc("/bad", func(w http.ResponseWriter, _ *http.Request) {
+		w.Write([]byte(hex.EncodeToString(make([]byte, 32))))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	cfg := DefaultConfig()
+	cfg.Dir = t.TempDir()
+	m := New(cfg, srv.Client(), utils.NewIP2CountryResolver())
+	src := Source{
+		Name:        "country",
+		URL:         srv.URL + "/db",
+		Archive:     "tar.gz",
+		ChecksumURL: srv.URL + "/sum",
+	}
+	if err := m.Download(context.Background(), src); err != nil {
+		t.Fatal(err)
+	}
+
+	src.ChecksumURL = srv.URL + "/bad"
+	if err := m.Download(context.Background(), src); err == nil {
+		t.Fatal("download with a wrong checksum succeeded")
+	}
+}
+
+func TestDownloadRejectsGarbage(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, _ *http.Request) {
+			w.Write([]byte("not a database"))
+		},
+	))
+	defer srv.Close()
+
+	cfg := DefaultConfig()
+	cfg.Dir = t.TempDir()
+	m := New(cfg, srv.Client(), utils.NewIP2CountryResolver())
+	src := Source{Name: "country", URL: srv.URL + "/country.mmdb"}
+	if err := m.Download(context.Background(), src); err == nil {
+		t.Fatal("garbage accepted as a database")
+	}
+	_, err := os.Stat(filepath.Join(cfg.Dir, "country.mmdb"))
+	if err == nil {
+		t.Fatal("garbage saved")
+	}
+}
+
+func TestRunFallsBackToBuiltin(t *testing.T) {
+	v4 := testDatabase(t)
+	v6, err := os.ReadFile("../../data/dbip-country-ipv6.mmdb")
+	if err != nil {
+		t.Skip("no test database:", err)
+	}
+	// The families are found out rather than given.
+	cfg := Config{Builtin: []Builtin{{Data: v6}, {Data: v4}}}
+	resolver := utils.NewIP2CountryResolver()
+	New(cfg, http.DefaultClient, resolver).Run(context.Background())
+
+	for _, ip := range []string{"8.8.8.8", "2001:4860::8888"} {
+		if code, _, _ := resolver.CountryCode(ip); code == "" {
+			t.Errorf("no country for %s", ip)
+		}
+	}
+}
+
+func TestCovers(t *testing.T) {
+	v4, err := maxminddb.FromBytes(testDatabase(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if has4, has6 := covers(v4, FamilyAuto); !has4 || has6 {
+		t.Errorf("IPv4 database covers v4 %v, v6 %v", has4, has6)
+	}
+	if has4, has6 := covers(v4, FamilyBoth); !has4 || !has6 {
+		t.Errorf("declared family ignored: v4 %v, v6 %v", has4, has6)
+	}
+
+	data, err := os.ReadFile("../../data/dbip-country-ipv6.mmdb")
+	if err != nil {
+		t.Skip("no test database:", err)
+	}
+	v6, err := maxminddb.FromBytes(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if has4, has6 := covers(v6, FamilyAuto); has4 || !has6 {
+		t.Errorf("IPv6 database covers v4 %v, v6 %v", has4, has6)
+	}
+}
@@ -0,0 +1,37 @@
+package peer
+
+// MaxBlockLength is the largest block we request or serve. Peers asking for
+// more are violating the de-facto protocol limit and get disconnected.
+const MaxBlockLength = 16 << 10
+
+// PieceBounds describes the piece geometry of a torrent and is used to
+// validate the index/begin/length triples carried by block messages.
+type PieceBounds struct {
+	Pieces      uint32
+	PieceLength uint32
+	TotalLength uint64
+	MaxBlock    uint32
+}
+
+func (b PieceBounds) PieceSize(index uint32) uint32 {
+	if index >= b.Pieces {
+		return 0
+	}
+	if index < b.Pieces-1 {
+		return b.PieceLength
+	}
+
+	last := b.TotalLength - uint64(b.Pieces-1)*uint64(b.PieceLength)
+	return uint32(last)
+}
+
+func (b PieceBounds) validBlock(index, begin, length uint32) bool {
+	if index >= b.Pieces || length == 0 {
+		return false
+	}
+	if b.MaxBlock > 0 && length > b.MaxBlock {
+		return false
+	}
+
+	return uint64(begin)+uint64(length) <= uint64(b.PieceSize(index))
+}
@@ -0,0 +1,41 @@
+package peer
+
+import "testing"
+
+// testBounds are four 32 KiB pieces of a 100 KiB torrent, the last piece
+// holding the 4 KiB left over.
+var testBounds = PieceBounds{
+	Pieces:      4,
+	PieceLength: 32 << 10,
+	TotalLength: 100 << 10,
+	MaxBlock:    MaxBlockLength,
+}
+
+func TestPieceSize(t *testing.T) {
+	tests := []struct {
+		index uint32
+		want  uint32
+	}{
+		{0, 32 << 10},
+		{2, 32 << 10},
+		{3, 4 << 10},
+		{4, 0},
+		{1 << 31, 0},
+	}
+	for _, tt := range tests {
+		if got := testBounds.PieceSize(tt.index); got != tt.want {
+			t.Errorf(
+				"PieceSize(%d) = %d; want %d",
+				tt.index,
+				got,
+				tt.want,
+			)
+		}
+	}
+
+	exact := testBounds
+	exact.TotalLength = 128 << 10
+	if got := exact.PieceSize(3); got != 32<<10 {
+		t.Errorf("full last piece: PieceSize(3) = %d", got)
+	}
+}
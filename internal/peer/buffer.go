@@ -0,0 +1,52 @@
+package peer
+
+import (
+	"math/bits"
+	"sync"
+)
+
+// Wire buffers are pooled in power-of-two size classes so that the common
+// message shapes (control messages, 16 KiB blocks) are recycled instead of
+// being allocated per read.
+const (
+	minBufferShift = 6  // 64 B
+	maxBufferShift = 18 // 256 KiB
+)
+
+var bufferPools [maxBufferShift - minBufferShift + 1]sync.Pool
+
+func sizeClass(n int) (int, bool) {
+	if n <= 1<<minBufferShift {
+		return 0, true
+	}
+
+	shift := bits.Len(uint(n - 1))
+	if shift > maxBufferShift {
+		return 0, false
+	}
+
+	return shift - minBufferShift, true
+}
+
+func getBuffer(n int) []byte {
+	class, ok := sizeClass(n)
+	if !ok {
+		return make([]byte, n)
+	}
+
+	if bp, ok := bufferPools[class].Get().(*[]byte); ok {
+		return (*bp)[:n]
+	}
+
+	return make([]byte, n, 1<<(class+minBufferShift))
+}
+
+func putBuffer(b []byte) {
+	class, ok := sizeClass(cap(b))
+	if !ok || cap(b) != 1<<(class+minBufferShift) {
+		return
+	}
+
+	b = b[:0]
+	bufferPools[class].Put(&b)
+}
@@ -0,0 +1,231 @@
+package peer
+
+import (
+	"sync"
+	"time"
+)
+
+type Source uint8
+
+const (
+	SourceTracker Source = iota
+	SourceDHT
+	SourcePEX
+	SourceIncoming
+)
+
+func (s Source) String() string {
+	switch s {
+	case SourceTracker:
+		return "tracker"
+	case SourceDHT:
+		return "dht"
+	case SourcePEX:
+		return "pex"
+	case SourceIncoming:
+		return "incoming"
+	default:
+		return "unknown"
+	}
+}
+
+type candidate struct {
+	addr        string
+	source      Source
+	lastSeen    time.Time
+	failures    int
+	nextAttempt time.Time
+	dialing     bool
+	connected   bool
+}
+
+// score ranks candidates for dialing: addresses that have never failed come
+// first, then the most recently advertised ones. Higher is better.
+func (c *candidate) score(now time.Time) float64 {
+	age := now.Sub(c.lastSeen).Minutes()
+	return -float64(c.failures)*60 - age
+}
+
+// candidateSet is the swarm-wide pool of addresses we know about but are not
+// necessarily connected to. It tracks where each address came from, when it
+// was last advertised and how often dialing it failed, so that the dialers
+// can pick the most promising address and back off from dead ones.
+type candidateSet struct {
+	cfg Config
+
+	mu     sync.Mutex
+	byAddr map[string]*candidate
+	notify chan struct{}
+}
+
+func newCandidateSet(cfg Config) *candidateSet {
+	return &candidateSet{
+		cfg:    cfg,
+		byAddr: make(map[string]*candidate),
+		notify: make(chan struct{}, max(cfg.DialWorkers, 1)),
+	}
+}
+
+func (cs *candidateSet) add(source Source, addrs []string) {
+	now := time.Now()
+
+	added := 0
+	cs.mu.Lock()
+	for _, addr := range addrs {
+		if c, ok := cs.byAddr[addr]; ok {
+			c.lastSeen = now
+			continue
+		}
+		if len(cs.byAddr) >= cs.cfg.MaxCandidates &&
+			!cs.evictLocked(now) {
+			break
+		}
+
+		cs.byAddr[addr] = &candidate{
+			addr:     addr,
+			source:   source,
+			lastSeen: now,
+		}
+		added++
+	}
+	cs.mu.Unlock()
+
+	for ; added > 0; added-- {
+		if !cs.wake() {
+			break
+		}
+	}
+}
+
+// next returns the best candidate that is eligible for dialing right now and
+// marks it as being dialed.
+func (cs *candidateSet) next() (string, bool) {
+	now := time.Now()
+
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	var best *candidate
+	for _, c := range cs.byAddr {
+		if c.dialing || c.connected || now.Before(c.nextAttempt) {
+			continue
+		}
+		if best == nil || c.score(now) > best.score(now) {
+			best = c
+		}
+	}
+	if best == nil {
+		return "", false
+	}
+
+	best.dialing = true
+	return best.addr, true
+}
+
+func (cs *candidateSet) dialFailed(addr string) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	c, ok := cs.byAddr[addr]
+	if !ok {
+		return
+	}
+
+	c.dialing = false
+	c.failures++
+	if c.failures >= cs.cfg.MaxDialFailures {
+		delete(cs.byAddr, addr)
+		return
+	}
+
+	backoff := cs.cfg.DialBackoff << (c.failures - 1)
+	if backoff <= 0 || backoff > cs.cfg.MaxDialBackoff {
+		backoff = cs.cfg.MaxDialBackoff
+	}
+	c.nextAttempt = time.Now().Add(backoff)
+}
+
+func (cs *candidateSet) connected(addr string) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	if c, ok := cs.byAddr[addr]; ok {
+		c.dialing = false
+		c.connected = true
+		c.failures = 0
+	}
+}
+
+func (cs *candidateSet) disconnected(addr string) {
+	cs.mu.Lock()
+	if c, ok := cs.byAddr[addr]; ok {
+		c.dialing = false
+		c.connected = false
+		c.nextAttempt = time.Now().Add(cs.cfg.ReconnectDelay)
+	}
+	cs.mu.Unlock()
+
+	cs.wake()
+}
+
+// evictLocked drops the worst idle candidate to make room for a new one.
+func (cs *candidateSet) evictLocked(now time.Time) bool {
+	var worst *candidate
+	for _, c := range cs.byAddr {
+		if c.dialing || c.connected {
+			continue
+		}
+		if worst == nil || c.score(now) < worst.score(now) {
+			worst = c
+		}
+	}
+	if worst == nil {
+		return false
+	}
+
+	delete(cs.byAddr, worst.addr)
+	return true
+}
+
+// wake nudges one idle dial worker and reports whether one could be reached.
+func (cs *candidateSet) wake() bool {
+	select {
+	case cs.notify <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+// DialStats sums up the addresses waiting to be dialed.
+type DialStats struct {
+	Candidates int `json:"candidates"`
+	// Ready can be dialed now; BackingOff only after an earlier failure
+	// or disconnection has been waited out.
+	Ready      int `json:"ready"`
+	BackingOff int `json:"backingOff"`
+	Dialing    int `json:"dialing"`
+	Connected  int `json:"connected"`
+}
+
+func (cs *candidateSet) stats() DialStats {
+	now := time.Now()
+
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	s := DialStats{Candidates: len(cs.byAddr)}
+	for _, c := range cs.byAddr {
+		switch {
+		case c.connected:
+			s.Connected++
+		case c.dialing:
+			s.Dialing++
+		case now.Before(c.nextAttempt):
+			s.BackingOff++
+		default:
+			s.Ready++
+		}
+	}
+	return s
+}
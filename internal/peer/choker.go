@@ -0,0 +1,147 @@
+package peer
+
+import (
+	"context"
+	"math/rand/v2"
+	"sort"
+	"time"
+)
+
+type SeedChokePolicy uint8
+
+const (
+	// SeedFastestUpload unchokes the peers we upload to fastest, maximising
+	// how quickly our data spreads through the swarm.
+	SeedFastestUpload SeedChokePolicy = iota
+	// SeedRoundRobin rotates upload slots so every interested peer gets a
+	// turn, regardless of its link speed.
+	SeedRoundRobin
+)
+
+// transferRates holds the per-round rate estimates. It is only touched by the
+// choker goroutine.
+type transferRates struct {
+	lastDownloaded uint64
+	lastUploaded   uint64
+	download       float64
+	upload         float64
+	lastUnchoked   time.Time
+}
+
+func (p *Peer) sampleRates(elapsed time.Duration) {
+	down, up := p.downloaded.Load(), p.uploaded.Load()
+	secs := elapsed.Seconds()
+	if secs <= 0 {
+		return
+	}
+
+	p.rates.download = float64(down-p.rates.lastDownloaded) / secs
+	p.rates.upload = float64(up-p.rates.lastUploaded) / secs
+	p.rates.lastDownloaded, p.rates.lastUploaded = down, up
+}
+
+// SetSeeding switches the choker between the downloader policy (reciprocate
+// peers that upload to us fastest) and the seeding policy.
+func (m *Manager) SetSeeding(seeding bool) {
+	m.seeding.Store(seeding)
+}
+
+func (m *Manager) runChoker(ctx context.Context) {
+	ticker := time.NewTicker(m.cfg.ChokeInterval)
+	defer ticker.Stop()
+
+	last := time.Now()
+	round := 0
+	var optimistic *Peer
+
+	for {
+		select {
+		case <-m.done:
+			return
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			elapsed := now.Sub(last)
+			last = now
+
+			// Rotate the optimistic slot every third round (~30s with the
+			// default interval), as in the reference implementation.
+			if round%3 == 0 {
+				optimistic = nil
+			}
+			round++
+
+			optimistic = m.chokeRound(elapsed, optimistic)
+		}
+	}
+}
+
+func (m *Manager) chokeRound(elapsed time.Duration, optimistic *Peer) *Peer {
+	m.peerMut.RLock()
+	peers := make([]*Peer, 0, len(m.peers))
+	for _, p := range m.peers {
+		peers = append(peers, p)
+	}
+	m.peerMut.RUnlock()
+
+	interested := peers[:0:0]
+	for _, p := range peers {
+		p.sampleRates(elapsed)
+		if p.peerInterested.Load() {
+			interested = append(interested, p)
+		}
+	}
+
+	seeding := m.seeding.Load()
+	sort.SliceStable(interested, func(i, j int) bool {
+		a, b := interested[i], interested[j]
+		switch {
+		case !seeding:
+			return a.rates.download > b.rates.download
+		case m.cfg.SeedPolicy == SeedRoundRobin:
+			return a.rates.lastUnchoked.Before(b.rates.lastUnchoked)
+		default:
+			return a.rates.upload > b.rates.upload
+		}
+	})
+
+	slots := m.cfg.UploadSlots
+	if slots <= 0 {
+		slots = 1
+	}
+	regular := slots - 1
+	if regular > len(interested) {
+		regular = len(interested)
+	}
+
+	unchoke := make(map[*Peer]struct{}, slots)
+	for _, p := range interested[:regular] {
+		unchoke[p] = struct{}{}
+	}
+
+	rest := interested[regular:]
+	if optimistic != nil && !optimistic.peerInterested.Load() {
+		optimistic = nil
+	}
+	if _, taken := unchoke[optimistic]; taken || optimistic == nil {
+		optimistic = nil
+		if len(rest) > 0 {
+			optimistic = rest[rand.IntN(len(rest))]
+		}
+	}
+	if optimistic != nil {
+		unchoke[optimistic] = struct{}{}
+	}
+
+	now := time.Now()
+	for _, p := range peers {
+		if _, ok := unchoke[p]; ok {
+			p.unchoke()
+			p.rates.lastUnchoked = now
+			continue
+		}
+		p.choke()
+	}
+
+	return optimistic
+}
@@ -0,0 +1,54 @@
+package peer
+
+import (
+	"cmp"
+	"slices"
+)
+
+// CountryStats sums up the connected peers from one country. Peers whose
+// country is unknown are counted under an empty CountryCode.
+type CountryStats struct {
+	CountryCode string `json:"isoCode"`
+	Country     string `json:"country"`
+	Flag        string `json:"flag"`
+	Peers       int    `json:"peers"`
+	// Downloaded and Uploaded total what was exchanged with the peers
+	// currently connected.
+	Downloaded uint64 `json:"downloaded"`
+	Uploaded   uint64 `json:"uploaded"`
+}
+
+// Countries breaks the connected peers down by country, most peers first.
+func (m *Manager) Countries() []CountryStats {
+	return countries(m.Peers())
+}
+
+func countries(infos []Info) []CountryStats {
+	byCode := make(map[string]*CountryStats)
+	for _, info := range infos {
+		s, ok := byCode[info.CountryCode]
+		if !ok {
+			s = &CountryStats{
+				CountryCode: info.CountryCode,
+				Country:     info.Country,
+				Flag:        info.Flag,
+			}
+			byCode[info.CountryCode] = s
+		}
+		s.Peers++
+		s.Downloaded += info.Downloaded
+		s.Uploaded += info.Uploaded
+	}
+
+	out := make([]CountryStats, 0, len(byCode))
+	for _, s := range byCode {
+		out = append(out, *s)
+	}
+	slices.SortFunc(out, func(a, b CountryStats) int {
+		if c := cmp.Compare(b.Peers, a.Peers); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.CountryCode, b.CountryCode)
+	})
+	return out
+}
@@ -4,21 +4,50 @@ import (
 	"context"
 	"net"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/prxssh/echo/internal/events"
 	"github.com/prxssh/echo/internal/utils"
-	"github.com/wailsapp/wails/v2/pkg/runtime"
 )
 
+// Locator tells where peers are; utils.IP2CountryResolver is one.
+type Locator interface {
+	Lookup(ip string) (utils.Location, error)
+}
+
 type peerMetadata struct {
 	Addr        string `json:"addr"`
 	CountryCode string `json:"isoCode"`
 	CountryName string `json:"country"`
 	Flag        string `json:"flag"`
+	ASN         uint32 `json:"asn,omitempty"`
+	Org         string `json:"org,omitempty"`
 }
 
-type peerMessageEvent struct {
+// peerEvent names the peer and torrent a "peer:msgs" entry is about.
+type peerEvent struct {
+	events.Torrent
 	peerMetadata
-	Type string `json:"type"`
+}
+
+const topicMessages events.Topic[[]*peerMessageEvent] = "peer:msgs"
+
+// peerMessageEvent sums up the messages a peer sent between two flushes
+// of the "peer:msgs" event: the type of the last one, and how many of each
+// type arrived.
+type peerMessageEvent struct {
+	peerEvent
+	Type   string         `json:"type"`
+	Counts map[string]int `json:"counts"`
+}
+
+// messageBatch collects peer messages for the next "peer:msgs" event, so
+// the embedder gets a few batched events a second rather than one per wire
+// message.
+type messageBatch struct {
+	mu      sync.Mutex
+	pending map[*Peer]*peerMessageEvent
 }
 
 func (p *Peer) metadata() peerMetadata {
@@ -26,31 +55,88 @@ func (p *Peer) metadata() peerMetadata {
 	if err != nil {
 		host = p.Addr()
 	}
-	code, name, _ := utils.IP2Country.CountryCode(host)
+	var loc utils.Location
+	if p.m.locator != nil {
+		loc, _ = p.m.locator.Lookup(host)
+	}
 
 	return peerMetadata{
 		Addr:        p.Addr(),
-		CountryCode: code,
-		CountryName: name,
-		Flag:        countryFlag(code),
+		CountryCode: loc.CountryCode,
+		CountryName: loc.CountryName,
+		Flag:        countryFlag(loc.CountryCode),
+		ASN:         loc.ASN,
+		Org:         loc.Org,
 	}
 }
 
-func (p *Peer) emitStarted(ctx context.Context) {
-	runtime.EventsEmit(ctx, "peers:started", p.metadata())
+func (p *Peer) event(ctx context.Context) peerEvent {
+	scope, _ := events.TorrentOf(ctx)
+	return peerEvent{Torrent: scope, peerMetadata: p.metadata()}
 }
 
-func (p *Peer) emitStopped(ctx context.Context) {
-	runtime.EventsEmit(ctx, "peers:stopped", p.metadata())
+// emitMessage notes a message from the peer for the next batch, or emits
+// it on its own if batching is off.
+func (p *Peer) emitMessage(ctx context.Context, typ string) {
+	if p.m.cfg.EventInterval <= 0 {
+		ev := &peerMessageEvent{
+			peerEvent: p.event(ctx),
+			Type:      typ,
+			Counts:    map[string]int{typ: 1},
+		}
+		topicMessages.Emit(ctx, []*peerMessageEvent{ev})
+		return
+	}
+
+	b := &p.m.messages
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	ev, ok := b.pending[p]
+	if !ok {
+		ev = &peerMessageEvent{Counts: make(map[string]int)}
+		b.pending[p] = ev
+	}
+	ev.Type = typ
+	ev.Counts[typ]++
 }
 
-func (p *Peer) emitMessage(ctx context.Context, typ string) {
-	payload := peerMessageEvent{
-		peerMetadata: p.metadata(),
-		Type:         typ,
+// flushMessages emits what the batch collected since the last flush.
+// Country lookups happen here, once per peer, rather than per message.
+func (m *Manager) flushMessages(ctx context.Context) {
+	b := &m.messages
+	b.mu.Lock()
+	pending := b.pending
+	b.pending = make(map[*Peer]*peerMessageEvent)
+	b.mu.Unlock()
+
+	if len(pending) == 0 {
+		return
+	}
+	batch := make([]*peerMessageEvent, 0, len(pending))
+	for p, ev := range pending {
+		ev.peerEvent = p.event(ctx)
+		batch = append(batch, ev)
 	}
+	topicMessages.Emit(ctx, batch)
+}
 
-	runtime.EventsEmit(ctx, "peer:msg", payload)
+// runMessageEvents flushes the message batch every EventInterval.
+func (m *Manager) runMessageEvents(ctx context.Context) {
+	ticker := time.NewTicker(m.cfg.EventInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-m.done:
+			m.flushMessages(ctx)
+			return
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.flushMessages(ctx)
+		}
+	}
 }
 
 func countryFlag(code string) string {
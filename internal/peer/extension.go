@@ -0,0 +1,162 @@
+package peer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/prxssh/echo/internal/bencode"
+)
+
+// extHandshakeID is the extended message id of the BEP 10 handshake.
+const extHandshakeID = 0
+
+const clientVersion = "Echo 0.1"
+
+// ExtensionHandshake is the bencoded dictionary exchanged right after the
+// BitTorrent handshake when both sides set the extension protocol bit.
+type ExtensionHandshake struct {
+	M          map[string]int64
+	Version    string
+	Port       uint16
+	UploadOnly bool
+	// MetadataSize is the size of the info dict, for BEP 9.
+	MetadataSize int64
+}
+
+func MessageExtendedHandshake(h *ExtensionHandshake) (*Message, error) {
+	m := make(map[string]any, len(h.M))
+	for name, id := range h.M {
+		m[name] = id
+	}
+
+	dict := map[string]any{"m": m}
+	if h.Version != "" {
+		dict["v"] = h.Version
+	}
+	if h.Port != 0 {
+		dict["p"] = int64(h.Port)
+	}
+	if h.UploadOnly {
+		dict["upload_only"] = int64(1)
+	}
+	if h.MetadataSize > 0 {
+		dict["metadata_size"] = h.MetadataSize
+	}
+
+	var buf bytes.Buffer
+	buf.WriteByte(extHandshakeID)
+	if err := bencode.NewEncoder(&buf).Encode(dict); err != nil {
+		return nil, err
+	}
+
+	return &Message{ID: MsgExtended, Payload: buf.Bytes()}, nil
+}
+
+func (m *Message) ParseExtended() (id uint8, payload []byte, ok bool) {
+	if len(m.Payload) < 1 {
+		return 0, nil, false
+	}
+
+	return m.Payload[0], m.Payload[1:], true
+}
+
+func parseExtensionHandshake(payload []byte) (*ExtensionHandshake, error) {
+	decoded, err := bencode.NewDecoder(bytes.NewReader(payload)).Decode()
+	if err != nil {
+		return nil, fmt.Errorf("extension handshake: %w", err)
+	}
+	dict, ok := decoded.(map[string]any)
+	if !ok {
+		return nil, errors.New("extension handshake: not a dictionary")
+	}
+
+	h := &ExtensionHandshake{M: make(map[string]int64)}
+	if m, ok := dict["m"].(map[string]any); ok {
+		for name, v := range m {
+			if id, ok := v.(int64); ok {
+				h.M[name] = id
+			}
+		}
+	}
+	if v, ok := dict["v"].(string); ok {
+		h.Version = v
+	}
+	if p, ok := dict["p"].(int64); ok && p > 0 && p <= 65535 {
+		h.Port = uint16(p)
+	}
+	if u, ok := dict["upload_only"].(int64); ok {
+		h.UploadOnly = u != 0
+	}
+	if n, ok := dict["metadata_size"].(int64); ok {
+		h.MetadataSize = n
+	}
+
+	return h, nil
+}
+
+// sendExtensionHandshake advertises our extensions, including the BEP 21
+// upload_only flag when we are a partial seed.
+func (p *Peer) sendExtensionHandshake() {
+	if !p.extensions {
+		return
+	}
+
+	m := map[string]int64{}
+	if p.m.metadata != nil {
+		m["ut_metadata"] = utMetadataID
+	}
+	msg, err := MessageExtendedHandshake(&ExtensionHandshake{
+		M:          m,
+		Version:    p.m.version,
+		UploadOnly: p.m.uploadOnly.Load(),
+	})
+	if err != nil {
+		return
+	}
+	p.Send(msg)
+}
+
+func (p *Peer) handleExtended(message *Message) error {
+	id, payload, ok := message.ParseExtended()
+	if !ok {
+		return errMalformed(message)
+	}
+	switch id {
+	case extHandshakeID:
+	case utMetadataID:
+		return p.handleMetadata(payload)
+	default:
+		return nil
+	}
+
+	h, err := parseExtensionHandshake(payload)
+	if err != nil {
+		return err
+	}
+	p.peerUploadOnly.Store(h.UploadOnly)
+	if h.Version != "" {
+		p.peerClient.Store(&h.Version)
+	}
+	p.peerMetadataID.Store(h.M["ut_metadata"])
+	if p.m.metadata != nil && p.m.metadata.setSize(h.MetadataSize) {
+		p.requestMetadata()
+	}
+
+	return nil
+}
+
+// SetUploadOnly marks us as a partial seed (BEP 21): every connected peer that
+// speaks the extension protocol gets a fresh handshake carrying upload_only.
+func (m *Manager) SetUploadOnly(uploadOnly bool) {
+	if m.uploadOnly.Swap(uploadOnly) == uploadOnly {
+		return
+	}
+
+	m.peerMut.RLock()
+	defer m.peerMut.RUnlock()
+
+	for _, p := range m.peers {
+		p.sendExtensionHandshake()
+	}
+}
@@ -9,18 +9,39 @@ import (
 
 type Handshake struct {
 	Pstr     string
+	Reserved [szReservedBytes]byte
 	InfoHash [sha1.Size]byte
 	PeerID   [sha1.Size]byte
 }
 
 const szReservedBytes = 8
 
+// reservedExtensionProtocol is the BEP 10 bit (20th from the right) and
+// reservedDHT the BEP 5 bit (last bit).
+const (
+	reservedExtensionByte = 5
+	reservedExtensionBit  = 0x10
+	reservedDHTByte       = 7
+	reservedDHTBit        = 0x01
+)
+
 func NewHandshake(infoHash, peerID [sha1.Size]byte) *Handshake {
-	return &Handshake{
+	h := &Handshake{
 		Pstr:     "BitTorrent protocol",
 		InfoHash: infoHash,
 		PeerID:   peerID,
 	}
+	h.Reserved[reservedExtensionByte] |= reservedExtensionBit
+
+	return h
+}
+
+func (h *Handshake) SupportsExtensions() bool {
+	return h.Reserved[reservedExtensionByte]&reservedExtensionBit != 0
+}
+
+func (h *Handshake) SupportsDHT() bool {
+	return h.Reserved[reservedDHTByte]&reservedDHTBit != 0
 }
 
 func (h *Handshake) Serialize() []byte {
@@ -29,30 +50,32 @@ func (h *Handshake) Serialize() []byte {
 	buf[0] = byte(len(h.Pstr))
 	offset := 1
 	offset += copy(buf[offset:], []byte(h.Pstr))
-	offset += copy(buf[offset:], make([]byte, szReservedBytes))
+	offset += copy(buf[offset:], h.Reserved[:])
 	offset += copy(buf[offset:], h.InfoHash[:])
 	offset += copy(buf[offset:], h.PeerID[:])
 
 	return buf
 }
 
-func (h *Handshake) Perform(w io.ReadWriter) error {
+// Perform sends our handshake and returns the one the remote answered with.
+func (h *Handshake) Perform(w io.ReadWriter) (*Handshake, error) {
 	_, err := w.Write(h.Serialize())
 	if err != nil {
-		return err
+		return nil, err
 	}
-	res, err := readHanshake(w)
+	res, err := ReadHandshake(w)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	if !bytes.Equal(h.InfoHash[:], res.InfoHash[:]) {
-		return errors.New("handshake: info hash mismatch")
+		return nil, errors.New("handshake: info hash mismatch")
 	}
-	return nil
+	return res, nil
 }
 
-func readHanshake(r io.Reader) (*Handshake, error) {
+// ReadHandshake reads the handshake a peer opens or answers with.
+func ReadHandshake(r io.Reader) (*Handshake, error) {
 	sizeBuf := make([]byte, 1)
 	_, err := io.ReadFull(r, sizeBuf)
 	if err != nil {
@@ -74,9 +97,12 @@ func readHanshake(r io.Reader) (*Handshake, error) {
 	)
 	copy(peerID[:], handshakeBuf[pstrlen+szReservedBytes+sha1.Size:])
 
-	return &Handshake{
+	h := &Handshake{
 		Pstr:     string(handshakeBuf[0:pstrlen]),
 		InfoHash: infoHash,
 		PeerID:   peerID,
-	}, nil
+	}
+	copy(h.Reserved[:], handshakeBuf[pstrlen:pstrlen+szReservedBytes])
+
+	return h, nil
 }
@@ -0,0 +1,146 @@
+package peer
+
+import "strings"
+
+// Info describes a connected peer for display.
+type Info struct {
+	Addr        string `json:"addr"`
+	Client      string `json:"client,omitempty"`
+	CountryCode string `json:"isoCode,omitempty"`
+	Country     string `json:"country,omitempty"`
+	Flag        string `json:"flag,omitempty"`
+	// ASN and Org name the network the peer is in, when known.
+	ASN uint32 `json:"asn,omitempty"`
+	Org string `json:"org,omitempty"`
+	// Progress is the percentage of the torrent the peer has.
+	Progress   float64 `json:"progress"`
+	Seed       bool    `json:"seed"`
+	Downloaded uint64  `json:"downloaded"`
+	Uploaded   uint64  `json:"uploaded"`
+	// DownRate and UpRate are in bytes per second, measured between the
+	// last two snapshots.
+	DownRate uint64 `json:"downRate"`
+	UpRate   uint64 `json:"upRate"`
+	// AmChoking and PeerChoking tell who is refusing to upload to whom;
+	// the Interested flags who wants data from whom.
+	AmChoking      bool `json:"amChoking"`
+	AmInterested   bool `json:"amInterested"`
+	PeerChoking    bool `json:"peerChoking"`
+	PeerInterested bool `json:"peerInterested"`
+	// Flags sums up the connection's state in the letters other clients
+	// use; see flags.
+	Flags string `json:"flags"`
+}
+
+// Peers describes every connected peer.
+func (m *Manager) Peers() []Info {
+	m.peerMut.RLock()
+	defer m.peerMut.RUnlock()
+
+	infos := make([]Info, 0, len(m.peers))
+	for _, p := range m.peers {
+		infos = append(infos, p.info())
+	}
+	return infos
+}
+
+// Counts returns how many peers are connected and how many of them are
+// seeds, having every piece.
+func (m *Manager) Counts() (peers, seeds int) {
+	m.peerMut.RLock()
+	defer m.peerMut.RUnlock()
+
+	for _, p := range m.peers {
+		if p.isSeed() {
+			seeds++
+		}
+	}
+	return len(m.peers), seeds
+}
+
+// Availability returns, for each piece, how many connected peers have it.
+func (m *Manager) Availability() []int {
+	avail := make([]int, m.pieces)
+
+	m.peerMut.RLock()
+	defer m.peerMut.RUnlock()
+
+	for _, p := range m.peers {
+		p.bfMu.Lock()
+		for i := range avail {
+			if p.pieceBF.Has(i) {
+				avail[i]++
+			}
+		}
+		p.bfMu.Unlock()
+	}
+	return avail
+}
+
+func (p *Peer) info() Info {
+	md := p.metadata()
+	info := Info{
+		Addr:           md.Addr,
+		CountryCode:    md.CountryCode,
+		Country:        md.CountryName,
+		Flag:           md.Flag,
+		ASN:            md.ASN,
+		Org:            md.Org,
+		Seed:           p.isSeed(),
+		Downloaded:     p.downloaded.Load(),
+		Uploaded:       p.uploaded.Load(),
+		DownRate:       p.shown.down.Load(),
+		UpRate:         p.shown.up.Load(),
+		AmChoking:      p.amChoking.Load(),
+		AmInterested:   p.amInterested.Load(),
+		PeerChoking:    p.peerChoking.Load(),
+		PeerInterested: p.peerInterested.Load(),
+	}
+	if c := p.peerClient.Load(); c != nil {
+		info.Client = *c
+	}
+	info.Flags = info.flags()
+	if p.m.pieces > 0 {
+		have := min(p.havePieces(), p.m.pieces)
+		info.Progress = float64(have) / float64(p.m.pieces) * 100
+	}
+	return info
+}
+
+// flags spells out the connection state: D we download from the peer, d
+// we would but it chokes us, U we upload to it, u it would like us to but
+// we choke it, K it unchokes us though we want nothing, ? we unchoke it
+// though it wants nothing. Connections are always outgoing and never
+// encrypted, so the I and E letters do not appear.
+func (i Info) flags() string {
+	var b strings.Builder
+	switch {
+	case i.AmInterested && !i.PeerChoking:
+		b.WriteByte('D')
+	case i.AmInterested:
+		b.WriteByte('d')
+	case !i.PeerChoking:
+		b.WriteByte('K')
+	}
+	switch {
+	case i.PeerInterested && !i.AmChoking:
+		b.WriteByte('U')
+	case i.PeerInterested:
+		b.WriteByte('u')
+	case !i.AmChoking:
+		b.WriteByte('?')
+	}
+	return b.String()
+}
+
+// havePieces counts the pieces the peer has told us it has.
+func (p *Peer) havePieces() int {
+	p.bfMu.Lock()
+	defer p.bfMu.Unlock()
+
+	return p.pieceBF.Count()
+}
+
+func (p *Peer) isSeed() bool {
+	return p.m.pieces > 0 && p.havePieces() >= p.m.pieces
+}
@@ -1,14 +1,30 @@
 package peer
 
 import (
+	"cmp"
 	"context"
 	"crypto/sha1"
+	"log/slog"
+	"net"
+	"net/netip"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/prxssh/echo/internal/piece"
 	"github.com/prxssh/echo/internal/tracker"
 )
 
+// DefaultMaxPeers is how many peers a manager keeps connected unless told
+// otherwise.
+const DefaultMaxPeers = 100
+
+// defaultBlockQueue holds 4 MiB of 16 KiB blocks.
+const defaultBlockQueue = 256
+
+// defaultMaxRequests keeps 512 KiB in flight from each peer.
+const defaultMaxRequests = 32
+
 type Config struct {
 	MaxPeers         uint32
 	DialWorkers      int
@@ -16,120 +32,410 @@ type Config struct {
 	WriteTimeout     time.Duration
 	HandshakeTimeout time.Duration
 	KeepAlive        time.Duration
+	IdleTimeout      time.Duration
+	MaxMessageLength uint32
+	MaxCandidates    int
+	MaxDialFailures  int
+	DialBackoff      time.Duration
+	MaxDialBackoff   time.Duration
+	ReconnectDelay   time.Duration
+	DrainTimeout     time.Duration
+	UploadSlots      int
+	ChokeInterval    time.Duration
+	SeedPolicy       SeedChokePolicy
+	// EventInterval is how often the messages received from peers are
+	// reported in one "peer:msgs" event; zero reports each as it comes.
+	EventInterval time.Duration
+	// SnapshotInterval is how often every connected peer is reported in
+	// a "peers:snapshot" event; zero turns the event off.
+	SnapshotInterval time.Duration
+	// MaxRequests is how many blocks are asked of a peer at a time.
+	MaxRequests int
+	// BlockQueue is how many piece blocks read from peers can wait for
+	// OnBlock, which bounds the memory they hold to BlockQueue blocks of
+	// at most MaxBlockLength. Peers are not read from while it is full.
+	BlockQueue int
 }
 
-func defaultConfig() Config {
+// DefaultConfig is what a manager runs with when Opts.Cfg is nil.
+func DefaultConfig() Config {
 	return Config{
-		MaxPeers:         100,
+		MaxPeers:         DefaultMaxPeers,
 		DialWorkers:      50,
 		ReadTimeout:      2 * time.Minute,
 		WriteTimeout:     30 * time.Second,
 		HandshakeTimeout: 1 * time.Second,
 		KeepAlive:        30 * time.Second,
+		IdleTimeout:      5 * time.Minute,
+		MaxMessageLength: DefaultMaxMessageLength,
+		MaxCandidates:    2000,
+		MaxDialFailures:  5,
+		DialBackoff:      30 * time.Second,
+		MaxDialBackoff:   30 * time.Minute,
+		ReconnectDelay:   2 * time.Minute,
+		DrainTimeout:     2 * time.Second,
+		UploadSlots:      4,
+		ChokeInterval:    10 * time.Second,
+		SeedPolicy:       SeedFastestUpload,
+		EventInterval:    250 * time.Millisecond,
+		SnapshotInterval: time.Second,
+		MaxRequests:      defaultMaxRequests,
+		BlockQueue:       defaultBlockQueue,
 	}
 }
 
+// OnBlockFunc receives a piece block on the manager's block worker, which
+// takes them from every peer in the order they were read, off the peers'
+// read loops. The slice is only valid for the duration of the call; the
+// buffer is recycled as soon as the callback returns, so implementations
+// must copy (or write out) the data before returning.
+type OnBlockFunc func(p *Peer, index, begin uint32, block []byte)
+
+// ReadBlockFunc fills block with the data at begin in a complete piece, for
+// a peer that asked for it.
+type ReadBlockFunc func(index, begin uint32, block []byte) error
+
+// block is a piece block on its way from a peer's read loop to OnBlock, in
+// a pooled buffer of its own.
+type block struct {
+	peer         *Peer
+	index, begin uint32
+	data         []byte
+}
+
+// OnPortFunc receives the DHT node address a peer advertised in a PORT
+// message.
+type OnPortFunc func(addr netip.AddrPort)
+
 type Manager struct {
 	infoHash [sha1.Size]byte
 	peerID   [sha1.Size]byte
 	pieces   int
+	bounds   PieceBounds
 	cfg      Config
+	onBlock  OnBlockFunc
+	blocks   chan block
+	// readBlock reads the blocks peers ask us for.
+	readBlock ReadBlockFunc
+	// picker and states, when set, pick the pieces and blocks peers are
+	// asked for.
+	picker  *piece.Picker
+	states  *piece.Blocks
+	onPort  OnPortFunc
+	dhtPort uint16
 
-	candidatesBuf chan *tracker.Peer
-	done          chan struct{}
+	metadata           *metadataState
+	onMetadata         OnMetadataFunc
+	onMetadataProgress OnMetadataProgressFunc
+	transport          Transport
+	locator            Locator
+	version            string
+
+	candidates *candidateSet
+	messages   messageBatch
+	seeding    atomic.Bool
+	uploadOnly atomic.Bool
+	// uploaded counts piece data sent to every peer, past and present.
+	uploaded atomic.Uint64
+
+	maxPeers  atomic.Uint32
+	downLimit rateLimiter
+	upLimit   rateLimiter
+
+	// lifecycle serializes Start and Stop; done is replaced on every
+	// Start so a stopped manager can run again.
+	lifecycle sync.Mutex
+	running   bool
+	done      chan struct{}
 
 	peerMut sync.RWMutex
 	peers   map[string]*Peer
 
-	dialWorkers sync.WaitGroup
+	workers sync.WaitGroup
 }
 
-func NewManager(
-	infoHash, peerID [sha1.Size]byte,
-	pieces int,
-	cfg *Config,
-) (*Manager, error) {
+type Opts struct {
+	InfoHash    [sha1.Size]byte
+	PeerID      [sha1.Size]byte
+	Pieces      int
+	PieceLength uint64
+	Size        uint64
+	Cfg         *Config
+	OnBlock     OnBlockFunc
+	// Picker and Blocks, when both are set, have the manager download:
+	// peers with pieces the picker wants are asked for their blocks, which
+	// reach OnBlock.
+	Picker *piece.Picker
+	Blocks *piece.Blocks
+	// ReadBlock, with Picker, has the manager upload: peers are told of
+	// the pieces the picker has done, and sent the blocks of them they
+	// ask for while unchoked.
+	ReadBlock ReadBlockFunc
+	Transport Transport
+	// DHTPort is advertised to peers that support the DHT; zero disables
+	// the DHT bit and PORT messages.
+	DHTPort uint16
+	OnPort  OnPortFunc
+	// OnMetadata, when set, makes the manager fetch the info dict from
+	// peers (BEP 9) for a torrent known only by its info-hash. Pieces,
+	// PieceLength and Size are then ignored.
+	OnMetadata         OnMetadataFunc
+	OnMetadataProgress OnMetadataProgressFunc
+	// Locator looks up the country and network peers are reported with;
+	// nil reports none.
+	Locator Locator
+	// Version is the client name and version sent in the extension
+	// handshake; empty sends Echo's.
+	Version string
+}
+
+func NewManager(opts Opts) (*Manager, error) {
 	m := &Manager{
-		infoHash:      infoHash,
-		peerID:        peerID,
-		pieces:        pieces,
-		done:          make(chan struct{}),
-		candidatesBuf: make(chan *tracker.Peer, 1001),
-		peers:         make(map[string]*Peer),
-	}
-	if cfg == nil {
-		m.cfg = defaultConfig()
+		infoHash:  opts.InfoHash,
+		peerID:    opts.PeerID,
+		pieces:    opts.Pieces,
+		onBlock:   opts.OnBlock,
+		readBlock: opts.ReadBlock,
+		picker:    opts.Picker,
+		states:    opts.Blocks,
+		onPort:    opts.OnPort,
+		dhtPort:   opts.DHTPort,
+		transport: opts.Transport,
+		locator:   opts.Locator,
+		version:   cmp.Or(opts.Version, clientVersion),
+		bounds: PieceBounds{
+			Pieces:      uint32(opts.Pieces),
+			PieceLength: uint32(opts.PieceLength),
+			TotalLength: opts.Size,
+			MaxBlock:    MaxBlockLength,
+		},
+		peers: make(map[string]*Peer),
+	}
+	m.messages.pending = make(map[*Peer]*peerMessageEvent)
+	if opts.OnMetadata != nil {
+		m.metadata = &metadataState{}
+		m.onMetadata = opts.OnMetadata
+		m.onMetadataProgress = opts.OnMetadataProgress
+	}
+	if opts.Cfg == nil {
+		m.cfg = DefaultConfig()
 	} else {
-		m.cfg = *cfg
+		m.cfg = *opts.Cfg
 	}
 
+	if m.transport == nil {
+		m.transport = tcpTransport{}
+	}
+	if m.cfg.MaxMessageLength == 0 {
+		m.cfg.MaxMessageLength = DefaultMaxMessageLength
+	}
+	// A bitfield for a torrent with many pieces can legitimately be larger
+	// than the configured cap, so never go below what it needs.
+	bitfieldLen := uint32((m.pieces+7)/8) + 1
+	if m.cfg.MaxMessageLength < bitfieldLen {
+		m.cfg.MaxMessageLength = bitfieldLen
+	}
+	if m.cfg.MaxRequests <= 0 {
+		m.cfg.MaxRequests = defaultMaxRequests
+	}
+	if m.cfg.BlockQueue <= 0 {
+		m.cfg.BlockQueue = defaultBlockQueue
+	}
+	if m.onBlock != nil {
+		m.blocks = make(chan block, m.cfg.BlockQueue)
+	}
+	m.candidates = newCandidateSet(m.cfg)
+	m.maxPeers.Store(m.cfg.MaxPeers)
+
 	return m, nil
 }
 
+// Start dials peers until Stop. A stopped manager can be started again, and
+// keeps the candidates it learned before.
 func (m *Manager) Start(ctx context.Context) {
+	m.lifecycle.Lock()
+	defer m.lifecycle.Unlock()
+
+	if m.running {
+		return
+	}
+	m.running = true
+	m.done = make(chan struct{})
+
 	for w := 0; w < m.cfg.DialWorkers; w++ {
-		m.dialWorkers.Go(func() { m.dialPeers(ctx) })
+		m.workers.Go(func() { m.dialPeers(ctx) })
+	}
+	if m.cfg.IdleTimeout > 0 {
+		m.workers.Go(func() { m.reapIdlePeers(ctx) })
+	}
+	if m.cfg.ChokeInterval > 0 {
+		m.workers.Go(func() { m.runChoker(ctx) })
+	}
+	if m.cfg.EventInterval > 0 {
+		m.workers.Go(func() { m.runMessageEvents(ctx) })
+	}
+	if m.cfg.SnapshotInterval > 0 {
+		m.workers.Go(func() { m.runSnapshots(ctx) })
+	}
+	if m.blocks != nil {
+		m.workers.Go(m.writeBlocks)
+	}
+}
+
+// writeBlocks hands the blocks peers queue to OnBlock until Stop. Blocks
+// still queued then are dropped with the peers that read them.
+func (m *Manager) writeBlocks() {
+	for {
+		select {
+		case <-m.done:
+			return
+		case b := <-m.blocks:
+			m.onBlock(b.peer, b.index, b.begin, b.data)
+			putBuffer(b.data)
+		}
 	}
 }
 
 func (m *Manager) Stop(ctx context.Context) {
-	select {
-	case <-m.done:
-	default:
-		close(m.done)
+	m.lifecycle.Lock()
+	defer m.lifecycle.Unlock()
+
+	if !m.running {
+		return
 	}
-	m.dialWorkers.Wait()
+	m.running = false
+	close(m.done)
+	m.workers.Wait()
 
+	// Peers drain concurrently so shutdown takes one DrainTimeout, not one
+	// per connection.
+	var wg sync.WaitGroup
 	m.peerMut.RLock()
 	for _, peer := range m.peers {
-		peer.Stop(ctx)
+		wg.Go(func() { peer.Stop(ctx) })
 	}
 	m.peerMut.RUnlock()
+	wg.Wait()
 }
 
-func (m *Manager) Enqueue(trackerPeers []*tracker.Peer) {
-	for _, trackerPeer := range trackerPeers {
-		if m.hasPeer(trackerPeer.Addr()) {
-			continue
-		}
+// Reconnect drops every peer, leaving them to be dialed again from the
+// candidates, as after the local address changes and their connections
+// are left dangling.
+func (m *Manager) Reconnect(ctx context.Context) {
+	var wg sync.WaitGroup
+	m.peerMut.RLock()
+	for _, peer := range m.peers {
+		wg.Go(func() { peer.Stop(ctx) })
+	}
+	m.peerMut.RUnlock()
+	wg.Wait()
+}
 
-		select {
-		case <-m.done:
-			return
-		case m.candidatesBuf <- trackerPeer:
-		default: // queue full, drop
-		}
+// Config returns the configuration the manager runs with.
+func (m *Manager) Config() Config {
+	return m.cfg
+}
+
+// SetMaxPeers changes how many peers the manager keeps connected; zero goes
+// back to the configured limit. Peers past a lowered limit are not dropped,
+// but none are dialed until enough disconnect.
+func (m *Manager) SetMaxPeers(n uint32) {
+	if n == 0 {
+		n = m.cfg.MaxPeers
 	}
+	m.maxPeers.Store(n)
+}
+
+// Uploaded returns the piece data sent to peers since the manager was
+// created.
+func (m *Manager) Uploaded() uint64 {
+	return m.uploaded.Load()
+}
+
+// Enqueue records peers learned from source as dial candidates.
+func (m *Manager) Enqueue(source Source, peers []*tracker.Peer) {
+	addrs := make([]string, 0, len(peers))
+	for _, p := range peers {
+		addrs = append(addrs, p.Addr())
+	}
+
+	m.candidates.add(source, addrs)
 }
 
 func (m *Manager) dialPeers(ctx context.Context) {
+	retry := time.NewTicker(time.Second)
+	defer retry.Stop()
+
 	for {
+		if m.countPeers() < int(m.maxPeers.Load()) {
+			if addr, ok := m.candidates.next(); ok {
+				m.dial(ctx, addr)
+				continue
+			}
+		}
+
 		select {
 		case <-m.done:
 			return
-		case trackerPeer, ok := <-m.candidatesBuf:
-			if !ok {
-				continue
-			}
-			if m.countPeers() >= int(m.cfg.MaxPeers) {
-				continue
-			}
+		case <-ctx.Done():
+			return
+		case <-m.candidates.notify:
+		case <-retry.C:
+		}
+	}
+}
 
-			peer, err := NewPeer(trackerPeer, m)
-			if err != nil {
-				continue
-			}
-			if !m.admitPeer(peer) {
-				peer.Stop(ctx)
-				continue
-			}
+func (m *Manager) dial(ctx context.Context, addr string) {
+	peer, err := NewPeer(ctx, addr, m)
+	if err != nil {
+		m.candidates.dialFailed(addr)
+		return
+	}
+	if !m.admitPeer(peer) {
+		peer.Stop(ctx)
+		m.candidates.disconnected(addr)
+		return
+	}
+	m.candidates.connected(addr)
 
-			go func(ctx context.Context, peer *Peer) {
-				peer.Start(ctx, m.done)
-				m.removePeer(ctx, peer.Addr())
-			}(ctx, peer)
-		}
+	go func(ctx context.Context, peer *Peer) {
+		peer.Start(ctx, m.done)
+		m.removePeer(ctx, peer.Addr())
+		m.candidates.disconnected(addr)
+	}(ctx, peer)
+}
+
+// Accept takes a connection a peer opened, whose handshake the caller has
+// read and matched to the torrent, and answers it. It reports false, having
+// closed conn, if the manager is stopped or has no room for the peer.
+func (m *Manager) Accept(
+	ctx context.Context,
+	conn net.Conn,
+	remote *Handshake,
+) bool {
+	_ = conn.SetWriteDeadline(time.Now().Add(m.cfg.HandshakeTimeout))
+	_, err := conn.Write(m.handshake(remote.InfoHash).Serialize())
+	_ = conn.SetWriteDeadline(time.Time{})
+	if err != nil {
+		_ = conn.Close()
+		return false
 	}
+	peer := newPeer(m, conn, remote)
+
+	// Holding lifecycle keeps Stop from missing the peer: either it is
+	// admitted first and stopped with the rest, or it finds done closed.
+	m.lifecycle.Lock()
+	defer m.lifecycle.Unlock()
+	if !m.running || !m.admitPeer(peer) {
+		_ = conn.Close()
+		return false
+	}
+	done := m.done
+	go func() {
+		peer.Start(ctx, done)
+		m.removePeer(ctx, peer.Addr())
+	}()
+	return true
 }
 
 func (m *Manager) admitPeer(peer *Peer) bool {
@@ -140,6 +446,9 @@ func (m *Manager) admitPeer(peer *Peer) bool {
 	if _, exists := m.peers[addr]; exists {
 		return false
 	}
+	if len(m.peers) >= int(m.maxPeers.Load()) {
+		return false
+	}
 	m.peers[addr] = peer
 
 	return true
@@ -147,13 +456,53 @@ func (m *Manager) admitPeer(peer *Peer) bool {
 
 func (m *Manager) removePeer(ctx context.Context, addr string) {
 	m.peerMut.Lock()
-	defer m.peerMut.Unlock()
-
 	peer, ok := m.peers[addr]
-	if !ok {
-		return
+	delete(m.peers, addr)
+	m.peerMut.Unlock()
+
+	if ok {
+		peer.Stop(ctx)
+	}
+}
+
+// reapIdlePeers disconnects peers that have not sent anything, not even a
+// keep-alive, for longer than IdleTimeout. This is separate from the read
+// deadline, which only bounds a single blocking read.
+func (m *Manager) reapIdlePeers(ctx context.Context) {
+	interval := m.cfg.IdleTimeout / 4
+	if interval < time.Second {
+		interval = time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-m.done:
+			return
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		var idle []*Peer
+		m.peerMut.RLock()
+		for _, peer := range m.peers {
+			if peer.IdleFor() > m.cfg.IdleTimeout {
+				idle = append(idle, peer)
+			}
+		}
+		m.peerMut.RUnlock()
+
+		for _, peer := range idle {
+			slog.Debug(
+				"reaping idle peer",
+				slog.String("addr", peer.Addr()),
+				slog.Duration("idle", peer.IdleFor()),
+			)
+			peer.Stop(ctx)
+		}
 	}
-	peer.Stop(ctx)
 }
 
 func (m *Manager) hasPeer(addr string) bool {
@@ -164,6 +513,12 @@ func (m *Manager) hasPeer(addr string) bool {
 	return ok
 }
 
+// DialStats sums up the candidates the dialers pick from, and the peers
+// they may still add.
+func (m *Manager) DialStats() DialStats {
+	return m.candidates.stats()
+}
+
 func (m *Manager) countPeers() int {
 	m.peerMut.RLock()
 	n := len(m.peers)
@@ -0,0 +1,237 @@
+package peer
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/prxssh/echo/internal/tracker"
+)
+
+const testBlock = 16 << 10
+
+// remote is the far end of a connection a Manager dials: it answers the
+// handshake, unchokes, and sends the blocks it is given, one for each
+// piece.
+func remote(t *testing.T, infoHash [20]byte, blocks [][]byte) *tracker.Peer {
+	t.Helper()
+	ln, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		serveBlocks(conn, infoHash, blocks)
+	}()
+
+	addr := ln.Addr().(*net.TCPAddr)
+	return &tracker.Peer{IP: addr.IP, Port: uint16(addr.Port)}
+}
+
+// serveBlocks is the remote's side of conn: it opens the handshake,
+// unchokes, and sends the blocks.
+func serveBlocks(conn net.Conn, infoHash [20]byte, blocks [][]byte) {
+	var id [20]byte
+	copy(id[:], "-RT0001-remote-peer0")
+	hs := NewHandshake(infoHash, id)
+	if _, err := hs.Perform(conn); err != nil {
+		return
+	}
+	if err := WriteMessage(conn, MessageUnchoke()); err != nil {
+		return
+	}
+	for i, b := range blocks {
+		err := WriteMessage(conn, MessagePiece(i, 0, b))
+		if err != nil {
+			return
+		}
+	}
+	// Hold the connection, taking whatever the manager sends, until the
+	// test is over.
+	io.Copy(io.Discard, conn)
+}
+
+func testManager(
+	t *testing.T,
+	blocks int,
+	cfg *Config,
+	onBlock OnBlockFunc,
+) *Manager {
+	t.Helper()
+	var infoHash, peerID [20]byte
+	copy(infoHash[:], "manager-test-infohas")
+	copy(peerID[:], "-ET0001-manager-test")
+	m, err := NewManager(Opts{
+		InfoHash:    infoHash,
+		PeerID:      peerID,
+		Pieces:      blocks,
+		PieceLength: testBlock,
+		Size:        uint64(blocks * testBlock),
+		Cfg:         cfg,
+		OnBlock:     onBlock,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func testBlocks(n int) [][]byte {
+	blocks := make([][]byte, n)
+	for i := range blocks {
+		blocks[i] = bytes.Repeat([]byte{byte(i + 1)}, testBlock)
+	}
+	return blocks
+}
+
+type received struct {
+	index, begin uint32
+	data         []byte
+}
+
+func TestManagerOnBlock(t *testing.T) {
+	blocks := testBlocks(6)
+	got := make(chan received, len(blocks))
+	m := testManager(t, len(blocks), nil, func(
+		_ *Peer,
+		index, begin uint32,
+		block []byte,
+	) {
+		got <- received{index, begin, bytes.Clone(block)}
+	})
+	m.Enqueue(SourceTracker, []*tracker.Peer{
+		remote(t, m.infoHash, blocks),
+	})
+	m.Start(t.Context())
+	defer m.Stop(t.Context())
+
+	for i, want := range blocks {
+		select {
+		case r := <-got:
+			if r.index != uint32(i) || r.begin != 0 {
+				t.Fatalf(
+					"block %d: got piece %d at %d",
+					i,
+					r.index,
+					r.begin,
+				)
+			}
+			if !bytes.Equal(r.data, want) {
+				t.Fatalf("block %d: wrong content", i)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("block %d never reached OnBlock", i)
+		}
+	}
+}
+
+// TestManagerBlockQueue checks that a slow OnBlock holds back the peers'
+// read loops once BlockQueue blocks wait for it, instead of letting them
+// read on into memory.
+func TestManagerBlockQueue(t *testing.T) {
+	blocks := testBlocks(8)
+	release := make(chan struct{})
+	done := make(chan struct{}, len(blocks))
+	cfg := DefaultConfig()
+	cfg.BlockQueue = 1
+	onBlock := func(*Peer, uint32, uint32, []byte) {
+		<-release
+		done <- struct{}{}
+	}
+	m := testManager(t, len(blocks), &cfg, onBlock)
+	m.Enqueue(SourceTracker, []*tracker.Peer{
+		remote(t, m.infoHash, blocks),
+	})
+	m.Start(t.Context())
+	defer m.Stop(t.Context())
+
+	// One block in OnBlock, one queued and one held by the read loop
+	// waiting to queue it: that is as far as the peer is read.
+	deadline := time.Now().Add(5 * time.Second)
+	for downloaded(m) < 3*testBlock && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if n := downloaded(m); n != 3*testBlock {
+		t.Fatalf("read %d bytes past a full queue", n)
+	}
+
+	close(release)
+	for i := range blocks {
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("block %d never reached OnBlock", i)
+		}
+	}
+}
+
+// downloaded sums the piece data the manager's peers have read.
+func downloaded(m *Manager) uint64 {
+	var n uint64
+	for _, info := range m.Peers() {
+		n += info.Downloaded
+	}
+	return n
+}
+
+// TestManagerAccept checks that a peer that connected to us is answered and
+// read from like one dialed, and that a stopped manager turns peers away.
+func TestManagerAccept(t *testing.T) {
+	blocks := testBlocks(2)
+	got := make(chan received, len(blocks))
+	m := testManager(t, len(blocks), nil, func(
+		_ *Peer,
+		index, begin uint32,
+		block []byte,
+	) {
+		got <- received{index, begin, bytes.Clone(block)}
+	})
+	m.Start(t.Context())
+
+	conn, far := net.Pipe()
+	defer far.Close()
+	go serveBlocks(far, m.infoHash, blocks)
+	hs, err := ReadHandshake(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !m.Accept(t.Context(), conn, hs) {
+		t.Fatal("running manager turned the peer away")
+	}
+	for i := range blocks {
+		select {
+		case r := <-got:
+			if !bytes.Equal(r.data, blocks[r.index]) {
+				t.Fatalf("block %d: wrong content", r.index)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("block %d never reached OnBlock", i)
+		}
+	}
+	m.Stop(t.Context())
+
+	conn, far = net.Pipe()
+	closed := make(chan struct{})
+	go func() {
+		// Our handshake, then nothing: the connection is closed.
+		io.Copy(io.Discard, far)
+		close(closed)
+	}()
+	if m.Accept(t.Context(), conn, hs) {
+		t.Fatal("stopped manager took a peer")
+	}
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("turned away peer left connected")
+	}
+}
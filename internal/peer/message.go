@@ -1,8 +1,8 @@
 package peer
 
 import (
-	"bytes"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"io"
 )
@@ -10,10 +10,26 @@ import (
 type Message struct {
 	ID      MessageID
 	Payload []byte
+
+	// buf is the pooled backing array of Payload for messages produced by
+	// ReadMessage. It is returned to the pool by Release.
+	buf []byte
 }
 
 type MessageID uint8
 
+// DefaultMaxMessageLength fits the largest message a well-behaved peer sends:
+// a piece message, a block of at most MaxBlockLength and the 13 bytes
+// framing it, or a ut_metadata piece behind its extended message header.
+// Bitfields of torrents with many pieces are let through on their own; see
+// NewManager.
+const DefaultMaxMessageLength = max(
+	MaxBlockLength+13,
+	2+maxMetadataHeader+metadataPieceSize,
+)
+
+var ErrMessageTooLarge = errors.New("peer: message exceeds maximum length")
+
 const (
 	MsgChoke         MessageID = 0
 	MsgUnchoke       MessageID = 1
@@ -24,6 +40,8 @@ const (
 	MsgRequest       MessageID = 6
 	MsgPiece         MessageID = 7
 	MsgCancel        MessageID = 8
+	MsgPort          MessageID = 9
+	MsgExtended      MessageID = 20
 )
 
 func (mid MessageID) String() string {
@@ -46,6 +64,10 @@ func (mid MessageID) String() string {
 		return "Piece"
 	case MsgCancel:
 		return "Cancel"
+	case MsgPort:
+		return "Port"
+	case MsgExtended:
+		return "Extended"
 	default:
 		return fmt.Sprintf("Unknown(%d)", mid)
 	}
@@ -74,56 +96,122 @@ func (m *Message) ParseHave() (uint32, bool) {
 	return binary.BigEndian.Uint32(m.Payload), true
 }
 
-func (m *Message) ParseRequest() (idx, begin, length uint32, ok bool) {
+func (m *Message) ParseRequest(
+	b PieceBounds,
+) (idx, begin, length uint32, ok bool) {
+	return m.parseBlockRef(b)
+}
+
+func (m *Message) ParseCancel(
+	b PieceBounds,
+) (idx, begin, length uint32, ok bool) {
+	return m.parseBlockRef(b)
+}
+
+func (m *Message) ParsePiece(
+	b PieceBounds,
+) (idx, begin uint32, block []byte, ok bool) {
+	if len(m.Payload) < 8 {
+		return 0, 0, nil, false
+	}
+
+	idx = binary.BigEndian.Uint32(m.Payload[0:4])
+	begin = binary.BigEndian.Uint32(m.Payload[4:8])
+	block = m.Payload[8:]
+	if !b.validBlock(idx, begin, uint32(len(block))) {
+		return 0, 0, nil, false
+	}
+
+	return idx, begin, block, true
+}
+
+func (m *Message) ParsePort() (uint16, bool) {
+	if len(m.Payload) != 2 {
+		return 0, false
+	}
+
+	port := binary.BigEndian.Uint16(m.Payload)
+	if port == 0 {
+		return 0, false
+	}
+
+	return port, true
+}
+
+func (m *Message) parseBlockRef(
+	b PieceBounds,
+) (idx, begin, length uint32, ok bool) {
 	if len(m.Payload) != 12 {
 		return 0, 0, 0, false
 	}
 
-	return binary.BigEndian.Uint32(
-			m.Payload[0:4],
-		), binary.BigEndian.Uint32(
-			m.Payload[4:8],
-		), binary.BigEndian.Uint32(
-			m.Payload[8:12],
-		), true
+	idx = binary.BigEndian.Uint32(m.Payload[0:4])
+	begin = binary.BigEndian.Uint32(m.Payload[4:8])
+	length = binary.BigEndian.Uint32(m.Payload[8:12])
+	if !b.validBlock(idx, begin, length) {
+		return 0, 0, 0, false
+	}
+
+	return idx, begin, length, true
 }
 
-func (m *Message) ParsePiece() (idx, begin uint32, block []byte, ok bool) {
-	if len(m.Payload) < 8 {
-		return 0, 0, nil, false
+// Release hands the message's buffer back to the pool. The message, and any
+// slice of its payload, must not be used afterwards.
+func (m *Message) Release() {
+	if m == nil || m.buf == nil {
+		return
 	}
 
-	return binary.BigEndian.Uint32(
-			m.Payload[0:4],
-		), binary.BigEndian.Uint32(
-			m.Payload[4:8],
-		), m.Payload[8:], true
+	putBuffer(m.buf)
+	m.buf, m.Payload = nil, nil
 }
 
-func ReadMessage(r io.Reader) (*Message, error) {
-	var length uint32
-	if err := binary.Read(r, binary.BigEndian, &length); err != nil {
+// ReadMessage reads a single length-prefixed message, rejecting any whose
+// length prefix exceeds maxLength before allocating for it. The returned
+// message borrows a pooled buffer; callers should Release it once they are
+// done with the payload.
+func ReadMessage(r io.Reader, maxLength uint32) (*Message, error) {
+	var prefix [4]byte
+	if _, err := io.ReadFull(r, prefix[:]); err != nil {
 		return nil, err
 	}
+
+	length := binary.BigEndian.Uint32(prefix[:])
 	if length == 0 { // keep-alive
 		return nil, nil
 	}
-	buf := make([]byte, length)
+	if length > maxLength {
+		return nil, fmt.Errorf(
+			"%w: %d > %d",
+			ErrMessageTooLarge,
+			length,
+			maxLength,
+		)
+	}
+	buf := getBuffer(int(length))
 	if _, err := io.ReadFull(r, buf); err != nil {
+		putBuffer(buf)
 		return nil, err
 	}
 
-	return &Message{ID: MessageID(buf[0]), Payload: buf[1:]}, nil
+	return &Message{ID: MessageID(buf[0]), Payload: buf[1:], buf: buf}, nil
 }
 
 func WriteMessage(w io.Writer, m *Message) error {
 	if m == nil { // keep-alive
 		var z [4]byte
-		_, err := io.Copy(w, bytes.NewReader(z[:]))
+		_, err := w.Write(z[:])
 		return err
 	}
 
-	_, err := io.Copy(w, bytes.NewReader(m.Serialize()))
+	buf := getBuffer(4 + 1 + len(m.Payload))
+	defer putBuffer(buf)
+
+	binary.BigEndian.PutUint32(buf[0:4], uint32(len(m.Payload)+1))
+	buf[4] = byte(m.ID)
+	copy(buf[5:], m.Payload)
+
+	_, err := w.Write(buf)
 	return err
 }
 
@@ -183,3 +271,10 @@ func MessageCancel(index, begin, length int) *Message {
 
 	return &Message{ID: MsgCancel, Payload: payload}
 }
+
+func MessagePort(port uint16) *Message {
+	payload := make([]byte, 2)
+	binary.BigEndian.PutUint16(payload, port)
+
+	return &Message{ID: MsgPort, Payload: payload}
+}
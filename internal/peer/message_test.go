@@ -0,0 +1,203 @@
+package peer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+	"time"
+)
+
+// blockRefTests are the index, begin and length triples that request and
+// cancel messages carry, and piece messages through their block's length,
+// checked against testBounds.
+var blockRefTests = []struct {
+	name                 string
+	index, begin, length uint32
+	ok                   bool
+}{
+	{"first block", 0, 0, 16 << 10, true},
+	{"end of piece", 2, 16 << 10, 16 << 10, true},
+	{"small block", 1, 100, 1, true},
+	{"last piece", 3, 0, 4 << 10, true},
+	{"last piece tail", 3, 3 << 10, 1 << 10, true},
+	{"past last piece", 3, 0, 16 << 10, false},
+	{"past piece end", 0, 16<<10 + 1, 16 << 10, false},
+	{"begin overflow", 0, 0xffffffff, 16 << 10, false},
+	{"begin wraps to zero", 0, 0xffffc000, 16 << 10, false},
+	{"above max block", 0, 0, 16<<10 + 1, false},
+	{"zero length", 0, 0, 0, false},
+	{"index out of range", 4, 0, 16 << 10, false},
+	{"huge index", 0xffffffff, 0, 16 << 10, false},
+}
+
+func TestParseRequest(t *testing.T) {
+	for _, tt := range blockRefTests {
+		for _, msg := range []*Message{
+			refMessage(MsgRequest, tt.index, tt.begin, tt.length),
+			refMessage(MsgCancel, tt.index, tt.begin, tt.length),
+		} {
+			parse := msg.ParseRequest
+			if msg.ID == MsgCancel {
+				parse = msg.ParseCancel
+			}
+			index, begin, length, ok := parse(testBounds)
+			if ok != tt.ok {
+				t.Errorf("%s %s: ok = %v", msg.ID, tt.name, ok)
+				continue
+			}
+			if ok && (index != tt.index || begin != tt.begin ||
+				length != tt.length) {
+				t.Errorf(
+					"%s %s: got %d, %d, %d",
+					msg.ID,
+					tt.name,
+					index,
+					begin,
+					length,
+				)
+			}
+		}
+	}
+
+	for _, n := range []int{0, 8, 11, 13} {
+		msg := &Message{ID: MsgRequest, Payload: make([]byte, n)}
+		if _, _, _, ok := msg.ParseRequest(testBounds); ok {
+			t.Errorf("%d byte payload accepted", n)
+		}
+	}
+}
+
+func TestParsePiece(t *testing.T) {
+	for _, tt := range blockRefTests {
+		block := bytes.Repeat([]byte{0xab}, int(tt.length))
+		payload := binary.BigEndian.AppendUint32(nil, tt.index)
+		payload = binary.BigEndian.AppendUint32(payload, tt.begin)
+		payload = append(payload, block...)
+		msg := &Message{ID: MsgPiece, Payload: payload}
+
+		index, begin, got, ok := msg.ParsePiece(testBounds)
+		if ok != tt.ok {
+			t.Errorf("%s: ok = %v", tt.name, ok)
+			continue
+		}
+		if ok && (index != tt.index || begin != tt.begin ||
+			!bytes.Equal(got, block)) {
+			t.Errorf("%s: got %d at %d", tt.name, index, begin)
+		}
+	}
+
+	short := &Message{ID: MsgPiece, Payload: make([]byte, 7)}
+	if _, _, _, ok := short.ParsePiece(testBounds); ok {
+		t.Error("7 byte payload accepted")
+	}
+}
+
+func TestParsePort(t *testing.T) {
+	tests := []struct {
+		payload []byte
+		want    uint16
+		ok      bool
+	}{
+		{[]byte{0x1a, 0xe1}, 6881, true},
+		{[]byte{0xff, 0xff}, 65535, true},
+		{[]byte{0, 1}, 1, true},
+		{[]byte{0, 0}, 0, false},
+		{[]byte{0x1a}, 0, false},
+		{[]byte{0x1a, 0xe1, 0}, 0, false},
+	}
+	for _, tt := range tests {
+		msg := &Message{ID: MsgPort, Payload: tt.payload}
+		got, ok := msg.ParsePort()
+		if got != tt.want || ok != tt.ok {
+			t.Errorf(
+				"ParsePort(%x) = %d, %v; want %d, %v",
+				tt.payload,
+				got,
+				ok,
+				tt.want,
+				tt.ok,
+			)
+		}
+	}
+}
+
+func TestMessageSizeCap(t *testing.T) {
+	const limit = 32
+	tests := []struct {
+		name   string
+		length uint32
+		err    error
+	}{
+		{"keep-alive", 0, nil},
+		{"at the cap", limit, nil},
+		{"over the cap", limit + 1, ErrMessageTooLarge},
+		{"huge", 0xffffffff, ErrMessageTooLarge},
+	}
+	for _, tt := range tests {
+		// Only the body of a message within the cap is sent: one past
+		// it must be turned down from its length prefix alone.
+		wire := binary.BigEndian.AppendUint32(nil, tt.length)
+		if tt.err == nil && tt.length > 0 {
+			wire = append(wire, byte(MsgHave))
+			wire = append(wire, make([]byte, tt.length-1)...)
+		}
+
+		msg, err := ReadMessage(bytes.NewReader(wire), limit)
+		if !errors.Is(err, tt.err) {
+			t.Errorf("ReadMessage %s: err = %v", tt.name, err)
+		}
+		if err == nil && tt.length > 0 &&
+			len(msg.Payload) != int(tt.length)-1 {
+			t.Errorf(
+				"ReadMessage %s: %d byte payload",
+				tt.name,
+				len(msg.Payload),
+			)
+		}
+		msg.Release()
+
+		rd := NewReader(bytes.NewReader(wire), limit, time.Minute)
+		if _, err := rd.Next(); !errors.Is(err, tt.err) {
+			t.Errorf("Reader %s: err = %v", tt.name, err)
+		}
+	}
+}
+
+func TestDefaultMaxMessageLength(t *testing.T) {
+	block := make([]byte, MaxBlockLength)
+	metadata, err := messageMetadata(3, map[string]any{
+		"msg_type":   int64(metadataData),
+		"piece":      int64(maxMetadataSize/metadataPieceSize - 1),
+		"total_size": int64(maxMetadataSize),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	metadata.Payload = append(metadata.Payload, block...)
+
+	for _, msg := range []*Message{MessagePiece(1, 0, block), metadata} {
+		var buf bytes.Buffer
+		WriteMessage(&buf, msg)
+		_, err := ReadMessage(&buf, DefaultMaxMessageLength)
+		if err != nil {
+			t.Errorf("%s at the largest: %v", msg.ID, err)
+		}
+	}
+
+	// Twice the largest block is more than any valid piece carries.
+	var buf bytes.Buffer
+	WriteMessage(&buf, MessagePiece(1, 0, append(block, block...)))
+	_, err = ReadMessage(&buf, DefaultMaxMessageLength)
+	if !errors.Is(err, ErrMessageTooLarge) {
+		t.Errorf("double block: err = %v", err)
+	}
+}
+
+func refMessage(id MessageID, index, begin, length uint32) *Message {
+	payload := make([]byte, 12)
+	binary.BigEndian.PutUint32(payload[0:4], index)
+	binary.BigEndian.PutUint32(payload[4:8], begin)
+	binary.BigEndian.PutUint32(payload[8:12], length)
+	return &Message{ID: id, Payload: payload}
+}
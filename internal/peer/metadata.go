@@ -0,0 +1,209 @@
+package peer
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/prxssh/echo/internal/bencode"
+)
+
+// utMetadataID is the extended message id we assign to ut_metadata (BEP 9).
+const utMetadataID = 1
+
+const (
+	metadataPieceSize = 16 << 10
+	maxMetadataSize   = 16 << 20
+	// maxMetadataHeader is room for the dictionary ahead of a piece of
+	// the info dict, with keys beyond those BEP 9 names.
+	maxMetadataHeader = 512
+)
+
+const (
+	metadataRequest = 0
+	metadataData    = 1
+	metadataReject  = 2
+)
+
+// OnMetadataFunc receives the info dict fetched from peers, after its hash
+// has been checked against the info-hash. It is called once, from its own
+// goroutine.
+type OnMetadataFunc func(info []byte)
+
+// OnMetadataProgressFunc is told how many of the info dict's pieces have
+// arrived each time one does. It is called from the peer's read loop and
+// must not block.
+type OnMetadataProgressFunc func(received, total int)
+
+// metadataState assembles the info dict from ut_metadata pieces sent by any
+// number of peers.
+type metadataState struct {
+	mu       sync.Mutex
+	size     int
+	pieces   [][]byte
+	received int
+	done     bool
+}
+
+// setSize records the size a peer advertised. The first size seen wins;
+// peers advertising another are ignored.
+func (s *metadataState) setSize(size int64) bool {
+	if size <= 0 || size > maxMetadataSize {
+		return false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.size == 0 {
+		s.size = int(size)
+		n := (s.size + metadataPieceSize - 1) / metadataPieceSize
+		s.pieces = make([][]byte, n)
+	}
+	return s.size == int(size)
+}
+
+// missing lists the pieces still to be fetched.
+func (s *metadataState) missing() []int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var out []int
+	for i, p := range s.pieces {
+		if p == nil {
+			out = append(out, i)
+		}
+	}
+	return out
+}
+
+// progress counts the pieces received and the pieces there are, zero
+// until a peer advertises the size.
+func (s *metadataState) progress() (received, total int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.received, len(s.pieces)
+}
+
+// add stores a piece and returns the whole info dict once every piece has
+// arrived and it hashes to infoHash. A mismatch discards what was received
+// so the pieces are fetched again.
+func (s *metadataState) add(
+	index int,
+	data []byte,
+	infoHash [sha1.Size]byte,
+) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.done || index < 0 || index >= len(s.pieces) {
+		return nil, nil
+	}
+	want := min(metadataPieceSize, s.size-index*metadataPieceSize)
+	if len(data) != want {
+		return nil, fmt.Errorf(
+			"ut_metadata: piece %d has %d bytes, want %d",
+			index,
+			len(data),
+			want,
+		)
+	}
+	if s.pieces[index] != nil {
+		return nil, nil
+	}
+	s.pieces[index] = bytes.Clone(data)
+	s.received++
+	if s.received < len(s.pieces) {
+		return nil, nil
+	}
+
+	// Only the hash is checked: bytes that match the info-hash are the
+	// info dict, canonical or not, and are not decoded before then.
+	info := bytes.Join(s.pieces, nil)
+	if sha1.Sum(info) != infoHash {
+		clear(s.pieces)
+		s.received = 0
+		return nil, errors.New("ut_metadata: info-hash mismatch")
+	}
+	s.done = true
+	return info, nil
+}
+
+func messageMetadata(peerID int64, dict map[string]any) (*Message, error) {
+	var buf bytes.Buffer
+	buf.WriteByte(byte(peerID))
+	if err := bencode.NewEncoder(&buf).Encode(dict); err != nil {
+		return nil, err
+	}
+
+	return &Message{ID: MsgExtended, Payload: buf.Bytes()}, nil
+}
+
+// requestMetadata asks the peer for every piece of the info dict we are
+// still missing.
+func (p *Peer) requestMetadata() {
+	id := p.peerMetadataID.Load()
+	if id == 0 {
+		return
+	}
+
+	for _, index := range p.m.metadata.missing() {
+		msg, err := messageMetadata(id, map[string]any{
+			"msg_type": int64(metadataRequest),
+			"piece":    int64(index),
+		})
+		if err != nil || !p.Send(msg) {
+			return
+		}
+	}
+}
+
+func (p *Peer) handleMetadata(payload []byte) error {
+	dec := bencode.NewDecoder(bytes.NewReader(payload))
+	decoded, err := dec.Decode()
+	if err != nil {
+		return fmt.Errorf("ut_metadata: %w", err)
+	}
+	dict, ok := decoded.(map[string]any)
+	if !ok {
+		return errors.New("ut_metadata: not a dictionary")
+	}
+	msgType, _ := dict["msg_type"].(int64)
+	index, _ := dict["piece"].(int64)
+
+	switch msgType {
+	case metadataRequest:
+		// We only fetch metadata, so every request is turned down.
+		id := p.peerMetadataID.Load()
+		if id == 0 {
+			return nil
+		}
+		msg, err := messageMetadata(id, map[string]any{
+			"msg_type": int64(metadataReject),
+			"piece":    index,
+		})
+		if err == nil {
+			p.Send(msg)
+		}
+	case metadataData:
+		if p.m.metadata == nil {
+			return nil
+		}
+		data := payload[dec.InputOffset():]
+		info, err := p.m.metadata.add(int(index), data, p.m.infoHash)
+		if err != nil {
+			return err
+		}
+		if p.m.onMetadataProgress != nil {
+			p.m.onMetadataProgress(p.m.metadata.progress())
+		}
+		if info != nil && p.m.onMetadata != nil {
+			go p.m.onMetadata(info)
+		}
+	}
+
+	return nil
+}
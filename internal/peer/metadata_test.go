@@ -0,0 +1,60 @@
+package peer
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"testing"
+)
+
+func TestMetadataHash(t *testing.T) {
+	canonical := "d6:lengthi1e4:name1:xe"
+	tests := []struct {
+		name string
+		info string
+		hash [sha1.Size]byte
+		ok   bool
+	}{
+		{"canonical", canonical, sha1.Sum([]byte(canonical)), true},
+		// Torrents made by clients that do not sort keys are still
+		// the info dict their info-hash names.
+		{
+			"unsorted keys",
+			"d4:name1:x6:lengthi1ee",
+			sha1.Sum([]byte("d4:name1:x6:lengthi1ee")),
+			true,
+		},
+		{"wrong hash", canonical, sha1.Sum([]byte("de")), false},
+		{
+			"deeply nested",
+			string(bytes.Repeat([]byte("l"), 1<<20)),
+			sha1.Sum([]byte(canonical)),
+			false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var s metadataState
+			if !s.setSize(int64(len(tt.info))) {
+				t.Fatal("setSize rejected the size")
+			}
+			var (
+				info []byte
+				err  error
+			)
+			raw := []byte(tt.info)
+			for i := 0; len(raw) > 0; i++ {
+				n := min(metadataPieceSize, len(raw))
+				info, err = s.add(i, raw[:n], tt.hash)
+				raw = raw[n:]
+			}
+			switch {
+			case tt.ok && (err != nil || string(info) != tt.info):
+				t.Fatalf("add = %.20q, %v", info, err)
+			case !tt.ok && err == nil:
+				t.Fatalf("add = %.20q; want an error", info)
+			case !tt.ok && len(s.missing()) != len(s.pieces):
+				t.Errorf("pieces kept after a mismatch")
+			}
+		})
+	}
+}
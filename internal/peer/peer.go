@@ -2,69 +2,120 @@ package peer
 
 import (
 	"context"
+	"crypto/sha1"
+	"errors"
+	"fmt"
 	"log/slog"
 	"net"
+	"net/netip"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/prxssh/echo/internal/bitfield"
-	"github.com/prxssh/echo/internal/tracker"
+	"github.com/prxssh/echo/pkg/logging"
 )
 
 type Peer struct {
 	m *Manager
 
 	conn net.Conn
-
-	amChoking      bool
-	amInterested   bool
-	peerChoking    bool
-	peerInterested bool
+	// reader is the read loop's alone.
+	reader   *Reader
+	requests requests
+	uploads  uploads
+
+	amChoking      atomic.Bool
+	amInterested   atomic.Bool
+	peerChoking    atomic.Bool
+	peerInterested atomic.Bool
+
+	downloaded atomic.Uint64
+	uploaded   atomic.Uint64
+	rates      transferRates
+	// shown holds the rates reported in snapshots, sampled on the
+	// snapshot worker's own clock.
+	shown shownRates
 
 	requestsQueue chan *Message
 	stopped       chan struct{}
 	stopOnce      sync.Once
+	writeMu       sync.Mutex
+
+	lastReceived atomic.Int64
+
+	extensions     bool
+	dht            bool
+	peerUploadOnly atomic.Bool
+	peerMetadataID atomic.Int64
 
+	peerClient atomic.Pointer[string]
+
+	// bfMu guards pieceBF, which the read loop updates and the manager
+	// reads to report on the peer.
+	bfMu    sync.Mutex
 	pieceBF bitfield.Bitfield
 }
 
-func NewPeer(trackerPeer *tracker.Peer, m *Manager) (*Peer, error) {
-	conn, err := net.DialTimeout(
-		"tcp",
-		trackerPeer.Addr(),
-		m.cfg.HandshakeTimeout,
-	)
+func NewPeer(ctx context.Context, addr string, m *Manager) (*Peer, error) {
+	conn, err := m.transport.Dial(ctx, addr, m.cfg.HandshakeTimeout)
 	if err != nil {
 		return nil, err
 	}
 
 	_ = conn.SetReadDeadline(time.Now().Add(m.cfg.HandshakeTimeout))
-	handshake := NewHandshake(m.infoHash, m.peerID)
-	if err := handshake.Perform(conn); err != nil {
+	remote, err := m.handshake(m.infoHash).Perform(conn)
+	if err != nil {
 		_ = conn.Close()
 		return nil, err
 	}
 	_ = conn.SetReadDeadline(time.Time{})
 
-	return &Peer{
-		m:              m,
-		conn:           conn,
-		amChoking:      true,
-		amInterested:   false,
-		peerChoking:    true,
-		peerInterested: false,
-		pieceBF:        bitfield.New(m.pieces),
-		requestsQueue:  make(chan *Message, 128),
-		stopped:        make(chan struct{}),
-	}, nil
+	return newPeer(m, conn, remote), nil
+}
+
+// handshake is ours for infoHash.
+func (m *Manager) handshake(infoHash [sha1.Size]byte) *Handshake {
+	handshake := NewHandshake(infoHash, m.peerID)
+	if m.dhtPort != 0 {
+		handshake.Reserved[reservedDHTByte] |= reservedDHTBit
+	}
+	return handshake
+}
+
+// newPeer wraps a connection whose handshakes have been exchanged.
+func newPeer(m *Manager, conn net.Conn, remote *Handshake) *Peer {
+	reader := NewReader(conn, m.cfg.MaxMessageLength, m.cfg.ReadTimeout)
+	peer := &Peer{
+		m:             m,
+		conn:          conn,
+		reader:        reader,
+		pieceBF:       bitfield.New(m.pieces),
+		requestsQueue: make(chan *Message, 128),
+		stopped:       make(chan struct{}),
+		uploads:       uploads{ready: make(chan struct{}, 1)},
+		extensions:    remote.SupportsExtensions(),
+		dht:           remote.SupportsDHT(),
+	}
+	peer.amChoking.Store(true)
+	peer.peerChoking.Store(true)
+	peer.lastReceived.Store(time.Now().UnixNano())
+
+	return peer
 }
 
 func (p *Peer) Start(ctx context.Context, globalDone <-chan struct{}) {
-	p.emitStarted(ctx)
+	p.sendBitfield()
+	p.sendExtensionHandshake()
+	if p.dht && p.m.dhtPort != 0 {
+		p.Send(MessagePort(p.m.dhtPort))
+	}
 
+	ctx = logging.WithPeer(ctx, p.Addr())
 	var wg sync.WaitGroup
 	wg.Go(func() { p.readMessages(ctx, globalDone) })
 	wg.Go(func() { p.writeMessages(ctx, globalDone) })
+	wg.Go(func() { p.serveUploads(ctx, globalDone) })
 
 	wg.Wait()
 }
@@ -73,18 +124,104 @@ func (p *Peer) Addr() string {
 	return p.conn.RemoteAddr().String()
 }
 
+// IdleFor reports how long it has been since the peer last sent us anything.
+func (p *Peer) IdleFor() time.Duration {
+	return time.Since(time.Unix(0, p.lastReceived.Load()))
+}
+
+func (p *Peer) choke() {
+	if !p.amChoking.Swap(true) {
+		p.clearUploads()
+		p.Send(MessageChoke())
+	}
+}
+
+func (p *Peer) unchoke() {
+	if p.amChoking.Swap(false) {
+		p.Send(MessageUnchoke())
+	}
+}
+
+// Send queues a message for the writer. It never blocks on a stopped peer and
+// reports whether the message was accepted.
+func (p *Peer) Send(message *Message) bool {
+	select {
+	case <-p.stopped:
+		return false
+	default:
+	}
+
+	select {
+	case <-p.stopped:
+		return false
+	case p.requestsQueue <- message:
+		return true
+	}
+}
+
+// Stop shuts the connection down gracefully: queued piece messages are
+// flushed and a final choke/not-interested is sent, bounded by DrainTimeout,
+// before the socket is closed. The queue itself is never closed so that a
+// concurrent Send cannot panic.
 func (p *Peer) Stop(ctx context.Context) {
 	p.stopOnce.Do(func() {
 		close(p.stopped)
+		p.drain()
 		_ = p.conn.Close()
-		close(p.requestsQueue)
-
-		p.emitStopped(ctx)
 	})
 }
 
+func (p *Peer) drain() {
+	timeout := p.m.cfg.DrainTimeout
+	if timeout <= 0 {
+		return
+	}
+
+	// A write stuck on a slow connection holds writeMu until its own
+	// deadline. Closing the connection once the timeout is up ends it, and
+	// the drain with it, however long it would have taken.
+	deadline := time.Now().Add(timeout)
+	timer := time.AfterFunc(timeout, func() { _ = p.conn.Close() })
+	defer timer.Stop()
+
+	p.writeMu.Lock()
+	defer p.writeMu.Unlock()
+
+	_ = p.conn.SetWriteDeadline(deadline)
+
+	var pending []*Message
+	for flushing := true; flushing; {
+		select {
+		case message := <-p.requestsQueue:
+			if message != nil && message.ID == MsgPiece {
+				pending = append(pending, message)
+			}
+		default:
+			flushing = false
+		}
+	}
+	if !p.amChoking.Load() {
+		pending = append(pending, MessageChoke())
+	}
+	if p.amInterested.Load() {
+		pending = append(pending, MessageNotInterested())
+	}
+
+	for _, message := range pending {
+		if err := WriteMessage(p.conn, message); err != nil {
+			slog.Debug(
+				"peer drain write error",
+				slog.String("addr", p.Addr()),
+				slog.String("error", err.Error()),
+			)
+			return
+		}
+	}
+}
+
 func (p *Peer) readMessages(ctx context.Context, globalDone <-chan struct{}) {
 	defer p.Stop(ctx)
+	defer p.endRequests()
 
 	for {
 		select {
@@ -99,61 +236,150 @@ func (p *Peer) readMessages(ctx context.Context, globalDone <-chan struct{}) {
 		if err != nil {
 			if ne, ok := err.(net.Error); ok &&
 				ne.Timeout() { // peer is just idle
-				slog.Debug(
-					"peer idle timeout",
-					slog.String("addr", p.Addr()),
-				)
+				slog.DebugContext(ctx, "peer idle timeout")
 				continue
 			}
+			if errors.Is(err, ErrMessageTooLarge) {
+				slog.WarnContext(
+					ctx,
+					"peer sent oversized message",
+					slog.String("error", err.Error()),
+				)
+				return
+			}
 
-			slog.Error(
+			slog.ErrorContext(
+				ctx,
 				"peer read error",
 				slog.String("error", err.Error()),
-				slog.String(
-					"addr",
-					p.conn.RemoteAddr().String(),
-				),
 			)
 			return
 		}
+
+		p.lastReceived.Store(time.Now().UnixNano())
 		if message == nil { // keep-alive
 			p.emitMessage(ctx, "Keep Alive")
 			continue
 		}
 
 		p.emitMessage(ctx, message.ID.String())
-
-		switch message.ID {
-		case MsgChoke:
-			p.peerChoking = true
-		case MsgUnchoke:
-			p.peerChoking = false
-		case MsgInterested:
-			p.peerInterested = true
-		case MsgNotInterested:
-			p.peerInterested = false
-		case MsgBitfield:
-			p.pieceBF = bitfield.FromBytes(message.Payload)
-		case MsgHave:
-			index, ok := message.ParseHave()
-			if !ok {
-				continue
-			}
-			p.pieceBF.Set(int(index))
-		case MsgPiece:
-			continue
-		case MsgRequest:
-			continue
-		default:
-			slog.Warn(
-				"unknown message",
-				slog.Int("id", int(message.ID)),
-				slog.Any("payload", message.Payload),
+		err = p.handleMessage(message)
+		message.Release()
+		if err != nil {
+			slog.WarnContext(
+				ctx,
+				"peer protocol violation",
+				slog.String("error", err.Error()),
 			)
+			return
 		}
 	}
 }
 
+func (p *Peer) handleMessage(message *Message) error {
+	switch message.ID {
+	case MsgChoke:
+		p.peerChoking.Store(true)
+		p.cancelRequests()
+	case MsgUnchoke:
+		p.peerChoking.Store(false)
+		p.fillRequests()
+	case MsgInterested:
+		p.peerInterested.Store(true)
+	case MsgNotInterested:
+		p.peerInterested.Store(false)
+	case MsgBitfield:
+		n := p.m.pieces
+		if p.m.metadata != nil {
+			// No piece count to check it against until the info
+			// dict arrives.
+			n = len(message.Payload) * 8
+		}
+		bf := bitfield.FromBytes(message.Payload, n)
+		if err := bf.Validate(n); err != nil {
+			return fmt.Errorf("bad bitfield: %w", err)
+		}
+		p.bfMu.Lock()
+		p.pieceBF = bf
+		p.bfMu.Unlock()
+		p.updateInterest()
+		p.fillRequests()
+	case MsgHave:
+		if p.m.metadata != nil {
+			break // no piece count until the info dict arrives
+		}
+		index, ok := message.ParseHave()
+		if !ok || index >= p.m.bounds.Pieces {
+			return errMalformed(message)
+		}
+		p.bfMu.Lock()
+		p.pieceBF.Set(int(index))
+		p.bfMu.Unlock()
+		p.updateInterest()
+		p.fillRequests()
+	case MsgPiece:
+		index, begin, block, ok := message.ParsePiece(p.m.bounds)
+		if !ok {
+			return errMalformed(message)
+		}
+		p.downloaded.Add(uint64(len(block)))
+		p.m.downLimit.wait(len(block))
+		p.blockReceived(index, begin)
+		p.queueBlock(index, begin, block)
+		p.fillRequests()
+	case MsgRequest:
+		index, begin, length, ok := message.ParseRequest(p.m.bounds)
+		if !ok {
+			return errMalformed(message)
+		}
+		p.queueUpload(index, begin, length)
+	case MsgCancel:
+		index, begin, length, ok := message.ParseCancel(p.m.bounds)
+		if !ok {
+			return errMalformed(message)
+		}
+		p.cancelUpload(index, begin, length)
+	case MsgPort:
+		port, ok := message.ParsePort()
+		if !ok {
+			return errMalformed(message)
+		}
+		p.handlePort(port)
+	case MsgExtended:
+		return p.handleExtended(message)
+	default:
+		slog.Warn(
+			"unknown message",
+			slog.Int("id", int(message.ID)),
+			slog.Any("payload", message.Payload),
+		)
+	}
+
+	return nil
+}
+
+// handlePort hands the peer's DHT node to the routing table. The port is paired
+// with the address we are connected to, never one the peer claims.
+func (p *Peer) handlePort(port uint16) {
+	if p.m.onPort == nil || p.m.dhtPort == 0 {
+		return
+	}
+	remote, err := netip.ParseAddrPort(p.Addr())
+	if err != nil {
+		return
+	}
+
+	p.m.onPort(netip.AddrPortFrom(remote.Addr().Unmap(), port))
+}
+
+func errMalformed(message *Message) error {
+	return fmt.Errorf(
+		"malformed %s payload (%d bytes)",
+		message.ID,
+		len(message.Payload),
+	)
+}
+
 func (p *Peer) writeMessages(ctx context.Context, globalDone <-chan struct{}) {
 	defer p.Stop(ctx)
 
@@ -173,25 +399,23 @@ func (p *Peer) writeMessages(ctx context.Context, globalDone <-chan struct{}) {
 			}
 
 			if err := p.writeMessage(nil); err != nil {
-				slog.Debug(
+				slog.DebugContext(
+					ctx,
 					"keep-alive write error",
-					slog.String("addr", p.Addr()),
 					slog.String("error", err.Error()),
 				)
 				return
 			}
 			lastKeepAliveSend = time.Now()
 
-		case message, ok := <-p.requestsQueue:
-			if !ok {
-				return
-			}
+		case message := <-p.requestsQueue:
 			if message == nil {
 				continue
 			}
 
 			if err := p.writeMessage(message); err != nil {
-				slog.Debug(
+				slog.DebugContext(
+					ctx,
 					"peer write error",
 					slog.String("error", err.Error()),
 				)
@@ -202,15 +426,46 @@ func (p *Peer) writeMessages(ctx context.Context, globalDone <-chan struct{}) {
 }
 
 func (p *Peer) writeMessage(message *Message) error {
+	// The upload limit is waited on before the lock, which drain needs.
+	if message != nil && message.ID == MsgPiece {
+		p.m.upLimit.wait(len(message.Payload))
+	}
+
+	p.writeMu.Lock()
+	defer p.writeMu.Unlock()
 	_ = p.conn.SetWriteDeadline(time.Now().Add(p.m.cfg.WriteTimeout))
 	defer p.conn.SetWriteDeadline(time.Time{})
 
-	return WriteMessage(p.conn, message)
+	if err := WriteMessage(p.conn, message); err != nil {
+		return err
+	}
+	if message != nil && message.ID == MsgPiece &&
+		len(message.Payload) > 8 {
+		n := uint64(len(message.Payload) - 8)
+		p.uploaded.Add(n)
+		p.m.uploaded.Add(n)
+	}
+
+	return nil
 }
 
 func (p *Peer) readMessage() (*Message, error) {
-	_ = p.conn.SetReadDeadline(time.Now().Add(p.m.cfg.ReadTimeout))
-	defer p.conn.SetReadDeadline(time.Time{})
+	return p.reader.Next()
+}
 
-	return ReadMessage(p.conn)
+// queueBlock hands a copy of a piece block to the manager's block worker,
+// waiting while its queue is full so that a peer sending faster than the
+// blocks are written out is slowed down rather than buffered without end.
+func (p *Peer) queueBlock(index, begin uint32, data []byte) {
+	if p.m.blocks == nil {
+		return
+	}
+	b := block{peer: p, index: index, begin: begin}
+	b.data = getBuffer(len(data))
+	copy(b.data, data)
+	select {
+	case p.m.blocks <- b:
+	case <-p.stopped:
+		putBuffer(b.data)
+	}
 }
@@ -0,0 +1,52 @@
+package peer
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+// TestStopDrainTimeout checks that Stop is bounded by DrainTimeout even
+// while a write to a peer that reads nothing holds the connection.
+func TestStopDrainTimeout(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.DrainTimeout = 50 * time.Millisecond
+	cfg.WriteTimeout = time.Minute
+	m, err := NewManager(Opts{
+		Pieces:      1,
+		PieceLength: 1,
+		Size:        1,
+		Cfg:         &cfg,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, remote := net.Pipe()
+	defer remote.Close()
+	p := &Peer{
+		m:             m,
+		conn:          conn,
+		requestsQueue: make(chan *Message, 128),
+		stopped:       make(chan struct{}),
+	}
+	p.amInterested.Store(true)
+
+	written := make(chan error, 1)
+	go func() { written <- p.writeMessage(MessageInterested()) }()
+	// The pipe has no buffer: the write holds writeMu until it fails.
+	time.Sleep(20 * time.Millisecond)
+
+	start := time.Now()
+	p.Stop(t.Context())
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Stop took %v", elapsed)
+	}
+	select {
+	case err := <-written:
+		if err == nil {
+			t.Error("write to a peer reading nothing succeeded")
+		}
+	case <-time.After(time.Second):
+		t.Error("the stuck write outlived Stop")
+	}
+}
@@ -0,0 +1,64 @@
+package peer
+
+import (
+	"sync"
+	"time"
+)
+
+// rateLimiter is a token bucket shared by every peer of a manager. It holds
+// at most a second's worth of tokens, so an idle torrent can burst that much.
+type rateLimiter struct {
+	mu     sync.Mutex
+	rate   int64 // bytes per second; zero or less is unlimited
+	tokens float64
+	last   time.Time
+}
+
+// setRate changes the bucket's rate. Tokens already saved are kept, up to
+// the new rate, so the rate can be adjusted often without granting a fresh
+// burst each time.
+func (l *rateLimiter) setRate(rate int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if rate == l.rate {
+		return
+	}
+	if l.rate <= 0 {
+		l.tokens = float64(max(rate, 0))
+	} else {
+		elapsed := time.Since(l.last).Seconds()
+		l.tokens += elapsed * float64(l.rate)
+	}
+	l.tokens = min(l.tokens, float64(max(rate, 0)))
+	l.rate = rate
+	l.last = time.Now()
+}
+
+// wait takes n bytes from the bucket, sleeping until they are available.
+func (l *rateLimiter) wait(n int) {
+	l.mu.Lock()
+	if l.rate <= 0 {
+		l.mu.Unlock()
+		return
+	}
+
+	now := time.Now()
+	rate := float64(l.rate)
+	l.tokens = min(l.tokens+now.Sub(l.last).Seconds()*rate, rate)
+	l.last = now
+	l.tokens -= float64(n)
+	deficit := -l.tokens
+	l.mu.Unlock()
+
+	if deficit > 0 {
+		time.Sleep(time.Duration(deficit / rate * float64(time.Second)))
+	}
+}
+
+// SetRateLimits caps the torrent's download and upload rates in bytes per
+// second across all its peers; zero lifts a limit.
+func (m *Manager) SetRateLimits(download, upload int64) {
+	m.downLimit.setRate(download)
+	m.upLimit.setRate(upload)
+}
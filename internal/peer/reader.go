@@ -0,0 +1,140 @@
+package peer
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// readBufferSize is what a Reader starts with: a few 16 KiB blocks and the
+// control messages between them, read from the socket at once.
+const readBufferSize = 64 << 10
+
+// Reader reads the messages of one connection through a buffer of its own,
+// reused for every message, so that a read loop neither allocates nor takes
+// a pooled buffer per message and drains as much as the socket holds with
+// each read.
+//
+// A read timing out loses nothing: what arrived of a message stays in the
+// buffer, and the next call to Next picks it up.
+type Reader struct {
+	r   io.Reader
+	max uint32
+
+	// conn, when r has read deadlines and a timeout is given, is pushed
+	// a deadline only once half the last one has passed, not before every
+	// read.
+	conn    interface{ SetReadDeadline(time.Time) error }
+	timeout time.Duration
+	expires time.Time
+
+	buf        []byte
+	start, end int
+	msg        Message
+}
+
+// NewReader returns a Reader of r rejecting messages longer than maxLength.
+// With a timeout, reads fail once r, if it has read deadlines, has been
+// silent for between half of it and all of it.
+func NewReader(r io.Reader, maxLength uint32, timeout time.Duration) *Reader {
+	rd := &Reader{
+		r:   r,
+		max: maxLength,
+		buf: make([]byte, readBufferSize),
+	}
+	if conn, ok := r.(interface {
+		SetReadDeadline(time.Time) error
+	}); ok && timeout > 0 {
+		rd.conn, rd.timeout = conn, timeout
+	}
+	return rd
+}
+
+// Next reads the next message, nil for a keep-alive. The message and its
+// payload are the Reader's own, good only until the next call; Release on
+// it does nothing.
+func (r *Reader) Next() (*Message, error) {
+	if err := r.fill(4); err != nil {
+		return nil, err
+	}
+	length := binary.BigEndian.Uint32(r.buf[r.start:])
+	if length == 0 { // keep-alive
+		r.consume(4)
+		return nil, nil
+	}
+	if length > r.max {
+		return nil, fmt.Errorf(
+			"%w: %d > %d",
+			ErrMessageTooLarge,
+			length,
+			r.max,
+		)
+	}
+
+	size := 4 + int(length)
+	if err := r.fill(size); err != nil {
+		return nil, err
+	}
+	body := r.buf[r.start+4 : r.start+size]
+	r.msg = Message{ID: MessageID(body[0]), Payload: body[1:]}
+	r.consume(size)
+	return &r.msg, nil
+}
+
+func (r *Reader) consume(n int) {
+	r.start += n
+	if r.start == r.end {
+		r.start, r.end = 0, 0
+	}
+}
+
+// fill reads until n bytes past start are buffered, making room for them
+// first: moving what is left to the front, or growing the buffer for a
+// message larger than it, up to the longest one allowed.
+func (r *Reader) fill(n int) error {
+	if r.end-r.start >= n {
+		return nil
+	}
+	if r.start+n > len(r.buf) {
+		buf := r.buf
+		if n > len(buf) {
+			buf = make([]byte, n)
+		}
+		r.end = copy(buf, r.buf[r.start:r.end])
+		r.start, r.buf = 0, buf
+	}
+
+	for r.end-r.start < n {
+		r.extendDeadline()
+		read, err := r.r.Read(r.buf[r.end:])
+		r.end += read
+		if read > 0 {
+			continue
+		}
+		if errors.Is(err, io.EOF) && r.end > r.start {
+			return io.ErrUnexpectedEOF
+		}
+		if err == nil {
+			err = io.ErrNoProgress
+		}
+		return err
+	}
+	return nil
+}
+
+// extendDeadline pushes the read deadline a timeout away once half of the
+// last one has passed, keeping deadline updates to a couple a timeout
+// however many messages arrive.
+func (r *Reader) extendDeadline() {
+	if r.conn == nil {
+		return
+	}
+	now := time.Now()
+	if r.expires.Sub(now) >= r.timeout/2 {
+		return
+	}
+	r.expires = now.Add(r.timeout)
+	_ = r.conn.SetReadDeadline(r.expires)
+}
@@ -0,0 +1,163 @@
+package peer
+
+import (
+	"sync"
+
+	"github.com/prxssh/echo/internal/bitfield"
+	"github.com/prxssh/echo/internal/piece"
+)
+
+// blockRef names a block by its piece and its number in the piece.
+type blockRef struct {
+	index, block int
+}
+
+// requests are what a peer downloads: the pieces claimed from the picker
+// for it, and the blocks of them asked for and not yet received.
+type requests struct {
+	mu sync.Mutex
+	// claimed holds the pieces with blocks not yet asked for.
+	claimed []int
+	pending map[blockRef]struct{}
+	// done is set once the read loop is over, after which nothing more
+	// is asked for.
+	done bool
+}
+
+// downloading reports whether the manager downloads pieces, rather than
+// only fetching metadata or having nothing to store them in.
+func (m *Manager) downloading() bool {
+	return m.picker != nil && m.states != nil && m.metadata == nil
+}
+
+// updateInterest tells the peer whether it has pieces we want, after its
+// bitfield or a have.
+func (p *Peer) updateInterest() {
+	if !p.m.downloading() || p.m.seeding.Load() {
+		return
+	}
+	p.bfMu.Lock()
+	has := p.pieceBF.Clone()
+	p.bfMu.Unlock()
+
+	want := p.m.picker.Missing(has).NextSet(0) >= 0
+	if want && !p.amInterested.Swap(true) {
+		p.Send(MessageInterested())
+	}
+	if !want && p.amInterested.Swap(false) {
+		p.Send(MessageNotInterested())
+	}
+}
+
+// fillRequests keeps MaxRequests blocks asked for while the peer lets us
+// download, claiming pieces it has from the picker as those claimed run
+// out of blocks to ask for.
+func (p *Peer) fillRequests() {
+	if !p.m.downloading() || p.peerChoking.Load() ||
+		!p.amInterested.Load() {
+		return
+	}
+	r := &p.requests
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.done {
+		return
+	}
+	if r.pending == nil {
+		r.pending = make(map[blockRef]struct{})
+	}
+
+	var has bitfield.Bitfield
+	for len(r.pending) < p.m.cfg.MaxRequests {
+		if len(r.claimed) == 0 {
+			if has.Len() == 0 {
+				p.bfMu.Lock()
+				has = p.pieceBF.Clone()
+				p.bfMu.Unlock()
+			}
+			index, ok := p.m.picker.ClaimFrom(has)
+			if !ok {
+				return
+			}
+			r.claimed = append(r.claimed, index)
+		}
+
+		index := r.claimed[0]
+		block, ok := p.m.states.Next(index)
+		if !ok {
+			// Every block is asked for, by us or before; the
+			// piece is the torrent's to verify from here.
+			r.claimed = r.claimed[1:]
+			continue
+		}
+		begin, length := p.m.states.Bounds(index, block)
+		if !p.Send(MessageRequest(index, int(begin), int(length))) {
+			p.m.states.Cancel(index, block)
+			return
+		}
+		r.pending[blockRef{index, block}] = struct{}{}
+	}
+}
+
+// blockReceived notes a requested block has arrived.
+func (p *Peer) blockReceived(index, begin uint32) {
+	ref := blockRef{int(index), int(begin / piece.BlockSize)}
+	p.requests.mu.Lock()
+	delete(p.requests.pending, ref)
+	p.requests.mu.Unlock()
+}
+
+// cancelRequests gives back what the peer was downloading when it chokes
+// us: its blocks in flight become missing again and its pieces are
+// released for other peers to finish.
+func (p *Peer) cancelRequests() {
+	p.releaseRequests(false)
+}
+
+// endRequests gives back what the peer was downloading once its read loop
+// is over, and stops it asking for more.
+func (p *Peer) endRequests() {
+	p.releaseRequests(true)
+}
+
+func (p *Peer) releaseRequests(end bool) {
+	if !p.m.downloading() {
+		return
+	}
+	r := &p.requests
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	released := make(map[int]bool)
+	for ref := range r.pending {
+		p.m.states.Cancel(ref.index, ref.block)
+		released[ref.index] = true
+	}
+	for _, index := range r.claimed {
+		released[index] = true
+	}
+	for index := range released {
+		p.m.picker.Release(index)
+	}
+	r.claimed, r.pending = nil, nil
+	r.done = r.done || end
+}
+
+// Wake has every peer ask for blocks again, as when a piece that failed its
+// hash check is up for grabs and the peers are not otherwise due to look.
+func (m *Manager) Wake() {
+	if !m.downloading() {
+		return
+	}
+	m.peerMut.RLock()
+	peers := make([]*Peer, 0, len(m.peers))
+	for _, p := range m.peers {
+		peers = append(peers, p)
+	}
+	m.peerMut.RUnlock()
+
+	for _, p := range peers {
+		go p.fillRequests()
+	}
+}
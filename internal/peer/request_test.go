@@ -0,0 +1,132 @@
+package peer
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/prxssh/echo/internal/bitfield"
+	"github.com/prxssh/echo/internal/piece"
+)
+
+// downloadingPeer returns an unchoked peer with both pieces of a torrent
+// of two 2-block pieces, interested in them and allowed three requests at
+// a time, which go out in order. Its messages collect in its queue, there
+// being no connection.
+func downloadingPeer(t *testing.T) *Peer {
+	t.Helper()
+	picker := piece.NewPicker(2)
+	picker.SetSequential(true)
+	blocks := piece.NewBlocks(2*piece.BlockSize, 4*piece.BlockSize)
+	picker.TrackBlocks(blocks)
+	cfg := DefaultConfig()
+	cfg.MaxRequests = 3
+	m, err := NewManager(Opts{
+		Pieces:      2,
+		PieceLength: 2 * piece.BlockSize,
+		Size:        4 * piece.BlockSize,
+		Cfg:         &cfg,
+		Picker:      picker,
+		Blocks:      blocks,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := &Peer{
+		m:             m,
+		pieceBF:       bitfield.New(2),
+		requestsQueue: make(chan *Message, 128),
+		stopped:       make(chan struct{}),
+	}
+	p.pieceBF.Set(0)
+	p.pieceBF.Set(1)
+	p.amInterested.Store(true)
+	return p
+}
+
+// requested drains the peer's queue of the requests it sent.
+func requested(t *testing.T, p *Peer) []blockRef {
+	t.Helper()
+	var refs []blockRef
+	for len(p.requestsQueue) > 0 {
+		msg := <-p.requestsQueue
+		index, begin, _, ok := msg.ParseRequest(p.m.bounds)
+		if msg.ID != MsgRequest || !ok {
+			t.Fatalf("sent %s, want a request", msg.ID)
+		}
+		refs = append(refs, blockRef{
+			int(index),
+			int(begin / piece.BlockSize),
+		})
+	}
+	return refs
+}
+
+// checkReleased checks that the blocks not received are missing again and
+// that both pieces can be claimed by another peer.
+func checkReleased(t *testing.T, m *Manager, received blockRef) {
+	t.Helper()
+	for _, ref := range []blockRef{{0, 0}, {0, 1}, {1, 0}, {1, 1}} {
+		want := piece.BlockMissing
+		if ref == received {
+			want = piece.BlockReceived
+		}
+		if s := m.states.State(ref.index, ref.block); s != want {
+			t.Errorf("block %v is %d, want %d", ref, s, want)
+		}
+	}
+	has := bitfield.New(2)
+	has.Set(0)
+	has.Set(1)
+	for index := range 2 {
+		if _, ok := m.picker.ClaimFrom(has); !ok {
+			t.Fatalf("claim %d: a piece is still the peer's", index)
+		}
+	}
+	m.picker.Release(0)
+	m.picker.Release(1)
+}
+
+func TestReleaseRequestsOnChoke(t *testing.T) {
+	p := downloadingPeer(t)
+	p.fillRequests()
+	if got := requested(t, p); len(got) != 3 {
+		t.Fatalf("requested %v, want three blocks", got)
+	}
+
+	// Block 0 of piece 0 arrives before the choke.
+	p.blockReceived(0, 0)
+	p.m.states.Receive(0, 0)
+	p.peerChoking.Store(true)
+	p.cancelRequests()
+	checkReleased(t, p.m, blockRef{0, 0})
+
+	// Once unchoked, the rest is asked for again.
+	p.peerChoking.Store(false)
+	p.fillRequests()
+	got := requested(t, p)
+	want := []blockRef{{0, 1}, {1, 0}, {1, 1}}
+	if !slices.Equal(got, want) {
+		t.Errorf("requested %v once unchoked, want %v", got, want)
+	}
+}
+
+func TestReleaseRequestsOnDisconnect(t *testing.T) {
+	p := downloadingPeer(t)
+	p.fillRequests()
+	if got := requested(t, p); len(got) != 3 {
+		t.Fatalf("requested %v, want three blocks", got)
+	}
+
+	p.blockReceived(0, piece.BlockSize)
+	p.m.states.Receive(0, piece.BlockSize)
+	p.endRequests()
+	checkReleased(t, p.m, blockRef{0, 1})
+
+	// A late fill, as from a wake-up, asks nothing of a peer that is
+	// gone.
+	p.fillRequests()
+	if got := requested(t, p); len(got) != 0 {
+		t.Errorf("requested %v after the disconnect", got)
+	}
+}
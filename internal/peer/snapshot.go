@@ -0,0 +1,79 @@
+package peer
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/prxssh/echo/internal/events"
+)
+
+// shownRates are the transfer rates snapshots report. The counters are
+// only touched by the snapshot worker; the rates are read by anyone.
+type shownRates struct {
+	lastDownloaded uint64
+	lastUploaded   uint64
+	down           atomic.Uint64
+	up             atomic.Uint64
+}
+
+const topicSnapshot events.Topic[peersSnapshot] = "peers:snapshot"
+
+// peersSnapshot is the payload of the "peers:snapshot" event: every peer
+// connected to the torrent. A peer missing from it has disconnected.
+type peersSnapshot struct {
+	events.Torrent
+	Peers []Info `json:"peers"`
+}
+
+func (p *Peer) sampleShownRates(elapsed time.Duration) {
+	secs := elapsed.Seconds()
+	if secs <= 0 {
+		return
+	}
+
+	down, up := p.downloaded.Load(), p.uploaded.Load()
+	s := &p.shown
+	s.down.Store(uint64(float64(down-s.lastDownloaded) / secs))
+	s.up.Store(uint64(float64(up-s.lastUploaded) / secs))
+	s.lastDownloaded, s.lastUploaded = down, up
+}
+
+// emitSnapshot samples the peers' rates and reports them all.
+func (m *Manager) emitSnapshot(ctx context.Context, elapsed time.Duration) {
+	m.peerMut.RLock()
+	infos := make([]Info, 0, len(m.peers))
+	for _, p := range m.peers {
+		p.sampleShownRates(elapsed)
+		infos = append(infos, p.info())
+	}
+	m.peerMut.RUnlock()
+
+	scope, _ := events.TorrentOf(ctx)
+	topicSnapshot.Emit(ctx, peersSnapshot{Torrent: scope, Peers: infos})
+}
+
+// runSnapshots emits a "peers:snapshot" every SnapshotInterval, and an
+// empty one when the manager stops, since its peers are dropped then.
+func (m *Manager) runSnapshots(ctx context.Context) {
+	ticker := time.NewTicker(m.cfg.SnapshotInterval)
+	defer ticker.Stop()
+
+	last := time.Now()
+	for {
+		select {
+		case <-m.done:
+			scope, _ := events.TorrentOf(ctx)
+			topicSnapshot.Emit(
+				ctx,
+				peersSnapshot{Torrent: scope, Peers: []Info{}},
+			)
+			return
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			m.emitSnapshot(ctx, now.Sub(last))
+			last = now
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package peer
+
+import (
+	"context"
+	"net"
+	"time"
+)
+
+// Transport opens the byte stream a peer connection runs over. The wire
+// protocol on top is the same regardless of transport, which is what lets
+// non-TCP carriers such as WebRTC data channels (WebTorrent) be plugged in
+// without touching the message handling.
+type Transport interface {
+	Dial(
+		ctx context.Context,
+		addr string,
+		timeout time.Duration,
+	) (net.Conn, error)
+}
+
+type tcpTransport struct{}
+
+func (tcpTransport) Dial(
+	ctx context.Context,
+	addr string,
+	timeout time.Duration,
+) (net.Conn, error) {
+	d := net.Dialer{Timeout: timeout}
+	return d.DialContext(ctx, "tcp", addr)
+}
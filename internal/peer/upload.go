@@ -0,0 +1,166 @@
+package peer
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"sync"
+)
+
+// maxUploadQueue bounds the requests a peer can have waiting on us; more
+// are dropped.
+const maxUploadQueue = 256
+
+// blockRequest is a block a peer asked us for.
+type blockRequest struct {
+	index, begin, length uint32
+}
+
+// uploads are the blocks a peer asked us for and has not had yet, served
+// in the order asked. ready is signalled as one is added.
+type uploads struct {
+	mu      sync.Mutex
+	pending []blockRequest
+	ready   chan struct{}
+}
+
+// uploading reports whether the manager serves the pieces it has.
+func (m *Manager) uploading() bool {
+	return m.picker != nil && m.readBlock != nil && m.metadata == nil
+}
+
+// Have tells every peer that piece index is complete, so that they can ask
+// us for it.
+func (m *Manager) Have(index int) {
+	if !m.uploading() {
+		return
+	}
+	m.peerMut.RLock()
+	peers := make([]*Peer, 0, len(m.peers))
+	for _, peer := range m.peers {
+		peers = append(peers, peer)
+	}
+	m.peerMut.RUnlock()
+
+	for _, peer := range peers {
+		peer.Send(MessageHave(index))
+	}
+}
+
+// sendBitfield tells a peer that just connected which pieces we have.
+func (p *Peer) sendBitfield() {
+	if !p.m.uploading() {
+		return
+	}
+	if have := p.m.picker.Bitfield(); have.NextSet(0) >= 0 {
+		p.Send(MessageBitfield(have.ToBytes()))
+	}
+}
+
+// queueUpload takes a request the peer made, unless it is choked, the piece
+// is not one we have or too many of its requests are waiting already.
+func (p *Peer) queueUpload(index, begin, length uint32) {
+	if !p.m.uploading() || p.amChoking.Load() ||
+		!p.m.picker.Have(int(index)) {
+		return
+	}
+	r := blockRequest{index, begin, length}
+	u := &p.uploads
+	u.mu.Lock()
+	queued := len(u.pending) < maxUploadQueue &&
+		!slices.Contains(u.pending, r)
+	if queued {
+		u.pending = append(u.pending, r)
+	}
+	u.mu.Unlock()
+
+	if queued {
+		select {
+		case u.ready <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// cancelUpload drops a request the peer no longer wants.
+func (p *Peer) cancelUpload(index, begin, length uint32) {
+	r := blockRequest{index, begin, length}
+	u := &p.uploads
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	u.pending = slices.DeleteFunc(u.pending, func(q blockRequest) bool {
+		return q == r
+	})
+}
+
+// clearUploads drops every request of a peer we choke, which it then
+// expects to go unanswered.
+func (p *Peer) clearUploads() {
+	u := &p.uploads
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	u.pending = nil
+}
+
+func (u *uploads) next() (blockRequest, bool) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if len(u.pending) == 0 {
+		return blockRequest{}, false
+	}
+	r := u.pending[0]
+	u.pending = u.pending[1:]
+	return r, true
+}
+
+// serveUploads sends the peer the blocks it asked for until it disconnects.
+// Each block is read only once its turn comes, so a cancel or a choke
+// takes back every request not yet being sent, and the upload limit is
+// waited on as it is written.
+func (p *Peer) serveUploads(ctx context.Context, globalDone <-chan struct{}) {
+	defer p.Stop(ctx)
+
+	for {
+		r, ok := p.uploads.next()
+		if !ok {
+			select {
+			case <-globalDone:
+				return
+			case <-p.stopped:
+				return
+			case <-p.uploads.ready:
+			}
+			continue
+		}
+
+		block := getBuffer(int(r.length))
+		err := p.m.readBlock(r.index, r.begin, block)
+		if err != nil {
+			putBuffer(block)
+			slog.WarnContext(
+				ctx,
+				"reading requested block failed",
+				slog.Int("piece", int(r.index)),
+				slog.String("error", err.Error()),
+			)
+			continue
+		}
+		message := MessagePiece(int(r.index), int(r.begin), block)
+		putBuffer(block)
+		// A choke while the block was read took the request back.
+		if p.amChoking.Load() {
+			continue
+		}
+		if err := p.writeMessage(message); err != nil {
+			slog.DebugContext(
+				ctx,
+				"peer write error",
+				slog.String("error", err.Error()),
+			)
+			return
+		}
+	}
+}
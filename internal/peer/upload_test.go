@@ -0,0 +1,108 @@
+package peer
+
+import (
+	"bytes"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/prxssh/echo/internal/piece"
+)
+
+// uploadingPeer returns an unchoked peer of a torrent of two 2-block
+// pieces, of which we have the first, and the far end of its connection.
+// Blocks read are filled with the piece's index and the block's number.
+func uploadingPeer(t *testing.T) (*Peer, net.Conn) {
+	t.Helper()
+	picker := piece.NewPicker(2)
+	picker.Done(0)
+	cfg := DefaultConfig()
+	cfg.DrainTimeout = 0
+	m, err := NewManager(Opts{
+		Pieces:      2,
+		PieceLength: 2 * piece.BlockSize,
+		Size:        4 * piece.BlockSize,
+		Cfg:         &cfg,
+		Picker:      picker,
+		ReadBlock: func(index, begin uint32, block []byte) error {
+			fill := byte(10*index + begin/piece.BlockSize)
+			copy(block, bytes.Repeat([]byte{fill}, len(block)))
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conn, far := net.Pipe()
+	t.Cleanup(func() { far.Close() })
+	p := &Peer{
+		m:             m,
+		conn:          conn,
+		requestsQueue: make(chan *Message, 128),
+		stopped:       make(chan struct{}),
+		uploads:       uploads{ready: make(chan struct{}, 1)},
+	}
+	return p, far
+}
+
+func pendingUploads(p *Peer) int {
+	p.uploads.mu.Lock()
+	defer p.uploads.mu.Unlock()
+	return len(p.uploads.pending)
+}
+
+func TestServeUploads(t *testing.T) {
+	p, far := uploadingPeer(t)
+	p.queueUpload(0, 0, piece.BlockSize)
+	p.queueUpload(0, piece.BlockSize, piece.BlockSize)
+	// Piece 1 is not ours to send.
+	p.queueUpload(1, 0, piece.BlockSize)
+	p.cancelUpload(0, 0, piece.BlockSize)
+	if n := pendingUploads(p); n != 1 {
+		t.Fatalf("%d requests pending, want 1", n)
+	}
+
+	go p.serveUploads(t.Context(), nil)
+	defer p.Stop(t.Context())
+
+	_ = far.SetReadDeadline(time.Now().Add(5 * time.Second))
+	message, err := NewReader(far, DefaultMaxMessageLength, 0).Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	index, begin, block, ok := message.ParsePiece(p.m.bounds)
+	if message.ID != MsgPiece || !ok {
+		t.Fatalf("sent %s, want a piece", message.ID)
+	}
+	if index != 0 || begin != piece.BlockSize {
+		t.Errorf("sent block at %d in piece %d", begin, index)
+	}
+	if !bytes.Equal(block, bytes.Repeat([]byte{1}, piece.BlockSize)) {
+		t.Error("sent the wrong data")
+	}
+	// The write is counted once it returns, after the block is read.
+	deadline := time.Now().Add(5 * time.Second)
+	for p.uploaded.Load() == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if up := p.uploaded.Load(); up != piece.BlockSize {
+		t.Errorf("uploaded %d, want %d", up, piece.BlockSize)
+	}
+}
+
+func TestChokeDropsUploads(t *testing.T) {
+	p, _ := uploadingPeer(t)
+	p.queueUpload(0, 0, piece.BlockSize)
+	p.queueUpload(0, piece.BlockSize, piece.BlockSize)
+	p.choke()
+	if n := pendingUploads(p); n != 0 {
+		t.Fatalf("%d requests pending after the choke", n)
+	}
+
+	// Requests made while choked are ignored.
+	p.queueUpload(0, 0, piece.BlockSize)
+	if n := pendingUploads(p); n != 0 {
+		t.Errorf("%d requests pending from a choked peer", n)
+	}
+}
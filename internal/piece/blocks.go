@@ -0,0 +1,283 @@
+package piece
+
+import (
+	"encoding/binary"
+	"errors"
+	"slices"
+	"sync"
+)
+
+// BlockSize is the size pieces are requested in, all but a piece's last
+// block being this long.
+const BlockSize = 16 << 10
+
+// blocksVersion starts the encoding MarshalBinary writes.
+const blocksVersion = 1
+
+// BlockState is where a block is on its way to being part of a verified
+// piece.
+type BlockState uint8
+
+const (
+	BlockMissing BlockState = iota
+	BlockRequested
+	BlockReceived
+	// BlockVerified is a block checked on its own, against its BEP 52
+	// hash tree leaf, ahead of the rest of its piece.
+	BlockVerified
+)
+
+// Blocks tracks the blocks of the pieces that are half complete: some of
+// their blocks are requested or have arrived, but the piece is not yet
+// done. The picker finishes such pieces first, writes mark blocks as
+// received, and the session saves them so a restart does not download them
+// again. Pieces with no block past missing, and done ones, are not kept.
+type Blocks struct {
+	mu          sync.Mutex
+	pieceLength int64
+	size        int64
+	n           int
+	pieces      map[int][]BlockState
+}
+
+// NewBlocks returns the tracker of a torrent of size bytes cut into pieces
+// of pieceLength.
+func NewBlocks(pieceLength, size int64) *Blocks {
+	n := 0
+	if pieceLength > 0 {
+		n = int((size + pieceLength - 1) / pieceLength)
+	}
+	return &Blocks{
+		pieceLength: pieceLength,
+		size:        size,
+		n:           n,
+		pieces:      make(map[int][]BlockState),
+	}
+}
+
+// Count returns how many blocks piece index has.
+func (b *Blocks) Count(index int) int {
+	if index < 0 || index >= b.n {
+		return 0
+	}
+	return int((b.pieceSize(index) + BlockSize - 1) / BlockSize)
+}
+
+// Bounds returns the offset in its piece and the length of a block.
+func (b *Blocks) Bounds(index, block int) (begin, length int64) {
+	begin = int64(block) * BlockSize
+	return begin, min(BlockSize, b.pieceSize(index)-begin)
+}
+
+func (b *Blocks) pieceSize(index int) int64 {
+	off := int64(index) * b.pieceLength
+	return min(b.pieceLength, b.size-off)
+}
+
+// states returns the blocks of piece index, tracking it from now on if
+// need be; nil for no such piece. mu must be held.
+func (b *Blocks) states(index int) []BlockState {
+	if s, ok := b.pieces[index]; ok {
+		return s
+	}
+	count := b.Count(index)
+	if count == 0 {
+		return nil
+	}
+	s := make([]BlockState, count)
+	b.pieces[index] = s
+	return s
+}
+
+// State returns the state of a block.
+func (b *Blocks) State(index, block int) BlockState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	s := b.pieces[index]
+	if block < 0 || block >= len(s) {
+		return BlockMissing
+	}
+	return s[block]
+}
+
+// Next marks the first missing block of piece index as requested and
+// returns it, or false if every block is already on its way.
+func (b *Blocks) Next(index int) (int, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	s := b.states(index)
+	block := slices.Index(s, BlockMissing)
+	if block < 0 {
+		return 0, false
+	}
+	s[block] = BlockRequested
+	return block, true
+}
+
+// Cancel returns a requested block that will not arrive to missing.
+func (b *Blocks) Cancel(index, block int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	s := b.pieces[index]
+	if block >= 0 && block < len(s) && s[block] == BlockRequested {
+		s[block] = BlockMissing
+	}
+}
+
+// Receive marks the block at offset begin of piece index as arrived, and
+// reports whether the piece has every block now, ready to verify.
+func (b *Blocks) Receive(index int, begin int64) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	s := b.states(index)
+	block := int(begin / BlockSize)
+	if begin < 0 || begin%BlockSize != 0 || block >= len(s) {
+		return false
+	}
+	if s[block] != BlockVerified {
+		s[block] = BlockReceived
+	}
+	return !slices.ContainsFunc(s, func(st BlockState) bool {
+		return st < BlockReceived
+	})
+}
+
+// Verify marks a received block as checked against its hash.
+func (b *Blocks) Verify(index, block int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	s := b.pieces[index]
+	if block >= 0 && block < len(s) && s[block] == BlockReceived {
+		s[block] = BlockVerified
+	}
+}
+
+// Reset forgets every block of piece index, as when the piece fails its
+// hash check and has to be downloaded again in full.
+func (b *Blocks) Reset(index int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	delete(b.pieces, index)
+}
+
+// Done forgets piece index once it is verified and stored; it is the
+// picker's to track from then on.
+func (b *Blocks) Done(index int) {
+	b.Reset(index)
+}
+
+// Partial returns the pieces with blocks received but not yet done, in
+// order.
+func (b *Blocks) Partial() []int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var partial []int
+	for index, s := range b.pieces {
+		if slices.ContainsFunc(s, received) {
+			partial = append(partial, index)
+		}
+	}
+	slices.Sort(partial)
+	return partial
+}
+
+func received(s BlockState) bool {
+	return s >= BlockReceived
+}
+
+// MarshalBinary encodes the blocks that have arrived, packed four to a
+// byte after the index of their piece and its count of blocks. Requested
+// blocks are left out, as the requests do not outlive the connections they
+// were sent on.
+func (b *Blocks) MarshalBinary() ([]byte, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	indices := make([]int, 0, len(b.pieces))
+	for index, s := range b.pieces {
+		if slices.ContainsFunc(s, received) {
+			indices = append(indices, index)
+		}
+	}
+	slices.Sort(indices)
+
+	out := []byte{blocksVersion}
+	out = binary.AppendUvarint(out, uint64(len(indices)))
+	for _, index := range indices {
+		s := b.pieces[index]
+		out = binary.AppendUvarint(out, uint64(index))
+		out = binary.AppendUvarint(out, uint64(len(s)))
+		packed := make([]byte, (len(s)+3)/4)
+		for i, st := range s {
+			if st == BlockRequested {
+				st = BlockMissing
+			}
+			packed[i/4] |= byte(st) << (2 * (i % 4))
+		}
+		out = append(out, packed...)
+	}
+	return out, nil
+}
+
+// UnmarshalBinary replaces the blocks tracked with those MarshalBinary
+// encoded for the same torrent, failing for pieces whose count of blocks
+// is not theirs here.
+func (b *Blocks) UnmarshalBinary(data []byte) error {
+	if len(data) == 0 || data[0] != blocksVersion {
+		return errors.New("piece: unknown block state encoding")
+	}
+	data = data[1:]
+	count, n := binary.Uvarint(data)
+	if n <= 0 {
+		return errors.New("piece: truncated block state")
+	}
+	data = data[n:]
+
+	pieces := make(map[int][]BlockState, min(count, uint64(b.n)))
+	for range count {
+		index, n := binary.Uvarint(data)
+		if n <= 0 || index >= uint64(b.n) {
+			return errors.New("piece: bad piece in block state")
+		}
+		data = data[n:]
+		blocks, n := binary.Uvarint(data)
+		if n <= 0 {
+			return errors.New("piece: truncated block state")
+		}
+		if blocks != uint64(b.Count(int(index))) {
+			return errors.New(
+				"piece: bad block count in block state",
+			)
+		}
+		data = data[n:]
+		s := make([]BlockState, blocks)
+		size := (len(s) + 3) / 4
+		if len(data) < size {
+			return errors.New("piece: truncated block state")
+		}
+		for i := range s {
+			s[i] = BlockState(data[i/4] >> (2 * (i % 4)) & 3)
+			if s[i] == BlockRequested {
+				s[i] = BlockMissing
+			}
+		}
+		data = data[size:]
+		pieces[int(index)] = s
+	}
+	if len(data) > 0 {
+		return errors.New("piece: trailing data in block state")
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.pieces = pieces
+	return nil
+}
@@ -0,0 +1,114 @@
+package piece
+
+import (
+	"slices"
+	"testing"
+)
+
+// testBlocks tracks a torrent of three 64 KiB pieces and a last one of
+// 20 KiB, four blocks each but the last, which has two.
+func testBlocks() *Blocks {
+	return NewBlocks(4*BlockSize, 3*4*BlockSize+20<<10)
+}
+
+func TestBlocksMarshalRoundTrip(t *testing.T) {
+	b := testBlocks()
+	b.Receive(0, 0)
+	b.Receive(0, 2*BlockSize)
+	b.Verify(0, 2)
+	b.Next(0) // block 1, requested: saved as missing
+	b.Next(1) // a piece with only requests is not saved
+	b.Receive(3, BlockSize)
+
+	data, err := b.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := testBlocks()
+	if err := got.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+
+	if p := got.Partial(); !slices.Equal(p, []int{0, 3}) {
+		t.Errorf("Partial = %v; want [0 3]", p)
+	}
+	want := map[int][]BlockState{
+		0: {BlockReceived, BlockMissing, BlockVerified, BlockMissing},
+		1: {BlockMissing, BlockMissing, BlockMissing, BlockMissing},
+		3: {BlockMissing, BlockReceived},
+	}
+	for index, states := range want {
+		for block, st := range states {
+			if s := got.State(index, block); s != st {
+				t.Errorf(
+					"State(%d, %d) = %d; want %d",
+					index,
+					block,
+					s,
+					st,
+				)
+			}
+		}
+	}
+
+	// Nothing received encodes to nothing to restore.
+	data, err = NewBlocks(4*BlockSize, 4*BlockSize).MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := got.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if p := got.Partial(); len(p) != 0 {
+		t.Errorf("Partial = %v after restoring nothing", p)
+	}
+}
+
+func TestBlocksUnmarshalTruncated(t *testing.T) {
+	b := testBlocks()
+	b.Receive(0, 0)
+	b.Receive(2, 3*BlockSize)
+	data, err := b.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for n := range len(data) {
+		got := testBlocks()
+		got.Receive(1, 0)
+		if err := got.UnmarshalBinary(data[:n]); err == nil {
+			t.Errorf("%d of %d bytes accepted", n, len(data))
+		}
+		// A failed restore leaves what was tracked alone.
+		if p := got.Partial(); !slices.Equal(p, []int{1}) {
+			t.Errorf("%d bytes: Partial = %v", n, p)
+		}
+	}
+
+	if err := b.UnmarshalBinary(append(data, 0)); err == nil {
+		t.Error("trailing byte accepted")
+	}
+}
+
+func TestBlocksUnmarshalMismatch(t *testing.T) {
+	tests := []struct {
+		name  string
+		from  *Blocks
+		index int
+	}{
+		{"more pieces", NewBlocks(4*BlockSize, 8*4*BlockSize), 6},
+		{"fewer blocks", NewBlocks(2*BlockSize, 8*2*BlockSize), 1},
+		{"more blocks", NewBlocks(8*BlockSize, 4*8*BlockSize), 1},
+		{"short last piece", NewBlocks(4*BlockSize, 4*4*BlockSize), 3},
+	}
+	for _, tt := range tests {
+		tt.from.Receive(tt.index, 0)
+		data, err := tt.from.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := testBlocks().UnmarshalBinary(data); err == nil {
+			t.Errorf("%s: block state accepted", tt.name)
+		}
+	}
+}
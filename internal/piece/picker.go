@@ -0,0 +1,305 @@
+package piece
+
+import (
+	"math/rand/v2"
+	"sync"
+
+	"github.com/prxssh/echo/internal/bitfield"
+)
+
+// Picker hands out pieces to downloaders so no two sources fetch the same
+// piece at once, and tracks which pieces are complete.
+type Picker struct {
+	mu      sync.Mutex
+	n       int
+	have    bitfield.Bitfield
+	claimed bitfield.Bitfield
+	// received holds the claimed pieces whose data has arrived but is not
+	// yet verified and stored.
+	received bitfield.Bitfield
+	done     int
+	// wanted, unless empty, limits Claim to the pieces it holds.
+	wanted bitfield.Bitfield
+	// priorities, when set, has Claim hand out the pieces with the
+	// highest value first.
+	priorities []int
+	// sequential makes Claim hand out pieces in order; otherwise it
+	// starts from a random piece so sources spread over the torrent.
+	sequential bool
+	// blocks, when set, tracks the half-complete pieces, which Claim
+	// hands out before any other.
+	blocks *Blocks
+}
+
+func NewPicker(n int) *Picker {
+	return &Picker{
+		n:        n,
+		have:     bitfield.New(n),
+		claimed:  bitfield.New(n),
+		received: bitfield.New(n),
+	}
+}
+
+// Claim reserves a piece that is neither complete nor claimed, from those
+// with the highest priority: the lowest in sequential mode, the first after
+// a random one otherwise. The caller must follow up with Done or Release.
+func (p *Picker) Claim() (int, bool) {
+	return p.claim(bitfield.Bitfield{})
+}
+
+// ClaimFrom is Claim limited to the pieces set in has, such as a peer's
+// bitfield.
+func (p *Picker) ClaimFrom(has bitfield.Bitfield) (int, bool) {
+	if has.Len() == 0 {
+		return 0, false
+	}
+	return p.claim(has)
+}
+
+// claim is Claim limited to the pieces in has, unless it is empty.
+func (p *Picker) claim(has bitfield.Bitfield) (int, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	claimable := func(i int) bool {
+		return (has.Len() == 0 || has.Has(i)) && p.claimable(i)
+	}
+	if p.blocks != nil {
+		for _, i := range p.blocks.Partial() {
+			if claimable(i) {
+				p.claimed.Set(i)
+				return i, true
+			}
+		}
+	}
+
+	start := 0
+	if !p.sequential && p.n > 0 {
+		start = rand.IntN(p.n)
+	}
+	best, bestPriority := -1, 0
+	for k := 0; k < p.n; k++ {
+		i := (start + k) % p.n
+		if !claimable(i) {
+			continue
+		}
+		if p.priorities == nil {
+			best = i
+			break
+		}
+		if best < 0 || p.priorities[i] > bestPriority {
+			best, bestPriority = i, p.priorities[i]
+		}
+	}
+	if best < 0 {
+		return 0, false
+	}
+	p.claimed.Set(best)
+	return best, true
+}
+
+// claimable reports whether piece i is wanted, and neither complete nor
+// claimed; mu must be held.
+func (p *Picker) claimable(i int) bool {
+	if p.wanted.Len() > 0 && !p.wanted.Has(i) {
+		return false
+	}
+	return !p.have.Has(i) && !p.claimed.Has(i)
+}
+
+// TrackBlocks has the picker finish the half-complete pieces b tracks
+// before starting others, and b forget the pieces that are done.
+func (p *Picker) TrackBlocks(b *Blocks) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.blocks = b
+}
+
+// SetWanted restricts downloading to the pieces set in wanted; an empty
+// bitfield wants every piece. Pieces already claimed are left to finish.
+func (p *Picker) SetWanted(wanted bitfield.Bitfield) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.wanted = wanted
+}
+
+// SetPriorities orders Claim by the priority of each piece; nil gives them
+// all the same.
+func (p *Picker) SetPriorities(priorities []int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if priorities != nil && len(priorities) != p.n {
+		priorities = nil
+	}
+	p.priorities = priorities
+}
+
+// SetSequential switches between handing out pieces in order, as streaming
+// a file needs, and spreading claims over the torrent.
+func (p *Picker) SetSequential(sequential bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.sequential = sequential
+}
+
+// Release returns a claimed piece that could not be completed.
+func (p *Picker) Release(index int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.claimed.Clear(index)
+	p.received.Clear(index)
+}
+
+// Received notes that a claimed piece's data has arrived, ahead of its
+// verification.
+func (p *Picker) Received(index int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.claimed.Has(index) {
+		p.received.Set(index)
+	}
+}
+
+// Done marks a piece as verified and stored.
+func (p *Picker) Done(index int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.claimed.Clear(index)
+	p.received.Clear(index)
+	if p.blocks != nil {
+		p.blocks.Done(index)
+	}
+	if index >= 0 && index < p.n && !p.have.Has(index) {
+		p.have.Set(index)
+		p.done++
+	}
+}
+
+// Forget marks a completed piece as missing again, as when its data on
+// disk no longer verifies.
+func (p *Picker) Forget(index int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.blocks != nil {
+		p.blocks.Reset(index)
+	}
+	if index >= 0 && index < p.n && p.have.Has(index) {
+		p.have.Clear(index)
+		p.done--
+	}
+}
+
+func (p *Picker) Have(index int) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.have.Has(index)
+}
+
+// Missing returns the pieces set in has, a peer's bitfield, that are wanted
+// and not yet complete: those worth asking the peer for.
+func (p *Picker) Missing(has bitfield.Bitfield) bitfield.Bitfield {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	missing := bitfield.New(p.n).Or(has).AndNot(p.have)
+	if p.wanted.Len() > 0 {
+		missing = missing.And(p.wanted)
+	}
+	return missing
+}
+
+// Complete reports whether every piece is done.
+func (p *Picker) Complete() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.done == p.n
+}
+
+// WantedComplete reports whether every wanted piece is done; with none
+// singled out, every piece.
+func (p *Picker) WantedComplete() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.wanted.Len() == 0 {
+		return p.done == p.n
+	}
+	return p.wanted.AndNot(p.have).NextSet(0) < 0
+}
+
+// Bitfield returns a copy of the completed pieces.
+func (p *Picker) Bitfield() bitfield.Bitfield {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.have.Clone()
+}
+
+// State is where a piece is on its way to disk.
+type State uint8
+
+const (
+	Missing State = iota
+	Requested
+	Downloaded
+	Verified
+)
+
+// States returns the state of every piece.
+func (p *Picker) States() []State {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	states := make([]State, p.n)
+	for i := range states {
+		switch {
+		case p.have.Has(i):
+			states[i] = Verified
+		case p.received.Has(i):
+			states[i] = Downloaded
+		case p.claimed.Has(i):
+			states[i] = Requested
+		}
+	}
+	return states
+}
+
+// Stats counts the pieces in each state, for diagnosing a download that
+// has stalled.
+type Stats struct {
+	Pieces   int `json:"pieces"`
+	Verified int `json:"verified"`
+	Claimed  int `json:"claimed"`
+	Received int `json:"received"`
+	// Wanted counts the pieces still to download.
+	Wanted     int  `json:"wanted"`
+	Sequential bool `json:"sequential"`
+}
+
+func (p *Picker) Stats() Stats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	s := Stats{
+		Pieces:     p.n,
+		Verified:   p.done,
+		Claimed:    p.claimed.Count(),
+		Received:   p.received.Count(),
+		Sequential: p.sequential,
+		Wanted:     p.n - p.done,
+	}
+	if p.wanted.Len() > 0 {
+		s.Wanted = p.wanted.AndNot(p.have).Count()
+	}
+	return s
+}
@@ -0,0 +1,50 @@
+package piece
+
+import (
+	"testing"
+
+	"github.com/prxssh/echo/internal/bitfield"
+)
+
+func TestClaimFrom(t *testing.T) {
+	p := NewPicker(8)
+	p.Done(2)
+
+	has := bitfield.New(8)
+	has.Set(2)
+	has.Set(5)
+	index, ok := p.ClaimFrom(has)
+	if !ok || index != 5 {
+		t.Fatalf("ClaimFrom = %d, %v; want 5, true", index, ok)
+	}
+	if index, ok := p.ClaimFrom(has); ok {
+		t.Errorf("claimed %d, which the peer lacks or is taken", index)
+	}
+	if _, ok := p.ClaimFrom(bitfield.Bitfield{}); ok {
+		t.Error("claimed from an empty bitfield")
+	}
+
+	p.Release(5)
+	if index, ok := p.ClaimFrom(has); !ok || index != 5 {
+		t.Errorf("ClaimFrom after Release = %d, %v", index, ok)
+	}
+}
+
+func TestWantedComplete(t *testing.T) {
+	p := NewPicker(4)
+	p.Done(0)
+	if p.WantedComplete() {
+		t.Fatal("complete with every piece wanted and one done")
+	}
+	wanted := bitfield.New(4)
+	wanted.Set(0)
+	wanted.Set(2)
+	p.SetWanted(wanted)
+	if p.WantedComplete() {
+		t.Fatal("complete with piece 2 missing")
+	}
+	p.Done(2)
+	if !p.WantedComplete() {
+		t.Error("not complete with every wanted piece done")
+	}
+}
@@ -0,0 +1,19 @@
+// Package power reports whether the machine is running on battery and
+// whether its network connection is metered, so transfers can back off.
+package power
+
+// State is the machine's power source and network cost. Conditions a
+// platform cannot detect read as false.
+type State struct {
+	OnBattery bool `json:"onBattery"`
+	Metered   bool `json:"metered"`
+}
+
+// Read returns the current state.
+func Read() (State, error) {
+	battery, err := onBattery()
+	if err != nil {
+		return State{}, err
+	}
+	return State{OnBattery: battery, Metered: metered()}, nil
+}
@@ -0,0 +1,20 @@
+package power
+
+import (
+	"os/exec"
+	"strings"
+)
+
+func onBattery() (bool, error) {
+	out, err := exec.Command("pmset", "-g", "batt").Output()
+	if err != nil {
+		return false, err
+	}
+	return strings.Contains(string(out), "'Battery Power'"), nil
+}
+
+// metered is always false: macOS offers no way to ask for Low Data Mode
+// outside its Network framework.
+func metered() bool {
+	return false
+}
@@ -0,0 +1,72 @@
+package power
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+const powerSupplyDir = "/sys/class/power_supply"
+
+func onBattery() (bool, error) {
+	return onBatteryIn(powerSupplyDir)
+}
+
+// onBatteryIn reads the power supplies below dir: the machine is on
+// battery when it has one and no mains or USB supply is online.
+func onBatteryIn(dir string) (bool, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	var battery, external bool
+	for _, e := range entries {
+		path := filepath.Join(dir, e.Name())
+		switch read(filepath.Join(path, "type")) {
+		case "Battery":
+			// Peripherals such as mice report batteries too.
+			if read(filepath.Join(path, "scope")) != "Device" {
+				battery = true
+			}
+		case "Mains", "USB", "USB_C", "USB_PD":
+			if read(filepath.Join(path, "online")) == "1" {
+				external = true
+			}
+		}
+	}
+	return battery && !external, nil
+}
+
+func read(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// metered asks NetworkManager, whose Metered property is 1 or 3 (yes or
+// guessed yes) on metered connections. Without it nothing is metered.
+func metered() bool {
+	out, err := exec.Command(
+		"busctl",
+		"get-property",
+		"org.freedesktop.NetworkManager",
+		"/org/freedesktop/NetworkManager",
+		"org.freedesktop.NetworkManager",
+		"Metered",
+	).Output()
+	if err != nil {
+		return false
+	}
+	switch strings.TrimSpace(string(out)) {
+	case "u 1", "u 3":
+		return true
+	}
+	return false
+}
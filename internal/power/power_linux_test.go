@@ -0,0 +1,75 @@
+package power
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestOnBatteryIn(t *testing.T) {
+	supplies := func(t *testing.T, files map[string]string) string {
+		dir := t.TempDir()
+		for name, content := range files {
+			path := filepath.Join(dir, name)
+			err := os.MkdirAll(filepath.Dir(path), 0o755)
+			if err == nil {
+				data := []byte(content + "\n")
+				err = os.WriteFile(path, data, 0o644)
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		return dir
+	}
+
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  bool
+	}{
+		{
+			name: "desktop",
+			files: map[string]string{
+				"AC/type":   "Mains",
+				"AC/online": "1",
+			},
+		},
+		{
+			name: "laptop on AC",
+			files: map[string]string{
+				"AC/type":    "Mains",
+				"AC/online":  "1",
+				"BAT0/type":  "Battery",
+				"BAT0/scope": "System",
+			},
+		},
+		{
+			name: "laptop unplugged",
+			files: map[string]string{
+				"AC/type":   "Mains",
+				"AC/online": "0",
+				"BAT0/type": "Battery",
+			},
+			want: true,
+		},
+		{
+			name: "wireless mouse",
+			files: map[string]string{
+				"hid-mouse/type":  "Battery",
+				"hid-mouse/scope": "Device",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := onBatteryIn(supplies(t, tt.files))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got %v; want %v", got, tt.want)
+			}
+		})
+	}
+}
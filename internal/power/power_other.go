@@ -0,0 +1,11 @@
+//go:build !linux && !darwin && !windows
+
+package power
+
+func onBattery() (bool, error) {
+	return false, nil
+}
+
+func metered() bool {
+	return false
+}
@@ -0,0 +1,40 @@
+package power
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+var getSystemPowerStatus = syscall.NewLazyDLL("kernel32.dll").
+	NewProc("GetSystemPowerStatus")
+
+// systemPowerStatus is SYSTEM_POWER_STATUS.
+type systemPowerStatus struct {
+	acLineStatus        byte
+	batteryFlag         byte
+	batteryLifePercent  byte
+	systemStatusFlag    byte
+	batteryLifeTime     uint32
+	batteryFullLifeTime uint32
+}
+
+const (
+	acOffline        = 0
+	batteryNoBattery = 128
+)
+
+func onBattery() (bool, error) {
+	var s systemPowerStatus
+	r, _, err := getSystemPowerStatus.Call(uintptr(unsafe.Pointer(&s)))
+	if r == 0 {
+		return false, err
+	}
+	return s.acLineStatus == acOffline &&
+		s.batteryFlag&batteryNoBattery == 0, nil
+}
+
+// metered is always false: the connection cost is only exposed through
+// WinRT.
+func metered() bool {
+	return false
+}
@@ -0,0 +1,112 @@
+package rss
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// episodeRe finds "S01E02" or "1x02" in a title.
+var episodeRe = regexp.MustCompile(
+	`(?i)\bs(\d{1,2})[ ._-]?e(\d{1,3})\b|\b(\d{1,2})x(\d{1,3})\b`,
+)
+
+// Episode returns the season and episode numbers found in title.
+func Episode(title string) (season, episode int, ok bool) {
+	m := episodeRe.FindStringSubmatch(title)
+	if m == nil {
+		return 0, 0, false
+	}
+	s, e := m[1], m[2]
+	if s == "" {
+		s, e = m[3], m[4]
+	}
+	season, _ = strconv.Atoi(s)
+	episode, _ = strconv.Atoi(e)
+	return season, episode, true
+}
+
+// episodeRange covers episodes from..to of one season. A to of -1 leaves
+// the range open, running on through every later season.
+type episodeRange struct {
+	season   int
+	from, to int
+}
+
+// EpisodeFilter is a set of episodes written as semicolon separated terms:
+// "1x5" is a single episode, "1x5-8" a range within season 1, "1x5-" episode
+// 5 of season 1 and everything after it, and "2x" all of season 2.
+type EpisodeFilter []episodeRange
+
+// ParseEpisodeFilter parses the syntax described on EpisodeFilter. An empty
+// string gives an empty filter, which matches everything.
+func ParseEpisodeFilter(s string) (EpisodeFilter, error) {
+	var f EpisodeFilter
+	for term := range strings.SplitSeq(s, ";") {
+		term = strings.TrimSpace(term)
+		if term == "" {
+			continue
+		}
+		r, err := parseEpisodeTerm(strings.ToLower(term))
+		if err != nil {
+			return nil, fmt.Errorf("rss: %q: %w", term, err)
+		}
+		f = append(f, r)
+	}
+	return f, nil
+}
+
+func parseEpisodeTerm(term string) (episodeRange, error) {
+	seasonStr, eps, ok := strings.Cut(term, "x")
+	if !ok {
+		return episodeRange{}, errors.New("missing 'x'")
+	}
+	season, err := strconv.Atoi(seasonStr)
+	if err != nil || season < 0 {
+		return episodeRange{}, errors.New("bad season")
+	}
+
+	r := episodeRange{season: season, to: math.MaxInt}
+	if eps == "" {
+		return r, nil
+	}
+	fromStr, toStr, isRange := strings.Cut(eps, "-")
+	if r.from, err = strconv.Atoi(fromStr); err != nil || r.from < 0 {
+		return episodeRange{}, errors.New("bad episode")
+	}
+	switch {
+	case !isRange:
+		r.to = r.from
+	case toStr == "":
+		r.to = -1
+	default:
+		r.to, err = strconv.Atoi(toStr)
+		if err != nil || r.to < r.from {
+			return episodeRange{}, errors.New("bad episode range")
+		}
+	}
+	return r, nil
+}
+
+// Contains reports whether the filter includes the episode.
+func (f EpisodeFilter) Contains(season, episode int) bool {
+	if len(f) == 0 {
+		return true
+	}
+	for _, r := range f {
+		if r.to == -1 {
+			if season > r.season ||
+				season == r.season && episode >= r.from {
+				return true
+			}
+			continue
+		}
+		if season == r.season && episode >= r.from && episode <= r.to {
+			return true
+		}
+	}
+	return false
+}
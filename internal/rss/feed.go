@@ -0,0 +1,180 @@
+// Package rss reads RSS 2.0 and Atom feeds of torrents and decides which of
+// their items to download.
+package rss
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxFeedSize bounds how much of a feed is read.
+const maxFeedSize = 8 << 20
+
+// Item is one entry of a feed.
+type Item struct {
+	// GUID identifies the item across fetches; feeds without one fall
+	// back to the link.
+	GUID      string    `json:"guid"`
+	Title     string    `json:"title"`
+	Link      string    `json:"link"`
+	Published time.Time `json:"published"`
+	// Size is the enclosure's length, when the feed gives it.
+	Size uint64 `json:"size,omitempty"`
+}
+
+type rssDoc struct {
+	Items []struct {
+		GUID      string `xml:"guid"`
+		Title     string `xml:"title"`
+		Link      string `xml:"link"`
+		PubDate   string `xml:"pubDate"`
+		Enclosure struct {
+			URL    string `xml:"url,attr"`
+			Type   string `xml:"type,attr"`
+			Length string `xml:"length,attr"`
+		} `xml:"enclosure"`
+	} `xml:"channel>item"`
+}
+
+type atomDoc struct {
+	Entries []struct {
+		ID      string `xml:"id"`
+		Title   string `xml:"title"`
+		Updated string `xml:"updated"`
+		Links   []struct {
+			Href   string `xml:"href,attr"`
+			Rel    string `xml:"rel,attr"`
+			Type   string `xml:"type,attr"`
+			Length string `xml:"length,attr"`
+		} `xml:"link"`
+	} `xml:"entry"`
+}
+
+// Parse reads an RSS 2.0 or Atom feed. An item's link is its torrent
+// enclosure if it has one, its plain link otherwise.
+func Parse(r io.Reader) ([]Item, error) {
+	data, err := io.ReadAll(io.LimitReader(r, maxFeedSize))
+	if err != nil {
+		return nil, err
+	}
+
+	var root struct{ XMLName xml.Name }
+	if err := xml.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("rss: %w", err)
+	}
+	switch root.XMLName.Local {
+	case "rss":
+		return parseRSS(data)
+	case "feed":
+		return parseAtom(data)
+	default:
+		name := root.XMLName.Local
+		return nil, fmt.Errorf("rss: unknown feed type <%s>", name)
+	}
+}
+
+func parseRSS(data []byte) ([]Item, error) {
+	var doc rssDoc
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("rss: %w", err)
+	}
+
+	items := make([]Item, 0, len(doc.Items))
+	for _, it := range doc.Items {
+		item := Item{
+			GUID:      strings.TrimSpace(it.GUID),
+			Title:     strings.TrimSpace(it.Title),
+			Link:      strings.TrimSpace(it.Link),
+			Published: parseTime(it.PubDate),
+		}
+		if it.Enclosure.URL != "" {
+			item.Link = strings.TrimSpace(it.Enclosure.URL)
+			item.Size = parseLength(it.Enclosure.Length)
+		}
+		items = appendItem(items, item)
+	}
+	return items, nil
+}
+
+func parseAtom(data []byte) ([]Item, error) {
+	var doc atomDoc
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("rss: %w", err)
+	}
+
+	items := make([]Item, 0, len(doc.Entries))
+	for _, e := range doc.Entries {
+		item := Item{
+			GUID:      strings.TrimSpace(e.ID),
+			Title:     strings.TrimSpace(e.Title),
+			Published: parseTime(e.Updated),
+		}
+		for _, l := range e.Links {
+			if l.Rel == "enclosure" || item.Link == "" {
+				item.Link = strings.TrimSpace(l.Href)
+				item.Size = parseLength(l.Length)
+			}
+		}
+		items = appendItem(items, item)
+	}
+	return items, nil
+}
+
+// appendItem drops items with nothing to download.
+func appendItem(items []Item, item Item) []Item {
+	if item.Link == "" {
+		return items
+	}
+	if item.GUID == "" {
+		item.GUID = item.Link
+	}
+	return append(items, item)
+}
+
+func parseLength(s string) uint64 {
+	n, _ := strconv.ParseUint(strings.TrimSpace(s), 10, 64)
+	return n
+}
+
+func parseTime(s string) time.Time {
+	s = strings.TrimSpace(s)
+	for _, layout := range []string{
+		time.RFC1123Z,
+		time.RFC1123,
+		time.RFC3339,
+	} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// Fetch downloads and parses the feed at url.
+func Fetch(
+	ctx context.Context,
+	client *http.Client,
+	url string,
+) ([]Item, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.New("rss: " + resp.Status)
+	}
+	return Parse(resp.Body)
+}
@@ -0,0 +1,81 @@
+package rss
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseRSS(t *testing.T) {
+	const feed = `<?xml version="1.0"?>
+<rss version="2.0"><channel>
+<item>
+  <title>Show S01E02 1080p</title>
+  <link>https://example.com/details/2</link>
+  <guid>item-2</guid>
+  <pubDate>Mon, 02 Jan 2006 15:04:05 -0700</pubDate>
+  <enclosure url="https://example.com/2.torrent" length="4096"
+    type="application/x-bittorrent"/>
+</item>
+<item>
+  <title>Magnet only</title>
+  <link>magnet:?xt=urn:btih:abc</link>
+</item>
+<item><title>No link</title></item>
+</channel></rss>`
+
+	items, err := Parse(strings.NewReader(feed))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 {
+		t.Fatalf("got %d items, want 2", len(items))
+	}
+	if items[0].Link != "https://example.com/2.torrent" {
+		t.Errorf("link = %q, want the enclosure", items[0].Link)
+	}
+	if items[0].Size != 4096 {
+		t.Errorf("size = %d, want 4096", items[0].Size)
+	}
+	if items[0].GUID != "item-2" {
+		t.Errorf("guid = %q", items[0].GUID)
+	}
+	if items[0].Published.IsZero() {
+		t.Error("pubDate not parsed")
+	}
+	if items[1].GUID != "magnet:?xt=urn:btih:abc" {
+		t.Errorf("guid = %q, want the link", items[1].GUID)
+	}
+}
+
+func TestParseAtom(t *testing.T) {
+	const feed = `<feed xmlns="http://www.w3.org/2005/Atom">
+<entry>
+  <id>urn:1</id>
+  <title>Show 2x03</title>
+  <updated>2006-01-02T15:04:05Z</updated>
+  <link href="https://example.com/page"/>
+  <link rel="enclosure" href="https://example.com/3.torrent"/>
+</entry>
+</feed>`
+
+	items, err := Parse(strings.NewReader(feed))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Item{
+		GUID:      "urn:1",
+		Title:     "Show 2x03",
+		Link:      "https://example.com/3.torrent",
+		Published: time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC),
+	}
+	if len(items) != 1 || items[0] != want {
+		t.Fatalf("got %+v, want %+v", items, want)
+	}
+}
+
+func TestParseUnknown(t *testing.T) {
+	if _, err := Parse(strings.NewReader("<html/>")); err == nil {
+		t.Fatal("expected an error")
+	}
+}
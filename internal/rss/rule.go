@@ -0,0 +1,76 @@
+package rss
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// Rule picks the items of a feed to download.
+type Rule struct {
+	Name string `json:"name"`
+	// Match is a regular expression the title must match. Empty matches
+	// every title.
+	Match string `json:"match"`
+	// Exclude is a regular expression rejecting titles that match it.
+	Exclude string `json:"exclude"`
+	// Episodes restricts titles carrying an episode number to those in
+	// the filter; see EpisodeFilter for the syntax. Titles without an
+	// episode number are rejected when it is set.
+	Episodes string `json:"episodes"`
+	// Category is given to torrents added by the rule.
+	Category string `json:"category"`
+	Disabled bool   `json:"disabled"`
+}
+
+// Matcher is a compiled Rule.
+type Matcher struct {
+	Rule     Rule
+	match    *regexp.Regexp
+	exclude  *regexp.Regexp
+	episodes EpisodeFilter
+}
+
+// Compile checks the rule's expressions and filter.
+func (r Rule) Compile() (*Matcher, error) {
+	m := &Matcher{Rule: r}
+
+	var err error
+	if m.match, err = compile(r.Match); err != nil {
+		return nil, fmt.Errorf("rss: rule %q: %w", r.Name, err)
+	}
+	if m.exclude, err = compile(r.Exclude); err != nil {
+		return nil, fmt.Errorf("rss: rule %q: %w", r.Name, err)
+	}
+	if m.episodes, err = ParseEpisodeFilter(r.Episodes); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// compile compiles a case-insensitive expression; an empty one gives nil.
+func compile(expr string) (*regexp.Regexp, error) {
+	if expr == "" {
+		return nil, nil
+	}
+	return regexp.Compile("(?i)" + expr)
+}
+
+// Match reports whether the rule selects an item titled title.
+func (m *Matcher) Match(title string) bool {
+	if m.Rule.Disabled {
+		return false
+	}
+	if m.match != nil && !m.match.MatchString(title) {
+		return false
+	}
+	if m.exclude != nil && m.exclude.MatchString(title) {
+		return false
+	}
+	if len(m.episodes) > 0 {
+		season, episode, ok := Episode(title)
+		if !ok || !m.episodes.Contains(season, episode) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,94 @@
+package rss
+
+import "testing"
+
+func TestEpisode(t *testing.T) {
+	tests := []struct {
+		title           string
+		season, episode int
+		ok              bool
+	}{
+		{"Show.S01E02.1080p", 1, 2, true},
+		{"Show s2 e10", 2, 10, true},
+		{"Show S03.E04", 3, 4, true},
+		{"Show 4x05 HDTV", 4, 5, true},
+		{"Show 1080p x264", 0, 0, false},
+	}
+	for _, tt := range tests {
+		s, e, ok := Episode(tt.title)
+		if s != tt.season || e != tt.episode || ok != tt.ok {
+			t.Errorf(
+				"Episode(%q) = %d, %d, %v",
+				tt.title,
+				s,
+				e,
+				ok,
+			)
+		}
+	}
+}
+
+func TestEpisodeFilter(t *testing.T) {
+	f, err := ParseEpisodeFilter("1x3; 1x5-7; 2x; 3x10-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		season, episode int
+		want            bool
+	}{
+		{1, 3, true},
+		{1, 4, false},
+		{1, 6, true},
+		{1, 8, false},
+		{2, 1, true},
+		{3, 9, false},
+		{3, 10, true},
+		{4, 1, true},
+	}
+	for _, tt := range tests {
+		if got := f.Contains(tt.season, tt.episode); got != tt.want {
+			t.Errorf(
+				"Contains(%d, %d) = %v, want %v",
+				tt.season,
+				tt.episode,
+				got,
+				tt.want,
+			)
+		}
+	}
+
+	for _, bad := range []string{"1", "ax2", "1x5-3", "1x-"} {
+		if _, err := ParseEpisodeFilter(bad); err == nil {
+			t.Errorf("ParseEpisodeFilter(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestRuleMatch(t *testing.T) {
+	m, err := Rule{
+		Match:    `show`,
+		Exclude:  `720p`,
+		Episodes: "1x2-",
+	}.Compile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := map[string]bool{
+		"Show S01E02 1080p": true,
+		"Show S01E02 720p":  false,
+		"Show S01E01 1080p": false,
+		"Show S02E01 1080p": true,
+		"Show special":      false,
+		"Other S01E05":      false,
+	}
+	for title, want := range tests {
+		if got := m.Match(title); got != want {
+			t.Errorf("Match(%q) = %v, want %v", title, got, want)
+		}
+	}
+
+	if _, err := (Rule{Match: "("}).Compile(); err == nil {
+		t.Error("bad regexp compiled")
+	}
+}
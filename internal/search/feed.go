@@ -0,0 +1,75 @@
+package search
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/prxssh/echo/internal/rss"
+)
+
+// Feed searches a torrent RSS or Atom feed, fetching it afresh each time:
+// a result is an item whose title holds every word of the query. Feeds
+// only list their latest items, so only recent torrents are found.
+type Feed struct {
+	name   string
+	url    string
+	client *http.Client
+}
+
+func NewFeed(name, url string, client *http.Client) *Feed {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &Feed{name: name, url: url, client: client}
+}
+
+func (f *Feed) Name() string { return f.name }
+
+func (f *Feed) Search(ctx context.Context, q Query) ([]Result, error) {
+	items, err := rss.Fetch(ctx, f.client, f.url)
+	if err != nil {
+		return nil, err
+	}
+
+	words := terms(q.Text)
+	var results []Result
+	for _, it := range items {
+		title := terms(it.Title)
+		if !containsAll(title, words) {
+			continue
+		}
+		res := Result{
+			Title:     it.Title,
+			Link:      it.Link,
+			Size:      it.Size,
+			Published: it.Published,
+			Provider:  f.name,
+		}
+		if strings.HasPrefix(res.Link, "magnet:") {
+			res.Magnet, res.Link = res.Link, ""
+			res.InfoHash = magnetInfoHash(res.Magnet)
+		}
+		results = append(results, res)
+		if q.Limit > 0 && len(results) == q.Limit {
+			break
+		}
+	}
+	return results, nil
+}
+
+// Test checks that the feed can be fetched and read.
+func (f *Feed) Test(ctx context.Context) error {
+	_, err := rss.Fetch(ctx, f.client, f.url)
+	return err
+}
+
+func containsAll(have, want []string) bool {
+	for _, w := range want {
+		if !slices.Contains(have, w) {
+			return false
+		}
+	}
+	return true
+}
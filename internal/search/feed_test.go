@@ -0,0 +1,48 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const feedResponse = `<rss version="2.0"><channel>
+<item>
+  <title>Show S01E02 1080p</title>
+  <enclosure url="https://example.com/2.torrent" length="4096"/>
+</item>
+<item>
+  <title>Show S01E03 720p</title>
+  <link>magnet:?xt=urn:btih:0123456789abcdef0123456789abcdef01234567</link>
+</item>
+<item><title>Other 1080p</title><link>https://example.com/o</link></item>
+</channel></rss>`
+
+func TestFeedSearch(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, feedResponse)
+		},
+	))
+	defer srv.Close()
+
+	f := NewFeed("feed", srv.URL, srv.Client())
+	ctx := context.Background()
+	got, err := f.Search(ctx, Query{Text: "show 1080P"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].Size != 4096 || got[0].Provider != "feed" {
+		t.Fatalf("show 1080p = %+v", got)
+	}
+
+	got, _ = f.Search(ctx, Query{Text: "s01e03"})
+	if len(got) != 1 || got[0].Link != "" || got[0].InfoHash == "" {
+		t.Errorf("s01e03 = %+v, want the magnet", got)
+	}
+	if err := f.Test(ctx); err != nil {
+		t.Errorf("Test: %v", err)
+	}
+}
@@ -0,0 +1,60 @@
+package search
+
+import (
+	"slices"
+	"time"
+)
+
+// Filter narrows results down beyond what the query text and categories
+// do. Zero fields don't filter.
+type Filter struct {
+	MinSeeders int    `json:"minSeeders"`
+	MinSize    uint64 `json:"minSize"`
+	MaxSize    uint64 `json:"maxSize"`
+	// MaxAge drops results published longer ago. Results without a
+	// publication date are kept.
+	MaxAge time.Duration `json:"maxAge"`
+}
+
+// Apply returns the results that match q's categories and filter, for
+// results from providers that can't filter, or not by everything.
+func (q Query) Apply(results []Result) []Result {
+	now := time.Now()
+	return slices.DeleteFunc(slices.Clone(results), func(r Result) bool {
+		return !q.match(r, now)
+	})
+}
+
+func (q Query) match(r Result, now time.Time) bool {
+	f := q.Filter
+	switch {
+	case r.Seeders < f.MinSeeders:
+		return false
+	case f.MinSize > 0 && r.Size < f.MinSize:
+		return false
+	case f.MaxSize > 0 && r.Size > f.MaxSize:
+		return false
+	case f.MaxAge > 0 && !r.Published.IsZero() &&
+		now.Sub(r.Published) > f.MaxAge:
+		return false
+	}
+	return inCategories(r.Categories, q.Categories)
+}
+
+// inCategories reports whether a result in the categories have is in one of
+// want. A top-level Newznab category, such as 2000, takes in its
+// subcategories, such as 2040. Results whose categories aren't known are
+// kept.
+func inCategories(have, want []int) bool {
+	if len(want) == 0 || len(have) == 0 {
+		return true
+	}
+	for _, w := range want {
+		for _, h := range have {
+			if h == w || (w%1000 == 0 && h/1000*1000 == w) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,56 @@
+package search
+
+import (
+	"testing"
+	"time"
+)
+
+func TestQueryApply(t *testing.T) {
+	now := time.Now()
+	results := []Result{
+		{
+			Title:      "ok",
+			Seeders:    10,
+			Size:       5 << 30,
+			Categories: []int{2040},
+		},
+		{Title: "few seeders", Seeders: 1, Size: 5 << 30},
+		{Title: "small", Seeders: 10, Size: 1 << 20},
+		{Title: "large", Seeders: 10, Size: 50 << 30},
+		{
+			Title:      "tv",
+			Seeders:    10,
+			Size:       5 << 30,
+			Categories: []int{5000},
+		},
+		{
+			Title:     "old",
+			Seeders:   10,
+			Size:      5 << 30,
+			Published: now.Add(-30 * 24 * time.Hour),
+		},
+		{
+			Title:     "recent",
+			Seeders:   10,
+			Size:      5 << 30,
+			Published: now.Add(-time.Hour),
+		},
+	}
+	q := Query{
+		Categories: []int{2000},
+		Filter: Filter{
+			MinSeeders: 5,
+			MinSize:    1 << 30,
+			MaxSize:    10 << 30,
+			MaxAge:     7 * 24 * time.Hour,
+		},
+	}
+
+	got := q.Apply(results)
+	if len(got) != 2 || got[0].Title != "ok" || got[1].Title != "recent" {
+		t.Errorf("got %+v, want ok and recent", got)
+	}
+	if len(Query{}.Apply(results)) != len(results) {
+		t.Error("an empty query filtered results out")
+	}
+}
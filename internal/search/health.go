@@ -0,0 +1,97 @@
+package search
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// latencyWeight is how much each search counts towards a provider's
+// average latency, which thereby follows recent searches most.
+const latencyWeight = 0.2
+
+// Health is how a provider has been doing.
+type Health struct {
+	Name     string `json:"name"`
+	Searches int    `json:"searches"`
+	Failures int    `json:"failures"`
+	// Latency averages how long searches took, recent ones weighing
+	// most.
+	Latency     time.Duration `json:"latency"`
+	LastError   string        `json:"lastError,omitempty"`
+	LastErrorAt time.Time     `json:"lastErrorAt"`
+	LastSuccess time.Time     `json:"lastSuccess"`
+}
+
+// Monitor tracks the health of providers across searches, by name.
+type Monitor struct {
+	mu     sync.Mutex
+	health map[string]*Health
+}
+
+func NewMonitor() *Monitor {
+	return &Monitor{health: make(map[string]*Health)}
+}
+
+// Wrap returns p with its searches recorded.
+func (m *Monitor) Wrap(p Provider) Provider {
+	return monitored{p, m}
+}
+
+type monitored struct {
+	Provider
+	m *Monitor
+}
+
+func (p monitored) Search(ctx context.Context, q Query) ([]Result, error) {
+	start := time.Now()
+	results, err := p.Provider.Search(ctx, q)
+	// A search cut short by the caller says nothing of the provider.
+	if err == nil || ctx.Err() == nil {
+		p.m.Record(p.Name(), time.Since(start), err)
+	}
+	return results, err
+}
+
+// Record notes a search of the named provider that took latency and ended
+// with err.
+func (m *Monitor) Record(name string, latency time.Duration, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	h, ok := m.health[name]
+	if !ok {
+		h = &Health{Name: name, Latency: latency}
+		m.health[name] = h
+	}
+	h.Searches++
+	h.Latency += time.Duration(latencyWeight * float64(latency-h.Latency))
+	if err != nil {
+		h.Failures++
+		h.LastError = err.Error()
+		h.LastErrorAt = time.Now()
+	} else {
+		h.LastSuccess = time.Now()
+	}
+}
+
+// Health returns how the named provider has been doing; ok is false if it
+// hasn't been searched yet.
+func (m *Monitor) Health(name string) (Health, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	h, ok := m.health[name]
+	if !ok {
+		return Health{Name: name}, false
+	}
+	return *h, true
+}
+
+// Forget drops what is known of the named provider.
+func (m *Monitor) Forget(name string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.health, name)
+}
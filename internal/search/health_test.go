@@ -0,0 +1,41 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMonitorRecords(t *testing.T) {
+	m := NewMonitor()
+	fake := &fakeProvider{}
+	p := m.Wrap(fake)
+
+	p.Search(context.Background(), Query{Text: "a"})
+	fake.err = errors.New("401 Unauthorized")
+	p.Search(context.Background(), Query{Text: "a"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	p.Search(ctx, Query{Text: "a"})
+
+	h, ok := m.Health("fake")
+	if !ok || h.Searches != 2 || h.Failures != 1 {
+		t.Fatalf("health = %+v, want 2 searches and 1 failure", h)
+	}
+	if h.LastError != "401 Unauthorized" || h.LastSuccess.IsZero() {
+		t.Errorf("health = %+v", h)
+	}
+}
+
+func TestMonitorLatencyAverages(t *testing.T) {
+	m := NewMonitor()
+	m.Record("p", time.Second, nil)
+	m.Record("p", 2*time.Second, nil)
+
+	h, _ := m.Health("p")
+	if h.Latency != 1200*time.Millisecond {
+		t.Errorf("latency = %s, want 1.2s", h.Latency)
+	}
+}
@@ -0,0 +1,179 @@
+package search
+
+import (
+	"context"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// defaultIndexLimit caps an Index's results when the query doesn't.
+const defaultIndexLimit = 100
+
+// IndexEntry is a torrent in an Index.
+type IndexEntry struct {
+	// InfoHash is lowercase hex.
+	InfoHash string `json:"infoHash"`
+	Name     string `json:"name"`
+	Size     uint64 `json:"size"`
+	// Files are the torrent's file paths, searched along with Name.
+	Files []string  `json:"files,omitempty"`
+	Added time.Time `json:"added"`
+}
+
+// Index is a full-text index of torrents kept locally, such as the ones
+// whose metadata was fetched while crawling the DHT. It searches as a
+// Provider: a result is a torrent whose name or file paths hold every word
+// of the query.
+type Index struct {
+	name string
+
+	mu      sync.RWMutex
+	entries map[string]IndexEntry
+	// terms maps each word to the info-hashes of the entries holding it.
+	terms map[string]map[string]struct{}
+}
+
+// NewIndex returns an index, searched as the provider called name, holding
+// entries.
+func NewIndex(name string, entries []IndexEntry) *Index {
+	ix := &Index{
+		name:    name,
+		entries: make(map[string]IndexEntry, len(entries)),
+		terms:   make(map[string]map[string]struct{}),
+	}
+	for _, e := range entries {
+		ix.Add(e)
+	}
+	return ix
+}
+
+func (ix *Index) Name() string { return ix.name }
+
+// Add indexes e, reporting false if its info-hash already was.
+func (ix *Index) Add(e IndexEntry) bool {
+	e.InfoHash = strings.ToLower(e.InfoHash)
+
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	if _, ok := ix.entries[e.InfoHash]; ok {
+		return false
+	}
+	ix.entries[e.InfoHash] = e
+	for _, term := range entryTerms(e) {
+		set, ok := ix.terms[term]
+		if !ok {
+			set = make(map[string]struct{})
+			ix.terms[term] = set
+		}
+		set[e.InfoHash] = struct{}{}
+	}
+	return true
+}
+
+// Has reports whether the hex info-hash is indexed.
+func (ix *Index) Has(infoHash string) bool {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+
+	_, ok := ix.entries[strings.ToLower(infoHash)]
+	return ok
+}
+
+// Len counts the torrents indexed.
+func (ix *Index) Len() int {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+
+	return len(ix.entries)
+}
+
+// Entries returns every entry, oldest first.
+func (ix *Index) Entries() []IndexEntry {
+	ix.mu.RLock()
+	out := make([]IndexEntry, 0, len(ix.entries))
+	for _, e := range ix.entries {
+		out = append(out, e)
+	}
+	ix.mu.RUnlock()
+
+	slices.SortFunc(out, func(a, b IndexEntry) int {
+		return a.Added.Compare(b.Added)
+	})
+	return out
+}
+
+// Search returns the entries holding every word of q.Text, newest first.
+// Categories are ignored; the index doesn't know them.
+func (ix *Index) Search(_ context.Context, q Query) ([]Result, error) {
+	words := terms(q.Text)
+	if len(words) == 0 {
+		return nil, nil
+	}
+
+	ix.mu.RLock()
+	var matches []IndexEntry
+	for hash := range ix.terms[words[0]] {
+		if ix.matchAll(hash, words[1:]) {
+			matches = append(matches, ix.entries[hash])
+		}
+	}
+	ix.mu.RUnlock()
+
+	slices.SortFunc(matches, func(a, b IndexEntry) int {
+		return b.Added.Compare(a.Added)
+	})
+	limit := q.Limit
+	if limit <= 0 {
+		limit = defaultIndexLimit
+	}
+	matches = matches[:min(len(matches), limit)]
+
+	results := make([]Result, len(matches))
+	for i, e := range matches {
+		results[i] = Result{
+			Title:     e.Name,
+			Magnet:    entryMagnet(e),
+			InfoHash:  e.InfoHash,
+			Size:      e.Size,
+			Published: e.Added,
+			Provider:  ix.name,
+		}
+	}
+	return results, nil
+}
+
+// matchAll reports whether hash holds every word; mu must be held.
+func (ix *Index) matchAll(hash string, words []string) bool {
+	for _, w := range words {
+		if _, ok := ix.terms[w][hash]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func entryMagnet(e IndexEntry) string {
+	return "magnet:?xt=urn:btih:" + e.InfoHash +
+		"&dn=" + url.QueryEscape(e.Name)
+}
+
+func entryTerms(e IndexEntry) []string {
+	all := terms(e.Name)
+	for _, f := range e.Files {
+		all = append(all, terms(f)...)
+	}
+	slices.Sort(all)
+	return slices.Compact(all)
+}
+
+// terms splits s into lowercase words of letters and digits.
+func terms(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
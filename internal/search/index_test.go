@@ -0,0 +1,51 @@
+package search
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestIndexSearch(t *testing.T) {
+	now := time.Now()
+	ix := NewIndex("local", []IndexEntry{
+		{
+			InfoHash: "AA",
+			Name:     "Ubuntu 24.04 Desktop",
+			Added:    now.Add(-time.Hour),
+		},
+		{
+			InfoHash: "bb",
+			Name:     "Debian.12.Netinst",
+			Files:    []string{"debian/ubuntu-compat.txt"},
+			Added:    now,
+		},
+		{InfoHash: "cc", Name: "Something else", Added: now},
+	})
+	if ix.Add(IndexEntry{InfoHash: "aa", Name: "again"}) {
+		t.Error("Add took a duplicate info-hash")
+	}
+	if ix.Len() != 3 || !ix.Has("BB") {
+		t.Fatalf("Len = %d", ix.Len())
+	}
+
+	got, err := ix.Search(context.Background(), Query{Text: "UBUNTU"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].InfoHash != "bb" || got[1].InfoHash != "aa" {
+		t.Fatalf("ubuntu = %+v", got)
+	}
+	if got[1].Provider != "local" || got[1].Magnet == "" {
+		t.Errorf("result = %+v", got[1])
+	}
+
+	got, _ = ix.Search(context.Background(), Query{Text: "ubuntu 24.04"})
+	if len(got) != 1 || got[0].Title != "Ubuntu 24.04 Desktop" {
+		t.Errorf("ubuntu 24.04 = %+v", got)
+	}
+	want := "magnet:?xt=urn:btih:aa&dn=Ubuntu+24.04+Desktop"
+	if got[0].Magnet != want {
+		t.Errorf("magnet = %q, want %q", got[0].Magnet, want)
+	}
+}
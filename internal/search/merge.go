@@ -0,0 +1,151 @@
+package search
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// sizeTolerance is how far apart, as a fraction of the larger, the sizes
+// of two results with the same title may be for them to be grouped.
+const sizeTolerance = 0.01
+
+// Merger gathers the results of several providers, keeping one of each
+// torrent. Results are the same torrent if they share an info-hash or a
+// download link or, lacking conflicting info-hashes, have the same title
+// once case and punctuation are ignored and near enough the same size.
+type Merger struct {
+	byKey   map[string]int
+	byTitle map[string][]int
+	results []Result
+}
+
+// Add merges results in. Of two results for the same torrent the one with
+// more seeders is kept, with the links and info-hash the other has filled
+// in where it lacks them, and both are listed in its Sources.
+func (m *Merger) Add(results []Result) {
+	if m.byKey == nil {
+		m.byKey = make(map[string]int)
+		m.byTitle = make(map[string][]int)
+	}
+	for _, r := range results {
+		if len(resultKeys(r)) == 0 {
+			continue
+		}
+		r.Sources = []Source{sourceOf(r)}
+		i, ok := m.find(r)
+		if ok {
+			m.results[i] = combine(m.results[i], r)
+		} else {
+			i = len(m.results)
+			m.results = append(m.results, r)
+			if t := normalTitle(r.Title); t != "" {
+				m.byTitle[t] = append(m.byTitle[t], i)
+			}
+		}
+		for _, key := range resultKeys(m.results[i]) {
+			m.byKey[key] = i
+		}
+	}
+}
+
+func (m *Merger) find(r Result) (int, bool) {
+	for _, key := range resultKeys(r) {
+		i, ok := m.byKey[key]
+		if ok && sameHash(m.results[i], r) {
+			return i, true
+		}
+	}
+	for _, i := range m.byTitle[normalTitle(r.Title)] {
+		other := m.results[i]
+		if sameHash(other, r) && nearSize(other.Size, r.Size) {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// Results returns the merged results, most seeders first.
+func (m *Merger) Results() []Result {
+	out := slices.Clone(m.results)
+	Rank(out)
+	return out
+}
+
+// Rank sorts results by seeders, then leechers, then DHT peers, most
+// first.
+func Rank(results []Result) {
+	slices.SortStableFunc(results, func(a, b Result) int {
+		if c := cmp.Compare(b.Seeders, a.Seeders); c != 0 {
+			return c
+		}
+		if c := cmp.Compare(b.Leechers, a.Leechers); c != 0 {
+			return c
+		}
+		return cmp.Compare(b.DHTPeers, a.DHTPeers)
+	})
+}
+
+// resultKeys returns the keys a result is known by: its info-hash and its
+// links. A result with none can't be downloaded.
+func resultKeys(r Result) []string {
+	var keys []string
+	if r.InfoHash != "" {
+		keys = append(keys, "hash:"+strings.ToLower(r.InfoHash))
+	}
+	if r.Link != "" {
+		keys = append(keys, "link:"+r.Link)
+	}
+	if r.Magnet != "" {
+		keys = append(keys, "link:"+r.Magnet)
+	}
+	return keys
+}
+
+// sameHash reports whether a and b may be the same torrent, going by
+// their info-hashes.
+func sameHash(a, b Result) bool {
+	return a.InfoHash == "" || b.InfoHash == "" ||
+		strings.EqualFold(a.InfoHash, b.InfoHash)
+}
+
+func nearSize(a, b uint64) bool {
+	if a == 0 || b == 0 {
+		return false
+	}
+	lo, hi := min(a, b), max(a, b)
+	return float64(hi-lo) <= float64(hi)*sizeTolerance
+}
+
+// normalTitle lowercases the title and turns each run of anything but
+// letters and digits into a single space.
+func normalTitle(title string) string {
+	fields := strings.FieldsFunc(
+		strings.ToLower(title),
+		func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		},
+	)
+	return strings.Join(fields, " ")
+}
+
+func combine(a, b Result) Result {
+	if b.Seeders > a.Seeders {
+		a, b = b, a
+	}
+	a.Link = cmp.Or(a.Link, b.Link)
+	a.Magnet = cmp.Or(a.Magnet, b.Magnet)
+	a.InfoHash = cmp.Or(a.InfoHash, b.InfoHash)
+	a.Size = cmp.Or(a.Size, b.Size)
+	a.Details = cmp.Or(a.Details, b.Details)
+	if len(a.Categories) == 0 {
+		a.Categories = b.Categories
+	}
+	sources := slices.Concat(a.Sources, b.Sources)
+	slices.SortStableFunc(sources, func(x, y Source) int {
+		return cmp.Compare(y.Seeders, x.Seeders)
+	})
+	a.Sources = sources
+	return a
+}
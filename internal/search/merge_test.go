@@ -0,0 +1,100 @@
+package search
+
+import "testing"
+
+func TestMergerDeduplicates(t *testing.T) {
+	var m Merger
+	m.Add([]Result{
+		{Title: "a", InfoHash: "AA", Link: "https://x/a", Seeders: 3},
+		{Title: "b", Link: "https://x/b", Seeders: 1},
+	})
+	m.Add([]Result{
+		{Title: "a2", InfoHash: "aa", Magnet: "magnet:?a", Seeders: 9},
+		{Title: "b", Link: "https://x/b", Seeders: 0},
+		{Title: "c", Magnet: "magnet:?c", Seeders: 5},
+		{Title: "nothing to download"},
+	})
+
+	got := m.Results()
+	if len(got) != 3 {
+		t.Fatalf("got %d results, want 3: %+v", len(got), got)
+	}
+	if got[0].Title != "a2" || got[0].Seeders != 9 {
+		t.Errorf("first = %+v, want the best-seeded copy of a", got[0])
+	}
+	if got[0].Link != "https://x/a" || got[0].Magnet != "magnet:?a" {
+		t.Errorf("links not combined: %+v", got[0])
+	}
+	if got[1].Title != "c" || got[2].Title != "b" {
+		t.Errorf("order = %q, %q", got[1].Title, got[2].Title)
+	}
+}
+
+func TestMergerGroupsSimilarTitles(t *testing.T) {
+	var m Merger
+	m.Add([]Result{
+		{
+			Title:    "Big.Buck.Bunny.1080p",
+			Link:     "https://x/1",
+			Size:     1000,
+			Seeders:  2,
+			Provider: "x",
+		},
+		{
+			Title:    "Big Buck Bunny 720p",
+			Link:     "https://x/2",
+			Size:     1000,
+			Provider: "x",
+		},
+	})
+	m.Add([]Result{
+		{
+			Title:    "big buck bunny (1080p)",
+			Magnet:   "magnet:?y",
+			InfoHash: "bb",
+			Size:     1005,
+			Seeders:  7,
+			Provider: "y",
+		},
+		{
+			Title:    "Big.Buck.Bunny.1080p",
+			InfoHash: "cc",
+			Magnet:   "magnet:?z",
+			Size:     1000,
+			Provider: "z",
+		},
+	})
+
+	got := m.Results()
+	if len(got) != 3 {
+		t.Fatalf("got %d results, want 3: %+v", len(got), got)
+	}
+	best := got[0]
+	if best.Provider != "y" || best.Magnet != "magnet:?y" {
+		t.Errorf("best = %+v, want y's copy", best)
+	}
+	if best.Link != "https://x/1" {
+		t.Errorf("link = %q, want x's filled in", best.Link)
+	}
+	if len(best.Sources) != 2 ||
+		best.Sources[0].Provider != "y" ||
+		best.Sources[1].Provider != "x" {
+		t.Errorf("sources = %+v, want y then x", best.Sources)
+	}
+}
+
+func TestNearSize(t *testing.T) {
+	for _, tt := range []struct {
+		a, b uint64
+		want bool
+	}{
+		{1000, 1000, true},
+		{1000, 1010, true},
+		{1000, 1100, false},
+		{0, 0, false},
+	} {
+		if got := nearSize(tt.a, tt.b); got != tt.want {
+			t.Errorf("nearSize(%d, %d) = %v", tt.a, tt.b, got)
+		}
+	}
+}
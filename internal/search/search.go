@@ -0,0 +1,116 @@
+// Package search finds torrents through indexers, such as the ones Jackett
+// or Prowlarr expose, each reached through a Provider.
+package search
+
+import (
+	"context"
+	"encoding/base32"
+	"encoding/hex"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Query is what to search for.
+type Query struct {
+	Text string `json:"text"`
+	// Categories are Newznab category IDs, such as 2000 for movies or
+	// 5000 for TV. None means every category.
+	Categories []int `json:"categories"`
+	// Limit caps how many results a provider returns; zero leaves it to
+	// the provider.
+	Limit  int    `json:"limit"`
+	Filter Filter `json:"filter"`
+}
+
+// Result is one torrent a provider found.
+type Result struct {
+	Title string `json:"title"`
+	// Link downloads the .torrent; Magnet is set instead, or as well,
+	// when the provider has a magnet link.
+	Link   string `json:"link,omitempty"`
+	Magnet string `json:"magnet,omitempty"`
+	// InfoHash is hex, and empty when the provider doesn't say.
+	InfoHash string `json:"infoHash,omitempty"`
+	Size     uint64 `json:"size"`
+	Seeders  int    `json:"seeders"`
+	Leechers int    `json:"leechers"`
+	// Verified is set once Seeders and Leechers have been replaced by
+	// what the trackers scraped, rather than what the indexer said.
+	Verified bool `json:"verified"`
+	// DHTPeers counts the peers the DHT knows of, seeding or not.
+	DHTPeers  int       `json:"dhtPeers,omitempty"`
+	Published time.Time `json:"published"`
+	// Categories are the result's Newznab category IDs, if known.
+	Categories []int `json:"categories,omitempty"`
+	// Details links to the result's page on the indexer.
+	Details string `json:"details,omitempty"`
+	// Indexer is the site the result came from, and Provider the
+	// provider that found it.
+	Indexer  string `json:"indexer,omitempty"`
+	Provider string `json:"provider"`
+	// Sources are every provider's copy of the result once merged, most
+	// seeders first; the result's own links are the first's.
+	Sources []Source `json:"sources,omitempty"`
+}
+
+// Source is where a merged result was found, with what that provider
+// said of it.
+type Source struct {
+	Provider string `json:"provider"`
+	Indexer  string `json:"indexer,omitempty"`
+	Title    string `json:"title"`
+	Link     string `json:"link,omitempty"`
+	Magnet   string `json:"magnet,omitempty"`
+	Details  string `json:"details,omitempty"`
+	Seeders  int    `json:"seeders"`
+	Leechers int    `json:"leechers"`
+}
+
+func sourceOf(r Result) Source {
+	return Source{
+		Provider: r.Provider,
+		Indexer:  r.Indexer,
+		Title:    r.Title,
+		Link:     r.Link,
+		Magnet:   r.Magnet,
+		Details:  r.Details,
+		Seeders:  r.Seeders,
+		Leechers: r.Leechers,
+	}
+}
+
+// Provider searches one source of torrents.
+type Provider interface {
+	// Name identifies the provider in results and errors.
+	Name() string
+	Search(ctx context.Context, q Query) ([]Result, error)
+}
+
+// magnetInfoHash returns the v1 info-hash of a magnet link, in hex, or ""
+// if it has none.
+func magnetInfoHash(magnet string) string {
+	u, err := url.Parse(magnet)
+	if err != nil || u.Scheme != "magnet" {
+		return ""
+	}
+	for _, xt := range u.Query()["xt"] {
+		h, ok := strings.CutPrefix(xt, "urn:btih:")
+		if !ok {
+			continue
+		}
+		switch len(h) {
+		case 40:
+			if _, err := hex.DecodeString(h); err == nil {
+				return strings.ToLower(h)
+			}
+		case 32:
+			enc := base32.StdEncoding
+			b, err := enc.DecodeString(strings.ToUpper(h))
+			if err == nil {
+				return hex.EncodeToString(b)
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,33 @@
+package search
+
+import "strings"
+
+// Swarm is a torrent's swarm as counted by its trackers and the DHT.
+type Swarm struct {
+	// Seeders and Leechers are the most any tracker scraped; Scraped is
+	// false when none knew the torrent.
+	Seeders  int
+	Leechers int
+	Scraped  bool
+	DHTPeers int
+}
+
+// ApplySwarms replaces the indexer's seeder and leecher counts with the
+// scraped ones, keyed by lowercase hex info-hash, then ranks results again.
+// Results no tracker knew keep the indexer's counts.
+func ApplySwarms(results []Result, swarms map[string]Swarm) {
+	for i := range results {
+		r := &results[i]
+		s, ok := swarms[strings.ToLower(r.InfoHash)]
+		if !ok {
+			continue
+		}
+		if s.Scraped {
+			r.Seeders = s.Seeders
+			r.Leechers = s.Leechers
+			r.Verified = true
+		}
+		r.DHTPeers = s.DHTPeers
+	}
+	Rank(results)
+}
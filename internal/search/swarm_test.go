@@ -0,0 +1,30 @@
+package search
+
+import "testing"
+
+func TestApplySwarms(t *testing.T) {
+	results := []Result{
+		{Title: "inflated", InfoHash: "aa", Seeders: 900},
+		{Title: "unknown", InfoHash: "bb", Seeders: 20},
+		{Title: "healthy", InfoHash: "CC", Seeders: 10},
+		{Title: "no hash", Link: "https://x/d", Seeders: 5},
+	}
+	ApplySwarms(results, map[string]Swarm{
+		"aa": {Seeders: 2, Leechers: 1, Scraped: true},
+		"bb": {DHTPeers: 7},
+		"cc": {Seeders: 150, Leechers: 40, Scraped: true, DHTPeers: 3},
+	})
+
+	want := []string{"healthy", "unknown", "no hash", "inflated"}
+	for i, r := range results {
+		if r.Title != want[i] {
+			t.Fatalf("result %d = %q, want %q", i, r.Title, want[i])
+		}
+	}
+	if r := results[0]; !r.Verified || r.Leechers != 40 || r.DHTPeers != 3 {
+		t.Errorf("healthy = %+v", r)
+	}
+	if r := results[1]; r.Verified || r.Seeders != 20 || r.DHTPeers != 7 {
+		t.Errorf("unknown = %+v", r)
+	}
+}
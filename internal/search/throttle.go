@@ -0,0 +1,206 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// firstBackoff is how long a provider is left alone after its first failure
+// in a row; each further failure doubles it, up to ThrottleConfig's
+// MaxBackoff.
+const firstBackoff = 30 * time.Second
+
+// ThrottleConfig says how a Throttle spares providers.
+type ThrottleConfig struct {
+	// CacheTTL is how long a provider's results are reused for the same
+	// query. Zero turns caching off.
+	CacheTTL time.Duration
+	// Interval is the least time between two searches of a provider;
+	// a search that comes sooner waits its turn.
+	Interval time.Duration
+	// MaxBackoff caps how long a failing provider is skipped. Zero turns
+	// backing off off.
+	MaxBackoff time.Duration
+}
+
+// Throttle caches providers' results and paces their searches, backing off
+// from ones that keep failing, so repeated searches don't hammer indexers
+// or use up their API quotas. It remembers providers by name across
+// searches.
+type Throttle struct {
+	cfg ThrottleConfig
+	now func() time.Time
+
+	mu        sync.Mutex
+	cache     map[string]cached
+	providers map[string]*pace
+}
+
+type cached struct {
+	results []Result
+	expires time.Time
+}
+
+// pace is what a Throttle knows of one provider.
+type pace struct {
+	// next is when the provider may be searched again.
+	next     time.Time
+	failures int
+}
+
+func NewThrottle(cfg ThrottleConfig) *Throttle {
+	return &Throttle{
+		cfg:       cfg,
+		now:       time.Now,
+		cache:     make(map[string]cached),
+		providers: make(map[string]*pace),
+	}
+}
+
+// Wrap returns p searched through the throttle.
+func (t *Throttle) Wrap(p Provider) Provider {
+	return throttled{p, t}
+}
+
+type throttled struct {
+	Provider
+	t *Throttle
+}
+
+// Reset forgets every cached result and backoff, such as when the
+// providers have been reconfigured.
+func (t *Throttle) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	clear(t.cache)
+	clear(t.providers)
+}
+
+func (p throttled) Search(ctx context.Context, q Query) ([]Result, error) {
+	name := p.Name()
+	key := cacheKey(name, q)
+	if results, ok := p.t.cached(key); ok {
+		return results, nil
+	}
+	if err := p.t.wait(ctx, name); err != nil {
+		return nil, err
+	}
+
+	results, err := p.Provider.Search(ctx, q)
+	switch {
+	case err == nil:
+		p.t.succeeded(name, key, results)
+	case ctx.Err() == nil:
+		p.t.failed(name)
+	}
+	return results, err
+}
+
+func (t *Throttle) cached(key string) ([]Result, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	c, ok := t.cache[key]
+	if !ok {
+		return nil, false
+	}
+	if t.now().After(c.expires) {
+		delete(t.cache, key)
+		return nil, false
+	}
+	return slices.Clone(c.results), true
+}
+
+// wait blocks until the provider may be searched, or fails straight away
+// if the provider is being backed off from.
+func (t *Throttle) wait(ctx context.Context, name string) error {
+	t.mu.Lock()
+	p := t.pace(name)
+	now := t.now()
+	delay := p.next.Sub(now)
+	if p.failures > 0 && delay > 0 {
+		t.mu.Unlock()
+		return fmt.Errorf(
+			"search: %s failed %d times, retrying in %s",
+			name,
+			p.failures,
+			delay.Round(time.Second),
+		)
+	}
+	// Claim the slot now so that searches waiting together are spaced
+	// out rather than all let through at once.
+	if p.next.Before(now) {
+		p.next = now
+	}
+	p.next = p.next.Add(t.cfg.Interval)
+	t.mu.Unlock()
+
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (t *Throttle) succeeded(name, key string, results []Result) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.pace(name).failures = 0
+	if t.cfg.CacheTTL > 0 {
+		t.cache[key] = cached{
+			results: slices.Clone(results),
+			expires: t.now().Add(t.cfg.CacheTTL),
+		}
+	}
+}
+
+func (t *Throttle) failed(name string) {
+	if t.cfg.MaxBackoff <= 0 {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	p := t.pace(name)
+	p.failures++
+	backoff := firstBackoff << min(p.failures-1, 16)
+	p.next = t.now().Add(min(backoff, t.cfg.MaxBackoff))
+}
+
+// pace returns what is known of the named provider; mu must be held.
+func (t *Throttle) pace(name string) *pace {
+	p, ok := t.providers[name]
+	if !ok {
+		p = &pace{}
+		t.providers[name] = p
+	}
+	return p
+}
+
+func cacheKey(name string, q Query) string {
+	cats := make([]string, len(q.Categories))
+	for i, c := range q.Categories {
+		cats[i] = strconv.Itoa(c)
+	}
+	slices.Sort(cats)
+	return strings.Join([]string{
+		name,
+		strings.ToLower(strings.TrimSpace(q.Text)),
+		strings.Join(cats, ","),
+		strconv.Itoa(q.Limit),
+	}, "\x00")
+}
@@ -0,0 +1,82 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+type fakeProvider struct {
+	calls int
+	err   error
+}
+
+func (p *fakeProvider) Name() string { return "fake" }
+
+func (p *fakeProvider) Search(context.Context, Query) ([]Result, error) {
+	p.calls++
+	if p.err != nil {
+		return nil, p.err
+	}
+	return []Result{{Title: "x", Provider: "fake"}}, nil
+}
+
+func TestThrottleCaches(t *testing.T) {
+	now := time.Unix(1000, 0)
+	th := NewThrottle(ThrottleConfig{CacheTTL: time.Minute})
+	th.now = func() time.Time { return now }
+	fake := &fakeProvider{}
+	p := th.Wrap(fake)
+	ctx := context.Background()
+
+	for _, text := range []string{"Ubuntu", " ubuntu "} {
+		got, err := p.Search(ctx, Query{Text: text})
+		if err != nil || len(got) != 1 {
+			t.Fatalf("Search(%q) = %v, %v", text, got, err)
+		}
+	}
+	if fake.calls != 1 {
+		t.Errorf("provider searched %d times, want 1", fake.calls)
+	}
+
+	p.Search(ctx, Query{Text: "ubuntu", Categories: []int{2000}})
+	now = now.Add(2 * time.Minute)
+	p.Search(ctx, Query{Text: "ubuntu"})
+	if fake.calls != 3 {
+		t.Errorf("provider searched %d times, want 3", fake.calls)
+	}
+}
+
+func TestThrottleBacksOff(t *testing.T) {
+	now := time.Unix(1000, 0)
+	th := NewThrottle(ThrottleConfig{MaxBackoff: time.Minute})
+	th.now = func() time.Time { return now }
+	fake := &fakeProvider{err: errors.New("503 Service Unavailable")}
+	p := th.Wrap(fake)
+	ctx := context.Background()
+
+	p.Search(ctx, Query{Text: "a"})
+	if _, err := p.Search(ctx, Query{Text: "a"}); err == nil {
+		t.Fatal("failing provider searched again straight away")
+	}
+	if fake.calls != 1 {
+		t.Fatalf("provider searched %d times, want 1", fake.calls)
+	}
+
+	now = now.Add(firstBackoff)
+	p.Search(ctx, Query{Text: "a"})
+	now = now.Add(firstBackoff)
+	if _, err := p.Search(ctx, Query{Text: "a"}); err == nil {
+		t.Error("backoff did not double after a second failure")
+	}
+
+	fake.err = nil
+	now = now.Add(time.Minute)
+	if _, err := p.Search(ctx, Query{Text: "a"}); err != nil {
+		t.Fatalf("after the backoff: %v", err)
+	}
+	if _, err := p.Search(ctx, Query{Text: "b"}); err != nil {
+		t.Errorf("success did not reset the backoff: %v", err)
+	}
+}
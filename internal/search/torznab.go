@@ -0,0 +1,310 @@
+package search
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxResponseSize bounds how much of a Torznab response is read.
+const maxResponseSize = 16 << 20
+
+// Torznab searches a Torznab or Newznab API, such as a Jackett indexer, or
+// Prowlarr's, which searches every indexer behind it.
+type Torznab struct {
+	name     string
+	endpoint string
+	apiKey   string
+	client   *http.Client
+}
+
+// NewTorznab returns a provider for the API at endpoint, the URL that
+// takes the t= and q= parameters, e.g.
+// http://localhost:9117/api/v2.0/indexers/all/results/torznab.
+func NewTorznab(
+	name, endpoint, apiKey string,
+	client *http.Client,
+) *Torznab {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &Torznab{
+		name:     name,
+		endpoint: endpoint,
+		apiKey:   apiKey,
+		client:   client,
+	}
+}
+
+func (t *Torznab) Name() string { return t.name }
+
+func (t *Torznab) Search(ctx context.Context, q Query) ([]Result, error) {
+	u, err := t.searchURL(q)
+	if err != nil {
+		return nil, err
+	}
+	body, err := t.get(ctx, u)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	results, err := ParseTorznab(body)
+	if err != nil {
+		return nil, err
+	}
+	for i := range results {
+		results[i].Provider = t.name
+	}
+	return results, nil
+}
+
+// Test checks that the API answers and takes the API key, by asking for
+// its capabilities rather than searching.
+func (t *Torznab) Test(ctx context.Context) error {
+	u, err := t.apiURL("caps")
+	if err != nil {
+		return err
+	}
+	body, err := t.get(ctx, u.String())
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(body, maxResponseSize))
+	if err != nil {
+		return err
+	}
+	return checkRoot(data, "caps")
+}
+
+func (t *Torznab) get(ctx context.Context, u string) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, errors.New("search: " + resp.Status)
+	}
+	return resp.Body, nil
+}
+
+// apiURL returns the endpoint with the t= function and the API key set.
+func (t *Torznab) apiURL(function string) (*url.URL, error) {
+	u, err := url.Parse(t.endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("search: %w", err)
+	}
+	params := u.Query()
+	params.Set("t", function)
+	if t.apiKey != "" {
+		params.Set("apikey", t.apiKey)
+	}
+	u.RawQuery = params.Encode()
+	return u, nil
+}
+
+func (t *Torznab) searchURL(q Query) (string, error) {
+	u, err := t.apiURL("search")
+	if err != nil {
+		return "", err
+	}
+	params := u.Query()
+	params.Set("q", q.Text)
+	if len(q.Categories) > 0 {
+		cats := make([]string, len(q.Categories))
+		for i, c := range q.Categories {
+			cats[i] = strconv.Itoa(c)
+		}
+		params.Set("cat", strings.Join(cats, ","))
+	}
+	if q.Limit > 0 {
+		params.Set("limit", strconv.Itoa(q.Limit))
+	}
+	// Newznab takes a maximum age in whole days; the rest of the filter
+	// is only applied to the results.
+	if age := q.Filter.MaxAge; age > 0 {
+		days := int((age + 24*time.Hour - 1) / (24 * time.Hour))
+		params.Set("maxage", strconv.Itoa(days))
+	}
+	u.RawQuery = params.Encode()
+	return u.String(), nil
+}
+
+// torznabAttr is a <torznab:attr> or <newznab:attr>; both parse alike.
+type torznabAttr struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type torznabDoc struct {
+	Items []struct {
+		Title    string `xml:"title"`
+		Link     string `xml:"link"`
+		Comments string `xml:"comments"`
+		PubDate  string `xml:"pubDate"`
+		Size     string `xml:"size"`
+		Jackett  string `xml:"jackettindexer"`
+		Prowlarr string `xml:"prowlarrindexer"`
+		// Category is the <category> element, which some indexers
+		// fill with the category's name rather than its ID.
+		Category  []string `xml:"category"`
+		Enclosure struct {
+			URL    string `xml:"url,attr"`
+			Length string `xml:"length,attr"`
+		} `xml:"enclosure"`
+		Attrs []torznabAttr `xml:"attr"`
+	} `xml:"channel>item"`
+}
+
+type torznabError struct {
+	Code        string `xml:"code,attr"`
+	Description string `xml:"description,attr"`
+}
+
+// ParseTorznab reads the response to a Torznab or Newznab search. An
+// <error> response is returned as an error.
+func ParseTorznab(r io.Reader) ([]Result, error) {
+	data, err := io.ReadAll(io.LimitReader(r, maxResponseSize))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkRoot(data, "rss"); err != nil {
+		return nil, err
+	}
+
+	var doc torznabDoc
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("search: %w", err)
+	}
+	results := make([]Result, 0, len(doc.Items))
+	for _, it := range doc.Items {
+		res := Result{
+			Title:     strings.TrimSpace(it.Title),
+			Link:      strings.TrimSpace(it.Link),
+			Details:   strings.TrimSpace(it.Comments),
+			Published: parseTime(it.PubDate),
+			Indexer:   strings.TrimSpace(it.Jackett + it.Prowlarr),
+		}
+		if it.Enclosure.URL != "" {
+			res.Link = strings.TrimSpace(it.Enclosure.URL)
+		}
+		res.Size = parseUint(it.Size)
+		if res.Size == 0 {
+			res.Size = parseUint(it.Enclosure.Length)
+		}
+		for _, c := range it.Category {
+			res.addCategory(c)
+		}
+		applyAttrs(&res, it.Attrs)
+		if strings.HasPrefix(res.Link, "magnet:") {
+			res.Magnet, res.Link = res.Link, ""
+		}
+		if res.InfoHash == "" {
+			res.InfoHash = magnetInfoHash(res.Magnet)
+		}
+		if res.Link == "" && res.Magnet == "" {
+			continue
+		}
+		results = append(results, res)
+	}
+	return results, nil
+}
+
+// checkRoot makes sure data is the <want> document expected, returning
+// an <error> response as an error.
+func checkRoot(data []byte, want string) error {
+	var root struct{ XMLName xml.Name }
+	if err := xml.Unmarshal(data, &root); err != nil {
+		return fmt.Errorf("search: %w", err)
+	}
+	switch root.XMLName.Local {
+	case want:
+		return nil
+	case "error":
+		var e torznabError
+		if err := xml.Unmarshal(data, &e); err != nil {
+			return fmt.Errorf("search: %w", err)
+		}
+		return fmt.Errorf("search: error %s: %s", e.Code, e.Description)
+	default:
+		name := root.XMLName.Local
+		return fmt.Errorf("search: unexpected response <%s>", name)
+	}
+}
+
+// applyAttrs fills res in from the item's attributes. "peers" counts
+// seeders and leechers together.
+func applyAttrs(res *Result, attrs []torznabAttr) {
+	peers := -1
+	leechers := -1
+	for _, a := range attrs {
+		v := strings.TrimSpace(a.Value)
+		switch strings.ToLower(a.Name) {
+		case "seeders":
+			res.Seeders, _ = strconv.Atoi(v)
+		case "leechers":
+			leechers, _ = strconv.Atoi(v)
+		case "peers":
+			peers, _ = strconv.Atoi(v)
+		case "infohash":
+			res.InfoHash = strings.ToLower(v)
+		case "magneturl":
+			res.Magnet = v
+		case "category":
+			res.addCategory(v)
+		case "size":
+			if res.Size == 0 {
+				res.Size = parseUint(v)
+			}
+		}
+	}
+	switch {
+	case leechers >= 0:
+		res.Leechers = leechers
+	case peers >= 0:
+		res.Leechers = max(0, peers-res.Seeders)
+	}
+}
+
+// addCategory records a category ID, ignoring names and repeats.
+func (r *Result) addCategory(s string) {
+	id, err := strconv.Atoi(strings.TrimSpace(s))
+	if err == nil && !slices.Contains(r.Categories, id) {
+		r.Categories = append(r.Categories, id)
+	}
+}
+
+func parseUint(s string) uint64 {
+	n, _ := strconv.ParseUint(strings.TrimSpace(s), 10, 64)
+	return n
+}
+
+func parseTime(s string) time.Time {
+	s = strings.TrimSpace(s)
+	for _, layout := range []string{
+		time.RFC1123Z,
+		time.RFC1123,
+		time.RFC3339,
+	} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
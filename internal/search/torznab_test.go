@@ -0,0 +1,165 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+const torznabResponse = `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0" xmlns:torznab="http://torznab.com/schemas/2015/feed">
+<channel>
+<item>
+  <title>Some.Linux.ISO.x64</title>
+  <guid>https://example.com/details/1</guid>
+  <jackettindexer id="example">Example</jackettindexer>
+  <comments>https://example.com/details/1</comments>
+  <pubDate>Mon, 02 Jan 2006 15:04:05 -0700</pubDate>
+  <size>1073741824</size>
+  <category>2000</category>
+  <link>https://jackett.local/dl/1.torrent</link>
+  <enclosure url="https://jackett.local/dl/1.torrent" length="1073741824"
+    type="application/x-bittorrent"/>
+  <torznab:attr name="category" value="2040"/>
+  <torznab:attr name="category" value="2000"/>
+  <torznab:attr name="seeders" value="12"/>
+  <torznab:attr name="peers" value="15"/>
+  <torznab:attr name="infohash"
+    value="AABBCCDDEEFF00112233445566778899AABBCCDD"/>
+</item>
+<item>
+  <title>Magnet only</title>
+  <link>magnet:?xt=urn:btih:0123456789abcdef0123456789abcdef01234567</link>
+  <torznab:attr name="seeders" value="3"/>
+  <torznab:attr name="leechers" value="7"/>
+</item>
+<item><title>Nothing to download</title></item>
+</channel>
+</rss>`
+
+func TestParseTorznab(t *testing.T) {
+	results, err := ParseTorznab(strings.NewReader(torznabResponse))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("got %d results, want 2", len(results))
+	}
+
+	r := results[0]
+	if r.Link != "https://jackett.local/dl/1.torrent" {
+		t.Errorf("link = %q", r.Link)
+	}
+	if r.Size != 1<<30 || r.Seeders != 12 || r.Leechers != 3 {
+		t.Errorf(
+			"size, seeders, leechers = %d, %d, %d; want %d, 12, 3",
+			r.Size,
+			r.Seeders,
+			r.Leechers,
+			1<<30,
+		)
+	}
+	if r.InfoHash != "aabbccddeeff00112233445566778899aabbccdd" {
+		t.Errorf("info-hash = %q", r.InfoHash)
+	}
+	if r.Indexer != "Example" || r.Published.IsZero() {
+		t.Errorf("indexer = %q, published = %v", r.Indexer, r.Published)
+	}
+	if !slices.Equal(r.Categories, []int{2000, 2040}) {
+		t.Errorf("categories = %v, want [2000 2040]", r.Categories)
+	}
+
+	m := results[1]
+	if m.Link != "" || !strings.HasPrefix(m.Magnet, "magnet:") {
+		t.Errorf("link = %q, magnet = %q", m.Link, m.Magnet)
+	}
+	if m.InfoHash != "0123456789abcdef0123456789abcdef01234567" {
+		t.Errorf("info-hash from magnet = %q", m.InfoHash)
+	}
+	if m.Leechers != 7 {
+		t.Errorf("leechers = %d, want 7", m.Leechers)
+	}
+}
+
+func TestParseTorznabError(t *testing.T) {
+	const resp = `<error code="100" description="Incorrect user credentials"/>`
+	_, err := ParseTorznab(strings.NewReader(resp))
+	if err == nil || !strings.Contains(err.Error(), "credentials") {
+		t.Fatalf("err = %v, want the API's error", err)
+	}
+}
+
+func TestTorznabSearch(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			q := r.URL.Query()
+			got := fmt.Sprintf(
+				"%s %s %s %s %s",
+				q.Get("t"),
+				q.Get("q"),
+				q.Get("apikey"),
+				q.Get("cat"),
+				q.Get("maxage"),
+			)
+			if got != "search ubuntu key 2000,5000 2" {
+				http.Error(w, got, http.StatusBadRequest)
+				return
+			}
+			fmt.Fprint(w, torznabResponse)
+		},
+	))
+	defer srv.Close()
+
+	p := NewTorznab("jackett", srv.URL+"/api", "key", srv.Client())
+	results, err := p.Search(context.Background(), Query{
+		Text:       "ubuntu",
+		Categories: []int{2000, 5000},
+		Filter:     Filter{MaxAge: 36 * time.Hour},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 || results[0].Provider != "jackett" {
+		t.Fatalf("results = %+v", results)
+	}
+}
+
+func TestMagnetInfoHashBase32(t *testing.T) {
+	const magnet = "magnet:?xt=urn:btih:VK54ZXPO74ABCIRTIRKWM54ITGVLXTG5"
+	got := magnetInfoHash(magnet)
+	if got != "aabbccddeeff00112233445566778899aabbccdd" {
+		t.Errorf("info-hash = %q", got)
+	}
+}
+
+func TestTorznabTest(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			q := r.URL.Query()
+			if q.Get("t") != "caps" || q.Get("apikey") != "key" {
+				fmt.Fprint(
+					w,
+					`<error code="100" description="Bad key"/>`,
+				)
+				return
+			}
+			fmt.Fprint(w, `<caps><searching/></caps>`)
+		},
+	))
+	defer srv.Close()
+
+	ctx := context.Background()
+	good := NewTorznab("good", srv.URL, "key", srv.Client())
+	if err := good.Test(ctx); err != nil {
+		t.Errorf("Test with the right key: %v", err)
+	}
+	bad := NewTorznab("bad", srv.URL, "wrong", srv.Client())
+	if err := bad.Test(ctx); err == nil {
+		t.Error("Test with a wrong key passed")
+	}
+}
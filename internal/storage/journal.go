@@ -0,0 +1,140 @@
+package storage
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// recordSize is the encoded size of a Record: piece, offset and length,
+// then a CRC-32 of those so a record torn by a crash is recognized.
+const recordSize = 4 + 8 + 4 + 4
+
+// Record describes a block write about to be applied.
+type Record struct {
+	Piece  uint32
+	Offset int64
+	Length uint32
+}
+
+// Journal is a write-ahead log of block writes. A write is recorded, and
+// the record flushed, before the data is written; once the data itself has
+// been flushed the journal is reset. After an unclean shutdown, the records
+// left name the only pieces whose data may not have reached the disk.
+type Journal struct {
+	mu   sync.Mutex
+	f    *os.File
+	path string
+	n    int
+}
+
+// OpenJournal opens the journal at path, creating it if need be, and
+// returns the records a previous run left in it.
+func OpenJournal(path string) (*Journal, []Record, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, nil, err
+	}
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	records, err := readRecords(f)
+	if err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+	// Drop a torn tail so new records follow the last whole one.
+	end := int64(len(records) * recordSize)
+	if err := f.Truncate(end); err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+	if _, err := f.Seek(end, io.SeekStart); err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+
+	return &Journal{f: f, path: path, n: len(records)}, records, nil
+}
+
+// readRecords decodes records up to the first that is incomplete or fails
+// its checksum.
+func readRecords(r io.Reader) ([]Record, error) {
+	var records []Record
+	buf := make([]byte, recordSize)
+	for {
+		_, err := io.ReadFull(r, buf)
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return records, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		sum := binary.BigEndian.Uint32(buf[16:])
+		if crc32.ChecksumIEEE(buf[:16]) != sum {
+			return records, nil
+		}
+		records = append(records, Record{
+			Piece:  binary.BigEndian.Uint32(buf[0:]),
+			Offset: int64(binary.BigEndian.Uint64(buf[4:])),
+			Length: binary.BigEndian.Uint32(buf[12:]),
+		})
+	}
+}
+
+// Append records a write and flushes the record to disk.
+func (j *Journal) Append(r Record) error {
+	var buf [recordSize]byte
+	binary.BigEndian.PutUint32(buf[0:], r.Piece)
+	binary.BigEndian.PutUint64(buf[4:], uint64(r.Offset))
+	binary.BigEndian.PutUint32(buf[12:], r.Length)
+	binary.BigEndian.PutUint32(buf[16:], crc32.ChecksumIEEE(buf[:16]))
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if _, err := j.f.Write(buf[:]); err != nil {
+		return err
+	}
+	j.n++
+	return j.f.Sync()
+}
+
+// Reset empties the journal, once every write it records has been flushed.
+func (j *Journal) Reset() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.n == 0 {
+		return nil
+	}
+	if err := j.f.Truncate(0); err != nil {
+		return err
+	}
+	if _, err := j.f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	j.n = 0
+	return j.f.Sync()
+}
+
+// Close closes the journal, deleting it if it holds no records.
+func (j *Journal) Close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	err := j.f.Close()
+	if j.n == 0 {
+		rmErr := os.Remove(j.path)
+		if !errors.Is(rmErr, fs.ErrNotExist) {
+			err = errors.Join(err, rmErr)
+		}
+	}
+	return err
+}
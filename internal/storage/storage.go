@@ -0,0 +1,302 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"syscall"
+)
+
+// File is one file of a torrent, laid out back to back with the others in
+// the torrent's byte stream. Padding files take up space in the stream but
+// are never written to disk.
+type File struct {
+	Path    []string
+	Length  int64
+	Padding bool
+}
+
+type entry struct {
+	File
+	offset int64
+}
+
+// Storage maps offsets in a torrent's byte stream onto files below a root
+// directory, opening them lazily on first use.
+type Storage struct {
+	dir   string
+	files []entry
+	size  int64
+
+	mu      sync.Mutex
+	handles map[int]*os.File
+	// skipped are the files left out of the download, which need not be
+	// on disk.
+	skipped map[int]bool
+}
+
+func New(dir string, files []File) *Storage {
+	s := &Storage{
+		dir:     dir,
+		files:   make([]entry, 0, len(files)),
+		handles: make(map[int]*os.File),
+	}
+	for _, f := range files {
+		s.files = append(s.files, entry{File: f, offset: s.size})
+		s.size += f.Length
+	}
+	return s
+}
+
+func (s *Storage) Size() int64 {
+	return s.size
+}
+
+func (s *Storage) WriteAt(p []byte, off int64) (int, error) {
+	return s.span(p, off, (*os.File).WriteAt)
+}
+
+// ReadAt reads from the files backing p. Padding and data not yet written
+// read as zeros.
+func (s *Storage) ReadAt(p []byte, off int64) (int, error) {
+	return s.span(p, off, readAt)
+}
+
+func readAt(f *os.File, b []byte, at int64) (int, error) {
+	n, err := f.ReadAt(b, at)
+	if err == io.EOF {
+		clear(b[n:])
+		return len(b), nil
+	}
+	return n, err
+}
+
+// Sync flushes written data in every open file to disk.
+func (s *Storage) Sync() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var errs []error
+	for _, f := range s.handles {
+		errs = append(errs, f.Sync())
+	}
+	return errors.Join(errs...)
+}
+
+func (s *Storage) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var errs []error
+	for i, f := range s.handles {
+		errs = append(errs, f.Close())
+		delete(s.handles, i)
+	}
+	return errors.Join(errs...)
+}
+
+// span splits p across the files it covers and applies op to each part.
+func (s *Storage) span(
+	p []byte,
+	off int64,
+	op func(f *os.File, b []byte, at int64) (int, error),
+) (int, error) {
+	if off < 0 || off+int64(len(p)) > s.size {
+		return 0, fmt.Errorf(
+			"storage: range %d+%d out of bounds",
+			off,
+			len(p),
+		)
+	}
+
+	i := sort.Search(len(s.files), func(i int) bool {
+		return s.files[i].offset+s.files[i].Length > off
+	})
+
+	n := 0
+	for ; n < len(p) && i < len(s.files); i++ {
+		e := s.files[i]
+		at := off + int64(n) - e.offset
+		chunk := p[n:min(len(p), n+int(e.Length-at))]
+
+		if e.Padding {
+			clear(chunk)
+			n += len(chunk)
+			continue
+		}
+
+		f, err := s.open(i)
+		if err != nil {
+			return n, err
+		}
+		m, err := op(f, chunk, at)
+		n += m
+		if err != nil {
+			return n, err
+		}
+	}
+
+	return n, nil
+}
+
+// SetSkipped records the indices of the files left out of the download,
+// replacing those recorded before.
+func (s *Storage) SetSkipped(indices []int) {
+	skipped := make(map[int]bool, len(indices))
+	for _, i := range indices {
+		skipped[i] = true
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.skipped = skipped
+}
+
+// Missing returns the paths of files that are not on disk. Padding files,
+// which are never written, and skipped files are left out.
+func (s *Storage) Missing() []string {
+	s.mu.Lock()
+	dir, skipped := s.dir, s.skipped
+	s.mu.Unlock()
+
+	var missing []string
+	for i, f := range s.files {
+		if f.Padding || f.Length == 0 || skipped[i] {
+			continue
+		}
+		path := filepath.Join(append([]string{dir}, f.Path...)...)
+		_, err := os.Stat(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			missing = append(missing, path)
+		}
+	}
+	return missing
+}
+
+// Move closes the files and moves those on disk from below the current
+// directory to below dir, copying them where a rename cannot cross file
+// systems. If a file fails to move, those already moved are put back and
+// the storage stays where it was.
+func (s *Storage) Move(dir string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if filepath.Clean(dir) == filepath.Clean(s.dir) {
+		return nil
+	}
+	for i, f := range s.handles {
+		if err := f.Close(); err != nil {
+			return err
+		}
+		delete(s.handles, i)
+	}
+
+	var moved []entry
+	for _, f := range s.files {
+		if f.Padding {
+			continue
+		}
+		src, dst := filePath(s.dir, f.Path), filePath(dir, f.Path)
+		if _, err := os.Lstat(src); errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err := moveFile(src, dst); err != nil {
+			for _, m := range moved {
+				from := filePath(dir, m.Path)
+				_ = moveFile(from, filePath(s.dir, m.Path))
+			}
+			return fmt.Errorf("storage: moving %s: %w", src, err)
+		}
+		moved = append(moved, f)
+	}
+
+	for _, f := range moved {
+		pruneDirs(s.dir, f.Path)
+	}
+	s.dir = dir
+	return nil
+}
+
+// moveFile renames src to dst, falling back to a copy when they are on
+// different file systems. An existing dst is never replaced.
+func moveFile(src, dst string) error {
+	if _, err := os.Lstat(dst); err == nil {
+		return fmt.Errorf("%s already exists", dst)
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return err
+	}
+	err := os.Rename(src, dst)
+	if !errors.Is(err, syscall.EXDEV) {
+		return err
+	}
+
+	if err := copyFile(src, dst); err != nil {
+		os.Remove(dst)
+		return err
+	}
+	return os.Remove(src)
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+func filePath(dir string, path []string) string {
+	return filepath.Join(append([]string{dir}, path...)...)
+}
+
+// pruneDirs removes the directories between root and the file at path
+// that the move left empty.
+func pruneDirs(root string, path []string) {
+	for i := len(path) - 1; i > 0; i-- {
+		if os.Remove(filePath(root, path[:i])) != nil {
+			return
+		}
+	}
+}
+
+func (s *Storage) open(i int) (*os.File, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if f, ok := s.handles[i]; ok {
+		return f, nil
+	}
+
+	path := filepath.Join(append([]string{s.dir}, s.files[i].Path...)...)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	s.handles[i] = f
+	return f, nil
+}
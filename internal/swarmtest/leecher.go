@@ -0,0 +1,72 @@
+package swarmtest
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/prxssh/echo/internal/torrent"
+)
+
+// pollInterval is how often a download is looked at to see if it is done.
+const pollInterval = 5 * time.Millisecond
+
+// Leecher downloads a torrent with the engine: it is a torrent.Torrent,
+// which finds the seeders through the tracker and downloads from them
+// through its peer manager, journaled writes and hash checks.
+type Leecher struct {
+	t *torrent.Torrent
+}
+
+// NewLeecher returns a leecher of t storing the download below dir.
+func NewLeecher(t *Torrent, dir string) (*Leecher, error) {
+	tr, err := torrent.ParseTorrent(t.Raw, torrent.Opts{DownloadDir: dir})
+	if err != nil {
+		return nil, err
+	}
+	return &Leecher{t: tr}, nil
+}
+
+// Download starts the torrent and waits for every piece to be verified and
+// written, or for ctx to end.
+func (l *Leecher) Download(ctx context.Context) error {
+	if err := l.t.Start(ctx); err != nil {
+		return err
+	}
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	n := l.t.Metainfo.Info.NumPieces
+	for {
+		have := l.Have()
+		if have == n {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf(
+				"swarmtest: %d of %d pieces downloaded: %w",
+				have,
+				n,
+				ctx.Err(),
+			)
+		case <-ticker.C:
+		}
+	}
+}
+
+// Have returns how many pieces are verified and written.
+func (l *Leecher) Have() int {
+	return l.t.Have().Count()
+}
+
+// Downloaded returns the bytes of blocks received.
+func (l *Leecher) Downloaded() uint64 {
+	_, downloaded, _ := l.t.Totals()
+	return downloaded
+}
+
+// Close stops the torrent, flushing and closing its files.
+func (l *Leecher) Close() {
+	l.t.Stop(context.Background())
+}
@@ -0,0 +1,144 @@
+package swarmtest
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/prxssh/echo/internal/peer"
+)
+
+// socket replays a stream of messages forever the way a busy connection
+// hands them over, up to chunk bytes a read, and counts the reads and the
+// deadlines set, which are what a read loop costs in syscalls.
+type socket struct {
+	stream    []byte
+	off       int
+	chunk     int
+	reads     int
+	deadlines int
+}
+
+func (s *socket) Read(b []byte) (int, error) {
+	s.reads++
+	n := copy(b[:min(len(b), s.chunk)], s.stream[s.off:])
+	s.off = (s.off + n) % len(s.stream)
+	return n, nil
+}
+
+func (s *socket) SetReadDeadline(time.Time) error {
+	s.deadlines++
+	return nil
+}
+
+// pieceStream returns n messages as a downloading peer receives them: 16
+// KiB blocks with a have after each.
+func pieceStream(n int) []byte {
+	block := make([]byte, 16<<10)
+	var buf bytes.Buffer
+	for i := range n {
+		peer.WriteMessage(&buf, peer.MessagePiece(i, 0, block))
+		peer.WriteMessage(&buf, peer.MessageHave(i))
+	}
+	return buf.Bytes()
+}
+
+func BenchmarkReadLoop(b *testing.B) {
+	const timeout = 2 * time.Minute
+	stream := pieceStream(64)
+
+	report := func(b *testing.B, s *socket) {
+		b.ReportMetric(float64(s.reads)/float64(b.N), "reads/op")
+		deadlines := float64(s.deadlines) / float64(b.N)
+		b.ReportMetric(deadlines, "deadlines/op")
+	}
+
+	// ReadMessage is the loop as it was: a deadline set and cleared, a
+	// length prefix read and a pooled buffer taken for every message.
+	b.Run("ReadMessage", func(b *testing.B) {
+		s := &socket{stream: stream, chunk: 64 << 10}
+		b.SetBytes(int64(len(stream)) / 128)
+		b.ReportAllocs()
+		for b.Loop() {
+			s.SetReadDeadline(time.Now().Add(timeout))
+			msg, err := peer.ReadMessage(
+				s,
+				peer.DefaultMaxMessageLength,
+			)
+			s.SetReadDeadline(time.Time{})
+			if err != nil {
+				b.Fatal(err)
+			}
+			msg.Release()
+		}
+		report(b, s)
+	})
+
+	b.Run("Reader", func(b *testing.B) {
+		s := &socket{stream: stream, chunk: 64 << 10}
+		rd := peer.NewReader(s, peer.DefaultMaxMessageLength, timeout)
+		b.SetBytes(int64(len(stream)) / 128)
+		b.ReportAllocs()
+		for b.Loop() {
+			if _, err := rd.Next(); err != nil {
+				b.Fatal(err)
+			}
+		}
+		report(b, s)
+	})
+}
+
+func TestReaderTimeout(t *testing.T) {
+	ln, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	client, err := net.Dial("tcp4", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	server, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+
+	block := bytes.Repeat([]byte{7}, 16<<10)
+	wire := peer.MessagePiece(3, 0, block).Serialize()
+	half := len(wire) / 2
+	if _, err := client.Write(wire[:half]); err != nil {
+		t.Fatal(err)
+	}
+
+	rd := peer.NewReader(
+		server,
+		peer.DefaultMaxMessageLength,
+		50*time.Millisecond,
+	)
+	if _, err := rd.Next(); !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Fatalf("Next = %v, want a timeout", err)
+	}
+
+	// The half that arrived before the timeout is kept for the rest.
+	if _, err := client.Write(wire[half:]); err != nil {
+		t.Fatal(err)
+	}
+	msg, err := rd.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	index, _, got, ok := msg.ParsePiece(peer.PieceBounds{
+		Pieces:      4,
+		PieceLength: 16 << 10,
+		TotalLength: 64 << 10,
+		MaxBlock:    peer.MaxBlockLength,
+	})
+	if !ok || index != 3 || !bytes.Equal(got, block) {
+		t.Errorf("got piece %d of %d bytes, ok %v", index, len(got), ok)
+	}
+}
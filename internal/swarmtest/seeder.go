@@ -0,0 +1,166 @@
+package swarmtest
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/netip"
+	"sync"
+	"sync/atomic"
+
+	"github.com/prxssh/echo/internal/bitfield"
+	"github.com/prxssh/echo/internal/peer"
+	"github.com/prxssh/echo/internal/tracker"
+)
+
+// seeders numbers the seeders made, for their peer IDs.
+var seeders atomic.Uint64
+
+// SeederOpts say how a seeder behaves.
+type SeederOpts struct {
+	// Corrupt, when set, serves garbage in place of the pieces it
+	// picks, as a broken or malicious peer would.
+	Corrupt func(index int) bool
+}
+
+// Seeder has every piece of a torrent, and serves them to whoever connects
+// and asks.
+type Seeder struct {
+	t      *Torrent
+	opts   SeederOpts
+	ln     net.Listener
+	peerID [20]byte
+	// Uploaded counts the bytes of blocks served.
+	Uploaded atomic.Uint64
+
+	wg    sync.WaitGroup
+	mu    sync.Mutex
+	conns map[net.Conn]struct{}
+}
+
+// NewSeeder starts a seeder of t listening on loopback.
+func NewSeeder(t *Torrent, opts SeederOpts) (*Seeder, error) {
+	ln, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	s := &Seeder{
+		t:      t,
+		opts:   opts,
+		ln:     ln,
+		peerID: peerID("S", seeders.Add(1)),
+		conns:  make(map[net.Conn]struct{}),
+	}
+	s.wg.Go(s.accept)
+	return s, nil
+}
+
+// Addr is where the seeder listens.
+func (s *Seeder) Addr() netip.AddrPort {
+	return s.ln.Addr().(*net.TCPAddr).AddrPort()
+}
+
+// Announce tells the torrent's tracker about the seeder.
+func (s *Seeder) Announce(ctx context.Context) error {
+	tr, err := tracker.NewTracker(s.t.Metainfo.AnnounceURLs[0])
+	if err != nil {
+		return err
+	}
+	_, err = tr.Announce(ctx, &tracker.AnnounceParams{
+		InfoHash: s.t.Metainfo.Info.Hash,
+		PeerID:   s.peerID,
+		Port:     s.Addr().Port(),
+		Event:    tracker.EventStarted,
+		NumWant:  50,
+	})
+	return err
+}
+
+// Close stops listening and drops every connection.
+func (s *Seeder) Close() error {
+	err := s.ln.Close()
+	s.mu.Lock()
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.mu.Unlock()
+	s.wg.Wait()
+	return err
+}
+
+func (s *Seeder) accept() {
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.conns[conn] = struct{}{}
+		s.mu.Unlock()
+		s.wg.Go(func() {
+			defer func() {
+				s.mu.Lock()
+				delete(s.conns, conn)
+				s.mu.Unlock()
+				conn.Close()
+			}()
+			s.serve(conn)
+		})
+	}
+}
+
+// serve answers one peer: the handshake, a full bitfield, an unchoke once
+// it is interested, then a block for every request.
+func (s *Seeder) serve(conn net.Conn) error {
+	m := s.t.Metainfo
+	hs := peer.NewHandshake(m.Info.Hash, s.peerID)
+	if _, err := hs.Perform(conn); err != nil {
+		return err
+	}
+	have := bitfield.New(m.Info.NumPieces)
+	have.SetAll()
+	err := peer.WriteMessage(conn, peer.MessageBitfield(have.ToBytes()))
+	if err != nil {
+		return err
+	}
+
+	b := bounds(m)
+	rd := peer.NewReader(conn, peer.DefaultMaxMessageLength, 0)
+	for {
+		msg, err := rd.Next()
+		if err != nil {
+			return err
+		}
+		if msg == nil {
+			continue
+		}
+		if err := s.answer(conn, msg, b); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *Seeder) answer(
+	conn net.Conn,
+	msg *peer.Message,
+	b peer.PieceBounds,
+) error {
+	switch msg.ID {
+	case peer.MsgInterested:
+		return peer.WriteMessage(conn, peer.MessageUnchoke())
+	case peer.MsgRequest:
+		index, begin, length, ok := msg.ParseRequest(b)
+		if !ok {
+			return errors.New("swarmtest: bad request")
+		}
+		off := int64(index)*int64(b.PieceLength) + int64(begin)
+		block := s.t.Data[off : off+int64(length)]
+		if s.opts.Corrupt != nil && s.opts.Corrupt(int(index)) {
+			block = make([]byte, length)
+		}
+		s.Uploaded.Add(uint64(length))
+		reply := peer.MessagePiece(int(index), int(begin), block)
+		return peer.WriteMessage(conn, reply)
+	}
+	return nil
+}
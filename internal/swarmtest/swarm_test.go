@@ -0,0 +1,120 @@
+package swarmtest
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// swarm starts a tracker and n seeders of a torrent of size bytes, the
+// seeders made with opts in turn and the last of them reused for the rest.
+func swarm(
+	tb testing.TB,
+	size int,
+	pieceLength uint64,
+	n int,
+	opts ...SeederOpts,
+) *Torrent {
+	tb.Helper()
+	tr := NewTracker()
+	tb.Cleanup(tr.Close)
+
+	t, err := NewTorrent(tb.TempDir(), size, pieceLength, tr.URL(), 1)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	for i := range n {
+		var o SeederOpts
+		if len(opts) > 0 {
+			o = opts[min(i, len(opts)-1)]
+		}
+		s, err := NewSeeder(t, o)
+		if err != nil {
+			tb.Fatal(err)
+		}
+		tb.Cleanup(func() { s.Close() })
+		if err := s.Announce(tb.Context()); err != nil {
+			tb.Fatal(err)
+		}
+	}
+	return t
+}
+
+// download has a leecher download t below dir, giving up after timeout.
+func download(
+	tb testing.TB,
+	t *Torrent,
+	dir string,
+	timeout time.Duration,
+) (*Leecher, error) {
+	tb.Helper()
+	l, err := NewLeecher(t, dir)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	tb.Cleanup(l.Close)
+
+	ctx, cancel := context.WithTimeout(tb.Context(), timeout)
+	defer cancel()
+	return l, l.Download(ctx)
+}
+
+func TestDownload(t *testing.T) {
+	tor := swarm(t, 1<<20+12345, 64<<10, 2)
+	dir := t.TempDir()
+	l, err := download(t, tor, dir, 30*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := l.Downloaded(); got < uint64(len(tor.Data)) {
+		t.Errorf("downloaded %d bytes, want %d", got, len(tor.Data))
+	}
+	l.Close()
+
+	path := filepath.Join(dir, tor.Metainfo.Info.Name)
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, tor.Data) {
+		t.Error("downloaded content differs from the seeded")
+	}
+}
+
+func TestCorruptSeeder(t *testing.T) {
+	corrupt := SeederOpts{Corrupt: func(int) bool { return true }}
+
+	// Every piece fails its hash check, so none is kept.
+	tor := swarm(t, 256<<10, 32<<10, 1, corrupt)
+	l, err := download(t, tor, t.TempDir(), 500*time.Millisecond)
+	if err == nil {
+		t.Fatal("downloaded from a seeder serving garbage")
+	}
+	if l.Have() != 0 {
+		t.Errorf("%d corrupt pieces were kept", l.Have())
+	}
+
+	// With an honest seeder in the swarm, the pieces the corrupt one
+	// spoiled are downloaded again from it.
+	tor = swarm(t, 256<<10, 32<<10, 2, corrupt, SeederOpts{})
+	_, err = download(t, tor, t.TempDir(), 30*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func BenchmarkDownload(b *testing.B) {
+	const size = 16 << 20
+	tor := swarm(b, size, 256<<10, 4)
+	b.SetBytes(size)
+	b.ReportAllocs()
+	for b.Loop() {
+		_, err := download(b, tor, b.TempDir(), 30*time.Second)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
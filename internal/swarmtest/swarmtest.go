@@ -0,0 +1,76 @@
+// Package swarmtest runs a swarm inside the process, so that the download
+// path can be tested and benchmarked without reaching the network: a
+// tracker on loopback, seeders serving a made-up torrent over the peer
+// wire protocol, and a leecher that is the engine's own torrent.Torrent,
+// announcing, dialing, requesting, verifying and writing as it does for
+// any other swarm.
+package swarmtest
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+
+	"github.com/prxssh/echo/internal/peer"
+	"github.com/prxssh/echo/internal/torrent"
+)
+
+// Torrent is content made up for a swarm, and its metainfo, parsed and as
+// the .torrent file has it.
+type Torrent struct {
+	Data     []byte
+	Metainfo *torrent.Metainfo
+	Raw      []byte
+}
+
+// NewTorrent makes up size bytes of content in dir, the same for the same
+// seed, and a torrent of it cut into pieces of pieceLength and announced
+// to announceURL.
+func NewTorrent(
+	dir string,
+	size int,
+	pieceLength uint64,
+	announceURL string,
+	seed uint64,
+) (*Torrent, error) {
+	data := make([]byte, size)
+	rng := rand.NewChaCha8([32]byte{byte(seed), byte(seed >> 8)})
+	rng.Read(data)
+
+	path := filepath.Join(dir, fmt.Sprintf("content-%d.bin", seed))
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return nil, err
+	}
+	raw, err := torrent.Create(torrent.CreateOpts{
+		Path:        path,
+		PieceLength: pieceLength,
+		Trackers:    []string{announceURL},
+	})
+	if err != nil {
+		return nil, err
+	}
+	m, err := torrent.ParseMetainfo(bytes.NewReader(raw))
+	if err != nil {
+		return nil, err
+	}
+	return &Torrent{Data: data, Metainfo: m, Raw: raw}, nil
+}
+
+// bounds returns the piece geometry messages about m are checked against.
+func bounds(m *torrent.Metainfo) peer.PieceBounds {
+	return peer.PieceBounds{
+		Pieces:      uint32(m.Info.NumPieces),
+		PieceLength: uint32(m.Info.PieceLength),
+		TotalLength: m.Size,
+		MaxBlock:    peer.MaxBlockLength,
+	}
+}
+
+// peerID returns a peer ID unique to role and n.
+func peerID(role string, n uint64) [20]byte {
+	var id [20]byte
+	copy(id[:], fmt.Sprintf("-ST0001-%s%08d", role, n))
+	return id
+}
@@ -0,0 +1,95 @@
+package swarmtest
+
+import (
+	"encoding/binary"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+
+	"github.com/prxssh/echo/internal/bencode"
+)
+
+// interval is how often the tracker asks peers to announce, in seconds.
+const interval = 60
+
+// Tracker is an HTTP tracker on loopback that hands every peer announcing
+// a torrent the others that did.
+type Tracker struct {
+	srv *httptest.Server
+
+	mu sync.Mutex
+	// swarms holds, by info-hash, the address of each peer by its ID.
+	swarms map[string]map[string]string
+}
+
+func NewTracker() *Tracker {
+	t := &Tracker{swarms: make(map[string]map[string]string)}
+	t.srv = httptest.NewServer(http.HandlerFunc(t.announce))
+	return t
+}
+
+// URL is the tracker's announce URL.
+func (t *Tracker) URL() string {
+	return t.srv.URL + "/announce"
+}
+
+func (t *Tracker) Close() {
+	t.srv.Close()
+}
+
+func (t *Tracker) announce(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	hash, id := q.Get("info_hash"), q.Get("peer_id")
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	port, perr := strconv.ParseUint(q.Get("port"), 10, 16)
+	if len(hash) != 20 || len(id) != 20 || err != nil || perr != nil {
+		w.Write(failure("bad announce"))
+		return
+	}
+	addr := net.JoinHostPort(host, strconv.FormatUint(port, 10))
+
+	t.mu.Lock()
+	swarm, ok := t.swarms[hash]
+	if !ok {
+		swarm = make(map[string]string)
+		t.swarms[hash] = swarm
+	}
+	if q.Get("event") == "stopped" {
+		delete(swarm, id)
+	} else if port != 0 {
+		swarm[id] = addr
+	}
+	var peers []byte
+	for other, addr := range swarm {
+		if other != id {
+			peers = appendCompact(peers, addr)
+		}
+	}
+	t.mu.Unlock()
+
+	body, _ := bencode.Marshal(map[string]any{
+		"interval": interval,
+		"peers":    peers,
+	})
+	w.Write(body)
+}
+
+// appendCompact appends addr, an IPv4 loopback one, in the compact form
+// of BEP 23.
+func appendCompact(b []byte, addr string) []byte {
+	host, port, _ := net.SplitHostPort(addr)
+	ip := net.ParseIP(host).To4()
+	p, _ := strconv.ParseUint(port, 10, 16)
+	if ip == nil {
+		return b
+	}
+	b = append(b, ip...)
+	return binary.BigEndian.AppendUint16(b, uint16(p))
+}
+
+func failure(reason string) []byte {
+	body, _ := bencode.Marshal(map[string]any{"failure reason": reason})
+	return body
+}
@@ -0,0 +1,75 @@
+package torrent
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+)
+
+// Check hashes the data already on disk and marks the pieces that verify
+// as complete, so a torrent whose files exist, such as one imported from
+// another client, picks up where they left off. The torrent must not be
+// running; it returns to the state it was in once the check is done.
+func (t *Torrent) Check(ctx context.Context) error {
+	t.runMu.Lock()
+	running := t.cancel != nil
+	t.runMu.Unlock()
+	if running {
+		return errors.New("torrent: cannot check a running torrent")
+	}
+
+	prev := t.State()
+	if err := t.setState(ctx, StateChecking, nil); err != nil {
+		return err
+	}
+	if err := t.verifyPieces(ctx); err != nil {
+		_ = t.setState(ctx, prev, nil)
+		return err
+	}
+	return t.setState(ctx, prev, nil)
+}
+
+// Recheck is Check for a torrent that may be running: one that is stops
+// for the check and starts again once it is done.
+func (t *Torrent) Recheck(ctx context.Context) error {
+	if !t.State().Active() {
+		return t.Check(ctx)
+	}
+
+	t.halt(ctx, StateChecking, nil)
+	err := t.verifyPieces(ctx)
+	if serr := t.Start(ctx); err == nil {
+		err = serr
+	}
+	return err
+}
+
+// verifyPieces hashes every piece on disk and marks it complete or
+// missing accordingly.
+func (t *Torrent) verifyPieces(ctx context.Context) error {
+	info := t.Metainfo.Info
+	buf := make([]byte, info.PieceLength)
+	for i := range info.NumPieces {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		data := buf[:t.Metainfo.PieceSize(i)]
+		off := int64(i) * int64(info.PieceLength)
+		_, err := t.storage.ReadAt(data, off)
+		if err == nil && info.VerifyPiece(i, data) {
+			t.picker.Done(i)
+		} else {
+			t.picker.Forget(i)
+		}
+	}
+	t.recount()
+
+	_, _, left := t.Totals()
+	slog.Info(
+		"checked torrent",
+		slog.String("name", info.Name),
+		slog.Uint64("left", left),
+	)
+	return nil
+}
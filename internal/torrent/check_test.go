@@ -0,0 +1,50 @@
+package torrent
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "file.bin")
+	content := make([]byte, 3*minPieceLength+100)
+	for i := range content {
+		content[i] = byte(i)
+	}
+	if err := os.WriteFile(path, content, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	data, err := Create(CreateOpts{Path: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Corrupt the second piece before checking.
+	content[minPieceLength+1] ^= 0xff
+	if err := os.WriteFile(path, content, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tr, err := ParseTorrent(data, Opts{DownloadDir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tr.Check(context.Background()); err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+
+	for i, want := range []bool{true, false, true, true} {
+		if got := tr.picker.Have(i); got != want {
+			t.Errorf("piece %d: have = %v; want %v", i, got, want)
+		}
+	}
+	if _, _, left := tr.Totals(); left != minPieceLength {
+		t.Errorf("left = %d; want %d", left, minPieceLength)
+	}
+	if s := tr.State(); s != StatePaused {
+		t.Errorf("state = %s; want %s", s, StatePaused)
+	}
+}
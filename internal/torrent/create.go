@@ -0,0 +1,248 @@
+package torrent
+
+import (
+	"context"
+	"crypto/sha1"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"math/bits"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/prxssh/echo/internal/bencode"
+)
+
+const (
+	minPieceLength   = 16 << 10
+	maxPieceLength   = 16 << 20
+	targetPieceCount = 1500
+	defaultCreatedBy = "Echo"
+	progressEvery    = 64
+)
+
+type CreateOpts struct {
+	// Path is the file or directory to create the torrent from.
+	Path string
+	// PieceLength is picked from the total size when zero.
+	PieceLength uint64
+	// Trackers are announced to in order, one tier each.
+	Trackers  []string
+	WebSeeds  []string
+	Comment   string
+	CreatedBy string
+	Private   bool
+	// Source tags the torrent for one tracker. It changes the info-hash,
+	// which lets the same files be cross-seeded to several private
+	// trackers.
+	Source string
+	// OnProgress, when set, is called as pieces are hashed.
+	OnProgress func(hashed, total int)
+}
+
+type createFile struct {
+	Length int64    `bencode:"length"`
+	Path   []string `bencode:"path"`
+}
+
+type createInfo struct {
+	Files       []createFile `bencode:"files,omitempty"`
+	Length      *int64       `bencode:"length"`
+	Name        string       `bencode:"name"`
+	PieceLength int64        `bencode:"piece length"`
+	Pieces      []byte       `bencode:"pieces"`
+	Private     bool         `bencode:"private,omitempty"`
+	Source      string       `bencode:"source,omitempty"`
+}
+
+type createMeta struct {
+	Announce     string     `bencode:"announce,omitempty"`
+	AnnounceList [][]string `bencode:"announce-list,omitempty"`
+	Comment      string     `bencode:"comment,omitempty"`
+	CreatedBy    string     `bencode:"created by,omitempty"`
+	CreationDate int64      `bencode:"creation date"`
+	Info         createInfo `bencode:"info"`
+	URLList      []string   `bencode:"url-list,omitempty"`
+}
+
+// Create hashes the files at opts.Path and returns the encoded .torrent.
+func Create(opts CreateOpts) ([]byte, error) {
+	return CreateContext(context.Background(), opts)
+}
+
+// CreateContext is Create, giving up on hashing once ctx is done.
+func CreateContext(ctx context.Context, opts CreateOpts) ([]byte, error) {
+	root := filepath.Clean(opts.Path)
+	st, err := os.Stat(root)
+	if err != nil {
+		return nil, err
+	}
+
+	info := createInfo{
+		Name:    filepath.Base(root),
+		Private: opts.Private,
+		Source:  opts.Source,
+	}
+	var paths []string
+	var total int64
+	if st.IsDir() {
+		info.Files, paths, total, err = collectFiles(root)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		total = st.Size()
+		info.Length = &total
+		paths = []string{root}
+	}
+
+	pieceLength := int64(opts.PieceLength)
+	if pieceLength == 0 {
+		pieceLength = autoPieceLength(total)
+	}
+	info.PieceLength = pieceLength
+
+	info.Pieces, err = hashFiles(
+		ctx,
+		paths,
+		total,
+		pieceLength,
+		opts.OnProgress,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	meta := createMeta{
+		Comment:      opts.Comment,
+		CreatedBy:    opts.CreatedBy,
+		CreationDate: time.Now().Unix(),
+		Info:         info,
+		URLList:      opts.WebSeeds,
+	}
+	if meta.CreatedBy == "" {
+		meta.CreatedBy = defaultCreatedBy
+	}
+	if len(opts.Trackers) > 0 {
+		meta.Announce = opts.Trackers[0]
+	}
+	if len(opts.Trackers) > 1 {
+		for _, tr := range opts.Trackers {
+			tier := []string{tr}
+			meta.AnnounceList = append(meta.AnnounceList, tier)
+		}
+	}
+
+	return bencode.Marshal(meta)
+}
+
+// collectFiles lists the regular files below root in lexical order.
+func collectFiles(root string) ([]createFile, []string, int64, error) {
+	var files []createFile
+	var paths []string
+	var total int64
+
+	err := filepath.WalkDir(
+		root,
+		func(p string, d fs.DirEntry, err error) error {
+			if err != nil || !d.Type().IsRegular() {
+				return err
+			}
+			fi, err := d.Info()
+			if err != nil {
+				return err
+			}
+
+			rel, err := filepath.Rel(root, p)
+			if err != nil {
+				return err
+			}
+			rel = filepath.ToSlash(rel)
+			files = append(files, createFile{
+				Length: fi.Size(),
+				Path:   strings.Split(rel, "/"),
+			})
+			paths = append(paths, p)
+			total += fi.Size()
+			return nil
+		},
+	)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	if len(files) == 0 {
+		return nil, nil, 0, errors.New("torrent: no files to add")
+	}
+
+	return files, paths, total, nil
+}
+
+// autoPieceLength aims for about targetPieceCount pieces, as a power of two
+// between 16 KiB and 16 MiB.
+func autoPieceLength(total int64) int64 {
+	n := max(total/targetPieceCount, minPieceLength)
+	pl := int64(1) << (bits.Len64(uint64(n - 1)))
+	return min(pl, maxPieceLength)
+}
+
+func hashFiles(
+	ctx context.Context,
+	paths []string,
+	total, pieceLength int64,
+	onProgress func(hashed, total int),
+) ([]byte, error) {
+	count := int((total + pieceLength - 1) / pieceLength)
+	pieces := make([]byte, 0, count*sha1.Size)
+	buf := make([]byte, pieceLength)
+	fill := 0
+
+	emit := func() {
+		sum := sha1.Sum(buf[:fill])
+		pieces = append(pieces, sum[:]...)
+		fill = 0
+
+		hashed := len(pieces) / sha1.Size
+		if onProgress != nil &&
+			(hashed%progressEvery == 0 || hashed == count) {
+			onProgress(hashed, count)
+		}
+	}
+
+	for _, p := range paths {
+		f, err := os.Open(p)
+		if err != nil {
+			return nil, err
+		}
+		for {
+			if err := ctx.Err(); err != nil {
+				f.Close()
+				return nil, err
+			}
+			n, err := io.ReadFull(f, buf[fill:])
+			fill += n
+			if fill == len(buf) {
+				emit()
+			}
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				break
+			}
+			if err != nil {
+				f.Close()
+				return nil, fmt.Errorf(
+					"torrent: reading %s: %w",
+					p,
+					err,
+				)
+			}
+		}
+		f.Close()
+	}
+	if fill > 0 {
+		emit()
+	}
+
+	return pieces, nil
+}
@@ -0,0 +1,109 @@
+package torrent
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestCreateMultiFile(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "album")
+	a := bytes.Repeat([]byte{'a'}, 40000)
+	b := bytes.Repeat([]byte{'b'}, 5000)
+	err := os.MkdirAll(filepath.Join(root, "disc1"), 0o755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	write := func(name string, data []byte) {
+		err := os.WriteFile(filepath.Join(root, name), data, 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("cover.jpg", b)
+	write(filepath.Join("disc1", "01.flac"), a)
+
+	var progress int
+	trackers := []string{"http://t1/announce", "http://t2/announce"}
+	data, err := Create(CreateOpts{
+		Path:       root,
+		Trackers:   trackers,
+		Private:    true,
+		Source:     "RED",
+		OnProgress: func(hashed, total int) { progress = hashed },
+	})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	m, err := ParseMetainfo(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("ParseMetainfo() error = %v", err)
+	}
+	if m.Info.Name != "album" || m.Info.Source != "RED" ||
+		!m.Info.Private || m.CreatedBy != defaultCreatedBy {
+		t.Fatalf("unexpected metainfo: %+v %+v", m, m.Info)
+	}
+	if m.Info.PieceLength != minPieceLength || m.Size != 45000 {
+		t.Fatalf(
+			"piece length %d, size %d",
+			m.Info.PieceLength,
+			m.Size,
+		)
+	}
+	if !reflect.DeepEqual(m.AnnounceURLs, trackers) {
+		t.Fatalf("AnnounceURLs = %v; want %v", m.AnnounceURLs, trackers)
+	}
+
+	stream := append(bytes.Clone(b), a...)
+	for i := range m.Info.NumPieces {
+		end := min((i+1)*minPieceLength, len(stream))
+		if !m.Info.VerifyPiece(i, stream[i*minPieceLength:end]) {
+			t.Fatalf("piece %d does not verify", i)
+		}
+	}
+	if progress != m.Info.NumPieces {
+		t.Fatalf("progress = %d; want %d", progress, m.Info.NumPieces)
+	}
+}
+
+func TestCreateSourceChangesInfoHash(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "file.bin")
+	if err := os.WriteFile(path, []byte("hello"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	hash := func(source string) [20]byte {
+		data, err := Create(CreateOpts{Path: path, Source: source})
+		if err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+		m, err := ParseMetainfo(bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("ParseMetainfo() error = %v", err)
+		}
+		return m.Info.Hash
+	}
+
+	if hash("") == hash("PTP") {
+		t.Fatal("source did not change the info-hash")
+	}
+}
+
+func TestCreateContextCancelled(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "file.bin")
+	if err := os.WriteFile(path, []byte("hello"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := CreateContext(ctx, CreateOpts{Path: path})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("CreateContext() error = %v; want Canceled", err)
+	}
+}
@@ -0,0 +1,31 @@
+package torrent
+
+import (
+	"encoding/hex"
+
+	"github.com/prxssh/echo/internal/peer"
+	"github.com/prxssh/echo/internal/piece"
+)
+
+// Debug is what the engine knows of a torrent beyond its Status, for
+// diagnosing why it has stalled.
+type Debug struct {
+	InfoHash string         `json:"infoHash"`
+	Name     string         `json:"name"`
+	State    State          `json:"state"`
+	Peers    int            `json:"peers"`
+	Picker   piece.Stats    `json:"picker"`
+	Dialer   peer.DialStats `json:"dialer"`
+}
+
+func (t *Torrent) Debug() Debug {
+	peers, _ := t.PeerManager.Counts()
+	return Debug{
+		InfoHash: hex.EncodeToString(t.Metainfo.Info.Hash[:]),
+		Name:     t.Metainfo.Info.Name,
+		State:    t.State(),
+		Peers:    peers,
+		Picker:   t.picker.Stats(),
+		Dialer:   t.PeerManager.DialStats(),
+	}
+}
@@ -0,0 +1,125 @@
+package torrent
+
+import (
+	"slices"
+
+	"github.com/prxssh/echo/internal/bitfield"
+	"github.com/prxssh/echo/internal/peer"
+	"github.com/prxssh/echo/internal/tracker"
+)
+
+// Details is everything the details pane shows about a torrent beyond its
+// Status.
+type Details struct {
+	Files    []FileStatus     `json:"files"`
+	Pieces   PieceSummary     `json:"pieces"`
+	Trackers []tracker.Status `json:"trackers"`
+	Peers    []peer.Info      `json:"peers"`
+}
+
+// FileStatus is one of the torrent's files and how much of it is done.
+type FileStatus struct {
+	// Path is the file's path within the torrent.
+	Path []string `json:"path"`
+	Size uint64   `json:"size"`
+	// Progress is the percentage of the file's bytes in verified
+	// pieces.
+	Progress float64 `json:"progress"`
+	// Index is the file's index in the info dict's file list, by which
+	// its priority is set.
+	Index int `json:"index"`
+	// Wanted is false for files left out of the download.
+	Wanted   bool         `json:"wanted"`
+	Priority FilePriority `json:"priority"`
+}
+
+// PieceSummary sums up which pieces we and the connected peers have.
+type PieceSummary struct {
+	Count  int    `json:"count"`
+	Length uint64 `json:"length"`
+	Have   int    `json:"have"`
+	// Unavailable counts the pieces we lack that no connected peer has.
+	Unavailable int `json:"unavailable"`
+	// Availability is how many full copies the connected peers hold
+	// between them: the rarest piece's count, plus the share of pieces
+	// seen more often than that.
+	Availability float64 `json:"availability"`
+}
+
+// Details returns the torrent's files, pieces, trackers and peers.
+func (t *Torrent) Details() Details {
+	have := t.picker.Bitfield()
+	return Details{
+		Files:    t.fileStatuses(have),
+		Pieces:   t.pieceSummary(have),
+		Trackers: t.TrackerManager.Statuses(),
+		Peers:    t.PeerManager.Peers(),
+	}
+}
+
+func (t *Torrent) fileStatuses(have bitfield.Bitfield) []FileStatus {
+	m := t.Metainfo
+	files := []File{{Length: m.Size, Path: []string{m.Info.Name}}}
+	if m.Info.Files != nil {
+		files = *m.Info.Files
+	}
+
+	priorities := t.FilePriorities()
+	pl := m.Info.PieceLength
+	out := make([]FileStatus, 0, len(files))
+	var off uint64
+	for i, f := range files {
+		start, end := off, off+f.Length
+		off = end
+		if f.Padding {
+			continue
+		}
+
+		fs := FileStatus{
+			Path:     f.Path,
+			Size:     f.Length,
+			Progress: 100,
+			Index:    i,
+			Wanted:   priorities[i] != FileSkip,
+			Priority: priorities[i],
+		}
+		if f.Length > 0 {
+			var done uint64
+			for p := start / pl; p*pl < end; p++ {
+				if !have.Has(int(p)) {
+					continue
+				}
+				done += min(end, (p+1)*pl) - max(start, p*pl)
+			}
+			fs.Progress = float64(done) / float64(f.Length) * 100
+		}
+		out = append(out, fs)
+	}
+	return out
+}
+
+func (t *Torrent) pieceSummary(have bitfield.Bitfield) PieceSummary {
+	info := t.Metainfo.Info
+	s := PieceSummary{
+		Count:  info.NumPieces,
+		Length: info.PieceLength,
+		Have:   min(have.Count(), info.NumPieces),
+	}
+
+	avail := t.PeerManager.Availability()
+	if len(avail) == 0 {
+		return s
+	}
+	rarest := slices.Min(avail)
+	above := 0
+	for i, n := range avail {
+		if n > rarest {
+			above++
+		}
+		if n == 0 && !have.Has(i) {
+			s.Unavailable++
+		}
+	}
+	s.Availability = float64(rarest) + float64(above)/float64(len(avail))
+	return s
+}
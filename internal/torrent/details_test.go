@@ -0,0 +1,96 @@
+package torrent
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDetails(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "set")
+	if err := os.Mkdir(src, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string][]byte{
+		"a.bin": make([]byte, 2*minPieceLength),
+		"b.bin": []byte("not zeros, which a missing file reads as"),
+	}
+	for name, content := range files {
+		path := filepath.Join(src, name)
+		if err := os.WriteFile(path, content, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	data, err := Create(CreateOpts{Path: src})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Only a.bin is on disk.
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "set"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	err = os.Rename(
+		filepath.Join(src, "a.bin"),
+		filepath.Join(dir, "set", "a.bin"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr, err := ParseTorrent(data, Opts{DownloadDir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tr.Check(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	d := tr.Details()
+	progress := make(map[string]float64)
+	for _, f := range d.Files {
+		progress[filepath.Join(f.Path...)] = f.Progress
+		if !f.Wanted {
+			t.Errorf("%v not wanted", f.Path)
+		}
+	}
+	if progress["a.bin"] != 100 || progress["b.bin"] != 0 {
+		t.Errorf("progress = %v; want a.bin 100, b.bin 0", progress)
+	}
+
+	p := d.Pieces
+	if p.Count != 3 || p.Have != 2 || p.Unavailable != 1 {
+		t.Errorf(
+			"Pieces = %+v; want 3 pieces, 2 had, 1 unavailable",
+			p,
+		)
+	}
+	if len(d.Peers) != 0 || p.Availability != 0 {
+		t.Errorf(
+			"with no peers: %d peers, availability %v",
+			len(d.Peers),
+			p.Availability,
+		)
+	}
+	for _, f := range d.Files {
+		path, complete, err := tr.FilePath(f.Index)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := filepath.Join(dir, "set", filepath.Join(f.Path...))
+		if path != want || complete != (f.Progress == 100) {
+			t.Errorf(
+				"FilePath(%d) = %q, %v; want %q, %v",
+				f.Index,
+				path,
+				complete,
+				want,
+				f.Progress == 100,
+			)
+		}
+	}
+	if _, _, err := tr.FilePath(len(d.Files)); err == nil {
+		t.Error("FilePath past the last file succeeded")
+	}
+}
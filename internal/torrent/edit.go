@@ -0,0 +1,100 @@
+package torrent
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/prxssh/echo/internal/bencode"
+)
+
+// EditOpts lists changes to a .torrent file. Nil fields are left as they are.
+type EditOpts struct {
+	// Trackers replaces the announce list, one slice per tier. An empty
+	// list removes all trackers.
+	Trackers *[][]string
+	WebSeeds *[]string
+	Comment  *string
+	// Private is the only change that touches the info dict, and so the
+	// only one that gives the torrent a new info-hash.
+	Private *bool
+}
+
+// EditMetainfo applies opts to the .torrent in data and returns the result.
+// Everything not being changed, the info dict in particular, is carried over
+// byte for byte so the info-hash stays the same.
+func EditMetainfo(data []byte, opts EditOpts) ([]byte, error) {
+	var top map[string]bencode.RawMessage
+	if err := bencode.Unmarshal(data, &top); err != nil {
+		return nil, fmt.Errorf("metainfo: %w", err)
+	}
+	if top["info"] == nil {
+		return nil, errors.New("metainfo: missing 'info' dictionary")
+	}
+
+	var err error
+	if opts.Trackers != nil {
+		delete(top, "announce")
+		delete(top, "announce-list")
+
+		var tiers [][]string
+		for _, tier := range *opts.Trackers {
+			if len(tier) > 0 {
+				tiers = append(tiers, tier)
+			}
+		}
+		if len(tiers) > 0 {
+			top["announce"], err = bencode.Marshal(tiers[0][0])
+			if err != nil {
+				return nil, err
+			}
+			top["announce-list"], err = bencode.Marshal(tiers)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	if opts.WebSeeds != nil {
+		delete(top, "url-list")
+		if len(*opts.WebSeeds) > 0 {
+			top["url-list"], err = bencode.Marshal(*opts.WebSeeds)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	if opts.Comment != nil {
+		delete(top, "comment")
+		if *opts.Comment != "" {
+			top["comment"], err = bencode.Marshal(*opts.Comment)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	if opts.Private != nil {
+		top["info"], err = setPrivate(top["info"], *opts.Private)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return bencode.Marshal(top)
+}
+
+// setPrivate rewrites only the 'private' key of the info dict, leaving the
+// encoding of every other key as it was.
+func setPrivate(
+	raw bencode.RawMessage,
+	private bool,
+) (bencode.RawMessage, error) {
+	var info map[string]bencode.RawMessage
+	if err := bencode.Unmarshal(raw, &info); err != nil {
+		return nil, fmt.Errorf("metainfo: info: %w", err)
+	}
+
+	delete(info, "private")
+	if private {
+		info["private"] = bencode.RawMessage("i1e")
+	}
+	return bencode.Marshal(info)
+}
@@ -0,0 +1,67 @@
+package torrent
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestEditMetainfoKeepsInfoHash(t *testing.T) {
+	data, _ := buildMultiFileMeta(t)
+	orig, err := ParseMetainfo(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("ParseMetainfo() error = %v", err)
+	}
+
+	trackers := [][]string{
+		{"http://new/a"},
+		{},
+		{"udp://new/b", "udp://c"},
+	}
+	seeds := []string{"http://seed/"}
+	comment := "edited"
+	edited, err := EditMetainfo(data, EditOpts{
+		Trackers: &trackers,
+		WebSeeds: &seeds,
+		Comment:  &comment,
+	})
+	if err != nil {
+		t.Fatalf("EditMetainfo() error = %v", err)
+	}
+
+	m, err := ParseMetainfo(bytes.NewReader(edited))
+	if err != nil {
+		t.Fatalf("ParseMetainfo(edited) error = %v", err)
+	}
+	if m.Info.Hash != orig.Info.Hash {
+		t.Fatalf("info-hash = %x; want %x", m.Info.Hash, orig.Info.Hash)
+	}
+	want := []string{"http://new/a", "udp://new/b", "udp://c"}
+	if !reflect.DeepEqual(m.AnnounceURLs, want) {
+		t.Fatalf("AnnounceURLs = %v; want %v", m.AnnounceURLs, want)
+	}
+	if !reflect.DeepEqual(m.WebSeeds, seeds) || m.Comment != comment {
+		t.Fatalf("WebSeeds = %v, Comment = %q", m.WebSeeds, m.Comment)
+	}
+}
+
+func TestEditMetainfoPrivate(t *testing.T) {
+	data, _ := buildMultiFileMeta(t)
+	orig, err := ParseMetainfo(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("ParseMetainfo() error = %v", err)
+	}
+
+	public := false
+	edited, err := EditMetainfo(data, EditOpts{Private: &public})
+	if err != nil {
+		t.Fatalf("EditMetainfo() error = %v", err)
+	}
+	m, err := ParseMetainfo(bytes.NewReader(edited))
+	if err != nil {
+		t.Fatalf("ParseMetainfo(edited) error = %v", err)
+	}
+	if m.Info.Private || m.Info.Hash == orig.Info.Hash {
+		t.Fatalf("Private = %v, hash %x", m.Info.Private, m.Info.Hash)
+	}
+}
@@ -0,0 +1,55 @@
+package torrent
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"syscall"
+
+	"github.com/prxssh/echo/internal/tracker"
+)
+
+// ErrFilesMissing is why a torrent with completed pieces fails to start
+// when its files are no longer where they were written.
+var ErrFilesMissing = errors.New("torrent: files missing")
+
+// transientErrnos are the system errors that may clear up by themselves.
+var transientErrnos = []syscall.Errno{
+	syscall.ENOSPC,
+	syscall.EDQUOT,
+	syscall.EIO,
+	syscall.EAGAIN,
+	syscall.EBUSY,
+}
+
+// Transient reports whether err, which put a torrent in StateErrored, may
+// clear up by itself, so that starting the torrent again later is worth a
+// try: a full disk may be freed and a timeout may not recur. Missing files,
+// permission problems and trackers refusing the torrent need the user.
+func Transient(err error) bool {
+	for _, errno := range transientErrnos {
+		if errors.Is(err, errno) {
+			return true
+		}
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// checkFiles fails with ErrFilesMissing if pieces have been completed but
+// a file holding them is gone, rather than quietly downloading it again.
+func (t *Torrent) checkFiles() error {
+	if t.picker.Bitfield().Count() == 0 {
+		return nil
+	}
+	if missing := t.storage.Missing(); len(missing) > 0 {
+		return fmt.Errorf("%w: %s", ErrFilesMissing, missing[0])
+	}
+	return nil
+}
+
+// rejected fails a private torrent once every tracker has refused it,
+// since without them it has no way to find peers.
+func (t *Torrent) rejected(err *tracker.FailureError) {
+	t.fail(fmt.Errorf("torrent: refused by every tracker: %w", err))
+}
@@ -0,0 +1,86 @@
+package torrent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/prxssh/echo/internal/tracker"
+)
+
+func TestTransient(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{
+			name: "disk full",
+			err: &fs.PathError{
+				Op:   "write",
+				Path: "f",
+				Err:  syscall.ENOSPC,
+			},
+			want: true,
+		},
+		{
+			name: "permission denied",
+			err: &fs.PathError{
+				Op:   "open",
+				Path: "f",
+				Err:  syscall.EACCES,
+			},
+		},
+		{
+			name: "files missing",
+			err:  fmt.Errorf("%w: f", ErrFilesMissing),
+		},
+		{
+			name: "tracker refused",
+			err:  &tracker.FailureError{Reason: "unregistered"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Transient(tt.err); got != tt.want {
+				t.Errorf("got %v; want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStartFilesMissing(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "file.bin")
+	if err := os.WriteFile(path, make([]byte, 100), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	data, err := Create(CreateOpts{Path: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr, err := ParseTorrent(data, Opts{DownloadDir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if err := tr.Check(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	tr.Queue(ctx)
+	if err := tr.Start(ctx); !errors.Is(err, ErrFilesMissing) {
+		t.Fatalf("Start() error = %v; want ErrFilesMissing", err)
+	}
+	if s := tr.State(); s != StateErrored {
+		t.Errorf("state = %s; want %s", s, StateErrored)
+	}
+}
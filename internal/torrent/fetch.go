@@ -0,0 +1,188 @@
+package torrent
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"net"
+	"net/netip"
+
+	"github.com/prxssh/echo/internal/dht"
+	"github.com/prxssh/echo/internal/events"
+	"github.com/prxssh/echo/internal/peer"
+	"github.com/prxssh/echo/internal/tracker"
+)
+
+// metadataLeft is reported to trackers while the torrent's size is still
+// unknown. Anything non-zero keeps them from treating us as a seed, which
+// some answer with leechers only.
+const metadataLeft = 1 << 14
+
+const TopicMetadataProgress = events.Topic[MetadataProgress](
+	"metadata:progress",
+)
+
+// MetadataProgress is the payload of "metadata:progress", emitted while a
+// magnet link's info dict is fetched each time one of its pieces arrives.
+type MetadataProgress struct {
+	InfoHash string `json:"infoHash"`
+	Name     string `json:"name"`
+	Received int    `json:"received"`
+	Total    int    `json:"total"`
+}
+
+// FetchMetadata finds peers for the magnet link through its trackers, its
+// x.pe peers and the DHT, downloads the info dict from them (BEP 9) and
+// returns a .torrent built from it.
+func FetchMetadata(
+	ctx context.Context,
+	mag *Magnet,
+	opts Opts,
+) ([]byte, error) {
+	fp := opts.Fingerprint.OrDefault()
+	peerID, err := generatePeerID(fp.PeerIDPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	scope := events.Torrent{
+		InfoHash: hex.EncodeToString(mag.InfoHash[:]),
+		Name:     mag.Name,
+	}
+	ctx = events.WithTorrent(ctx, scope)
+
+	found := make(chan []byte, 1)
+	peerOpts := peer.Opts{
+		InfoHash:   mag.InfoHash,
+		PeerID:     peerID,
+		Cfg:        opts.Peer,
+		Locator:    opts.Locator,
+		Version:    fp.Version,
+		OnMetadata: func(info []byte) { found <- info },
+		OnMetadataProgress: func(received, total int) {
+			TopicMetadataProgress.Emit(ctx, MetadataProgress{
+				InfoHash: scope.InfoHash,
+				Name:     scope.Name,
+				Received: received,
+				Total:    total,
+			})
+		},
+	}
+	if d := opts.DHT; d != nil {
+		peerOpts.DHTPort = d.Port()
+		peerOpts.OnPort = func(addr netip.AddrPort) {
+			d.AddNode(context.Background(), addr)
+		}
+	}
+	peerManager, err := peer.NewManager(peerOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	trackerManager, err := tracker.NewManager(
+		mag.Trackers,
+		tracker.Opts{
+			InfoHash:  mag.InfoHash,
+			PeerID:    peerID,
+			Port:      opts.ListenPort,
+			Left:      metadataLeft,
+			Proxy:     opts.Proxy,
+			UserAgent: fp.UserAgent,
+			DialUDP:   opts.dialUDP(),
+			OnPeers: func(peers []*tracker.Peer) {
+				peerManager.Enqueue(peer.SourceTracker, peers)
+			},
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	fetchCtx, cancel := context.WithCancel(ctx)
+	go trackerManager.Start(fetchCtx)
+	go peerManager.Start(fetchCtx)
+	if peers := directPeers(mag.Peers); len(peers) > 0 {
+		peerManager.Enqueue(peer.SourceTracker, peers)
+	}
+	if opts.DHT != nil {
+		go func() {
+			hashes := [][sha1.Size]byte{mag.InfoHash}
+			// Inbound peers are not routed to a fetch, so it is
+			// not announced.
+			peers := dhtPeers(fetchCtx, opts.DHT, hashes, 0)
+			peerManager.Enqueue(peer.SourceDHT, peers)
+		}()
+	}
+
+	var info []byte
+	select {
+	case info = <-found:
+	case <-ctx.Done():
+	}
+	cancel()
+	trackerManager.Stop(ctx)
+	peerManager.Stop(ctx)
+
+	if info == nil {
+		return nil, ctx.Err()
+	}
+	return BuildTorrent(info, mag)
+}
+
+// BuildTorrent wraps an info dict fetched for mag in a .torrent that
+// announces to the magnet's trackers and web seeds.
+func BuildTorrent(info []byte, mag *Magnet) ([]byte, error) {
+	if sha1.Sum(info) != mag.InfoHash {
+		return nil, errors.New("metainfo: info dict does not match")
+	}
+
+	data := append([]byte("d4:info"), info...)
+	data = append(data, 'e')
+
+	tiers := make([][]string, 0, len(mag.Trackers))
+	for _, tr := range mag.Trackers {
+		tiers = append(tiers, []string{tr})
+	}
+	return EditMetainfo(data, EditOpts{
+		Trackers: &tiers,
+		WebSeeds: &mag.WebSeeds,
+	})
+}
+
+// directPeers turns the magnet's x.pe addresses into dial candidates. Only
+// literal IPs are accepted; names would need resolving on every dial.
+func directPeers(addrs []string) []*tracker.Peer {
+	var peers []*tracker.Peer
+	for _, a := range addrs {
+		ap, err := netip.ParseAddrPort(a)
+		if err != nil {
+			continue
+		}
+		peers = append(peers, &tracker.Peer{
+			IP:   net.IP(ap.Addr().AsSlice()),
+			Port: ap.Port(),
+		})
+	}
+	return peers
+}
+
+// dhtPeers looks each hash up in the DHT, announcing us under it on port
+// unless it is zero.
+func dhtPeers(
+	ctx context.Context,
+	d *dht.DHT,
+	hashes [][sha1.Size]byte,
+	port uint16,
+) []*tracker.Peer {
+	var peers []*tracker.Peer
+	for _, hash := range hashes {
+		for _, addr := range d.GetPeers(ctx, hash, port) {
+			peers = append(peers, &tracker.Peer{
+				IP:   net.IP(addr.Addr().AsSlice()),
+				Port: addr.Port(),
+			})
+		}
+	}
+	return peers
+}
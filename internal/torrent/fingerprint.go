@@ -0,0 +1,115 @@
+package torrent
+
+import (
+	"cmp"
+	"crypto/rand"
+	"crypto/sha1"
+	"errors"
+	"strings"
+)
+
+// maxPeerIDPrefix leaves a peer ID enough random bytes to be unique.
+const maxPeerIDPrefix = 12
+
+// Fingerprint is how the client introduces itself to peers and trackers.
+// Empty fields are taken from DefaultFingerprint.
+type Fingerprint struct {
+	// PeerIDPrefix starts every peer ID, as "-EC0001-" does; the rest
+	// is random.
+	PeerIDPrefix string `json:"peerIdPrefix"`
+	// Version is the client name and version sent in the extension
+	// handshake.
+	Version string `json:"version"`
+	// UserAgent is sent with HTTP tracker announces and scrapes.
+	UserAgent string `json:"userAgent"`
+}
+
+var DefaultFingerprint = Fingerprint{
+	PeerIDPrefix: "-EC0001-",
+	Version:      "Echo 0.1",
+	UserAgent:    "Echo/0.1",
+}
+
+// FingerprintPreset is a known client's fingerprint, for masquerading as
+// it on trackers that only let whitelisted clients in.
+type FingerprintPreset struct {
+	Name        string      `json:"name"`
+	Fingerprint Fingerprint `json:"fingerprint"`
+}
+
+// FingerprintPresets lists the clients Fingerprint can masquerade as,
+// Echo first.
+var FingerprintPresets = []FingerprintPreset{
+	{"Echo", DefaultFingerprint},
+	{"qBittorrent 4.6.7", Fingerprint{
+		PeerIDPrefix: "-qB4670-",
+		Version:      "qBittorrent/4.6.7",
+		UserAgent:    "qBittorrent/4.6.7",
+	}},
+	{"Transmission 4.0.6", Fingerprint{
+		PeerIDPrefix: "-TR4060-",
+		Version:      "Transmission 4.0.6",
+		UserAgent:    "Transmission/4.0.6",
+	}},
+	{"Deluge 2.1.1", Fingerprint{
+		PeerIDPrefix: "-DE211s-",
+		Version:      "Deluge 2.1.1",
+		UserAgent:    "Deluge 2.1.1",
+	}},
+	{"µTorrent 3.5.5", Fingerprint{
+		PeerIDPrefix: "-UT355W-",
+		Version:      "µTorrent 3.5.5",
+		UserAgent:    "uTorrent/355(46090)",
+	}},
+	{"rTorrent 0.9.8", Fingerprint{
+		PeerIDPrefix: "-lt0D80-",
+		Version:      "rTorrent 0.9.8",
+		UserAgent:    "rtorrent/0.9.8/0.13.8",
+	}},
+}
+
+// LookupFingerprint returns the fingerprint of the preset named, ignoring
+// case. The client's name alone, as "qbittorrent", names its preset too.
+func LookupFingerprint(name string) (Fingerprint, bool) {
+	for _, p := range FingerprintPresets {
+		client, _, _ := strings.Cut(p.Name, " ")
+		if strings.EqualFold(p.Name, name) ||
+			strings.EqualFold(client, name) {
+			return p.Fingerprint, true
+		}
+	}
+	return Fingerprint{}, false
+}
+
+// OrDefault fills f's empty fields from DefaultFingerprint.
+func (f Fingerprint) OrDefault() Fingerprint {
+	d := DefaultFingerprint
+	return Fingerprint{
+		PeerIDPrefix: cmp.Or(f.PeerIDPrefix, d.PeerIDPrefix),
+		Version:      cmp.Or(f.Version, d.Version),
+		UserAgent:    cmp.Or(f.UserAgent, d.UserAgent),
+	}
+}
+
+func (f Fingerprint) Validate() error {
+	if len(f.PeerIDPrefix) > maxPeerIDPrefix {
+		return errors.New("peer ID prefix is longer than 12 bytes")
+	}
+	for _, s := range []string{f.PeerIDPrefix, f.Version, f.UserAgent} {
+		if strings.ContainsAny(s, "\r\n\x00") {
+			return errors.New("fingerprint has control characters")
+		}
+	}
+	return nil
+}
+
+func generatePeerID(prefix string) ([sha1.Size]byte, error) {
+	var peerID [sha1.Size]byte
+
+	n := copy(peerID[:], prefix)
+	if _, err := rand.Read(peerID[n:]); err != nil {
+		return [sha1.Size]byte{}, err
+	}
+
+	return peerID, nil
+}
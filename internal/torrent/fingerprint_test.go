@@ -0,0 +1,59 @@
+package torrent
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGeneratePeerID(t *testing.T) {
+	a, err := generatePeerID("-qB4670-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := generatePeerID("-qB4670-")
+	if !strings.HasPrefix(string(a[:]), "-qB4670-") {
+		t.Errorf("peer id = %q", a)
+	}
+	if a == b {
+		t.Error("peer ids repeat")
+	}
+}
+
+func TestLookupFingerprint(t *testing.T) {
+	for _, name := range []string{"qBittorrent 4.6.7", "qbittorrent"} {
+		fp, ok := LookupFingerprint(name)
+		if !ok || fp.PeerIDPrefix != "-qB4670-" {
+			t.Errorf("%q = %+v, %v", name, fp, ok)
+		}
+	}
+	if _, ok := LookupFingerprint("azureus"); ok {
+		t.Error("unknown client found")
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	fp := Fingerprint{UserAgent: "Custom/1.0"}.OrDefault()
+	want := Fingerprint{
+		PeerIDPrefix: DefaultFingerprint.PeerIDPrefix,
+		Version:      DefaultFingerprint.Version,
+		UserAgent:    "Custom/1.0",
+	}
+	if fp != want {
+		t.Errorf("fingerprint = %+v, want %+v", fp, want)
+	}
+
+	for _, p := range FingerprintPresets {
+		if err := p.Fingerprint.Validate(); err != nil {
+			t.Errorf("%s: %v", p.Name, err)
+		}
+	}
+	bad := []Fingerprint{
+		{PeerIDPrefix: "-XX0000-toolong-"},
+		{UserAgent: "Echo\r\nX-Injected: 1"},
+	}
+	for _, fp := range bad {
+		if fp.Validate() == nil {
+			t.Errorf("%+v validated", fp)
+		}
+	}
+}
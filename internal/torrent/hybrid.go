@@ -0,0 +1,315 @@
+package torrent
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"math/bits"
+	"slices"
+	"sort"
+)
+
+// merkleBlockSize is the size of the leaves of a BEP 52 file hash tree.
+const merkleBlockSize = 16 << 10
+
+// v2Info holds the v2 half of a hybrid torrent: every non-padding file with
+// its offset in the v1 piece stream and the hashes it verifies against.
+type v2Info struct {
+	files  []v2File
+	layers map[[sha256.Size]byte][][sha256.Size]byte
+}
+
+type v2File struct {
+	path   []string
+	length uint64
+	root   [sha256.Size]byte
+	offset uint64
+}
+
+// Hybrid reports whether the torrent carries both v1 and v2 metadata.
+func (i *Info) Hybrid() bool {
+	return i.v2 != nil
+}
+
+// InfoHashes returns every hash the torrent is known by on trackers and the
+// DHT: the v1 hash and, for hybrid torrents, the truncated v2 hash.
+func (i *Info) InfoHashes() [][sha1.Size]byte {
+	hashes := [][sha1.Size]byte{i.Hash}
+	if i.Hybrid() {
+		hashes = append(hashes, [sha1.Size]byte(i.HashV2[:sha1.Size]))
+	}
+	return hashes
+}
+
+// VerifyPiece checks data against the v1 hash of piece index and, for hybrid
+// torrents, against the v2 hash of the file region it covers. Pieces of a
+// hybrid torrent never span files: padding aligns each file to a piece
+// boundary, so the tail of the data past the file's end is padding.
+func (i *Info) VerifyPiece(index int, data []byte) bool {
+	if index < 0 || index >= i.NumPieces {
+		return false
+	}
+	if sha1.Sum(data) != i.PieceHash(index) {
+		return false
+	}
+	if !i.Hybrid() {
+		return true
+	}
+
+	start := uint64(index) * i.PieceLength
+	fi := sort.Search(len(i.v2.files), func(n int) bool {
+		f := i.v2.files[n]
+		return f.offset+f.length > start
+	})
+	if fi == len(i.v2.files) || i.v2.files[fi].offset > start {
+		return true // all padding, covered by the v1 hash
+	}
+
+	f := i.v2.files[fi]
+	n := min(uint64(len(data)), f.offset+f.length-start)
+	if f.length <= i.PieceLength {
+		return merkleRoot(data[:n], 0) == f.root
+	}
+
+	layer := i.v2.layers[f.root]
+	piece := (start - f.offset) / i.PieceLength
+	leaves := int(i.PieceLength / merkleBlockSize)
+	return merkleRoot(data[:n], leaves) == layer[piece]
+}
+
+// parseV2 reads the BEP 52 file tree and piece layers of a hybrid torrent and
+// checks that both describe the same data: identical files in the same
+// order, padding that aligns every file to a piece boundary, and piece
+// layers that hash up to each file's root.
+func (p *parser) parseV2(raw map[string]any, info *Info, size uint64) error {
+	pl := info.PieceLength
+	if pl < merkleBlockSize || pl&(pl-1) != 0 {
+		return errors.New(
+			"metainfo: invalid v2 piece length",
+		)
+	}
+
+	tree, ok := raw["file tree"].(map[string]any)
+	if !ok {
+		return errors.New("metainfo: missing or invalid 'file tree'")
+	}
+	files, err := walkFileTree(tree, nil)
+	if err != nil {
+		return err
+	}
+	if err := alignV1Files(info, files, size); err != nil {
+		return err
+	}
+
+	layers, err := p.parsePieceLayers(files, pl)
+	if err != nil {
+		return err
+	}
+
+	info.MetaVersion = 2
+	info.HashV2 = sha256.Sum256(p.rawInfo)
+	info.v2 = &v2Info{files: files, layers: layers}
+	return nil
+}
+
+// walkFileTree flattens a file tree into its files in key order, which is the
+// order the v1 file list of a hybrid torrent must follow.
+func walkFileTree(tree map[string]any, dir []string) ([]v2File, error) {
+	names := make([]string, 0, len(tree))
+	for name := range tree {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	var files []v2File
+	for _, name := range names {
+		node, ok := tree[name].(map[string]any)
+		if !ok || name == "" {
+			return nil, fmt.Errorf(
+				"metainfo: invalid file tree entry %q",
+				name,
+			)
+		}
+		path := append(slices.Clone(dir), name)
+
+		leaf, ok := node[""].(map[string]any)
+		if !ok {
+			sub, err := walkFileTree(node, path)
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, sub...)
+			continue
+		}
+
+		length, ok := intFrom(leaf, "length")
+		if !ok || length < 0 {
+			return nil, fmt.Errorf(
+				"metainfo: invalid length for file %q",
+				path,
+			)
+		}
+		f := v2File{path: path, length: uint64(length)}
+		if length > 0 {
+			root, _ := stringFrom(leaf, "pieces root")
+			if len(root) != sha256.Size {
+				return nil, fmt.Errorf(
+					"metainfo: invalid pieces root for %q",
+					path,
+				)
+			}
+			copy(f.root[:], root)
+		}
+		files = append(files, f)
+	}
+
+	return files, nil
+}
+
+// alignV1Files matches the v1 file list against the v2 files, recording where
+// each v2 file starts in the v1 piece stream.
+func alignV1Files(info *Info, files []v2File, size uint64) error {
+	v1 := []File{{Length: size, Path: []string{info.Name}}}
+	if info.Files != nil {
+		v1 = *info.Files
+	}
+
+	var offset uint64
+	next := 0
+	for _, f := range v1 {
+		if f.Padding {
+			offset += f.Length
+			continue
+		}
+		if next == len(files) {
+			return errors.New(
+				"metainfo: v1 file list longer than v2 tree",
+			)
+		}
+
+		v2 := &files[next]
+		if f.Length != v2.length || !slices.Equal(f.Path, v2.path) {
+			return fmt.Errorf(
+				"metainfo: v1 file %q does not match %q",
+				f.Path,
+				v2.path,
+			)
+		}
+		if offset%info.PieceLength != 0 {
+			return fmt.Errorf(
+				"metainfo: file %q is not piece aligned",
+				f.Path,
+			)
+		}
+
+		v2.offset = offset
+		offset += f.Length
+		next++
+	}
+	if next != len(files) {
+		return errors.New(
+			"metainfo: v2 tree has files not in v1 list",
+		)
+	}
+
+	want := (size + info.PieceLength - 1) / info.PieceLength
+	if uint64(info.NumPieces) != want {
+		return fmt.Errorf(
+			"metainfo: %d v1 piece hashes for %d pieces",
+			info.NumPieces,
+			want,
+		)
+	}
+	return nil
+}
+
+// parsePieceLayers checks the piece layer of every file longer than a piece
+// against its root. Smaller files are verified against the root directly.
+func (p *parser) parsePieceLayers(
+	files []v2File,
+	pieceLength uint64,
+) (map[[sha256.Size]byte][][sha256.Size]byte, error) {
+	raw, _ := p.data["piece layers"].(map[string]any)
+	layers := make(map[[sha256.Size]byte][][sha256.Size]byte)
+
+	for _, f := range files {
+		if f.length <= pieceLength {
+			continue
+		}
+
+		s, ok := raw[string(f.root[:])].(string)
+		pieces := (f.length + pieceLength - 1) / pieceLength
+		if !ok || uint64(len(s)) != pieces*sha256.Size {
+			return nil, fmt.Errorf(
+				"metainfo: invalid piece layer for %q",
+				f.path,
+			)
+		}
+
+		layer := make([][sha256.Size]byte, pieces)
+		for i := range layer {
+			copy(layer[i][:], s[i*sha256.Size:])
+		}
+		if layerRoot(layer, pieceLength) != f.root {
+			return nil, fmt.Errorf(
+				"metainfo: piece layer for %q mismatches root",
+				f.path,
+			)
+		}
+		layers[f.root] = layer
+	}
+
+	return layers, nil
+}
+
+// merkleRoot hashes data in 16 KiB blocks and returns the root of the tree
+// over them. The leaves are padded with zero hashes up to leaves, or to the
+// next power of two when leaves is 0.
+func merkleRoot(data []byte, leaves int) [sha256.Size]byte {
+	n := max((len(data)+merkleBlockSize-1)/merkleBlockSize, 1)
+	if leaves == 0 {
+		leaves = 1 << bits.Len(uint(n-1))
+	}
+
+	hashes := make([][sha256.Size]byte, leaves)
+	for i := 0; i*merkleBlockSize < len(data); i++ {
+		end := min((i+1)*merkleBlockSize, len(data))
+		hashes[i] = sha256.Sum256(data[i*merkleBlockSize : end])
+	}
+	return reduceHashes(hashes)
+}
+
+// layerRoot returns the root over a file's piece layer, padded to a power of
+// two with the root of a piece made entirely of zero leaves.
+func layerRoot(
+	layer [][sha256.Size]byte,
+	pieceLength uint64,
+) [sha256.Size]byte {
+	var pad [sha256.Size]byte
+	for n := pieceLength / merkleBlockSize; n > 1; n /= 2 {
+		pad = hashPair(pad, pad)
+	}
+
+	hashes := make([][sha256.Size]byte, 1<<bits.Len(uint(len(layer)-1)))
+	copy(hashes, layer)
+	for i := len(layer); i < len(hashes); i++ {
+		hashes[i] = pad
+	}
+	return reduceHashes(hashes)
+}
+
+// reduceHashes folds a power-of-two number of hashes into their root.
+func reduceHashes(hashes [][sha256.Size]byte) [sha256.Size]byte {
+	for len(hashes) > 1 {
+		for i := 0; i < len(hashes)/2; i++ {
+			hashes[i] = hashPair(hashes[2*i], hashes[2*i+1])
+		}
+		hashes = hashes[:len(hashes)/2]
+	}
+	return hashes[0]
+}
+
+func hashPair(a, b [sha256.Size]byte) [sha256.Size]byte {
+	return sha256.Sum256(append(a[:], b[:]...))
+}
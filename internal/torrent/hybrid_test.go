@@ -0,0 +1,160 @@
+package torrent
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"crypto/sha256"
+	"strings"
+	"testing"
+
+	"github.com/prxssh/echo/internal/bencode"
+)
+
+// buildHybridMeta returns a hybrid torrent with a 32 KiB piece length, a
+// two-piece file padded to a piece boundary and a one-piece file, along with
+// the v1 pieces.
+func buildHybridMeta(
+	t *testing.T,
+	mutate func(info, top map[string]any),
+) ([]byte, [][]byte) {
+	t.Helper()
+
+	const pl = 32 << 10
+	a := bytes.Repeat([]byte{'a'}, 40000)
+	b := bytes.Repeat([]byte{'b'}, 1000)
+	pad := make([]byte, 2*pl-len(a))
+
+	stream := append(append(append([]byte{}, a...), pad...), b...)
+	pieces := [][]byte{stream[:pl], stream[pl : 2*pl], stream[2*pl:]}
+	var v1 []byte
+	for _, p := range pieces {
+		sum := sha1.Sum(p)
+		v1 = append(v1, sum[:]...)
+	}
+
+	var zero [32]byte
+	h := func(b ...[]byte) []byte {
+		sum := sha256.Sum256(bytes.Join(b, nil))
+		return sum[:]
+	}
+	p0 := h(h(a[:16384]), h(a[16384:32768]))
+	p1 := h(h(a[32768:]), zero[:])
+	rootA := h(p0, p1)
+	rootB := h(b)
+
+	info := map[string]any{
+		"name":         "dir",
+		"piece length": int64(pl),
+		"pieces":       string(v1),
+		"meta version": int64(2),
+		"files": []any{
+			map[string]any{
+				"length": int64(len(a)),
+				"path":   []any{"a"},
+			},
+			map[string]any{
+				"length": int64(len(pad)),
+				"path":   []any{".pad", "25536"},
+				"attr":   "p",
+			},
+			map[string]any{
+				"length": int64(len(b)),
+				"path":   []any{"b"},
+			},
+		},
+		"file tree": map[string]any{
+			"a": map[string]any{"": map[string]any{
+				"length":      int64(len(a)),
+				"pieces root": string(rootA),
+			}},
+			"b": map[string]any{"": map[string]any{
+				"length":      int64(len(b)),
+				"pieces root": string(rootB),
+			}},
+		},
+	}
+	top := map[string]any{
+		"info": info,
+		"piece layers": map[string]any{
+			string(rootA): string(p0) + string(p1),
+		},
+	}
+	if mutate != nil {
+		mutate(info, top)
+	}
+
+	data, err := bencode.Marshal(top)
+	if err != nil {
+		t.Fatalf("failed to encode metainfo: %v", err)
+	}
+	return data, pieces
+}
+
+func TestHybridTorrent(t *testing.T) {
+	data, pieces := buildHybridMeta(t, nil)
+
+	m, err := ParseMetainfo(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("ParseMetainfo() error = %v", err)
+	}
+	if !m.Info.Hybrid() || m.Info.MetaVersion != 2 {
+		t.Fatalf("Hybrid() = false; want true")
+	}
+
+	infoStart := bytes.Index(data, []byte("4:infod")) + len("4:info")
+	infoEnd := bytes.Index(data, []byte("12:piece layers"))
+	want := sha256.Sum256(data[infoStart:infoEnd])
+	if m.Info.HashV2 != want {
+		t.Fatalf("HashV2 = %x; want %x", m.Info.HashV2, want)
+	}
+
+	hashes := m.Info.InfoHashes()
+	if len(hashes) != 2 || !bytes.Equal(hashes[1][:], want[:20]) {
+		t.Fatalf("InfoHashes() = %x", hashes)
+	}
+
+	for i, p := range pieces {
+		if !m.Info.VerifyPiece(i, p) {
+			t.Errorf("VerifyPiece(%d) = false; want true", i)
+		}
+		bad := bytes.Clone(p)
+		bad[0] ^= 0xff
+		if m.Info.VerifyPiece(i, bad) {
+			t.Errorf("VerifyPiece(%d, corrupt) = true", i)
+		}
+	}
+}
+
+func TestHybridTorrentErrors(t *testing.T) {
+	cases := map[string]func(info, top map[string]any){
+		"bad piece layer": func(info, top map[string]any) {
+			layers := top["piece layers"].(map[string]any)
+			for k := range layers {
+				layers[k] = strings.Repeat("x", 64)
+			}
+		},
+		"missing pad file": func(info, top map[string]any) {
+			files := info["files"].([]any)
+			info["files"] = []any{files[0], files[2]}
+		},
+		"file mismatch": func(info, top map[string]any) {
+			tree := info["file tree"].(map[string]any)
+			tree["c"] = tree["b"]
+			delete(tree, "b")
+		},
+		"v2 only": func(info, top map[string]any) {
+			delete(info, "pieces")
+			delete(info, "files")
+		},
+	}
+
+	for name, mutate := range cases {
+		t.Run(name, func(t *testing.T) {
+			data, _ := buildHybridMeta(t, mutate)
+			_, err := ParseMetainfo(bytes.NewReader(data))
+			if err == nil {
+				t.Fatal("ParseMetainfo() error = nil")
+			}
+		})
+	}
+}
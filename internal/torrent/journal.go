@@ -0,0 +1,156 @@
+package torrent
+
+import (
+	"encoding/hex"
+	"path/filepath"
+
+	"github.com/prxssh/echo/internal/bitfield"
+	"github.com/prxssh/echo/internal/piece"
+	"github.com/prxssh/echo/internal/storage"
+)
+
+// OpenJournal keeps a write-ahead journal of piece writes in dir, so that
+// after a crash only the pieces it names need checking, and notes those a
+// previous run left records for. The journal is named by info-hash and
+// shared by any copy of the torrent, so it must only be opened once the
+// torrent is known not to be one. It must be called before RestoreBlocks
+// and RestorePieces.
+func (t *Torrent) OpenJournal(dir string) error {
+	name := hex.EncodeToString(t.Metainfo.Info.Hash[:]) + ".journal"
+	j, records, err := storage.OpenJournal(filepath.Join(dir, name))
+	if err != nil {
+		return err
+	}
+
+	t.journal = j
+	t.dirty = bitfield.New(t.Metainfo.Info.NumPieces)
+	for _, r := range records {
+		if int(r.Piece) < t.Metainfo.Info.NumPieces {
+			t.dirty.Set(int(r.Piece))
+		}
+	}
+	return nil
+}
+
+// write journals a block write, when there is a journal, and applies it.
+// It reports whether piece index has every block now.
+func (t *Torrent) write(index int, off int64, data []byte) (bool, error) {
+	t.ioMu.Lock()
+	defer t.ioMu.Unlock()
+
+	if t.journal != nil {
+		err := t.journal.Append(storage.Record{
+			Piece:  uint32(index),
+			Offset: off,
+			Length: uint32(len(data)),
+		})
+		if err != nil {
+			return false, err
+		}
+	}
+	if _, err := t.storage.WriteAt(data, off); err != nil {
+		return false, err
+	}
+	begin := off - int64(index)*int64(t.Metainfo.Info.PieceLength)
+	complete := false
+	for n := int64(0); n < int64(len(data)); n += piece.BlockSize {
+		complete = t.blocks.Receive(index, begin+n)
+	}
+	return complete, nil
+}
+
+// Checkpoint flushes the torrent's files and, with them safely on disk,
+// empties its journal.
+func (t *Torrent) Checkpoint() error {
+	t.ioMu.Lock()
+	defer t.ioMu.Unlock()
+
+	if err := t.storage.Sync(); err != nil {
+		return err
+	}
+	if t.journal != nil {
+		return t.journal.Reset()
+	}
+	return nil
+}
+
+// Have returns the pieces that are complete, to be saved and handed back
+// to RestorePieces on the next run.
+func (t *Torrent) Have() bitfield.Bitfield {
+	return t.picker.Bitfield()
+}
+
+// BlockState encodes the blocks the half-complete pieces have, to be saved
+// and handed back to RestoreBlocks on the next run; nil if there are none.
+func (t *Torrent) BlockState() []byte {
+	if len(t.blocks.Partial()) == 0 {
+		return nil
+	}
+	data, _ := t.blocks.MarshalBinary()
+	return data
+}
+
+// RestoreBlocks takes back the blocks of the half-complete pieces saved on
+// the last run, but for the pieces the journal shows were being written as
+// it ended, whose blocks may not have reached the disk. It must be called
+// before RestorePieces.
+func (t *Torrent) RestoreBlocks(data []byte) error {
+	if err := t.blocks.UnmarshalBinary(data); err != nil {
+		return err
+	}
+	for i := range t.dirty.Indices() {
+		t.blocks.Reset(i)
+	}
+	return nil
+}
+
+// RestorePieces marks the pieces in have as complete without reading them,
+// except for those the journal shows were being written when the last run
+// ended: only those are read back and verified, so an unclean shutdown
+// costs a check of a few pieces rather than the whole torrent. Pieces that
+// fail the check are downloaded again. It must be called before the
+// torrent starts.
+func (t *Torrent) RestorePieces(have bitfield.Bitfield) error {
+	info := t.Metainfo.Info
+	for i := range info.NumPieces {
+		if t.dirty.Has(i) || !have.Has(i) {
+			continue
+		}
+		t.picker.Done(i)
+	}
+
+	buf := make([]byte, info.PieceLength)
+	for i := range info.NumPieces {
+		if !t.dirty.Has(i) {
+			continue
+		}
+		data := buf[:t.Metainfo.PieceSize(i)]
+		off := int64(i) * int64(info.PieceLength)
+		_, err := t.storage.ReadAt(data, off)
+		if err == nil && info.VerifyPiece(i, data) {
+			t.picker.Done(i)
+		}
+	}
+	t.dirty = bitfield.Bitfield{}
+	t.recount()
+
+	return t.Checkpoint()
+}
+
+// recount works out how much is left from the pieces the picker has.
+func (t *Torrent) recount() {
+	var left uint64
+	for i := range t.Metainfo.Info.NumPieces {
+		if !t.picker.Have(i) {
+			left += t.Metainfo.PieceSize(i)
+		}
+	}
+	t.updatePartialSeed()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.Left = left
+	uploaded := t.Uploaded + t.PeerManager.Uploaded()
+	t.TrackerManager.UpdateStats(uploaded, t.Downloaded, t.Left)
+}
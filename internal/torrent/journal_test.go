@@ -0,0 +1,130 @@
+package torrent
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/prxssh/echo/internal/bitfield"
+)
+
+func TestRestorePieces(t *testing.T) {
+	dir := t.TempDir()
+	journals := filepath.Join(dir, "journal")
+	path := filepath.Join(dir, "file.bin")
+	content := make([]byte, 3*minPieceLength+100)
+	for i := range content {
+		content[i] = byte(i)
+	}
+	if err := os.WriteFile(path, content, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	data, err := Create(CreateOpts{Path: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := Opts{DownloadDir: dir}
+	open := func() *Torrent {
+		t.Helper()
+		tr, err := ParseTorrent(data, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := tr.OpenJournal(journals); err != nil {
+			t.Fatal(err)
+		}
+		return tr
+	}
+
+	// A write to the second piece is journaled but the process dies
+	// before it is checkpointed, leaving the piece torn.
+	tr := open()
+	torn := make([]byte, minPieceLength)
+	if _, err := tr.write(1, minPieceLength, torn); err != nil {
+		t.Fatal(err)
+	}
+
+	tr = open()
+	have := bitfield.New(4)
+	for i := range 4 {
+		have.Set(i)
+	}
+	if err := tr.RestorePieces(have); err != nil {
+		t.Fatalf("RestorePieces() error = %v", err)
+	}
+
+	for i, want := range []bool{true, false, true, true} {
+		if got := tr.picker.Have(i); got != want {
+			t.Errorf("piece %d: have = %v; want %v", i, got, want)
+		}
+	}
+	if _, _, left := tr.Totals(); left != minPieceLength {
+		t.Errorf("left = %d; want %d", left, minPieceLength)
+	}
+
+	// The checkpoint at the end of RestorePieces empties the journal.
+	tr = open()
+	if n := tr.dirty.Count(); n != 0 {
+		t.Errorf("dirty pieces after checkpoint = %d; want 0", n)
+	}
+}
+
+func TestReceiveBlock(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "file.bin")
+	content := make([]byte, 2*2*minPieceLength+100)
+	for i := range content {
+		content[i] = byte(i * 7)
+	}
+	if err := os.WriteFile(path, content, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	data, err := Create(CreateOpts{
+		Path:        path,
+		PieceLength: 2 * minPieceLength,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr, err := ParseTorrent(data, Opts{
+		DownloadDir: filepath.Join(dir, "download"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tr.OpenJournal(filepath.Join(dir, "journal")); err != nil {
+		t.Fatal(err)
+	}
+	defer tr.Stop(t.Context())
+
+	// Piece 0 arrives whole, one block at a time; piece 1 with a spoiled
+	// block, which fails the check and leaves it to be downloaded again.
+	tr.receiveBlock(0, 0, content[:minPieceLength])
+	if tr.picker.Have(0) {
+		t.Fatal("piece 0 done with a block missing")
+	}
+	block := content[minPieceLength : 2*minPieceLength]
+	tr.receiveBlock(0, minPieceLength, block)
+	if !tr.picker.Have(0) {
+		t.Error("piece 0 not done with every block written")
+	}
+
+	off := 2 * minPieceLength
+	tr.receiveBlock(1, 0, content[off:off+minPieceLength])
+	tr.receiveBlock(1, minPieceLength, make([]byte, minPieceLength))
+	if tr.picker.Have(1) {
+		t.Error("piece 1 done though it fails its hash check")
+	}
+	if len(tr.blocks.Partial()) != 0 {
+		t.Error("blocks of the failed piece were kept")
+	}
+
+	_, downloaded, left := tr.Totals()
+	if want := uint64(4 * minPieceLength); downloaded != want {
+		t.Errorf("downloaded = %d; want %d", downloaded, want)
+	}
+	want := uint64(len(content) - 2*minPieceLength)
+	if left != want {
+		t.Errorf("left = %d; want %d", left, want)
+	}
+}
@@ -0,0 +1,55 @@
+package torrent
+
+import (
+	"errors"
+	"fmt"
+)
+
+var errSizeOverflow = errors.New("metainfo: total file size overflows")
+
+// LayoutError reports an info dict whose piece hashes do not cover its
+// files: Size bytes in pieces of PieceLength need WantPieces hashes, but the
+// torrent has Pieces.
+type LayoutError struct {
+	Pieces      int
+	WantPieces  uint64
+	PieceLength uint64
+	Size        uint64
+}
+
+func (e *LayoutError) Error() string {
+	return fmt.Sprintf(
+		"metainfo: %d piece hashes, want %d for %d bytes of %d",
+		e.Pieces,
+		e.WantPieces,
+		e.Size,
+		e.PieceLength,
+	)
+}
+
+// checkLayout makes sure the files exactly fill the piece space: every
+// piece but the last is full, and the last holds at least one byte.
+func checkLayout(info *Info, size uint64) error {
+	want := (size + info.PieceLength - 1) / info.PieceLength
+	if uint64(info.NumPieces) != want {
+		return &LayoutError{
+			Pieces:      info.NumPieces,
+			WantPieces:  want,
+			PieceLength: info.PieceLength,
+			Size:        size,
+		}
+	}
+	return nil
+}
+
+// PieceSize returns the length of piece index; only the last piece may be
+// shorter than PieceLength.
+func (m *Metainfo) PieceSize(index int) uint64 {
+	if index < 0 || index >= m.Info.NumPieces {
+		return 0
+	}
+	if index < m.Info.NumPieces-1 {
+		return m.Info.PieceLength
+	}
+	return m.Size - uint64(index)*m.Info.PieceLength
+}
@@ -0,0 +1,74 @@
+package torrent
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/prxssh/echo/internal/bencode"
+)
+
+func TestLayoutErrors(t *testing.T) {
+	parse := func(info map[string]any) error {
+		data, err := bencode.Marshal(map[string]any{"info": info})
+		if err != nil {
+			t.Fatalf("encode: %v", err)
+		}
+		_, err = ParseMetainfo(bytes.NewReader(data))
+		return err
+	}
+
+	cases := map[string]struct {
+		length int64
+		pieces int
+		want   uint64
+	}{
+		"too few":  {length: 33, pieces: 2, want: 3},
+		"too many": {length: 32, pieces: 3, want: 2},
+		"empty":    {length: 0, pieces: 1, want: 0},
+	}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			pieces := make([]byte, 20*c.pieces)
+			err := parse(map[string]any{
+				"name":         "x",
+				"piece length": int64(16),
+				"pieces":       string(pieces),
+				"length":       c.length,
+			})
+			var le *LayoutError
+			if !errors.As(err, &le) {
+				t.Fatalf("error = %v; want *LayoutError", err)
+			}
+			if le.Pieces != c.pieces || le.WantPieces != c.want {
+				t.Fatalf("LayoutError = %+v", le)
+			}
+		})
+	}
+
+	err := parse(map[string]any{
+		"name":         "x",
+		"piece length": int64(16),
+		"pieces":       string(make([]byte, 20)),
+		"files": []any{
+			map[string]any{
+				"length": int64(math.MaxInt64),
+				"path":   []any{"a"},
+			},
+			map[string]any{"length": int64(1), "path": []any{"b"}},
+		},
+	})
+	if !errors.Is(err, errSizeOverflow) {
+		t.Fatalf("error = %v; want %v", err, errSizeOverflow)
+	}
+}
+
+func TestPieceSize(t *testing.T) {
+	m := &Metainfo{Size: 40, Info: &Info{PieceLength: 16, NumPieces: 3}}
+	for i, want := range []uint64{16, 16, 8, 0} {
+		if got := m.PieceSize(i); got != want {
+			t.Errorf("PieceSize(%d) = %d; want %d", i, got, want)
+		}
+	}
+}
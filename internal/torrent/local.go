@@ -0,0 +1,42 @@
+package torrent
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// RootPath returns where the torrent lives on disk: its directory for a
+// multi-file torrent, its only file otherwise.
+func (t *Torrent) RootPath() string {
+	return filepath.Join(t.DownloadDir(), t.Metainfo.Info.Name)
+}
+
+// FilePath returns where the file at index in the info dict's file list is
+// stored, and whether every piece of it is downloaded and verified.
+func (t *Torrent) FilePath(index int) (string, bool, error) {
+	files := storageFiles(t.Metainfo)
+	if index < 0 || index >= len(files) || files[index].Padding {
+		err := fmt.Errorf("torrent: no file at index %d", index)
+		return "", false, err
+	}
+
+	dir := t.DownloadDir()
+	path := filepath.Join(append([]string{dir}, files[index].Path...)...)
+	// Sanitized paths stay below the download directory; make sure.
+	rel, err := filepath.Rel(dir, path)
+	if err != nil || rel == ".." ||
+		strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		err := fmt.Errorf("torrent: %s is outside %s", path, dir)
+		return "", false, err
+	}
+
+	have := t.picker.Bitfield()
+	wanted := t.Metainfo.piecesForFiles([]int{index})
+	for i := range t.Metainfo.Info.NumPieces {
+		if wanted.Has(i) && !have.Has(i) {
+			return path, false, nil
+		}
+	}
+	return path, true, nil
+}
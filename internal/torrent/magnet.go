@@ -0,0 +1,135 @@
+package torrent
+
+import (
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Magnet is a parsed magnet link.
+type Magnet struct {
+	InfoHash [sha1.Size]byte
+	Name     string
+	Trackers []string
+	WebSeeds []string
+	// Peers are host:port addresses to dial directly (x.pe).
+	Peers []string
+	// SelectOnly lists the indices of the files to download (BEP 53); nil
+	// means all of them.
+	SelectOnly []int
+}
+
+// ParseMagnet reads a magnet link with a v1 info-hash, in hex or base32.
+func ParseMagnet(uri string) (*Magnet, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil, fmt.Errorf("magnet: %w", err)
+	}
+	if u.Scheme != "magnet" {
+		return nil, fmt.Errorf("magnet: not a magnet link: %q", uri)
+	}
+	q := u.Query()
+
+	m := &Magnet{
+		Name:     q.Get("dn"),
+		Trackers: q["tr"],
+		WebSeeds: q["ws"],
+		Peers:    q["x.pe"],
+	}
+	for _, so := range q["so"] {
+		files, err := parseSelectOnly(so)
+		if err != nil {
+			return nil, err
+		}
+		m.SelectOnly = append(m.SelectOnly, files...)
+	}
+
+	found := false
+	for _, xt := range q["xt"] {
+		hash, ok := strings.CutPrefix(xt, "urn:btih:")
+		if !ok {
+			continue
+		}
+		if err := decodeBTIH(hash, &m.InfoHash); err != nil {
+			return nil, err
+		}
+		found = true
+	}
+	if !found {
+		return nil, errors.New("magnet: no urn:btih info-hash")
+	}
+
+	return m, nil
+}
+
+// maxSelectRange caps how many indices one so= range may expand to.
+const maxSelectRange = 1 << 16
+
+// parseSelectOnly reads a BEP 53 list such as "0,2,4,6-8".
+func parseSelectOnly(s string) ([]int, error) {
+	var out []int
+	for _, part := range strings.Split(s, ",") {
+		lo, hi, isRange := strings.Cut(part, "-")
+		first, err := strconv.Atoi(lo)
+		last := first
+		if err == nil && isRange {
+			last, err = strconv.Atoi(hi)
+		}
+		if err != nil || first < 0 || last < first ||
+			last-first >= maxSelectRange {
+			return nil, fmt.Errorf("magnet: bad so= entry %q", part)
+		}
+		for i := first; i <= last; i++ {
+			out = append(out, i)
+		}
+	}
+	return out, nil
+}
+
+func decodeBTIH(s string, out *[sha1.Size]byte) error {
+	var b []byte
+	var err error
+	switch len(s) {
+	case 40:
+		b, err = hex.DecodeString(s)
+	case 32:
+		b, err = base32.StdEncoding.DecodeString(strings.ToUpper(s))
+	default:
+		return fmt.Errorf("magnet: bad info-hash length %d", len(s))
+	}
+	if err != nil {
+		return fmt.Errorf("magnet: bad info-hash: %w", err)
+	}
+
+	copy(out[:], b)
+	return nil
+}
+
+// MagnetURI returns a magnet link for the torrent: its v1 info-hash, the
+// SHA-256 multihash for hybrid torrents, the display name and every tracker.
+func (m *Metainfo) MagnetURI() string {
+	var sb strings.Builder
+	sb.WriteString("magnet:?xt=urn:btih:")
+	sb.WriteString(hex.EncodeToString(m.Info.Hash[:]))
+
+	if m.Info.Hybrid() {
+		// 0x12 is the multihash code for sha2-256, 0x20 its length.
+		sb.WriteString("&xt=urn:btmh:1220")
+		sb.WriteString(hex.EncodeToString(m.Info.HashV2[:]))
+	}
+	if m.Info.Name != "" {
+		sb.WriteString("&dn=")
+		sb.WriteString(url.QueryEscape(m.Info.Name))
+	}
+	for _, tr := range m.AnnounceURLs {
+		sb.WriteString("&tr=")
+		sb.WriteString(url.QueryEscape(tr))
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,152 @@
+package torrent
+
+import (
+	"bytes"
+	"encoding/base32"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/prxssh/echo/internal/bencode"
+)
+
+func TestParseMagnet(t *testing.T) {
+	data, _ := buildSingleFileMeta(t, false)
+	m, err := ParseMetainfo(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("ParseMetainfo() error = %v", err)
+	}
+
+	mag, err := ParseMagnet(m.MagnetURI() + "&x.pe=10.0.0.1:6881")
+	if err != nil {
+		t.Fatalf("ParseMagnet() error = %v", err)
+	}
+	want := &Magnet{
+		InfoHash: m.Info.Hash,
+		Name:     "file.bin",
+		Trackers: []string{"http://tracker/announce"},
+		Peers:    []string{"10.0.0.1:6881"},
+	}
+	if !reflect.DeepEqual(mag, want) {
+		t.Fatalf("ParseMagnet() = %+v; want %+v", mag, want)
+	}
+
+	b32 := base32.StdEncoding.EncodeToString(m.Info.Hash[:])
+	mag, err = ParseMagnet("magnet:?xt=urn:btih:" + strings.ToLower(b32))
+	if err != nil || mag.InfoHash != m.Info.Hash {
+		t.Fatalf("base32: %+v, %v", mag, err)
+	}
+
+	for _, bad := range []string{
+		"http://example.com/",
+		"magnet:?dn=nothing",
+		"magnet:?xt=urn:btih:abcd",
+	} {
+		if _, err := ParseMagnet(bad); err == nil {
+			t.Errorf("ParseMagnet(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestBuildTorrent(t *testing.T) {
+	data, _ := buildSingleFileMeta(t, false)
+	var top map[string]bencode.RawMessage
+	if err := bencode.Unmarshal(data, &top); err != nil {
+		t.Fatal(err)
+	}
+	info := []byte(top["info"])
+
+	orig, err := ParseMetainfo(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("ParseMetainfo() error = %v", err)
+	}
+	mag := &Magnet{
+		InfoHash: orig.Info.Hash,
+		Trackers: []string{"http://a/announce", "udp://b:80"},
+		WebSeeds: []string{"http://seed/"},
+	}
+
+	built, err := BuildTorrent(info, mag)
+	if err != nil {
+		t.Fatalf("BuildTorrent() error = %v", err)
+	}
+	m, err := ParseMetainfo(bytes.NewReader(built))
+	if err != nil {
+		t.Fatalf("ParseMetainfo(built) error = %v", err)
+	}
+	if m.Info.Hash != orig.Info.Hash || m.Info.Name != "file.bin" {
+		t.Fatalf("built info = %+v", m.Info)
+	}
+	if !reflect.DeepEqual(m.AnnounceURLs, mag.Trackers) ||
+		!reflect.DeepEqual(m.WebSeeds, mag.WebSeeds) {
+		t.Fatalf("trackers %v, seeds %v", m.AnnounceURLs, m.WebSeeds)
+	}
+
+	mag.InfoHash[0] ^= 1
+	if _, err := BuildTorrent(info, mag); err == nil {
+		t.Fatal("BuildTorrent() accepted a mismatched info dict")
+	}
+}
+
+func TestParseSelectOnly(t *testing.T) {
+	mag, err := ParseMagnet(
+		"magnet:?xt=urn:btih:" + strings.Repeat("ab", 20) +
+			"&so=0,2,4,6-8&so=10",
+	)
+	if err != nil {
+		t.Fatalf("ParseMagnet() error = %v", err)
+	}
+	want := []int{0, 2, 4, 6, 7, 8, 10}
+	if !reflect.DeepEqual(mag.SelectOnly, want) {
+		t.Fatalf("SelectOnly = %v; want %v", mag.SelectOnly, want)
+	}
+
+	for _, bad := range []string{"", "1,,2", "-1", "3-1", "a", "0-99999"} {
+		if _, err := parseSelectOnly(bad); err == nil {
+			t.Errorf("parseSelectOnly(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestPiecesForFiles(t *testing.T) {
+	m := &Metainfo{
+		Size: 40,
+		Info: &Info{
+			PieceLength: 10,
+			NumPieces:   4,
+			Files: &[]File{
+				{Length: 15}, // pieces 0-1
+				{Length: 0},
+				{Length: 20}, // pieces 1-3
+				{Length: 5},  // piece 3
+			},
+		},
+	}
+
+	cases := []struct {
+		files []int
+		want  []int
+	}{
+		{[]int{0}, []int{0, 1}},
+		{[]int{3}, []int{3}},
+		{[]int{0, 2}, []int{0, 1, 2, 3}},
+		{[]int{1, 9, -1}, nil},
+	}
+	for _, c := range cases {
+		bf := m.piecesForFiles(c.files)
+		var got []int
+		for i := range m.Info.NumPieces {
+			if bf.Has(i) {
+				got = append(got, i)
+			}
+		}
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf(
+				"piecesForFiles(%v) = %v; want %v",
+				c.files,
+				got,
+				c.want,
+			)
+		}
+	}
+}
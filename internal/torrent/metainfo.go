@@ -3,9 +3,14 @@ package torrent
 import (
 	"bytes"
 	"crypto/sha1"
+	"crypto/sha256"
 	"errors"
 	"fmt"
 	"io"
+	"math"
+	"net"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/prxssh/echo/internal/bencode"
@@ -14,25 +19,47 @@ import (
 type Metainfo struct {
 	Info         *Info     `json:"info"`
 	AnnounceURLs []string  `json:"announceUrls"`
+	WebSeeds     []string  `json:"webSeeds"`
+	HTTPSeeds    []string  `json:"httpSeeds"`
+	Nodes        []string  `json:"nodes"`
 	CreationDate time.Time `json:"creationDate"`
 	Comment      string    `json:"comment"`
 	Encoding     string    `json:"encoding"`
+	CreatedBy    string    `json:"createdBy"`
 	Mode         FileMode  `json:"-"`
 	Size         uint64    `json:"size"`
 }
 
 type Info struct {
-	Hash        [sha1.Size]byte   `json:"infoHash"`
-	Name        string            `json:"name"`
-	Files       *[]File           `json:"files"`
-	PieceLength uint64            `json:"pieceLength"`
-	Pieces      [][sha1.Size]byte `json:"pieces"`
-	Private     bool              `json:"private"`
+	Hash        [sha1.Size]byte `json:"infoHash"`
+	Name        string          `json:"name"`
+	Files       *[]File         `json:"files"`
+	PieceLength uint64          `json:"pieceLength"`
+	NumPieces   int             `json:"numPieces"`
+	Private     bool            `json:"private"`
+	Source      string          `json:"source"`
+
+	// MetaVersion is 2 for hybrid v1+v2 torrents, whose HashV2 is set and
+	// whose pieces verify against both hash sets.
+	MetaVersion int               `json:"metaVersion"`
+	HashV2      [sha256.Size]byte `json:"infoHashV2"`
+
+	// Similar and Collections name torrents likely to share files with
+	// this one (BEP 38).
+	Similar     [][sha1.Size]byte `json:"similar,omitempty"`
+	Collections []string          `json:"collections,omitempty"`
+
+	pieces *pieceHashes
+	v2     *v2Info
 }
 
 type File struct {
-	Length uint64   `json:"length"`
-	Path   []string `json:"path"`
+	Length  uint64   `json:"length"`
+	Path    []string `json:"path"`
+	Padding bool     `json:"padding"`
+	// SHA1 is the hash of the whole file, when the torrent lists it
+	// (BEP 47).
+	SHA1 []byte `json:"-"`
 }
 
 type FileMode string
@@ -53,22 +80,45 @@ func ParseMetainfo(r io.Reader) (*Metainfo, error) {
 
 type parser struct {
 	data map[string]any
+	// rawInfo holds the info dict exactly as it appeared in the file. The
+	// info-hash must be computed over these bytes: re-encoding the decoded
+	// dict would change the hash of any torrent that is not canonical.
+	rawInfo bencode.RawMessage
+	pieces  pieceHashes
 }
 
 func newParser(r io.Reader) (*parser, error) {
-	decoded, err := bencode.NewDecoder(r).Decode()
+	b, err := io.ReadAll(r)
 	if err != nil {
 		return nil, err
 	}
 
-	data, ok := decoded.(map[string]any)
-	if !ok {
-		return nil, errors.New(
-			"metainfo: top-level is not a bencoded dictionary",
+	var top map[string]bencode.RawMessage
+	if err := bencode.Unmarshal(b, &top); err != nil {
+		return nil, fmt.Errorf(
+			"metainfo: top-level is not a bencoded dictionary: %w",
+			err,
 		)
 	}
 
-	return &parser{data: data}, nil
+	p := &parser{
+		data:    make(map[string]any, len(top)),
+		rawInfo: top["info"],
+	}
+	for k, raw := range top {
+		dec := bencode.NewDecoder(bytes.NewReader(raw))
+		if k == "info" {
+			dec.StreamKey("pieces", &p.pieces)
+		}
+
+		v, err := dec.Decode()
+		if err != nil {
+			return nil, err
+		}
+		p.data[k] = v
+	}
+
+	return p, nil
 }
 
 func (p *parser) parse() (*Metainfo, error) {
@@ -85,9 +135,16 @@ func (p *parser) parse() (*Metainfo, error) {
 		return nil, err
 	}
 
+	webSeeds := p.parseURLs("url-list")
+	httpSeeds := p.parseURLs("httpseeds")
+	nodes := p.parseNodes()
+
 	creation := p.getInt("creation date")
 	comment := p.getString("comment")
 	encoding := p.getString("encoding")
+	createdBy := p.getString("created by")
+
+	p.parseSimilar(info)
 
 	mode := FileModeSingle
 	if info.Files != nil {
@@ -97,9 +154,13 @@ func (p *parser) parse() (*Metainfo, error) {
 	return &Metainfo{
 		Info:         info,
 		AnnounceURLs: announceURLs,
+		WebSeeds:     webSeeds,
+		HTTPSeeds:    httpSeeds,
+		Nodes:        nodes,
 		CreationDate: time.Unix(creation, 0),
 		Comment:      comment,
 		Encoding:     encoding,
+		CreatedBy:    createdBy,
 		Mode:         mode,
 		Size:         totalSize,
 	}, nil
@@ -113,18 +174,20 @@ func (p *parser) parseInfoDict() (*Info, uint64, error) {
 		)
 	}
 
-	hash, err := computeInfoHash(raw)
-	if err != nil {
-		return nil, 0, err
-	}
+	hash := sha1.Sum(p.rawInfo)
 
 	pieceLength, err := parsePieceLength(raw)
 	if err != nil {
 		return nil, 0, err
 	}
 
-	pieces, err := parsePieces(raw)
-	if err != nil {
+	version, _ := intFrom(raw, "meta version")
+	if version == 2 && raw["pieces"] == nil {
+		return nil, 0, errors.New(
+			"metainfo: v2-only torrents are not supported",
+		)
+	}
+	if err := checkPieces(raw, &p.pieces); err != nil {
 		return nil, 0, err
 	}
 
@@ -134,6 +197,7 @@ func (p *parser) parseInfoDict() (*Info, uint64, error) {
 	}
 
 	name, _ := stringFrom(raw, "name")
+	source, _ := stringFrom(raw, "source")
 	priv := parsePrivateFlag(raw)
 
 	info := &Info{
@@ -141,8 +205,22 @@ func (p *parser) parseInfoDict() (*Info, uint64, error) {
 		Name:        name,
 		Files:       files,
 		PieceLength: pieceLength,
-		Pieces:      pieces,
+		NumPieces:   p.pieces.count(),
 		Private:     priv,
+		Source:      source,
+		MetaVersion: 1,
+		pieces:      &p.pieces,
+	}
+	if err := checkLayout(info, totalSize); err != nil {
+		return nil, 0, err
+	}
+	if version == 2 {
+		if err := p.parseV2(raw, info, totalSize); err != nil {
+			return nil, 0, err
+		}
+	}
+	if err := sanitizeFiles(info); err != nil {
+		return nil, 0, err
 	}
 	return info, totalSize, nil
 }
@@ -183,18 +261,47 @@ func (p *parser) parseAnnounceURLs() ([]string, error) {
 	return urls, nil
 }
 
-func computeInfoHash(raw map[string]any) ([sha1.Size]byte, error) {
-	var buf bytes.Buffer
+// parseURLs reads a seed list: the BEP 19 'url-list' or the BEP 17
+// 'httpseeds', each either a single URL or a list of them.
+func (p *parser) parseURLs(key string) []string {
+	var urls []string
+	switch v := p.data[key].(type) {
+	case string:
+		if v != "" {
+			urls = append(urls, v)
+		}
+	case []any:
+		for _, u := range v {
+			if s, ok := u.(string); ok && s != "" {
+				urls = append(urls, s)
+			}
+		}
+	}
+	return urls
+}
 
-	if err := bencode.NewEncoder(&buf).Encode(raw); err != nil {
-		return [sha1.Size]byte{}, fmt.Errorf(
-			"metainfo: failed to re-encode info for hash: %w",
-			err,
+// parseNodes reads the DHT bootstrap nodes of a trackerless torrent, a list of
+// [host, port] pairs, as host:port strings. Malformed entries are skipped.
+func (p *parser) parseNodes() []string {
+	list, _ := p.data["nodes"].([]any)
+
+	nodes := make([]string, 0, len(list))
+	for _, n := range list {
+		pair, ok := n.([]any)
+		if !ok || len(pair) != 2 {
+			continue
+		}
+		host, ok := pair[0].(string)
+		port, okPort := pair[1].(int64)
+		if !ok || !okPort || host == "" || port <= 0 || port > 65535 {
+			continue
+		}
+		nodes = append(
+			nodes,
+			net.JoinHostPort(host, strconv.FormatInt(port, 10)),
 		)
 	}
-
-	sum := sha1.Sum(buf.Bytes())
-	return sum, nil
+	return nodes
 }
 
 func parsePieceLength(raw map[string]any) (uint64, error) {
@@ -209,26 +316,17 @@ func parsePieceLength(raw map[string]any) (uint64, error) {
 	return uint64(pl), nil
 }
 
-func parsePieces(raw map[string]any) ([][sha1.Size]byte, error) {
-	s, ok := raw["pieces"].(string)
-	if !ok {
-		return nil, errors.New("metainfo: missing or invalid 'pieces'")
+func checkPieces(raw map[string]any, h *pieceHashes) error {
+	if _, ok := raw["pieces"].(bencode.Streamed); !ok {
+		return errors.New("metainfo: missing or invalid 'pieces'")
 	}
-
-	b := []byte(s)
-	if len(b)%sha1.Size != 0 {
-		return nil, errors.New(
+	if h.size%sha1.Size != 0 {
+		return errors.New(
 			"metainfo: 'pieces' length is not a multiple of 20 bytes",
 		)
 	}
 
-	n := len(b) / sha1.Size
-	out := make([][sha1.Size]byte, n)
-	for i := 0; i < n; i++ {
-		copy(out[i][:], b[i*sha1.Size:(i+1)*sha1.Size])
-	}
-
-	return out, nil
+	return nil
 }
 
 func parsePrivateFlag(raw map[string]any) bool {
@@ -296,7 +394,19 @@ func parseMultiFiles(filesAny []any) (*[]File, uint64, error) {
 			path = append(path, ps)
 		}
 
-		flist = append(flist, File{Length: uint64(length), Path: path})
+		attr, _ := stringFrom(fdict, "attr")
+		f := File{
+			Length:  uint64(length),
+			Path:    path,
+			Padding: strings.ContainsRune(attr, 'p'),
+		}
+		if sum, _ := stringFrom(fdict, "sha1"); len(sum) == sha1.Size {
+			f.SHA1 = []byte(sum)
+		}
+		flist = append(flist, f)
+		if total > math.MaxInt64-uint64(length) {
+			return nil, 0, errSizeOverflow
+		}
 		total += uint64(length)
 	}
 	return &flist, total, nil
@@ -3,7 +3,9 @@ package torrent
 import (
 	"bytes"
 	"crypto/sha1"
+	"fmt"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/prxssh/echo/internal/bencode"
@@ -22,7 +24,7 @@ func buildSingleFileMeta(
 		"name":         "file.bin",
 		"piece length": int64(16384),
 		"pieces":       string(pieces),
-		"length":       int64(12345),
+		"length":       int64(20000),
 	}
 	if withPrivate {
 		info["private"] = int64(1)
@@ -65,7 +67,7 @@ func buildMultiFileMeta(t *testing.T) ([]byte, map[string]any) {
 
 	info := map[string]any{
 		"name":         "my-dir",
-		"piece length": int64(32768),
+		"piece length": int64(128),
 		"pieces":       string(pieces),
 		"files":        files,
 		"private":      int64(1),
@@ -122,7 +124,7 @@ func TestNew_SingleFile(t *testing.T) {
 	if m.Mode != FileModeSingle {
 		t.Fatalf("Mode = %q; want %q", m.Mode, FileModeSingle)
 	}
-	if got, want := m.Size, uint64(12345); got != want {
+	if got, want := m.Size, uint64(20000); got != want {
 		t.Fatalf("Size = %d; want %d", got, want)
 	}
 
@@ -136,7 +138,7 @@ func TestNew_SingleFile(t *testing.T) {
 	if got, want := m.Info.PieceLength, uint64(16384); got != want {
 		t.Fatalf("PieceLength = %d; want %d", got, want)
 	}
-	if got := len(m.Info.Pieces); got != 2 { // 40 bytes / 20
+	if got := m.Info.NumPieces; got != 2 { // 40 bytes / 20
 		t.Fatalf("len(Pieces) = %d; want 2", got)
 	}
 	if m.Info.Private {
@@ -298,3 +300,116 @@ func TestNew_Errors(t *testing.T) {
 		}
 	})
 }
+
+func TestInfoHashUsesOriginalBytes(t *testing.T) {
+	// Keys out of order: a re-encoded dict would hash differently.
+	info := "d6:lengthi1e4:name1:x6:pieces20:" +
+		string(make([]byte, 20)) + "12:piece lengthi1ee"
+	data := []byte("d4:info" + info + "e")
+
+	m, err := ParseMetainfo(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("ParseMetainfo() error = %v", err)
+	}
+	if want := sha1.Sum([]byte(info)); m.Info.Hash != want {
+		t.Fatalf("Info.Hash = %x; want %x", m.Info.Hash, want)
+	}
+}
+
+func TestMagnetURI(t *testing.T) {
+	data, _ := buildSingleFileMeta(t, false)
+	m, err := ParseMetainfo(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("ParseMetainfo() error = %v", err)
+	}
+
+	want := fmt.Sprintf(
+		"magnet:?xt=urn:btih:%x&dn=file.bin&tr=%s",
+		m.Info.Hash,
+		"http%3A%2F%2Ftracker%2Fannounce",
+	)
+	if got := m.MagnetURI(); got != want {
+		t.Fatalf("MagnetURI() = %q; want %q", got, want)
+	}
+
+	hybrid, _ := buildHybridMeta(t, nil)
+	m, err = ParseMetainfo(bytes.NewReader(hybrid))
+	if err != nil {
+		t.Fatalf("ParseMetainfo() error = %v", err)
+	}
+	btmh := fmt.Sprintf("&xt=urn:btmh:1220%x", m.Info.HashV2)
+	if !strings.Contains(m.MagnetURI(), btmh) {
+		t.Fatalf("MagnetURI() = %q; want %q", m.MagnetURI(), btmh)
+	}
+}
+
+func TestWebSeeds(t *testing.T) {
+	cases := map[string]struct {
+		seeds any
+		want  []string
+	}{
+		"single": {"http://seed/file", []string{"http://seed/file"}},
+		"list": {
+			[]any{"http://a/", "", "http://b/"},
+			[]string{"http://a/", "http://b/"},
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, info := buildSingleFileMeta(t, false)
+			data, err := bencode.Marshal(map[string]any{
+				"info":      info,
+				"url-list":  c.seeds,
+				"httpseeds": c.seeds,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			m, err := ParseMetainfo(bytes.NewReader(data))
+			if err != nil {
+				t.Fatalf("ParseMetainfo() error = %v", err)
+			}
+			if !reflect.DeepEqual(m.HTTPSeeds, c.want) {
+				t.Fatalf(
+					"HTTPSeeds = %v; want %v",
+					m.HTTPSeeds,
+					c.want,
+				)
+			}
+			if !reflect.DeepEqual(m.WebSeeds, c.want) {
+				t.Fatalf(
+					"WebSeeds = %v; want %v",
+					m.WebSeeds,
+					c.want,
+				)
+			}
+		})
+	}
+}
+
+func TestNodes(t *testing.T) {
+	_, info := buildSingleFileMeta(t, false)
+	data, err := bencode.Marshal(map[string]any{
+		"info": info,
+		"nodes": []any{
+			[]any{"router.example.org", int64(6881)},
+			[]any{"::1", int64(6882)},
+			[]any{"bad"},
+			[]any{"10.0.0.1", int64(70000)},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := ParseMetainfo(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("ParseMetainfo() error = %v", err)
+	}
+	want := []string{"router.example.org:6881", "[::1]:6882"}
+	if !reflect.DeepEqual(m.Nodes, want) {
+		t.Fatalf("Nodes = %v; want %v", m.Nodes, want)
+	}
+}
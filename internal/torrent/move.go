@@ -0,0 +1,51 @@
+package torrent
+
+import (
+	"context"
+	"fmt"
+)
+
+// Move relocates the torrent's files below dir. A running torrent is
+// stopped while its files move and started again afterwards; a paused one
+// stays paused. If the move fails, the files are left where they were and
+// the torrent goes back to how it was.
+func (t *Torrent) Move(ctx context.Context, dir string) error {
+	prev := t.State()
+	if prev != StatePaused && !prev.Active() {
+		return fmt.Errorf("torrent: cannot move while %s", prev)
+	}
+
+	t.halt(ctx, StateMoving, nil)
+	err := t.moveFiles(dir)
+	if prev.Active() {
+		if serr := t.Start(ctx); err == nil {
+			err = serr
+		}
+	} else {
+		_ = t.setState(ctx, StatePaused, nil)
+	}
+	return err
+}
+
+// moveFiles flushes the torrent's files and moves them. With everything
+// written safely on disk, the journal has nothing left to recover.
+func (t *Torrent) moveFiles(dir string) error {
+	t.ioMu.Lock()
+	defer t.ioMu.Unlock()
+
+	if err := t.storage.Sync(); err != nil {
+		return err
+	}
+	if err := t.storage.Move(dir); err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	t.dir = dir
+	t.mu.Unlock()
+
+	if t.journal != nil {
+		return t.journal.Reset()
+	}
+	return nil
+}
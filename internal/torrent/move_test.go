@@ -0,0 +1,83 @@
+package torrent
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMove(t *testing.T) {
+	from := t.TempDir()
+	set := filepath.Join(from, "set")
+	if err := os.MkdirAll(filepath.Join(set, "sub"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{"a.txt": "alpha", "sub/b.txt": "beta"}
+	for name, data := range files {
+		path := filepath.Join(set, name)
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	data, err := Create(CreateOpts{Path: set})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr, err := ParseTorrent(data, Opts{DownloadDir: from})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if err := tr.Check(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	to := filepath.Join(t.TempDir(), "new")
+	if err := tr.Move(ctx, to); err != nil {
+		t.Fatalf("Move() error = %v", err)
+	}
+	if got := tr.DownloadDir(); got != to {
+		t.Errorf("DownloadDir() = %q; want %q", got, to)
+	}
+	if got := tr.State(); got != StatePaused {
+		t.Errorf("State() = %s; want paused", got)
+	}
+	for name, want := range files {
+		got, err := os.ReadFile(filepath.Join(to, "set", name))
+		if err != nil || string(got) != want {
+			t.Errorf("%s = %q, %v; want %q", name, got, err, want)
+		}
+	}
+	if _, err := os.Stat(set); !os.IsNotExist(err) {
+		t.Errorf("old directory left behind: %v", err)
+	}
+	if err := tr.Check(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, left := tr.Totals(); left != 0 {
+		t.Errorf("left after move = %d; want 0", left)
+	}
+
+	// A file in the way fails the move and leaves everything in place.
+	back := t.TempDir()
+	clash := filepath.Join(back, "set", "sub", "b.txt")
+	if err := os.MkdirAll(filepath.Dir(clash), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(clash, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := tr.Move(ctx, back); err == nil {
+		t.Fatal("Move() onto an existing file succeeded")
+	}
+	if got := tr.DownloadDir(); got != to {
+		t.Errorf("DownloadDir() = %q after failing; want %q", got, to)
+	}
+	for name := range files {
+		_, err := os.Stat(filepath.Join(to, "set", name))
+		if err != nil {
+			t.Errorf("%s not put back: %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package torrent
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// windowsReserved are device names Windows refuses as file names, with or
+// without an extension.
+var windowsReserved = map[string]struct{}{
+	"CON": {}, "PRN": {}, "AUX": {}, "NUL": {},
+	"COM1": {}, "COM2": {}, "COM3": {}, "COM4": {}, "COM5": {},
+	"COM6": {}, "COM7": {}, "COM8": {}, "COM9": {},
+	"LPT1": {}, "LPT2": {}, "LPT3": {}, "LPT4": {}, "LPT5": {},
+	"LPT6": {}, "LPT7": {}, "LPT8": {}, "LPT9": {},
+}
+
+// sanitizeFiles makes the info dict's paths safe to create below the
+// download directory. Components that could escape it, absolute paths and
+// control characters fail the torrent; Windows device names are renamed, and
+// paths that collide with an earlier file (ignoring case, as many
+// filesystems do) get a " (n)" suffix. The result depends only on the
+// torrent, so every client run maps it to the same files.
+func sanitizeFiles(info *Info) error {
+	name, err := sanitizeComponent(info.Name)
+	if err != nil {
+		return err
+	}
+	info.Name = name
+
+	if info.Files == nil {
+		return nil
+	}
+
+	used := make(map[string]bool) // lower-cased path -> is a file
+	for i := range *info.Files {
+		f := &(*info.Files)[i]
+		if f.Padding {
+			continue
+		}
+
+		comps := make([]string, len(f.Path))
+		for j, c := range f.Path {
+			if comps[j], err = sanitizeComponent(c); err != nil {
+				return err
+			}
+		}
+		dedupePath(comps, used)
+		f.Path = comps
+	}
+
+	return nil
+}
+
+func sanitizeComponent(c string) (string, error) {
+	switch {
+	case c == "" || c == "." || c == "..":
+		return "", fmt.Errorf("metainfo: unsafe path component %q", c)
+	case strings.ContainsAny(c, `/\`):
+		return "", fmt.Errorf("metainfo: path separator in %q", c)
+	case len(c) >= 2 && c[1] == ':' && isASCIILetter(c[0]):
+		return "", fmt.Errorf("metainfo: absolute path %q", c)
+	}
+	for _, r := range c {
+		if r < 0x20 || r == 0x7f {
+			return "", fmt.Errorf(
+				"metainfo: control character in %q",
+				c,
+			)
+		}
+	}
+
+	stem, _, _ := strings.Cut(c, ".")
+	if _, ok := windowsReserved[strings.ToUpper(stem)]; ok {
+		return "_" + c, nil
+	}
+	return c, nil
+}
+
+// dedupePath renames the first component of comps that collides with what
+// is already in used, then records the result. A directory may be shared by
+// many files, but a file may not share its path with anything.
+func dedupePath(comps []string, used map[string]bool) {
+	for k := range comps {
+		last := k == len(comps)-1
+		orig := comps[k]
+
+		for n := 1; ; n++ {
+			key := strings.ToLower(path.Join(comps[:k+1]...))
+			isFile, taken := used[key]
+			if !taken || (!last && !isFile) {
+				break
+			}
+			comps[k] = withSuffix(orig, n, last)
+		}
+	}
+
+	for k := range comps {
+		key := strings.ToLower(path.Join(comps[:k+1]...))
+		used[key] = k == len(comps)-1
+	}
+}
+
+// withSuffix inserts " (n)" into name, before the extension for files.
+func withSuffix(name string, n int, file bool) string {
+	ext := ""
+	if file {
+		ext = path.Ext(name)
+		if ext == name {
+			ext = ""
+		}
+	}
+	return fmt.Sprintf("%s (%d)%s", strings.TrimSuffix(name, ext), n, ext)
+}
+
+func isASCIILetter(b byte) bool {
+	return (b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z')
+}
@@ -0,0 +1,64 @@
+package torrent
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSanitizeFiles(t *testing.T) {
+	files := []File{
+		{Path: []string{"a.txt"}},
+		{Path: []string{"A.TXT"}},
+		{Path: []string{"a.txt"}},
+		{Path: []string{"CON.txt"}},
+		{Path: []string{"dir", "x"}},
+		{Path: []string{"dir"}},
+		{Path: []string{"dir", "y"}},
+		{Path: []string{".pad", "1"}, Padding: true},
+		{Path: []string{".pad", "1"}, Padding: true},
+	}
+	info := &Info{Name: "aux", Files: &files}
+
+	if err := sanitizeFiles(info); err != nil {
+		t.Fatalf("sanitizeFiles() error = %v", err)
+	}
+
+	want := [][]string{
+		{"a.txt"},
+		{"A (1).TXT"},
+		{"a (2).txt"},
+		{"_CON.txt"},
+		{"dir", "x"},
+		{"dir (1)"},
+		{"dir", "y"},
+		{".pad", "1"},
+		{".pad", "1"},
+	}
+	for i, f := range files {
+		if !reflect.DeepEqual(f.Path, want[i]) {
+			t.Errorf("file %d = %q; want %q", i, f.Path, want[i])
+		}
+	}
+	if info.Name != "_aux" {
+		t.Errorf("Name = %q; want %q", info.Name, "_aux")
+	}
+}
+
+func TestSanitizeFilesRejectsUnsafePaths(t *testing.T) {
+	unsafe := [][]string{
+		{"..", "etc", "passwd"},
+		{"ok", "."},
+		{"/etc/passwd"},
+		{`C:`, "Windows"},
+		{`dir\..\..`},
+		{"bell\x07"},
+		{""},
+	}
+	for _, p := range unsafe {
+		files := []File{{Path: p}}
+		info := &Info{Name: "x", Files: &files}
+		if err := sanitizeFiles(info); err == nil {
+			t.Errorf("sanitizeFiles(%q) error = nil", p)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package torrent
+
+import (
+	"context"
+	"time"
+
+	"github.com/prxssh/echo/internal/events"
+	"github.com/prxssh/echo/internal/piece"
+)
+
+// pieceMapInterval is how often changes to the piece map are reported.
+const pieceMapInterval = 500 * time.Millisecond
+
+const TopicPieceDelta events.Topic[PieceDelta] = "pieces:delta"
+
+// PieceDelta is the payload of the "pieces:delta" event: the pieces whose
+// state changed since the last one, and their new states, encoded as in
+// PieceMap.
+type PieceDelta struct {
+	events.Torrent
+	Indices []int  `json:"indices"`
+	States  string `json:"states"`
+}
+
+// PieceMap returns the state of every piece as one digit per piece: 0
+// missing, 1 requested, 2 downloaded and awaiting verification, 3 verified.
+func (t *Torrent) PieceMap() string {
+	return encodePieceStates(t.picker.States())
+}
+
+func encodePieceStates(states []piece.State) string {
+	b := make([]byte, len(states))
+	for i, s := range states {
+		b[i] = '0' + byte(s)
+	}
+	return string(b)
+}
+
+// diffPieceMaps lists the pieces whose state differs between two maps of
+// the same torrent, with their states in cur.
+func diffPieceMaps(prev, cur string) ([]int, string) {
+	if len(prev) != len(cur) {
+		prev = ""
+	}
+
+	var indices []int
+	var states []byte
+	for i := range len(cur) {
+		if i < len(prev) && prev[i] == cur[i] {
+			continue
+		}
+		indices = append(indices, i)
+		states = append(states, cur[i])
+	}
+	return indices, string(states)
+}
+
+// runPieceMap emits a "pieces:delta" every pieceMapInterval in which a
+// piece changed state, while the torrent runs. Listeners fetch the whole
+// map with PieceMap, and again after a state change, since pieces verified
+// by a check are not reported as deltas.
+func (t *Torrent) runPieceMap(ctx context.Context) {
+	ticker := time.NewTicker(pieceMapInterval)
+	defer ticker.Stop()
+
+	last := t.PieceMap()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			cur := t.PieceMap()
+			indices, states := diffPieceMaps(last, cur)
+			last = cur
+			if len(indices) == 0 {
+				continue
+			}
+
+			scope, _ := events.TorrentOf(ctx)
+			TopicPieceDelta.Emit(ctx, PieceDelta{
+				Torrent: scope,
+				Indices: indices,
+				States:  states,
+			})
+		}
+	}
+}
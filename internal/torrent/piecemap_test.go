@@ -0,0 +1,48 @@
+package torrent
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/prxssh/echo/internal/piece"
+)
+
+func TestEncodePieceStates(t *testing.T) {
+	states := []piece.State{
+		piece.Missing,
+		piece.Requested,
+		piece.Downloaded,
+		piece.Verified,
+	}
+	if got := encodePieceStates(states); got != "0123" {
+		t.Errorf("encodePieceStates = %q, want %q", got, "0123")
+	}
+}
+
+func TestDiffPieceMaps(t *testing.T) {
+	cases := []struct {
+		prev, cur string
+		indices   []int
+		states    string
+	}{
+		{"0123", "0123", nil, ""},
+		{"0013", "1033", []int{0, 2}, "13"},
+		// Maps of different lengths are compared piece by piece from
+		// nothing.
+		{"00", "310", []int{0, 1, 2}, "310"},
+	}
+	for _, c := range cases {
+		indices, states := diffPieceMaps(c.prev, c.cur)
+		if !slices.Equal(indices, c.indices) || states != c.states {
+			t.Errorf(
+				"diffPieceMaps(%q, %q) = %v, %q; want %v, %q",
+				c.prev,
+				c.cur,
+				indices,
+				states,
+				c.indices,
+				c.states,
+			)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package torrent
+
+import "crypto/sha1"
+
+// hashesPerChunk bounds each allocation of piece hashes, so a torrent with
+// hundreds of thousands of pieces needs neither one huge slice nor the
+// copies made while growing it.
+const hashesPerChunk = 1 << 14
+
+// pieceHashes receives the 'pieces' string straight from the decoder and
+// keeps it as fixed-size chunks of the raw blob, so a large torrent's hashes
+// are never held as one big string or as a slice per hash.
+type pieceHashes struct {
+	chunks [][]byte
+	size   int
+}
+
+func (h *pieceHashes) Write(b []byte) (int, error) {
+	n := len(b)
+	for len(b) > 0 {
+		if len(h.chunks) == 0 || len(h.last()) == cap(h.last()) {
+			chunk := make([]byte, 0, hashesPerChunk*sha1.Size)
+			h.chunks = append(h.chunks, chunk)
+		}
+		last := &h.chunks[len(h.chunks)-1]
+		take := min(cap(*last)-len(*last), len(b))
+		*last = append(*last, b[:take]...)
+		h.size += take
+		b = b[take:]
+	}
+	return n, nil
+}
+
+func (h *pieceHashes) last() []byte {
+	return h.chunks[len(h.chunks)-1]
+}
+
+func (h *pieceHashes) count() int {
+	return h.size / sha1.Size
+}
+
+func (h *pieceHashes) at(i int) [sha1.Size]byte {
+	chunk := h.chunks[i/hashesPerChunk]
+	off := (i % hashesPerChunk) * sha1.Size
+	return [sha1.Size]byte(chunk[off : off+sha1.Size])
+}
+
+// PieceHash returns the SHA-1 hash of piece i.
+func (i *Info) PieceHash(index int) [sha1.Size]byte {
+	return i.pieces.at(index)
+}
@@ -0,0 +1,33 @@
+package torrent
+
+import (
+	"crypto/sha1"
+	"encoding/binary"
+	"testing"
+)
+
+func TestPieceHashesChunks(t *testing.T) {
+	n := hashesPerChunk + 3
+	blob := make([]byte, n*sha1.Size)
+	for i := range n {
+		binary.BigEndian.PutUint32(blob[i*sha1.Size:], uint32(i))
+	}
+
+	// Odd-sized writes straddle both hash and chunk boundaries.
+	var h pieceHashes
+	for rest := blob; len(rest) > 0; {
+		k := min(7777, len(rest))
+		h.Write(rest[:k])
+		rest = rest[k:]
+	}
+
+	if h.count() != n || len(h.chunks) != 2 {
+		t.Fatalf("count = %d, chunks = %d", h.count(), len(h.chunks))
+	}
+	for _, i := range []int{0, hashesPerChunk - 1, hashesPerChunk, n - 1} {
+		got := h.at(i)
+		if binary.BigEndian.Uint32(got[:]) != uint32(i) {
+			t.Fatalf("at(%d) = %x", i, got)
+		}
+	}
+}
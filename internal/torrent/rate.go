@@ -0,0 +1,101 @@
+package torrent
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+const (
+	// rateInterval is how often transfer rates are sampled.
+	rateInterval = time.Second
+	// rateWindow is the time constant of the rates' moving average:
+	// a sample's weight falls to 1/e after this long.
+	rateWindow = 5 * time.Second
+)
+
+// rateMeter turns a growing byte counter into an exponentially weighted
+// moving average of bytes per second.
+type rateMeter struct {
+	mu     sync.Mutex
+	rate   float64
+	total  uint64
+	at     time.Time
+	primed bool
+}
+
+// sample records the counter's value at now and returns the new rate. The
+// first sample only sets the baseline.
+func (m *rateMeter) sample(total uint64, now time.Time) float64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.primed || total < m.total {
+		m.total, m.at, m.primed = total, now, true
+		return m.rate
+	}
+	dt := now.Sub(m.at)
+	if dt <= 0 {
+		return m.rate
+	}
+
+	instant := float64(total-m.total) / dt.Seconds()
+	w := 1 - math.Exp(-float64(dt)/float64(rateWindow))
+	m.rate += w * (instant - m.rate)
+	m.total, m.at = total, now
+	return m.rate
+}
+
+func (m *rateMeter) value() float64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.rate
+}
+
+// reset zeroes the rate, as when the torrent stops transferring.
+func (m *rateMeter) reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.rate, m.primed = 0, false
+}
+
+// Rates returns the smoothed download and upload rates in bytes per second.
+func (t *Torrent) Rates() (download, upload float64) {
+	return t.downRate.value(), t.upRate.value()
+}
+
+// ETA estimates how long the download has left at the current rate: zero
+// once it is complete, and -1 when the torrent isn't downloading.
+func (t *Torrent) ETA() time.Duration {
+	_, _, left := t.Totals()
+	if left == 0 {
+		return 0
+	}
+	rate := t.downRate.value()
+	if t.State() != StateDownloading || rate < 1 {
+		return -1
+	}
+	return time.Duration(float64(left) / rate * float64(time.Second))
+}
+
+// runRates samples the transfer counters while the torrent runs.
+func (t *Torrent) runRates(ctx context.Context) {
+	ticker := time.NewTicker(rateInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			t.downRate.reset()
+			t.upRate.reset()
+			return
+		case now := <-ticker.C:
+			up, down, _ := t.Totals()
+			t.downRate.sample(down, now)
+			t.upRate.sample(up, now)
+		}
+	}
+}
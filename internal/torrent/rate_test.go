@@ -0,0 +1,41 @@
+package torrent
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestRateMeter(t *testing.T) {
+	var m rateMeter
+	now := time.Unix(0, 0)
+
+	if r := m.sample(1000, now); r != 0 {
+		t.Fatalf("first sample rate = %v; want 0", r)
+	}
+
+	// A steady 1000 B/s converges on 1000.
+	var total uint64 = 1000
+	var r float64
+	for range 60 {
+		now = now.Add(time.Second)
+		total += 1000
+		r = m.sample(total, now)
+	}
+	if math.Abs(r-1000) > 1 {
+		t.Fatalf("steady rate = %v; want ~1000", r)
+	}
+
+	// One idle second only pulls the average part of the way down.
+	now = now.Add(time.Second)
+	r = m.sample(total, now)
+	want := 1000 * math.Exp(-float64(time.Second)/float64(rateWindow))
+	if math.Abs(r-want) > 1 {
+		t.Fatalf("rate after idle second = %v; want ~%v", r, want)
+	}
+
+	m.reset()
+	if r := m.value(); r != 0 {
+		t.Fatalf("rate after reset = %v; want 0", r)
+	}
+}
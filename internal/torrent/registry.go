@@ -0,0 +1,86 @@
+package torrent
+
+import (
+	"crypto/sha1"
+	"errors"
+	"sync"
+)
+
+var ErrDuplicate = errors.New("torrent: already added")
+
+// Registry holds the session's torrents, addressable by any of their
+// info-hashes: the v1 SHA-1 and, for hybrid torrents, the truncated v2
+// SHA-256. A hybrid torrent can therefore not be added twice, once under
+// each hash.
+type Registry struct {
+	mu     sync.RWMutex
+	byHash map[[sha1.Size]byte]*Torrent
+}
+
+func NewRegistry() *Registry {
+	return &Registry{byHash: make(map[[sha1.Size]byte]*Torrent)}
+}
+
+// Add registers t under all of its hashes, or returns ErrDuplicate if any of
+// them is taken.
+func (r *Registry) Add(t *Torrent) error {
+	hashes := t.Metainfo.Info.InfoHashes()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, h := range hashes {
+		if _, ok := r.byHash[h]; ok {
+			return ErrDuplicate
+		}
+	}
+	for _, h := range hashes {
+		r.byHash[h] = t
+	}
+	return nil
+}
+
+// Has reports whether a torrent goes by hash.
+func (r *Registry) Has(hash [sha1.Size]byte) bool {
+	_, ok := r.Get(hash)
+	return ok
+}
+
+func (r *Registry) Get(hash [sha1.Size]byte) (*Torrent, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	t, ok := r.byHash[hash]
+	return t, ok
+}
+
+// Remove drops the torrent going by hash under all of its hashes.
+func (r *Registry) Remove(hash [sha1.Size]byte) (*Torrent, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	t, ok := r.byHash[hash]
+	if !ok {
+		return nil, false
+	}
+	for _, h := range t.Metainfo.Info.InfoHashes() {
+		delete(r.byHash, h)
+	}
+	return t, true
+}
+
+// List returns every torrent once.
+func (r *Registry) List() []*Torrent {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	seen := make(map[*Torrent]bool, len(r.byHash))
+	list := make([]*Torrent, 0, len(r.byHash))
+	for _, t := range r.byHash {
+		if !seen[t] {
+			seen[t] = true
+			list = append(list, t)
+		}
+	}
+	return list
+}
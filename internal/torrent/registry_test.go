@@ -0,0 +1,42 @@
+package torrent
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"errors"
+	"testing"
+)
+
+func TestRegistryHybridHashes(t *testing.T) {
+	data, _ := buildHybridMeta(t, nil)
+	m, err := ParseMetainfo(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("ParseMetainfo() error = %v", err)
+	}
+	v1 := m.Info.Hash
+	v2 := [sha1.Size]byte(m.Info.HashV2[:sha1.Size])
+
+	r := NewRegistry()
+	tor := &Torrent{Metainfo: m}
+	if err := r.Add(tor); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	for _, h := range [][sha1.Size]byte{v1, v2} {
+		if got, ok := r.Get(h); !ok || got != tor {
+			t.Fatalf("Get(%x) = %v, %v", h, got, ok)
+		}
+	}
+	if err := r.Add(&Torrent{Metainfo: m}); !errors.Is(err, ErrDuplicate) {
+		t.Fatalf("second Add() error = %v; want ErrDuplicate", err)
+	}
+	if n := len(r.List()); n != 1 {
+		t.Fatalf("List() has %d torrents; want 1", n)
+	}
+
+	if _, ok := r.Remove(v2); !ok {
+		t.Fatal("Remove(v2) found nothing")
+	}
+	if r.Has(v1) || r.Has(v2) {
+		t.Fatal("torrent still registered after Remove")
+	}
+}
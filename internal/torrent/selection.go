@@ -0,0 +1,232 @@
+package torrent
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/prxssh/echo/internal/bitfield"
+)
+
+// piecesForFiles returns the pieces holding any byte of the files at the
+// given indices into the info dict's file list. Indices past the end are
+// ignored, since a magnet link's so= may not match the torrent exactly.
+func (m *Metainfo) piecesForFiles(indices []int) bitfield.Bitfield {
+	wanted := bitfield.New(m.Info.NumPieces)
+
+	lengths := m.fileLengths()
+	offsets := make([]uint64, len(lengths))
+	var off uint64
+	for i, l := range lengths {
+		offsets[i] = off
+		off += l
+	}
+
+	pl := m.Info.PieceLength
+	for _, i := range indices {
+		if i < 0 || i >= len(lengths) || lengths[i] == 0 {
+			continue
+		}
+		first := offsets[i] / pl
+		last := (offsets[i] + lengths[i] - 1) / pl
+		for p := first; p <= last; p++ {
+			wanted.Set(int(p))
+		}
+	}
+
+	return wanted
+}
+
+// FilePriority says how soon a file's pieces are downloaded. FileSkip
+// leaves the file out of the download.
+type FilePriority string
+
+const (
+	FileSkip   FilePriority = "skip"
+	FileLow    FilePriority = "low"
+	FileNormal FilePriority = "normal"
+	FileHigh   FilePriority = "high"
+)
+
+func (p FilePriority) weight() int {
+	switch p {
+	case FileSkip:
+		return 0
+	case FileLow:
+		return 1
+	case FileHigh:
+		return 3
+	default:
+		return 2
+	}
+}
+
+func (p FilePriority) valid() bool {
+	switch p {
+	case FileSkip, FileLow, FileNormal, FileHigh:
+		return true
+	}
+	return false
+}
+
+// numFiles counts the files in the info dict's file list, padding included.
+func (m *Metainfo) numFiles() int {
+	if m.Info.Files == nil {
+		return 1
+	}
+	return len(*m.Info.Files)
+}
+
+// initialPriorities gives every file FileNormal, or FileSkip if it is left
+// out of selectOnly. Priorities of the wrong length are ignored.
+func (m *Metainfo) initialPriorities(
+	priorities []FilePriority,
+	selectOnly []int,
+) []FilePriority {
+	out := make([]FilePriority, m.numFiles())
+	if len(priorities) == len(out) {
+		for i, p := range priorities {
+			out[i] = p
+			if !p.valid() {
+				out[i] = FileNormal
+			}
+		}
+		return out
+	}
+
+	for i := range out {
+		out[i] = FileNormal
+		if selectOnly != nil {
+			out[i] = FileSkip
+		}
+	}
+	for _, i := range selectOnly {
+		if i >= 0 && i < len(out) {
+			out[i] = FileNormal
+		}
+	}
+	return out
+}
+
+// piecePriorities works out from the file priorities which pieces are
+// wanted and how soon. A piece shared by several files takes the highest
+// priority among them. The bitfield is empty and the priorities nil when
+// every file is wanted at the same priority.
+func (m *Metainfo) piecePriorities(
+	files []FilePriority,
+) (bitfield.Bitfield, []int) {
+	weights := make([]int, m.Info.NumPieces)
+	var selected []int
+	skipped, uniform := false, true
+
+	pl := m.Info.PieceLength
+	var off uint64
+	for i, length := range m.fileLengths() {
+		start := off
+		off += length
+		if files[i] == FileSkip {
+			skipped = true
+			continue
+		}
+		selected = append(selected, i)
+		if files[i] != FileNormal {
+			uniform = false
+		}
+		if length == 0 {
+			continue
+		}
+		for p := start / pl; p <= (off-1)/pl; p++ {
+			weights[p] = max(weights[p], files[i].weight())
+		}
+	}
+
+	var wanted bitfield.Bitfield
+	if skipped {
+		wanted = m.piecesForFiles(selected)
+	}
+	if uniform {
+		weights = nil
+	}
+	return wanted, weights
+}
+
+func (m *Metainfo) fileLengths() []uint64 {
+	if m.Info.Files == nil {
+		return []uint64{m.Size}
+	}
+	lengths := make([]uint64, 0, len(*m.Info.Files))
+	for _, f := range *m.Info.Files {
+		lengths = append(lengths, f.Length)
+	}
+	return lengths
+}
+
+// FilePriorities returns the priority of each file, indexed like the info
+// dict's file list.
+func (t *Torrent) FilePriorities() []FilePriority {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return slices.Clone(t.priorities)
+}
+
+// SetFilePriority changes the priority of the files at the given indices.
+// The new priorities take effect straight away, on a running torrent too.
+func (t *Torrent) SetFilePriority(indices []int, p FilePriority) error {
+	if !p.valid() {
+		return fmt.Errorf("torrent: unknown file priority %q", p)
+	}
+	return t.updatePriorities(indices, func(FilePriority) FilePriority {
+		return p
+	})
+}
+
+// SetFilesWanted adds the files at the given indices to the download, at
+// normal priority unless they had another, or leaves them out of it.
+func (t *Torrent) SetFilesWanted(indices []int, wanted bool) error {
+	return t.updatePriorities(indices, func(p FilePriority) FilePriority {
+		switch {
+		case !wanted:
+			return FileSkip
+		case p == FileSkip:
+			return FileNormal
+		}
+		return p
+	})
+}
+
+func (t *Torrent) updatePriorities(
+	indices []int,
+	update func(FilePriority) FilePriority,
+) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, i := range indices {
+		if i < 0 || i >= len(t.priorities) {
+			return fmt.Errorf("torrent: no file at index %d", i)
+		}
+	}
+	for _, i := range indices {
+		t.priorities[i] = update(t.priorities[i])
+	}
+	t.applyPriorities()
+	return nil
+}
+
+// applyPriorities hands the file priorities to the picker, tells the
+// storage which files it need not keep on disk, and peers and trackers
+// whether we are now a partial seed. t.mu must be held.
+func (t *Torrent) applyPriorities() {
+	wanted, weights := t.Metainfo.piecePriorities(t.priorities)
+	t.picker.SetWanted(wanted)
+	t.picker.SetPriorities(weights)
+
+	var skipped []int
+	for i, p := range t.priorities {
+		if p == FileSkip {
+			skipped = append(skipped, i)
+		}
+	}
+	t.storage.SetSkipped(skipped)
+	t.updatePartialSeed()
+}
@@ -0,0 +1,232 @@
+package torrent
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/prxssh/echo/internal/bencode"
+	"github.com/prxssh/echo/internal/peer"
+)
+
+func TestPiecePriorities(t *testing.T) {
+	m := &Metainfo{
+		Size: 40,
+		Info: &Info{
+			PieceLength: 10,
+			NumPieces:   4,
+			Files: &[]File{
+				{Length: 15}, // pieces 0-1
+				{Length: 0},
+				{Length: 20}, // pieces 1-3
+				{Length: 5},  // piece 3
+			},
+		},
+	}
+
+	const (
+		skip = FileSkip
+		low  = FileLow
+		norm = FileNormal
+		high = FileHigh
+	)
+	cases := []struct {
+		files   []FilePriority
+		wanted  []int
+		weights []int
+	}{
+		{
+			[]FilePriority{norm, norm, norm, norm},
+			nil,
+			nil,
+		},
+		{
+			[]FilePriority{high, norm, low, low},
+			nil,
+			[]int{3, 3, 1, 1},
+		},
+		{
+			[]FilePriority{skip, norm, skip, norm},
+			[]int{3},
+			nil,
+		},
+		{
+			[]FilePriority{low, skip, skip, skip},
+			[]int{0, 1},
+			[]int{1, 1, 0, 0},
+		},
+	}
+	for _, c := range cases {
+		bf, weights := m.piecePriorities(c.files)
+		var wanted []int
+		if bf.Len() > 0 {
+			wanted = []int{}
+			for i := range m.Info.NumPieces {
+				if bf.Has(i) {
+					wanted = append(wanted, i)
+				}
+			}
+		}
+		if !reflect.DeepEqual(wanted, c.wanted) {
+			t.Errorf(
+				"%v: wanted = %v; want %v",
+				c.files,
+				wanted,
+				c.wanted,
+			)
+		}
+		if !reflect.DeepEqual(weights, c.weights) {
+			t.Errorf(
+				"%v: weights = %v; want %v",
+				c.files,
+				weights,
+				c.weights,
+			)
+		}
+	}
+}
+
+func TestSetFilesWanted(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "set")
+	if err := os.Mkdir(src, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a.bin", "b.bin"} {
+		path := filepath.Join(src, name)
+		err := os.WriteFile(path, make([]byte, minPieceLength), 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	data, err := Create(CreateOpts{Path: src})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr, err := ParseTorrent(data, Opts{DownloadDir: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := tr.SelectOnly(); got != nil {
+		t.Fatalf("SelectOnly = %v; want nil", got)
+	}
+	if err := tr.SetFilePriority([]int{1}, FileHigh); err != nil {
+		t.Fatal(err)
+	}
+	if err := tr.SetFilesWanted([]int{0}, false); err != nil {
+		t.Fatal(err)
+	}
+	if got := tr.SelectOnly(); !reflect.DeepEqual(got, []int{1}) {
+		t.Errorf("SelectOnly = %v; want [1]", got)
+	}
+	// Wanting a file back leaves a priority other than skip alone.
+	if err := tr.SetFilesWanted([]int{0, 1}, true); err != nil {
+		t.Fatal(err)
+	}
+	want := []FilePriority{FileNormal, FileHigh}
+	if got := tr.FilePriorities(); !reflect.DeepEqual(got, want) {
+		t.Errorf("FilePriorities = %v; want %v", got, want)
+	}
+
+	if err := tr.SetFilePriority([]int{0}, "urgent"); err == nil {
+		t.Error("SetFilePriority with an unknown priority succeeded")
+	}
+	if err := tr.SetFilesWanted([]int{2}, false); err == nil {
+		t.Error("SetFilesWanted past the last file succeeded")
+	}
+
+	// Missing files are only a problem when they are wanted.
+	if err := tr.SetFilesWanted([]int{1}, false); err != nil {
+		t.Fatal(err)
+	}
+	if got := len(tr.storage.Missing()); got != 1 {
+		t.Errorf("Missing reports %d files; want 1", got)
+	}
+}
+
+// TestPartialSeed checks that a connected peer is told upload_only once
+// every wanted file is complete, and told otherwise once another file is
+// wanted.
+func TestPartialSeed(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "set")
+	if err := os.Mkdir(src, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	content := make([]byte, minPieceLength)
+	for i := range content {
+		content[i] = byte(i * 3)
+	}
+	for _, name := range []string{"a.bin", "b.bin"} {
+		path := filepath.Join(src, name)
+		if err := os.WriteFile(path, content, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	data, err := Create(CreateOpts{Path: src, PieceLength: minPieceLength})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr, err := ParseTorrent(data, Opts{
+		DownloadDir: t.TempDir(),
+		SelectOnly:  []int{0},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr.PeerManager.Start(t.Context())
+	defer tr.PeerManager.Stop(t.Context())
+
+	conn, far := net.Pipe()
+	defer far.Close()
+	var id [20]byte
+	copy(id[:], "-RT0001-partial-seed")
+	remote := peer.NewHandshake(tr.Metainfo.Info.Hash, id)
+	go tr.PeerManager.Accept(t.Context(), conn, remote)
+	if _, err := peer.ReadHandshake(far); err != nil {
+		t.Fatal(err)
+	}
+	reader := peer.NewReader(far, peer.DefaultMaxMessageLength, 0)
+	_ = far.SetReadDeadline(time.Now().Add(5 * time.Second))
+	// uploadOnly reads up to the next extension handshake and returns
+	// its upload_only.
+	uploadOnly := func() bool {
+		t.Helper()
+		for {
+			message, err := reader.Next()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if message == nil || message.ID != peer.MsgExtended {
+				continue
+			}
+			id, payload, ok := message.ParseExtended()
+			if !ok || id != 0 {
+				continue
+			}
+			var h struct {
+				UploadOnly int64 `bencode:"upload_only"`
+			}
+			if err := bencode.Unmarshal(payload, &h); err != nil {
+				t.Fatal(err)
+			}
+			return h.UploadOnly != 0
+		}
+	}
+	if uploadOnly() {
+		t.Fatal("upload_only with nothing downloaded")
+	}
+
+	tr.receiveBlock(0, 0, content)
+	if !uploadOnly() {
+		t.Fatal("no upload_only with the wanted file complete")
+	}
+	if err := tr.SetFilesWanted([]int{1}, true); err != nil {
+		t.Fatal(err)
+	}
+	if uploadOnly() {
+		t.Error("upload_only with another file wanted")
+	}
+}
@@ -0,0 +1,39 @@
+package torrent
+
+// Settings tune how a torrent runs; the zero value leaves everything to the
+// peer and tracker managers' defaults. Clients layer per-torrent overrides
+// on top of their own defaults and apply the result with Torrent.Apply.
+type Settings struct {
+	// MaxPeers caps connected peers; zero is the peer manager's default.
+	MaxPeers uint32 `json:"maxPeers"`
+	// DownloadLimit and UploadLimit cap transfer rates in bytes per
+	// second; zero is unlimited.
+	DownloadLimit int64 `json:"downloadLimit"`
+	UploadLimit   int64 `json:"uploadLimit"`
+	// NumWant is how many peers announces ask for; zero is the tracker
+	// manager's default.
+	NumWant uint32 `json:"numWant"`
+	// Sequential downloads pieces in order, so files can be previewed
+	// while they download.
+	Sequential bool `json:"sequential"`
+}
+
+// Apply puts s into effect, on a running torrent as well.
+func (t *Torrent) Apply(s Settings) {
+	t.PeerManager.SetMaxPeers(s.MaxPeers)
+	t.PeerManager.SetRateLimits(s.DownloadLimit, s.UploadLimit)
+	t.TrackerManager.SetNumWant(s.NumWant)
+	t.picker.SetSequential(s.Sequential)
+
+	t.mu.Lock()
+	t.settings = s
+	t.mu.Unlock()
+}
+
+// Settings returns the settings last applied.
+func (t *Torrent) Settings() Settings {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.settings
+}
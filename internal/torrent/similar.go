@@ -0,0 +1,254 @@
+package torrent
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"crypto/sha256"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// parseSimilar reads the BEP 38 'similar' and 'collections' keys, which may
+// sit in the info dict or, to leave the info-hash alone, beside it.
+func (p *parser) parseSimilar(info *Info) {
+	dicts := []map[string]any{p.data}
+	if raw, ok := p.data["info"].(map[string]any); ok {
+		dicts = append(dicts, raw)
+	}
+
+	for _, d := range dicts {
+		similar, _ := d["similar"].([]any)
+		for _, v := range similar {
+			s, ok := v.(string)
+			if !ok || len(s) != sha1.Size {
+				continue
+			}
+			hash := [sha1.Size]byte([]byte(s))
+			known := slices.Contains(info.Similar, hash)
+			if hash != info.Hash && !known {
+				info.Similar = append(info.Similar, hash)
+			}
+		}
+		collections, _ := d["collections"].([]any)
+		for _, v := range collections {
+			s, _ := v.(string)
+			if s == "" || slices.Contains(info.Collections, s) {
+				continue
+			}
+			info.Collections = append(info.Collections, s)
+		}
+	}
+}
+
+// Related reports whether either torrent names the other as similar or
+// they share a collection (BEP 38).
+func (m *Metainfo) Related(other *Metainfo) bool {
+	a, b := m.Info, other.Info
+	if slices.Contains(a.Similar, b.Hash) ||
+		slices.Contains(b.Similar, a.Hash) {
+		return true
+	}
+	for _, c := range a.Collections {
+		if slices.Contains(b.Collections, c) {
+			return true
+		}
+	}
+	return false
+}
+
+// fileSpan is one of a torrent's files and where it lies in the torrent's
+// byte stream.
+type fileSpan struct {
+	path   []string
+	offset uint64
+	length uint64
+	sha1   []byte
+	root   [sha256.Size]byte
+}
+
+// fileSpans lists the torrent's files that hold data, with their paths as
+// laid out below the download directory.
+func (m *Metainfo) fileSpans() []fileSpan {
+	var infoFiles []File
+	if m.Info.Files != nil {
+		infoFiles = *m.Info.Files
+	}
+	roots := make(map[uint64][sha256.Size]byte)
+	if m.Info.v2 != nil {
+		for _, f := range m.Info.v2.files {
+			roots[f.offset] = f.root
+		}
+	}
+
+	var spans []fileSpan
+	var off uint64
+	for i, f := range storageFiles(m) {
+		length := uint64(f.Length)
+		if !f.Padding && length > 0 {
+			s := fileSpan{
+				path:   f.Path,
+				offset: off,
+				length: length,
+				root:   roots[off],
+			}
+			if i < len(infoFiles) {
+				s.sha1 = infoFiles[i].SHA1
+			}
+			spans = append(spans, s)
+		}
+		off += length
+	}
+	return spans
+}
+
+// sameData reports whether two files are known to hold the same bytes by
+// their BEP 47 or v2 hashes.
+func (s fileSpan) sameData(o fileSpan) bool {
+	if s.length != o.length {
+		return false
+	}
+	if s.sha1 != nil && bytes.Equal(s.sha1, o.sha1) {
+		return true
+	}
+	return s.root != [sha256.Size]byte{} && s.root == o.root
+}
+
+// SharedFile is a file another torrent has complete on disk that holds the
+// data of one of this torrent's files.
+type SharedFile struct {
+	// Path is where this torrent keeps the file; Source is the other
+	// torrent's copy.
+	Path   string
+	Source string
+}
+
+// FindShared looks through others for complete files holding the same data
+// as files t has yet to write. Files match on their BEP 47 or v2 hashes or,
+// between torrents related through BEP 38, on their name and size. Every
+// piece of t lying wholly inside a candidate is checked against it first,
+// so nothing is reused that would fail verification.
+func (t *Torrent) FindShared(others []*Torrent) []SharedFile {
+	dir := t.DownloadDir()
+	var shared []SharedFile
+	for _, s := range t.Metainfo.fileSpans() {
+		path := filepath.Join(append([]string{dir}, s.path...)...)
+		if _, err := os.Lstat(path); !errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+
+		for _, o := range others {
+			src, ok := t.match(s, o)
+			if ok {
+				shared = append(shared, SharedFile{
+					Path:   path,
+					Source: src,
+				})
+				break
+			}
+		}
+	}
+	return shared
+}
+
+// match finds a file of o usable for s and returns its path.
+func (t *Torrent) match(s fileSpan, o *Torrent) (string, bool) {
+	if o == t {
+		return "", false
+	}
+	related := t.Metainfo.Related(o.Metainfo)
+	name := s.path[len(s.path)-1]
+
+	for _, of := range o.Metainfo.fileSpans() {
+		byName := related && of.length == s.length &&
+			of.path[len(of.path)-1] == name
+		strong := s.sameData(of)
+		if !byName && !strong {
+			continue
+		}
+		if !o.hasSpan(of) {
+			continue
+		}
+
+		dir := o.DownloadDir()
+		src := filepath.Join(append([]string{dir}, of.path...)...)
+		checked, ok := t.verifySpan(s, src)
+		if ok && (checked > 0 || strong) {
+			return src, true
+		}
+	}
+	return "", false
+}
+
+// hasSpan reports whether every piece overlapping s has been verified.
+func (t *Torrent) hasSpan(s fileSpan) bool {
+	pl := t.Metainfo.Info.PieceLength
+	for p := s.offset / pl; p <= (s.offset+s.length-1)/pl; p++ {
+		if !t.picker.Have(int(p)) {
+			return false
+		}
+	}
+	return true
+}
+
+// verifySpan checks the pieces of t lying wholly inside s against the file
+// at src, returning how many it checked and whether all of them passed.
+func (t *Torrent) verifySpan(s fileSpan, src string) (int, bool) {
+	f, err := os.Open(src)
+	if err != nil {
+		return 0, false
+	}
+	defer f.Close()
+
+	info := t.Metainfo.Info
+	pl := info.PieceLength
+	buf := make([]byte, pl)
+	checked := 0
+	for p := (s.offset + pl - 1) / pl; ; p++ {
+		size := t.Metainfo.PieceSize(int(p))
+		if size == 0 || p*pl+size > s.offset+s.length {
+			break
+		}
+		data := buf[:size]
+		_, err := f.ReadAt(data, int64(p*pl-s.offset))
+		if err != nil || !info.VerifyPiece(int(p), data) {
+			return checked, false
+		}
+		checked++
+	}
+	return checked, true
+}
+
+// Reuse gives t the shared file, as a hard link where the file system
+// allows and as a copy otherwise. t must not have started.
+func (t *Torrent) Reuse(f SharedFile) error {
+	if err := os.MkdirAll(filepath.Dir(f.Path), 0o755); err != nil {
+		return err
+	}
+	if os.Link(f.Source, f.Path) == nil {
+		return nil
+	}
+	return copyFile(f.Source, f.Path)
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(dst)
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,112 @@
+package torrent
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFindShared(t *testing.T) {
+	seedDir := t.TempDir()
+	set := filepath.Join(seedDir, "set")
+	if err := os.Mkdir(set, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	content := make([]byte, 3*minPieceLength+100)
+	for i := range content {
+		content[i] = byte(i * 7)
+	}
+	files := map[string][]byte{"a.bin": content, "b.bin": []byte("other")}
+	for name, data := range files {
+		path := filepath.Join(set, name)
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	whole, err := Create(CreateOpts{Path: set})
+	if err != nil {
+		t.Fatal(err)
+	}
+	single, err := Create(CreateOpts{Path: filepath.Join(set, "a.bin")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	seed, err := ParseTorrent(whole, Opts{DownloadDir: seedDir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if err := seed.Check(ctx); err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	tr, err := ParseTorrent(single, Opts{DownloadDir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	others := []*Torrent{seed, tr}
+
+	// Without a BEP 38 relation or file hashes, a name is not enough.
+	if got := tr.FindShared(others); len(got) != 0 {
+		t.Fatalf("unrelated: FindShared() = %v; want none", got)
+	}
+
+	seed.Metainfo.Info.Collections = []string{"set"}
+	tr.Metainfo.Info.Collections = []string{"set"}
+	got := tr.FindShared(others)
+	want := SharedFile{
+		Path:   filepath.Join(dir, "a.bin"),
+		Source: filepath.Join(set, "a.bin"),
+	}
+	if len(got) != 1 || got[0] != want {
+		t.Fatalf("FindShared() = %v; want [%v]", got, want)
+	}
+
+	if err := tr.Reuse(got[0]); err != nil {
+		t.Fatalf("Reuse() error = %v", err)
+	}
+	if err := tr.Check(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, left := tr.Totals(); left != 0 {
+		t.Errorf("left after reuse = %d; want 0", left)
+	}
+}
+
+func TestParseCollections(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "f")
+	if err := os.WriteFile(path, []byte("data"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	data, err := Create(CreateOpts{Path: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Outside the info dict, so the info-hash is unchanged.
+	data = append(data[:len(data)-1], "11:collectionsl3:setee"...)
+	m, err := ParseMetainfo(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := m.Info.Collections; len(c) != 1 || c[0] != "set" {
+		t.Errorf("Collections = %q; want [set]", c)
+	}
+}
+
+func TestRelated(t *testing.T) {
+	a := &Metainfo{Info: &Info{Hash: [20]byte{1}}}
+	b := &Metainfo{Info: &Info{Hash: [20]byte{2}}}
+	if a.Related(b) {
+		t.Fatal("Related() = true for unrelated torrents")
+	}
+	b.Info.Similar = [][20]byte{a.Info.Hash}
+	if !a.Related(b) || !b.Related(a) {
+		t.Error("similar torrents are not related both ways")
+	}
+}
@@ -0,0 +1,178 @@
+package torrent
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/prxssh/echo/internal/events"
+)
+
+// State is where a torrent is in its lifecycle.
+type State string
+
+const (
+	StateChecking    State = "checking"
+	StateDownloading State = "downloading"
+	StateSeeding     State = "seeding"
+	StatePaused      State = "paused"
+	StateQueued      State = "queued"
+	StateErrored     State = "errored"
+	StateMoving      State = "moving"
+)
+
+// transitions lists the states each state may move to.
+var transitions = map[State][]State{
+	StatePaused: {
+		StateChecking,
+		StateDownloading,
+		StateSeeding,
+		StateQueued,
+		StateMoving,
+	},
+	StateQueued: {
+		StateChecking,
+		StateDownloading,
+		StateSeeding,
+		StatePaused,
+		StateErrored,
+	},
+	StateChecking: {
+		StateDownloading,
+		StateSeeding,
+		StatePaused,
+		StateQueued,
+		StateErrored,
+	},
+	StateDownloading: {
+		StateChecking,
+		StateSeeding,
+		StatePaused,
+		StateQueued,
+		StateErrored,
+		StateMoving,
+	},
+	StateSeeding: {
+		StateChecking,
+		StateDownloading,
+		StatePaused,
+		StateQueued,
+		StateErrored,
+		StateMoving,
+	},
+	StateErrored: {
+		StateChecking,
+		StateDownloading,
+		StateSeeding,
+		StatePaused,
+		StateQueued,
+	},
+	StateMoving: {
+		StateDownloading,
+		StateSeeding,
+		StatePaused,
+		StateErrored,
+	},
+}
+
+// Active reports whether the torrent is connected to peers in this state.
+func (s State) Active() bool {
+	return s == StateDownloading || s == StateSeeding
+}
+
+// TopicState carries a torrent's every change of state.
+const TopicState events.Topic[StateChange] = "torrent:state"
+
+// StateChange is the payload of the "torrent:state" event.
+type StateChange struct {
+	InfoHash string `json:"infoHash"`
+	Name     string `json:"name"`
+	From     State  `json:"from"`
+	To       State  `json:"to"`
+	Error    string `json:"error,omitempty"`
+}
+
+// State returns the torrent's current state.
+func (t *Torrent) State() State {
+	t.stateMu.Lock()
+	defer t.stateMu.Unlock()
+
+	return t.state
+}
+
+// Err returns the error that put the torrent in StateErrored.
+func (t *Torrent) Err() error {
+	t.stateMu.Lock()
+	defer t.stateMu.Unlock()
+
+	return t.err
+}
+
+// SeedTime returns how long the torrent has spent seeding over its
+// lifetime.
+func (t *Torrent) SeedTime() time.Duration {
+	t.stateMu.Lock()
+	defer t.stateMu.Unlock()
+
+	d := t.seedTime
+	if t.state == StateSeeding {
+		d += time.Since(t.seedingSince)
+	}
+	return d
+}
+
+// setState moves the torrent to s and emits the transition as a
+// "torrent:state" event. err is recorded for StateErrored.
+func (t *Torrent) setState(ctx context.Context, s State, err error) error {
+	t.stateMu.Lock()
+	from := t.state
+	if from == s {
+		t.stateMu.Unlock()
+		return nil
+	}
+	if !slices.Contains(transitions[from], s) {
+		t.stateMu.Unlock()
+		return fmt.Errorf("torrent: cannot go from %s to %s", from, s)
+	}
+	now := time.Now()
+	if from == StateSeeding {
+		t.seedTime += now.Sub(t.seedingSince)
+	}
+	if s == StateSeeding {
+		t.seedingSince = now
+	}
+	t.state = s
+	t.err = err
+	t.stateMu.Unlock()
+
+	scope := t.scope()
+	change := StateChange{
+		InfoHash: scope.InfoHash,
+		Name:     scope.Name,
+		From:     from,
+		To:       s,
+	}
+	if err != nil {
+		change.Error = err.Error()
+	}
+	TopicState.Emit(events.WithTorrent(ctx, scope), change)
+	return nil
+}
+
+// scope identifies the torrent in the events it emits.
+func (t *Torrent) scope() events.Torrent {
+	return events.Torrent{
+		InfoHash: hex.EncodeToString(t.Metainfo.Info.Hash[:]),
+		Name:     t.Metainfo.Info.Name,
+	}
+}
+
+// activeState is the state a running torrent should be in.
+func (t *Torrent) activeState() State {
+	if _, _, left := t.Totals(); left == 0 {
+		return StateSeeding
+	}
+	return StateDownloading
+}
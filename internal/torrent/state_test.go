@@ -0,0 +1,88 @@
+package torrent
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prxssh/echo/internal/events"
+)
+
+func TestSetState(t *testing.T) {
+	var got []StateChange
+	ctx := events.WithSink(
+		context.Background(),
+		func(name string, data any) {
+			if name == "torrent:state" {
+				got = append(got, data.(StateChange))
+			}
+		},
+	)
+	tor := &Torrent{
+		Metainfo: &Metainfo{Info: &Info{}},
+		state:    StatePaused,
+	}
+
+	if err := tor.setState(ctx, StateDownloading, nil); err != nil {
+		t.Fatalf("paused -> downloading: %v", err)
+	}
+	if err := tor.setState(ctx, StateMoving, nil); err != nil {
+		t.Fatalf("downloading -> moving: %v", err)
+	}
+	if err := tor.setState(ctx, StateQueued, nil); err == nil {
+		t.Fatal("moving -> queued allowed; want an error")
+	}
+	fail := errors.New("disk full")
+	if err := tor.setState(ctx, StateErrored, fail); err != nil {
+		t.Fatalf("moving -> errored: %v", err)
+	}
+	if tor.State() != StateErrored || tor.Err() != fail {
+		t.Fatalf("State() = %s, Err() = %v", tor.State(), tor.Err())
+	}
+	if err := tor.setState(ctx, StateErrored, fail); err != nil {
+		t.Fatalf("errored -> errored: %v", err)
+	}
+
+	want := []StateChange{
+		{From: StatePaused, To: StateDownloading},
+		{From: StateDownloading, To: StateMoving},
+		{From: StateMoving, To: StateErrored, Error: "disk full"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("emitted %d changes; want %d", len(got), len(want))
+	}
+	for i := range want {
+		want[i].InfoHash = got[i].InfoHash
+		if got[i] != want[i] {
+			t.Errorf(
+				"change %d = %+v; want %+v",
+				i,
+				got[i],
+				want[i],
+			)
+		}
+	}
+}
+
+func TestSeedTime(t *testing.T) {
+	ctx := context.Background()
+	tor := &Torrent{
+		Metainfo: &Metainfo{Info: &Info{}},
+		state:    StatePaused,
+	}
+	tor.RestoreCounters(0, 0, time.Hour)
+
+	if err := tor.setState(ctx, StateSeeding, nil); err != nil {
+		t.Fatalf("paused -> seeding: %v", err)
+	}
+	tor.seedingSince = tor.seedingSince.Add(-time.Minute)
+	if err := tor.setState(ctx, StatePaused, nil); err != nil {
+		t.Fatalf("seeding -> paused: %v", err)
+	}
+
+	got := tor.SeedTime()
+	if got < time.Hour+time.Minute || got > time.Hour+2*time.Minute {
+		t.Fatalf("SeedTime() = %v; want about 1h1m", got)
+	}
+}
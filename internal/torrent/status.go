@@ -0,0 +1,77 @@
+package torrent
+
+import (
+	"encoding/hex"
+	"time"
+)
+
+// Status is a snapshot of a torrent's progress and transfer rates, with
+// everything a row of the torrent list shows.
+type Status struct {
+	InfoHash   string `json:"infoHash"`
+	Name       string `json:"name"`
+	Size       uint64 `json:"size"`
+	State      State  `json:"state"`
+	Error      string `json:"error,omitempty"`
+	Uploaded   uint64 `json:"uploaded"`
+	Downloaded uint64 `json:"downloaded"`
+	Left       uint64 `json:"left"`
+	// Progress is the percentage of the torrent that is complete.
+	Progress float64 `json:"progress"`
+	// DownloadRate and UploadRate are smoothed, in bytes per second.
+	DownloadRate float64 `json:"downloadRate"`
+	UploadRate   float64 `json:"uploadRate"`
+	// ETA is the estimated time left to download; see Torrent.ETA.
+	ETA time.Duration `json:"eta"`
+	// Peers counts the connected peers, Seeds those of them that have
+	// the whole torrent.
+	Peers int     `json:"peers"`
+	Seeds int     `json:"seeds"`
+	Ratio float64 `json:"ratio"`
+	// DownloadLimit and UploadLimit are the rate limits in effect, in
+	// bytes per second, after the torrent's share of the global limits.
+	// Zero is unlimited.
+	DownloadLimit int64 `json:"downloadLimit"`
+	UploadLimit   int64 `json:"uploadLimit"`
+	// QueuePosition, filled in by whoever keeps the queue, is the
+	// torrent's zero-based place in it.
+	QueuePosition int `json:"queuePosition"`
+	// RetryIn, filled in by whoever retries errored torrents, is how
+	// long until this one is started again. Zero means no retry is
+	// planned.
+	RetryIn time.Duration `json:"retryIn,omitempty"`
+}
+
+// Status returns a snapshot of the torrent.
+func (t *Torrent) Status() Status {
+	up, down, left := t.Totals()
+	downRate, upRate := t.Rates()
+	peers, seeds := t.PeerManager.Counts()
+	size := t.Metainfo.Size
+	settings := t.Settings()
+	s := Status{
+		InfoHash:      hex.EncodeToString(t.Metainfo.Info.Hash[:]),
+		Name:          t.Metainfo.Info.Name,
+		Size:          size,
+		State:         t.State(),
+		Uploaded:      up,
+		Downloaded:    down,
+		Left:          left,
+		Progress:      100,
+		DownloadRate:  downRate,
+		UploadRate:    upRate,
+		ETA:           t.ETA(),
+		Peers:         peers,
+		Seeds:         seeds,
+		Ratio:         t.Ratio(),
+		DownloadLimit: settings.DownloadLimit,
+		UploadLimit:   settings.UploadLimit,
+	}
+	if size > 0 {
+		s.Progress = float64(size-min(left, size)) / float64(size) * 100
+	}
+	if err := t.Err(); err != nil {
+		s.Error = err.Error()
+	}
+	return s
+}
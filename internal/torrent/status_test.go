@@ -0,0 +1,54 @@
+package torrent
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStatus(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "f.bin")
+	content := make([]byte, 2*minPieceLength)
+	if err := os.WriteFile(path, content, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	data, err := Create(CreateOpts{Path: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tr, err := ParseTorrent(data, Opts{DownloadDir: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := tr.Status()
+	if s.Name != "f.bin" || s.Size != uint64(len(content)) {
+		t.Errorf(
+			"Name, Size = %q, %d; want f.bin, %d",
+			s.Name,
+			s.Size,
+			len(content),
+		)
+	}
+	if s.Progress != 0 || s.Peers != 0 || s.Seeds != 0 {
+		t.Errorf(
+			"fresh torrent: Progress %v, Peers %d, Seeds %d",
+			s.Progress,
+			s.Peers,
+			s.Seeds,
+		)
+	}
+
+	tr, err = ParseTorrent(data, Opts{DownloadDir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tr.Check(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := tr.Status().Progress; got != 100 {
+		t.Errorf("complete torrent: Progress = %v; want 100", got)
+	}
+}
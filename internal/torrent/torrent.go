@@ -3,13 +3,28 @@ package torrent
 import (
 	"bytes"
 	"context"
-	"crypto/rand"
 	"crypto/sha1"
+	"log/slog"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"slices"
+	"sync"
+	"time"
 
+	"github.com/prxssh/echo/internal/bitfield"
+	"github.com/prxssh/echo/internal/dht"
+	"github.com/prxssh/echo/internal/events"
 	"github.com/prxssh/echo/internal/peer"
+	"github.com/prxssh/echo/internal/piece"
+	"github.com/prxssh/echo/internal/storage"
 	"github.com/prxssh/echo/internal/tracker"
+	"github.com/prxssh/echo/internal/udpmux"
+	"github.com/prxssh/echo/internal/webseed"
 )
 
+const dhtAnnounceInterval = 15 * time.Minute
+
 type Torrent struct {
 	PeerID         [sha1.Size]byte  `json:"-"`
 	Metainfo       *Metainfo        `json:"metainfo"`
@@ -18,10 +33,87 @@ type Torrent struct {
 	Downloaded     uint64           `json:"downloaded"`
 	Left           uint64           `json:"left"`
 	PeerManager    *peer.Manager    `json:"-"`
+
+	raw        []byte
+	dir        string
+	priorities []FilePriority
+	dht        *dht.DHT
+	// listenPort is announced to the DHT.
+	listenPort uint16
+	storage    *storage.Storage
+	picker     *piece.Picker
+	blocks     *piece.Blocks
+	webSeeds   *webseed.Downloader
+	settings   Settings
+	mu         sync.Mutex
+
+	// ioMu orders piece writes against checkpoints, so the journal is
+	// never reset while a write it records is still unflushed.
+	ioMu    sync.Mutex
+	journal *storage.Journal
+	// dirty holds the pieces the journal shows were being written when
+	// the last run ended.
+	dirty bitfield.Bitfield
+
+	downRate rateMeter
+	upRate   rateMeter
+
+	// runMu guards cancel and runCtx, which are set while the torrent
+	// runs.
+	runMu  sync.Mutex
+	cancel context.CancelFunc
+	runCtx context.Context
+
+	// stateMu guards the lifecycle state and the seeding time, which
+	// grows while the torrent is in StateSeeding.
+	stateMu      sync.Mutex
+	state        State
+	err          error
+	seedTime     time.Duration
+	seedingSince time.Time
 }
 
-func ParseTorrent(data []byte) (*Torrent, error) {
-	peerID, err := generatePeerID()
+type Opts struct {
+	// DHT, when set, is used to find peers for public torrents alongside
+	// the trackers.
+	DHT *dht.DHT
+	// DownloadDir is where the torrent's files are written.
+	DownloadDir string
+	// SelectOnly, when set, limits downloading to the files at these
+	// indices, as a magnet link's so= parameter asks (BEP 53).
+	SelectOnly []int
+	// FilePriorities, when set, gives each file its priority and takes
+	// the place of SelectOnly.
+	FilePriorities []FilePriority
+	// Proxy picks the proxy for HTTP trackers and web seeds, as an
+	// http.Transport's Proxy does.
+	Proxy func(*http.Request) (*url.URL, error)
+	// Locator looks up where peers are for display.
+	Locator peer.Locator
+	// Fingerprint is how the torrent introduces itself to peers and
+	// trackers.
+	Fingerprint Fingerprint
+	// UDP, when set, is the socket UDP trackers are talked to over,
+	// shared with the DHT.
+	UDP *udpmux.Mux
+	// ListenPort is the TCP port peers connect to us on, announced to
+	// trackers and the DHT.
+	ListenPort uint16
+	// Peer configures the connections to peers; nil uses the defaults.
+	Peer *peer.Config
+}
+
+// dialUDP opens UDP trackers' sockets on the shared one, if there is one.
+func (o Opts) dialUDP() tracker.DialUDP {
+	if o.UDP == nil {
+		return nil
+	}
+	return tracker.DialShared(o.UDP)
+}
+
+func ParseTorrent(data []byte, opts Opts) (*Torrent, error) {
+	fp := opts.Fingerprint.OrDefault()
+	peerID, err := generatePeerID(fp.PeerIDPrefix)
 	if err != nil {
 		return nil, err
 	}
@@ -31,60 +123,484 @@ func ParseTorrent(data []byte) (*Torrent, error) {
 		return nil, err
 	}
 
-	peerManager, err := peer.NewManager(
-		metainfo.Info.Hash,
-		peerID,
-		len(metainfo.Info.Pieces),
-		nil,
+	picker := piece.NewPicker(metainfo.Info.NumPieces)
+	blocks := piece.NewBlocks(
+		int64(metainfo.Info.PieceLength),
+		int64(metainfo.Size),
 	)
+	picker.TrackBlocks(blocks)
+
+	// The block worker only runs once the torrent has started, by which
+	// time torrent is set.
+	var torrent *Torrent
+	peerOpts := peer.Opts{
+		InfoHash:    metainfo.Info.Hash,
+		PeerID:      peerID,
+		Pieces:      metainfo.Info.NumPieces,
+		PieceLength: metainfo.Info.PieceLength,
+		Size:        metainfo.Size,
+		Cfg:         opts.Peer,
+		Locator:     opts.Locator,
+		Version:     fp.Version,
+		Picker:      picker,
+		Blocks:      blocks,
+		OnBlock: func(_ *peer.Peer, index, begin uint32, block []byte) {
+			torrent.receiveBlock(int(index), int64(begin), block)
+		},
+		ReadBlock: func(index, begin uint32, block []byte) error {
+			return torrent.readBlock(index, begin, block)
+		},
+	}
+	if d := opts.DHT; d != nil && !metainfo.Info.Private {
+		peerOpts.DHTPort = d.Port()
+		peerOpts.OnPort = func(addr netip.AddrPort) {
+			d.AddNode(context.Background(), addr)
+		}
+	}
+	peerManager, err := peer.NewManager(peerOpts)
 	if err != nil {
 		return nil, err
 	}
 
+	hashes := metainfo.Info.InfoHashes()
 	trackerManager, err := tracker.NewManager(
 		metainfo.AnnounceURLs,
 		tracker.Opts{
-			InfoHash: metainfo.Info.Hash,
-			PeerID:   peerID,
-			Port:     6969,
-			Left:     metainfo.Size,
-			OnPeers:  peerManager.Enqueue,
+			InfoHash:      hashes[0],
+			AltInfoHashes: hashes[1:],
+			PeerID:        peerID,
+			Port:          opts.ListenPort,
+			Left:          metainfo.Size,
+			Proxy:         opts.Proxy,
+			UserAgent:     fp.UserAgent,
+			DialUDP:       opts.dialUDP(),
+			OnPeers: func(peers []*tracker.Peer) {
+				peerManager.Enqueue(peer.SourceTracker, peers)
+			},
 		},
 	)
 	if err != nil {
 		return nil, err
 	}
 
-	torrent := &Torrent{
+	priorities := metainfo.initialPriorities(
+		opts.FilePriorities,
+		opts.SelectOnly,
+	)
+	torrent = &Torrent{
 		PeerID:         peerID,
 		Metainfo:       metainfo,
 		TrackerManager: trackerManager,
 		Left:           metainfo.Size,
 		PeerManager:    peerManager,
+		raw:            data,
+		dir:            opts.DownloadDir,
+		priorities:     priorities,
+		dht:            opts.DHT,
+		listenPort:     opts.ListenPort,
+		storage: storage.New(
+			opts.DownloadDir,
+			storageFiles(metainfo),
+		),
+		picker: picker,
+		blocks: blocks,
+		state:  StatePaused,
 	}
+	if metainfo.Info.Private {
+		trackerManager.OnRejected = torrent.rejected
+	}
+	torrent.applyPriorities()
 
+	if len(metainfo.WebSeeds) > 0 || len(metainfo.HTTPSeeds) > 0 {
+		torrent.webSeeds, err = webseed.New(webseed.Opts{
+			URLs:        metainfo.WebSeeds,
+			HTTPSeeds:   metainfo.HTTPSeeds,
+			InfoHash:    metainfo.Info.Hash,
+			Name:        metainfo.Info.Name,
+			Files:       webSeedFiles(metainfo),
+			MultiFile:   metainfo.Mode == FileModeMultiple,
+			PieceLength: int64(metainfo.Info.PieceLength),
+			Picker:      torrent.picker,
+			Verify:      metainfo.Info.VerifyPiece,
+			Write:       torrent.writePiece,
+			Client:      webSeedClient(opts.Proxy),
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
 	return torrent, nil
 }
 
-func (t *Torrent) Start(ctx context.Context) {
+// webSeedClient returns the HTTP client web seeds are fetched with, or nil
+// for the default one if there is no proxy.
+func webSeedClient(
+	proxy func(*http.Request) (*url.URL, error),
+) *http.Client {
+	if proxy == nil {
+		return nil
+	}
+	return &http.Client{Transport: &http.Transport{Proxy: proxy}}
+}
+
+// storageFiles lays out the torrent's files below the download directory;
+// multi-file torrents get a directory of their own.
+func storageFiles(m *Metainfo) []storage.File {
+	if m.Info.Files == nil {
+		return []storage.File{{
+			Path:   []string{m.Info.Name},
+			Length: int64(m.Size),
+		}}
+	}
+
+	files := make([]storage.File, 0, len(*m.Info.Files))
+	for _, f := range *m.Info.Files {
+		files = append(files, storage.File{
+			Path:    append([]string{m.Info.Name}, f.Path...),
+			Length:  int64(f.Length),
+			Padding: f.Padding,
+		})
+	}
+	return files
+}
+
+func webSeedFiles(m *Metainfo) []webseed.File {
+	if m.Info.Files == nil {
+		return []webseed.File{{
+			Path:   []string{m.Info.Name},
+			Length: int64(m.Size),
+		}}
+	}
+
+	files := make([]webseed.File, 0, len(*m.Info.Files))
+	for _, f := range *m.Info.Files {
+		files = append(files, webseed.File{
+			Path:    f.Path,
+			Length:  int64(f.Length),
+			Padding: f.Padding,
+		})
+	}
+	return files
+}
+
+// writePiece stores a verified piece and updates the transfer totals.
+func (t *Torrent) writePiece(index int, data []byte) error {
+	off := int64(index) * int64(t.Metainfo.Info.PieceLength)
+	if _, err := t.write(index, off, data); err != nil {
+		go t.fail(err)
+		return err
+	}
+	t.account(uint64(len(data)), uint64(len(data)))
+	// Done before peers hear of it, so they find it done when they ask.
+	t.picker.Done(index)
+	t.PeerManager.Have(index)
+	return nil
+}
+
+// readBlock reads a block of a complete piece for a peer that asked for it.
+func (t *Torrent) readBlock(index, begin uint32, block []byte) error {
+	off := int64(index)*int64(t.Metainfo.Info.PieceLength) + int64(begin)
+	_, err := t.storage.ReadAt(block, off)
+	return err
+}
+
+// receiveBlock stores a block a peer sent, through the journal like any
+// other write, and once its piece has every block reads the piece back to
+// verify it. A piece that fails is downloaded again from scratch.
+func (t *Torrent) receiveBlock(index int, begin int64, block []byte) {
+	if t.picker.Have(index) {
+		return
+	}
+	off := int64(index)*int64(t.Metainfo.Info.PieceLength) + begin
+	complete, err := t.write(index, off, block)
+	if err != nil {
+		go t.fail(err)
+		return
+	}
+	t.account(uint64(len(block)), 0)
+	if !complete {
+		return
+	}
+
+	data := make([]byte, t.Metainfo.PieceSize(index))
+	start := int64(index) * int64(t.Metainfo.Info.PieceLength)
+	_, err = t.storage.ReadAt(data, start)
+	if err != nil || !t.Metainfo.Info.VerifyPiece(index, data) {
+		slog.Warn(
+			"piece failed hash check",
+			slog.Int("piece", index),
+		)
+		t.blocks.Reset(index)
+		t.picker.Release(index)
+		t.PeerManager.Wake()
+		return
+	}
+	t.picker.Done(index)
+	t.account(0, uint64(len(data)))
+	t.PeerManager.Have(index)
+}
+
+// account adds downloaded bytes to the transfer totals and takes verified
+// ones off what is left, switching to seeding once nothing is.
+func (t *Torrent) account(downloaded, verified uint64) {
+	t.mu.Lock()
+	t.Downloaded += downloaded
+	t.Left -= min(t.Left, verified)
+	uploaded := t.Uploaded + t.PeerManager.Uploaded()
+	t.TrackerManager.UpdateStats(uploaded, t.Downloaded, t.Left)
+	done := t.Left == 0
+	t.mu.Unlock()
+
+	if verified > 0 {
+		t.updatePartialSeed()
+	}
+	if done {
+		t.PeerManager.SetSeeding(true)
+		t.runMu.Lock()
+		ctx := t.runCtx
+		t.runMu.Unlock()
+		if ctx != nil {
+			_ = t.setState(ctx, StateSeeding, nil)
+		}
+	}
+}
+
+// Start begins downloading and seeding, or resumes a paused torrent:
+// trackers are announced to with event=started and peers are dialed again.
+func (t *Torrent) Start(ctx context.Context) error {
+	t.runMu.Lock()
+	defer t.runMu.Unlock()
+
+	if t.cancel != nil {
+		return nil
+	}
+	if err := t.checkFiles(); err != nil {
+		_ = t.setState(ctx, StateErrored, err)
+		return err
+	}
+	state := t.activeState()
+	if err := t.setState(ctx, state, nil); err != nil {
+		return err
+	}
+	ctx = events.WithTorrent(ctx, t.scope())
+	t.runCtx = ctx
+	ctx, t.cancel = context.WithCancel(ctx)
+
+	t.PeerManager.SetSeeding(state == StateSeeding)
+
 	go t.TrackerManager.Start(ctx)
 	go t.PeerManager.Start(ctx)
+	go t.runRates(ctx)
+	go t.runPieceMap(ctx)
+	if t.webSeeds != nil {
+		go t.webSeeds.Start(ctx)
+	}
+	if t.dht != nil && !t.Metainfo.Info.Private {
+		go func() {
+			// Trackerless torrents name the nodes to join through.
+			if len(t.Metainfo.Nodes) > 0 {
+				t.dht.Bootstrap(ctx, t.Metainfo.Nodes)
+			}
+			t.runDHTAnnounce(ctx)
+		}()
+	}
+	return nil
 }
 
-func (t *Torrent) Stop(ctx context.Context) {
+// Pause disconnects every peer and tells the trackers we stopped. Progress
+// is kept, and Start resumes where the torrent left off.
+func (t *Torrent) Pause(ctx context.Context) {
+	t.halt(ctx, StatePaused, nil)
+}
+
+// Queue stops the torrent like Pause, but marks it as waiting for a slot
+// rather than stopped by the user.
+func (t *Torrent) Queue(ctx context.Context) {
+	t.halt(ctx, StateQueued, nil)
+}
+
+// fail stops a running torrent after an error it cannot recover from, such
+// as its files becoming unwritable. See Transient for which errors are
+// worth retrying.
+func (t *Torrent) fail(err error) {
+	t.runMu.Lock()
+	ctx := t.runCtx
+	t.runMu.Unlock()
+	if ctx == nil {
+		return
+	}
+
+	slog.ErrorContext(
+		ctx,
+		"torrent failed",
+		slog.String("name", t.Metainfo.Info.Name),
+		slog.String("error", err.Error()),
+	)
+	t.halt(ctx, StateErrored, err)
+}
+
+// halt stops the tracker and peer managers and moves the torrent to state.
+func (t *Torrent) halt(ctx context.Context, state State, err error) {
+	t.runMu.Lock()
+	defer t.runMu.Unlock()
+
+	if t.cancel == nil {
+		_ = t.setState(ctx, state, err)
+		return
+	}
 	t.TrackerManager.Stop(ctx)
 	t.PeerManager.Stop(ctx)
+	t.cancel()
+	t.cancel = nil
+	_ = t.setState(t.runCtx, state, err)
+	t.runCtx = nil
+}
+
+// Stop pauses the torrent, sending stopped announces within ctx, and
+// flushes and closes its files.
+func (t *Torrent) Stop(ctx context.Context) {
+	t.Pause(ctx)
+	if err := t.Checkpoint(); err != nil {
+		slog.Warn(
+			"flushing torrent files failed",
+			slog.String("name", t.Metainfo.Info.Name),
+			slog.String("error", err.Error()),
+		)
+	}
+	if err := t.storage.Close(); err != nil {
+		slog.Warn(
+			"closing torrent files failed",
+			slog.String("name", t.Metainfo.Info.Name),
+			slog.String("error", err.Error()),
+		)
+	}
+	if t.journal != nil {
+		if err := t.journal.Close(); err != nil {
+			slog.Warn(
+				"closing torrent journal failed",
+				slog.String("name", t.Metainfo.Info.Name),
+				slog.String("error", err.Error()),
+			)
+		}
+	}
 }
 
-func generatePeerID() ([sha1.Size]byte, error) {
-	var peerID [sha1.Size]byte
+// SetPartialSeed tells peers (BEP 21 upload_only) and trackers (event=paused)
+// that everything we want is complete even though the torrent as a whole is
+// not, so nobody waits on us for the rest.
+func (t *Torrent) SetPartialSeed(partial bool) {
+	t.TrackerManager.SetUploadOnly(partial)
+	t.PeerManager.SetUploadOnly(partial)
+}
+
+// updatePartialSeed makes the torrent a partial seed while every wanted
+// piece is done but others are not, and takes that back once more are
+// wanted or every piece is done.
+func (t *Torrent) updatePartialSeed() {
+	t.SetPartialSeed(t.picker.WantedComplete() && !t.picker.Complete())
+}
 
-	prefix := []byte("-EC0001-")
-	copy(peerID[:], prefix)
+// Totals returns the bytes uploaded and downloaded over the torrent's
+// lifetime, and the bytes still left.
+func (t *Torrent) Totals() (uploaded, downloaded, left uint64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
 
-	if _, err := rand.Read(peerID[len(prefix):]); err != nil {
-		return [sha1.Size]byte{}, err
+	uploaded = t.Uploaded + t.PeerManager.Uploaded()
+	return uploaded, t.Downloaded, t.Left
+}
+
+// RestoreCounters carries lifetime counters over from an earlier session.
+// It must be called before the torrent starts.
+func (t *Torrent) RestoreCounters(
+	uploaded, downloaded uint64,
+	seedTime time.Duration,
+) {
+	t.mu.Lock()
+	t.Uploaded = uploaded
+	t.Downloaded = downloaded
+	t.mu.Unlock()
+
+	t.stateMu.Lock()
+	t.seedTime = seedTime
+	t.stateMu.Unlock()
+}
+
+// Ratio returns the share ratio: bytes uploaded over bytes downloaded, or
+// over the torrent's size if nothing was downloaded, as for a torrent that
+// started out complete.
+func (t *Torrent) Ratio() float64 {
+	uploaded, downloaded, _ := t.Totals()
+	if downloaded == 0 {
+		downloaded = t.Metainfo.Size
+	}
+	if downloaded == 0 {
+		return 0
 	}
+	return float64(uploaded) / float64(downloaded)
+}
+
+// MetainfoBytes returns the .torrent the torrent was added from, or built
+// from fetched metadata for a magnet link.
+func (t *Torrent) MetainfoBytes() []byte {
+	return t.raw
+}
+
+// DownloadDir returns the directory the torrent's files are written below.
+func (t *Torrent) DownloadDir() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
 
-	return peerID, nil
+	return t.dir
+}
+
+// SelectOnly returns the indices of the files being downloaded, or nil if
+// none is skipped.
+func (t *Torrent) SelectOnly() []int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !slices.Contains(t.priorities, FileSkip) {
+		return nil
+	}
+	selected := []int{}
+	for i, p := range t.priorities {
+		if p != FileSkip {
+			selected = append(selected, i)
+		}
+	}
+	return selected
+}
+
+// EditMetainfo applies opts to the .torrent the torrent was added from.
+func (t *Torrent) EditMetainfo(opts EditOpts) ([]byte, error) {
+	return EditMetainfo(t.raw, opts)
+}
+
+// runDHTAnnounce periodically looks the torrent up in the DHT under each of
+// its info-hashes, announces us and feeds the peers it finds to the peer
+// manager.
+func (t *Torrent) runDHTAnnounce(ctx context.Context) {
+	ticker := time.NewTicker(dhtAnnounceInterval)
+	defer ticker.Stop()
+
+	for {
+		hashes := t.Metainfo.Info.InfoHashes()
+		peers := dhtPeers(ctx, t.dht, hashes, t.listenPort)
+		if len(peers) > 0 {
+			t.PeerManager.Enqueue(peer.SourceDHT, peers)
+		}
+		slog.DebugContext(
+			ctx,
+			"dht announce",
+			slog.String("name", t.Metainfo.Info.Name),
+			slog.Int("peers", len(peers)),
+		)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
 }
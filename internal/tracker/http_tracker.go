@@ -21,6 +21,8 @@ import (
 type HTTPTrackerClient struct {
 	announceURL *url.URL
 	client      *http.Client
+	// userAgent, when set, is sent with announces and scrapes.
+	userAgent string
 }
 
 const (
@@ -37,21 +39,6 @@ const (
 	paramEvent      = "event"
 )
 
-const (
-	keyFailureReason = "failure reason"
-	keyWarningMsg    = "warning message"
-	keyInterval      = "interval"
-	keyMinInterval   = "min interval"
-	keyTrackerID     = "tracker id"
-	keyComplete      = "complete"
-	keyIncomplete    = "incomplete"
-	keyPeers         = "peers"
-	keyPeers6        = "peers6"
-	keyPeerID        = "peer id"
-	keyPeerIP        = "ip"
-	keyPeerPort      = "port"
-)
-
 func NewHTTPTrackerClient(u *url.URL) (*HTTPTrackerClient, error) {
 	transport := &http.Transport{
 		MaxIdleConns:        100,
@@ -68,6 +55,13 @@ func NewHTTPTrackerClient(u *url.URL) (*HTTPTrackerClient, error) {
 	}, nil
 }
 
+// setProxy routes announces and scrapes through the proxy picked by proxy.
+func (c *HTTPTrackerClient) setProxy(
+	proxy func(*http.Request) (*url.URL, error),
+) {
+	c.client.Transport.(*http.Transport).Proxy = proxy
+}
+
 func (c *HTTPTrackerClient) URL() string {
 	return c.announceURL.String()
 }
@@ -91,6 +85,9 @@ func (c *HTTPTrackerClient) Announce(
 	if err != nil {
 		return nil, err
 	}
+	if c.userAgent != "" {
+		req.Header.Set("User-Agent", c.userAgent)
+	}
 
 	resp, err := c.client.Do(req)
 	if err != nil {
@@ -138,6 +135,9 @@ func (c *HTTPTrackerClient) Scrape(
 	if err != nil {
 		return nil, err
 	}
+	if c.userAgent != "" {
+		req.Header.Set("User-Agent", c.userAgent)
+	}
 
 	resp, err := c.client.Do(req)
 	if err != nil {
@@ -189,84 +189,88 @@ func (c *HTTPTrackerClient) buildAnnounceURL(
 	return reqURL.String()
 }
 
+type announceBody struct {
+	FailureReason  string `bencode:"failure reason"`
+	WarningMessage string `bencode:"warning message"`
+	Interval       *int64 `bencode:"interval"`
+	MinInterval    int64  `bencode:"min interval"`
+	TrackerID      string `bencode:"tracker id"`
+	Complete       int64  `bencode:"complete"`
+	Incomplete     int64  `bencode:"incomplete"`
+	// Peers come either as a compact string or as a list of dicts.
+	Peers  bencode.RawMessage `bencode:"peers"`
+	Peers6 bencode.RawMessage `bencode:"peers6"`
+}
+
+type dictPeer struct {
+	IP   string `bencode:"ip"`
+	Port int64  `bencode:"port"`
+}
+
 func parseAnnounceResponse(r io.Reader) (*AnnounceResponse, error) {
-	raw, err := bencode.NewDecoder(r).Decode()
+	data, err := io.ReadAll(r)
 	if err != nil {
+		return nil, err
+	}
+
+	var body announceBody
+	if err := bencode.Unmarshal(data, &body); err != nil {
 		return nil, fmt.Errorf(
 			"failed to unmarshal tracker response: %w",
 			err,
 		)
 	}
-	announceDict, ok := raw.(map[string]any)
-	if !ok {
-		return nil, fmt.Errorf(
-			"unexpected response type, expected dictionary, got %T",
-			raw,
-		)
-	}
 
-	if failure, ok := announceDict[keyFailureReason].(string); ok {
-		return nil, fmt.Errorf("tracker error: %s", failure)
+	if body.FailureReason != "" {
+		return nil, &FailureError{Reason: body.FailureReason}
 	}
-	if warning, ok := announceDict[keyWarningMsg].(string); ok {
-		slog.Warn("tracker warning", "message", warning)
+	if body.WarningMessage != "" {
+		slog.Warn("tracker warning", "message", body.WarningMessage)
 	}
-
-	interval, ok := asInt64(announceDict[keyInterval])
-	if !ok {
+	if body.Interval == nil {
 		return nil, fmt.Errorf("announce: missing 'interval'")
 	}
-	minInterval, _ := asInt64(announceDict[keyMinInterval])
-	complete, _ := asInt64(announceDict[keyComplete])
-	incomplete, _ := asInt64(announceDict[keyIncomplete])
-	trackerID, _ := announceDict[keyTrackerID].(string)
-	peers, err := parsePeersField(announceDict)
-	if err != nil {
-		return nil, err
-	}
-
-	return &AnnounceResponse{
-		Peers:       peers,
-		TrackerID:   trackerID,
-		Seeders:     uint32(complete),
-		Leechers:    uint32(incomplete),
-		Interval:    time.Duration(interval) * time.Second,
-		MinInterval: time.Duration(minInterval) * time.Second,
-	}, nil
-}
 
-func parsePeersField(d map[string]any) ([]*Peer, error) {
-	var out []*Peer
-
-	if v, ok := d[keyPeers]; ok {
-		ps, err := parsePeersAny(v, false)
+	var peers []*Peer
+	if body.Peers != nil {
+		ps, err := parsePeers(body.Peers, false)
 		if err != nil {
 			return nil, fmt.Errorf("parse peers: %w", err)
 		}
-		out = append(out, ps...)
+		peers = append(peers, ps...)
 	}
-	if v6, ok := d[keyPeers6]; ok {
-		ps, err := parsePeersAny(v6, true)
+	if body.Peers6 != nil {
+		ps, err := parsePeers(body.Peers6, true)
 		if err != nil {
 			return nil, fmt.Errorf("parse peers6: %w", err)
 		}
-		out = append(out, ps...)
+		peers = append(peers, ps...)
 	}
 
-	return out, nil
+	return &AnnounceResponse{
+		Peers:       peers,
+		TrackerID:   body.TrackerID,
+		Seeders:     uint32(max(body.Complete, 0)),
+		Leechers:    uint32(max(body.Incomplete, 0)),
+		Interval:    time.Duration(*body.Interval) * time.Second,
+		MinInterval: time.Duration(body.MinInterval) * time.Second,
+	}, nil
 }
 
-func parsePeersAny(v any, ipv6 bool) ([]*Peer, error) {
-	switch t := v.(type) {
-	case string:
-		return parseCompactPeers([]byte(t), ipv6)
-	case []byte:
-		return parseCompactPeers(t, ipv6)
-	case []any:
-		return parseDictPeers(t)
-	default:
-		return nil, fmt.Errorf("invalid peers type %T", v)
+func parsePeers(raw bencode.RawMessage, ipv6 bool) ([]*Peer, error) {
+	if raw[0] == 'l' {
+		var list []dictPeer
+		if err := bencode.Unmarshal(raw, &list); err != nil {
+			return nil, err
+		}
+		return parseDictPeers(list)
+	}
+
+	var compact []byte
+	if err := bencode.Unmarshal(raw, &compact); err != nil {
+		return nil, err
 	}
+	return parseCompactPeers(compact, ipv6)
 }
 
 func parseCompactPeers(b []byte, ipv6 bool) ([]*Peer, error) {
@@ -300,30 +304,18 @@ func parseCompactPeers(b []byte, ipv6 bool) ([]*Peer, error) {
 	return peers, nil
 }
 
-func parseDictPeers(list []any) ([]*Peer, error) {
+func parseDictPeers(list []dictPeer) ([]*Peer, error) {
 	peers := make([]*Peer, 0, len(list))
-	for i, it := range list {
-		m, ok := it.(map[string]any)
-		if !ok {
-			return nil, fmt.Errorf("peer[%d]: not dict (%T)", i, it)
-		}
-
-		var ip net.IP
-		if s, ok := asString(m[keyPeerIP]); ok {
-			ip = net.ParseIP(s)
-		} else if bs, ok := m[keyPeerIP].([]byte); ok {
-			ip = net.IP(bs)
-		}
+	for i, dp := range list {
+		ip := net.ParseIP(dp.IP)
 		if ip == nil {
 			return nil, fmt.Errorf("peer[%d]: invalid ip", i)
 		}
-
-		port64, ok := asInt64(m[keyPeerPort])
-		if !ok || port64 < 1 || port64 > 65535 {
+		if dp.Port < 1 || dp.Port > 65535 {
 			return nil, fmt.Errorf("peer[%d]: invalid port", i)
 		}
 
-		peers = append(peers, &Peer{IP: ip, Port: uint16(port64)})
+		peers = append(peers, &Peer{IP: ip, Port: uint16(dp.Port)})
 	}
 
 	return peers, nil
@@ -352,71 +344,47 @@ func (c *HTTPTrackerClient) buildScrapeURL(
 	return u.String(), nil
 }
 
+type scrapeBody struct {
+	FailureReason string                `bencode:"failure reason"`
+	Files         map[string]scrapeFile `bencode:"files"`
+}
+
+type scrapeFile struct {
+	Complete   int64  `bencode:"complete"`
+	Incomplete int64  `bencode:"incomplete"`
+	Downloaded int64  `bencode:"downloaded"`
+	Name       string `bencode:"name"`
+}
+
 func parseScrapeResponse(r io.Reader) (*ScrapeResponse, error) {
-	decoded, err := bencode.NewDecoder(r).Decode()
+	data, err := io.ReadAll(r)
 	if err != nil {
-		return nil, fmt.Errorf("decode scrape: %w", err)
+		return nil, err
 	}
-	root, ok := decoded.(map[string]any)
-	if !ok {
-		return nil, fmt.Errorf("scrape: not a dict (%T)", decoded)
+
+	var body scrapeBody
+	if err := bencode.Unmarshal(data, &body); err != nil {
+		return nil, fmt.Errorf("decode scrape: %w", err)
 	}
-	if failure, ok := asString(root[keyFailureReason]); ok &&
-		failure != "" {
-		return nil, fmt.Errorf("tracker error: %s", failure)
+	if body.FailureReason != "" {
+		return nil, &FailureError{Reason: body.FailureReason}
 	}
 
-	files, _ := root["files"].(map[string]any)
-	out := make(map[[sha1.Size]byte]ScrapeStats, len(files))
-	for k, v := range files {
-		fdict, ok := v.(map[string]any)
-		if !ok {
-			continue
-		}
-
+	out := make(map[[sha1.Size]byte]ScrapeStats, len(body.Files))
+	for k, f := range body.Files {
 		var ih [sha1.Size]byte
-		kb := []byte(k)
-		if len(kb) != sha1.Size {
+		if len(k) != sha1.Size {
 			continue
 		}
-		copy(ih[:], kb)
+		copy(ih[:], k)
 
-		var s ScrapeStats
-		if n, ok := asInt64(fdict["complete"]); ok && n >= 0 {
-			s.Seeders = uint32(n)
-		}
-		if n, ok := asInt64(fdict["incomplete"]); ok && n >= 0 {
-			s.Leechers = uint32(n)
-		}
-		if n, ok := asInt64(fdict["downloaded"]); ok && n >= 0 {
-			s.Completed = uint32(n)
+		out[ih] = ScrapeStats{
+			Seeders:   uint32(max(f.Complete, 0)),
+			Leechers:  uint32(max(f.Incomplete, 0)),
+			Completed: uint32(max(f.Downloaded, 0)),
+			Name:      f.Name,
 		}
-		if name, ok := asString(fdict["name"]); ok {
-			s.Name = name
-		}
-		out[ih] = s
 	}
 
 	return &ScrapeResponse{Stats: out}, nil
 }
-
-func asString(v any) (string, bool) {
-	switch t := v.(type) {
-	case string:
-		return t, true
-	case []byte:
-		return string(t), true
-	default:
-		return "", false
-	}
-}
-
-func asInt64(v any) (int64, bool) {
-	switch t := v.(type) {
-	case int64:
-		return t, true
-	case int:
-		return int64(t), true
-	}
-	return 0, false
-}
@@ -4,14 +4,18 @@ import (
 	"context"
 	"crypto/sha1"
 	"errors"
+	"fmt"
 	"log/slog"
 	"math"
 	"math/rand/v2"
+	"net/http"
+	"net/url"
 	"sync"
 	"sync/atomic"
 	"time"
 
-	"github.com/wailsapp/wails/v2/pkg/runtime"
+	"github.com/prxssh/echo/internal/events"
+	"github.com/prxssh/echo/pkg/logging"
 	"golang.org/x/sync/errgroup"
 )
 
@@ -45,17 +49,69 @@ func defaultConfig() Config {
 
 type OnPeersFunc func(peers []*Peer)
 
+// OnRejectedFunc is told when every tracker has turned the torrent down,
+// with the last failure received. It is called from its own goroutine.
+type OnRejectedFunc func(err *FailureError)
+
+// Refusal is the payload of "tracker:refused", emitted when a tracker
+// that accepted the torrent, or had not been asked yet, turns it down.
+type Refusal struct {
+	InfoHash string `json:"infoHash"`
+	Name     string `json:"name"`
+	Tracker  string `json:"tracker"`
+	Reason   string `json:"reason"`
+}
+
+// Announce is the payload of "tracker:announce", emitted for every answer
+// a tracker gives.
+type Announce struct {
+	InfoHash    string        `json:"infoHash"`
+	Name        string        `json:"name"`
+	Tracker     string        `json:"tracker"`
+	Seeders     uint32        `json:"seeders"`
+	Leechers    uint32        `json:"leechers"`
+	Interval    time.Duration `json:"interval"`
+	MinInterval time.Duration `json:"minInterval"`
+	PeersCount  int           `json:"peersCount"`
+}
+
+const (
+	TopicAnnounce events.Topic[Announce] = "tracker:announce"
+	TopicRefused  events.Topic[Refusal]  = "tracker:refused"
+)
+
 type Manager struct {
 	cfg        Config
 	trackers   []Tracker
 	port       uint16
-	infoHash   [sha1.Size]byte
+	infoHashes [][sha1.Size]byte
 	peerID     [sha1.Size]byte
 	uploaded   atomic.Uint64
 	downloaded atomic.Uint64
 	left       atomic.Uint64
+	uploadOnly atomic.Bool
 	closed     atomic.Bool
+	numWant    atomic.Uint32
 	OnPeers    OnPeersFunc
+	OnRejected OnRejectedFunc
+
+	// rejected holds the trackers whose last announce was refused.
+	rejectMu sync.Mutex
+	rejected map[string]bool
+
+	statusMu sync.Mutex
+	status   map[string]Status
+
+	// wake holds, per tracker URL, a channel closed to cut the wait
+	// before the next announce short.
+	wakeMu sync.Mutex
+	wake   map[string]chan struct{}
+
+	// swarm holds the seeder and leecher counts of the last successful
+	// announce, packed as seeders<<32 | leechers, with swarmKnown set
+	// once there has been one.
+	swarm      atomic.Uint64
+	swarmKnown atomic.Bool
 }
 
 type Opts struct {
@@ -67,15 +123,28 @@ type Opts struct {
 	Left       uint64
 	Cfg        *Config
 	OnPeers    OnPeersFunc
+	// Proxy, when set, picks the proxy for announces to HTTP trackers.
+	Proxy func(*http.Request) (*url.URL, error)
+	// UserAgent, when set, is sent to HTTP trackers in place of Go's.
+	UserAgent string
+	// DialUDP, when set, opens the sockets UDP trackers are talked to
+	// over; otherwise each has its own.
+	DialUDP DialUDP
+
+	// AltInfoHashes are announced alongside InfoHash, such as the
+	// truncated v2 hash of a hybrid torrent.
+	AltInfoHashes [][sha1.Size]byte
 }
 
 func NewManager(announceURLs []string, opts Opts) (*Manager, error) {
 	m := &Manager{
 		cfg:      defaultConfig(),
 		port:     opts.Port,
-		infoHash: opts.InfoHash,
 		peerID:   opts.PeerID,
 		trackers: make([]Tracker, 0, len(announceURLs)),
+		rejected: make(map[string]bool),
+		status:   make(map[string]Status),
+		wake:     make(map[string]chan struct{}),
 	}
 	if opts.OnPeers == nil {
 		return nil, errors.New(
@@ -87,11 +156,32 @@ func NewManager(announceURLs []string, opts Opts) (*Manager, error) {
 	if opts.Cfg != nil {
 		m.cfg = *opts.Cfg
 	}
+	m.numWant.Store(m.cfg.NumWant)
+	m.infoHashes = append(
+		[][sha1.Size]byte{opts.InfoHash},
+		opts.AltInfoHashes...,
+	)
 
 	m.UpdateStats(opts.Uploaded, opts.Downloaded, opts.Left)
 
 	for _, url := range announceURLs {
 		tracker, err := NewTracker(url)
+		if h, ok := tracker.(*HTTPTrackerClient); ok {
+			h.userAgent = opts.UserAgent
+			if opts.Proxy != nil {
+				h.setProxy(opts.Proxy)
+			}
+		}
+		if u, ok := tracker.(*UDPTrackerClient); ok && opts.DialUDP != nil {
+			u.SetDial(opts.DialUDP)
+		}
+		if errors.Is(err, ErrWebSocketTracker) {
+			slog.Debug(
+				"skipping webtorrent tracker",
+				slog.String("url", url),
+			)
+			continue
+		}
 		if err != nil {
 			slog.Warn(
 				"tracker init failed",
@@ -114,16 +204,48 @@ func (m *Manager) UpdateStats(uploaded, downloaded, left uint64) {
 	m.left.Store(left)
 }
 
+// Swarm returns the seeders and leechers reported by the last successful
+// announce to any tracker; ok is false before the first.
+func (m *Manager) Swarm() (seeders, leechers uint32, ok bool) {
+	v := m.swarm.Load()
+	return uint32(v >> 32), uint32(v), m.swarmKnown.Load()
+}
+
+// SetUploadOnly marks the torrent as a partial seed: regular announces carry
+// event=paused so trackers stop handing us out as a downloader.
+func (m *Manager) SetUploadOnly(uploadOnly bool) {
+	m.uploadOnly.Store(uploadOnly)
+}
+
+// SetNumWant changes how many peers later announces ask for; zero goes back
+// to the configured number.
+func (m *Manager) SetNumWant(n uint32) {
+	if n == 0 {
+		n = m.cfg.NumWant
+	}
+	m.numWant.Store(n)
+}
+
+// Start announces to every tracker until ctx is done, beginning with
+// event=started. After Stop it may be called again to resume.
 func (m *Manager) Start(ctx context.Context) error {
 	if len(m.trackers) == 0 {
 		return errors.New("no tracker to start")
 	}
+	m.closed.Store(false)
+	m.rejectMu.Lock()
+	clear(m.rejected)
+	m.rejectMu.Unlock()
 
 	grp, ctx := errgroup.WithContext(ctx)
 	for _, tracker := range m.trackers {
 		tracker := tracker
 
-		grp.Go(func() error { return m.runAnnounceLoop(ctx, tracker) })
+		for _, hash := range m.infoHashes {
+			grp.Go(func() error {
+				return m.runAnnounceLoop(ctx, tracker, hash)
+			})
+		}
 
 		if m.cfg.ScrapeEvery > 0 && tracker.SupportsScrape() {
 			grp.Go(
@@ -151,39 +273,47 @@ func (m *Manager) Stop(ctx context.Context) {
 	var wg sync.WaitGroup
 	for _, tracker := range m.trackers {
 		tr := tracker
-		wg.Go(func() {
-			_ = m.sendStopped(context.Background(), tr)
-		})
+		for _, hash := range m.infoHashes {
+			wg.Go(func() { _ = m.sendStopped(ctx, tr, hash) })
+		}
 	}
 	wg.Wait()
 	m.closed.Store(true)
 }
 
-func (m *Manager) runAnnounceLoop(ctx context.Context, tracker Tracker) error {
+func (m *Manager) runAnnounceLoop(
+	ctx context.Context,
+	tracker Tracker,
+	infoHash [sha1.Size]byte,
+) error {
 	startedSent, completedSent := false, false
 	interval := m.cfg.FallbackInterval
 	backoff := m.cfg.InitialBackoff
 
 	for {
 		req := &AnnounceParams{
-			InfoHash:   m.infoHash,
+			InfoHash:   infoHash,
 			PeerID:     m.peerID,
 			Port:       m.port,
 			Uploaded:   m.uploaded.Load(),
 			Downloaded: m.downloaded.Load(),
 			Left:       m.left.Load(),
-			NumWant:    m.cfg.NumWant,
+			NumWant:    m.numWant.Load(),
 		}
 		switch {
 		case !startedSent:
 			req.Event = EventStarted
 		case req.Left == 0 && !completedSent:
 			req.Event = EventCompleted
+		case m.uploadOnly.Load():
+			req.Event = EventPaused
 		default:
 			req.Event = EventNone
 		}
 
-		slog.Debug(
+		reqCtx := logging.WithRequestID(ctx)
+		slog.DebugContext(
+			reqCtx,
 			"tracker announce",
 			slog.String("url", tracker.URL()),
 			slog.String("event", req.Event.String()),
@@ -196,8 +326,10 @@ func (m *Manager) runAnnounceLoop(ctx context.Context, tracker Tracker) error {
 		)
 		resp, err := tracker.Announce(callCtx, req)
 		cancel()
+		m.noteRejection(ctx, tracker, err)
 		if err != nil {
-			slog.Warn(
+			slog.WarnContext(
+				reqCtx,
 				"announce failed",
 				slog.String("url", tracker.URL()),
 				slog.String("error", err.Error()),
@@ -209,14 +341,22 @@ func (m *Manager) runAnnounceLoop(ctx context.Context, tracker Tracker) error {
 					float64(m.cfg.MaxBackoff),
 				),
 			)
-			if err := sleepCtx(ctx, jitter(m.cfg, backoff)); err != nil {
-				_ = m.sendStopped(context.Background(), tracker)
+			wait := jitter(m.cfg, backoff)
+			m.noteStatus(tracker.URL(), nil, err, wait)
+			err = m.sleep(ctx, tracker.URL(), wait)
+			if err != nil {
+				_ = m.sendStopped(
+					context.Background(),
+					tracker,
+					infoHash,
+				)
 				return err
 			}
 			continue
 		}
 
-		slog.Debug(
+		slog.DebugContext(
+			reqCtx,
 			"announce successful",
 			slog.String("url", tracker.URL()),
 			slog.Any("interval", resp.Interval),
@@ -230,15 +370,20 @@ func (m *Manager) runAnnounceLoop(ctx context.Context, tracker Tracker) error {
 			completedSent = true
 		}
 
-		runtime.EventsEmit(ctx, "tracker:announce", map[string]any{
-			"tracker":     tracker.URL(),
-			"seeders":     resp.Seeders,
-			"leechers":    resp.Leechers,
-			"interval":    resp.Interval,
-			"minInterval": resp.MinInterval,
-			"peersCount":  len(resp.Peers),
+		scope, _ := events.TorrentOf(ctx)
+		TopicAnnounce.Emit(ctx, Announce{
+			InfoHash:    scope.InfoHash,
+			Name:        scope.Name,
+			Tracker:     tracker.URL(),
+			Seeders:     resp.Seeders,
+			Leechers:    resp.Leechers,
+			Interval:    resp.Interval,
+			MinInterval: resp.MinInterval,
+			PeersCount:  len(resp.Peers),
 		})
 
+		m.swarm.Store(uint64(resp.Seeders)<<32 | uint64(resp.Leechers))
+		m.swarmKnown.Store(true)
 		m.emitPeers(tracker.URL(), resp.Peers)
 		backoff = m.cfg.InitialBackoff
 
@@ -250,13 +395,115 @@ func (m *Manager) runAnnounceLoop(ctx context.Context, tracker Tracker) error {
 			next < resp.MinInterval {
 			next = resp.MinInterval
 		}
-		if err := sleepCtx(ctx, jitter(m.cfg, next)); err != nil {
-			_ = m.sendStopped(context.Background(), tracker)
+		wait := jitter(m.cfg, next)
+		m.noteStatus(tracker.URL(), resp, nil, wait)
+		if err := m.sleep(ctx, tracker.URL(), wait); err != nil {
+			_ = m.sendStopped(
+				context.Background(),
+				tracker,
+				infoHash,
+			)
 			return err
 		}
 	}
 }
 
+// Reannounce has every tracker announce now rather than when its interval
+// is up.
+func (m *Manager) Reannounce() {
+	for _, tr := range m.trackers {
+		m.wakeUp(tr.URL())
+	}
+}
+
+// ReannounceTracker has the tracker at url announce now.
+func (m *Manager) ReannounceTracker(url string) error {
+	for _, tr := range m.trackers {
+		if tr.URL() == url {
+			m.wakeUp(url)
+			return nil
+		}
+	}
+	return fmt.Errorf("tracker: no tracker %s", url)
+}
+
+func (m *Manager) wakeUp(url string) {
+	m.wakeMu.Lock()
+	defer m.wakeMu.Unlock()
+
+	if ch, ok := m.wake[url]; ok {
+		close(ch)
+		delete(m.wake, url)
+	}
+}
+
+// sleep waits d before the tracker's next announce, less if Reannounce
+// wakes it.
+func (m *Manager) sleep(
+	ctx context.Context,
+	url string,
+	d time.Duration,
+) error {
+	m.wakeMu.Lock()
+	ch, ok := m.wake[url]
+	if !ok {
+		ch = make(chan struct{})
+		m.wake[url] = ch
+	}
+	m.wakeMu.Unlock()
+
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	case <-ch:
+		return nil
+	}
+}
+
+// noteRejection records whether the tracker refused the last announce and
+// tells OnRejected once every tracker has. Errors other than refusals, such
+// as timeouts, leave the record as it was.
+func (m *Manager) noteRejection(
+	ctx context.Context,
+	tracker Tracker,
+	err error,
+) {
+	var failure *FailureError
+	if err != nil && !errors.As(err, &failure) {
+		return
+	}
+
+	m.rejectMu.Lock()
+	was := len(m.rejected) == len(m.trackers)
+	refused := failure != nil && !m.rejected[tracker.URL()]
+	if failure != nil {
+		m.rejected[tracker.URL()] = true
+	} else {
+		delete(m.rejected, tracker.URL())
+	}
+	now := len(m.rejected) == len(m.trackers)
+	m.rejectMu.Unlock()
+
+	if refused {
+		scope, _ := events.TorrentOf(ctx)
+		TopicRefused.Emit(ctx, Refusal{
+			InfoHash: scope.InfoHash,
+			Name:     scope.Name,
+			Tracker:  tracker.URL(),
+			Reason:   failure.Reason,
+		})
+	}
+
+	if now && !was && m.OnRejected != nil {
+		go m.OnRejected(failure)
+	}
+}
+
 func (m *Manager) runScrapeLoop(ctx context.Context, tracker Tracker) error {
 	t := time.NewTicker(m.cfg.ScrapeEvery)
 	defer t.Stop()
@@ -271,12 +518,16 @@ func (m *Manager) runScrapeLoop(ctx context.Context, tracker Tracker) error {
 	}
 }
 
-func (m *Manager) sendStopped(ctx context.Context, tracker Tracker) error {
+func (m *Manager) sendStopped(
+	ctx context.Context,
+	tracker Tracker,
+	infoHash [sha1.Size]byte,
+) error {
 	callCtx, cancel := context.WithTimeout(ctx, m.cfg.StoppedTimeout)
 	defer cancel()
 
 	_, err := tracker.Announce(callCtx, &AnnounceParams{
-		InfoHash:   m.infoHash,
+		InfoHash:   infoHash,
 		PeerID:     m.peerID,
 		Port:       m.port,
 		Uploaded:   m.uploaded.Load(),
@@ -286,7 +537,8 @@ func (m *Manager) sendStopped(ctx context.Context, tracker Tracker) error {
 		Event:      EventStopped,
 	})
 	if err != nil {
-		slog.Warn(
+		slog.WarnContext(
+			ctx,
 			"stopped announce failed",
 			slog.String("url", tracker.URL()),
 			slog.String("error", err.Error()),
@@ -334,15 +586,3 @@ func jitter(cfg Config, d time.Duration) time.Duration {
 
 	return time.Duration(lo + rand.Float64()*(hi-lo))
 }
-
-func sleepCtx(ctx context.Context, d time.Duration) error {
-	t := time.NewTimer(d)
-	defer t.Stop()
-
-	select {
-	case <-ctx.Done():
-		return ctx.Err()
-	case <-t.C:
-		return nil
-	}
-}
@@ -0,0 +1,63 @@
+package tracker
+
+import "time"
+
+// Status is how a tracker answered the last announce.
+type Status struct {
+	URL string `json:"url"`
+	// Working is set while the last announce succeeded; Error holds why
+	// it failed otherwise. Neither is set before the first announce.
+	Working  bool   `json:"working"`
+	Error    string `json:"error,omitempty"`
+	Seeders  uint32 `json:"seeders"`
+	Leechers uint32 `json:"leechers"`
+	// Peers is how many peers the last successful announce returned.
+	Peers        int       `json:"peers"`
+	LastAnnounce time.Time `json:"lastAnnounce"`
+	NextAnnounce time.Time `json:"nextAnnounce"`
+}
+
+// Statuses reports on every tracker, in announce list order.
+func (m *Manager) Statuses() []Status {
+	m.statusMu.Lock()
+	defer m.statusMu.Unlock()
+
+	out := make([]Status, 0, len(m.trackers))
+	for _, tr := range m.trackers {
+		s, ok := m.status[tr.URL()]
+		if !ok {
+			s = Status{URL: tr.URL()}
+		}
+		out = append(out, s)
+	}
+	return out
+}
+
+// noteStatus records the outcome of an announce and when the next is due.
+// Failures keep the swarm counts of the last success.
+func (m *Manager) noteStatus(
+	url string,
+	resp *AnnounceResponse,
+	err error,
+	next time.Duration,
+) {
+	m.statusMu.Lock()
+	defer m.statusMu.Unlock()
+
+	now := time.Now()
+	s := m.status[url]
+	s.URL = url
+	s.LastAnnounce = now
+	s.NextAnnounce = now.Add(next)
+	if err != nil {
+		s.Working = false
+		s.Error = err.Error()
+	} else {
+		s.Working = true
+		s.Error = ""
+		s.Seeders = resp.Seeders
+		s.Leechers = resp.Leechers
+		s.Peers = len(resp.Peers)
+	}
+	m.status[url] = s
+}
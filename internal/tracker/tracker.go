@@ -3,6 +3,7 @@ package tracker
 import (
 	"context"
 	"crypto/sha1"
+	"errors"
 	"fmt"
 	"net"
 	"net/url"
@@ -80,6 +81,8 @@ const (
 	EventStarted
 	EventStopped
 	EventCompleted
+	// EventPaused is the BEP 21 event announced by partial seeds.
+	EventPaused
 )
 
 const (
@@ -95,11 +98,30 @@ func (e Event) String() string {
 		return "started"
 	case EventStopped:
 		return "stopped"
+	case EventPaused:
+		return "paused"
 	default:
 		return "completed"
 	}
 }
 
+// ErrWebSocketTracker is returned for ws:// and wss:// announce URLs. Those are
+// WebTorrent signalling trackers, which hand out WebRTC offers rather than
+// peer addresses, and need a WebRTC transport we do not ship.
+var ErrWebSocketTracker = errors.New(
+	"tracker: websocket (webtorrent) trackers are not supported",
+)
+
+// FailureError is a tracker turning an announce down with a failure
+// reason, as opposed to not being reached at all.
+type FailureError struct {
+	Reason string
+}
+
+func (e *FailureError) Error() string {
+	return "tracker error: " + e.Reason
+}
+
 func NewTracker(announceURL string) (Tracker, error) {
 	url, err := url.Parse(announceURL)
 	if err != nil {
@@ -115,6 +137,8 @@ func NewTracker(announceURL string) (Tracker, error) {
 		return NewHTTPTrackerClient(url)
 	case "udp":
 		return NewUDPTrackerClient(url)
+	case "ws", "wss":
+		return nil, ErrWebSocketTracker
 	default:
 		return nil, fmt.Errorf(
 			"tracker: unsupported schema %q",
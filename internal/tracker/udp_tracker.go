@@ -3,15 +3,34 @@ package tracker
 import (
 	"context"
 	"crypto/rand"
+	"crypto/sha1"
 	"encoding/binary"
 	"errors"
 	"net"
+	"net/netip"
 	"net/url"
+	"slices"
 	"time"
+
+	"github.com/prxssh/echo/internal/udpmux"
 )
 
+// UDPConn is a socket connected to one tracker: a *net.UDPConn, or a
+// share of the socket the DHT listens on.
+type UDPConn interface {
+	Read(b []byte) (int, error)
+	Write(b []byte) (int, error)
+	SetDeadline(t time.Time) error
+	Close() error
+}
+
+// DialUDP opens the socket a UDP tracker at addr is talked to over.
+type DialUDP func(addr netip.AddrPort) (UDPConn, error)
+
 type UDPTrackerClient struct {
-	conn            *net.UDPConn
+	addr            netip.AddrPort
+	dial            DialUDP
+	conn            UDPConn
 	key             uint32
 	connectionID    uint64
 	connectionIDTTL time.Time
@@ -25,6 +44,9 @@ const (
 	connectionIDTTL = 60 * time.Second
 	maxRetries      = 8
 	maxUDPPacket    = 2048
+	// maxScrapeHashes is how many info-hashes fit in one scrape, keeping
+	// the request and its answer under a safe datagram size.
+	maxScrapeHashes = 74
 )
 
 const (
@@ -44,10 +66,6 @@ func NewUDPTrackerClient(u *url.URL) (*UDPTrackerClient, error) {
 	if err != nil {
 		return nil, err
 	}
-	conn, err := net.DialUDP("udp", nil, addr)
-	if err != nil {
-		return nil, err
-	}
 
 	key, err := randU32()
 	if err != nil {
@@ -58,15 +76,53 @@ func NewUDPTrackerClient(u *url.URL) (*UDPTrackerClient, error) {
 	}
 
 	return &UDPTrackerClient{
-		conn:        conn,
+		addr:        addr.AddrPort(),
+		dial:        dialOwn,
 		key:         key,
 		isIPV6:      addr.IP.To4() == nil,
 		announceURL: u.String(),
 	}, nil
 }
 
+// dialOwn gives the tracker a socket of its own.
+func dialOwn(addr netip.AddrPort) (UDPConn, error) {
+	return net.DialUDP("udp", nil, net.UDPAddrFromAddrPort(addr))
+}
+
+// DialShared talks to trackers over m's socket.
+func DialShared(m *udpmux.Mux) DialUDP {
+	return func(addr netip.AddrPort) (UDPConn, error) {
+		conn, err := m.Dial(addr)
+		if err != nil {
+			return nil, err
+		}
+		return conn, nil
+	}
+}
+
+// SetDial has the tracker talked to over what dial opens, rather than a
+// socket of its own. It must be called before the first announce.
+func (c *UDPTrackerClient) SetDial(dial DialUDP) {
+	c.dial = dial
+}
+
 func (c *UDPTrackerClient) Close() error {
-	c.conn.Close()
+	if c.conn != nil {
+		c.conn.Close()
+	}
+	return nil
+}
+
+// open dials the tracker the first time it is needed.
+func (c *UDPTrackerClient) open() error {
+	if c.conn != nil {
+		return nil
+	}
+	conn, err := c.dial(c.addr)
+	if err != nil {
+		return err
+	}
+	c.conn = conn
 	return nil
 }
 
@@ -75,13 +131,16 @@ func (c *UDPTrackerClient) URL() string {
 }
 
 func (c *UDPTrackerClient) SupportsScrape() bool {
-	return false
+	return true
 }
 
 func (c *UDPTrackerClient) Announce(
 	ctx context.Context,
 	params *AnnounceParams,
 ) (*AnnounceResponse, error) {
+	if err := c.open(); err != nil {
+		return nil, err
+	}
 	deadline, hasDeadline := ctx.Deadline()
 
 	for n := 0; n <= maxRetries; n++ {
@@ -91,21 +150,8 @@ func (c *UDPTrackerClient) Announce(
 		}
 		_ = c.conn.SetDeadline(time.Now().Add(timeout))
 
-		// Refresh connection id if expired.
-		if time.Now().After(c.connectionIDTTL) {
-			transactionID, err := randU32()
-			if err != nil {
-				continue
-			}
-			if err := c.sendConnectPacket(transactionID); err != nil {
-				continue
-			}
-			connectionID, err := c.readConnectPacket(transactionID)
-			if err != nil {
-				continue
-			}
-			c.connectionID = connectionID
-			c.connectionIDTTL = time.Now().Add(connectionIDTTL)
+		if err := c.connect(); err != nil {
+			continue
 		}
 
 		transactionID, err := randU32()
@@ -120,6 +166,10 @@ func (c *UDPTrackerClient) Announce(
 			continue
 		}
 		resp, err := c.readAnnouncePacket(transactionID)
+		var failure *FailureError
+		if errors.As(err, &failure) {
+			return nil, err
+		}
 		if err != nil {
 			// On mismatch, force re-connect next attempt.
 			if errors.Is(err, errActionMismatch) ||
@@ -134,11 +184,96 @@ func (c *UDPTrackerClient) Announce(
 	return nil, errors.New("announce failed, exhausted all attempts")
 }
 
+// Scrape asks for the swarm sizes of params.InfoHashes, maxScrapeHashes at
+// a time as BEP 15 allows.
 func (c *UDPTrackerClient) Scrape(
 	ctx context.Context,
 	params *ScrapeParams,
 ) (*ScrapeResponse, error) {
-	return nil, errors.ErrUnsupported
+	out := &ScrapeResponse{Stats: map[[sha1.Size]byte]ScrapeStats{}}
+	if params == nil {
+		return out, nil
+	}
+	if err := c.open(); err != nil {
+		return nil, err
+	}
+	chunks := slices.Chunk(params.InfoHashes, maxScrapeHashes)
+	for hashes := range chunks {
+		stats, err := c.scrape(ctx, hashes)
+		if err != nil {
+			return nil, err
+		}
+		for i, h := range hashes {
+			out.Stats[h] = stats[i]
+		}
+	}
+	return out, nil
+}
+
+func (c *UDPTrackerClient) scrape(
+	ctx context.Context,
+	hashes [][sha1.Size]byte,
+) ([]ScrapeStats, error) {
+	deadline, hasDeadline := ctx.Deadline()
+
+	for n := 0; n <= maxRetries; n++ {
+		timeout := backoffWindow(deadline, hasDeadline, n)
+		if timeout <= 0 {
+			return nil, context.DeadlineExceeded
+		}
+		_ = c.conn.SetDeadline(time.Now().Add(timeout))
+
+		if err := c.connect(); err != nil {
+			continue
+		}
+		transactionID, err := randU32()
+		if err != nil {
+			continue
+		}
+		err = c.sendScrapePacket(transactionID, hashes)
+		if err != nil {
+			continue
+		}
+		stats, err := c.readScrapePacket(
+			transactionID,
+			len(hashes),
+		)
+		var failure *FailureError
+		if errors.As(err, &failure) {
+			return nil, err
+		}
+		if err != nil {
+			if errors.Is(err, errActionMismatch) ||
+				errors.Is(err, errTransactionIDMismatch) {
+				c.connectionIDTTL = time.Time{}
+			}
+			continue
+		}
+		return stats, nil
+	}
+
+	return nil, errors.New("scrape failed, exhausted all attempts")
+}
+
+// connect fetches a new connection id once the last one has expired.
+func (c *UDPTrackerClient) connect() error {
+	if time.Now().Before(c.connectionIDTTL) {
+		return nil
+	}
+	transactionID, err := randU32()
+	if err != nil {
+		return err
+	}
+	if err := c.sendConnectPacket(transactionID); err != nil {
+		return err
+	}
+	connectionID, err := c.readConnectPacket(transactionID)
+	if err != nil {
+		return err
+	}
+	c.connectionID = connectionID
+	c.connectionIDTTL = time.Now().Add(connectionIDTTL)
+	return nil
 }
 
 func (c *UDPTrackerClient) sendConnectPacket(transactionID uint32) error {
@@ -197,7 +332,7 @@ func (c *UDPTrackerClient) sendAnnouncePacket(
 	binary.BigEndian.PutUint64(packet[56:64], params.Downloaded)
 	binary.BigEndian.PutUint64(packet[64:72], params.Left)
 	binary.BigEndian.PutUint64(packet[72:80], params.Uploaded)
-	binary.BigEndian.PutUint32(packet[80:84], uint32(params.Event))
+	binary.BigEndian.PutUint32(packet[80:84], udpEvent(params.Event))
 	binary.BigEndian.PutUint32(packet[84:88], 0)
 	binary.BigEndian.PutUint32(packet[88:92], c.key)
 	binary.BigEndian.PutUint32(packet[92:96], params.NumWant)
@@ -223,7 +358,7 @@ func (c *UDPTrackerClient) readAnnouncePacket(
 
 	action := binary.BigEndian.Uint32(packet[0:4])
 	if action == actionError {
-		return nil, errors.New(string(packet[8:nread]))
+		return nil, &FailureError{Reason: string(packet[8:nread])}
 	}
 	if action != actionAnnounce {
 		return nil, errActionMismatch
@@ -267,6 +402,80 @@ func (c *UDPTrackerClient) readAnnouncePacket(
 	}, nil
 }
 
+func (c *UDPTrackerClient) sendScrapePacket(
+	transactionID uint32,
+	hashes [][sha1.Size]byte,
+) error {
+	packet := make([]byte, 16, 16+len(hashes)*sha1.Size)
+	binary.BigEndian.PutUint64(packet[0:8], c.connectionID)
+	binary.BigEndian.PutUint32(packet[8:12], actionScrape)
+	binary.BigEndian.PutUint32(packet[12:16], transactionID)
+	for _, h := range hashes {
+		packet = append(packet, h[:]...)
+	}
+
+	_, err := c.conn.Write(packet)
+	return err
+}
+
+// readScrapePacket reads the stats of the n info-hashes scraped, in the
+// order they were asked for.
+func (c *UDPTrackerClient) readScrapePacket(
+	transactionID uint32,
+	n int,
+) ([]ScrapeStats, error) {
+	packet := make([]byte, maxUDPPacket)
+	nread, err := c.conn.Read(packet)
+	if err != nil {
+		return nil, err
+	}
+	if nread < 8 {
+		return nil, errors.New("scrape resp too short")
+	}
+
+	action := binary.BigEndian.Uint32(packet[0:4])
+	if action == actionError {
+		return nil, &FailureError{Reason: string(packet[8:nread])}
+	}
+	if action != actionScrape {
+		return nil, errActionMismatch
+	}
+	receivedTransactionID := binary.BigEndian.Uint32(packet[4:8])
+	if receivedTransactionID != transactionID {
+		return nil, errTransactionIDMismatch
+	}
+
+	body := packet[8:nread]
+	if len(body) < n*12 {
+		return nil, errors.New("scrape resp too short")
+	}
+	stats := make([]ScrapeStats, n)
+	for i := range stats {
+		entry := body[i*12 : i*12+12]
+		stats[i] = ScrapeStats{
+			Seeders:   binary.BigEndian.Uint32(entry[0:4]),
+			Completed: binary.BigEndian.Uint32(entry[4:8]),
+			Leechers:  binary.BigEndian.Uint32(entry[8:12]),
+		}
+	}
+	return stats, nil
+}
+
+// udpEvent maps an Event onto the BEP 15 wire values, which are ordered
+// differently from the HTTP ones and have no equivalent for paused.
+func udpEvent(e Event) uint32 {
+	switch e {
+	case EventCompleted:
+		return 1
+	case EventStarted:
+		return 2
+	case EventStopped:
+		return 3
+	default:
+		return 0
+	}
+}
+
 func randU32() (uint32, error) {
 	var b [4]byte
 
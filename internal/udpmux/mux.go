@@ -0,0 +1,369 @@
+// Package udpmux shares one UDP socket between the DHT, uTP and UDP
+// trackers, so that all of them use the port the client announces, as
+// NATs that map one port and trackers that check the announced one expect.
+// Each gets a Conn of its own, and incoming packets are told apart by what
+// they look like and who they come from.
+package udpmux
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/netip"
+	"os"
+	"sync"
+	"time"
+)
+
+// maxPacket bounds the datagrams read; none of the protocols sends larger.
+const maxPacket = 4096
+
+// queueLen is how many packets a Conn holds unread before dropping more,
+// as a full socket buffer would.
+const queueLen = 256
+
+// Proto is a protocol sharing the socket.
+type Proto int
+
+const (
+	DHT Proto = iota
+	UTP
+	Tracker
+	numProtos
+)
+
+var ErrClosed = errors.New("udpmux: closed")
+
+// transaction is an outstanding UDP tracker request, whose answer carries
+// its transaction ID back.
+type transaction struct {
+	addr netip.AddrPort
+	id   uint32
+}
+
+type Mux struct {
+	conn *net.UDPConn
+
+	mu        sync.Mutex
+	listeners [numProtos]*Conn
+	pending   map[transaction]*Conn
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+// Listen opens the socket on port, zero picking a free one, and starts
+// reading from it.
+func Listen(port uint16) (*Mux, error) {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{Port: int(port)})
+	if err != nil {
+		return nil, fmt.Errorf("udpmux: listen: %w", err)
+	}
+	m := &Mux{
+		conn:    conn,
+		pending: make(map[transaction]*Conn),
+		done:    make(chan struct{}),
+	}
+	m.wg.Go(m.readLoop)
+	return m, nil
+}
+
+// Port returns the port the socket is bound to.
+func (m *Mux) Port() uint16 {
+	return uint16(m.conn.LocalAddr().(*net.UDPAddr).Port)
+}
+
+// Close closes the socket and every Conn on it.
+func (m *Mux) Close() error {
+	select {
+	case <-m.done:
+		return nil
+	default:
+	}
+	close(m.done)
+	err := m.conn.Close()
+	m.wg.Wait()
+	return err
+}
+
+// Listen returns the Conn the packets of proto, DHT or UTP, arrive on,
+// replacing any taken before.
+func (m *Mux) Listen(proto Proto) *Conn {
+	c := newConn(m, proto, netip.AddrPort{})
+	m.mu.Lock()
+	m.listeners[proto] = c
+	m.mu.Unlock()
+	return c
+}
+
+// Dial returns a Conn talking to the UDP tracker at addr. Answers reach it
+// by the transaction ID of the request it wrote last, so any number of
+// Conns can talk to the same tracker.
+func (m *Mux) Dial(addr netip.AddrPort) (*Conn, error) {
+	select {
+	case <-m.done:
+		return nil, ErrClosed
+	default:
+	}
+	return newConn(m, Tracker, unmap(addr)), nil
+}
+
+func (m *Mux) readLoop() {
+	buf := make([]byte, maxPacket)
+	for {
+		n, from, err := m.conn.ReadFromUDPAddrPort(buf)
+		if err != nil {
+			select {
+			case <-m.done:
+				return
+			default:
+			}
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			slog.Debug(
+				"udp read failed",
+				slog.String("error", err.Error()),
+			)
+			continue
+		}
+		from = unmap(from)
+		packet := append([]byte(nil), buf[:n]...)
+		if c := m.route(from, packet); c != nil {
+			c.deliver(packet, from)
+		}
+	}
+}
+
+// route picks the Conn a packet from addr goes to: the tracker Conn whose
+// transaction it answers, the DHT's for a bencoded dictionary, uTP's for
+// what has a uTP header, or none.
+func (m *Mux) route(from netip.AddrPort, packet []byte) *Conn {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if id, ok := trackerTransaction(packet); ok {
+		tx := transaction{addr: from, id: id}
+		if c, ok := m.pending[tx]; ok {
+			delete(m.pending, tx)
+			return c
+		}
+	}
+	switch {
+	case isDHT(packet):
+		return m.listeners[DHT]
+	case isUTP(packet):
+		return m.listeners[UTP]
+	}
+	return nil
+}
+
+// expect routes the answer to the tracker request c is writing to it,
+// forgetting the one before, which is no longer waited for.
+func (m *Mux) expect(c *Conn, id uint32) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if c.pending != nil {
+		delete(m.pending, *c.pending)
+	}
+	tx := transaction{addr: c.remote, id: id}
+	m.pending[tx] = c
+	c.pending = &tx
+}
+
+// forget drops c's routes once it is closed.
+func (m *Mux) forget(c *Conn) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if c.pending != nil {
+		delete(m.pending, *c.pending)
+		c.pending = nil
+	}
+	if m.listeners[c.proto] == c {
+		m.listeners[c.proto] = nil
+	}
+}
+
+// isDHT reports whether packet is a KRPC message, which is a bencoded
+// dictionary.
+func isDHT(packet []byte) bool {
+	return len(packet) > 0 && packet[0] == 'd'
+}
+
+// isUTP reports whether packet starts with a uTP header (BEP 29): version
+// 1 and a type from ST_DATA to ST_SYN in the first byte, and 20 bytes in
+// all.
+func isUTP(packet []byte) bool {
+	return len(packet) >= 20 && packet[0]&0x0f == 1 && packet[0]>>4 <= 4
+}
+
+// trackerTransaction returns the transaction ID of what could be a UDP
+// tracker's answer (BEP 15): an action from connect to error, then the ID.
+func trackerTransaction(packet []byte) (uint32, bool) {
+	if len(packet) < 8 || packet[0] != 0 || packet[1] != 0 ||
+		packet[2] != 0 || packet[3] > 3 {
+		return 0, false
+	}
+	id := uint32(packet[4])<<24 | uint32(packet[5])<<16 |
+		uint32(packet[6])<<8 | uint32(packet[7])
+	return id, true
+}
+
+// requestTransaction returns the transaction ID of a UDP tracker request,
+// which follows its connection ID and action.
+func requestTransaction(packet []byte) (uint32, bool) {
+	if len(packet) < 16 {
+		return 0, false
+	}
+	id := uint32(packet[12])<<24 | uint32(packet[13])<<16 |
+		uint32(packet[14])<<8 | uint32(packet[15])
+	return id, true
+}
+
+func unmap(addr netip.AddrPort) netip.AddrPort {
+	return netip.AddrPortFrom(addr.Addr().Unmap(), addr.Port())
+}
+
+type packet struct {
+	data []byte
+	from netip.AddrPort
+}
+
+// Conn is one protocol's share of the socket. It reads what the Mux routes
+// to it and writes straight to the socket. A dialed Conn, a tracker's, has
+// a remote address it reads and writes with Read and Write.
+type Conn struct {
+	mux    *Mux
+	proto  Proto
+	remote netip.AddrPort
+	// pending is the tracker transaction being waited on; Mux.mu
+	// guards it.
+	pending *transaction
+
+	in        chan packet
+	closed    chan struct{}
+	closeOnce sync.Once
+
+	deadlineMu sync.Mutex
+	deadline   time.Time
+	// wake is closed and replaced when the deadline changes, waking the
+	// reader to look at it again.
+	wake chan struct{}
+}
+
+func newConn(m *Mux, proto Proto, remote netip.AddrPort) *Conn {
+	return &Conn{
+		mux:    m,
+		proto:  proto,
+		remote: remote,
+		in:     make(chan packet, queueLen),
+		closed: make(chan struct{}),
+		wake:   make(chan struct{}),
+	}
+}
+
+// deliver queues a packet for reading, dropping it if the queue is full.
+func (c *Conn) deliver(data []byte, from netip.AddrPort) {
+	select {
+	case c.in <- packet{data: data, from: from}:
+	default:
+	}
+}
+
+// ReadFromUDPAddrPort reads the next packet routed to c.
+func (c *Conn) ReadFromUDPAddrPort(b []byte) (int, netip.AddrPort, error) {
+	for {
+		select {
+		case <-c.closed:
+			return 0, netip.AddrPort{}, net.ErrClosed
+		default:
+		}
+		c.deadlineMu.Lock()
+		deadline, wake := c.deadline, c.wake
+		c.deadlineMu.Unlock()
+
+		var expired <-chan time.Time
+		if !deadline.IsZero() {
+			d := time.Until(deadline)
+			if d <= 0 {
+				err := os.ErrDeadlineExceeded
+				return 0, netip.AddrPort{}, err
+			}
+			expired = time.After(d)
+		}
+
+		select {
+		case p := <-c.in:
+			return copy(b, p.data), p.from, nil
+		case <-c.closed:
+			return 0, netip.AddrPort{}, net.ErrClosed
+		case <-c.mux.done:
+			return 0, netip.AddrPort{}, net.ErrClosed
+		case <-expired:
+			return 0, netip.AddrPort{}, os.ErrDeadlineExceeded
+		case <-wake:
+		}
+	}
+}
+
+func (c *Conn) WriteToUDPAddrPort(
+	b []byte,
+	addr netip.AddrPort,
+) (int, error) {
+	select {
+	case <-c.closed:
+		return 0, net.ErrClosed
+	default:
+	}
+	return c.mux.conn.WriteToUDPAddrPort(b, addr)
+}
+
+// Read reads the next answer from a dialed Conn's tracker.
+func (c *Conn) Read(b []byte) (int, error) {
+	n, _, err := c.ReadFromUDPAddrPort(b)
+	return n, err
+}
+
+// Write sends a request to a dialed Conn's tracker, routing the answer
+// with its transaction ID back to c.
+func (c *Conn) Write(b []byte) (int, error) {
+	if !c.remote.IsValid() {
+		return 0, errors.New("udpmux: write on a conn not dialed")
+	}
+	if id, ok := requestTransaction(b); ok {
+		c.mux.expect(c, id)
+	}
+	return c.WriteToUDPAddrPort(b, c.remote)
+}
+
+// LocalAddr is the shared socket's address.
+func (c *Conn) LocalAddr() net.Addr {
+	return c.mux.conn.LocalAddr()
+}
+
+// SetDeadline bounds reads; writes go straight out and never wait.
+func (c *Conn) SetDeadline(t time.Time) error {
+	return c.SetReadDeadline(t)
+}
+
+func (c *Conn) SetReadDeadline(t time.Time) error {
+	c.deadlineMu.Lock()
+	defer c.deadlineMu.Unlock()
+
+	c.deadline = t
+	close(c.wake)
+	c.wake = make(chan struct{})
+	return nil
+}
+
+// Close stops routing packets to c, leaving the socket open for the rest.
+func (c *Conn) Close() error {
+	c.closeOnce.Do(func() {
+		close(c.closed)
+		c.mux.forget(c)
+	})
+	return nil
+}
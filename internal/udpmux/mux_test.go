@@ -0,0 +1,122 @@
+package udpmux
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+	"net/netip"
+	"os"
+	"testing"
+	"time"
+)
+
+func listen(t *testing.T) *Mux {
+	t.Helper()
+	m, err := Listen(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { m.Close() })
+	return m
+}
+
+func loopback(port uint16) netip.AddrPort {
+	return netip.AddrPortFrom(netip.MustParseAddr("127.0.0.1"), port)
+}
+
+func listenPeer(t *testing.T) *net.UDPConn {
+	t.Helper()
+	conn, err := net.ListenUDP("udp", net.UDPAddrFromAddrPort(loopback(0)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func read(t *testing.T, c *Conn) []byte {
+	t.Helper()
+	c.SetReadDeadline(time.Now().Add(2 * time.Second))
+	buf := make([]byte, maxPacket)
+	n, _, err := c.ReadFromUDPAddrPort(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf[:n]
+}
+
+func TestRouteByKind(t *testing.T) {
+	m := listen(t)
+	dht := m.Listen(DHT)
+	utp := m.Listen(UTP)
+
+	peer := listenPeer(t)
+	to := net.UDPAddrFromAddrPort(loopback(m.Port()))
+
+	syn := make([]byte, 20)
+	syn[0] = 4<<4 | 1
+	peer.WriteToUDP([]byte("d1:y1:qe"), to)
+	peer.WriteToUDP(syn, to)
+
+	if got := read(t, dht); string(got) != "d1:y1:qe" {
+		t.Errorf("dht read %q", got)
+	}
+	if got := read(t, utp); got[0] != syn[0] {
+		t.Errorf("utp read %x", got)
+	}
+}
+
+func TestTrackerTransactions(t *testing.T) {
+	m := listen(t)
+	tracker := listenPeer(t)
+	addr := tracker.LocalAddr().(*net.UDPAddr).AddrPort()
+
+	// Two conns to the same tracker get the answers to their own
+	// requests.
+	a, _ := m.Dial(addr)
+	b, _ := m.Dial(addr)
+	request := func(c *Conn, id uint32) {
+		packet := make([]byte, 16)
+		binary.BigEndian.PutUint32(packet[12:16], id)
+		if _, err := c.Write(packet); err != nil {
+			t.Fatal(err)
+		}
+	}
+	request(a, 1)
+	request(b, 2)
+
+	buf := make([]byte, 64)
+	for range 2 {
+		tracker.SetReadDeadline(time.Now().Add(2 * time.Second))
+		n, from, err := tracker.ReadFromUDPAddrPort(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		answer := make([]byte, 8)
+		copy(answer[4:], buf[12:n])
+		tracker.WriteToUDPAddrPort(answer, from)
+	}
+
+	if got := binary.BigEndian.Uint32(read(t, a)[4:]); got != 1 {
+		t.Errorf("a read transaction %d", got)
+	}
+	if got := binary.BigEndian.Uint32(read(t, b)[4:]); got != 2 {
+		t.Errorf("b read transaction %d", got)
+	}
+}
+
+func TestDeadline(t *testing.T) {
+	m := listen(t)
+	c := m.Listen(DHT)
+	c.SetReadDeadline(time.Now().Add(10 * time.Millisecond))
+	_, _, err := c.ReadFromUDPAddrPort(make([]byte, 8))
+	if !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Fatalf("err = %v, want deadline exceeded", err)
+	}
+
+	c.Close()
+	_, _, err = c.ReadFromUDPAddrPort(make([]byte, 8))
+	if !errors.Is(err, net.ErrClosed) {
+		t.Fatalf("err = %v after close", err)
+	}
+}
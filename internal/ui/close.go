@@ -0,0 +1,164 @@
+package ui
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/prxssh/echo/internal/torrent"
+	"github.com/prxssh/echo/pkg/echo"
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// Title is the window's title while it is in the foreground.
+const Title = "Echo - BitTorrent Client & Search Engine"
+
+// backgroundTitleInterval is how often the title of a window running in
+// the background is refreshed with the transfer status.
+const backgroundTitleInterval = 2 * time.Second
+
+// CloseAction says what closing the window does while torrents are
+// transferring. With nothing transferring, the app always quits.
+type CloseAction string
+
+const (
+	// CloseAsk asks the frontend, with a "app:close-requested" event,
+	// which of the others to do.
+	CloseAsk CloseAction = "ask"
+	// CloseQuit stops the engine and exits.
+	CloseQuit CloseAction = "quit"
+	// CloseBackground minimises the window and keeps the engine running,
+	// with the transfer status in the window's title.
+	CloseBackground CloseAction = "background"
+)
+
+// closeRequest is the payload of "app:close-requested".
+type closeRequest struct {
+	Downloading int `json:"downloading"`
+	Seeding     int `json:"seeding"`
+}
+
+// SetCloseAction changes what closing the window does; see CloseAction.
+func (ui *UI) SetCloseAction(action CloseAction) error {
+	switch action {
+	case CloseAsk, CloseQuit, CloseBackground:
+	default:
+		return fmt.Errorf("ui: unknown close action %q", action)
+	}
+
+	ui.closeMu.Lock()
+	defer ui.closeMu.Unlock()
+	ui.closeAction = action
+	return nil
+}
+
+// BeforeClose runs when the window is asked to close, and reports whether
+// to keep it open.
+func (ui *UI) BeforeClose() (prevent bool) {
+	ui.closeMu.Lock()
+	action, quitting := ui.closeAction, ui.quitting
+	ui.closeMu.Unlock()
+
+	req := ui.transfers()
+	if quitting || req.Downloading+req.Seeding == 0 {
+		return false
+	}
+	switch action {
+	case CloseQuit:
+		return false
+	case CloseBackground:
+		ui.RunInBackground()
+	default:
+		runtime.EventsEmit(ui.ctx, "app:close-requested", req)
+	}
+	return true
+}
+
+// Quit stops the engine and exits without asking.
+func (ui *UI) Quit() {
+	ui.closeMu.Lock()
+	ui.quitting = true
+	ui.closeMu.Unlock()
+
+	runtime.Quit(ui.ctx)
+}
+
+// RunInBackground minimises the window, leaving the engine running. Until
+// the window is brought back its title shows how the transfers are doing.
+func (ui *UI) RunInBackground() {
+	ui.closeMu.Lock()
+	running := ui.background
+	ui.background = true
+	ui.closeMu.Unlock()
+
+	runtime.WindowMinimise(ui.ctx)
+	if !running {
+		go ui.runBackgroundTitle()
+	}
+}
+
+func (ui *UI) runBackgroundTitle() {
+	t := time.NewTicker(backgroundTitleInterval)
+	defer t.Stop()
+
+	for {
+		runtime.WindowSetTitle(ui.ctx, ui.statusTitle())
+		select {
+		case <-ui.ctx.Done():
+			return
+		case <-t.C:
+		}
+		if !runtime.WindowIsMinimised(ui.ctx) {
+			break
+		}
+	}
+
+	ui.closeMu.Lock()
+	ui.background = false
+	ui.closeMu.Unlock()
+	runtime.WindowSetTitle(ui.ctx, Title)
+}
+
+// transfers counts the torrents downloading and seeding.
+func (ui *UI) transfers() closeRequest {
+	var req closeRequest
+	for _, t := range ui.client.List() {
+		switch t.State() {
+		case torrent.StateDownloading:
+			req.Downloading++
+		case torrent.StateSeeding:
+			req.Seeding++
+		}
+	}
+	return req
+}
+
+// statusTitle sums the transfers up for the title of a window running in
+// the background.
+func (ui *UI) statusTitle() string {
+	req := ui.transfers()
+	title := fmt.Sprintf(
+		"Echo - %d downloading, %d seeding",
+		req.Downloading,
+		req.Seeding,
+	)
+	speeds, _ := ui.client.SessionSpeeds(echo.SpeedRecent)
+	if n := len(speeds); n > 0 {
+		last := speeds[n-1]
+		title += fmt.Sprintf(
+			" • ↓ %s ↑ %s",
+			formatRate(last.Download),
+			formatRate(last.Upload),
+		)
+	}
+	return title
+}
+
+func formatRate(bytesPerSec float64) string {
+	units := []string{"B/s", "KiB/s", "MiB/s", "GiB/s"}
+	i := 0
+	for bytesPerSec >= 1024 && i < len(units)-1 {
+		bytesPerSec /= 1024
+		i++
+	}
+	return fmt.Sprintf("%.1f %s", bytesPerSec, units[i])
+}
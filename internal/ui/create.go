@@ -0,0 +1,123 @@
+package ui
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+
+	"github.com/prxssh/echo/internal/torrent"
+	"github.com/prxssh/echo/pkg/echo"
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// CreatedTorrent sums up the torrent CreateTorrent made, which is kept for
+// SaveCreatedTorrent and SeedCreatedTorrent.
+type CreatedTorrent struct {
+	Name        string `json:"name"`
+	InfoHash    string `json:"infoHash"`
+	Size        uint64 `json:"size"`
+	PieceLength uint64 `json:"pieceLength"`
+	Pieces      int    `json:"pieces"`
+}
+
+// ChooseCreateSource asks for the file, or the folder, to make a torrent
+// from. It returns "" if the dialog is cancelled.
+func (ui *UI) ChooseCreateSource(folder bool) (string, error) {
+	if folder {
+		return runtime.OpenDirectoryDialog(
+			ui.ctx,
+			runtime.OpenDialogOptions{Title: "Folder to share"},
+		)
+	}
+	return runtime.OpenFileDialog(
+		ui.ctx,
+		runtime.OpenDialogOptions{Title: "File to share"},
+	)
+}
+
+// CreateTorrent hashes the files and keeps the resulting torrent. Progress
+// is pushed as "create:progress" events. Starting another, or
+// CancelCreate, stops it.
+func (ui *UI) CreateTorrent(opts echo.CreateOptions) (CreatedTorrent, error) {
+	ctx, cancel := context.WithCancel(ui.ctx)
+	defer cancel()
+
+	ui.createMu.Lock()
+	if ui.createCancel != nil {
+		ui.createCancel()
+	}
+	ui.createCancel = cancel
+	ui.created, ui.createdPath = nil, ""
+	ui.createMu.Unlock()
+
+	data, err := ui.client.CreateTorrent(ctx, opts)
+	if err != nil {
+		return CreatedTorrent{}, err
+	}
+	m, err := torrent.ParseMetainfo(bytes.NewReader(data))
+	if err != nil {
+		return CreatedTorrent{}, err
+	}
+
+	ui.createMu.Lock()
+	defer ui.createMu.Unlock()
+	if ctx.Err() != nil {
+		return CreatedTorrent{}, ctx.Err()
+	}
+	ui.created, ui.createdPath = data, opts.Path
+	return CreatedTorrent{
+		Name:        m.Info.Name,
+		InfoHash:    hex.EncodeToString(m.Info.Hash[:]),
+		Size:        m.Size,
+		PieceLength: m.Info.PieceLength,
+		Pieces:      m.Info.NumPieces,
+	}, nil
+}
+
+// CancelCreate stops the torrent being hashed, if any.
+func (ui *UI) CancelCreate() {
+	ui.createMu.Lock()
+	defer ui.createMu.Unlock()
+
+	if ui.createCancel != nil {
+		ui.createCancel()
+		ui.createCancel = nil
+	}
+}
+
+// SaveCreatedTorrent writes the torrent CreateTorrent made to a path picked
+// in a save dialog. It returns the path, or "" if the dialog was cancelled.
+func (ui *UI) SaveCreatedTorrent() (string, error) {
+	data, _, err := ui.createdTorrent()
+	if err != nil {
+		return "", err
+	}
+	m, err := torrent.ParseMetainfo(bytes.NewReader(data))
+	if err != nil {
+		return "", err
+	}
+
+	return ui.saveTorrentAs(m.Info.Name, data)
+}
+
+// SeedCreatedTorrent adds the torrent CreateTorrent made, seeding its files
+// from where they are once they have been checked.
+func (ui *UI) SeedCreatedTorrent(opts echo.AddOptions) (*echo.Torrent, error) {
+	data, path, err := ui.createdTorrent()
+	if err != nil {
+		return nil, err
+	}
+
+	return ui.client.SeedTorrent(data, path, opts)
+}
+
+func (ui *UI) createdTorrent() ([]byte, string, error) {
+	ui.createMu.Lock()
+	defer ui.createMu.Unlock()
+
+	if ui.created == nil {
+		return nil, "", errors.New("ui: no torrent has been created")
+	}
+	return ui.created, ui.createdPath, nil
+}
@@ -0,0 +1,108 @@
+package ui
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/prxssh/echo/internal/torrent"
+	"github.com/prxssh/echo/pkg/echo"
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// maxDroppedText caps how much of a dropped file that isn't a .torrent is
+// read looking for magnet links.
+const maxDroppedText = 64 << 10
+
+// DroppedItem is a .torrent file or magnet link dropped on the window,
+// ready to be added with AddTorrents once its options have been picked.
+// Error says why it can't be added, in which case Request is empty.
+type DroppedItem struct {
+	Label   string          `json:"label"`
+	Request echo.AddRequest `json:"request"`
+	Error   string          `json:"error,omitempty"`
+}
+
+// DropText takes text dropped on the window, such as a magnet link dragged
+// from a browser, and pushes the magnet links in it the same way as
+// dropped files.
+func (ui *UI) DropText(text string) {
+	ui.dropped(droppedMagnets(text, "dropped text"))
+}
+
+// dropFiles handles files dropped on the window. Each .torrent is checked
+// and any other file is searched for magnet links, then the lot is pushed
+// as a "drop:received" event for the frontend to ask for their options.
+func (ui *UI) dropFiles(_, _ int, paths []string) {
+	var items []DroppedItem
+	for _, path := range paths {
+		items = append(items, droppedFile(path)...)
+	}
+	ui.dropped(items)
+}
+
+func (ui *UI) dropped(items []DroppedItem) {
+	if len(items) > 0 {
+		runtime.EventsEmit(ui.ctx, "drop:received", items)
+	}
+}
+
+func droppedFile(path string) []DroppedItem {
+	name := filepath.Base(path)
+	f, err := os.Open(path)
+	if err != nil {
+		return []DroppedItem{{Label: name, Error: err.Error()}}
+	}
+	defer f.Close()
+
+	if strings.EqualFold(filepath.Ext(path), ".torrent") {
+		m, err := torrent.ParseMetainfo(f)
+		if err != nil {
+			return []DroppedItem{{Label: name, Error: err.Error()}}
+		}
+		return []DroppedItem{{
+			Label:   m.Info.Name,
+			Request: echo.AddRequest{Path: path},
+		}}
+	}
+
+	text, err := io.ReadAll(io.LimitReader(f, maxDroppedText))
+	if err != nil {
+		return []DroppedItem{{Label: name, Error: err.Error()}}
+	}
+	return droppedMagnets(string(text), name)
+}
+
+// droppedMagnets picks the magnet links, one per line, out of text. label
+// names where the text came from should it hold none.
+func droppedMagnets(text, label string) []DroppedItem {
+	var items []DroppedItem
+	for line := range strings.Lines(text) {
+		uri := strings.TrimSpace(line)
+		if !strings.HasPrefix(strings.ToLower(uri), "magnet:") {
+			continue
+		}
+		mag, err := torrent.ParseMagnet(uri)
+		if err != nil {
+			item := DroppedItem{Label: uri, Error: err.Error()}
+			items = append(items, item)
+			continue
+		}
+		name := uri
+		if mag.Name != "" {
+			name = mag.Name
+		}
+		items = append(items, DroppedItem{
+			Label:   name,
+			Request: echo.AddRequest{Magnet: uri},
+		})
+	}
+	if len(items) == 0 {
+		return []DroppedItem{{
+			Label: label,
+			Error: "not a .torrent file or magnet link",
+		}}
+	}
+	return items
+}
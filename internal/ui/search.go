@@ -0,0 +1,79 @@
+package ui
+
+import (
+	"context"
+
+	"github.com/prxssh/echo/pkg/echo"
+)
+
+// Search asks the named indexers, or every indexer if none are named, for
+// query in the given Newznab categories, keeping the results that pass
+// filter. Results are pushed as
+// "search:results" events as each indexer answers, and returned merged
+// once all have and their swarms have been checked. Starting another
+// search, or CancelSearch, stops it.
+func (ui *UI) Search(
+	query string,
+	categories []int,
+	providers []string,
+	filter echo.SearchFilter,
+) ([]echo.SearchResult, error) {
+	ctx, cancel := context.WithCancel(ui.ctx)
+	defer cancel()
+
+	ui.searchMu.Lock()
+	if ui.searchCancel != nil {
+		ui.searchCancel()
+	}
+	ui.searchCancel = cancel
+	ui.searchMu.Unlock()
+
+	return ui.client.Search(ctx, echo.SearchQuery{
+		Text:       query,
+		Categories: categories,
+		Indexers:   providers,
+		Filter:     filter,
+	})
+}
+
+// CancelSearch stops the search running, if any.
+func (ui *UI) CancelSearch() {
+	ui.searchMu.Lock()
+	defer ui.searchMu.Unlock()
+
+	if ui.searchCancel != nil {
+		ui.searchCancel()
+		ui.searchCancel = nil
+	}
+}
+
+// PreviewResult fetches the result's metadata in the background, from its
+// .torrent link or from peers for a magnet link, and returns its files
+// without adding it. Previewing another result, or CancelPreview, stops
+// it.
+func (ui *UI) PreviewResult(
+	result echo.SearchResult,
+) (echo.ResultPreview, error) {
+	ctx, cancel := context.WithCancel(ui.ctx)
+	defer cancel()
+
+	ui.searchMu.Lock()
+	if ui.previewCancel != nil {
+		ui.previewCancel()
+	}
+	ui.previewCancel = cancel
+	ui.searchMu.Unlock()
+
+	return ui.client.PreviewResult(ctx, result)
+}
+
+// CancelPreview stops the result preview being fetched, if any.
+func (ui *UI) CancelPreview() {
+	ui.searchMu.Lock()
+	defer ui.searchMu.Unlock()
+
+	if ui.previewCancel != nil {
+		ui.previewCancel()
+		ui.previewCancel = nil
+	}
+}
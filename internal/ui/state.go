@@ -0,0 +1,36 @@
+package ui
+
+import "github.com/prxssh/echo/pkg/echo"
+
+// FullState is everything the frontend shows, taken at once so a reloaded
+// window can start over from it instead of replaying past events.
+type FullState struct {
+	// Torrents and Statuses are in queue order.
+	Torrents []*echo.Torrent      `json:"torrents"`
+	Statuses []echo.TorrentStatus `json:"statuses"`
+	// Labels maps each torrent's hex info-hash to its category and tags.
+	Labels     map[string]echo.Labels `json:"labels"`
+	Categories []echo.Category        `json:"categories"`
+	Magnets    []echo.MagnetStatus    `json:"magnets"`
+	Settings   echo.GlobalSettings    `json:"settings"`
+	Stats      echo.SessionStats      `json:"stats"`
+	Power      echo.PowerStatus       `json:"power"`
+	Suspended  bool                   `json:"suspended"`
+}
+
+// GetFullState returns the torrents, their statuses and labels, the
+// settings and the session stats in one go. Changes after it follow as the
+// usual events.
+func (ui *UI) GetFullState() FullState {
+	return FullState{
+		Torrents:   ui.client.List(),
+		Statuses:   ui.client.Statuses(),
+		Labels:     ui.TorrentLabels(),
+		Categories: ui.client.Categories(),
+		Magnets:    ui.client.Magnets(),
+		Settings:   ui.client.GlobalSettings(),
+		Stats:      ui.client.SessionStats(),
+		Power:      ui.client.Power(),
+		Suspended:  ui.client.Suspended(),
+	}
+}
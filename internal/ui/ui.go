@@ -3,39 +3,688 @@ package ui
 import (
 	"context"
 	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
 
+	"github.com/prxssh/echo/internal/desktop"
+	"github.com/prxssh/echo/internal/peer"
 	"github.com/prxssh/echo/internal/torrent"
+	"github.com/prxssh/echo/pkg/echo"
+	"github.com/prxssh/echo/pkg/logging"
+	"github.com/wailsapp/wails/v2/pkg/runtime"
 )
 
+// UI binds the engine to the Wails frontend. Engine events are forwarded
+// as Wails events of the same name.
 type UI struct {
-	ctx      context.Context
-	torrents map[[sha1.Size]byte]*torrent.Torrent
+	ctx    context.Context
+	client *echo.Client
+	logs   *logging.RingHandler
+	// stopEvents stops forwarding the engine's events.
+	stopEvents context.CancelFunc
+
+	// closeMu guards what closing the window does, whether Quit was
+	// called, and whether the window is running in the background.
+	closeMu     sync.Mutex
+	closeAction CloseAction
+	quitting    bool
+	background  bool
+
+	// createMu guards the torrent CreateTorrent made, the path it was
+	// made from, and how to stop the one being hashed.
+	createMu     sync.Mutex
+	createCancel context.CancelFunc
+	created      []byte
+	createdPath  string
+
+	// searchMu guards how to stop the search running, and the result
+	// preview being fetched.
+	searchMu      sync.Mutex
+	searchCancel  context.CancelFunc
+	previewCancel context.CancelFunc
 }
 
-func New() *UI {
-	return &UI{torrents: make(map[[sha1.Size]byte]*torrent.Torrent)}
+// New returns the UI of client, whose events Startup starts forwarding.
+// The client is started and closed by the engine, not the UI. logs, when
+// set, holds the recent log entries the log viewer shows.
+func New(client *echo.Client, logs *logging.RingHandler) *UI {
+	return &UI{
+		client:      client,
+		logs:        logs,
+		closeAction: CloseAsk,
+	}
 }
 
 func (ui *UI) Startup(ctx context.Context) {
 	ui.ctx = ctx
+	events, stop := context.WithCancel(ctx)
+	ui.stopEvents = stop
+	go ui.forwardEvents(events)
+	runtime.OnFileDrop(ctx, ui.dropFiles)
+	if ui.logs != nil {
+		ui.logs.Subscribe(func(e logging.Entry) {
+			runtime.EventsEmit(ctx, "logs:new", e)
+		})
+	}
+}
+
+// Shutdown runs when the window closes, before the engine shuts down.
+func (ui *UI) Shutdown(ctx context.Context) {
+	if ui.stopEvents != nil {
+		ui.stopEvents()
+	}
+}
+
+// forwardEvents emits the engine's events until ctx is done, picking the
+// stream up again after the last event read whenever the engine cuts it
+// off for falling behind.
+func (ui *UI) forwardEvents(ctx context.Context) {
+	var last uint64
+	for ctx.Err() == nil {
+		for e := range ui.client.Stream(ctx, last) {
+			last = e.Seq
+			runtime.EventsEmit(ui.ctx, e.Name, e.Data)
+		}
+	}
+}
+
+// GetRecentLogs returns up to limit of the latest log entries at level
+// ("debug", "info", "warn" or "error") or above, oldest first. New entries
+// follow as "logs:new" events.
+func (ui *UI) GetRecentLogs(
+	level string,
+	limit int,
+) ([]logging.Entry, error) {
+	if ui.logs == nil {
+		return nil, nil
+	}
+	l, err := logging.ParseLevel(level)
+	if err != nil {
+		return nil, fmt.Errorf("ui: %w", err)
+	}
+	return ui.logs.Recent(l, limit), nil
+}
+
+// QueryLogs is GetRecentLogs narrowed down to the given modules, if any,
+// and to entries whose message or attributes hold text, if set.
+func (ui *UI) QueryLogs(
+	level string,
+	modules []string,
+	text string,
+	limit int,
+) ([]logging.Entry, error) {
+	if ui.logs == nil {
+		return nil, nil
+	}
+	l, err := logging.ParseLevel(level)
+	if err != nil {
+		return nil, fmt.Errorf("ui: %w", err)
+	}
+	return ui.logs.Query(logging.Query{
+		Level:   l,
+		Modules: modules,
+		Text:    text,
+		Limit:   limit,
+	}), nil
+}
+
+// LogModules returns the modules the kept log entries came from.
+func (ui *UI) LogModules() []string {
+	if ui.logs == nil {
+		return nil
+	}
+	return ui.logs.Modules()
+}
+
+// GetSettings returns the global settings: paths, ports, limits, proxy and
+// encryption policy.
+func (ui *UI) GetSettings() echo.GlobalSettings {
+	return ui.client.GlobalSettings()
+}
+
+// UpdateSettings changes the global settings given in patch, saves them and
+// applies them to the running session where it can.
+func (ui *UI) UpdateSettings(
+	patch echo.SettingsPatch,
+) (echo.GlobalSettings, error) {
+	return ui.client.UpdateSettings(patch)
+}
+
+// CheckPort tests whether the listen port can be reached from outside,
+// which can take up to a minute.
+func (ui *UI) CheckPort() (echo.PortCheck, error) {
+	return ui.client.CheckPort(ui.ctx)
+}
+
+// FingerprintPresets lists the clients Echo can introduce itself as to
+// peers and trackers.
+func (ui *UI) FingerprintPresets() []echo.FingerprintPreset {
+	return echo.FingerprintPresets()
+}
+
+// SessionStats returns the session and all-time transfer totals; the same
+// snapshot is pushed periodically as the "session:stats" event.
+func (ui *UI) SessionStats() echo.SessionStats {
+	return ui.client.SessionStats()
+}
+
+// DHTStats reports DHT health; the same snapshot is pushed periodically as
+// the "dht:stats" event.
+func (ui *UI) DHTStats() echo.DHTStats {
+	return ui.client.Stats().DHT
+}
+
+func (ui *UI) AddTorrent(
+	data []byte,
+	opts echo.AddOptions,
+) (*echo.Torrent, error) {
+	return ui.client.AddTorrent(data, opts)
+}
+
+// AddTorrentFromPath adds the .torrent file at path.
+func (ui *UI) AddTorrentFromPath(
+	path string,
+	opts echo.AddOptions,
+) (*echo.Torrent, error) {
+	return ui.client.AddTorrentFile(path, opts)
+}
+
+// AddTorrentFromURL downloads a .torrent over HTTP and adds it.
+func (ui *UI) AddTorrentFromURL(
+	url string,
+	opts echo.AddOptions,
+) (*echo.Torrent, error) {
+	return ui.client.AddTorrentURL(ui.ctx, url, opts)
+}
+
+// AddTorrents adds several torrents, each with its own options, and says
+// how each went.
+func (ui *UI) AddTorrents(reqs []echo.AddRequest) []echo.AddResult {
+	return ui.client.AddBatch(ui.ctx, reqs)
+}
+
+// ChooseTorrentFiles asks for .torrent files with the native file dialog
+// and returns their paths, for AddTorrentFromPath. None are returned if the
+// dialog is cancelled.
+func (ui *UI) ChooseTorrentFiles() ([]string, error) {
+	opts := runtime.OpenDialogOptions{
+		Title: "Add torrents",
+		Filters: []runtime.FileFilter{
+			{DisplayName: "Torrent files", Pattern: "*.torrent"},
+		},
+	}
+	return runtime.OpenMultipleFilesDialog(ui.ctx, opts)
+}
+
+// AddMagnet starts fetching the metadata for a magnet link from peers and
+// returns a placeholder for it straight away. "metadata:progress" events
+// follow as the info dict's pieces arrive, then "magnet:done" once the
+// torrent is added, its .torrent saved to the torrents directory. Only the
+// files the link selects with so= are downloaded.
+func (ui *UI) AddMagnet(
+	uri string,
+	opts echo.AddOptions,
+) (echo.MagnetStatus, error) {
+	return ui.client.AddMagnetAsync(uri, opts)
+}
+
+// PendingMagnets lists the magnet links whose metadata is being fetched.
+func (ui *UI) PendingMagnets() []echo.MagnetStatus {
+	return ui.client.Magnets()
+}
+
+// ListTorrents returns a snapshot of every torrent in queue order, with
+// what a row of the torrent table shows; updates are pushed as the
+// "torrent:status" event.
+func (ui *UI) ListTorrents() []echo.TorrentStatus {
+	return ui.client.Statuses()
+}
+
+// Torrents returns the session's torrents with their metainfo, including
+// those restored from the last run.
+func (ui *UI) Torrents() []*echo.Torrent {
+	return ui.client.List()
+}
+
+// TorrentStates maps each torrent's hex info-hash to its state; changes are
+// pushed as the "torrent:state" event.
+func (ui *UI) TorrentStates() map[string]echo.State {
+	states := make(map[string]echo.State)
+	for _, t := range ui.client.List() {
+		hash := hex.EncodeToString(t.Metainfo.Info.Hash[:])
+		states[hash] = t.State()
+	}
+	return states
+}
+
+// GetTorrentDetails returns what the details pane's tabs show: the file
+// tree with per-file progress, piece availability, trackers and peers.
+func (ui *UI) GetTorrentDetails(
+	infoHash [sha1.Size]byte,
+) (echo.TorrentDetails, error) {
+	return ui.client.Details(infoHash)
+}
+
+// GetPeerCountries breaks the torrent's connected peers down by country,
+// for the map of where they are; updates are pushed as the
+// "torrent:countries" event.
+func (ui *UI) GetPeerCountries(
+	infoHash [sha1.Size]byte,
+) ([]peer.CountryStats, error) {
+	return ui.client.Countries(infoHash)
+}
+
+// GetPieceMap returns the torrent's piece states for the piece bar, one
+// digit per piece: 0 missing, 1 requested, 2 downloaded, 3 verified.
+func (ui *UI) GetPieceMap(infoHash [sha1.Size]byte) (string, error) {
+	return ui.client.PieceMap(infoHash)
+}
+
+// GetSessionSpeeds returns the session's download and upload rates for the
+// speed graph: "recent" is ten minutes at one sample a second, "day" a day
+// of per-minute averages.
+func (ui *UI) GetSessionSpeeds(
+	r echo.SpeedRange,
+) ([]echo.SpeedSample, error) {
+	return ui.client.SessionSpeeds(r)
+}
+
+// GetTorrentSpeeds returns one torrent's rates, as GetSessionSpeeds does
+// the session's.
+func (ui *UI) GetTorrentSpeeds(
+	infoHash [sha1.Size]byte,
+	r echo.SpeedRange,
+) ([]echo.SpeedSample, error) {
+	return ui.client.TorrentSpeeds(infoHash, r)
+}
+
+// SetFilePriority changes how soon one of the torrent's files downloads:
+// "high", "normal", "low", or "skip" to leave it out.
+func (ui *UI) SetFilePriority(
+	infoHash [sha1.Size]byte,
+	index int,
+	priority echo.FilePriority,
+) error {
+	return ui.client.SetFilePriority(infoHash, index, priority)
+}
+
+// SetFilesWanted adds files to the torrent's download or leaves them out.
+func (ui *UI) SetFilesWanted(
+	infoHash [sha1.Size]byte,
+	indices []int,
+	wanted bool,
+) error {
+	return ui.client.SetFilesWanted(infoHash, indices, wanted)
+}
+
+// RevealTorrent shows the torrent's folder, or its file if it has only one,
+// in the file manager. Nothing may be on disk yet, in which case the
+// download folder is shown instead.
+func (ui *UI) RevealTorrent(infoHash [sha1.Size]byte) error {
+	path, err := ui.client.TorrentPath(infoHash)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(path); err != nil {
+		return desktop.Open(filepath.Dir(path))
+	}
+	return desktop.Reveal(path)
 }
 
-func (ui *UI) AddTorrent(data []byte) (*torrent.Torrent, error) {
-	torrent, err := torrent.ParseTorrent(data)
+// OpenTorrentFile launches one of the torrent's files with its default
+// application, once the file is completely downloaded.
+func (ui *UI) OpenTorrentFile(infoHash [sha1.Size]byte, index int) error {
+	path, complete, err := ui.client.FilePath(infoHash, index)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	torrent.Start(ui.ctx)
+	if !complete {
+		name := filepath.Base(path)
+		return fmt.Errorf("ui: %s is not downloaded yet", name)
+	}
+	return desktop.Open(path)
+}
 
-	return torrent, nil
+// ForceReannounce announces the torrent to all its trackers now.
+func (ui *UI) ForceReannounce(infoHash [sha1.Size]byte) error {
+	return ui.client.Reannounce(infoHash)
+}
+
+// ReannounceTracker announces the torrent to the tracker at url now.
+func (ui *UI) ReannounceTracker(infoHash [sha1.Size]byte, url string) error {
+	return ui.client.ReannounceTracker(infoHash, url)
+}
+
+// ForceRecheck verifies the torrent's data on disk again, pausing it for
+// the check if it runs.
+func (ui *UI) ForceRecheck(infoHash [sha1.Size]byte) error {
+	return ui.client.Recheck(infoHash)
 }
 
 func (ui *UI) RemoveTorrent(infoHash [sha1.Size]byte) {
-	torrent, ok := ui.torrents[infoHash]
-	if !ok {
-		return
+	ui.client.Remove(infoHash)
+}
+
+func (ui *UI) PauseTorrent(infoHash [sha1.Size]byte) error {
+	return ui.client.Pause(infoHash)
+}
+
+func (ui *UI) ResumeTorrent(infoHash [sha1.Size]byte) error {
+	return ui.client.Resume(infoHash)
+}
+
+// Overrides returns the torrent's own settings, which take precedence over
+// the global ones.
+func (ui *UI) Overrides(infoHash [sha1.Size]byte) (echo.Overrides, error) {
+	return ui.client.Overrides(infoHash)
+}
+
+func (ui *UI) SetOverrides(
+	infoHash [sha1.Size]byte,
+	overrides echo.Overrides,
+) error {
+	return ui.client.SetOverrides(infoHash, overrides)
+}
+
+// ShareLimits returns the ratio and seeding-time limits that apply to the
+// torrent.
+func (ui *UI) ShareLimits(infoHash [sha1.Size]byte) (echo.ShareLimits, error) {
+	return ui.client.ShareLimits(infoHash)
+}
+
+// SetShareLimits gives the torrent limits of its own; nil reverts to the
+// global ones.
+func (ui *UI) SetShareLimits(
+	infoHash [sha1.Size]byte,
+	limits *echo.ShareLimits,
+) error {
+	return ui.client.SetShareLimits(infoHash, limits)
+}
+
+// SetGlobalRateLimits changes the session's rate limits; zero is
+// unlimited.
+func (ui *UI) SetGlobalRateLimits(limits echo.RateLimits) error {
+	return ui.client.SetRateLimits(limits)
+}
+
+// SetTorrentRateLimits gives the torrent rate limits of its own; nil
+// reverts to the global ones. The limits in effect show up in the
+// torrent's status.
+func (ui *UI) SetTorrentRateLimits(
+	infoHash [sha1.Size]byte,
+	limits *echo.RateLimits,
+) error {
+	return ui.client.SetTorrentRateLimits(infoHash, limits)
+}
+
+func (ui *UI) Categories() []echo.Category {
+	return ui.client.Categories()
+}
+
+// AddCategory creates a category or changes its save path.
+func (ui *UI) AddCategory(cat echo.Category) error {
+	return ui.client.AddCategory(cat)
+}
+
+func (ui *UI) RemoveCategory(name string) {
+	ui.client.RemoveCategory(name)
+}
+
+func (ui *UI) SetCategory(
+	infoHash [sha1.Size]byte,
+	name string,
+	move bool,
+) error {
+	return ui.client.SetCategory(infoHash, name, move)
+}
+
+func (ui *UI) SetTags(infoHash [sha1.Size]byte, tags []string) error {
+	return ui.client.SetTags(infoHash, tags)
+}
+
+// TorrentLabels maps each torrent's hex info-hash to its category and tags,
+// for filtering the torrent list.
+func (ui *UI) TorrentLabels() map[string]echo.Labels {
+	labels := make(map[string]echo.Labels)
+	for _, t := range ui.client.List() {
+		hash := t.Metainfo.Info.Hash
+		l, err := ui.client.Labels(hash)
+		if err != nil {
+			continue
+		}
+		labels[hex.EncodeToString(hash[:])] = l
+	}
+	return labels
+}
+
+func (ui *UI) WatchFolders() []echo.WatchFolder {
+	return ui.client.WatchFolders()
+}
+
+// SetWatchFolders replaces the folders whose .torrent and .magnet files are
+// added automatically.
+func (ui *UI) SetWatchFolders(folders []echo.WatchFolder) error {
+	return ui.client.SetWatchFolders(folders)
+}
+
+// Feeds returns the RSS and Atom feeds polled for torrents.
+func (ui *UI) Feeds() []echo.Feed {
+	return ui.client.Feeds()
+}
+
+func (ui *UI) SetFeeds(feeds []echo.Feed) error {
+	return ui.client.SetFeeds(feeds)
+}
+
+// PauseWindows returns the weekly times during which every transfer is
+// paused.
+// Indexers returns the Torznab and Newznab APIs searched for torrents.
+func (ui *UI) Indexers() []echo.Indexer {
+	return ui.client.Indexers()
+}
+
+func (ui *UI) SetIndexers(indexers []echo.Indexer) error {
+	return ui.client.SetIndexers(indexers)
+}
+
+func (ui *UI) SetIndexerEnabled(name string, enabled bool) error {
+	return ui.client.SetIndexerEnabled(name, enabled)
+}
+
+func (ui *UI) IndexerHealth() []echo.IndexerHealth {
+	return ui.client.IndexerHealth()
+}
+
+// TestIndexer checks that the indexer answers and takes its API key, and
+// returns how long it took to.
+func (ui *UI) TestIndexer(indexer echo.Indexer) (time.Duration, error) {
+	return ui.client.TestIndexer(ui.ctx, indexer)
+}
+
+// IndexedTorrents counts the torrents in the local index built from
+// crawling the DHT, which is searched as echo.LocalIndexName.
+func (ui *UI) IndexedTorrents() int {
+	return ui.client.IndexedTorrents()
+}
+
+// SavedSearches returns the searches run on a schedule, whose new results
+// are notified of or added.
+func (ui *UI) SavedSearches() []echo.SavedSearch {
+	return ui.client.SavedSearches()
+}
+
+func (ui *UI) SetSavedSearches(saved []echo.SavedSearch) error {
+	return ui.client.SetSavedSearches(saved)
+}
+
+func (ui *UI) PauseWindows() []echo.PauseWindow {
+	return ui.client.PauseWindows()
+}
+
+func (ui *UI) SetPauseWindows(windows []echo.PauseWindow) error {
+	return ui.client.SetPauseWindows(windows)
+}
+
+// Suspended reports whether a pause window is in force; changes arrive as
+// the "session:suspended" event.
+func (ui *UI) Suspended() bool {
+	return ui.client.Suspended()
+}
+
+// PowerPolicy returns how the session backs off on battery or a metered
+// connection.
+func (ui *UI) PowerPolicy() echo.PowerPolicy {
+	return ui.client.PowerPolicy()
+}
+
+func (ui *UI) SetPowerPolicy(p echo.PowerPolicy) error {
+	return ui.client.SetPowerPolicy(p)
+}
+
+// Power returns the machine's power and network state and what the session
+// is doing about it; changes arrive as the "session:power" event.
+func (ui *UI) Power() echo.PowerStatus {
+	return ui.client.Power()
+}
+
+// Addresses returns the machine's local and external addresses; changes
+// arrive as the "network:address" event.
+func (ui *UI) Addresses() echo.Addresses {
+	return ui.client.Addresses()
+}
+
+// Quotas returns the disk and monthly transfer quotas.
+func (ui *UI) Quotas() echo.Quotas {
+	return ui.client.Quotas()
+}
+
+func (ui *UI) SetQuotas(q echo.Quotas) error {
+	return ui.client.SetQuotas(q)
+}
+
+// QuotaStatus returns how near the session is to its quotas; changes
+// arrive as the "session:quota" event.
+func (ui *UI) QuotaStatus() echo.QuotaStatus {
+	return ui.client.QuotaStatus()
+}
+
+// History returns the completed downloads, most recent first, including
+// those of torrents since removed. New ones arrive as the "history:added"
+// event.
+func (ui *UI) History() []echo.HistoryEntry {
+	return ui.client.History()
+}
+
+func (ui *UI) ClearHistory() error {
+	return ui.client.ClearHistory()
+}
+
+// Import asks for another client's state directory and recreates its
+// torrents in the session. Cancelling the dialog imports nothing.
+func (ui *UI) Import(source echo.ImportSource) (echo.ImportResult, error) {
+	title := "Choose qBittorrent's BT_backup directory"
+	if source == echo.ImportTransmission {
+		title = "Choose Transmission's config directory"
+	}
+	dir, err := runtime.OpenDirectoryDialog(
+		ui.ctx,
+		runtime.OpenDialogOptions{Title: title},
+	)
+	if err != nil || dir == "" {
+		return echo.ImportResult{}, err
+	}
+	return ui.client.Import(source, dir)
+}
+
+// QueuePosition returns the torrent's zero-based place in the queue.
+func (ui *UI) QueuePosition(infoHash [sha1.Size]byte) (int, error) {
+	return ui.client.QueuePosition(infoHash)
+}
+
+func (ui *UI) SetQueuePosition(infoHash [sha1.Size]byte, pos int) error {
+	return ui.client.SetQueuePosition(infoHash, pos)
+}
+
+func (ui *UI) MoveToTop(infoHash [sha1.Size]byte) error {
+	return ui.client.MoveToTop(infoHash)
+}
+
+func (ui *UI) MoveUp(infoHash [sha1.Size]byte) error {
+	return ui.client.MoveUp(infoHash)
+}
+
+func (ui *UI) MoveDown(infoHash [sha1.Size]byte) error {
+	return ui.client.MoveDown(infoHash)
+}
+
+func (ui *UI) MoveToBottom(infoHash [sha1.Size]byte) error {
+	return ui.client.MoveToBottom(infoHash)
+}
+
+// MagnetURI returns a magnet link for an added torrent, for the "Copy magnet
+// link" action.
+func (ui *UI) MagnetURI(infoHash [sha1.Size]byte) (string, error) {
+	torrent, err := ui.torrent(infoHash)
+	if err != nil {
+		return "", err
 	}
 
-	torrent.Stop(ui.ctx)
-	delete(ui.torrents, infoHash)
+	return torrent.Metainfo.MagnetURI(), nil
+}
+
+// ExportTorrent writes the torrent's .torrent file, including one built
+// from a magnet link's fetched metadata, to a path picked in a save dialog.
+// It returns the path, or "" if the dialog was cancelled.
+func (ui *UI) ExportTorrent(infoHash [sha1.Size]byte) (string, error) {
+	t, err := ui.torrent(infoHash)
+	if err != nil {
+		return "", err
+	}
+
+	return ui.saveTorrentAs(t.Metainfo.Info.Name, t.MetainfoBytes())
+}
+
+// SaveTorrentFile is ExportTorrent with the trackers replaced by tiers.
+func (ui *UI) SaveTorrentFile(
+	infoHash [sha1.Size]byte,
+	tiers [][]string,
+) (string, error) {
+	t, err := ui.torrent(infoHash)
+	if err != nil {
+		return "", err
+	}
+	data, err := t.EditMetainfo(torrent.EditOpts{Trackers: &tiers})
+	if err != nil {
+		return "", err
+	}
+
+	return ui.saveTorrentAs(t.Metainfo.Info.Name, data)
+}
+
+func (ui *UI) saveTorrentAs(name string, data []byte) (string, error) {
+	path, err := runtime.SaveFileDialog(ui.ctx, runtime.SaveDialogOptions{
+		DefaultFilename: name + ".torrent",
+		Title:           "Save .torrent",
+		Filters: []runtime.FileFilter{
+			{DisplayName: "Torrent files", Pattern: "*.torrent"},
+		},
+		CanCreateDirectories: true,
+	})
+	if err != nil || path == "" {
+		return "", err
+	}
+
+	return path, os.WriteFile(path, data, 0o644)
+}
+
+func (ui *UI) torrent(infoHash [sha1.Size]byte) (*echo.Torrent, error) {
+	t, ok := ui.client.Get(infoHash)
+	if !ok {
+		return nil, fmt.Errorf("ui: unknown torrent %x", infoHash)
+	}
+	return t, nil
 }
@@ -4,56 +4,102 @@ import (
 	"errors"
 	"net"
 	"net/netip"
+	"sync/atomic"
+	"time"
 
 	"github.com/oschwald/maxminddb-golang"
 )
 
+// IP2CountryResolver looks addresses up in the country databases last
+// swapped into it, one for IPv4 and one for IPv6, which may be the same,
+// and in an optional ASN database.
 type IP2CountryResolver struct {
-	v4 *maxminddb.Reader
-	v6 *maxminddb.Reader
+	dbs atomic.Pointer[countryDBs]
+	asn atomic.Pointer[maxminddb.Reader]
+
+	// cache holds what recent lookups found, since the same peers are
+	// looked up for every event about them.
+	cache *LRU[netip.Addr, Location]
 }
 
-var IP2Country *IP2CountryResolver
+// Location is what the databases know of an address; fields they don't
+// know are empty.
+type Location struct {
+	CountryCode string
+	CountryName string
+	ASN         uint32
+	Org         string
+}
 
-func NewIP2CountryResolver(v4Path, v6Path string) error {
-	if v4Path == "" && v6Path == "" {
-		return errors.New("must provide at least one mmdb path")
-	}
+const (
+	lookupCacheSize = 4096
+	lookupCacheTTL  = 10 * time.Minute
+)
 
-	var (
-		v4, v6 *maxminddb.Reader
-		err    error
-	)
-	if v4Path != "" {
-		if v4, err = maxminddb.Open(v4Path); err != nil {
-			return err
-		}
-	}
-	if v6Path != "" {
-		if v6, err = maxminddb.Open(v6Path); err != nil {
-			if v4 != nil {
-				_ = v4.Close()
-			}
-			return err
-		}
+type countryDBs struct {
+	v4, v6 *maxminddb.Reader
+}
+
+// NewIP2CountryResolver returns a resolver that finds nothing until
+// databases are swapped into it.
+func NewIP2CountryResolver() *IP2CountryResolver {
+	return &IP2CountryResolver{
+		cache: NewLRU[netip.Addr, Location](
+			lookupCacheSize,
+			lookupCacheTTL,
+		),
 	}
-	IP2Country = &IP2CountryResolver{v4: v4, v6: v6}
+}
 
-	return nil
+// Swap makes lookups use v4 and v6 from now on; either may be nil. The
+// readers swapped out are left to lookups still using them and then the
+// garbage collector, so readers should be read with maxminddb.FromBytes
+// rather than mapped with Open.
+func (r *IP2CountryResolver) Swap(v4, v6 *maxminddb.Reader) {
+	r.dbs.Store(&countryDBs{v4: v4, v6: v6})
+	r.cache.Clear()
 }
 
-func (r *IP2CountryResolver) Close() error {
-	var e1, e2 error
-	if r.v4 != nil {
-		e1 = r.v4.Close()
+// SwapASN makes ASN lookups use db from now on, as Swap does the country
+// databases. A nil db turns them off.
+func (r *IP2CountryResolver) SwapASN(db *maxminddb.Reader) {
+	r.asn.Store(db)
+	r.cache.Clear()
+}
+
+// Lookup returns what the databases know of the address.
+func (r *IP2CountryResolver) Lookup(ipstr string) (Location, error) {
+	if r == nil {
+		return Location{}, errors.New("resolver is nil")
 	}
-	if r.v6 != nil {
-		e2 = r.v6.Close()
+
+	addr, err := netip.ParseAddr(ipstr)
+	if err != nil {
+		return Location{}, err
 	}
-	if e1 != nil {
-		return e1
+	addr = addr.Unmap()
+	if loc, ok := r.cache.Get(addr); ok {
+		return loc, nil
 	}
-	return e2
+
+	var loc Location
+	loc.CountryCode, loc.CountryName = r.country(addr)
+	loc.ASN, loc.Org = r.network(addr)
+	r.cache.Put(addr, loc)
+	return loc, nil
+}
+
+func (r *IP2CountryResolver) CountryCode(ipstr string) (string, string, error) {
+	loc, err := r.Lookup(ipstr)
+	return loc.CountryCode, loc.CountryName, err
+}
+
+// ASN returns the number and organization of the autonomous system the
+// address is in, telling a home connection from, say, a data center.
+// Without an ASN database, or for private addresses, it returns zero.
+func (r *IP2CountryResolver) ASN(ipstr string) (uint32, string, error) {
+	loc, err := r.Lookup(ipstr)
+	return loc.ASN, loc.Org, err
 }
 
 type mmCountry struct {
@@ -70,31 +116,27 @@ type sapicsCountry struct {
 	} `maxminddb:"country"`
 }
 
-func (r *IP2CountryResolver) CountryCode(ipstr string) (string, string, error) {
-	if r == nil {
-		return "", "", errors.New("resolver is nil")
-	}
-
-	addr, err := netip.ParseAddr(ipstr)
-	if err != nil {
-		return "", "", err
-	}
+func (r *IP2CountryResolver) country(addr netip.Addr) (string, string) {
 	if addr.IsPrivate() || addr.IsLoopback() || addr.IsLinkLocalUnicast() ||
 		addr.IsMulticast() || addr.IsUnspecified() {
-		return "", "", nil
+		return "", ""
 	}
 
+	dbs := r.dbs.Load()
+	if dbs == nil {
+		return "", ""
+	}
 	var reader *maxminddb.Reader
 	switch {
 	case addr.Is4():
-		reader = r.v4
+		reader = dbs.v4
 	case addr.Is6():
-		reader = r.v6
+		reader = dbs.v6
 	default:
-		return "", "", errors.New("unknown IP family")
+		return "", ""
 	}
 	if reader == nil {
-		return "", "", nil
+		return "", ""
 	}
 
 	ip := net.IP(addr.AsSlice())
@@ -102,18 +144,37 @@ func (r *IP2CountryResolver) CountryCode(ipstr string) (string, string, error) {
 	var mm mmCountry
 	if err := reader.Lookup(ip, &mm); err == nil &&
 		mm.Country.ISOCode != "" {
-		return mm.Country.ISOCode, mm.Country.Names["en"], nil
+		return mm.Country.ISOCode, mm.Country.Names["en"]
 	}
 
 	var sp sapicsCountry
 	if err := reader.Lookup(ip, &sp); err == nil {
 		if sp.Country.ISOCode != "" {
-			return sp.Country.ISOCode, sp.Country.Names["en"], nil
+			return sp.Country.ISOCode, sp.Country.Names["en"]
 		}
 		if sp.CountryCode != "" {
-			return sp.CountryCode, "", nil
+			return sp.CountryCode, ""
 		}
 	}
 
-	return "", "", nil
+	return "", ""
+}
+
+type mmASN struct {
+	Number       uint32 `maxminddb:"autonomous_system_number"`
+	Organization string `maxminddb:"autonomous_system_organization"`
+}
+
+func (r *IP2CountryResolver) network(addr netip.Addr) (uint32, string) {
+	db := r.asn.Load()
+	if db == nil || addr.IsPrivate() || addr.IsLoopback() ||
+		addr.IsLinkLocalUnicast() || addr.IsUnspecified() {
+		return 0, ""
+	}
+
+	var rec mmASN
+	if err := db.Lookup(net.IP(addr.AsSlice()), &rec); err != nil {
+		return 0, ""
+	}
+	return rec.Number, rec.Organization
 }
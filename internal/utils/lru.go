@@ -0,0 +1,84 @@
+package utils
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// LRU is a cache safe for concurrent use that holds at most size entries,
+// evicting the least recently used, each for at most ttl.
+type LRU[K comparable, V any] struct {
+	size int
+	ttl  time.Duration
+
+	mu      sync.Mutex
+	order   *list.List
+	entries map[K]*list.Element
+}
+
+type lruEntry[K comparable, V any] struct {
+	key     K
+	value   V
+	expires time.Time
+}
+
+func NewLRU[K comparable, V any](size int, ttl time.Duration) *LRU[K, V] {
+	return &LRU[K, V]{
+		size:    size,
+		ttl:     ttl,
+		order:   list.New(),
+		entries: make(map[K]*list.Element, size),
+	}
+}
+
+func (c *LRU[K, V]) Get(key K) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var zero V
+	el, ok := c.entries[key]
+	if !ok {
+		return zero, false
+	}
+	e := el.Value.(*lruEntry[K, V])
+	if time.Now().After(e.expires) {
+		c.order.Remove(el)
+		delete(c.entries, key)
+		return zero, false
+	}
+	c.order.MoveToFront(el)
+	return e.value, true
+}
+
+func (c *LRU[K, V]) Put(key K, value V) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expires := time.Now().Add(c.ttl)
+	if el, ok := c.entries[key]; ok {
+		e := el.Value.(*lruEntry[K, V])
+		e.value, e.expires = value, expires
+		c.order.MoveToFront(el)
+		return
+	}
+	c.entries[key] = c.order.PushFront(&lruEntry[K, V]{
+		key:     key,
+		value:   value,
+		expires: expires,
+	})
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*lruEntry[K, V]).key)
+	}
+}
+
+// Clear empties the cache, as when what it caches has changed.
+func (c *LRU[K, V]) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.order.Init()
+	clear(c.entries)
+}
@@ -0,0 +1,77 @@
+package webseed
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// busyError is a BEP 17 seed answering 503 with the number of seconds to
+// wait before asking again.
+type busyError struct {
+	retryAfter time.Duration
+}
+
+func (e *busyError) Error() string {
+	return fmt.Sprintf("seed busy, retry in %s", e.retryAfter)
+}
+
+// fetchHTTPSeed reads a whole piece from a BEP 17 seed, which is addressed
+// by info-hash and piece index instead of file path.
+func (d *Downloader) fetchHTTPSeed(
+	ctx context.Context,
+	seed string,
+	index int,
+	buf []byte,
+) error {
+	u, err := url.Parse(seed)
+	if err != nil {
+		return err
+	}
+	q := u.Query()
+	q.Set("info_hash", string(d.infoHash[:]))
+	q.Set("piece", strconv.Itoa(index))
+	u.RawQuery = q.Encode()
+
+	ctx, cancel := context.WithTimeout(ctx, d.cfg.RequestTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(
+		ctx,
+		http.MethodGet,
+		u.String(),
+		nil,
+	)
+	if err != nil {
+		return err
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusServiceUnavailable:
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 32))
+		secs, err := strconv.Atoi(strings.TrimSpace(string(body)))
+		if err != nil || secs <= 0 {
+			secs = int(d.cfg.InitialBackoff / time.Second)
+		}
+		return &busyError{retryAfter: time.Duration(secs) * time.Second}
+	default:
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	if _, err := io.ReadFull(resp.Body, buf); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,340 @@
+package webseed
+
+import (
+	"context"
+	"crypto/sha1"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/prxssh/echo/internal/piece"
+)
+
+type Config struct {
+	RequestTimeout time.Duration
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	IdleWait       time.Duration
+}
+
+func defaultConfig() Config {
+	return Config{
+		RequestTimeout: 60 * time.Second,
+		InitialBackoff: 15 * time.Second,
+		MaxBackoff:     30 * time.Minute,
+		IdleWait:       5 * time.Second,
+	}
+}
+
+// File is one file of the torrent in stream order, as the web seed serves it.
+type File struct {
+	Path    []string
+	Length  int64
+	Padding bool
+}
+
+type VerifyFunc func(index int, data []byte) bool
+
+type WriteFunc func(index int, data []byte) error
+
+type Opts struct {
+	URLs []string
+	// HTTPSeeds are BEP 17 seeds, which serve whole pieces by info-hash
+	// and index rather than files.
+	HTTPSeeds   []string
+	InfoHash    [sha1.Size]byte
+	Name        string
+	Files       []File
+	MultiFile   bool
+	PieceLength int64
+	Picker      *piece.Picker
+	Verify      VerifyFunc
+	Write       WriteFunc
+	Client      *http.Client
+	Cfg         *Config
+}
+
+// Downloader fetches pieces from BEP 19 web seeds, plain HTTP servers that
+// hold the torrent's files and answer byte-range requests, and from BEP 17
+// HTTP seeds. Each seed runs on its own, claiming pieces from the same
+// picker as the peers.
+type Downloader struct {
+	cfg         Config
+	urls        []string
+	httpSeeds   []string
+	infoHash    [sha1.Size]byte
+	name        string
+	files       []File
+	multiFile   bool
+	pieceLength int64
+	size        int64
+	picker      *piece.Picker
+	verify      VerifyFunc
+	write       WriteFunc
+	client      *http.Client
+	downloaded  atomic.Uint64
+}
+
+func New(opts Opts) (*Downloader, error) {
+	if opts.Picker == nil || opts.Verify == nil || opts.Write == nil {
+		return nil, errors.New(
+			"webseed: Picker, Verify and Write are required",
+		)
+	}
+	if opts.PieceLength <= 0 {
+		return nil, errors.New("webseed: invalid piece length")
+	}
+
+	d := &Downloader{
+		cfg:         defaultConfig(),
+		urls:        opts.URLs,
+		httpSeeds:   opts.HTTPSeeds,
+		infoHash:    opts.InfoHash,
+		name:        opts.Name,
+		files:       opts.Files,
+		multiFile:   opts.MultiFile,
+		pieceLength: opts.PieceLength,
+		picker:      opts.Picker,
+		verify:      opts.Verify,
+		write:       opts.Write,
+		client:      opts.Client,
+	}
+	if opts.Cfg != nil {
+		d.cfg = *opts.Cfg
+	}
+	if d.client == nil {
+		d.client = http.DefaultClient
+	}
+	for _, f := range d.files {
+		d.size += f.Length
+	}
+
+	return d, nil
+}
+
+// Downloaded returns the number of verified bytes fetched from web seeds.
+func (d *Downloader) Downloaded() uint64 {
+	return d.downloaded.Load()
+}
+
+// Start runs every seed until the torrent is complete or ctx is done.
+func (d *Downloader) Start(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, u := range d.urls {
+		wg.Go(func() { d.runSeed(ctx, u, d.fetchWebSeed) })
+	}
+	for _, u := range d.httpSeeds {
+		wg.Go(func() { d.runSeed(ctx, u, d.fetchHTTPSeed) })
+	}
+	wg.Wait()
+}
+
+// fetchFunc fills buf with piece index from seed.
+type fetchFunc func(
+	ctx context.Context,
+	seed string,
+	index int,
+	buf []byte,
+) error
+
+func (d *Downloader) runSeed(
+	ctx context.Context,
+	seed string,
+	fetch fetchFunc,
+) {
+	backoff := d.cfg.InitialBackoff
+
+	for !d.picker.Complete() {
+		index, ok := d.picker.Claim()
+		if !ok {
+			if sleepCtx(ctx, d.cfg.IdleWait) != nil {
+				return
+			}
+			continue
+		}
+
+		err := d.fetchPiece(ctx, seed, index, fetch)
+		if err == nil {
+			backoff = d.cfg.InitialBackoff
+			continue
+		}
+		d.picker.Release(index)
+		if ctx.Err() != nil {
+			return
+		}
+
+		// A busy seed says how long to stay away; that replaces the
+		// backoff for this round only.
+		wait := backoff
+		var busy *busyError
+		if errors.As(err, &busy) {
+			wait = busy.retryAfter
+		} else {
+			backoff = min(backoff*2, d.cfg.MaxBackoff)
+		}
+
+		slog.Warn(
+			"web seed failed",
+			slog.String("url", seed),
+			slog.Int("piece", index),
+			slog.Duration("backoff", wait),
+			slog.String("error", err.Error()),
+		)
+		if sleepCtx(ctx, wait) != nil {
+			return
+		}
+	}
+}
+
+func (d *Downloader) fetchPiece(
+	ctx context.Context,
+	seed string,
+	index int,
+	fetch fetchFunc,
+) error {
+	off := int64(index) * d.pieceLength
+	buf := make([]byte, min(d.pieceLength, d.size-off))
+	if err := fetch(ctx, seed, index, buf); err != nil {
+		return err
+	}
+	d.picker.Received(index)
+
+	if !d.verify(index, buf) {
+		return fmt.Errorf("piece %d failed hash check", index)
+	}
+	if err := d.write(index, buf); err != nil {
+		return err
+	}
+
+	d.picker.Done(index)
+	d.downloaded.Add(uint64(len(buf)))
+	return nil
+}
+
+// fetchWebSeed reads a piece from a BEP 19 seed, one range request per file
+// the piece covers.
+func (d *Downloader) fetchWebSeed(
+	ctx context.Context,
+	seed string,
+	index int,
+	buf []byte,
+) error {
+	off := int64(index) * d.pieceLength
+	n := 0
+	for _, s := range d.segments(off, int64(len(buf))) {
+		part := buf[n : n+int(s.length)]
+		n += len(part)
+		if s.file.Padding {
+			continue // buf is already zeroed
+		}
+
+		u := d.fileURL(seed, s.file)
+		if err := d.fetchRange(ctx, u, s.offset, part); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type segment struct {
+	file   File
+	offset int64
+	length int64
+}
+
+// segments splits the stream range [off, off+length) into per-file ranges.
+func (d *Downloader) segments(off, length int64) []segment {
+	var out []segment
+	var start int64
+	for _, f := range d.files {
+		end := start + f.Length
+		if end > off && length > 0 {
+			at := off - start
+			n := min(length, f.Length-at)
+			out = append(
+				out,
+				segment{file: f, offset: at, length: n},
+			)
+			off += n
+			length -= n
+		}
+		start = end
+	}
+	return out
+}
+
+// fileURL follows BEP 19: a URL ending in '/' names a directory holding the
+// torrent's name, and multi-file torrents always treat it as one.
+func (d *Downloader) fileURL(seed string, f File) string {
+	if !d.multiFile {
+		if strings.HasSuffix(seed, "/") {
+			return seed + url.PathEscape(d.name)
+		}
+		return seed
+	}
+
+	if !strings.HasSuffix(seed, "/") {
+		seed += "/"
+	}
+	parts := append([]string{d.name}, f.Path...)
+	for i, p := range parts {
+		parts[i] = url.PathEscape(p)
+	}
+	return seed + strings.Join(parts, "/")
+}
+
+func (d *Downloader) fetchRange(
+	ctx context.Context,
+	u string,
+	off int64,
+	dst []byte,
+) error {
+	ctx, cancel := context.WithTimeout(ctx, d.cfg.RequestTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set(
+		"Range",
+		fmt.Sprintf("bytes=%d-%d", off, off+int64(len(dst))-1),
+	)
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusPartialContent:
+	case resp.StatusCode == http.StatusOK && off == 0:
+		// The server ignored the range; the head of the body is the
+		// part we asked for.
+	default:
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	_, err = io.ReadFull(resp.Body, dst)
+	return err
+}
+
+func sleepCtx(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
@@ -3,13 +3,17 @@ package main
 import (
 	"context"
 	"embed"
+	"flag"
+	"fmt"
 	"log/slog"
 	"os"
-	"time"
+	"os/signal"
+	"syscall"
 
+	"github.com/prxssh/echo/internal/config"
+	"github.com/prxssh/echo/internal/engine"
+	"github.com/prxssh/echo/internal/geoip"
 	"github.com/prxssh/echo/internal/ui"
-	"github.com/prxssh/echo/internal/utils"
-	"github.com/prxssh/echo/pkg/logging"
 	"github.com/wailsapp/wails/v2"
 	"github.com/wailsapp/wails/v2/pkg/options"
 	"github.com/wailsapp/wails/v2/pkg/options/assetserver"
@@ -18,30 +22,51 @@ import (
 //go:embed all:frontend/dist
 var assets embed.FS
 
-func main() {
-	setupLogger()
+// geoData are the country databases used until fresher ones have been
+// downloaded.
+//
+//go:embed data/dbip-country-ipv4.mmdb data/dbip-country-ipv6.mmdb
+var geoData embed.FS
 
-	if err := utils.NewIP2CountryResolver(
-		"./data/dbip-country-ipv4.mmdb",
-		"./data/dbip-country-ipv6.mmdb",
-	); err != nil {
-		slog.Error(
-			"ip2country setup failed",
-			slog.String("error", err.Error()),
-		)
+func main() {
+	openProfile := config.ProfileFlags(flag.CommandLine)
+	flag.Parse()
+	profile, err := openProfile()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "echo:", err)
+		os.Exit(2)
+	}
+	eng, err := engine.Open(profile, engine.Options{
+		Level:    slog.LevelDebug,
+		KeepLogs: true,
+		GeoIP:    builtinGeoIP(),
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "echo:", err)
 		os.Exit(1)
 	}
+	app := ui.New(eng.Client, eng.Logs)
 
-	app := ui.New()
-
-	err := wails.Run(&options.App{
-		Title:      "Echo - BitTorrent Client & Search Engine",
+	err = wails.Run(&options.App{
+		Title:      ui.Title,
 		Fullscreen: true,
 		AssetServer: &assetserver.Options{
 			Assets: assets,
 		},
 		OnStartup: func(ctx context.Context) {
 			app.Startup(ctx)
+			eng.Start(ctx)
+			go quitOnSignal(ctx, app)
+		},
+		OnBeforeClose: func(ctx context.Context) bool {
+			return app.BeforeClose()
+		},
+		OnShutdown: func(ctx context.Context) {
+			app.Shutdown(ctx)
+			eng.Close(ctx)
+		},
+		DragAndDrop: &options.DragAndDrop{
+			EnableFileDrop: true,
 		},
 		Bind:             []any{app},
 		BackgroundColour: &options.RGBA{R: 27, G: 38, B: 54, A: 1},
@@ -55,22 +80,32 @@ func main() {
 	}
 }
 
-func setupLogger() {
-	opts := &logging.PrettyHandlerOptions{
-		SlogOpts: slog.HandlerOptions{
-			Level:     slog.LevelDebug,
-			AddSource: true,
-		},
-		UseColor:          true,
-		ShowSource:        true,
-		FullSource:        false,
-		CompactJSON:       false,
-		TimeFormat:        time.RFC3339,
-		LevelWidth:        7,
-		FieldSeparator:    " | ",
-		DisableHTMLEscape: true,
+// quitOnSignal closes the app on SIGINT or SIGTERM without asking, so the
+// engine gets to shut down cleanly.
+func quitOnSignal(ctx context.Context, app *ui.UI) {
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sig)
+
+	select {
+	case s := <-sig:
+		slog.Info("quitting", slog.String("signal", s.String()))
+		app.Quit()
+	case <-ctx.Done():
+	}
+}
+
+// builtinGeoIP returns the databases in geoData, for the client to use
+// until it has downloaded fresher ones.
+func builtinGeoIP() []geoip.Builtin {
+	var dbs []geoip.Builtin
+	for _, name := range []string{
+		"data/dbip-country-ipv4.mmdb",
+		"data/dbip-country-ipv6.mmdb",
+	} {
+		if data, err := geoData.ReadFile(name); err == nil {
+			dbs = append(dbs, geoip.Builtin{Data: data})
+		}
 	}
-	handler := logging.NewPrettyHandler(os.Stdout, opts)
-	logger := slog.New(handler)
-	slog.SetDefault(logger)
+	return dbs
 }
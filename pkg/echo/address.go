@@ -0,0 +1,147 @@
+package echo
+
+import (
+	"log/slog"
+	"net"
+	"net/netip"
+	"slices"
+	"time"
+)
+
+// Addresses are the machine's own addresses and the public one the DHT's
+// nodes see it at. A change is pushed as the "network:address" event.
+type Addresses struct {
+	// Local lists the interfaces' addresses, loopback and link-local
+	// ones left out, sorted.
+	Local []string `json:"local"`
+	// External is the last address the DHT's nodes agreed on, empty
+	// until they first do.
+	External string `json:"external"`
+}
+
+// moved reports whether the machine's addresses differ from prev's. An
+// external address only just agreed on, or forgotten while the DHT takes
+// a new ID, is not a move.
+func (a Addresses) moved(prev Addresses) bool {
+	if !slices.Equal(a.Local, prev.Local) {
+		return true
+	}
+	return a.External != "" && prev.External != "" &&
+		a.External != prev.External
+}
+
+// Addresses returns the addresses last read.
+func (c *Client) Addresses() Addresses {
+	c.addressMu.Lock()
+	defer c.addressMu.Unlock()
+
+	a := c.addresses
+	a.Local = slices.Clone(a.Local)
+	return a
+}
+
+// readAddresses returns the machine's addresses now.
+func (c *Client) readAddresses() (Addresses, error) {
+	var a Addresses
+	if c.dht != nil {
+		if ip := c.dht.ExternalIP(); ip.IsValid() {
+			a.External = ip.String()
+		}
+	}
+
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return a, err
+	}
+	a.Local = []string{}
+	for _, addr := range addrs {
+		prefix, err := netip.ParsePrefix(addr.String())
+		if err != nil {
+			continue
+		}
+		ip := prefix.Addr()
+		if ip.IsLoopback() || ip.IsLinkLocalUnicast() {
+			continue
+		}
+		a.Local = append(a.Local, ip.String())
+	}
+	slices.Sort(a.Local)
+	return a, nil
+}
+
+// addressChanged lets the swarms know where to find us after an address
+// changes: every running torrent announces at once, the DHT takes a new
+// node ID if a local address changed, and with ReconnectOnIPChange set
+// every peer is dropped and dialed again.
+func (c *Client) addressChanged(prev, now Addresses) {
+	slog.Info(
+		"address changed",
+		slog.Any("local", now.Local),
+		slog.String("external", now.External),
+	)
+	c.publish("network:address", now)
+
+	reconnect := c.GlobalSettings().ReconnectOnIPChange
+	for _, t := range c.torrents.List() {
+		if !t.State().Active() {
+			continue
+		}
+		t.TrackerManager.Reannounce()
+		if reconnect {
+			go t.PeerManager.Reconnect(c.ctx)
+		}
+	}
+
+	// A new external IP the nodes agreed on has had the DHT take an ID
+	// for it already.
+	if c.dht != nil && !slices.Equal(prev.Local, now.Local) {
+		go func() {
+			if err := c.dht.Renew(c.ctx); err != nil {
+				slog.Warn(
+					"renewing dht node id failed",
+					slog.String("error", err.Error()),
+				)
+			}
+		}()
+	}
+}
+
+// runAddresses reads the addresses every AddressInterval until the client
+// closes. The first read only sets the baseline.
+func (c *Client) runAddresses() {
+	if c.cfg.AddressInterval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(c.cfg.AddressInterval)
+	defer ticker.Stop()
+
+	first := true
+	for {
+		now, err := c.readAddresses()
+		if err != nil {
+			slog.Debug(
+				"reading addresses failed",
+				slog.String("error", err.Error()),
+			)
+		} else {
+			c.addressMu.Lock()
+			prev := c.addresses
+			c.addresses = now
+			if now.External == "" {
+				c.addresses.External = prev.External
+			}
+			c.addressMu.Unlock()
+
+			if !first && now.moved(prev) {
+				c.addressChanged(prev, now)
+			}
+			first = false
+		}
+
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
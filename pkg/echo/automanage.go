@@ -0,0 +1,114 @@
+package echo
+
+import (
+	"cmp"
+	"slices"
+	"time"
+)
+
+// wellSeeded is how many seeders per leecher, and at least how many in all,
+// make a swarm need us little enough to give our seeding slot away.
+const (
+	wellSeededRatio = 2
+	wellSeededMin   = 10
+)
+
+// seedNeed rates how much a finished torrent's swarm needs another seed:
+// leechers per seeder, from the trackers' last counts. A swarm with no
+// seeders needs one most; a well-seeded one not at all. Torrents whose
+// swarm was never seen get a neutral 1.
+func seedNeed(t *Torrent) float64 {
+	seeders, leechers, ok := t.TrackerManager.Swarm()
+	switch {
+	case !ok:
+		return 1
+	case seeders == 0:
+		return float64(leechers) + 2
+	case seeders >= wellSeededMin &&
+		seeders >= wellSeededRatio*(leechers+1):
+		return 0
+	}
+	return float64(leechers+1) / float64(seeders)
+}
+
+// rankSeeds orders the finished torrents by how much their swarms need
+// them, those that have seeded least first among equals, so idle seeds get
+// a turn as the ranking is redone. It runs every AutoManageInterval rather
+// than on every schedule, so active torrents aren't swapped back and forth.
+func (c *Client) rankSeeds() {
+	type ranked struct {
+		t    *Torrent
+		need float64
+		seed time.Duration
+	}
+	var seeds []ranked
+	for _, t := range c.ordered() {
+		if _, _, left := t.Totals(); left == 0 {
+			r := ranked{t: t, need: seedNeed(t), seed: t.SeedTime()}
+			seeds = append(seeds, r)
+		}
+	}
+	slices.SortStableFunc(seeds, func(a, b ranked) int {
+		if a.need != b.need {
+			return cmp.Compare(b.need, a.need)
+		}
+		return cmp.Compare(a.seed, b.seed)
+	})
+
+	rank := make(map[*Torrent]int, len(seeds))
+	for i, s := range seeds {
+		rank[s.t] = i
+	}
+	c.queueMu.Lock()
+	c.seedRank = rank
+	c.queueMu.Unlock()
+}
+
+// scheduleOrder is the order schedule hands out slots in. Downloads always
+// go in queue order. With AutoManage, seeds go by the last ranking, and
+// those finished since come last.
+func (c *Client) scheduleOrder() []*Torrent {
+	list := c.ordered()
+	if !c.cfg.AutoManage {
+		return list
+	}
+
+	c.queueMu.Lock()
+	rank := c.seedRank
+	c.queueMu.Unlock()
+
+	position := func(t *Torrent) int {
+		if _, _, left := t.Totals(); left > 0 {
+			return -1
+		}
+		if r, ok := rank[t]; ok {
+			return r
+		}
+		return len(rank)
+	}
+	slices.SortStableFunc(list, func(a, b *Torrent) int {
+		return cmp.Compare(position(a), position(b))
+	})
+	return list
+}
+
+// runAutoManage re-ranks the seeds every AutoManageInterval and hands the
+// slots out again, until the client closes.
+func (c *Client) runAutoManage() {
+	if !c.cfg.AutoManage || c.cfg.AutoManageInterval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(c.cfg.AutoManageInterval)
+	defer ticker.Stop()
+
+	for {
+		c.rankSeeds()
+		c.reschedule()
+
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,265 @@
+package echo
+
+import (
+	"crypto/sha1"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/prxssh/echo/internal/bandwidth"
+	"github.com/prxssh/echo/internal/peer"
+)
+
+// Priority weights a torrent's share of the global rate limits and
+// connection budget against the other active torrents.
+type Priority string
+
+const (
+	PriorityLow    Priority = "low"
+	PriorityNormal Priority = "normal"
+	PriorityHigh   Priority = "high"
+)
+
+func (p Priority) weight() int {
+	switch p {
+	case PriorityLow:
+		return 1
+	case PriorityHigh:
+		return 4
+	default:
+		return 2
+	}
+}
+
+func (p Priority) valid() bool {
+	switch p {
+	case "", PriorityLow, PriorityNormal, PriorityHigh:
+		return true
+	}
+	return false
+}
+
+const (
+	// bandwidthInterval is how often the global limits are shared out
+	// again, following the torrents' rates.
+	bandwidthInterval = 2 * time.Second
+	// minDemand is the least a torrent is assumed to want, so one that
+	// went quiet can pick up again.
+	minDemand = 16 << 10
+)
+
+// share is a torrent's cut of the global limits; zero fields leave the
+// torrent's own settings alone.
+type share struct {
+	download int64
+	upload   int64
+	peers    uint32
+}
+
+// Priority returns the torrent's priority.
+func (c *Client) Priority(infoHash [sha1.Size]byte) (Priority, error) {
+	t, ok := c.torrents.Get(infoHash)
+	if !ok {
+		return "", fmt.Errorf("echo: unknown torrent %x", infoHash)
+	}
+	return c.priorityFor(t), nil
+}
+
+// SetPriority changes the torrent's priority; the new weights take effect
+// the next time the limits are shared out.
+func (c *Client) SetPriority(infoHash [sha1.Size]byte, p Priority) error {
+	if !p.valid() {
+		return fmt.Errorf("echo: unknown priority %q", p)
+	}
+	o, err := c.Overrides(infoHash)
+	if err != nil {
+		return err
+	}
+	o.Priority = &p
+	if p == PriorityNormal {
+		o.Priority = nil
+	}
+	return c.SetOverrides(infoHash, o)
+}
+
+func (c *Client) priorityFor(t *Torrent) Priority {
+	if p := c.overridesFor(t).Priority; p != nil {
+		return *p
+	}
+	return PriorityNormal
+}
+
+// RateLimits cap download and upload rates, in bytes per second. Zero is
+// unlimited.
+type RateLimits struct {
+	Download int64 `json:"download"`
+	Upload   int64 `json:"upload"`
+}
+
+// SetRateLimits changes the global rate limits and saves them with the
+// settings. They are shared out among the active torrents straight away.
+func (c *Client) SetRateLimits(l RateLimits) error {
+	_, err := c.UpdateSettings(SettingsPatch{
+		DownloadLimit: &l.Download,
+		UploadLimit:   &l.Upload,
+	})
+	return err
+}
+
+// SetTorrentRateLimits gives the torrent rate limits of its own, which
+// take effect straight away; nil goes back to the client's. The limits in
+// effect are reported in the torrent's status.
+func (c *Client) SetTorrentRateLimits(
+	infoHash [sha1.Size]byte,
+	l *RateLimits,
+) error {
+	if l != nil && (l.Download < 0 || l.Upload < 0) {
+		return errors.New("echo: rate limits cannot be negative")
+	}
+	o, err := c.Overrides(infoHash)
+	if err != nil {
+		return err
+	}
+	o.DownloadLimit, o.UploadLimit = nil, nil
+	if l != nil {
+		o.DownloadLimit, o.UploadLimit = &l.Download, &l.Upload
+	}
+	return c.SetOverrides(infoHash, o)
+}
+
+// runBandwidth shares the global limits out among the active torrents
+// until the client closes.
+func (c *Client) runBandwidth() {
+	ticker := time.NewTicker(bandwidthInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		c.shareBandwidth()
+	}
+}
+
+// shareBandwidth splits Config.DownloadLimit and UploadLimit, tightened
+// while throttled for power, among the active torrents by priority, giving what one can't use to the others, and
+// Config.MaxConnections by priority alone.
+func (c *Client) shareBandwidth() {
+	var (
+		active  []*Torrent
+		weights []int
+		down    []bandwidth.Claim
+		up      []bandwidth.Claim
+	)
+	for _, t := range c.ordered() {
+		if !t.State().Active() {
+			continue
+		}
+		w := c.priorityFor(t).weight()
+		own := c.overridesFor(t).apply(c.cfg.Torrent)
+		applied := t.Settings()
+		downRate, upRate := t.Rates()
+		_, _, left := t.Totals()
+
+		downClaim := bandwidth.Claim{Weight: w}
+		if left > 0 {
+			downClaim.Demand = demand(
+				downRate,
+				applied.DownloadLimit,
+				own.DownloadLimit,
+			)
+		}
+		active = append(active, t)
+		weights = append(weights, w)
+		down = append(down, downClaim)
+		up = append(up, bandwidth.Claim{
+			Weight: w,
+			Demand: demand(
+				upRate,
+				applied.UploadLimit,
+				own.UploadLimit,
+			),
+		})
+	}
+
+	downLimit, upLimit := c.rateLimits()
+	downShares := bandwidth.Allocate(downLimit, down)
+	upShares := bandwidth.Allocate(upLimit, up)
+	var slots []int
+	if n := c.GlobalSettings().MaxConnections; n > 0 {
+		slots = bandwidth.Slots(n, weights)
+	}
+
+	shares := make(map[*Torrent]share, len(active))
+	for i, t := range active {
+		sh := share{download: downShares[i], upload: upShares[i]}
+		if slots != nil {
+			sh.peers = uint32(slots[i])
+		}
+		// A torrent with nothing left to download still needs a
+		// trickle for the pieces it re-requests.
+		if downLimit > 0 && sh.download == 0 {
+			sh.download = minDemand
+		}
+		shares[t] = sh
+	}
+
+	c.bandwidthMu.Lock()
+	c.shares = shares
+	c.bandwidthMu.Unlock()
+
+	for _, t := range active {
+		if s := c.settingsFor(t); s != t.Settings() {
+			t.Apply(s)
+		}
+	}
+}
+
+// demand estimates the rate a torrent could use. One running close to the
+// limit it was given may want more than it shows, so it is taken to want
+// anything; otherwise it gets headroom over its current rate. Its own limit
+// caps either.
+func demand(rate float64, applied, own int64) int64 {
+	d := int64(-1)
+	if applied <= 0 || rate < 0.9*float64(applied) {
+		d = max(int64(rate*1.25), minDemand)
+	}
+	if own > 0 && (d < 0 || d > own) {
+		d = own
+	}
+	return d
+}
+
+// withShare narrows s to the torrent's share of the global limits.
+func (c *Client) withShare(t *Torrent, s TorrentSettings) TorrentSettings {
+	c.bandwidthMu.Lock()
+	sh, ok := c.shares[t]
+	c.bandwidthMu.Unlock()
+	if !ok {
+		return s
+	}
+
+	s.DownloadLimit = tighter(s.DownloadLimit, sh.download)
+	s.UploadLimit = tighter(s.UploadLimit, sh.upload)
+	if sh.peers > 0 {
+		if s.MaxPeers == 0 {
+			s.MaxPeers = peer.DefaultMaxPeers
+		}
+		s.MaxPeers = min(s.MaxPeers, sh.peers)
+	}
+	return s
+}
+
+// tighter returns the stricter of two rate limits, where zero is none.
+func tighter(a, b int64) int64 {
+	switch {
+	case a <= 0:
+		return b
+	case b <= 0:
+		return a
+	default:
+		return min(a, b)
+	}
+}
@@ -0,0 +1,68 @@
+package echo
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// AddRequest is one torrent of a batch, given by exactly one of a .torrent's
+// contents, a path to one, a URL to download it from or a magnet link.
+type AddRequest struct {
+	Data    []byte     `json:"data"`
+	Path    string     `json:"path"`
+	URL     string     `json:"url"`
+	Magnet  string     `json:"magnet"`
+	Options AddOptions `json:"options"`
+}
+
+// AddResult is how adding one torrent of a batch went. Duplicate is set,
+// along with Error, for torrents the session already has.
+type AddResult struct {
+	Torrent   *Torrent `json:"torrent"`
+	Error     string   `json:"error"`
+	Duplicate bool     `json:"duplicate"`
+}
+
+// AddBatch adds each request with its own options and reports on each in
+// the same order. The requests are added side by side, so a magnet link
+// waiting on its metadata doesn't hold up the rest; AddBatch returns once
+// every one has been added or failed, or ctx is done.
+func (c *Client) AddBatch(ctx context.Context, reqs []AddRequest) []AddResult {
+	results := make([]AddResult, len(reqs))
+
+	var wg sync.WaitGroup
+	for i, req := range reqs {
+		wg.Go(func() {
+			t, err := c.addRequest(ctx, req)
+			if err != nil {
+				results[i] = AddResult{
+					Error:     err.Error(),
+					Duplicate: errors.Is(err, ErrDuplicate),
+				}
+				return
+			}
+			results[i] = AddResult{Torrent: t}
+		})
+	}
+	wg.Wait()
+
+	return results
+}
+
+func (c *Client) addRequest(
+	ctx context.Context,
+	req AddRequest,
+) (*Torrent, error) {
+	switch {
+	case req.Data != nil:
+		return c.AddTorrent(req.Data, req.Options)
+	case req.Path != "":
+		return c.AddTorrentFile(req.Path, req.Options)
+	case req.URL != "":
+		return c.AddTorrentURL(ctx, req.URL, req.Options)
+	case req.Magnet != "":
+		return c.AddMagnet(ctx, req.Magnet, req.Options)
+	}
+	return nil, errors.New("echo: nothing to add")
+}
@@ -0,0 +1,940 @@
+// Package echo is the BitTorrent engine behind the Echo GUI, usable on its
+// own by daemons and other Go programs.
+package echo
+
+import (
+	"context"
+	"crypto/sha1"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/prxssh/echo/internal/bitfield"
+	"github.com/prxssh/echo/internal/dht"
+	"github.com/prxssh/echo/internal/events"
+	"github.com/prxssh/echo/internal/geoip"
+	"github.com/prxssh/echo/internal/peer"
+	"github.com/prxssh/echo/internal/search"
+	"github.com/prxssh/echo/internal/torrent"
+	"github.com/prxssh/echo/internal/udpmux"
+	"github.com/prxssh/echo/internal/utils"
+)
+
+// Torrent is a torrent in the session.
+type Torrent = torrent.Torrent
+
+type (
+	DHTConfig   = dht.Config
+	DHTStats    = dht.Stats
+	GeoIPConfig = geoip.Config
+	PeerConfig  = peer.Config
+	State       = torrent.State
+	StateChange = torrent.StateChange
+	// TorrentStatus is a snapshot of a torrent's progress and rates.
+	TorrentStatus = torrent.Status
+	// TorrentSettings tune how a torrent runs.
+	TorrentSettings = torrent.Settings
+	// TorrentDetails lists a torrent's files, pieces, trackers and peers.
+	TorrentDetails = torrent.Details
+	// Fingerprint is how the client introduces itself to peers and
+	// trackers.
+	Fingerprint       = torrent.Fingerprint
+	FingerprintPreset = torrent.FingerprintPreset
+)
+
+// ErrDuplicate is returned when adding a torrent the session already has,
+// under any of its info-hashes.
+var ErrDuplicate = torrent.ErrDuplicate
+
+// ErrClosed is returned when adding a torrent to a client that is shutting
+// down.
+var ErrClosed = errors.New("echo: client closed")
+
+type Config struct {
+	// DownloadDir is where torrents' files are written.
+	DownloadDir string
+	// TorrentsDir keeps a .torrent for every magnet link whose metadata
+	// was fetched, so it can be re-added without asking peers again.
+	// Empty disables this.
+	TorrentsDir string
+	// SessionPath is where the session's torrents are listed so they are
+	// re-added on the next Start. Empty disables this.
+	SessionPath string
+	// HistoryPath is where completed downloads are recorded. Empty keeps
+	// the history in memory only.
+	HistoryPath string
+	// JournalDir holds a journal of each torrent's piece writes, so that
+	// after a crash only the pieces being written are checked again.
+	// Empty disables journaling.
+	JournalDir string
+	// DisableDHT turns the DHT node off; torrents then find peers through
+	// their trackers only.
+	DisableDHT bool
+	DHT        DHTConfig
+	// ListenPort is the TCP port peers connect to us on, announced to
+	// trackers and the DHT. Zero picks a free port on every Start.
+	ListenPort uint16
+	// Peer tunes every torrent's connections to peers. MaxPeers is the
+	// limit of torrents without one of their own.
+	Peer PeerConfig
+	// SeparateUDP gives UDP trackers sockets of their own rather than
+	// sharing the DHT's port, which NATs and trackers expect announces
+	// to come from.
+	SeparateUDP bool
+	// GeoIP says where the country databases peers are looked up in are
+	// kept and downloaded from. Without a Dir or sources, peers show no
+	// country.
+	GeoIP GeoIPConfig
+	// MaxActiveDownloads and MaxActiveSeeds cap how many torrents run at
+	// once; the rest wait in the queue. Zero means no limit.
+	MaxActiveDownloads int
+	MaxActiveSeeds     int
+	// AutoManage hands the seeding slots to the torrents whose swarms
+	// need them most rather than going by queue order, re-ranking every
+	// AutoManageInterval so the other seeds get a turn.
+	AutoManage         bool
+	AutoManageInterval time.Duration
+	// Torrent holds the settings of every torrent without overrides.
+	Torrent TorrentSettings
+	// DownloadLimit and UploadLimit cap the session's transfer rates in
+	// bytes per second, shared among active torrents by priority. Zero
+	// is unlimited.
+	DownloadLimit int64
+	UploadLimit   int64
+	// MaxConnections caps the peers connected across every torrent,
+	// shared by priority. Zero is unlimited.
+	MaxConnections int
+	// ShareLimits apply to every torrent without limits of its own.
+	ShareLimits ShareLimits
+	// WatchFolders are scanned every WatchInterval for .torrent and
+	// .magnet files to add. Folders saved with the session take their
+	// place.
+	WatchFolders  []WatchFolder
+	WatchInterval time.Duration
+	// PauseWindows are weekly times during which every transfer is
+	// paused. Windows saved with the session take their place.
+	PauseWindows []PauseWindow
+	// Power says how to back off on battery or a metered connection,
+	// which is checked every PowerInterval. A policy saved with the
+	// session takes its place.
+	Power         PowerPolicy
+	PowerInterval time.Duration
+	// Quotas cap disk usage and the month's transfer. Quotas saved with
+	// the session take their place.
+	Quotas Quotas
+	// StatsInterval is how often "session:stats" is pushed. Zero turns
+	// the event off; SessionStats still works.
+	StatsInterval time.Duration
+	// RSSInterval is how often feeds without an interval of their own
+	// are fetched, and SavedSearchInterval how often saved searches
+	// without one are run.
+	RSSInterval         time.Duration
+	SavedSearchInterval time.Duration
+	// SearchTimeout bounds how long Search waits for each indexer. Zero
+	// waits as long as Search's context allows.
+	SearchTimeout time.Duration
+	// SearchCacheTTL is how long an indexer's results are reused for
+	// the same query, SearchInterval the least time between two searches
+	// of an indexer, and SearchMaxBackoff the longest an indexer that
+	// keeps failing is skipped for.
+	SearchCacheTTL   time.Duration
+	SearchInterval   time.Duration
+	SearchMaxBackoff time.Duration
+	// ScrapeTrackers are scraped for the swarm sizes of search results,
+	// which then rank by those rather than by what the indexers said.
+	// SwarmCheckTimeout bounds the check; zero turns it off.
+	ScrapeTrackers    []string
+	SwarmCheckTimeout time.Duration
+	// CredentialsPath is where indexers' API keys are kept, readable by
+	// the user alone, instead of in the session file. Empty keeps them
+	// in the session file.
+	CredentialsPath string
+	// IndexPath is where the local search index, built from crawling
+	// the DHT while GlobalSettings.CrawlDHT is on, is kept. Empty keeps
+	// it in memory only.
+	IndexPath string
+	// RetryBackoff is how long a torrent that failed with a transient
+	// error, such as a full disk, waits before it is started again. The
+	// wait doubles on each further failure, up to MaxRetryBackoff. Zero
+	// turns automatic retries off.
+	RetryBackoff    time.Duration
+	MaxRetryBackoff time.Duration
+	// SettingsPath is where the settings changed with UpdateSettings are
+	// saved. Empty keeps them for this run only.
+	SettingsPath string
+	// Proxy, Encryption, CrawlDHT, LogLevels, DebugAddr and Fingerprint
+	// are described with GlobalSettings.
+	Proxy       string
+	Encryption  Encryption
+	CrawlDHT    bool
+	LogLevels   map[string]slog.Level
+	DebugAddr   string
+	Fingerprint Fingerprint
+	// Notifications says which "notification" events are sent, and
+	// whether they are also shown by the system.
+	Notifications NotifySettings
+	// PortCheckURL, if set, is asked by CheckPort whether the port is
+	// open, with "{port}" replaced by it; see askPortService. Otherwise
+	// CheckPort waits up to PortCheckTimeout for a peer to connect.
+	PortCheckURL     string
+	PortCheckTimeout time.Duration
+	// AddressInterval is how often the machine's addresses are read for
+	// changes; see Addresses. Zero never reads them.
+	AddressInterval time.Duration
+	// ReconnectOnIPChange is described with GlobalSettings.
+	ReconnectOnIPChange bool
+	// ShutdownTimeout bounds how long Close waits for torrents to stop.
+	// Zero waits as long as Close's context allows.
+	ShutdownTimeout time.Duration
+}
+
+// DefaultConfig downloads to ~/Downloads and keeps state below the user's
+// config directory.
+func DefaultConfig() Config {
+	cfg := Config{
+		DHT:                 dht.DefaultConfig(),
+		ListenPort:          6881,
+		Peer:                peer.DefaultConfig(),
+		GeoIP:               geoip.DefaultConfig(),
+		MaxActiveDownloads:  3,
+		MaxActiveSeeds:      5,
+		MaxConnections:      500,
+		AutoManageInterval:  15 * time.Minute,
+		ShutdownTimeout:     10 * time.Second,
+		PortCheckTimeout:    time.Minute,
+		WatchInterval:       5 * time.Second,
+		RSSInterval:         30 * time.Minute,
+		SavedSearchInterval: time.Hour,
+		SearchTimeout:       30 * time.Second,
+		SearchCacheTTL:      10 * time.Minute,
+		SearchInterval:      2 * time.Second,
+		SearchMaxBackoff:    15 * time.Minute,
+		ScrapeTrackers:      DefaultScrapeTrackers,
+		SwarmCheckTimeout:   10 * time.Second,
+		StatsInterval:       2 * time.Second,
+		RetryBackoff:        30 * time.Second,
+		PowerInterval:       30 * time.Second,
+		AddressInterval:     30 * time.Second,
+		MaxRetryBackoff:     time.Hour,
+		Encryption:          EncryptionPrefer,
+		Notifications: NotifySettings{
+			Completed: true,
+			Errored:   true,
+			Tracker:   true,
+			Search:    true,
+		},
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		cfg.DownloadDir = filepath.Join(home, "Downloads")
+	}
+	if dir, err := os.UserConfigDir(); err == nil {
+		cfg.SetStateDir(filepath.Join(dir, "echo"))
+	}
+	return cfg
+}
+
+// SetStateDir keeps the session's state, from the DHT's routing table to
+// the saved settings, below dir, so that instances given different ones
+// share nothing.
+func (cfg *Config) SetStateDir(dir string) {
+	cfg.DHT.StatePath = filepath.Join(dir, "dht.json")
+	cfg.GeoIP.Dir = filepath.Join(dir, "geoip")
+	cfg.TorrentsDir = filepath.Join(dir, "torrents")
+	cfg.SessionPath = filepath.Join(dir, "session.json")
+	cfg.JournalDir = filepath.Join(dir, "journal")
+	cfg.HistoryPath = filepath.Join(dir, "history.json")
+	cfg.SettingsPath = filepath.Join(dir, "settings.json")
+	cfg.IndexPath = filepath.Join(dir, "index.json")
+	cfg.CredentialsPath = filepath.Join(dir, "credentials.json")
+}
+
+// Event is a notification from the engine, such as "tracker:announce",
+// "torrent:state" or "dht:stats". Events about a torrent carry its
+// info-hash and name, and are sent a second time with ":<info-hash>"
+// appended to the name. Seq numbers the events in the order they were
+// published, for a Stream to pick up where it left off.
+type Event struct {
+	Seq  uint64
+	Name string
+	Data any
+}
+
+// Stats sums up the session.
+type Stats struct {
+	Torrents   int      `json:"torrents"`
+	Uploaded   uint64   `json:"uploaded"`
+	Downloaded uint64   `json:"downloaded"`
+	DHT        DHTStats `json:"dht"`
+}
+
+// Client is a session: the torrents it runs and the DHT node they share.
+type Client struct {
+	// cfgMu guards the Config fields that Settings covers; the rest
+	// never change.
+	cfgMu   sync.RWMutex
+	cfg     Config
+	http    *http.Client
+	ctx     context.Context
+	cancel  context.CancelFunc
+	closing atomic.Bool
+	dht     *dht.DHT
+	// udp is the socket the DHT and UDP trackers share, nil if each has
+	// its own.
+	udp *udpmux.Mux
+	// listener takes the connections of peers, nil if the port could not
+	// be opened. lastInbound is when a peer from outside the local
+	// network last connected, in Unix nanoseconds.
+	listener    net.Listener
+	lastInbound atomic.Int64
+	torrents    *torrent.Registry
+	// geo looks peers up in the databases runGeoIP keeps fresh.
+	geo *utils.IP2CountryResolver
+
+	// sessionMu guards magnets, the links whose metadata is being
+	// fetched, and serializes writes to the session file.
+	sessionMu sync.Mutex
+	magnets   map[[sha1.Size]byte]pendingMagnet
+
+	// queueMu guards queue, every torrent in the order active slots are
+	// handed out, and seedRank, the seeds' order under AutoManage. kick
+	// wakes the loop that hands the slots out.
+	queueMu  sync.Mutex
+	queue    []*Torrent
+	seedRank map[*Torrent]int
+	kick     chan struct{}
+
+	// labelsMu guards the categories and the torrents' labels.
+	labelsMu   sync.Mutex
+	categories map[string]Category
+	labels     map[*Torrent]Labels
+
+	// watchMu guards watch, the folders being watched.
+	watchMu sync.Mutex
+	watch   []WatchFolder
+
+	// rssMu guards the feeds, the GUIDs of the items downloaded from each
+	// and when each was last fetched.
+	rssMu     sync.Mutex
+	feeds     []Feed
+	rssSeen   map[string]map[string]bool
+	rssPolled map[string]time.Time
+
+	// searchMu guards indexers, the APIs searched for torrents.
+	searchMu sync.Mutex
+	indexers []Indexer
+	// throttle caches and paces the indexers' searches, and monitor
+	// keeps track of how they go.
+	throttle *search.Throttle
+	monitor  *search.Monitor
+	// index holds the torrents found crawling the DHT.
+	index *search.Index
+
+	// savedMu guards the saved searches, the results each has already
+	// turned up and when each was last run.
+	savedMu    sync.Mutex
+	saved      []SavedSearch
+	savedSeen  map[string]map[string]bool
+	savedRunAt map[string]time.Time
+
+	// overridesMu guards overrides, the torrents' own settings.
+	overridesMu sync.Mutex
+	overrides   map[*Torrent]Overrides
+
+	// statsMu guards the transfer totals and the per-torrent samples
+	// they are built from.
+	statsMu    sync.Mutex
+	started    time.Time
+	session    Transfer
+	allTime    Transfer
+	pastUptime time.Duration
+	samples    map[*Torrent]Transfer
+	// month is what was transferred since monthStart, for the monthly
+	// cap.
+	month      Transfer
+	monthStart time.Time
+
+	// speedMu guards the rate histories of the session and of each
+	// torrent that has transferred anything.
+	speedMu       sync.Mutex
+	sessionSpeeds *speedHistory
+	torrentSpeeds map[*Torrent]*speedHistory
+
+	// bandwidthMu guards shares, the active torrents' cuts of the global
+	// limits.
+	bandwidthMu sync.Mutex
+	shares      map[*Torrent]share
+
+	// timetableMu guards pauseWindows; inWindow is set while one of them
+	// is in force.
+	timetableMu  sync.Mutex
+	pauseWindows []PauseWindow
+	inWindow     atomic.Bool
+
+	// powerMu guards the power policy and the last power state read;
+	// powerPaused is set while the policy pauses the session.
+	powerMu     sync.Mutex
+	powerPolicy PowerPolicy
+	power       PowerStatus
+	powerPaused atomic.Bool
+
+	// quotaMu guards the quotas and how near the session last was to
+	// them; quotaPaused is set while the monthly cap is reached.
+	quotaMu     sync.Mutex
+	quotas      Quotas
+	quotaStatus QuotaStatus
+	quotaPaused atomic.Bool
+
+	// addressMu guards addresses, the machine's as last read.
+	addressMu sync.Mutex
+	addresses Addresses
+
+	// suspended is set while a pause window, the power policy or the
+	// monthly cap holds every torrent in the queue.
+	suspended atomic.Bool
+
+	// historyMu guards history, the completed downloads, oldest first.
+	historyMu sync.Mutex
+	history   []HistoryEntry
+
+	// retryMu guards retries, the planned restarts of errored torrents.
+	retryMu sync.Mutex
+	retries map[*Torrent]*retry
+
+	// debugMu guards debug, the server of DebugAddr while one runs.
+	debugMu sync.Mutex
+	debug   *debugServer
+
+	subMu  sync.RWMutex
+	subs   map[int]func(Event)
+	nextID int
+
+	// streamMu guards the last event's number, the backlog of recent
+	// events, and the streams they are sent down.
+	streamMu sync.Mutex
+	seq      uint64
+	backlog  [streamBacklog]Event
+	streams  map[*stream]struct{}
+}
+
+// New creates a client; a nil cfg means DefaultConfig.
+func New(cfg *Config) *Client {
+	c := &Client{
+		torrents:   torrent.NewRegistry(),
+		geo:        utils.NewIP2CountryResolver(),
+		magnets:    make(map[[sha1.Size]byte]pendingMagnet),
+		kick:       make(chan struct{}, 1),
+		overrides:  make(map[*Torrent]Overrides),
+		categories: make(map[string]Category),
+		labels:     make(map[*Torrent]Labels),
+		rssSeen:    make(map[string]map[string]bool),
+		rssPolled:  make(map[string]time.Time),
+		savedSeen:  make(map[string]map[string]bool),
+		savedRunAt: make(map[string]time.Time),
+		samples:    make(map[*Torrent]Transfer),
+		retries:    make(map[*Torrent]*retry),
+		subs:       make(map[int]func(Event)),
+		streams:    make(map[*stream]struct{}),
+		index:      search.NewIndex(LocalIndexName, nil),
+		monitor:    search.NewMonitor(),
+
+		sessionSpeeds: newSpeedHistory(),
+		torrentSpeeds: make(map[*Torrent]*speedHistory),
+	}
+	if cfg == nil {
+		c.cfg = DefaultConfig()
+	} else {
+		c.cfg = *cfg
+	}
+	c.watch = slices.Clone(c.cfg.WatchFolders)
+	c.pauseWindows = slices.Clone(c.cfg.PauseWindows)
+	c.powerPolicy = c.cfg.Power
+	c.quotas = c.cfg.Quotas
+	c.throttle = search.NewThrottle(search.ThrottleConfig{
+		CacheTTL:   c.cfg.SearchCacheTTL,
+		Interval:   c.cfg.SearchInterval,
+		MaxBackoff: c.cfg.SearchMaxBackoff,
+	})
+	c.http = &http.Client{
+		Timeout:       30 * time.Second,
+		Transport:     &http.Transport{Proxy: c.proxy},
+		CheckRedirect: stopAtMagnet,
+	}
+	return c
+}
+
+// Start brings the session up and re-adds the torrents of the last one.
+// Torrents run until Close or until ctx is done. If the listen port cannot
+// be opened or the DHT fails to start, the error is returned but the client
+// stays usable without them, dialing peers only.
+func (c *Client) Start(ctx context.Context) error {
+	ctx = events.WithSink(ctx, c.publish)
+	c.ctx, c.cancel = context.WithCancel(ctx)
+	c.started = time.Now()
+
+	c.loadSettings()
+	c.startUDP()
+	err := errors.Join(c.startListener(), c.startDHT())
+	c.loadHistory()
+	c.loadIndex()
+	go c.runHistory()
+	go c.runNotifications()
+	go c.runQueue()
+	go c.runShareLimits()
+	go c.runWatchFolders()
+	go c.runRSS()
+	go c.runSavedSearches()
+	go c.runStats()
+	go c.runSpeeds()
+	go c.runBandwidth()
+	go c.runAutoManage()
+	go c.runRetries()
+	go c.runTimetable()
+	go c.runPower()
+	go c.runQuotas()
+	go c.runAddresses()
+	go c.runCrawler()
+	go c.runGeoIP()
+	c.serveDebug()
+	c.restoreSession()
+	return err
+}
+
+// startUDP opens the DHT's port for the DHT and UDP trackers to share.
+// If it cannot be opened, each opens a socket of its own.
+func (c *Client) startUDP() {
+	if c.cfg.SeparateUDP {
+		return
+	}
+	c.cfgMu.RLock()
+	port := c.cfg.DHT.Port
+	c.cfgMu.RUnlock()
+
+	udp, err := udpmux.Listen(port)
+	if err != nil {
+		slog.Warn(
+			"opening shared udp port failed",
+			slog.Int("port", int(port)),
+			slog.String("error", err.Error()),
+		)
+		return
+	}
+	c.udp = udp
+}
+
+func (c *Client) startDHT() error {
+	if c.cfg.DisableDHT {
+		return nil
+	}
+	c.cfgMu.RLock()
+	cfg := c.cfg.DHT
+	c.cfgMu.RUnlock()
+	if c.udp != nil {
+		cfg.Conn = c.udp.Listen(udpmux.DHT)
+	}
+	d, err := dht.New(&cfg)
+	if err != nil {
+		return fmt.Errorf("echo: dht: %w", err)
+	}
+	if err := d.Start(c.ctx); err != nil {
+		return fmt.Errorf("echo: dht: %w", err)
+	}
+	c.dht = d
+	return nil
+}
+
+// Close shuts the session down: it stops taking new torrents, saves the
+// session, stops every torrent (announcing event=stopped and flushing its
+// files) and then the DHT. Torrents still stopping after ShutdownTimeout,
+// or when ctx is done, are abandoned and the context's error is returned.
+func (c *Client) Close(ctx context.Context) error {
+	if c.closing.Swap(true) {
+		return nil
+	}
+	c.persistSession()
+	c.saveIndex()
+	if c.listener != nil {
+		c.listener.Close()
+	}
+
+	if c.cfg.ShutdownTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.cfg.ShutdownTimeout)
+		defer cancel()
+	}
+
+	var wg sync.WaitGroup
+	for _, t := range c.ordered() {
+		c.torrents.Remove(t.Metainfo.Info.Hash)
+		c.dequeue(t)
+		wg.Go(func() { t.Stop(ctx) })
+	}
+	stopped := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(stopped)
+	}()
+
+	var err error
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		err = ctx.Err()
+		slog.Warn(
+			"shutdown timed out; abandoning torrents",
+			slog.String("error", err.Error()),
+		)
+	}
+
+	if c.dht != nil {
+		c.dht.Stop()
+	}
+	if c.udp != nil {
+		c.udp.Close()
+	}
+	c.stopDebug()
+	if c.cancel != nil {
+		c.cancel()
+	}
+	return err
+}
+
+// Subscribe calls fn for every event until the returned function is called.
+// fn runs on the engine's goroutines and must not block.
+func (c *Client) Subscribe(fn func(Event)) (unsubscribe func()) {
+	c.subMu.Lock()
+	defer c.subMu.Unlock()
+
+	id := c.nextID
+	c.nextID++
+	c.subs[id] = fn
+
+	return func() {
+		c.subMu.Lock()
+		defer c.subMu.Unlock()
+		delete(c.subs, id)
+	}
+}
+
+func (c *Client) publish(name string, data any) {
+	e := c.record(name, data)
+
+	c.subMu.RLock()
+	defer c.subMu.RUnlock()
+
+	for _, fn := range c.subs {
+		fn(e)
+	}
+}
+
+// AddOptions say where a torrent goes and how it starts as it is added.
+type AddOptions struct {
+	// Category files the torrent under an existing category, and
+	// downloads it to the category's save path.
+	Category string   `json:"category"`
+	Tags     []string `json:"tags"`
+	// SavePath downloads the torrent here rather than to the category's
+	// save path.
+	SavePath string `json:"savePath"`
+	// Paused adds the torrent without queueing it to start.
+	Paused     bool `json:"paused"`
+	Sequential bool `json:"sequential"`
+	// Files are the indices of the files to download; nil downloads
+	// them all. For a magnet link it takes the place of so=, so the
+	// selection is made before the metadata arrives.
+	Files []int `json:"files"`
+}
+
+// AddTorrent parses a .torrent file and starts downloading it.
+func (c *Client) AddTorrent(data []byte, opts AddOptions) (*Torrent, error) {
+	ao, err := c.addOptsFor(opts)
+	if err != nil {
+		return nil, err
+	}
+	return c.add(data, ao)
+}
+
+type addOpts struct {
+	downloadDir string
+	selectOnly  []int
+	priorities  []FilePriority
+	labels      Labels
+	// paused adds the torrent without queueing it to start.
+	paused     bool
+	sequential bool
+	// restored carries counters and limits over from the last session.
+	restored *sessionEntry
+}
+
+// addOptsFor checks opts and works out where the torrent is downloaded.
+func (c *Client) addOptsFor(opts AddOptions) (addOpts, error) {
+	dir, err := c.savePath(opts.Category)
+	if err != nil {
+		return addOpts{}, err
+	}
+	if opts.SavePath != "" {
+		dir = opts.SavePath
+	}
+	return addOpts{
+		downloadDir: dir,
+		selectOnly:  opts.Files,
+		labels: Labels{
+			Category: opts.Category,
+			Tags:     cleanTags(opts.Tags),
+		},
+		paused:     opts.Paused,
+		sequential: opts.Sequential,
+	}, nil
+}
+
+func (c *Client) add(data []byte, opts addOpts) (*Torrent, error) {
+	if c.ctx == nil {
+		return nil, errors.New("echo: client not started")
+	}
+	if c.closing.Load() {
+		return nil, ErrClosed
+	}
+
+	t, err := torrent.ParseTorrent(data, torrent.Opts{
+		DHT:            c.dht,
+		UDP:            c.udp,
+		DownloadDir:    opts.downloadDir,
+		SelectOnly:     opts.selectOnly,
+		FilePriorities: opts.priorities,
+		Proxy:          c.proxy,
+		Locator:        c.geo,
+		Fingerprint:    c.GlobalSettings().Fingerprint,
+		ListenPort:     c.ListenPort(),
+		Peer:           &c.cfg.Peer,
+	})
+	if err != nil {
+		return nil, err
+	}
+	// Registering first turns a duplicate away before it opens, and so
+	// disturbs, the journal the torrent already added writes to.
+	if err := c.torrents.Add(t); err != nil {
+		return nil, err
+	}
+	if c.cfg.JournalDir != "" {
+		if err := t.OpenJournal(c.cfg.JournalDir); err != nil {
+			c.torrents.Remove(t.Metainfo.Info.Hash)
+			return nil, err
+		}
+	}
+	if e := opts.restored; e != nil {
+		t.RestoreCounters(e.Uploaded, e.Downloaded, e.SeedTime)
+		if len(e.Blocks) > 0 {
+			err = t.RestoreBlocks(e.Blocks)
+		}
+		if err != nil {
+			slog.Warn(
+				"restoring torrent blocks failed",
+				slog.String("name", t.Metainfo.Info.Name),
+				slog.String("error", err.Error()),
+			)
+		}
+		have := bitfield.FromBytes(
+			e.Have,
+			t.Metainfo.Info.NumPieces,
+		)
+		if err := t.RestorePieces(have); err != nil {
+			slog.Warn(
+				"restoring torrent pieces failed",
+				slog.String("name", t.Metainfo.Info.Name),
+				slog.String("error", err.Error()),
+			)
+		}
+	}
+	reused := opts.restored == nil && c.reuseFiles(t)
+	c.enqueue(t)
+	c.setLabels(t, opts.labels)
+	if e := opts.restored; e != nil && e.Overrides != nil {
+		c.setOverrides(t, *e.Overrides)
+	} else if opts.sequential {
+		sequential := true
+		c.setOverrides(t, Overrides{Sequential: &sequential})
+	}
+	t.Apply(c.settingsFor(t))
+	switch {
+	case reused:
+		// Files taken from other torrents are checked first.
+		go c.checkAndStart([]*Torrent{t}, []bool{!opts.paused})
+	case !opts.paused:
+		t.Queue(c.ctx)
+		c.reschedule()
+	}
+	c.saveSession()
+
+	return t, nil
+}
+
+// AddMagnet fetches the metadata for a magnet link from peers and starts
+// downloading the files it selects. It blocks until the metadata arrives or
+// ctx is done; see AddMagnetAsync for the events sent meanwhile.
+func (c *Client) AddMagnet(
+	ctx context.Context,
+	uri string,
+	opts AddOptions,
+) (*Torrent, error) {
+	f, err := c.prepareMagnet(uri, opts)
+	if err != nil {
+		return nil, err
+	}
+	return c.fetchMagnet(ctx, f)
+}
+
+func (c *Client) saveTorrent(infoHash [sha1.Size]byte, data []byte) error {
+	dir := c.GlobalSettings().TorrentsDir
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%x.torrent", infoHash)
+	path := filepath.Join(dir, name)
+	return os.WriteFile(path, data, 0o644)
+}
+
+// Remove stops the torrent going by infoHash and drops it from the session.
+// Its files are left on disk.
+func (c *Client) Remove(infoHash [sha1.Size]byte) bool {
+	t, ok := c.torrents.Remove(infoHash)
+	if ok {
+		c.dequeue(t)
+		c.setOverrides(t, Overrides{})
+		c.setLabels(t, Labels{})
+		t.Stop(c.ctx)
+		c.reschedule()
+		c.saveSession()
+	}
+	return ok
+}
+
+// Pause disconnects the torrent from its peers and trackers, keeping its
+// progress.
+func (c *Client) Pause(infoHash [sha1.Size]byte) error {
+	t, ok := c.torrents.Get(infoHash)
+	if !ok {
+		return fmt.Errorf("echo: unknown torrent %x", infoHash)
+	}
+	t.Pause(c.ctx)
+	c.reschedule()
+	c.saveSession()
+	return nil
+}
+
+// Resume queues a paused torrent to start again, which it does as soon as
+// the active limits leave a slot for it.
+func (c *Client) Resume(infoHash [sha1.Size]byte) error {
+	t, ok := c.torrents.Get(infoHash)
+	if !ok {
+		return fmt.Errorf("echo: unknown torrent %x", infoHash)
+	}
+	if t.State().Active() {
+		return nil
+	}
+	t.Queue(c.ctx)
+	c.reschedule()
+	c.saveSession()
+	return nil
+}
+
+// Reannounce has a running torrent announce to every tracker now rather
+// than when their intervals are up.
+func (c *Client) Reannounce(infoHash [sha1.Size]byte) error {
+	t, err := c.running(infoHash)
+	if err != nil {
+		return err
+	}
+	t.TrackerManager.Reannounce()
+	return nil
+}
+
+// ReannounceTracker has a running torrent announce to one of its trackers
+// now.
+func (c *Client) ReannounceTracker(
+	infoHash [sha1.Size]byte,
+	url string,
+) error {
+	t, err := c.running(infoHash)
+	if err != nil {
+		return err
+	}
+	return t.TrackerManager.ReannounceTracker(url)
+}
+
+func (c *Client) running(infoHash [sha1.Size]byte) (*Torrent, error) {
+	t, ok := c.torrents.Get(infoHash)
+	if !ok {
+		return nil, fmt.Errorf("echo: unknown torrent %x", infoHash)
+	}
+	if !t.State().Active() {
+		name := t.Metainfo.Info.Name
+		return nil, fmt.Errorf("echo: %s is not running", name)
+	}
+	return t, nil
+}
+
+// Recheck hashes the torrent's data on disk again and marks what verifies
+// as complete. A running torrent stops for the check and starts again.
+func (c *Client) Recheck(infoHash [sha1.Size]byte) error {
+	t, ok := c.torrents.Get(infoHash)
+	if !ok {
+		return fmt.Errorf("echo: unknown torrent %x", infoHash)
+	}
+	err := t.Recheck(c.ctx)
+	c.reschedule()
+	c.saveSession()
+	return err
+}
+
+// Get finds a torrent by its v1 or truncated v2 info-hash.
+func (c *Client) Get(infoHash [sha1.Size]byte) (*Torrent, bool) {
+	return c.torrents.Get(infoHash)
+}
+
+// Details returns the torrent's files with their progress, a summary of
+// its pieces, and its trackers and peers.
+func (c *Client) Details(infoHash [sha1.Size]byte) (TorrentDetails, error) {
+	t, ok := c.torrents.Get(infoHash)
+	if !ok {
+		err := fmt.Errorf("echo: unknown torrent %x", infoHash)
+		return TorrentDetails{}, err
+	}
+	return t.Details(), nil
+}
+
+// PieceMap returns the state of each of the torrent's pieces, one digit
+// per piece; see torrent.Torrent.PieceMap. Changes arrive as
+// "pieces:delta" events.
+func (c *Client) PieceMap(infoHash [sha1.Size]byte) (string, error) {
+	t, ok := c.torrents.Get(infoHash)
+	if !ok {
+		return "", fmt.Errorf("echo: unknown torrent %x", infoHash)
+	}
+	return t.PieceMap(), nil
+}
+
+// List returns the session's torrents in queue order.
+func (c *Client) List() []*Torrent {
+	return c.ordered()
+}
+
+func (c *Client) Stats() Stats {
+	var s Stats
+	for _, t := range c.torrents.List() {
+		up, down, _ := t.Totals()
+		s.Torrents++
+		s.Uploaded += up
+		s.Downloaded += down
+	}
+	if c.dht != nil {
+		s.DHT = c.dht.Stats()
+	}
+	return s
+}
@@ -0,0 +1,57 @@
+package echo
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+
+	"github.com/prxssh/echo/internal/geoip"
+	"github.com/prxssh/echo/internal/peer"
+)
+
+// TorrentCountries is one torrent's connected peers broken down by
+// country; "torrent:countries" events carry one per torrent with peers.
+type TorrentCountries struct {
+	InfoHash  string              `json:"infoHash"`
+	Countries []peer.CountryStats `json:"countries"`
+}
+
+// Countries breaks the torrent's connected peers down by country, most
+// peers first. The same breakdown is pushed periodically as the
+// "torrent:countries" event.
+func (c *Client) Countries(
+	infoHash [sha1.Size]byte,
+) ([]peer.CountryStats, error) {
+	t, ok := c.torrents.Get(infoHash)
+	if !ok {
+		return nil, fmt.Errorf("echo: unknown torrent %x", infoHash)
+	}
+	return t.PeerManager.Countries(), nil
+}
+
+// countryBreakdown returns the breakdown of every torrent with connected
+// peers, in queue order.
+func (c *Client) countryBreakdown() []TorrentCountries {
+	var out []TorrentCountries
+	for _, t := range c.ordered() {
+		countries := t.PeerManager.Countries()
+		if len(countries) == 0 {
+			continue
+		}
+		hash := t.Metainfo.Info.Hash
+		out = append(out, TorrentCountries{
+			InfoHash:  hex.EncodeToString(hash[:]),
+			Countries: countries,
+		})
+	}
+	return out
+}
+
+// runGeoIP keeps the country databases fresh for as long as the session
+// runs. Downloads go through the proxy but, being large, without the
+// session client's timeout.
+func (c *Client) runGeoIP() {
+	client := &http.Client{Transport: c.http.Transport}
+	geoip.New(c.cfg.GeoIP, client, c.geo).Run(c.ctx)
+}
@@ -0,0 +1,197 @@
+package echo
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/prxssh/echo/internal/dht"
+	"github.com/prxssh/echo/internal/search"
+	"github.com/prxssh/echo/internal/torrent"
+)
+
+// LocalIndexName is the provider of search results from the local index
+// built by crawling the DHT.
+const LocalIndexName = "DHT index"
+
+const (
+	// crawlInterval is how often a random corner of the DHT is sampled
+	// for info-hashes.
+	crawlInterval = 15 * time.Second
+	// crawlFetchTimeout bounds each metadata fetch; most crawled
+	// torrents have no peers left and are given up on.
+	crawlFetchTimeout = 30 * time.Second
+	crawlWorkers      = 2
+	crawlBacklog      = 200
+	indexSaveInterval = 5 * time.Minute
+)
+
+// IndexedTorrents counts the torrents in the local search index.
+func (c *Client) IndexedTorrents() int {
+	return c.index.Len()
+}
+
+// runCrawler samples the DHT for info-hashes (BEP 51) while
+// GlobalSettings.CrawlDHT is on, fetches their metadata from peers and adds
+// the torrents to the local index that Search falls back on.
+func (c *Client) runCrawler() {
+	if c.dht == nil {
+		return
+	}
+
+	backlog := make(chan [sha1.Size]byte, crawlBacklog)
+	for range crawlWorkers {
+		go func() {
+			for hash := range backlog {
+				c.crawl(hash)
+			}
+		}()
+	}
+	defer close(backlog)
+
+	sample := time.NewTicker(crawlInterval)
+	defer sample.Stop()
+	save := time.NewTicker(indexSaveInterval)
+	defer save.Stop()
+
+	for {
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-save.C:
+			c.saveIndex()
+		case <-sample.C:
+			if !c.GlobalSettings().CrawlDHT {
+				continue
+			}
+			target, err := dht.RandomID()
+			if err != nil {
+				continue
+			}
+			hashes := c.dht.SampleInfoHashes(c.ctx, target)
+			for _, h := range hashes {
+				if c.index.Has(hex.EncodeToString(h[:])) {
+					continue
+				}
+				select {
+				case backlog <- h:
+				default:
+				}
+			}
+		}
+	}
+}
+
+// crawl fetches the metadata of a sampled info-hash and indexes it.
+func (c *Client) crawl(hash [sha1.Size]byte) {
+	if c.ctx.Err() != nil || c.index.Has(hex.EncodeToString(hash[:])) {
+		return
+	}
+	ctx, cancel := context.WithTimeout(c.ctx, crawlFetchTimeout)
+	defer cancel()
+
+	data, err := torrent.FetchMetadata(
+		ctx,
+		&torrent.Magnet{InfoHash: hash},
+		torrent.Opts{
+			DHT:         c.dht,
+			UDP:         c.udp,
+			Fingerprint: c.GlobalSettings().Fingerprint,
+			Peer:        &c.cfg.Peer,
+		},
+	)
+	if err != nil {
+		return
+	}
+	m, err := torrent.ParseMetainfo(bytes.NewReader(data))
+	if err != nil {
+		return
+	}
+
+	entry := search.IndexEntry{
+		InfoHash: hex.EncodeToString(hash[:]),
+		Name:     m.Info.Name,
+		Size:     m.Size,
+		Added:    time.Now(),
+	}
+	if m.Info.Files != nil {
+		for _, f := range *m.Info.Files {
+			if !f.Padding {
+				path := strings.Join(f.Path, "/")
+				entry.Files = append(entry.Files, path)
+			}
+		}
+	}
+	if c.index.Add(entry) {
+		slog.Debug(
+			"indexed crawled torrent",
+			slog.String("info_hash", entry.InfoHash),
+			slog.String("name", entry.Name),
+		)
+	}
+}
+
+// loadIndex reads the local search index saved by earlier runs.
+func (c *Client) loadIndex() {
+	if c.cfg.IndexPath == "" {
+		return
+	}
+	data, err := os.ReadFile(c.cfg.IndexPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return
+	}
+	var entries []search.IndexEntry
+	if err == nil {
+		err = json.Unmarshal(data, &entries)
+	}
+	if err != nil {
+		slog.Warn(
+			"loading search index failed",
+			slog.String("path", c.cfg.IndexPath),
+			slog.String("error", err.Error()),
+		)
+		return
+	}
+	for _, e := range entries {
+		c.index.Add(e)
+	}
+}
+
+func (c *Client) saveIndex() {
+	if err := c.writeIndex(); err != nil {
+		slog.Warn(
+			"saving search index failed",
+			slog.String("path", c.cfg.IndexPath),
+			slog.String("error", err.Error()),
+		)
+	}
+}
+
+func (c *Client) writeIndex() error {
+	path := c.cfg.IndexPath
+	if path == "" || c.index.Len() == 0 {
+		return nil
+	}
+	data, err := json.Marshal(c.index.Entries())
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
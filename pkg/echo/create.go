@@ -0,0 +1,79 @@
+package echo
+
+import (
+	"context"
+	"path/filepath"
+
+	"github.com/prxssh/echo/internal/torrent"
+)
+
+// CreateOptions describe a torrent to make from local files.
+type CreateOptions struct {
+	// Path is the file or directory to share.
+	Path string `json:"path"`
+	// PieceLength is picked from the total size when zero.
+	PieceLength uint64 `json:"pieceLength"`
+	// Trackers are announced to in order, one tier each.
+	Trackers []string `json:"trackers"`
+	WebSeeds []string `json:"webSeeds"`
+	Comment  string   `json:"comment"`
+	Private  bool     `json:"private"`
+	// Source tags the torrent for one tracker, giving it an info-hash of
+	// its own.
+	Source string `json:"source"`
+}
+
+// CreateProgress is the payload of "create:progress", pushed as the files
+// of a torrent being created are hashed.
+type CreateProgress struct {
+	Path   string `json:"path"`
+	Hashed int    `json:"hashed"`
+	Total  int    `json:"total"`
+}
+
+// CreateTorrent hashes the files at opts.Path and returns the .torrent,
+// pushing "create:progress" events as it goes. It gives up once ctx is
+// done.
+func (c *Client) CreateTorrent(
+	ctx context.Context,
+	opts CreateOptions,
+) ([]byte, error) {
+	return torrent.CreateContext(ctx, torrent.CreateOpts{
+		Path:        opts.Path,
+		PieceLength: opts.PieceLength,
+		Trackers:    opts.Trackers,
+		WebSeeds:    opts.WebSeeds,
+		Comment:     opts.Comment,
+		Private:     opts.Private,
+		Source:      opts.Source,
+		OnProgress: func(hashed, total int) {
+			c.publish("create:progress", CreateProgress{
+				Path:   opts.Path,
+				Hashed: hashed,
+				Total:  total,
+			})
+		},
+	})
+}
+
+// SeedTorrent adds a torrent made with CreateTorrent from the files at
+// path, seeding them where they are once they have been checked.
+func (c *Client) SeedTorrent(
+	data []byte,
+	path string,
+	opts AddOptions,
+) (*Torrent, error) {
+	ao, err := c.addOptsFor(opts)
+	if err != nil {
+		return nil, err
+	}
+	ao.downloadDir = filepath.Dir(filepath.Clean(path))
+	ao.paused = true
+
+	t, err := c.add(data, ao)
+	if err != nil {
+		return nil, err
+	}
+	go c.checkAndStart([]*Torrent{t}, []bool{!opts.Paused})
+	return t, nil
+}
@@ -0,0 +1,95 @@
+package echo
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+)
+
+// credentials are the secrets kept in Config.CredentialsPath: indexers'
+// API keys, by indexer name.
+type credentials struct {
+	APIKeys map[string]string `json:"apiKeys,omitempty"`
+}
+
+// sessionIndexers returns the indexers to save with the session: without
+// their API keys when those are kept in Config.CredentialsPath, in which
+// case the keys are saved there.
+func (c *Client) sessionIndexers() []Indexer {
+	indexers := c.Indexers()
+	if c.cfg.CredentialsPath == "" {
+		return indexers
+	}
+
+	creds := credentials{APIKeys: make(map[string]string)}
+	for i, ix := range indexers {
+		if ix.APIKey != "" {
+			creds.APIKeys[ix.Name] = ix.APIKey
+			indexers[i].APIKey = ""
+		}
+	}
+	if err := writeCredentials(c.cfg.CredentialsPath, creds); err != nil {
+		slog.Warn(
+			"saving credentials failed",
+			slog.String("path", c.cfg.CredentialsPath),
+			slog.String("error", err.Error()),
+		)
+		// Keep the keys in the session rather than lose them.
+		return c.Indexers()
+	}
+	return indexers
+}
+
+// restoreCredentials gives the indexers restored from the session the API
+// keys saved apart from it. Keys still in the session, from before they
+// were kept apart, win.
+func (c *Client) restoreCredentials() {
+	path := c.cfg.CredentialsPath
+	if path == "" {
+		return
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return
+	}
+	var creds credentials
+	if err == nil {
+		err = json.Unmarshal(data, &creds)
+	}
+	if err != nil {
+		slog.Warn(
+			"loading credentials failed",
+			slog.String("path", path),
+			slog.String("error", err.Error()),
+		)
+		return
+	}
+
+	c.searchMu.Lock()
+	defer c.searchMu.Unlock()
+
+	for i, ix := range c.indexers {
+		if ix.APIKey == "" {
+			c.indexers[i].APIKey = creds.APIKeys[ix.Name]
+		}
+	}
+}
+
+func writeCredentials(path string, creds credentials) error {
+	data, err := json.Marshal(creds)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
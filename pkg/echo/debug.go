@@ -0,0 +1,152 @@
+package echo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"net/netip"
+	"runtime"
+	rpprof "runtime/pprof"
+	"time"
+
+	"github.com/prxssh/echo/internal/torrent"
+)
+
+// TorrentDebug is a torrent's piece picker and dialer state.
+type TorrentDebug = torrent.Debug
+
+// DebugInfo is the engine's internals, for diagnosing a session that has
+// stalled.
+type DebugInfo struct {
+	Goroutines int `json:"goroutines"`
+	// Queue counts the torrents waiting for an active slot.
+	Queue    int            `json:"queue"`
+	DHT      DHTStats       `json:"dht"`
+	Torrents []TorrentDebug `json:"torrents"`
+}
+
+// Debug returns the engine's internals, the torrents in queue order.
+func (c *Client) Debug() DebugInfo {
+	info := DebugInfo{
+		Goroutines: runtime.NumGoroutine(),
+		Torrents:   []TorrentDebug{},
+	}
+	if c.dht != nil {
+		info.DHT = c.dht.Stats()
+	}
+	for _, t := range c.ordered() {
+		d := t.Debug()
+		if d.State == torrent.StateQueued {
+			info.Queue++
+		}
+		info.Torrents = append(info.Torrents, d)
+	}
+	return info
+}
+
+type debugServer struct {
+	addr string
+	srv  *http.Server
+}
+
+// serveDebug starts or stops serving DebugAddr to match the settings.
+func (c *Client) serveDebug() {
+	addr := c.GlobalSettings().DebugAddr
+
+	c.debugMu.Lock()
+	defer c.debugMu.Unlock()
+
+	if c.debug != nil && c.debug.addr == addr {
+		return
+	}
+	c.stopDebugLocked()
+	if addr == "" || c.closing.Load() {
+		return
+	}
+
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		slog.Warn(
+			"debug listener failed",
+			slog.String("addr", addr),
+			slog.String("error", err.Error()),
+		)
+		return
+	}
+	srv := &http.Server{
+		Handler:           c.debugHandler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	c.debug = &debugServer{addr: addr, srv: srv}
+	slog.Info("serving debug", slog.String("addr", ln.Addr().String()))
+	go func() {
+		err := srv.Serve(ln)
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Warn(
+				"debug listener failed",
+				slog.String("error", err.Error()),
+			)
+		}
+	}()
+}
+
+func (c *Client) stopDebug() {
+	c.debugMu.Lock()
+	defer c.debugMu.Unlock()
+
+	c.stopDebugLocked()
+}
+
+func (c *Client) stopDebugLocked() {
+	if c.debug == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	c.debug.srv.Shutdown(ctx)
+	c.debug = nil
+}
+
+// debugHandler serves pprof under /debug/pprof/, every goroutine's stack
+// at /debug/goroutines and Debug as JSON at /debug/engine.
+func (c *Client) debugHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.HandleFunc("GET /debug/goroutines", debugGoroutines)
+	mux.HandleFunc("GET /debug/engine", c.debugEngine)
+	return mux
+}
+
+func debugGoroutines(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	rpprof.Lookup("goroutine").WriteTo(w, 2)
+}
+
+func (c *Client) debugEngine(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(c.Debug())
+}
+
+// loopback reports whether addr, a host and port, can only be reached
+// from this machine.
+func loopback(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip, err := netip.ParseAddr(host)
+	return err == nil && ip.IsLoopback()
+}
@@ -0,0 +1,128 @@
+package echo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// maxTorrentSize bounds a .torrent read from a file or downloaded.
+const maxTorrentSize = 16 << 20
+
+// AddTorrentFile reads the .torrent at path and adds it like AddTorrent.
+func (c *Client) AddTorrentFile(
+	path string,
+	opts AddOptions,
+) (*Torrent, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	data, err := readTorrent(f)
+	if err != nil {
+		return nil, fmt.Errorf("echo: %s: %w", path, err)
+	}
+	return c.AddTorrent(data, opts)
+}
+
+// magnetRedirect is returned by downloadTorrent for a URL redirecting to a
+// magnet link, as indexers' download links do for torrents they only have
+// magnet links for.
+type magnetRedirect struct {
+	uri string
+}
+
+func (r *magnetRedirect) Error() string {
+	return "echo: redirected to a magnet link"
+}
+
+// AddTorrentURL downloads a .torrent over HTTP or HTTPS and adds it like
+// AddTorrent. A URL redirecting to a magnet link is added like AddMagnet.
+func (c *Client) AddTorrentURL(
+	ctx context.Context,
+	rawURL string,
+	opts AddOptions,
+) (*Torrent, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("echo: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("echo: %s: not an HTTP URL", rawURL)
+	}
+
+	data, err := c.downloadTorrent(ctx, rawURL)
+	var mr *magnetRedirect
+	if errors.As(err, &mr) {
+		return c.AddMagnet(ctx, mr.uri, opts)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return c.AddTorrent(data, opts)
+}
+
+func (c *Client) downloadTorrent(
+	ctx context.Context,
+	rawURL string,
+) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if loc := resp.Header.Get("Location"); isMagnet(loc) {
+		return nil, &magnetRedirect{uri: loc}
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("echo: %s: %s", rawURL, resp.Status)
+	}
+	if resp.ContentLength > maxTorrentSize {
+		return nil, fmt.Errorf("echo: %s: torrent too large", rawURL)
+	}
+	data, err := readTorrent(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("echo: %s: %w", rawURL, err)
+	}
+	return data, nil
+}
+
+// stopAtMagnet is the HTTP client's CheckRedirect: a redirect to a magnet
+// link is not followed but left for downloadTorrent to find.
+func stopAtMagnet(req *http.Request, via []*http.Request) error {
+	if req.URL.Scheme == "magnet" {
+		return http.ErrUseLastResponse
+	}
+	if len(via) >= 10 {
+		return errors.New("stopped after 10 redirects")
+	}
+	return nil
+}
+
+func isMagnet(uri string) bool {
+	return strings.HasPrefix(strings.ToLower(uri), "magnet:")
+}
+
+// readTorrent reads r to the end, failing once it passes maxTorrentSize.
+func readTorrent(r io.Reader) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, maxTorrentSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxTorrentSize {
+		return nil, errors.New("torrent too large")
+	}
+	return data, nil
+}
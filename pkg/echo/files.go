@@ -0,0 +1,78 @@
+package echo
+
+import (
+	"crypto/sha1"
+	"fmt"
+
+	"github.com/prxssh/echo/internal/torrent"
+)
+
+// FilePriority says how soon a file is downloaded, or that it is skipped.
+type FilePriority = torrent.FilePriority
+
+const (
+	FileSkip   = torrent.FileSkip
+	FileLow    = torrent.FileLow
+	FileNormal = torrent.FileNormal
+	FileHigh   = torrent.FileHigh
+)
+
+// SetFilePriority changes the priority of the torrent's file at index, by
+// its place in the info dict's file list. It takes effect straight away.
+func (c *Client) SetFilePriority(
+	infoHash [sha1.Size]byte,
+	index int,
+	p FilePriority,
+) error {
+	t, ok := c.torrents.Get(infoHash)
+	if !ok {
+		return fmt.Errorf("echo: unknown torrent %x", infoHash)
+	}
+	if err := t.SetFilePriority([]int{index}, p); err != nil {
+		return err
+	}
+	c.saveSession()
+	return nil
+}
+
+// SetFilesWanted adds the torrent's files at the given indices to the
+// download, or leaves them out of it.
+func (c *Client) SetFilesWanted(
+	infoHash [sha1.Size]byte,
+	indices []int,
+	wanted bool,
+) error {
+	t, ok := c.torrents.Get(infoHash)
+	if !ok {
+		return fmt.Errorf("echo: unknown torrent %x", infoHash)
+	}
+	if err := t.SetFilesWanted(indices, wanted); err != nil {
+		return err
+	}
+	c.saveSession()
+	return nil
+}
+
+// TorrentPath returns where the torrent is stored: its directory for a
+// multi-file torrent, its file otherwise.
+func (c *Client) TorrentPath(infoHash [sha1.Size]byte) (string, error) {
+	t, ok := c.torrents.Get(infoHash)
+	if !ok {
+		return "", fmt.Errorf("echo: unknown torrent %x", infoHash)
+	}
+	return t.RootPath(), nil
+}
+
+// FilePath returns where the torrent's file at index is stored, and
+// whether all of it is downloaded and verified.
+func (c *Client) FilePath(
+	infoHash [sha1.Size]byte,
+	index int,
+) (string, bool, error) {
+	t, ok := c.torrents.Get(infoHash)
+	if !ok {
+		err := fmt.Errorf("echo: unknown torrent %x", infoHash)
+		return "", false, err
+	}
+	return t.FilePath(index)
+}
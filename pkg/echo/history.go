@@ -0,0 +1,153 @@
+package echo
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/prxssh/echo/internal/torrent"
+)
+
+// maxHistory caps how many completions are remembered; the oldest go
+// first.
+const maxHistory = 1000
+
+// HistoryEntry records a completed download. It outlives the torrent.
+type HistoryEntry struct {
+	InfoHash  string    `json:"infoHash"`
+	Name      string    `json:"name"`
+	Size      uint64    `json:"size"`
+	Category  string    `json:"category,omitempty"`
+	Completed time.Time `json:"completed"`
+	// Ratio is the share ratio when the download completed.
+	Ratio float64 `json:"ratio"`
+}
+
+// History returns the completed downloads, most recent first.
+func (c *Client) History() []HistoryEntry {
+	c.historyMu.Lock()
+	defer c.historyMu.Unlock()
+
+	h := slices.Clone(c.history)
+	slices.Reverse(h)
+	return h
+}
+
+// ClearHistory forgets every completed download.
+func (c *Client) ClearHistory() error {
+	c.historyMu.Lock()
+	defer c.historyMu.Unlock()
+
+	c.history = nil
+	return c.writeHistory()
+}
+
+// runHistory records torrents as they finish downloading until the client
+// closes. Torrents that start out complete, such as restored seeds, are
+// not recorded again.
+func (c *Client) runHistory() {
+	unsubscribe := c.Subscribe(func(e Event) {
+		change, ok := torrent.TopicState.Match(e.Name, e.Data)
+		if !ok {
+			return
+		}
+		if change.From == torrent.StateDownloading &&
+			change.To == torrent.StateSeeding {
+			go c.recordCompletion(change.InfoHash)
+		}
+	})
+	defer unsubscribe()
+
+	<-c.ctx.Done()
+}
+
+func (c *Client) recordCompletion(infoHash string) {
+	raw, err := hex.DecodeString(infoHash)
+	if err != nil || len(raw) != sha1.Size {
+		return
+	}
+	t, ok := c.torrents.Get([sha1.Size]byte(raw))
+	if !ok {
+		return
+	}
+
+	entry := HistoryEntry{
+		InfoHash:  infoHash,
+		Name:      t.Metainfo.Info.Name,
+		Size:      t.Metainfo.Size,
+		Category:  c.labelsFor(t).Category,
+		Completed: time.Now(),
+		Ratio:     t.Ratio(),
+	}
+	c.publish("history:added", entry)
+
+	c.historyMu.Lock()
+	defer c.historyMu.Unlock()
+
+	c.history = append(c.history, entry)
+	if n := len(c.history) - maxHistory; n > 0 {
+		c.history = slices.Delete(c.history, 0, n)
+	}
+	if err := c.writeHistory(); err != nil {
+		slog.Warn(
+			"saving history failed",
+			slog.String("path", c.cfg.HistoryPath),
+			slog.String("error", err.Error()),
+		)
+	}
+}
+
+// loadHistory reads the history saved by earlier runs.
+func (c *Client) loadHistory() {
+	if c.cfg.HistoryPath == "" {
+		return
+	}
+	data, err := os.ReadFile(c.cfg.HistoryPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return
+	}
+	var history []HistoryEntry
+	if err == nil {
+		err = json.Unmarshal(data, &history)
+	}
+	if err != nil {
+		slog.Warn(
+			"loading history failed",
+			slog.String("path", c.cfg.HistoryPath),
+			slog.String("error", err.Error()),
+		)
+		return
+	}
+
+	c.historyMu.Lock()
+	c.history = history
+	c.historyMu.Unlock()
+}
+
+// writeHistory saves the history; historyMu must be held.
+func (c *Client) writeHistory() error {
+	path := c.cfg.HistoryPath
+	if path == "" {
+		return nil
+	}
+	data, err := json.Marshal(c.history)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
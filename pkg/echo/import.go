@@ -0,0 +1,366 @@
+package echo
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/prxssh/echo/internal/bencode"
+	"github.com/prxssh/echo/internal/torrent"
+)
+
+// ImportSource names another client whose torrents can be imported.
+type ImportSource string
+
+const (
+	// ImportQBittorrent reads qBittorrent's BT_backup directory of
+	// .torrent and .fastresume files.
+	ImportQBittorrent ImportSource = "qbittorrent"
+	// ImportTransmission reads Transmission's config directory, or the
+	// resume directory inside it.
+	ImportTransmission ImportSource = "transmission"
+)
+
+// ImportResult sums up an import.
+type ImportResult struct {
+	Added int `json:"added"`
+	// Skipped counts torrents the session already had.
+	Skipped int      `json:"skipped"`
+	Errors  []string `json:"errors"`
+}
+
+// imported is a torrent read from another client's state.
+type imported struct {
+	name     string
+	metainfo []byte
+	savePath string
+	// trackers replace the .torrent's announce list when set, since the
+	// other client may have edited them.
+	trackers   [][]string
+	paused     bool
+	category   string
+	tags       []string
+	uploaded   uint64
+	downloaded uint64
+	seedTime   time.Duration
+	// skipped lists the indices of files not being downloaded.
+	skipped []int
+}
+
+// Import recreates another client's torrents in the session, with their
+// save paths, trackers, labels and transfer totals. Each is added paused
+// and its data on disk is checked in the background; those that were
+// running in the other client are then queued to start.
+func (c *Client) Import(source ImportSource, dir string) (ImportResult, error) {
+	var (
+		list []imported
+		errs []error
+		err  error
+	)
+	switch source {
+	case ImportQBittorrent:
+		list, errs, err = readQBittorrent(dir)
+	case ImportTransmission:
+		list, errs, err = readTransmission(dir)
+	default:
+		err = fmt.Errorf("echo: unknown import source %q", source)
+	}
+	if err != nil {
+		return ImportResult{}, err
+	}
+
+	var res ImportResult
+	for _, e := range errs {
+		res.Errors = append(res.Errors, e.Error())
+	}
+	var added []*Torrent
+	var start []bool
+	for _, im := range list {
+		t, err := c.addImported(im)
+		switch {
+		case errors.Is(err, ErrDuplicate):
+			res.Skipped++
+		case err != nil:
+			res.Errors = append(
+				res.Errors,
+				fmt.Sprintf("%s: %v", im.name, err),
+			)
+		default:
+			res.Added++
+			added = append(added, t)
+			start = append(start, !im.paused)
+		}
+	}
+
+	go c.checkAndStart(added, start)
+	return res, nil
+}
+
+func (c *Client) addImported(im imported) (*Torrent, error) {
+	data := im.metainfo
+	if len(im.trackers) > 0 {
+		var err error
+		data, err = torrent.EditMetainfo(data, torrent.EditOpts{
+			Trackers: &im.trackers,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	m, err := torrent.ParseMetainfo(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	dir := im.savePath
+	if dir == "" {
+		dir = c.GlobalSettings().DownloadDir
+	}
+	if im.category != "" {
+		c.labelsMu.Lock()
+		_, ok := c.categories[im.category]
+		c.labelsMu.Unlock()
+		if !ok {
+			err := c.AddCategory(Category{Name: im.category})
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return c.add(data, addOpts{
+		downloadDir: dir,
+		selectOnly:  selectedFiles(m, im.skipped),
+		labels: Labels{
+			Category: im.category,
+			Tags:     cleanTags(im.tags),
+		},
+		paused: true,
+		restored: &sessionEntry{
+			Uploaded:   im.uploaded,
+			Downloaded: im.downloaded,
+			SeedTime:   im.seedTime,
+		},
+	})
+}
+
+// selectedFiles turns the files to skip into the files to download, or nil
+// when none are skipped.
+func selectedFiles(m *torrent.Metainfo, skipped []int) []int {
+	if len(skipped) == 0 {
+		return nil
+	}
+	n := 1
+	if m.Info.Files != nil {
+		n = len(*m.Info.Files)
+	}
+
+	skip := make(map[int]bool, len(skipped))
+	for _, i := range skipped {
+		skip[i] = true
+	}
+	selected := []int{}
+	for i := range n {
+		if !skip[i] {
+			selected = append(selected, i)
+		}
+	}
+	return selected
+}
+
+// checkAndStart verifies the paused torrents' data one at a time, so their
+// files are not all read at once, and queues those marked to start.
+func (c *Client) checkAndStart(list []*Torrent, start []bool) {
+	for i, t := range list {
+		if err := t.Check(c.ctx); err != nil {
+			slog.Warn(
+				"checking torrent failed",
+				slog.String("name", t.Metainfo.Info.Name),
+				slog.String("error", err.Error()),
+			)
+			continue
+		}
+		if start[i] {
+			_ = c.Resume(t.Metainfo.Info.Hash)
+		}
+	}
+}
+
+// qbtResume holds the fields Echo uses from a qBittorrent .fastresume
+// file, which is libtorrent's resume data plus qBittorrent's own keys.
+type qbtResume struct {
+	SavePath     string             `bencode:"save_path"`
+	QBtSavePath  string             `bencode:"qBt-savePath"`
+	Trackers     [][]string         `bencode:"trackers"`
+	Paused       int64              `bencode:"paused"`
+	Category     string             `bencode:"qBt-category"`
+	Tags         []string           `bencode:"qBt-tags"`
+	Uploaded     int64              `bencode:"total_uploaded"`
+	Downloaded   int64              `bencode:"total_downloaded"`
+	SeedingTime  int64              `bencode:"seeding_time"`
+	FilePriority []int64            `bencode:"file_priority"`
+	Name         string             `bencode:"qBt-name"`
+	Info         bencode.RawMessage `bencode:"info"`
+}
+
+// readQBittorrent reads every <hash>.fastresume in dir along with the
+// <hash>.torrent beside it. Newer versions may keep the info dict in the
+// .fastresume instead.
+func readQBittorrent(dir string) ([]imported, []error, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.fastresume"))
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(paths) == 0 {
+		err := fmt.Errorf("echo: no .fastresume files in %s", dir)
+		return nil, nil, err
+	}
+
+	var (
+		list []imported
+		errs []error
+	)
+	for _, path := range paths {
+		im, err := readQBtResume(path)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", path, err))
+			continue
+		}
+		list = append(list, im)
+	}
+	return list, errs, nil
+}
+
+func readQBtResume(path string) (imported, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return imported{}, err
+	}
+	var r qbtResume
+	if err := bencode.Unmarshal(raw, &r); err != nil {
+		return imported{}, err
+	}
+
+	base := strings.TrimSuffix(path, ".fastresume")
+	data, err := os.ReadFile(base + ".torrent")
+	if errors.Is(err, fs.ErrNotExist) && r.Info != nil {
+		data = append(append([]byte("d4:info"), r.Info...), 'e')
+	} else if err != nil {
+		return imported{}, err
+	}
+
+	im := imported{
+		name:       filepath.Base(base),
+		metainfo:   data,
+		savePath:   r.SavePath,
+		trackers:   r.Trackers,
+		paused:     r.Paused != 0,
+		category:   r.Category,
+		tags:       r.Tags,
+		uploaded:   uint64(max(r.Uploaded, 0)),
+		downloaded: uint64(max(r.Downloaded, 0)),
+		seedTime:   time.Duration(r.SeedingTime) * time.Second,
+	}
+	if r.Name != "" {
+		im.name = r.Name
+	}
+	if im.savePath == "" {
+		im.savePath = r.QBtSavePath
+	}
+	for i, p := range r.FilePriority {
+		if p == 0 {
+			im.skipped = append(im.skipped, i)
+		}
+	}
+	return im, nil
+}
+
+// trResume holds the fields Echo uses from a Transmission .resume file.
+type trResume struct {
+	Destination string   `bencode:"destination"`
+	Name        string   `bencode:"name"`
+	Paused      int64    `bencode:"paused"`
+	Uploaded    int64    `bencode:"uploaded"`
+	Downloaded  int64    `bencode:"downloaded"`
+	SeedingTime int64    `bencode:"seeding-time-seconds"`
+	DND         []int64  `bencode:"dnd"`
+	Labels      []string `bencode:"labels"`
+	Group       string   `bencode:"group"`
+}
+
+// readTransmission reads the .resume files in the resume directory and
+// the .torrent of the same name in the torrents directory next to it.
+// Transmission keeps trackers in the .torrent itself.
+func readTransmission(dir string) ([]imported, []error, error) {
+	resumeDir := filepath.Join(dir, "resume")
+	if _, err := os.Stat(resumeDir); err != nil {
+		resumeDir = dir
+	}
+	torrentsDir := filepath.Join(filepath.Dir(resumeDir), "torrents")
+
+	paths, err := filepath.Glob(filepath.Join(resumeDir, "*.resume"))
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(paths) == 0 {
+		return nil, nil, fmt.Errorf("echo: no .resume files in %s", dir)
+	}
+
+	var (
+		list []imported
+		errs []error
+	)
+	for _, path := range paths {
+		im, err := readTrResume(path, torrentsDir)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", path, err))
+			continue
+		}
+		list = append(list, im)
+	}
+	return list, errs, nil
+}
+
+func readTrResume(path, torrentsDir string) (imported, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return imported{}, err
+	}
+	var r trResume
+	if err := bencode.Unmarshal(raw, &r); err != nil {
+		return imported{}, err
+	}
+
+	name := strings.TrimSuffix(filepath.Base(path), ".resume")
+	data, err := os.ReadFile(filepath.Join(torrentsDir, name+".torrent"))
+	if err != nil {
+		return imported{}, err
+	}
+
+	im := imported{
+		name:       name,
+		metainfo:   data,
+		savePath:   r.Destination,
+		paused:     r.Paused != 0,
+		category:   r.Group,
+		tags:       r.Labels,
+		uploaded:   uint64(max(r.Uploaded, 0)),
+		downloaded: uint64(max(r.Downloaded, 0)),
+		seedTime:   time.Duration(r.SeedingTime) * time.Second,
+	}
+	if r.Name != "" {
+		im.name = r.Name
+	}
+	for i, dnd := range r.DND {
+		if dnd != 0 {
+			im.skipped = append(im.skipped, i)
+		}
+	}
+	return im, nil
+}
@@ -0,0 +1,232 @@
+package echo
+
+import (
+	"cmp"
+	"crypto/sha1"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/prxssh/echo/internal/torrent"
+)
+
+// Category groups torrents and gives those added to it a save path of their
+// own.
+type Category struct {
+	Name string `json:"name"`
+	// SavePath is where torrents added to the category download to;
+	// empty uses Config.DownloadDir.
+	SavePath string `json:"savePath"`
+	// Quota, in bytes, caps the data of the category's torrents; once
+	// it is reached their downloads wait in the queue. Zero is no cap.
+	Quota uint64 `json:"quota"`
+}
+
+// Labels are a torrent's category, which may be empty, and free-form tags.
+type Labels struct {
+	Category string   `json:"category"`
+	Tags     []string `json:"tags"`
+}
+
+// Filter narrows List down; empty fields match every torrent.
+type Filter struct {
+	Category string `json:"category"`
+	Tag      string `json:"tag"`
+}
+
+// AddCategory creates a category, or changes the save path of an existing
+// one. The new path applies to torrents added from then on.
+func (c *Client) AddCategory(cat Category) error {
+	cat.Name = strings.TrimSpace(cat.Name)
+	if cat.Name == "" {
+		return errors.New("echo: category needs a name")
+	}
+
+	c.labelsMu.Lock()
+	c.categories[cat.Name] = cat
+	c.labelsMu.Unlock()
+
+	c.saveSession()
+	return nil
+}
+
+// RemoveCategory deletes a category; its torrents are left without one.
+func (c *Client) RemoveCategory(name string) {
+	c.labelsMu.Lock()
+	delete(c.categories, name)
+	for t, l := range c.labels {
+		if l.Category == name {
+			l.Category = ""
+			c.labels[t] = l
+		}
+	}
+	c.labelsMu.Unlock()
+
+	c.saveSession()
+}
+
+// Categories lists the categories by name.
+func (c *Client) Categories() []Category {
+	c.labelsMu.Lock()
+	defer c.labelsMu.Unlock()
+
+	return slices.SortedFunc(
+		maps.Values(c.categories),
+		func(a, b Category) int { return cmp.Compare(a.Name, b.Name) },
+	)
+}
+
+// Labels returns the torrent's category and tags.
+func (c *Client) Labels(infoHash [sha1.Size]byte) (Labels, error) {
+	t, ok := c.torrents.Get(infoHash)
+	if !ok {
+		err := fmt.Errorf("echo: unknown torrent %x", infoHash)
+		return Labels{}, err
+	}
+	return c.labelsFor(t), nil
+}
+
+// SetCategory files the torrent under an existing category, or under none
+// if name is empty. With move set, its files are moved to the category's
+// save path first, and the category only changes if they all got there;
+// otherwise they stay where they are.
+func (c *Client) SetCategory(
+	infoHash [sha1.Size]byte,
+	name string,
+	move bool,
+) error {
+	t, ok := c.torrents.Get(infoHash)
+	if !ok {
+		return fmt.Errorf("echo: unknown torrent %x", infoHash)
+	}
+	dir, err := c.savePath(name)
+	if err != nil {
+		return err
+	}
+	if move && dir != t.DownloadDir() {
+		if err := c.move(t, dir); err != nil {
+			return err
+		}
+	}
+
+	c.labelsMu.Lock()
+	l := c.labels[t]
+	l.Category = name
+	c.labels[t] = l
+	c.labelsMu.Unlock()
+
+	c.saveSession()
+	return nil
+}
+
+// move relocates the torrent's files to dir. A queued torrent is paused
+// for the move and queued again after it.
+func (c *Client) move(t *Torrent, dir string) error {
+	queued := t.State() == torrent.StateQueued
+	if queued {
+		t.Pause(c.ctx)
+	}
+	err := t.Move(c.ctx, dir)
+	if queued {
+		t.Queue(c.ctx)
+		c.reschedule()
+	}
+	return err
+}
+
+// SetTags replaces the torrent's tags. Blank and repeated tags are dropped.
+func (c *Client) SetTags(infoHash [sha1.Size]byte, tags []string) error {
+	t, ok := c.torrents.Get(infoHash)
+	if !ok {
+		return fmt.Errorf("echo: unknown torrent %x", infoHash)
+	}
+
+	c.labelsMu.Lock()
+	l := c.labels[t]
+	l.Tags = cleanTags(tags)
+	c.labels[t] = l
+	c.labelsMu.Unlock()
+
+	c.saveSession()
+	return nil
+}
+
+// Tags lists every tag in use, sorted.
+func (c *Client) Tags() []string {
+	c.labelsMu.Lock()
+	defer c.labelsMu.Unlock()
+
+	var tags []string
+	for _, l := range c.labels {
+		tags = append(tags, l.Tags...)
+	}
+	return cleanTags(tags)
+}
+
+// ListFiltered returns the torrents matching f, in queue order.
+func (c *Client) ListFiltered(f Filter) []*Torrent {
+	var out []*Torrent
+	for _, t := range c.ordered() {
+		l := c.labelsFor(t)
+		if f.Category != "" && l.Category != f.Category {
+			continue
+		}
+		if f.Tag != "" && !slices.Contains(l.Tags, f.Tag) {
+			continue
+		}
+		out = append(out, t)
+	}
+	return out
+}
+
+func (c *Client) labelsFor(t *Torrent) Labels {
+	c.labelsMu.Lock()
+	defer c.labelsMu.Unlock()
+
+	l := c.labels[t]
+	l.Tags = slices.Clone(l.Tags)
+	return l
+}
+
+func (c *Client) setLabels(t *Torrent, l Labels) {
+	c.labelsMu.Lock()
+	defer c.labelsMu.Unlock()
+
+	if l.Category == "" && len(l.Tags) == 0 {
+		delete(c.labels, t)
+	} else {
+		c.labels[t] = l
+	}
+}
+
+// savePath returns where a torrent added to the category downloads to.
+func (c *Client) savePath(category string) (string, error) {
+	if category == "" {
+		return c.GlobalSettings().DownloadDir, nil
+	}
+
+	c.labelsMu.Lock()
+	defer c.labelsMu.Unlock()
+
+	cat, ok := c.categories[category]
+	if !ok {
+		return "", fmt.Errorf("echo: unknown category %q", category)
+	}
+	if cat.SavePath == "" {
+		return c.GlobalSettings().DownloadDir, nil
+	}
+	return cat.SavePath, nil
+}
+
+func cleanTags(tags []string) []string {
+	var out []string
+	for _, tag := range tags {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			out = append(out, tag)
+		}
+	}
+	slices.Sort(out)
+	return slices.Compact(out)
+}
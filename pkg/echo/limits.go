@@ -0,0 +1,115 @@
+package echo
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/prxssh/echo/internal/torrent"
+)
+
+// limitCheckInterval is how often seeding torrents are checked against
+// their share limits.
+const limitCheckInterval = 30 * time.Second
+
+// LimitAction is what happens to a torrent that reaches its share limits.
+type LimitAction string
+
+const (
+	LimitPause  LimitAction = "pause"
+	LimitRemove LimitAction = "remove"
+)
+
+// ShareLimits stop a torrent from seeding forever. Zero fields are no
+// limit; the torrent stops at whichever limit it reaches first.
+type ShareLimits struct {
+	// Ratio is uploaded over downloaded bytes.
+	Ratio    float64       `json:"ratio"`
+	SeedTime time.Duration `json:"seedTime"`
+	Action   LimitAction   `json:"action"`
+}
+
+// reached reports whether t has hit either limit.
+func (l ShareLimits) reached(t *Torrent) bool {
+	if l.Ratio > 0 && t.Ratio() >= l.Ratio {
+		return true
+	}
+	return l.SeedTime > 0 && t.SeedTime() >= l.SeedTime
+}
+
+// ShareLimits returns the limits that apply to the torrent: its own if set,
+// the client's otherwise.
+func (c *Client) ShareLimits(infoHash [sha1.Size]byte) (ShareLimits, error) {
+	t, ok := c.torrents.Get(infoHash)
+	if !ok {
+		err := fmt.Errorf("echo: unknown torrent %x", infoHash)
+		return ShareLimits{}, err
+	}
+	return c.shareLimits(t), nil
+}
+
+// SetShareLimits overrides the client's share limits for one torrent; nil
+// goes back to the client's.
+func (c *Client) SetShareLimits(
+	infoHash [sha1.Size]byte,
+	limits *ShareLimits,
+) error {
+	t, ok := c.torrents.Get(infoHash)
+	if !ok {
+		return fmt.Errorf("echo: unknown torrent %x", infoHash)
+	}
+
+	o := c.overridesFor(t)
+	o.ShareLimits = limits
+	c.setOverrides(t, o)
+
+	c.saveSession()
+	return nil
+}
+
+func (c *Client) shareLimits(t *Torrent) ShareLimits {
+	if l := c.overridesFor(t).ShareLimits; l != nil {
+		return *l
+	}
+	return c.cfg.ShareLimits
+}
+
+// runShareLimits pauses or removes seeding torrents once they reach their
+// share limits.
+func (c *Client) runShareLimits() {
+	ticker := time.NewTicker(limitCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		for _, t := range c.ordered() {
+			if t.State() != torrent.StateSeeding {
+				continue
+			}
+			limits := c.shareLimits(t)
+			if !limits.reached(t) {
+				continue
+			}
+
+			slog.Info(
+				"share limit reached",
+				slog.String("name", t.Metainfo.Info.Name),
+				slog.Float64("ratio", t.Ratio()),
+				slog.Duration("seedTime", t.SeedTime()),
+				slog.String("action", string(limits.Action)),
+			)
+			hash := t.Metainfo.Info.Hash
+			if limits.Action == LimitRemove {
+				c.Remove(hash)
+			} else {
+				_ = c.Pause(hash)
+			}
+		}
+	}
+}
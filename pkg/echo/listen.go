@@ -0,0 +1,102 @@
+package echo
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/prxssh/echo/internal/peer"
+)
+
+// inboundHandshakeTimeout bounds how long a peer that connected has to send
+// its handshake.
+const inboundHandshakeTimeout = 10 * time.Second
+
+// startListener opens the TCP port peers connect to us on.
+func (c *Client) startListener() error {
+	c.cfgMu.RLock()
+	port := c.cfg.ListenPort
+	c.cfgMu.RUnlock()
+
+	ln, err := net.Listen("tcp", ":"+strconv.Itoa(int(port)))
+	if err != nil {
+		return fmt.Errorf("echo: listen: %w", err)
+	}
+	c.listener = ln
+	go c.acceptPeers(ln)
+	return nil
+}
+
+// ListenPort returns the TCP port peers connect to us on, zero if none is
+// open.
+func (c *Client) ListenPort() uint16 {
+	if c.listener == nil {
+		return 0
+	}
+	return uint16(c.listener.Addr().(*net.TCPAddr).Port)
+}
+
+// acceptPeers hands the connections peers open to their torrents until the
+// listener is closed.
+func (c *Client) acceptPeers(ln net.Listener) {
+	for {
+		conn, err := ln.Accept()
+		if errors.Is(err, net.ErrClosed) {
+			return
+		}
+		if err != nil {
+			slog.Warn(
+				"accepting peer failed",
+				slog.String("error", err.Error()),
+			)
+			select {
+			case <-c.ctx.Done():
+				return
+			case <-time.After(time.Second):
+			}
+			continue
+		}
+		go c.handOff(conn)
+	}
+}
+
+// handOff reads the handshake of a peer that connected and gives the
+// connection to the torrent it asks for, if that one is running.
+func (c *Client) handOff(conn net.Conn) {
+	_ = conn.SetReadDeadline(time.Now().Add(inboundHandshakeTimeout))
+	remote, err := peer.ReadHandshake(conn)
+	_ = conn.SetReadDeadline(time.Time{})
+	if err != nil || remote.Pstr != "BitTorrent protocol" {
+		_ = conn.Close()
+		return
+	}
+	if fromOutside(conn.RemoteAddr()) {
+		c.lastInbound.Store(time.Now().UnixNano())
+	}
+	t, ok := c.torrents.Get(remote.InfoHash)
+	if !ok {
+		_ = conn.Close()
+		return
+	}
+	if !t.PeerManager.Accept(c.ctx, conn, remote) {
+		slog.Debug(
+			"inbound peer turned away",
+			slog.String("addr", conn.RemoteAddr().String()),
+			slog.String("name", t.Metainfo.Info.Name),
+		)
+	}
+}
+
+// fromOutside reports whether addr is beyond the local network, so that
+// its connecting shows the port reachable through any NAT.
+func fromOutside(addr net.Addr) bool {
+	tcp, ok := addr.(*net.TCPAddr)
+	if !ok {
+		return false
+	}
+	ip := tcp.AddrPort().Addr().Unmap()
+	return ip.IsGlobalUnicast() && !ip.IsPrivate()
+}
@@ -0,0 +1,200 @@
+package echo
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/prxssh/echo/internal/events"
+	"github.com/prxssh/echo/internal/torrent"
+)
+
+// MagnetStatus is a magnet link whose metadata is being fetched.
+type MagnetStatus struct {
+	InfoHash string `json:"infoHash"`
+	Name     string `json:"name"`
+	Magnet   string `json:"magnet"`
+	// Received and Total count the info dict's pieces. Total is zero
+	// until a peer has said how large the info dict is.
+	Received int `json:"received"`
+	Total    int `json:"total"`
+	// Error says why the fetch failed, in "magnet:done" events.
+	Error string `json:"error,omitempty"`
+}
+
+// magnetFetch is a magnet link ready to have its metadata fetched.
+type magnetFetch struct {
+	uri    string
+	mag    *torrent.Magnet
+	opts   addOpts
+	forget func()
+}
+
+// AddMagnetAsync starts fetching the metadata for a magnet link and returns
+// straight away. Progress is pushed as "metadata:progress" events, and a
+// "magnet:done" event follows once the torrent has been added, or with an
+// Error if the fetch failed.
+func (c *Client) AddMagnetAsync(
+	uri string,
+	opts AddOptions,
+) (MagnetStatus, error) {
+	f, err := c.prepareMagnet(uri, opts)
+	if err != nil {
+		return MagnetStatus{}, err
+	}
+	go func() {
+		if _, err := c.fetchMagnet(c.ctx, f); err != nil {
+			slog.Warn(
+				"fetching magnet metadata failed",
+				slog.String("magnet", uri),
+				slog.String("error", err.Error()),
+			)
+		}
+	}()
+	return magnetStatus(f.mag.InfoHash, pendingMagnet{
+		uri:  uri,
+		name: f.mag.Name,
+	}), nil
+}
+
+// Magnets lists the magnet links whose metadata is being fetched.
+func (c *Client) Magnets() []MagnetStatus {
+	c.sessionMu.Lock()
+	defer c.sessionMu.Unlock()
+
+	out := make([]MagnetStatus, 0, len(c.magnets))
+	for hash, m := range c.magnets {
+		out = append(out, magnetStatus(hash, m))
+	}
+	slices.SortFunc(out, func(a, b MagnetStatus) int {
+		return strings.Compare(a.InfoHash, b.InfoHash)
+	})
+	return out
+}
+
+// prepareMagnet checks a magnet link can be added and records it as being
+// fetched.
+func (c *Client) prepareMagnet(
+	uri string,
+	opts AddOptions,
+) (*magnetFetch, error) {
+	mag, err := torrent.ParseMagnet(uri)
+	if err != nil {
+		return nil, err
+	}
+	ao, err := c.addOptsFor(opts)
+	if err != nil {
+		return nil, err
+	}
+	if ao.selectOnly == nil {
+		ao.selectOnly = mag.SelectOnly
+	}
+	if c.closing.Load() {
+		return nil, ErrClosed
+	}
+	if c.torrents.Has(mag.InfoHash) {
+		return nil, ErrDuplicate
+	}
+	forget, err := c.trackMagnet(mag.InfoHash, pendingMagnet{
+		uri:      uri,
+		opts:     opts,
+		name:     mag.Name,
+		fetching: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &magnetFetch{uri: uri, mag: mag, opts: ao, forget: forget}, nil
+}
+
+// fetchMagnet fetches the metadata and adds the torrent, then sends
+// "magnet:done".
+func (c *Client) fetchMagnet(
+	ctx context.Context,
+	f *magnetFetch,
+) (*Torrent, error) {
+	data, err := c.fetchInfo(ctx, f)
+
+	c.sessionMu.Lock()
+	status := magnetStatus(f.mag.InfoHash, c.magnets[f.mag.InfoHash])
+	c.sessionMu.Unlock()
+	f.forget()
+
+	var t *Torrent
+	if err == nil {
+		t, err = c.add(data, f.opts)
+	}
+	if err != nil {
+		status.Error = err.Error()
+	}
+	c.publish("magnet:done", status)
+	return t, err
+}
+
+// fetchInfo fetches the magnet link's metadata, keeping the .torrent built
+// from it in the torrents directory.
+func (c *Client) fetchInfo(
+	ctx context.Context,
+	f *magnetFetch,
+) ([]byte, error) {
+	hash := f.mag.InfoHash
+	sink := func(name string, data any) {
+		p, ok := torrent.TopicMetadataProgress.Match(name, data)
+		if ok {
+			c.noteMagnetProgress(hash, p)
+		}
+		c.publish(name, data)
+	}
+	data, err := torrent.FetchMetadata(
+		events.WithSink(ctx, sink),
+		f.mag,
+		torrent.Opts{
+			DHT:         c.dht,
+			UDP:         c.udp,
+			Proxy:       c.proxy,
+			Locator:     c.geo,
+			Fingerprint: c.GlobalSettings().Fingerprint,
+			ListenPort:  c.ListenPort(),
+			Peer:        &c.cfg.Peer,
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+	if c.GlobalSettings().TorrentsDir != "" {
+		if err := c.saveTorrent(hash, data); err != nil {
+			slog.Warn(
+				"saving fetched torrent failed",
+				slog.String("error", err.Error()),
+			)
+		}
+	}
+
+	return data, nil
+}
+
+func (c *Client) noteMagnetProgress(
+	hash [sha1.Size]byte,
+	p torrent.MetadataProgress,
+) {
+	c.sessionMu.Lock()
+	defer c.sessionMu.Unlock()
+
+	if m, ok := c.magnets[hash]; ok {
+		m.received, m.total = p.Received, p.Total
+		c.magnets[hash] = m
+	}
+}
+
+func magnetStatus(hash [sha1.Size]byte, m pendingMagnet) MagnetStatus {
+	return MagnetStatus{
+		InfoHash: hex.EncodeToString(hash[:]),
+		Name:     m.name,
+		Magnet:   m.uri,
+		Received: m.received,
+		Total:    m.total,
+	}
+}
@@ -0,0 +1,126 @@
+package echo
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/prxssh/echo/internal/desktop"
+	"github.com/prxssh/echo/internal/torrent"
+	"github.com/prxssh/echo/internal/tracker"
+)
+
+// NotifyKind says what a notification is about.
+type NotifyKind string
+
+const (
+	// NotifyCompleted is sent when a torrent finishes downloading.
+	NotifyCompleted NotifyKind = "completed"
+	// NotifyErrored is sent when a torrent stops on an error.
+	NotifyErrored NotifyKind = "errored"
+	// NotifyTracker is sent when a tracker refuses a torrent, as with an
+	// unregistered torrent or an expired passkey.
+	NotifyTracker NotifyKind = "tracker"
+	// NotifySearch is sent when a saved search turns up new results.
+	NotifySearch NotifyKind = "search"
+)
+
+// NotifySettings turns each kind of notification on or off.
+type NotifySettings struct {
+	Completed bool `json:"completed"`
+	Errored   bool `json:"errored"`
+	Tracker   bool `json:"tracker"`
+	Search    bool `json:"search"`
+	// Native also shows the notifications that are on as system
+	// notifications, where the platform has them.
+	Native bool `json:"native"`
+}
+
+func (s NotifySettings) enabled(kind NotifyKind) bool {
+	switch kind {
+	case NotifyCompleted:
+		return s.Completed
+	case NotifyErrored:
+		return s.Errored
+	case NotifyTracker:
+		return s.Tracker
+	case NotifySearch:
+		return s.Search
+	}
+	return false
+}
+
+// Notification is the payload of "notification" events: something about a
+// torrent the user should hear about. InfoHash and Name are empty for a
+// saved search's results.
+type Notification struct {
+	Kind     NotifyKind `json:"kind"`
+	InfoHash string     `json:"infoHash"`
+	Name     string     `json:"name"`
+	Title    string     `json:"title"`
+	Message  string     `json:"message"`
+	Time     time.Time  `json:"time"`
+}
+
+// runNotifications turns completions, errors and tracker refusals into
+// notifications until the client closes.
+func (c *Client) runNotifications() {
+	unsubscribe := c.Subscribe(func(e Event) {
+		if n, ok := notificationFor(e); ok {
+			go c.notify(n)
+		}
+	})
+	defer unsubscribe()
+
+	<-c.ctx.Done()
+}
+
+// notificationFor picks out the events worth a notification. Events are
+// also sent under their info-hash suffixed names; those are skipped so
+// each is only seen once.
+func notificationFor(e Event) (Notification, bool) {
+	var n Notification
+	if data, ok := torrent.TopicState.Match(e.Name, e.Data); ok {
+		switch {
+		case data.From == torrent.StateDownloading &&
+			data.To == torrent.StateSeeding:
+			n.Kind = NotifyCompleted
+			n.Title = "Download complete"
+			n.Message = data.Name
+		case data.To == torrent.StateErrored:
+			n.Kind = NotifyErrored
+			n.Title = "Torrent stopped"
+			n.Message = data.Name + ": " + data.Error
+		default:
+			return n, false
+		}
+		n.InfoHash, n.Name = data.InfoHash, data.Name
+	} else if data, ok := tracker.TopicRefused.Match(e.Name, e.Data); ok {
+		n.Kind = NotifyTracker
+		n.Title = "Tracker refused " + data.Name
+		n.Message = data.Tracker + ": " + data.Reason
+		n.InfoHash, n.Name = data.InfoHash, data.Name
+	} else {
+		return n, false
+	}
+	n.Time = time.Now()
+	return n, true
+}
+
+func (c *Client) notify(n Notification) {
+	s := c.GlobalSettings().Notifications
+	if !s.enabled(n.Kind) {
+		return
+	}
+
+	c.publish("notification", n)
+	if !s.Native {
+		return
+	}
+	if err := desktop.Notify(n.Title, n.Message); err != nil {
+		slog.Warn(
+			"showing notification failed",
+			slog.String("kind", string(n.Kind)),
+			slog.String("error", err.Error()),
+		)
+	}
+}
@@ -0,0 +1,100 @@
+package echo
+
+import (
+	"crypto/sha1"
+	"fmt"
+)
+
+// Overrides are a torrent's own settings. Nil fields fall back to the
+// client's Config.
+type Overrides struct {
+	MaxPeers      *uint32      `json:"maxPeers,omitempty"`
+	DownloadLimit *int64       `json:"downloadLimit,omitempty"`
+	UploadLimit   *int64       `json:"uploadLimit,omitempty"`
+	NumWant       *uint32      `json:"numWant,omitempty"`
+	Sequential    *bool        `json:"sequential,omitempty"`
+	ShareLimits   *ShareLimits `json:"shareLimits,omitempty"`
+	Priority      *Priority    `json:"priority,omitempty"`
+}
+
+// apply layers the overrides on top of s.
+func (o Overrides) apply(s TorrentSettings) TorrentSettings {
+	if o.MaxPeers != nil {
+		s.MaxPeers = *o.MaxPeers
+	}
+	if o.DownloadLimit != nil {
+		s.DownloadLimit = *o.DownloadLimit
+	}
+	if o.UploadLimit != nil {
+		s.UploadLimit = *o.UploadLimit
+	}
+	if o.NumWant != nil {
+		s.NumWant = *o.NumWant
+	}
+	if o.Sequential != nil {
+		s.Sequential = *o.Sequential
+	}
+	return s
+}
+
+// Overrides returns the torrent's own settings.
+func (c *Client) Overrides(infoHash [sha1.Size]byte) (Overrides, error) {
+	t, ok := c.torrents.Get(infoHash)
+	if !ok {
+		err := fmt.Errorf("echo: unknown torrent %x", infoHash)
+		return Overrides{}, err
+	}
+	return c.overridesFor(t), nil
+}
+
+// SetOverrides replaces the torrent's own settings and puts the result into
+// effect straight away.
+func (c *Client) SetOverrides(infoHash [sha1.Size]byte, o Overrides) error {
+	t, ok := c.torrents.Get(infoHash)
+	if !ok {
+		return fmt.Errorf("echo: unknown torrent %x", infoHash)
+	}
+	if o.Priority != nil && !o.Priority.valid() {
+		return fmt.Errorf("echo: unknown priority %q", *o.Priority)
+	}
+
+	c.setOverrides(t, o)
+	t.Apply(c.settingsFor(t))
+	c.saveSession()
+	return nil
+}
+
+// Settings returns the settings in effect for the torrent: the client's,
+// with the torrent's overrides on top.
+func (c *Client) Settings(infoHash [sha1.Size]byte) (TorrentSettings, error) {
+	t, ok := c.torrents.Get(infoHash)
+	if !ok {
+		err := fmt.Errorf("echo: unknown torrent %x", infoHash)
+		return TorrentSettings{}, err
+	}
+	return t.Settings(), nil
+}
+
+func (c *Client) overridesFor(t *Torrent) Overrides {
+	c.overridesMu.Lock()
+	defer c.overridesMu.Unlock()
+
+	return c.overrides[t]
+}
+
+func (c *Client) setOverrides(t *Torrent, o Overrides) {
+	c.overridesMu.Lock()
+	defer c.overridesMu.Unlock()
+
+	if o == (Overrides{}) {
+		delete(c.overrides, t)
+	} else {
+		c.overrides[t] = o
+	}
+}
+
+// settingsFor layers the torrent's overrides on the client's settings and
+// narrows the result to the torrent's share of the global limits.
+func (c *Client) settingsFor(t *Torrent) TorrentSettings {
+	return c.withShare(t, c.overridesFor(t).apply(c.cfg.Torrent))
+}
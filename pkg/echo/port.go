@@ -0,0 +1,168 @@
+package echo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prxssh/echo/internal/torrent"
+)
+
+// portQuietPeriod is how long a listen port reachable from outside can go
+// without a peer connecting to it while torrents are announced; one quiet
+// for longer is taken as unreachable. The DHT's port is judged the same
+// way by the queries of nodes it has not written to.
+const portQuietPeriod = 15 * time.Minute
+
+type PortStatus string
+
+const (
+	PortOpen   PortStatus = "open"
+	PortClosed PortStatus = "closed"
+	// PortUnknown is a port no peer has connected to yet, before
+	// portQuietPeriod has passed or while no torrent is announced.
+	PortUnknown PortStatus = "unknown"
+)
+
+// PortCheck is whether the TCP listen port can be reached from outside.
+type PortCheck struct {
+	Port     uint16     `json:"port"`
+	Protocol string     `json:"protocol"`
+	Status   PortStatus `json:"status"`
+	// Method is how the status was found: "peers", from peers connecting
+	// unasked, or "service", from Config.PortCheckURL.
+	Method     string `json:"method"`
+	ExternalIP string `json:"externalIp,omitempty"`
+	// DHTReachable is whether a node the DHT had not written to queried
+	// its UDP port within portQuietPeriod, which shows that port open.
+	DHTReachable bool      `json:"dhtReachable"`
+	CheckedAt    time.Time `json:"checkedAt"`
+}
+
+// CheckPort tests whether the TCP port peers connect to can be reached from
+// outside, and sends the result as a "port:check" event. Without a
+// Config.PortCheckURL it waits up to PortCheckTimeout for a peer to
+// connect from outside the local network.
+func (c *Client) CheckPort(ctx context.Context) (PortCheck, error) {
+	port := c.ListenPort()
+	if port == 0 {
+		return PortCheck{}, errors.New("echo: no port listens")
+	}
+	check := PortCheck{Port: port, Protocol: "tcp"}
+	if c.dht != nil {
+		check.ExternalIP = c.dht.Stats().ExternalIP
+		check.DHTReachable = recently(c.dht.LastUnsolicited())
+	}
+
+	var err error
+	if url := c.cfg.PortCheckURL; url != "" {
+		check.Method = "service"
+		check.Status, err = c.askPortService(ctx, url, check.Port)
+	} else {
+		check.Method = "peers"
+		check.Status, err = c.awaitInbound(ctx)
+	}
+	if err != nil {
+		return PortCheck{}, err
+	}
+	check.CheckedAt = time.Now()
+	c.publish("port:check", check)
+	return check, nil
+}
+
+// recently reports whether t is within portQuietPeriod.
+func recently(t time.Time) bool {
+	return !t.IsZero() && time.Since(t) < portQuietPeriod
+}
+
+// awaitInbound waits for a peer connecting from outside to show the port
+// open.
+func (c *Client) awaitInbound(ctx context.Context) (PortStatus, error) {
+	recent := func() bool {
+		return recently(time.Unix(0, c.lastInbound.Load()))
+	}
+	if recent() {
+		return PortOpen, nil
+	}
+
+	if c.cfg.PortCheckTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.cfg.PortCheckTimeout)
+		defer cancel()
+	}
+	tick := time.NewTicker(time.Second)
+	defer tick.Stop()
+	for {
+		select {
+		case <-tick.C:
+			if recent() {
+				return PortOpen, nil
+			}
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.Canceled) {
+				return "", ctx.Err()
+			}
+			// Peers only learn of the port from announces, which
+			// take a while to be handed out.
+			if time.Since(c.started) < portQuietPeriod ||
+				!c.announcing() {
+				return PortUnknown, nil
+			}
+			return PortClosed, nil
+		}
+	}
+}
+
+// announcing reports whether a torrent is running, telling trackers and
+// the DHT where peers reach us.
+func (c *Client) announcing() bool {
+	for _, t := range c.torrents.List() {
+		switch t.State() {
+		case torrent.StateDownloading, torrent.StateSeeding:
+			return true
+		}
+	}
+	return false
+}
+
+// askPortService asks the service at url, with "{port}" replaced by port,
+// whether the port is open. It answers "1", "open" or "true" if it is,
+// and "0", "closed" or "false" if not. The request is made directly,
+// since the service checks the address it comes from.
+func (c *Client) askPortService(
+	ctx context.Context,
+	url string,
+	port uint16,
+) (PortStatus, error) {
+	url = strings.ReplaceAll(url, "{port}", strconv.Itoa(int(port)))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", fmt.Errorf("echo: port check: %w", err)
+	}
+	client := &http.Client{Timeout: c.cfg.PortCheckTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("echo: port check: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("echo: port check: %s", resp.Status)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 64))
+	if err != nil {
+		return "", fmt.Errorf("echo: port check: %w", err)
+	}
+	switch strings.ToLower(strings.TrimSpace(string(body))) {
+	case "1", "open", "true":
+		return PortOpen, nil
+	case "0", "closed", "false":
+		return PortClosed, nil
+	}
+	return "", fmt.Errorf("echo: port check: unknown answer %q", body)
+}
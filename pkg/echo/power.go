@@ -0,0 +1,184 @@
+package echo
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/prxssh/echo/internal/power"
+)
+
+// PowerAction is what the session does while on battery or a metered
+// connection.
+type PowerAction string
+
+const (
+	PowerIgnore PowerAction = ""
+	// PowerPause holds every torrent in the queue, as a pause window
+	// does.
+	PowerPause PowerAction = "pause"
+	// PowerThrottle caps the session's rates at PowerPolicy's throttle
+	// limits.
+	PowerThrottle PowerAction = "throttle"
+)
+
+func (a PowerAction) valid() bool {
+	switch a {
+	case PowerIgnore, PowerPause, PowerThrottle:
+		return true
+	}
+	return false
+}
+
+// PowerPolicy says how the session backs off on battery power or a metered
+// connection. Where both apply, pausing wins over throttling.
+type PowerPolicy struct {
+	OnBattery PowerAction `json:"onBattery"`
+	OnMetered PowerAction `json:"onMetered"`
+	// ThrottleDownload and ThrottleUpload, in bytes per second, are the
+	// session's limits while throttled; zero leaves that direction
+	// alone.
+	ThrottleDownload int64 `json:"throttleDownload"`
+	ThrottleUpload   int64 `json:"throttleUpload"`
+}
+
+// PowerStatus is the machine's state and what the session is doing about
+// it, pushed as the "session:power" event when either changes.
+type PowerStatus struct {
+	power.State
+	Action PowerAction `json:"action"`
+}
+
+// action picks the strongest of the actions that apply to s.
+func (p PowerPolicy) action(s power.State) PowerAction {
+	var actions []PowerAction
+	if s.OnBattery {
+		actions = append(actions, p.OnBattery)
+	}
+	if s.Metered {
+		actions = append(actions, p.OnMetered)
+	}
+
+	act := PowerIgnore
+	for _, a := range actions {
+		if a == PowerPause {
+			return PowerPause
+		}
+		if a == PowerThrottle {
+			act = PowerThrottle
+		}
+	}
+	return act
+}
+
+// PowerPolicy returns how the session backs off on battery or a metered
+// connection.
+func (c *Client) PowerPolicy() PowerPolicy {
+	c.powerMu.Lock()
+	defer c.powerMu.Unlock()
+
+	return c.powerPolicy
+}
+
+// SetPowerPolicy replaces the power policy, taking effect at once.
+func (c *Client) SetPowerPolicy(p PowerPolicy) error {
+	if !p.OnBattery.valid() || !p.OnMetered.valid() {
+		return fmt.Errorf(
+			"echo: unknown power action %q or %q",
+			p.OnBattery,
+			p.OnMetered,
+		)
+	}
+	if p.ThrottleDownload < 0 || p.ThrottleUpload < 0 {
+		return fmt.Errorf("echo: negative throttle limit")
+	}
+
+	c.powerMu.Lock()
+	c.powerPolicy = p
+	state := c.power.State
+	c.powerMu.Unlock()
+
+	c.applyPower(state)
+	c.saveSession()
+	return nil
+}
+
+// Power returns the machine's last read state and the action taken.
+func (c *Client) Power() PowerStatus {
+	c.powerMu.Lock()
+	defer c.powerMu.Unlock()
+
+	return c.power
+}
+
+// applyPower acts on state as the policy says.
+func (c *Client) applyPower(state power.State) {
+	c.powerMu.Lock()
+	prev := c.power
+	c.power = PowerStatus{
+		State:  state,
+		Action: c.powerPolicy.action(state),
+	}
+	now := c.power
+	c.powerMu.Unlock()
+	if now == prev {
+		return
+	}
+
+	slog.Info(
+		"power state changed",
+		slog.Bool("battery", state.OnBattery),
+		slog.Bool("metered", state.Metered),
+		slog.String("action", string(now.Action)),
+	)
+	c.publish("session:power", now)
+	c.powerPaused.Store(now.Action == PowerPause)
+	c.updateSuspended()
+	if now.Action != prev.Action {
+		c.shareBandwidth()
+	}
+}
+
+// rateLimits returns the session's download and upload limits, tightened
+// while throttled for power.
+func (c *Client) rateLimits() (down, up int64) {
+	s := c.GlobalSettings()
+	down, up = s.DownloadLimit, s.UploadLimit
+
+	c.powerMu.Lock()
+	defer c.powerMu.Unlock()
+
+	if c.power.Action == PowerThrottle {
+		down = tighter(down, c.powerPolicy.ThrottleDownload)
+		up = tighter(up, c.powerPolicy.ThrottleUpload)
+	}
+	return down, up
+}
+
+// runPower reads the machine's power and network state every PowerInterval
+// until the client closes.
+func (c *Client) runPower() {
+	if c.cfg.PowerInterval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(c.cfg.PowerInterval)
+	defer ticker.Stop()
+
+	for {
+		state, err := power.Read()
+		if err != nil {
+			slog.Debug(
+				"reading power state failed",
+				slog.String("error", err.Error()),
+			)
+		} else {
+			c.applyPower(state)
+		}
+
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
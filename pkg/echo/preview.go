@@ -0,0 +1,115 @@
+package echo
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/prxssh/echo/internal/torrent"
+)
+
+// previewTimeout bounds fetching a result's metadata from peers when ctx
+// doesn't.
+const previewTimeout = 2 * time.Minute
+
+// ResultPreview is what a search result holds, read from its metadata
+// before it is added.
+type ResultPreview struct {
+	Name     string        `json:"name"`
+	InfoHash string        `json:"infoHash"`
+	Size     uint64        `json:"size"`
+	Files    []PreviewFile `json:"files"`
+	Private  bool          `json:"private"`
+}
+
+// PreviewFile is one of the files a previewed result would download.
+type PreviewFile struct {
+	Path   string `json:"path"`
+	Length uint64 `json:"length"`
+}
+
+// PreviewResult fetches r's metadata, downloading its .torrent or, for a
+// magnet link, asking peers for it, and returns its files without adding
+// it.
+func (c *Client) PreviewResult(
+	ctx context.Context,
+	r SearchResult,
+) (ResultPreview, error) {
+	uri := r.Magnet
+	var data []byte
+	if r.Link != "" {
+		var err error
+		data, err = c.downloadTorrent(ctx, r.Link)
+		var mr *magnetRedirect
+		switch {
+		case errors.As(err, &mr):
+			uri, data = mr.uri, nil
+		case err != nil && uri == "":
+			return ResultPreview{}, err
+		}
+	}
+	if data == nil {
+		if uri == "" {
+			err := errors.New("echo: result has no link")
+			return ResultPreview{}, err
+		}
+		var err error
+		data, err = c.fetchPreview(ctx, uri)
+		if err != nil {
+			return ResultPreview{}, err
+		}
+	}
+
+	m, err := torrent.ParseMetainfo(bytes.NewReader(data))
+	if err != nil {
+		return ResultPreview{}, err
+	}
+	return newPreview(m), nil
+}
+
+func (c *Client) fetchPreview(ctx context.Context, uri string) ([]byte, error) {
+	mag, err := torrent.ParseMagnet(uri)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, previewTimeout)
+	defer cancel()
+
+	return torrent.FetchMetadata(
+		ctx,
+		mag,
+		torrent.Opts{
+			DHT:         c.dht,
+			UDP:         c.udp,
+			Proxy:       c.proxy,
+			Fingerprint: c.GlobalSettings().Fingerprint,
+			Peer:        &c.cfg.Peer,
+		},
+	)
+}
+
+func newPreview(m *torrent.Metainfo) ResultPreview {
+	p := ResultPreview{
+		Name:     m.Info.Name,
+		InfoHash: hex.EncodeToString(m.Info.Hash[:]),
+		Size:     m.Size,
+		Private:  m.Info.Private,
+	}
+	if m.Info.Files == nil {
+		p.Files = []PreviewFile{{Path: m.Info.Name, Length: m.Size}}
+		return p
+	}
+	for _, f := range *m.Info.Files {
+		if f.Padding {
+			continue
+		}
+		p.Files = append(p.Files, PreviewFile{
+			Path:   strings.Join(f.Path, "/"),
+			Length: f.Length,
+		})
+	}
+	return p
+}
@@ -0,0 +1,194 @@
+package echo
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"log/slog"
+	"slices"
+
+	"github.com/prxssh/echo/internal/torrent"
+)
+
+// enqueue appends t to the end of the queue.
+func (c *Client) enqueue(t *Torrent) {
+	c.queueMu.Lock()
+	defer c.queueMu.Unlock()
+
+	c.queue = append(c.queue, t)
+}
+
+// dequeue drops t from the queue.
+func (c *Client) dequeue(t *Torrent) {
+	c.queueMu.Lock()
+	defer c.queueMu.Unlock()
+
+	c.queue = slices.DeleteFunc(c.queue, func(q *Torrent) bool {
+		return q == t
+	})
+}
+
+// ordered returns the session's torrents in queue order.
+func (c *Client) ordered() []*Torrent {
+	c.queueMu.Lock()
+	defer c.queueMu.Unlock()
+
+	return slices.Clone(c.queue)
+}
+
+// QueuePosition returns the torrent's zero-based place in the queue.
+func (c *Client) QueuePosition(infoHash [sha1.Size]byte) (int, error) {
+	t, ok := c.torrents.Get(infoHash)
+	if !ok {
+		return 0, fmt.Errorf("echo: unknown torrent %x", infoHash)
+	}
+
+	c.queueMu.Lock()
+	defer c.queueMu.Unlock()
+
+	return slices.Index(c.queue, t), nil
+}
+
+// SetQueuePosition moves the torrent to pos in the queue, clamped to its
+// ends. Torrents earlier in the queue get active slots first.
+func (c *Client) SetQueuePosition(infoHash [sha1.Size]byte, pos int) error {
+	t, ok := c.torrents.Get(infoHash)
+	if !ok {
+		return fmt.Errorf("echo: unknown torrent %x", infoHash)
+	}
+
+	c.moveInQueue(t, func(int) int { return pos })
+	return nil
+}
+
+// MoveToTop moves the torrent to the front of the queue.
+func (c *Client) MoveToTop(infoHash [sha1.Size]byte) error {
+	return c.SetQueuePosition(infoHash, 0)
+}
+
+// MoveUp moves the torrent one place towards the front of the queue.
+func (c *Client) MoveUp(infoHash [sha1.Size]byte) error {
+	return c.moveBy(infoHash, -1)
+}
+
+// MoveDown moves the torrent one place towards the back of the queue.
+func (c *Client) MoveDown(infoHash [sha1.Size]byte) error {
+	return c.moveBy(infoHash, 1)
+}
+
+// MoveToBottom moves the torrent to the back of the queue.
+func (c *Client) MoveToBottom(infoHash [sha1.Size]byte) error {
+	c.queueMu.Lock()
+	last := len(c.queue)
+	c.queueMu.Unlock()
+
+	return c.SetQueuePosition(infoHash, last)
+}
+
+func (c *Client) moveBy(infoHash [sha1.Size]byte, delta int) error {
+	t, ok := c.torrents.Get(infoHash)
+	if !ok {
+		return fmt.Errorf("echo: unknown torrent %x", infoHash)
+	}
+
+	c.moveInQueue(t, func(i int) int { return i + delta })
+	return nil
+}
+
+// moveInQueue moves t from its place i to to(i),
+// clamped to the ends of the queue, and hands out active slots again.
+func (c *Client) moveInQueue(t *Torrent, to func(i int) int) {
+	c.queueMu.Lock()
+	i := slices.Index(c.queue, t)
+	if i >= 0 {
+		c.queue = slices.Delete(c.queue, i, i+1)
+		pos := max(0, min(to(i), len(c.queue)))
+		c.queue = slices.Insert(c.queue, pos, t)
+	}
+	c.queueMu.Unlock()
+
+	c.reschedule()
+	c.saveSession()
+}
+
+// reschedule asks the queue loop to hand out active slots again.
+func (c *Client) reschedule() {
+	select {
+	case c.kick <- struct{}{}:
+	default:
+	}
+}
+
+// runQueue reschedules whenever a torrent changes state, since finishing a
+// download or pausing a torrent frees a slot.
+func (c *Client) runQueue() {
+	unsubscribe := c.Subscribe(func(e Event) {
+		if e.Name == string(torrent.TopicState) {
+			c.reschedule()
+		}
+	})
+	defer unsubscribe()
+
+	for {
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-c.kick:
+			c.schedule()
+		}
+	}
+}
+
+// schedule walks the queue in order, starting queued torrents while there
+// are slots for them and queueing active torrents past the limits. Paused,
+// errored, checking and moving torrents hold no slot, nor do downloads a
+// disk quota holds, and while the session is suspended there are no slots
+// at all.
+func (c *Client) schedule() {
+	suspended := c.suspended.Load()
+	s := c.GlobalSettings()
+	var downloads, seeds int
+	for _, t := range c.scheduleOrder() {
+		state := t.State()
+		if state != torrent.StateQueued && !state.Active() {
+			continue
+		}
+
+		_, _, left := t.Totals()
+		slot := false
+		switch {
+		case suspended:
+		case left > 0 && c.overQuota(t):
+		case left > 0:
+			slot = withinLimit(downloads, s.MaxActiveDownloads)
+			if slot {
+				downloads++
+			}
+		default:
+			slot = withinLimit(seeds, s.MaxActiveSeeds)
+			if slot {
+				seeds++
+			}
+		}
+
+		switch {
+		case slot && state == torrent.StateQueued:
+			err := t.Start(c.ctx)
+			if err != nil {
+				name := t.Metainfo.Info.Name
+				slog.Warn(
+					"starting queued torrent failed",
+					slog.String("name", name),
+					slog.String("error", err.Error()),
+				)
+			}
+		case !slot && state.Active():
+			t.Queue(c.ctx)
+		}
+	}
+}
+
+// withinLimit reports whether another slot fits under limit; zero or less
+// means unlimited.
+func withinLimit(active, limit int) bool {
+	return limit <= 0 || active < limit
+}
@@ -0,0 +1,206 @@
+package echo
+
+import (
+	"errors"
+	"log/slog"
+	"maps"
+	"slices"
+	"time"
+)
+
+// quotaTick is how often disk usage and the month's transfer are checked
+// against the quotas, bounding how far past one a download can run.
+const quotaTick = time.Minute
+
+// maxResetDay keeps the monthly reset on a day every month has.
+const maxResetDay = 28
+
+// Quotas cap the space torrents take up on disk and what the session
+// transfers in a month, for small disks and metered connections. Zero
+// leaves a quota off.
+type Quotas struct {
+	// Disk, in bytes, caps the data of every torrent together. Once it
+	// is reached, downloads wait in the queue while seeds carry on, as
+	// they do for a category whose own quota is reached.
+	Disk uint64 `json:"disk"`
+	// Monthly, in bytes uploaded and downloaded, caps the month's
+	// transfer. Once it is reached the session is suspended until the
+	// month resets.
+	Monthly uint64 `json:"monthly"`
+	// ResetDay is the day of the month, 1 to 28, the month's count
+	// starts again at midnight on; zero is the first.
+	ResetDay int `json:"resetDay"`
+}
+
+func (q Quotas) validate() error {
+	if q.ResetDay < 0 || q.ResetDay > maxResetDay {
+		return errors.New("echo: quota reset day is not 1 to 28")
+	}
+	return nil
+}
+
+// period returns when the month counting t started and when it ends.
+func (q Quotas) period(t time.Time) (start, end time.Time) {
+	day := max(q.ResetDay, 1)
+	start = time.Date(t.Year(), t.Month(), day, 0, 0, 0, 0, t.Location())
+	if start.After(t) {
+		start = start.AddDate(0, -1, 0)
+	}
+	return start, start.AddDate(0, 1, 0)
+}
+
+// QuotaStatus is how near the session is to its quotas, pushed as the
+// "session:quota" event when one is reached or no longer is.
+type QuotaStatus struct {
+	Quotas Quotas `json:"quotas"`
+	// DiskUsed counts the bytes of the pieces every torrent has, and
+	// CategoryUsed those of each category's torrents.
+	DiskUsed     uint64            `json:"diskUsed"`
+	CategoryUsed map[string]uint64 `json:"categoryUsed"`
+	// DiskFull is set while the disk quota holds every download, and
+	// FullCategories lists those whose own quota holds theirs.
+	DiskFull       bool     `json:"diskFull"`
+	FullCategories []string `json:"fullCategories"`
+	// Month is what was transferred between PeriodStart and now; the
+	// count starts again at PeriodEnd.
+	Month       Transfer  `json:"month"`
+	PeriodStart time.Time `json:"periodStart"`
+	PeriodEnd   time.Time `json:"periodEnd"`
+	// CapReached is set while the monthly cap suspends the session.
+	CapReached bool `json:"capReached"`
+}
+
+// Quotas returns the disk and monthly transfer quotas.
+func (c *Client) Quotas() Quotas {
+	c.quotaMu.Lock()
+	defer c.quotaMu.Unlock()
+
+	return c.quotas
+}
+
+// SetQuotas replaces the disk and monthly transfer quotas, taking effect
+// at once.
+func (c *Client) SetQuotas(q Quotas) error {
+	if err := q.validate(); err != nil {
+		return err
+	}
+
+	c.quotaMu.Lock()
+	c.quotas = q
+	c.quotaMu.Unlock()
+
+	c.applyQuotas(time.Now())
+	c.saveSession()
+	return nil
+}
+
+// QuotaStatus returns how near the session is to its quotas.
+func (c *Client) QuotaStatus() QuotaStatus {
+	c.quotaMu.Lock()
+	defer c.quotaMu.Unlock()
+
+	s := c.quotaStatus
+	s.CategoryUsed = maps.Clone(s.CategoryUsed)
+	s.FullCategories = slices.Clone(s.FullCategories)
+	return s
+}
+
+// monthTransfer returns what was transferred this month, starting the
+// count again if the month has reset since it was last read.
+func (c *Client) monthTransfer(start time.Time) Transfer {
+	c.statsMu.Lock()
+	defer c.statsMu.Unlock()
+
+	c.sampleStats()
+	if start.After(c.monthStart) {
+		c.month = Transfer{}
+		c.monthStart = start
+	}
+	return c.month
+}
+
+// diskUsage returns the bytes of the pieces every torrent has, in all and
+// by category.
+func (c *Client) diskUsage() (total uint64, byCategory map[string]uint64) {
+	byCategory = make(map[string]uint64)
+	for _, t := range c.torrents.List() {
+		_, _, left := t.Totals()
+		used := t.Metainfo.Size - min(left, t.Metainfo.Size)
+		total += used
+		if cat := c.labelsFor(t).Category; cat != "" {
+			byCategory[cat] += used
+		}
+	}
+	return total, byCategory
+}
+
+// applyQuotas measures the session against its quotas as of now, holding
+// downloads while a disk quota is reached and suspending the session while
+// the monthly cap is.
+func (c *Client) applyQuotas(now time.Time) {
+	q := c.Quotas()
+	s := QuotaStatus{Quotas: q}
+	s.PeriodStart, s.PeriodEnd = q.period(now)
+	s.Month = c.monthTransfer(s.PeriodStart)
+	s.CapReached = q.Monthly > 0 &&
+		s.Month.Uploaded+s.Month.Downloaded >= q.Monthly
+
+	s.DiskUsed, s.CategoryUsed = c.diskUsage()
+	s.DiskFull = q.Disk > 0 && s.DiskUsed >= q.Disk
+	s.FullCategories = []string{}
+	for _, cat := range c.Categories() {
+		if cat.Quota > 0 && s.CategoryUsed[cat.Name] >= cat.Quota {
+			s.FullCategories = append(s.FullCategories, cat.Name)
+		}
+	}
+
+	c.quotaMu.Lock()
+	prev := c.quotaStatus
+	c.quotaStatus = s
+	c.quotaMu.Unlock()
+
+	held := s.DiskFull != prev.DiskFull ||
+		!slices.Equal(s.FullCategories, prev.FullCategories)
+	if !held && s.CapReached == prev.CapReached {
+		return
+	}
+	slog.Info(
+		"quotas changed",
+		slog.Bool("diskFull", s.DiskFull),
+		slog.Any("fullCategories", s.FullCategories),
+		slog.Bool("capReached", s.CapReached),
+	)
+	c.publish("session:quota", c.QuotaStatus())
+	c.quotaPaused.Store(s.CapReached)
+	c.updateSuspended()
+	if held {
+		c.reschedule()
+	}
+}
+
+// overQuota reports whether a disk quota holds t's download.
+func (c *Client) overQuota(t *Torrent) bool {
+	cat := c.labelsFor(t).Category
+
+	c.quotaMu.Lock()
+	defer c.quotaMu.Unlock()
+
+	s := c.quotaStatus
+	return s.DiskFull || cat != "" && slices.Contains(s.FullCategories, cat)
+}
+
+// runQuotas checks the quotas every quotaTick until the client closes.
+func (c *Client) runQuotas() {
+	ticker := time.NewTicker(quotaTick)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		c.applyQuotas(time.Now())
+	}
+}
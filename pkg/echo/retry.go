@@ -0,0 +1,132 @@
+package echo
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/prxssh/echo/internal/torrent"
+)
+
+const (
+	retryTick = 5 * time.Second
+	// retryReset is how long a retried torrent must run without failing
+	// again before its backoff starts over.
+	retryReset = 10 * time.Minute
+	// maxRetryDoublings keeps the backoff from overflowing when there is
+	// no MaxRetryBackoff.
+	maxRetryDoublings = 20
+)
+
+// retry tracks an errored torrent's automatic restarts.
+type retry struct {
+	attempts int
+	// pending is set while a restart is planned for at.
+	pending   bool
+	at        time.Time
+	restarted time.Time
+}
+
+// runRetries restarts torrents that failed with a transient error, backing
+// off from RetryBackoff up to MaxRetryBackoff each time they fail again.
+func (c *Client) runRetries() {
+	if c.cfg.RetryBackoff <= 0 {
+		return
+	}
+	ticker := time.NewTicker(retryTick)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		due := c.dueRetries(time.Now())
+		for _, t := range due {
+			slog.Info(
+				"retrying errored torrent",
+				slog.String("name", t.Metainfo.Info.Name),
+			)
+			t.Queue(c.ctx)
+		}
+		if len(due) > 0 {
+			c.reschedule()
+		}
+	}
+}
+
+// dueRetries plans restarts for newly errored torrents and returns those
+// whose restart is due.
+func (c *Client) dueRetries(now time.Time) []*Torrent {
+	c.retryMu.Lock()
+	defer c.retryMu.Unlock()
+
+	var due []*Torrent
+	live := make(map[*Torrent]bool)
+	for _, t := range c.torrents.List() {
+		live[t] = true
+		r, ok := c.retries[t]
+		if t.State() != torrent.StateErrored {
+			switch {
+			case !ok:
+			case r.pending:
+				// Started by hand before the retry was due.
+				r.pending = false
+				r.restarted = now
+			case now.Sub(r.restarted) >= retryReset:
+				delete(c.retries, t)
+			}
+			continue
+		}
+		if !torrent.Transient(t.Err()) {
+			delete(c.retries, t)
+			continue
+		}
+
+		if r == nil {
+			r = &retry{}
+			c.retries[t] = r
+		}
+		if !r.pending {
+			r.attempts++
+			r.pending = true
+			r.at = now.Add(c.retryBackoff(r.attempts))
+		} else if !now.Before(r.at) {
+			r.pending = false
+			r.restarted = now
+			due = append(due, t)
+		}
+	}
+	for t := range c.retries {
+		if !live[t] {
+			delete(c.retries, t)
+		}
+	}
+	return due
+}
+
+// retryBackoff is how long to wait before the given restart attempt.
+func (c *Client) retryBackoff(attempt int) time.Duration {
+	d := c.cfg.RetryBackoff
+	for range min(attempt-1, maxRetryDoublings) {
+		d *= 2
+	}
+	if c.cfg.MaxRetryBackoff > 0 {
+		d = min(d, c.cfg.MaxRetryBackoff)
+	}
+	return d
+}
+
+// retryIn returns how long until t is restarted, or zero if no restart is
+// planned.
+func (c *Client) retryIn(t *Torrent) time.Duration {
+	c.retryMu.Lock()
+	defer c.retryMu.Unlock()
+
+	r, ok := c.retries[t]
+	if !ok || !r.pending {
+		return 0
+	}
+	return max(time.Until(r.at), time.Nanosecond)
+}
@@ -0,0 +1,28 @@
+package echo
+
+import "log/slog"
+
+// reuseFiles gives a newly added torrent any of its files that other
+// torrents in the session already have (BEP 38), reporting whether it took
+// any. The torrent's data must then be checked before it starts.
+func (c *Client) reuseFiles(t *Torrent) bool {
+	reused := false
+	for _, f := range t.FindShared(c.torrents.List()) {
+		if err := t.Reuse(f); err != nil {
+			slog.Warn(
+				"reusing file failed",
+				slog.String("path", f.Path),
+				slog.String("source", f.Source),
+				slog.String("error", err.Error()),
+			)
+			continue
+		}
+		slog.Info(
+			"reusing file from another torrent",
+			slog.String("path", f.Path),
+			slog.String("source", f.Source),
+		)
+		reused = true
+	}
+	return reused
+}
@@ -0,0 +1,227 @@
+package echo
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/prxssh/echo/internal/rss"
+)
+
+// RSSRule picks the items of a feed to download.
+type RSSRule = rss.Rule
+
+// Feed is an RSS or Atom feed polled for torrents to add.
+type Feed struct {
+	URL string `json:"url"`
+	// Interval is how often the feed is fetched. Zero means
+	// Config.RSSInterval.
+	Interval time.Duration `json:"interval"`
+	// Rules are tried in order; the first to match an item adds it.
+	Rules []RSSRule `json:"rules"`
+}
+
+// rssTick is how often feeds are checked for being due.
+const rssTick = time.Minute
+
+// Feeds returns the feeds being polled.
+func (c *Client) Feeds() []Feed {
+	c.rssMu.Lock()
+	defer c.rssMu.Unlock()
+
+	return slices.Clone(c.feeds)
+}
+
+// SetFeeds replaces the feeds being polled. Items already downloaded from a
+// feed that is kept are not downloaded again.
+func (c *Client) SetFeeds(feeds []Feed) error {
+	urls := make(map[string]bool, len(feeds))
+	for _, f := range feeds {
+		if f.URL == "" {
+			return errors.New("echo: feed has no URL")
+		}
+		if urls[f.URL] {
+			return fmt.Errorf("echo: feed %q listed twice", f.URL)
+		}
+		urls[f.URL] = true
+		for _, r := range f.Rules {
+			if _, err := r.Compile(); err != nil {
+				return err
+			}
+		}
+	}
+
+	c.rssMu.Lock()
+	c.feeds = slices.Clone(feeds)
+	maps.DeleteFunc(c.rssSeen, func(url string, _ map[string]bool) bool {
+		return !urls[url]
+	})
+	maps.DeleteFunc(c.rssPolled, func(url string, _ time.Time) bool {
+		return !urls[url]
+	})
+	c.rssMu.Unlock()
+
+	c.saveSession()
+	return nil
+}
+
+// feedSeen returns the GUIDs of the items downloaded from each feed.
+func (c *Client) feedSeen() map[string][]string {
+	c.rssMu.Lock()
+	defer c.rssMu.Unlock()
+
+	seen := make(map[string][]string, len(c.rssSeen))
+	for url, guids := range c.rssSeen {
+		seen[url] = slices.Sorted(maps.Keys(guids))
+	}
+	return seen
+}
+
+// runRSS polls the feeds that are due until the client closes.
+func (c *Client) runRSS() {
+	ticker := time.NewTicker(rssTick)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		for _, f := range c.dueFeeds() {
+			if c.closing.Load() {
+				return
+			}
+			c.pollFeed(f)
+		}
+	}
+}
+
+func (c *Client) dueFeeds() []Feed {
+	c.rssMu.Lock()
+	defer c.rssMu.Unlock()
+
+	var due []Feed
+	for _, f := range c.feeds {
+		interval := f.Interval
+		if interval <= 0 {
+			interval = c.cfg.RSSInterval
+		}
+		if interval <= 0 || time.Since(c.rssPolled[f.URL]) < interval {
+			continue
+		}
+		c.rssPolled[f.URL] = time.Now()
+		due = append(due, f)
+	}
+	return due
+}
+
+// pollFeed fetches the feed and adds the items its rules match. Only items
+// still in the feed are remembered, which keeps the record from growing
+// without bound.
+func (c *Client) pollFeed(f Feed) {
+	items, err := rss.Fetch(c.ctx, c.http, f.URL)
+	if err != nil {
+		slog.Warn(
+			"fetching feed failed",
+			slog.String("url", f.URL),
+			slog.String("error", err.Error()),
+		)
+		return
+	}
+
+	var matchers []*rss.Matcher
+	for _, r := range f.Rules {
+		if m, err := r.Compile(); err == nil {
+			matchers = append(matchers, m)
+		}
+	}
+
+	c.rssMu.Lock()
+	seen := c.rssSeen[f.URL]
+	c.rssMu.Unlock()
+
+	next := make(map[string]bool)
+	for _, item := range items {
+		if seen[item.GUID] {
+			next[item.GUID] = true
+			continue
+		}
+		i := slices.IndexFunc(matchers, func(m *rss.Matcher) bool {
+			return m.Match(item.Title)
+		})
+		if i < 0 {
+			continue
+		}
+
+		err := c.addFeedItem(item, matchers[i].Rule)
+		if err != nil && !errors.Is(err, ErrDuplicate) {
+			slog.Warn(
+				"adding feed item failed",
+				slog.String("feed", f.URL),
+				slog.String("title", item.Title),
+				slog.String("error", err.Error()),
+			)
+			continue
+		}
+		slog.Info(
+			"added feed item",
+			slog.String("feed", f.URL),
+			slog.String("title", item.Title),
+			slog.String("rule", matchers[i].Rule.Name),
+		)
+		next[item.GUID] = true
+	}
+
+	c.rssMu.Lock()
+	if slices.ContainsFunc(c.feeds, func(g Feed) bool {
+		return g.URL == f.URL
+	}) {
+		c.rssSeen[f.URL] = next
+	}
+	c.rssMu.Unlock()
+
+	if !maps.Equal(seen, next) {
+		c.saveSession()
+	}
+}
+
+// addFeedItem adds the torrent an item links to. Magnet links, and links
+// redirecting to them, are fetched in the background, like those from
+// watch folders.
+func (c *Client) addFeedItem(item rss.Item, rule RSSRule) error {
+	opts := AddOptions{Category: rule.Category}
+
+	link := item.Link
+	var data []byte
+	var err error
+	if !isMagnet(link) {
+		data, err = c.downloadTorrent(c.ctx, link)
+		var mr *magnetRedirect
+		if errors.As(err, &mr) {
+			link = mr.uri
+		}
+	}
+	if isMagnet(link) {
+		go func() {
+			_, err := c.AddMagnet(c.ctx, link, opts)
+			if err != nil && !errors.Is(err, ErrDuplicate) {
+				slog.Warn(
+					"adding magnet link from feed failed",
+					slog.String("title", item.Title),
+					slog.String("error", err.Error()),
+				)
+			}
+		}()
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	_, err = c.AddTorrent(data, opts)
+	return err
+}
@@ -0,0 +1,262 @@
+package echo
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// SavedSearch is a search run again on a schedule, whose new results are
+// notified of or added.
+type SavedSearch struct {
+	Name  string      `json:"name"`
+	Query SearchQuery `json:"query"`
+	// Interval is how often the search is run. Zero means
+	// Config.SavedSearchInterval.
+	Interval time.Duration `json:"interval"`
+	// AutoAdd adds new results, under Category, rather than only
+	// notifying of them.
+	AutoAdd  bool   `json:"autoAdd"`
+	Category string `json:"category,omitempty"`
+}
+
+const (
+	// savedSearchTick is how often saved searches are checked for being
+	// due.
+	savedSearchTick = time.Minute
+	// maxSearchSeen bounds the results remembered for a saved search;
+	// past it, only those of the latest run are kept.
+	maxSearchSeen = 1000
+)
+
+// SavedSearches returns the searches run on a schedule.
+func (c *Client) SavedSearches() []SavedSearch {
+	c.savedMu.Lock()
+	defer c.savedMu.Unlock()
+
+	return slices.Clone(c.saved)
+}
+
+// SetSavedSearches replaces the searches run on a schedule. The first run
+// of a new search, or one whose query changed, only notes the results
+// already out there; later runs report the ones that weren't.
+func (c *Client) SetSavedSearches(saved []SavedSearch) error {
+	queries := make(map[string]SearchQuery, len(saved))
+	for _, s := range saved {
+		if s.Name == "" {
+			return errors.New("echo: saved search has no name")
+		}
+		if s.Query.Text == "" {
+			return fmt.Errorf(
+				"echo: saved search %q has no query",
+				s.Name,
+			)
+		}
+		if _, ok := queries[s.Name]; ok {
+			return fmt.Errorf(
+				"echo: saved search %q listed twice",
+				s.Name,
+			)
+		}
+		queries[s.Name] = s.Query
+	}
+
+	c.savedMu.Lock()
+	for _, s := range c.saved {
+		q, ok := queries[s.Name]
+		if !ok || !sameQuery(q, s.Query) {
+			delete(c.savedSeen, s.Name)
+			delete(c.savedRunAt, s.Name)
+		}
+	}
+	c.saved = slices.Clone(saved)
+	c.savedMu.Unlock()
+
+	c.saveSession()
+	return nil
+}
+
+func sameQuery(a, b SearchQuery) bool {
+	return a.Text == b.Text &&
+		slices.Equal(a.Categories, b.Categories) &&
+		slices.Equal(a.Indexers, b.Indexers) &&
+		a.Filter == b.Filter
+}
+
+// searchSeen returns the results each saved search has turned up.
+func (c *Client) searchSeen() map[string][]string {
+	c.savedMu.Lock()
+	defer c.savedMu.Unlock()
+
+	seen := make(map[string][]string, len(c.savedSeen))
+	for name, keys := range c.savedSeen {
+		seen[name] = slices.Sorted(maps.Keys(keys))
+	}
+	return seen
+}
+
+// runSavedSearches runs the saved searches that are due until the client
+// closes.
+func (c *Client) runSavedSearches() {
+	ticker := time.NewTicker(savedSearchTick)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		for _, s := range c.dueSearches() {
+			if c.closing.Load() {
+				return
+			}
+			c.runSavedSearch(s)
+		}
+	}
+}
+
+func (c *Client) dueSearches() []SavedSearch {
+	c.savedMu.Lock()
+	defer c.savedMu.Unlock()
+
+	var due []SavedSearch
+	for _, s := range c.saved {
+		interval := s.Interval
+		if interval <= 0 {
+			interval = c.cfg.SavedSearchInterval
+		}
+		last := c.savedRunAt[s.Name]
+		if interval <= 0 || time.Since(last) < interval {
+			continue
+		}
+		c.savedRunAt[s.Name] = time.Now()
+		due = append(due, s)
+	}
+	return due
+}
+
+// runSavedSearch runs s, and notifies of and, if s says to, adds the
+// results it hadn't turned up before.
+func (c *Client) runSavedSearch(s SavedSearch) {
+	results, err := c.search(c.ctx, s.Query, false)
+	if err != nil {
+		slog.Warn(
+			"saved search failed",
+			slog.String("search", s.Name),
+			slog.String("error", err.Error()),
+		)
+		return
+	}
+
+	c.savedMu.Lock()
+	seen, ran := c.savedSeen[s.Name]
+	c.savedMu.Unlock()
+
+	next := maps.Clone(seen)
+	if next == nil {
+		next = make(map[string]bool)
+	}
+	var fresh []SearchResult
+	for _, r := range results {
+		key := resultKey(r)
+		if key == "" || next[key] {
+			continue
+		}
+		next[key] = true
+		if ran {
+			fresh = append(fresh, r)
+		}
+	}
+	if len(next) > maxSearchSeen {
+		clear(next)
+		for _, r := range results {
+			next[resultKey(r)] = true
+		}
+	}
+
+	c.savedMu.Lock()
+	kept := slices.ContainsFunc(c.saved, func(t SavedSearch) bool {
+		return t.Name == s.Name && sameQuery(t.Query, s.Query)
+	})
+	if kept {
+		c.savedSeen[s.Name] = next
+	}
+	c.savedMu.Unlock()
+
+	if !kept {
+		return
+	}
+	if !ran || !maps.Equal(seen, next) {
+		c.saveSession()
+	}
+	if len(fresh) == 0 {
+		return
+	}
+
+	slog.Info(
+		"saved search found new results",
+		slog.String("search", s.Name),
+		slog.Int("results", len(fresh)),
+	)
+	if s.AutoAdd {
+		for _, r := range fresh {
+			c.addSearchResult(s, r)
+		}
+	}
+	c.notify(searchNotification(s, fresh))
+}
+
+// resultKey tells a result apart from others across runs.
+func resultKey(r SearchResult) string {
+	switch {
+	case r.InfoHash != "":
+		return r.InfoHash
+	case r.Magnet != "":
+		return r.Magnet
+	}
+	return r.Link
+}
+
+// addSearchResult adds a saved search's result in the background, as
+// magnet links wait for their metadata.
+func (c *Client) addSearchResult(s SavedSearch, r SearchResult) {
+	req := AddRequest{Options: AddOptions{Category: s.Category}}
+	if r.Magnet != "" {
+		req.Magnet = r.Magnet
+	} else {
+		req.URL = r.Link
+	}
+	go func() {
+		_, err := c.addRequest(c.ctx, req)
+		if err != nil && !errors.Is(err, ErrDuplicate) {
+			slog.Warn(
+				"adding saved search result failed",
+				slog.String("search", s.Name),
+				slog.String("title", r.Title),
+				slog.String("error", err.Error()),
+			)
+		}
+	}()
+}
+
+func searchNotification(s SavedSearch, fresh []SearchResult) Notification {
+	n := Notification{
+		Kind:    NotifySearch,
+		Title:   "New results for " + s.Name,
+		Message: fresh[0].Title,
+		Time:    time.Now(),
+	}
+	if s.AutoAdd {
+		n.Title = "Added results for " + s.Name
+	}
+	if more := len(fresh) - 1; more > 0 {
+		n.Message += " and " + strconv.Itoa(more) + " more"
+	}
+	return n
+}
@@ -0,0 +1,318 @@
+package echo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/prxssh/echo/internal/search"
+)
+
+// IndexerKind says how an indexer is searched. Empty means
+// IndexerTorznab.
+type IndexerKind string
+
+const (
+	// IndexerTorznab is a Torznab or Newznab API, such as a Jackett
+	// indexer or a Prowlarr instance.
+	IndexerTorznab IndexerKind = "torznab"
+	// IndexerRSS is a torrent RSS or Atom feed, whose latest items are
+	// searched by title.
+	IndexerRSS IndexerKind = "rss"
+)
+
+// Indexer is a source searched for torrents.
+type Indexer struct {
+	Name string      `json:"name"`
+	Kind IndexerKind `json:"kind,omitempty"`
+	// URL is the API endpoint, the one taking t=search, or the feed.
+	URL string `json:"url"`
+	// APIKey is kept in Config.CredentialsPath rather than the session
+	// file when that is set.
+	APIKey string `json:"apiKey"`
+	// Disabled indexers are kept but not searched.
+	Disabled bool `json:"disabled,omitempty"`
+}
+
+// IndexerHealth is how an indexer's searches have gone this run.
+type IndexerHealth = search.Health
+
+// Indexers returns the indexers searched for torrents.
+func (c *Client) Indexers() []Indexer {
+	c.searchMu.Lock()
+	defer c.searchMu.Unlock()
+
+	return slices.Clone(c.indexers)
+}
+
+// SetIndexers replaces the indexers searched for torrents.
+func (c *Client) SetIndexers(indexers []Indexer) error {
+	names := make(map[string]bool, len(indexers))
+	for _, ix := range indexers {
+		if err := ix.validate(); err != nil {
+			return err
+		}
+		if names[ix.Name] {
+			return fmt.Errorf(
+				"echo: indexer %q listed twice",
+				ix.Name,
+			)
+		}
+		names[ix.Name] = true
+	}
+
+	c.searchMu.Lock()
+	for _, ix := range c.indexers {
+		if !names[ix.Name] {
+			c.monitor.Forget(ix.Name)
+		}
+	}
+	c.indexers = slices.Clone(indexers)
+	c.searchMu.Unlock()
+	c.throttle.Reset()
+
+	c.saveSession()
+	return nil
+}
+
+// SetIndexerEnabled turns searching the named indexer on or off.
+func (c *Client) SetIndexerEnabled(name string, enabled bool) error {
+	c.searchMu.Lock()
+	i := slices.IndexFunc(c.indexers, func(ix Indexer) bool {
+		return ix.Name == name
+	})
+	if i < 0 {
+		c.searchMu.Unlock()
+		return fmt.Errorf("echo: no indexer %q", name)
+	}
+	c.indexers[i].Disabled = !enabled
+	c.searchMu.Unlock()
+
+	c.saveSession()
+	return nil
+}
+
+// IndexerHealth returns how each indexer's searches have gone since the
+// client started: how many failed, how long they took and the last error.
+func (c *Client) IndexerHealth() []IndexerHealth {
+	indexers := c.Indexers()
+	out := make([]IndexerHealth, len(indexers))
+	for i, ix := range indexers {
+		out[i], _ = c.monitor.Health(ix.Name)
+	}
+	return out
+}
+
+// TestIndexer checks that ix, which needn't have been added yet, answers
+// and takes its API key, and returns how long it took to.
+func (c *Client) TestIndexer(
+	ctx context.Context,
+	ix Indexer,
+) (time.Duration, error) {
+	if err := ix.validate(); err != nil {
+		return 0, err
+	}
+	ctx, cancel := c.searchContext(ctx)
+	defer cancel()
+
+	start := time.Now()
+	if err := c.indexerProvider(ix).Test(ctx); err != nil {
+		return 0, err
+	}
+	return time.Since(start), nil
+}
+
+func (ix Indexer) validate() error {
+	if ix.Name == "" {
+		return errors.New("echo: indexer has no name")
+	}
+	if ix.Name == LocalIndexName {
+		return fmt.Errorf("echo: %q is the local index's name", ix.Name)
+	}
+	switch ix.Kind {
+	case "", IndexerTorznab, IndexerRSS:
+	default:
+		return fmt.Errorf("echo: unknown indexer kind %q", ix.Kind)
+	}
+	u, err := url.Parse(ix.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return fmt.Errorf("echo: indexer %q has no HTTP URL", ix.Name)
+	}
+	return nil
+}
+
+// indexerProvider is a search provider that can also be tested.
+type indexerProvider interface {
+	search.Provider
+	Test(ctx context.Context) error
+}
+
+func (c *Client) indexerProvider(ix Indexer) indexerProvider {
+	if ix.Kind == IndexerRSS {
+		return search.NewFeed(ix.Name, ix.URL, c.http)
+	}
+	return search.NewTorznab(ix.Name, ix.URL, ix.APIKey, c.http)
+}
+
+// SearchResult is a torrent found by searching the indexers.
+type SearchResult = search.Result
+
+// SearchFilter narrows search results down by seeders, size and age.
+type SearchFilter = search.Filter
+
+// SearchQuery is what Search looks for.
+type SearchQuery struct {
+	Text string `json:"text"`
+	// Categories are Newznab category IDs; none means every category.
+	Categories []int `json:"categories"`
+	// Indexers names the indexers to search; none means every one.
+	Indexers []string `json:"indexers"`
+	// Filter is passed on to the indexers as far as they take it, and
+	// applied to their results.
+	Filter SearchFilter `json:"filter"`
+}
+
+// SearchUpdate is the payload of "search:results", pushed as each indexer
+// answers a search.
+type SearchUpdate struct {
+	// Query is the text searched for, which tells one search's updates
+	// from another's.
+	Query   string `json:"query"`
+	Indexer string `json:"indexer"`
+	Error   string `json:"error,omitempty"`
+	// Results are every indexer's so far, merged, most seeders first.
+	Results []SearchResult `json:"results"`
+	// Pending counts the indexers yet to answer; zero once all have.
+	Pending int `json:"pending"`
+	// Checking is set in the update after the last answer if the
+	// results' swarms are being checked, and a final update with the
+	// results ranked by the check follows.
+	Checking bool `json:"checking"`
+}
+
+// Search asks the indexers side by side, giving each up to
+// Config.SearchTimeout, and returns their results merged, one per torrent
+// and most seeders first. A "search:results" event follows each indexer's
+// answer. It fails only if every indexer did. The results' swarms are then
+// checked with the trackers and the DHT, see checkSwarms, and the results
+// that don't match q's categories and filter dropped.
+func (c *Client) Search(
+	ctx context.Context,
+	q SearchQuery,
+) ([]SearchResult, error) {
+	return c.search(ctx, q, true)
+}
+
+// search is Search, sending "search:results" events only if publish is
+// set.
+func (c *Client) search(
+	ctx context.Context,
+	q SearchQuery,
+	publish bool,
+) ([]SearchResult, error) {
+	providers, err := c.providers(q.Indexers)
+	if err != nil {
+		return nil, err
+	}
+	query := search.Query{
+		Text:       q.Text,
+		Categories: q.Categories,
+		Filter:     q.Filter,
+	}
+
+	type answer struct {
+		provider string
+		results  []SearchResult
+		err      error
+	}
+	answers := make(chan answer)
+	for _, p := range providers {
+		go func() {
+			ctx, cancel := c.searchContext(ctx)
+			defer cancel()
+			results, err := p.Search(ctx, query)
+			answers <- answer{p.Name(), results, err}
+		}()
+	}
+
+	check := c.cfg.SwarmCheckTimeout > 0
+
+	var merged search.Merger
+	var errs []error
+	for pending := len(providers) - 1; pending >= 0; pending-- {
+		a := <-answers
+		update := SearchUpdate{
+			Query:   q.Text,
+			Indexer: a.provider,
+			Pending: pending,
+		}
+		if a.err != nil {
+			update.Error = a.err.Error()
+			err := fmt.Errorf("%s: %w", a.provider, a.err)
+			errs = append(errs, err)
+		}
+		merged.Add(a.results)
+		update.Results = query.Apply(merged.Results())
+		update.Checking = check && pending == 0 &&
+			len(errs) < len(providers)
+		if publish {
+			c.publish("search:results", update)
+		}
+	}
+
+	if len(errs) == len(providers) {
+		return nil, errors.Join(errs...)
+	}
+	// Swarms are checked before filtering, as results without an
+	// indexer's seeder count may turn out to have enough.
+	results := merged.Results()
+	if check {
+		c.checkSwarms(ctx, results)
+		if publish {
+			c.publish("search:results", SearchUpdate{
+				Query:   q.Text,
+				Results: query.Apply(results),
+			})
+		}
+	}
+	return query.Apply(results), nil
+}
+
+// providers returns a search provider for each enabled indexer, or only
+// the named ones if any are named, paced and cached by c.throttle and
+// watched by c.monitor. The
+// local index, once it holds anything, counts as an indexer named
+// LocalIndexName.
+func (c *Client) providers(names []string) ([]search.Provider, error) {
+	var providers []search.Provider
+	if c.index.Len() > 0 &&
+		(len(names) == 0 || slices.Contains(names, LocalIndexName)) {
+		providers = append(providers, c.index)
+	}
+	for _, ix := range c.Indexers() {
+		if ix.Disabled {
+			continue
+		}
+		if len(names) > 0 && !slices.Contains(names, ix.Name) {
+			continue
+		}
+		p := c.monitor.Wrap(c.indexerProvider(ix))
+		providers = append(providers, c.throttle.Wrap(p))
+	}
+	if len(providers) == 0 {
+		return nil, errors.New("echo: no indexers to search")
+	}
+	return providers, nil
+}
+
+func (c *Client) searchContext(
+	ctx context.Context,
+) (context.Context, context.CancelFunc) {
+	if c.cfg.SearchTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, c.cfg.SearchTimeout)
+}
@@ -0,0 +1,382 @@
+package echo
+
+import (
+	"crypto/sha1"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/prxssh/echo/internal/torrent"
+)
+
+// sessionFile is the on-disk list of the session's torrents, re-added by
+// Start so that restarting the client doesn't lose them.
+type sessionFile struct {
+	Torrents   []sessionEntry `json:"torrents"`
+	Categories []Category     `json:"categories,omitempty"`
+	// WatchFolders replace Config.WatchFolders when set.
+	WatchFolders []WatchFolder `json:"watchFolders,omitempty"`
+	Feeds        []Feed        `json:"feeds,omitempty"`
+	// FeedSeen lists, by feed URL, the GUIDs of the items already
+	// downloaded.
+	FeedSeen map[string][]string `json:"feedSeen,omitempty"`
+	Stats    *savedStats         `json:"stats,omitempty"`
+	// PauseWindows replace Config.PauseWindows when set.
+	PauseWindows []PauseWindow `json:"pauseWindows,omitempty"`
+	// Power replaces Config.Power when set.
+	Power *PowerPolicy `json:"power,omitempty"`
+	// Quotas replace Config.Quotas when set.
+	Quotas   *Quotas   `json:"quotas,omitempty"`
+	Indexers []Indexer `json:"indexers,omitempty"`
+	// SavedSearches are run again on a schedule; SearchSeen lists, by
+	// saved search, the results each has already turned up.
+	SavedSearches []SavedSearch       `json:"savedSearches,omitempty"`
+	SearchSeen    map[string][]string `json:"searchSeen,omitempty"`
+}
+
+// sessionEntry is a torrent, or a magnet link whose metadata was still
+// being fetched.
+type sessionEntry struct {
+	Metainfo    []byte        `json:"metainfo,omitempty"`
+	Magnet      string        `json:"magnet,omitempty"`
+	DownloadDir string        `json:"downloadDir"`
+	SelectOnly  []int         `json:"selectOnly,omitempty"`
+	State       torrent.State `json:"state,omitempty"`
+	Category    string        `json:"category,omitempty"`
+	Tags        []string      `json:"tags,omitempty"`
+
+	// FilePriorities, when set, take the place of SelectOnly.
+	FilePriorities []FilePriority `json:"filePriorities,omitempty"`
+
+	// Lifetime counters, from which share ratios are computed.
+	Uploaded   uint64        `json:"uploaded,omitempty"`
+	Downloaded uint64        `json:"downloaded,omitempty"`
+	SeedTime   time.Duration `json:"seedTime,omitempty"`
+	Overrides  *Overrides    `json:"overrides,omitempty"`
+	// Have is the bitfield of completed pieces, so restoring a torrent
+	// does not mean checking or downloading it again.
+	Have []byte `json:"have,omitempty"`
+	// Blocks are those the half-complete pieces have, so they are not
+	// downloaded again either.
+	Blocks []byte `json:"blocks,omitempty"`
+}
+
+func loadSession(path string) (*sessionFile, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var f sessionFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+// saveSession records a change to the session. Once Close has saved the
+// session for the last time, changes made while shutting down are dropped.
+func (c *Client) saveSession() {
+	if c.closing.Load() {
+		return
+	}
+	c.persistSession()
+}
+
+// persistSession writes every torrent and pending magnet link to the
+// session file. Failures are logged; the session carries on in memory.
+func (c *Client) persistSession() {
+	if c.cfg.SessionPath == "" {
+		return
+	}
+
+	c.sessionMu.Lock()
+	defer c.sessionMu.Unlock()
+
+	policy := c.PowerPolicy()
+	quotas := c.Quotas()
+	f := sessionFile{
+		Categories:    c.Categories(),
+		WatchFolders:  c.WatchFolders(),
+		Feeds:         c.Feeds(),
+		FeedSeen:      c.feedSeen(),
+		Stats:         c.savedStats(),
+		PauseWindows:  c.PauseWindows(),
+		Power:         &policy,
+		Quotas:        &quotas,
+		Indexers:      c.sessionIndexers(),
+		SavedSearches: c.SavedSearches(),
+		SearchSeen:    c.searchSeen(),
+	}
+	for _, t := range c.ordered() {
+		uploaded, downloaded, _ := t.Totals()
+		labels := c.labelsFor(t)
+		f.Torrents = append(f.Torrents, sessionEntry{
+			Metainfo:       t.MetainfoBytes(),
+			DownloadDir:    t.DownloadDir(),
+			SelectOnly:     t.SelectOnly(),
+			FilePriorities: ownPriorities(t),
+			State:          t.State(),
+			Category:       labels.Category,
+			Tags:           labels.Tags,
+			Uploaded:       uploaded,
+			Downloaded:     downloaded,
+			SeedTime:       t.SeedTime(),
+			Overrides:      c.ownOverrides(t),
+			Have:           t.Have().ToBytes(),
+			Blocks:         t.BlockState(),
+		})
+	}
+	for _, m := range c.magnets {
+		f.Torrents = append(f.Torrents, magnetEntry(m))
+	}
+
+	if err := writeSession(c.cfg.SessionPath, &f); err != nil {
+		slog.Warn(
+			"saving session failed",
+			slog.String("path", c.cfg.SessionPath),
+			slog.String("error", err.Error()),
+		)
+	}
+}
+
+// ownPriorities returns the torrent's file priorities, or nil if every
+// file is wanted at normal priority or skipped, which SelectOnly covers.
+func ownPriorities(t *Torrent) []FilePriority {
+	priorities := t.FilePriorities()
+	for _, p := range priorities {
+		if p != FileNormal && p != FileSkip {
+			return priorities
+		}
+	}
+	return nil
+}
+
+// ownOverrides returns the torrent's overrides, or nil if it has none.
+func (c *Client) ownOverrides(t *Torrent) *Overrides {
+	o := c.overridesFor(t)
+	if o == (Overrides{}) {
+		return nil
+	}
+	return &o
+}
+
+func writeSession(path string, f *sessionFile) error {
+	data, err := json.Marshal(f)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// restoreSession re-adds the torrents saved by the last run. Paused torrents
+// stay paused; magnet links go back to fetching metadata in the background.
+func (c *Client) restoreSession() {
+	f, err := loadSession(c.cfg.SessionPath)
+	if err != nil {
+		slog.Warn(
+			"loading session failed",
+			slog.String("path", c.cfg.SessionPath),
+			slog.String("error", err.Error()),
+		)
+		return
+	}
+	if f == nil {
+		return
+	}
+
+	c.restoreStats(f.Stats)
+	c.labelsMu.Lock()
+	for _, cat := range f.Categories {
+		c.categories[cat.Name] = cat
+	}
+	c.labelsMu.Unlock()
+	if len(f.WatchFolders) > 0 {
+		c.watchMu.Lock()
+		c.watch = f.WatchFolders
+		c.watchMu.Unlock()
+	}
+	c.searchMu.Lock()
+	c.indexers = f.Indexers
+	c.searchMu.Unlock()
+	c.restoreCredentials()
+	c.rssMu.Lock()
+	c.feeds = f.Feeds
+	for url, guids := range f.FeedSeen {
+		seen := make(map[string]bool, len(guids))
+		for _, g := range guids {
+			seen[g] = true
+		}
+		c.rssSeen[url] = seen
+	}
+	c.rssMu.Unlock()
+	c.savedMu.Lock()
+	c.saved = f.SavedSearches
+	for name, keys := range f.SearchSeen {
+		seen := make(map[string]bool, len(keys))
+		for _, k := range keys {
+			seen[k] = true
+		}
+		c.savedSeen[name] = seen
+	}
+	c.savedMu.Unlock()
+	if len(f.PauseWindows) > 0 {
+		c.timetableMu.Lock()
+		c.pauseWindows = f.PauseWindows
+		c.timetableMu.Unlock()
+	}
+	if f.Power != nil {
+		c.powerMu.Lock()
+		c.powerPolicy = *f.Power
+		c.powerMu.Unlock()
+	}
+	if f.Quotas != nil {
+		c.quotaMu.Lock()
+		c.quotas = *f.Quotas
+		c.quotaMu.Unlock()
+	}
+	// Suspend first if a window is in force or the monthly cap reached,
+	// so the torrents being re-added are not started only to be queued
+	// again.
+	c.applyTimetable(time.Now())
+	c.applyQuotas(time.Now())
+
+	// Pending magnet links are tracked up front so that saving the session
+	// while the torrents are re-added doesn't drop them.
+	var magnets []pendingMagnet
+	for _, e := range f.Torrents {
+		if e.Metainfo != nil || e.Magnet == "" {
+			continue
+		}
+		mag, err := torrent.ParseMagnet(e.Magnet)
+		if err != nil {
+			continue
+		}
+		m := pendingMagnet{uri: e.Magnet, opts: magnetOptions(e)}
+		c.sessionMu.Lock()
+		c.magnets[mag.InfoHash] = m
+		c.sessionMu.Unlock()
+		magnets = append(magnets, m)
+	}
+
+	for _, e := range f.Torrents {
+		if e.Metainfo == nil {
+			continue
+		}
+
+		_, err := c.add(e.Metainfo, addOpts{
+			downloadDir: e.DownloadDir,
+			selectOnly:  e.SelectOnly,
+			priorities:  e.FilePriorities,
+			labels:      Labels{Category: e.Category, Tags: e.Tags},
+			paused:      e.State == torrent.StatePaused,
+			restored:    &e,
+		})
+		if err != nil {
+			slog.Warn(
+				"restoring torrent failed",
+				slog.String("error", err.Error()),
+			)
+		}
+	}
+	for _, m := range magnets {
+		go c.restoreMagnet(m)
+	}
+}
+
+func (c *Client) restoreMagnet(m pendingMagnet) {
+	if _, err := c.AddMagnet(c.ctx, m.uri, m.opts); err != nil {
+		slog.Warn(
+			"restoring magnet link failed",
+			slog.String("magnet", m.uri),
+			slog.String("error", err.Error()),
+		)
+	}
+}
+
+// pendingMagnet is a magnet link whose metadata is being fetched, with the
+// options it was added with and how many of the info dict's pieces have
+// arrived. Links restored with the session are pending before their fetch
+// starts.
+type pendingMagnet struct {
+	uri      string
+	opts     AddOptions
+	name     string
+	fetching bool
+	received int
+	total    int
+}
+
+// magnetEntry saves a pending magnet link with the options it was added
+// with, in the fields a torrent would keep them in.
+func magnetEntry(m pendingMagnet) sessionEntry {
+	e := sessionEntry{
+		Magnet:      m.uri,
+		DownloadDir: m.opts.SavePath,
+		SelectOnly:  m.opts.Files,
+		Category:    m.opts.Category,
+		Tags:        m.opts.Tags,
+	}
+	if m.opts.Paused {
+		e.State = torrent.StatePaused
+	}
+	if m.opts.Sequential {
+		sequential := true
+		e.Overrides = &Overrides{Sequential: &sequential}
+	}
+	return e
+}
+
+func magnetOptions(e sessionEntry) AddOptions {
+	o := e.Overrides
+	return AddOptions{
+		Category:   e.Category,
+		Tags:       e.Tags,
+		SavePath:   e.DownloadDir,
+		Paused:     e.State == torrent.StatePaused,
+		Sequential: o != nil && o.Sequential != nil && *o.Sequential,
+		Files:      e.SelectOnly,
+	}
+}
+
+// trackMagnet records a magnet link whose metadata is being fetched, so a
+// restart picks the fetch up again. The returned function forgets it. A
+// link already being fetched is a duplicate.
+func (c *Client) trackMagnet(
+	hash [sha1.Size]byte,
+	m pendingMagnet,
+) (func(), error) {
+	c.sessionMu.Lock()
+	if c.magnets[hash].fetching {
+		c.sessionMu.Unlock()
+		return nil, ErrDuplicate
+	}
+	c.magnets[hash] = m
+	c.sessionMu.Unlock()
+	c.saveSession()
+
+	return func() {
+		c.sessionMu.Lock()
+		delete(c.magnets, hash)
+		c.sessionMu.Unlock()
+	}, nil
+}
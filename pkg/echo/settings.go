@@ -0,0 +1,371 @@
+package echo
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"maps"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/prxssh/echo/internal/torrent"
+	"github.com/prxssh/echo/pkg/logging"
+)
+
+// Encryption says whether connections to peers are encrypted (MSE). Empty
+// means EncryptionPrefer.
+type Encryption string
+
+const (
+	EncryptionPrefer  Encryption = "prefer"
+	EncryptionRequire Encryption = "require"
+	EncryptionDisable Encryption = "disable"
+)
+
+func (e Encryption) valid() bool {
+	switch e {
+	case "", EncryptionPrefer, EncryptionRequire, EncryptionDisable:
+		return true
+	}
+	return false
+}
+
+// GlobalSettings are the session-wide options the user changes while the
+// client runs. They are saved to Config.SettingsPath, and once saved take
+// the place of the matching Config fields.
+type GlobalSettings struct {
+	DownloadDir string `json:"downloadDir"`
+	TorrentsDir string `json:"torrentsDir"`
+	// DHTPort is the UDP port the DHT listens on, which UDP trackers
+	// share. A change takes effect the next time the client starts.
+	DHTPort uint16 `json:"dhtPort"`
+	// ListenPort is the TCP port peers connect to us on. Like DHTPort, a
+	// change takes effect the next time the client starts.
+	ListenPort         uint16 `json:"listenPort"`
+	DownloadLimit      int64  `json:"downloadLimit"`
+	UploadLimit        int64  `json:"uploadLimit"`
+	MaxConnections     int    `json:"maxConnections"`
+	MaxActiveDownloads int    `json:"maxActiveDownloads"`
+	MaxActiveSeeds     int    `json:"maxActiveSeeds"`
+	// Proxy is an http, https or socks5 URL that HTTP tracker announces,
+	// web seeds and .torrent and RSS downloads go through. Peers are
+	// connected to directly. Empty uses no proxy.
+	Proxy string `json:"proxy"`
+	// Encryption is kept for when the peer wire protocol supports MSE;
+	// connections are not encrypted yet.
+	Encryption    Encryption     `json:"encryption"`
+	Notifications NotifySettings `json:"notifications"`
+	// CrawlDHT samples the DHT for torrents and fetches their metadata,
+	// building a local index that Search uses alongside the indexers.
+	CrawlDHT bool `json:"crawlDHT"`
+	// LogLevels quiets the records of modules, such as "tracker" or
+	// "peer", below their level; see logging.SetLevel.
+	LogLevels map[string]slog.Level `json:"logLevels"`
+	// DebugAddr is the localhost address serving pprof, goroutine dumps
+	// and the engine's internals; see DebugInfo. Empty serves none.
+	DebugAddr string `json:"debugAddr"`
+	// Fingerprint is the peer ID prefix, extension handshake version and
+	// tracker user agent of the torrents added from now on. Its empty
+	// fields are Echo's; see FingerprintPresets for other clients'.
+	Fingerprint Fingerprint `json:"fingerprint"`
+	// ReconnectOnIPChange drops every peer when the machine's address
+	// changes, rather than waiting for connections left on the old one
+	// to time out. Trackers are announced to either way.
+	ReconnectOnIPChange bool `json:"reconnectOnIpChange"`
+	// DHTReadOnly makes the DHT node query-only (BEP 43), for machines
+	// behind NATs that drop the queries other nodes would send it. Like
+	// DHTPort, a change takes effect the next time the client starts.
+	DHTReadOnly bool `json:"dhtReadOnly"`
+}
+
+// SettingsPatch lists changes to the global settings. Nil fields are left as they
+// are.
+type SettingsPatch struct {
+	DownloadDir        *string         `json:"downloadDir,omitempty"`
+	TorrentsDir        *string         `json:"torrentsDir,omitempty"`
+	DHTPort            *uint16         `json:"dhtPort,omitempty"`
+	ListenPort         *uint16         `json:"listenPort,omitempty"`
+	DownloadLimit      *int64          `json:"downloadLimit,omitempty"`
+	UploadLimit        *int64          `json:"uploadLimit,omitempty"`
+	MaxConnections     *int            `json:"maxConnections,omitempty"`
+	MaxActiveDownloads *int            `json:"maxActiveDownloads,omitempty"`
+	MaxActiveSeeds     *int            `json:"maxActiveSeeds,omitempty"`
+	Proxy              *string         `json:"proxy,omitempty"`
+	Encryption         *Encryption     `json:"encryption,omitempty"`
+	Notifications      *NotifySettings `json:"notifications,omitempty"`
+	CrawlDHT           *bool           `json:"crawlDHT,omitempty"`
+	// LogLevels, when set, replaces every module's level.
+	LogLevels           map[string]slog.Level `json:"logLevels,omitempty"`
+	DebugAddr           *string               `json:"debugAddr,omitempty"`
+	Fingerprint         *Fingerprint          `json:"fingerprint,omitempty"`
+	ReconnectOnIPChange *bool                 `json:"reconnectOnIpChange,omitempty"`
+	DHTReadOnly         *bool                 `json:"dhtReadOnly,omitempty"`
+}
+
+// apply layers the patch on top of s.
+func (p SettingsPatch) apply(s GlobalSettings) GlobalSettings {
+	if p.DownloadDir != nil {
+		s.DownloadDir = *p.DownloadDir
+	}
+	if p.TorrentsDir != nil {
+		s.TorrentsDir = *p.TorrentsDir
+	}
+	if p.DHTPort != nil {
+		s.DHTPort = *p.DHTPort
+	}
+	if p.ListenPort != nil {
+		s.ListenPort = *p.ListenPort
+	}
+	if p.DownloadLimit != nil {
+		s.DownloadLimit = *p.DownloadLimit
+	}
+	if p.UploadLimit != nil {
+		s.UploadLimit = *p.UploadLimit
+	}
+	if p.MaxConnections != nil {
+		s.MaxConnections = *p.MaxConnections
+	}
+	if p.MaxActiveDownloads != nil {
+		s.MaxActiveDownloads = *p.MaxActiveDownloads
+	}
+	if p.MaxActiveSeeds != nil {
+		s.MaxActiveSeeds = *p.MaxActiveSeeds
+	}
+	if p.Proxy != nil {
+		s.Proxy = *p.Proxy
+	}
+	if p.Encryption != nil {
+		s.Encryption = *p.Encryption
+	}
+	if p.Notifications != nil {
+		s.Notifications = *p.Notifications
+	}
+	if p.CrawlDHT != nil {
+		s.CrawlDHT = *p.CrawlDHT
+	}
+	if p.LogLevels != nil {
+		s.LogLevels = p.LogLevels
+	}
+	if p.DebugAddr != nil {
+		s.DebugAddr = *p.DebugAddr
+	}
+	if p.Fingerprint != nil {
+		s.Fingerprint = *p.Fingerprint
+	}
+	if p.ReconnectOnIPChange != nil {
+		s.ReconnectOnIPChange = *p.ReconnectOnIPChange
+	}
+	if p.DHTReadOnly != nil {
+		s.DHTReadOnly = *p.DHTReadOnly
+	}
+	return s
+}
+
+func (s GlobalSettings) validate() error {
+	if s.DownloadDir == "" {
+		return errors.New("echo: download directory is required")
+	}
+	if s.DownloadLimit < 0 || s.UploadLimit < 0 {
+		return errors.New("echo: rate limits cannot be negative")
+	}
+	if s.MaxConnections < 0 || s.MaxActiveDownloads < 0 ||
+		s.MaxActiveSeeds < 0 {
+		return errors.New("echo: limits cannot be negative")
+	}
+	if !s.Encryption.valid() {
+		return fmt.Errorf("echo: unknown encryption %q", s.Encryption)
+	}
+	if s.Proxy != "" {
+		if _, err := parseProxy(s.Proxy); err != nil {
+			return err
+		}
+	}
+	if _, ok := s.LogLevels[""]; ok {
+		return errors.New("echo: log level for no module")
+	}
+	if s.DebugAddr != "" && !loopback(s.DebugAddr) {
+		return errors.New("echo: debug address must be on localhost")
+	}
+	if err := s.Fingerprint.Validate(); err != nil {
+		return fmt.Errorf("echo: %w", err)
+	}
+	return nil
+}
+
+func parseProxy(raw string) (*url.URL, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("echo: proxy: %w", err)
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5":
+	default:
+		err := fmt.Errorf("echo: proxy: unknown scheme %q", u.Scheme)
+		return nil, err
+	}
+	if u.Host == "" {
+		return nil, errors.New("echo: proxy: missing host")
+	}
+	return u, nil
+}
+
+// GlobalSettings returns the global settings in effect.
+func (c *Client) GlobalSettings() GlobalSettings {
+	c.cfgMu.RLock()
+	defer c.cfgMu.RUnlock()
+
+	return GlobalSettings{
+		DownloadDir:         c.cfg.DownloadDir,
+		TorrentsDir:         c.cfg.TorrentsDir,
+		DHTPort:             c.cfg.DHT.Port,
+		ListenPort:          c.cfg.ListenPort,
+		DownloadLimit:       c.cfg.DownloadLimit,
+		UploadLimit:         c.cfg.UploadLimit,
+		MaxConnections:      c.cfg.MaxConnections,
+		MaxActiveDownloads:  c.cfg.MaxActiveDownloads,
+		MaxActiveSeeds:      c.cfg.MaxActiveSeeds,
+		Proxy:               c.cfg.Proxy,
+		Encryption:          c.cfg.Encryption,
+		Notifications:       c.cfg.Notifications,
+		CrawlDHT:            c.cfg.CrawlDHT,
+		LogLevels:           maps.Clone(c.cfg.LogLevels),
+		DebugAddr:           c.cfg.DebugAddr,
+		Fingerprint:         c.cfg.Fingerprint,
+		ReconnectOnIPChange: c.cfg.ReconnectOnIPChange,
+		DHTReadOnly:         c.cfg.DHT.ReadOnly,
+	}
+}
+
+// UpdateSettings applies the patch, saves the result and puts it into
+// effect: new limits and proxy straight away, new directories for the
+// torrents added from now on.
+func (c *Client) UpdateSettings(
+	patch SettingsPatch,
+) (GlobalSettings, error) {
+	s := patch.apply(c.GlobalSettings())
+	if err := s.validate(); err != nil {
+		return GlobalSettings{}, err
+	}
+
+	c.setSettings(s)
+	c.saveSettings()
+	c.reschedule()
+	c.shareBandwidth()
+	c.serveDebug()
+	return s, nil
+}
+
+func (c *Client) setSettings(s GlobalSettings) {
+	c.cfgMu.Lock()
+	defer c.cfgMu.Unlock()
+
+	c.cfg.DownloadDir = s.DownloadDir
+	c.cfg.TorrentsDir = s.TorrentsDir
+	c.cfg.DHT.Port = s.DHTPort
+	c.cfg.ListenPort = s.ListenPort
+	c.cfg.DownloadLimit = s.DownloadLimit
+	c.cfg.UploadLimit = s.UploadLimit
+	c.cfg.MaxConnections = s.MaxConnections
+	c.cfg.MaxActiveDownloads = s.MaxActiveDownloads
+	c.cfg.MaxActiveSeeds = s.MaxActiveSeeds
+	c.cfg.Proxy = s.Proxy
+	c.cfg.Encryption = s.Encryption
+	c.cfg.Notifications = s.Notifications
+	c.cfg.CrawlDHT = s.CrawlDHT
+	c.cfg.LogLevels = maps.Clone(s.LogLevels)
+	c.cfg.DebugAddr = s.DebugAddr
+	c.cfg.Fingerprint = s.Fingerprint
+	c.cfg.ReconnectOnIPChange = s.ReconnectOnIPChange
+	c.cfg.DHT.ReadOnly = s.DHTReadOnly
+	logging.SetLevels(s.LogLevels)
+}
+
+// proxy picks the proxy for the client's HTTP requests, for use as an
+// http.Transport's Proxy.
+func (c *Client) proxy(*http.Request) (*url.URL, error) {
+	raw := c.GlobalSettings().Proxy
+	if raw == "" {
+		return nil, nil
+	}
+	return parseProxy(raw)
+}
+
+// loadSettings puts the settings saved by an earlier run in place of the
+// Config's.
+func (c *Client) loadSettings() {
+	logging.SetLevels(c.cfg.LogLevels)
+	if c.cfg.SettingsPath == "" {
+		return
+	}
+
+	data, err := os.ReadFile(c.cfg.SettingsPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return
+	}
+	// Fields missing from the file keep the Config's values.
+	s := c.GlobalSettings()
+	if err == nil {
+		err = json.Unmarshal(data, &s)
+	}
+	if err == nil {
+		err = s.validate()
+	}
+	if err != nil {
+		slog.Warn(
+			"loading settings failed",
+			slog.String("path", c.cfg.SettingsPath),
+			slog.String("error", err.Error()),
+		)
+		return
+	}
+	c.setSettings(s)
+}
+
+func (c *Client) saveSettings() {
+	path := c.cfg.SettingsPath
+	if path == "" {
+		return
+	}
+
+	err := writeSettings(path, c.GlobalSettings())
+	if err != nil {
+		slog.Warn(
+			"saving settings failed",
+			slog.String("path", path),
+			slog.String("error", err.Error()),
+		)
+	}
+}
+
+func writeSettings(path string, s GlobalSettings) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// LookupFingerprint returns the fingerprint of the client named, as
+// FingerprintPresets lists it or by its name alone, ignoring case.
+func LookupFingerprint(name string) (Fingerprint, bool) {
+	return torrent.LookupFingerprint(name)
+}
+
+// FingerprintPresets lists the clients whose fingerprints can be taken on,
+// for trackers that only let whitelisted clients in.
+func FingerprintPresets() []FingerprintPreset {
+	return slices.Clone(torrent.FingerprintPresets)
+}
@@ -0,0 +1,211 @@
+package echo
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"slices"
+	"time"
+)
+
+const (
+	// speedInterval is how often transfer rates are recorded.
+	speedInterval = time.Second
+	// recentSpeeds keeps ten minutes of samples at speedInterval, and
+	// daySpeeds a day of one-minute averages.
+	recentSpeeds = 600
+	daySpeeds    = 24 * 60
+)
+
+// SpeedRange picks which of the kept speed histories to read.
+type SpeedRange string
+
+const (
+	// SpeedRecent is the last ten minutes, a sample a second.
+	SpeedRecent SpeedRange = "recent"
+	// SpeedDay is the last 24 hours, a sample a minute averaging it.
+	SpeedDay SpeedRange = "day"
+)
+
+// SpeedSample is the download and upload rate, in bytes per second, at a
+// point in time.
+type SpeedSample struct {
+	At       time.Time `json:"at"`
+	Download float64   `json:"download"`
+	Upload   float64   `json:"upload"`
+}
+
+// speedPoint is a SpeedSample kept small, since a history is kept for
+// every torrent that has transferred anything.
+type speedPoint struct {
+	at       int64
+	download float32
+	upload   float32
+}
+
+// speedRing holds the last len(points) points, overwriting the oldest.
+type speedRing struct {
+	points []speedPoint
+	next   int
+	full   bool
+}
+
+func newSpeedRing(n int) speedRing {
+	return speedRing{points: make([]speedPoint, n)}
+}
+
+func (r *speedRing) add(p speedPoint) {
+	r.points[r.next] = p
+	r.next = (r.next + 1) % len(r.points)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// samples returns the points, oldest first.
+func (r *speedRing) samples() []SpeedSample {
+	points := r.points[:r.next]
+	if r.full {
+		points = slices.Concat(r.points[r.next:], points)
+	}
+
+	out := make([]SpeedSample, len(points))
+	for i, p := range points {
+		out[i] = SpeedSample{
+			At:       time.Unix(p.at, 0),
+			Download: float64(p.download),
+			Upload:   float64(p.upload),
+		}
+	}
+	return out
+}
+
+// speedHistory keeps the recent samples as they are and folds each
+// minute's into its average for the day ring.
+type speedHistory struct {
+	recent speedRing
+	day    speedRing
+
+	minute  int64
+	sumDown float64
+	sumUp   float64
+	count   int
+}
+
+func newSpeedHistory() *speedHistory {
+	return &speedHistory{
+		recent: newSpeedRing(recentSpeeds),
+		day:    newSpeedRing(daySpeeds),
+	}
+}
+
+func (h *speedHistory) add(at time.Time, down, up float64) {
+	h.recent.add(speedPoint{
+		at:       at.Unix(),
+		download: float32(down),
+		upload:   float32(up),
+	})
+
+	minute := at.Unix() / 60
+	if h.count > 0 && minute != h.minute {
+		n := float64(h.count)
+		h.day.add(speedPoint{
+			at:       h.minute * 60,
+			download: float32(h.sumDown / n),
+			upload:   float32(h.sumUp / n),
+		})
+		h.sumDown, h.sumUp, h.count = 0, 0, 0
+	}
+	h.minute = minute
+	h.sumDown += down
+	h.sumUp += up
+	h.count++
+}
+
+func (h *speedHistory) samples(r SpeedRange) ([]SpeedSample, error) {
+	switch r {
+	case SpeedRecent, "":
+		return h.recent.samples(), nil
+	case SpeedDay:
+		return h.day.samples(), nil
+	}
+	return nil, fmt.Errorf("echo: unknown speed range %q", r)
+}
+
+// SessionSpeeds returns the session's transfer rates over the range,
+// oldest first.
+func (c *Client) SessionSpeeds(r SpeedRange) ([]SpeedSample, error) {
+	c.speedMu.Lock()
+	defer c.speedMu.Unlock()
+
+	return c.sessionSpeeds.samples(r)
+}
+
+// TorrentSpeeds returns the torrent's transfer rates over the range,
+// oldest first. A torrent that has not transferred anything this session
+// has none.
+func (c *Client) TorrentSpeeds(
+	infoHash [sha1.Size]byte,
+	r SpeedRange,
+) ([]SpeedSample, error) {
+	t, ok := c.torrents.Get(infoHash)
+	if !ok {
+		return nil, fmt.Errorf("echo: unknown torrent %x", infoHash)
+	}
+
+	c.speedMu.Lock()
+	defer c.speedMu.Unlock()
+
+	h, ok := c.torrentSpeeds[t]
+	if !ok {
+		h = newSpeedHistory()
+	}
+	return h.samples(r)
+}
+
+// runSpeeds records the session's and each torrent's rates every
+// speedInterval until the client closes, so graphs can be drawn from
+// history rather than only from the moment they are opened.
+func (c *Client) runSpeeds() {
+	ticker := time.NewTicker(speedInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.ctx.Done():
+			return
+		case now := <-ticker.C:
+			c.sampleSpeeds(now)
+		}
+	}
+}
+
+func (c *Client) sampleSpeeds(now time.Time) {
+	c.speedMu.Lock()
+	defer c.speedMu.Unlock()
+
+	seen := make(map[*Torrent]bool)
+	var totalDown, totalUp float64
+	for _, t := range c.torrents.List() {
+		down, up := t.Rates()
+		totalDown += down
+		totalUp += up
+		seen[t] = true
+
+		// Torrents that never transfer get no history.
+		h, ok := c.torrentSpeeds[t]
+		if !ok && down == 0 && up == 0 {
+			continue
+		}
+		if !ok {
+			h = newSpeedHistory()
+			c.torrentSpeeds[t] = h
+		}
+		h.add(now, down, up)
+	}
+	for t := range c.torrentSpeeds {
+		if !seen[t] {
+			delete(c.torrentSpeeds, t)
+		}
+	}
+	c.sessionSpeeds.add(now, totalDown, totalUp)
+}
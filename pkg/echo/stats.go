@@ -0,0 +1,181 @@
+package echo
+
+import (
+	"log/slog"
+	"time"
+)
+
+// statsSaveInterval is how often the lifetime totals are written out
+// between other saves of the session, bounding what a crash loses.
+const statsSaveInterval = 5 * time.Minute
+
+// Transfer is a pair of byte counts.
+type Transfer struct {
+	Uploaded   uint64 `json:"uploaded"`
+	Downloaded uint64 `json:"downloaded"`
+}
+
+// SessionStats are the transfer totals since Start and over every session
+// before it. They are pushed periodically as the "session:stats" event.
+type SessionStats struct {
+	Session Transfer `json:"session"`
+	AllTime Transfer `json:"allTime"`
+	// Ratio is the all-time upload to download ratio, zero until
+	// something has been downloaded.
+	Ratio  float64       `json:"ratio"`
+	Uptime time.Duration `json:"uptime"`
+	// TotalUptime adds up the uptime of every session.
+	TotalUptime time.Duration `json:"totalUptime"`
+}
+
+// savedStats carries the lifetime totals across restarts.
+type savedStats struct {
+	Uploaded   uint64        `json:"uploaded"`
+	Downloaded uint64        `json:"downloaded"`
+	Uptime     time.Duration `json:"uptime"`
+	// Month and MonthStart carry the monthly cap's count over.
+	Month      Transfer  `json:"month"`
+	MonthStart time.Time `json:"monthStart"`
+}
+
+// SessionStats returns the session and lifetime transfer totals.
+func (c *Client) SessionStats() SessionStats {
+	c.statsMu.Lock()
+	defer c.statsMu.Unlock()
+
+	c.sampleStats()
+	s := SessionStats{
+		Session:     c.session,
+		AllTime:     c.allTime,
+		Uptime:      time.Since(c.started),
+		TotalUptime: c.pastUptime + time.Since(c.started),
+	}
+	if s.AllTime.Downloaded > 0 {
+		s.Ratio = float64(s.AllTime.Uploaded) /
+			float64(s.AllTime.Downloaded)
+	}
+	return s
+}
+
+// sampleStats adds what each torrent transferred since the last sample to
+// the totals. A torrent's first sample only sets its baseline, so counters
+// carried over from a previous session or another client are not counted
+// again. Callers hold statsMu.
+func (c *Client) sampleStats() {
+	seen := make(map[*Torrent]bool)
+	for _, t := range c.torrents.List() {
+		up, down, _ := t.Totals()
+		cur := Transfer{Uploaded: up, Downloaded: down}
+		seen[t] = true
+
+		last, ok := c.samples[t]
+		c.samples[t] = cur
+		if !ok {
+			continue
+		}
+		// Counters only grow; the min keeps a reset from wrapping.
+		up -= min(last.Uploaded, up)
+		down -= min(last.Downloaded, down)
+		c.session.Uploaded += up
+		c.session.Downloaded += down
+		c.allTime.Uploaded += up
+		c.allTime.Downloaded += down
+		c.month.Uploaded += up
+		c.month.Downloaded += down
+	}
+	for t := range c.samples {
+		if !seen[t] {
+			delete(c.samples, t)
+		}
+	}
+}
+
+// Statuses returns a snapshot of every torrent, in queue order. The same
+// snapshot is pushed periodically as the "torrent:status" event.
+func (c *Client) Statuses() []TorrentStatus {
+	list := c.ordered()
+	statuses := make([]TorrentStatus, 0, len(list))
+	for i, t := range list {
+		s := t.Status()
+		s.QueuePosition = i
+		s.RetryIn = c.retryIn(t)
+		statuses = append(statuses, s)
+	}
+	return statuses
+}
+
+// savedStats returns the lifetime totals to write to the session file.
+func (c *Client) savedStats() *savedStats {
+	s := c.SessionStats()
+
+	c.statsMu.Lock()
+	defer c.statsMu.Unlock()
+
+	return &savedStats{
+		Uploaded:   s.AllTime.Uploaded,
+		Downloaded: s.AllTime.Downloaded,
+		Uptime:     s.TotalUptime,
+		Month:      c.month,
+		MonthStart: c.monthStart,
+	}
+}
+
+// restoreStats picks the lifetime totals up from the last session.
+func (c *Client) restoreStats(s *savedStats) {
+	if s == nil {
+		return
+	}
+
+	c.statsMu.Lock()
+	defer c.statsMu.Unlock()
+
+	c.allTime.Uploaded += s.Uploaded
+	c.allTime.Downloaded += s.Downloaded
+	c.pastUptime = s.Uptime
+	c.month.Uploaded += s.Month.Uploaded
+	c.month.Downloaded += s.Month.Downloaded
+	c.monthStart = s.MonthStart
+}
+
+// runStats pushes "session:stats", "torrent:status" and "torrent:countries"
+// every StatsInterval until the client closes, saving the session now and
+// then so the totals survive a crash.
+func (c *Client) runStats() {
+	if c.cfg.StatsInterval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(c.cfg.StatsInterval)
+	defer ticker.Stop()
+
+	saved := time.Now()
+	for {
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		c.publish("session:stats", c.SessionStats())
+		c.publish("torrent:status", c.Statuses())
+		c.publish("torrent:countries", c.countryBreakdown())
+		if time.Since(saved) >= statsSaveInterval {
+			c.checkpoint()
+			c.saveSession()
+			saved = time.Now()
+		}
+	}
+}
+
+// checkpoint flushes every torrent's files so their journals can be
+// emptied.
+func (c *Client) checkpoint() {
+	for _, t := range c.torrents.List() {
+		if err := t.Checkpoint(); err != nil {
+			slog.Warn(
+				"flushing torrent files failed",
+				slog.String("name", t.Metainfo.Info.Name),
+				slog.String("error", err.Error()),
+			)
+		}
+	}
+}
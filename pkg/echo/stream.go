@@ -0,0 +1,98 @@
+package echo
+
+import "context"
+
+// streamBacklog is how many of the latest events are kept for streams
+// picking up where they left off.
+const streamBacklog = 1024
+
+// streamQueue is how many events a stream holds unread before it is cut
+// off.
+const streamQueue = 256
+
+// StreamReset is the event a Stream starts with when events were missed
+// that the backlog no longer has, so the consumer reads the state afresh
+// rather than patch it from what follows.
+const StreamReset = "stream:reset"
+
+// stream is a consumer's channel of events; done is closed with it.
+type stream struct {
+	ch   chan Event
+	done chan struct{}
+}
+
+// Stream sends every event published after the one numbered since down the
+// returned channel, those still in the backlog first, until ctx is done.
+// A since of zero starts with the next event. If events after since are no
+// longer kept, or since is ahead of the session, as for a consumer that
+// outlived a previous engine, the stream starts with a StreamReset event.
+//
+// The channel is closed once ctx is done, or as soon as the consumer falls
+// too far behind; it then calls Stream again with the Seq of the last event
+// it read. This lets frontends, in the process or across the network, come
+// and go without the engine waiting on them.
+func (c *Client) Stream(ctx context.Context, since uint64) <-chan Event {
+	c.streamMu.Lock()
+	defer c.streamMu.Unlock()
+
+	var replay []Event
+	switch {
+	case since == 0 || since == c.seq:
+	case since > c.seq || c.seq-since > streamBacklog:
+		replay = append(replay, Event{Seq: c.seq, Name: StreamReset})
+	default:
+		for seq := since + 1; seq <= c.seq; seq++ {
+			replay = append(replay, c.backlog[seq%streamBacklog])
+		}
+	}
+
+	s := &stream{
+		ch:   make(chan Event, len(replay)+streamQueue),
+		done: make(chan struct{}),
+	}
+	for _, e := range replay {
+		s.ch <- e
+	}
+	c.streams[s] = struct{}{}
+
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-s.done:
+			return
+		}
+		c.streamMu.Lock()
+		defer c.streamMu.Unlock()
+		c.dropStream(s)
+	}()
+	return s.ch
+}
+
+// record numbers an event, keeps it in the backlog and sends it down every
+// stream, cutting off those whose consumer has fallen behind.
+func (c *Client) record(name string, data any) Event {
+	c.streamMu.Lock()
+	defer c.streamMu.Unlock()
+
+	c.seq++
+	e := Event{Seq: c.seq, Name: name, Data: data}
+	c.backlog[e.Seq%streamBacklog] = e
+	for s := range c.streams {
+		select {
+		case s.ch <- e:
+		default:
+			c.dropStream(s)
+		}
+	}
+	return e
+}
+
+// dropStream closes s unless it was already; streamMu must be held.
+func (c *Client) dropStream(s *stream) {
+	if _, ok := c.streams[s]; !ok {
+		return
+	}
+	delete(c.streams, s)
+	close(s.ch)
+	close(s.done)
+}
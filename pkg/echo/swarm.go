@@ -0,0 +1,114 @@
+package echo
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"io"
+	"log/slog"
+	"sync"
+
+	"github.com/prxssh/echo/internal/search"
+	"github.com/prxssh/echo/internal/tracker"
+)
+
+// maxDHTSwarmChecks caps how many of the best-ranked results are also
+// looked up in the DHT, which is far slower than a scrape.
+const maxDHTSwarmChecks = 20
+
+// DefaultScrapeTrackers are open trackers that answer scrapes for any
+// torrent.
+var DefaultScrapeTrackers = []string{
+	"udp://tracker.opentrackr.org:1337/announce",
+	"udp://open.demonii.com:1337/announce",
+	"udp://open.stealth.si:80/announce",
+	"udp://exodus.desync.com:6969/announce",
+}
+
+// checkSwarms scrapes the results' info-hashes from Config.ScrapeTrackers,
+// all in one batch per tracker, and looks the best-ranked ones up in the
+// DHT, then ranks the results by what was found. It gives up after
+// Config.SwarmCheckTimeout, keeping whatever came back by then.
+func (c *Client) checkSwarms(ctx context.Context, results []SearchResult) {
+	ctx, cancel := context.WithTimeout(ctx, c.cfg.SwarmCheckTimeout)
+	defer cancel()
+
+	var hashes [][sha1.Size]byte
+	for _, r := range results {
+		raw, err := hex.DecodeString(r.InfoHash)
+		if err == nil && len(raw) == sha1.Size {
+			hashes = append(hashes, [sha1.Size]byte(raw))
+		}
+	}
+	if len(hashes) == 0 {
+		return
+	}
+
+	var mu sync.Mutex
+	swarms := make(map[string]search.Swarm, len(hashes))
+	var wg sync.WaitGroup
+	for _, url := range c.cfg.ScrapeTrackers {
+		wg.Go(func() {
+			stats, err := c.scrape(ctx, url, hashes)
+			if err != nil {
+				slog.Debug(
+					"scrape failed",
+					slog.String("tracker", url),
+					slog.String("error", err.Error()),
+				)
+				return
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			for h, st := range stats {
+				if st.Seeders == 0 && st.Leechers == 0 {
+					continue
+				}
+				key := hex.EncodeToString(h[:])
+				s := swarms[key]
+				s.Seeders = max(s.Seeders, int(st.Seeders))
+				s.Leechers = max(s.Leechers, int(st.Leechers))
+				s.Scraped = true
+				swarms[key] = s
+			}
+		})
+	}
+	if c.dht != nil {
+		for _, h := range hashes[:min(len(hashes), maxDHTSwarmChecks)] {
+			wg.Go(func() {
+				n := len(c.dht.GetPeers(ctx, h, 0))
+				mu.Lock()
+				defer mu.Unlock()
+				key := hex.EncodeToString(h[:])
+				s := swarms[key]
+				s.DHTPeers = n
+				swarms[key] = s
+			})
+		}
+	}
+	wg.Wait()
+
+	search.ApplySwarms(results, swarms)
+}
+
+func (c *Client) scrape(
+	ctx context.Context,
+	url string,
+	hashes [][sha1.Size]byte,
+) (map[[sha1.Size]byte]tracker.ScrapeStats, error) {
+	t, err := tracker.NewTracker(url)
+	if err != nil {
+		return nil, err
+	}
+	if u, ok := t.(*tracker.UDPTrackerClient); ok && c.udp != nil {
+		u.SetDial(tracker.DialShared(c.udp))
+	}
+	if closer, ok := t.(io.Closer); ok {
+		defer closer.Close()
+	}
+	resp, err := t.Scrape(ctx, &tracker.ScrapeParams{InfoHashes: hashes})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Stats, nil
+}
@@ -0,0 +1,135 @@
+package echo
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+)
+
+// timetableTick is how often the pause windows are checked.
+const timetableTick = 30 * time.Second
+
+// minutesPerDay bounds PauseWindow.From and To.
+const minutesPerDay = 24 * 60
+
+// PauseWindow is a weekly stretch of time during which every transfer is
+// paused, for connections that are metered or needed for other things at
+// set hours.
+type PauseWindow struct {
+	// Days are the days the window starts on; none means every day.
+	Days []time.Weekday `json:"days"`
+	// From and To are minutes after midnight. A window whose To is not
+	// after its From runs past midnight into the next day, and one whose
+	// From equals its To lasts a whole day.
+	From int `json:"from"`
+	To   int `json:"to"`
+}
+
+// covers reports whether t falls in the window.
+func (w PauseWindow) covers(t time.Time) bool {
+	minute := t.Hour()*60 + t.Minute()
+	startsOn := func(day time.Weekday) bool {
+		return len(w.Days) == 0 || slices.Contains(w.Days, day)
+	}
+	today := t.Weekday()
+	yesterday := (today + 6) % 7
+
+	if w.From < w.To {
+		return startsOn(today) && minute >= w.From && minute < w.To
+	}
+	return startsOn(today) && minute >= w.From ||
+		startsOn(yesterday) && minute < w.To
+}
+
+func (w PauseWindow) validate() error {
+	if w.From < 0 || w.From >= minutesPerDay ||
+		w.To < 0 || w.To >= minutesPerDay {
+		return fmt.Errorf(
+			"echo: pause window %d-%d is outside the day",
+			w.From,
+			w.To,
+		)
+	}
+	for _, d := range w.Days {
+		if d < time.Sunday || d > time.Saturday {
+			return fmt.Errorf("echo: invalid weekday %d", d)
+		}
+	}
+	return nil
+}
+
+// PauseWindows returns the weekly windows during which transfers pause.
+func (c *Client) PauseWindows() []PauseWindow {
+	c.timetableMu.Lock()
+	defer c.timetableMu.Unlock()
+
+	return slices.Clone(c.pauseWindows)
+}
+
+// SetPauseWindows replaces the weekly windows during which transfers
+// pause, taking effect at once.
+func (c *Client) SetPauseWindows(windows []PauseWindow) error {
+	for _, w := range windows {
+		if err := w.validate(); err != nil {
+			return err
+		}
+	}
+
+	c.timetableMu.Lock()
+	c.pauseWindows = slices.Clone(windows)
+	c.timetableMu.Unlock()
+
+	c.applyTimetable(time.Now())
+	c.saveSession()
+	return nil
+}
+
+// Suspended reports whether a pause window, the power policy or the monthly
+// cap is holding every torrent in the queue.
+func (c *Client) Suspended() bool {
+	return c.suspended.Load()
+}
+
+// applyTimetable suspends or resumes the session as the pause windows say
+// for now.
+func (c *Client) applyTimetable(now time.Time) {
+	inWindow := slices.ContainsFunc(
+		c.PauseWindows(),
+		func(w PauseWindow) bool { return w.covers(now) },
+	)
+	c.inWindow.Store(inWindow)
+	c.updateSuspended()
+}
+
+// updateSuspended suspends the session while a pause window, the power
+// policy or the monthly cap asks for it. Suspending leaves every slot
+// empty, so active torrents go back to the queue and start again, in
+// order, once it ends; torrents paused by hand stay paused.
+func (c *Client) updateSuspended() {
+	suspend := c.inWindow.Load() || c.powerPaused.Load() ||
+		c.quotaPaused.Load()
+	if c.suspended.Swap(suspend) == suspend {
+		return
+	}
+
+	slog.Info("pause schedule", slog.Bool("suspended", suspend))
+	c.publish("session:suspended", suspend)
+	c.reschedule()
+}
+
+// runTimetable checks the pause windows until the client closes.
+func (c *Client) runTimetable() {
+	ticker := time.NewTicker(timetableTick)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		c.applyTimetable(time.Now())
+	}
+}
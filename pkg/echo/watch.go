@@ -0,0 +1,169 @@
+package echo
+
+import (
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// WatchAction is what happens to a file in a watch folder once it has been
+// added.
+type WatchAction string
+
+const (
+	// WatchRename appends ".added" to the file name, or ".failed" if it
+	// could not be added. It is the default.
+	WatchRename WatchAction = "rename"
+	WatchDelete WatchAction = "delete"
+)
+
+// WatchFolder is a directory whose .torrent and .magnet files are added
+// automatically. A .magnet file holds a magnet link on its first line.
+type WatchFolder struct {
+	Dir string `json:"dir"`
+	// Category files the folder's torrents under an existing category.
+	Category string      `json:"category"`
+	AfterAdd WatchAction `json:"afterAdd"`
+}
+
+// watchSettle is how long a file must go unmodified before it is picked up,
+// so one still being copied into the folder is not read half-written.
+const watchSettle = 2 * time.Second
+
+// WatchFolders returns the folders being watched.
+func (c *Client) WatchFolders() []WatchFolder {
+	c.watchMu.Lock()
+	defer c.watchMu.Unlock()
+
+	return slices.Clone(c.watch)
+}
+
+// SetWatchFolders replaces the folders being watched.
+func (c *Client) SetWatchFolders(folders []WatchFolder) error {
+	for _, f := range folders {
+		if f.Dir == "" {
+			return errors.New("echo: watch folder has no directory")
+		}
+	}
+
+	c.watchMu.Lock()
+	c.watch = slices.Clone(folders)
+	c.watchMu.Unlock()
+
+	c.saveSession()
+	return nil
+}
+
+// runWatchFolders scans the watch folders until the client closes.
+func (c *Client) runWatchFolders() {
+	if c.cfg.WatchInterval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(c.cfg.WatchInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		for _, f := range c.WatchFolders() {
+			c.scanWatchFolder(f)
+		}
+	}
+}
+
+func (c *Client) scanWatchFolder(f WatchFolder) {
+	entries, err := os.ReadDir(f.Dir)
+	if err != nil {
+		slog.Debug(
+			"reading watch folder failed",
+			slog.String("dir", f.Dir),
+			slog.String("error", err.Error()),
+		)
+		return
+	}
+
+	for _, e := range entries {
+		ext := strings.ToLower(filepath.Ext(e.Name()))
+		if e.IsDir() || (ext != ".torrent" && ext != ".magnet") {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil || time.Since(info.ModTime()) < watchSettle {
+			continue
+		}
+		if c.closing.Load() {
+			return
+		}
+
+		path := filepath.Join(f.Dir, e.Name())
+		err = c.addWatched(path, ext, f)
+		if err != nil && !errors.Is(err, ErrDuplicate) {
+			slog.Warn(
+				"adding from watch folder failed",
+				slog.String("path", path),
+				slog.String("error", err.Error()),
+			)
+		}
+		finishWatched(path, f.AfterAdd, err)
+	}
+}
+
+// addWatched adds the file at path. Magnet links are fetched in the
+// background, so the file is dealt with before their metadata arrives.
+func (c *Client) addWatched(path, ext string, f WatchFolder) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	opts := AddOptions{Category: f.Category}
+
+	if ext == ".torrent" {
+		_, err := c.AddTorrent(data, opts)
+		return err
+	}
+
+	uri, _, _ := strings.Cut(strings.TrimSpace(string(data)), "\n")
+	uri = strings.TrimSpace(uri)
+	if !strings.HasPrefix(uri, "magnet:") {
+		return errors.New("echo: no magnet link in file")
+	}
+	go func() {
+		if _, err := c.AddMagnet(c.ctx, uri, opts); err != nil {
+			slog.Warn(
+				"adding magnet link from watch folder failed",
+				slog.String("path", path),
+				slog.String("error", err.Error()),
+			)
+		}
+	}()
+	return nil
+}
+
+// finishWatched moves a handled file out of the way so the next scan does
+// not pick it up again.
+func finishWatched(path string, action WatchAction, addErr error) {
+	var err error
+	switch {
+	case addErr != nil && !errors.Is(addErr, ErrDuplicate):
+		err = os.Rename(path, path+".failed")
+	case action == WatchDelete:
+		err = os.Remove(path)
+	default:
+		err = os.Rename(path, path+".added")
+	}
+	if err != nil {
+		slog.Warn(
+			"clearing watched file failed",
+			slog.String("path", path),
+			slog.String("error", err.Error()),
+		)
+	}
+}
@@ -0,0 +1,104 @@
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"slices"
+)
+
+type attrsKey struct{}
+
+// With returns a copy of ctx whose records, logged through a
+// ContextHandler, carry attrs after those ctx already has.
+func With(ctx context.Context, attrs ...slog.Attr) context.Context {
+	if len(attrs) == 0 {
+		return ctx
+	}
+	return context.WithValue(
+		ctx,
+		attrsKey{},
+		slices.Concat(FromContext(ctx), attrs),
+	)
+}
+
+// WithPeer tags the records logged under ctx with the peer's address.
+func WithPeer(ctx context.Context, addr string) context.Context {
+	return With(ctx, slog.String("peer", addr))
+}
+
+// WithRequestID tags the records logged under ctx with a new request ID,
+// telling apart those of requests running at once.
+func WithRequestID(ctx context.Context) context.Context {
+	var id [4]byte
+	_, _ = rand.Read(id[:])
+	return With(ctx, slog.String("request_id", hex.EncodeToString(id[:])))
+}
+
+// FromContext returns the attributes With added to ctx.
+func FromContext(ctx context.Context) []slog.Attr {
+	attrs, _ := ctx.Value(attrsKey{}).([]slog.Attr)
+	return attrs
+}
+
+// ContextFunc returns attributes for the records logged under ctx from
+// values other packages keep in it.
+type ContextFunc func(ctx context.Context) []slog.Attr
+
+// ContextHandler adds to each record the attributes its context carries,
+// those from funcs and then With's, before the record's own, so that
+// records logged with slog's Context functions are tagged without every
+// call naming, say, the torrent.
+type ContextHandler struct {
+	next  slog.Handler
+	funcs []ContextFunc
+}
+
+func NewContextHandler(
+	next slog.Handler,
+	funcs ...ContextFunc,
+) *ContextHandler {
+	return &ContextHandler{next: next, funcs: funcs}
+}
+
+func (h *ContextHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *ContextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if ctx == nil {
+		return h.next.Handle(ctx, r)
+	}
+
+	var attrs []slog.Attr
+	for _, f := range h.funcs {
+		attrs = append(attrs, f(ctx)...)
+	}
+	attrs = append(attrs, FromContext(ctx)...)
+	if len(attrs) == 0 {
+		return h.next.Handle(ctx, r)
+	}
+
+	tagged := slog.NewRecord(r.Time, r.Level, r.Message, r.PC)
+	tagged.AddAttrs(attrs...)
+	r.Attrs(func(a slog.Attr) bool {
+		tagged.AddAttrs(a)
+		return true
+	})
+	return h.next.Handle(ctx, tagged)
+}
+
+func (h *ContextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	return &ContextHandler{next: h.next.WithAttrs(attrs), funcs: h.funcs}
+}
+
+func (h *ContextHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return &ContextHandler{next: h.next.WithGroup(name), funcs: h.funcs}
+}
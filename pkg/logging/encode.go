@@ -0,0 +1,260 @@
+package logging
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// maxPooledBuffer keeps the rare huge record from pinning its buffer in
+// bufPool.
+const maxPooledBuffer = 64 << 10
+
+var bufPool = sync.Pool{
+	New: func() any {
+		b := make([]byte, 0, 1024)
+		return &b
+	},
+}
+
+func getBuffer() *[]byte {
+	return bufPool.Get().(*[]byte)
+}
+
+func putBuffer(b *[]byte) {
+	if cap(*b) > maxPooledBuffer {
+		return
+	}
+	*b = (*b)[:0]
+	bufPool.Put(b)
+}
+
+// attrEncoder appends attributes to a buffer as the fields of a JSON
+// object, indented two spaces a level unless compact, straight from their
+// slog.Values rather than through a map and encoding/json.
+type attrEncoder struct {
+	compact    bool
+	escapeHTML bool
+	sortKeys   bool
+	maxLength  int
+	timeFormat string
+
+	// depth is how deep in the object the next field goes, 1 being the
+	// top level, and first whether it is the first at that depth.
+	depth int
+	first bool
+}
+
+func (e *attrEncoder) separator(buf []byte) []byte {
+	if !e.first {
+		buf = append(buf, ',')
+	}
+	e.first = false
+	if e.compact {
+		return buf
+	}
+	buf = append(buf, '\n')
+	for range e.depth {
+		buf = append(buf, "  "...)
+	}
+	return buf
+}
+
+func (e *attrEncoder) key(buf []byte, key string) []byte {
+	buf = e.separator(buf)
+	buf = appendString(buf, key, e.escapeHTML)
+	if e.compact {
+		return append(buf, ':')
+	}
+	return append(buf, ": "...)
+}
+
+// openGroup starts a nested object for name's fields.
+func (e *attrEncoder) openGroup(buf []byte, name string) []byte {
+	buf = e.key(buf, name)
+	e.depth++
+	e.first = true
+	return append(buf, '{')
+}
+
+func (e *attrEncoder) closeGroup(buf []byte) []byte {
+	e.depth--
+	e.first = false
+	if !e.compact {
+		buf = append(buf, '\n')
+		for range e.depth {
+			buf = append(buf, "  "...)
+		}
+	}
+	return append(buf, '}')
+}
+
+// attrs appends each of attrs, sorted by key if the encoder sorts.
+func (e *attrEncoder) attrs(buf []byte, attrs []slog.Attr) []byte {
+	if e.sortKeys {
+		attrs = slices.Clone(attrs)
+		slices.SortStableFunc(attrs, func(a, b slog.Attr) int {
+			return strings.Compare(a.Key, b.Key)
+		})
+	}
+	for _, a := range attrs {
+		buf = e.attr(buf, a)
+	}
+	return buf
+}
+
+// attr appends a, leaving out empty keys and groups with nothing in them,
+// as slog's own handlers do.
+func (e *attrEncoder) attr(buf []byte, a slog.Attr) []byte {
+	v := a.Value.Resolve()
+	if v.Kind() == slog.KindGroup {
+		group := v.Group()
+		if len(group) == 0 {
+			return buf
+		}
+		// An unnamed group's attributes belong to the enclosing one.
+		if a.Key == "" {
+			return e.attrs(buf, group)
+		}
+		mark, first := len(buf), e.first
+		buf = e.openGroup(buf, a.Key)
+		start := len(buf)
+		buf = e.attrs(buf, group)
+		if len(buf) == start {
+			e.depth--
+			e.first = first
+			return buf[:mark]
+		}
+		return e.closeGroup(buf)
+	}
+	if a.Key == "" {
+		return buf
+	}
+	buf = e.key(buf, a.Key)
+	return e.value(buf, v)
+}
+
+func (e *attrEncoder) value(buf []byte, v slog.Value) []byte {
+	switch v.Kind() {
+	case slog.KindString:
+		return e.string(buf, v.String())
+	case slog.KindInt64:
+		return strconv.AppendInt(buf, v.Int64(), 10)
+	case slog.KindUint64:
+		return strconv.AppendUint(buf, v.Uint64(), 10)
+	case slog.KindFloat64:
+		f := v.Float64()
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			text := strconv.FormatFloat(f, 'g', -1, 64)
+			return appendString(buf, text, false)
+		}
+		return strconv.AppendFloat(buf, f, 'g', -1, 64)
+	case slog.KindBool:
+		return strconv.AppendBool(buf, v.Bool())
+	case slog.KindDuration:
+		return appendString(buf, v.Duration().String(), false)
+	case slog.KindTime:
+		return e.time(buf, v.Time())
+	}
+	return e.any(buf, v.Any())
+}
+
+func (e *attrEncoder) string(buf []byte, s string) []byte {
+	if e.maxLength > 0 && len(s) > e.maxLength {
+		s = s[:e.maxLength] + "..."
+	}
+	return appendString(buf, s, e.escapeHTML)
+}
+
+func (e *attrEncoder) time(buf []byte, t time.Time) []byte {
+	buf = append(buf, '"')
+	buf = t.AppendFormat(buf, e.timeFormat)
+	return append(buf, '"')
+}
+
+// any appends what the other kinds don't cover: errors as their message,
+// and the rest as encoding/json has them, or as text if it can't.
+func (e *attrEncoder) any(buf []byte, v any) []byte {
+	switch v := v.(type) {
+	case nil:
+		return append(buf, "null"...)
+	case string:
+		return e.string(buf, v)
+	case error:
+		return e.string(buf, v.Error())
+	case []byte:
+		return e.string(buf, string(v))
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return e.string(buf, fmt.Sprint(v))
+	}
+	return append(buf, data...)
+}
+
+const hexDigits = "0123456789abcdef"
+
+// appendString appends s as a JSON string, escaped as encoding/json
+// would, invalid UTF-8 included.
+func appendString(buf []byte, s string, escapeHTML bool) []byte {
+	buf = append(buf, '"')
+	start := 0
+	for i := 0; i < len(s); {
+		c := s[i]
+		if c < utf8.RuneSelf {
+			html := c == '<' || c == '>' || c == '&'
+			if c >= 0x20 && c != '"' && c != '\\' &&
+				(!escapeHTML || !html) {
+				i++
+				continue
+			}
+			buf = append(buf, s[start:i]...)
+			switch c {
+			case '"', '\\':
+				buf = append(buf, '\\', c)
+			case '\n':
+				buf = append(buf, '\\', 'n')
+			case '\r':
+				buf = append(buf, '\\', 'r')
+			case '\t':
+				buf = append(buf, '\\', 't')
+			default:
+				buf = append(buf, `\u00`...)
+				buf = append(
+					buf,
+					hexDigits[c>>4],
+					hexDigits[c&0xf],
+				)
+			}
+			i++
+			start = i
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			buf = append(buf, s[start:i]...)
+			buf = append(buf, `\ufffd`...)
+			i += size
+			start = i
+			continue
+		}
+		if r == '\u2028' || r == '\u2029' {
+			buf = append(buf, s[start:i]...)
+			buf = append(buf, `\u202`...)
+			buf = append(buf, hexDigits[r&0xf])
+			i += size
+			start = i
+			continue
+		}
+		i += size
+	}
+	buf = append(buf, s[start:]...)
+	return append(buf, '"')
+}
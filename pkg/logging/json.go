@@ -0,0 +1,87 @@
+package logging
+
+import (
+	"context"
+	"io"
+	"log/slog"
+)
+
+// JSONHandler writes each record as a line of JSON, for shipping logs to
+// an aggregator rather than reading them. It takes the same options as
+// PrettyHandler; colors, level width, field separator and indentation,
+// which only make sense to a reader, are ignored.
+type JSONHandler struct {
+	pretty *PrettyHandler
+}
+
+func NewJSONHandler(w io.Writer, opts *PrettyHandlerOptions) *JSONHandler {
+	o := DefaultOptions()
+	if opts != nil {
+		o = *opts
+	}
+	o.UseColor = false
+	o.CompactJSON = true
+	return &JSONHandler{pretty: NewPrettyHandler(w, &o)}
+}
+
+func (h *JSONHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.pretty.Enabled(ctx, level)
+}
+
+// Handle writes the time, level, source and message first, then the
+// record's attributes, groups nested as objects.
+func (h *JSONHandler) Handle(_ context.Context, r slog.Record) error {
+	if !allowed(r) {
+		return nil
+	}
+
+	p := h.pretty
+	bufp := getBuffer()
+	defer putBuffer(bufp)
+
+	e := p.encoder()
+	e.depth, e.first = 1, true
+	buf := append(*bufp, '{')
+	if !p.opts.DisableTimestamp {
+		buf = e.key(buf, "time")
+		buf = e.time(buf, r.Time)
+	}
+	buf = e.key(buf, "level")
+	buf = appendString(buf, r.Level.String(), false)
+	if p.opts.ShowSource {
+		if source := p.appendSource(nil, r.PC); len(source) > 0 {
+			buf = e.key(buf, "source")
+			buf = appendString(buf, string(source), e.escapeHTML)
+		}
+	}
+	buf = e.key(buf, "msg")
+	buf = appendString(buf, r.Message, e.escapeHTML)
+
+	mark := len(buf)
+	buf = append(buf, ',')
+	if buf = p.appendAttrs(buf, r); len(buf) == mark+1 {
+		buf = buf[:mark]
+	}
+	buf = append(buf, "}\n"...)
+	*bufp = buf
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	_, err := p.writer.Write(buf)
+	return err
+}
+
+func (h *JSONHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	return &JSONHandler{pretty: h.pretty.WithAttrs(attrs).(*PrettyHandler)}
+}
+
+func (h *JSONHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return &JSONHandler{pretty: h.pretty.WithGroup(name).(*PrettyHandler)}
+}
@@ -0,0 +1,119 @@
+package logging
+
+import (
+	"fmt"
+	"log/slog"
+	"maps"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Modules are named after the last element of the logging package's
+// import path, such as "tracker" or "peer". A module's level quiets its
+// records below it on top of the handlers' own levels; it can't make a
+// handler log what its level drops.
+var modules struct {
+	mu     sync.RWMutex
+	levels map[string]slog.Level
+	// set is whether any level is, sparing records the lookup of their
+	// module when none is.
+	set atomic.Bool
+	// byPC caches the module each logging call site is in.
+	byPC sync.Map
+}
+
+// SetLevel quiets records from module below level.
+func SetLevel(module string, level slog.Level) {
+	modules.mu.Lock()
+	defer modules.mu.Unlock()
+
+	if modules.levels == nil {
+		modules.levels = make(map[string]slog.Level)
+	}
+	modules.levels[module] = level
+	modules.set.Store(true)
+}
+
+// ResetLevel leaves module's records to the handlers' levels again.
+func ResetLevel(module string) {
+	modules.mu.Lock()
+	defer modules.mu.Unlock()
+
+	delete(modules.levels, module)
+	modules.set.Store(len(modules.levels) > 0)
+}
+
+// SetLevels replaces every module's level with those in levels.
+func SetLevels(levels map[string]slog.Level) {
+	modules.mu.Lock()
+	defer modules.mu.Unlock()
+
+	modules.levels = maps.Clone(levels)
+	modules.set.Store(len(levels) > 0)
+}
+
+// Levels returns the modules' levels.
+func Levels() map[string]slog.Level {
+	modules.mu.RLock()
+	defer modules.mu.RUnlock()
+
+	return maps.Clone(modules.levels)
+}
+
+// ParseLevels reads module levels written as "tracker=warn,peer=error".
+func ParseLevels(s string) (map[string]slog.Level, error) {
+	levels := make(map[string]slog.Level)
+	for pair := range strings.SplitSeq(s, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		module, name, ok := strings.Cut(pair, "=")
+		module = strings.TrimSpace(module)
+		if !ok || module == "" {
+			err := fmt.Errorf("logging: bad module level %q", pair)
+			return nil, err
+		}
+		level, err := ParseLevel(strings.TrimSpace(name))
+		if err != nil {
+			return nil, fmt.Errorf("logging: %s: %w", module, err)
+		}
+		levels[module] = level
+	}
+	return levels, nil
+}
+
+// Module returns the module of the function at pc, as slog.Record.PC
+// gives it, or "" if it can't tell.
+func Module(pc uintptr) string {
+	if pc == 0 {
+		return ""
+	}
+	if m, ok := modules.byPC.Load(pc); ok {
+		return m.(string)
+	}
+
+	frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+	fn := frame.Function
+	if i := strings.LastIndex(fn, "/"); i >= 0 {
+		fn = fn[i+1:]
+	}
+	module, _, _ := strings.Cut(fn, ".")
+	modules.byPC.Store(pc, module)
+	return module
+}
+
+// allowed reports whether r passes its module's level, if it has one.
+func allowed(r slog.Record) bool {
+	if !modules.set.Load() {
+		return true
+	}
+	module := Module(r.PC)
+
+	modules.mu.RLock()
+	defer modules.mu.RUnlock()
+
+	level, ok := modules.levels[module]
+	return !ok || r.Level >= level
+}
@@ -0,0 +1,92 @@
+package logging
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+)
+
+// MultiHandler passes each record on to several handlers, such as one
+// writing to the console and one to a file, each deciding for itself,
+// through its Enabled, which records it takes.
+type MultiHandler struct {
+	handlers []slog.Handler
+}
+
+func NewMultiHandler(handlers ...slog.Handler) *MultiHandler {
+	return &MultiHandler{handlers: handlers}
+}
+
+func (h *MultiHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, next := range h.handlers {
+		if next.Enabled(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+// Handle passes r to every handler that takes its level, each with a
+// copy of its own, and returns their errors joined.
+func (h *MultiHandler) Handle(ctx context.Context, r slog.Record) error {
+	var errs []error
+	for _, next := range h.handlers {
+		if !next.Enabled(ctx, r.Level) {
+			continue
+		}
+		if err := next.Handle(ctx, r.Clone()); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (h *MultiHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	handlers := make([]slog.Handler, len(h.handlers))
+	for i, next := range h.handlers {
+		handlers[i] = next.WithAttrs(attrs)
+	}
+	return &MultiHandler{handlers: handlers}
+}
+
+func (h *MultiHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	handlers := make([]slog.Handler, len(h.handlers))
+	for i, next := range h.handlers {
+		handlers[i] = next.WithGroup(name)
+	}
+	return &MultiHandler{handlers: handlers}
+}
+
+// LevelHandler drops the records below its level before they reach the
+// handler it wraps, giving one of a MultiHandler's handlers a level of its
+// own.
+type LevelHandler struct {
+	level slog.Leveler
+	next  slog.Handler
+}
+
+func NewLevelHandler(level slog.Leveler, next slog.Handler) *LevelHandler {
+	return &LevelHandler{level: level, next: next}
+}
+
+func (h *LevelHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= h.level.Level() && h.next.Enabled(ctx, level)
+}
+
+func (h *LevelHandler) Handle(ctx context.Context, r slog.Record) error {
+	return h.next.Handle(ctx, r)
+}
+
+func (h *LevelHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return NewLevelHandler(h.level, h.next.WithAttrs(attrs))
+}
+
+func (h *LevelHandler) WithGroup(name string) slog.Handler {
+	return NewLevelHandler(h.level, h.next.WithGroup(name))
+}
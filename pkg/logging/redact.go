@@ -0,0 +1,178 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/netip"
+	"path"
+	"regexp"
+	"strings"
+)
+
+const redacted = "[redacted]"
+
+// DefaultRedactKeys are attribute keys whose values are never logged.
+var DefaultRedactKeys = []string{
+	"passkey",
+	"authkey",
+	"apikey",
+	"api_key",
+	"cookie",
+	"*password*",
+	"*secret*",
+	"*token*",
+}
+
+var (
+	// secretParam matches the value of a query parameter private trackers
+	// and indexers authenticate with.
+	secretParam = regexp.MustCompile(
+		`(?i)([?&](?:passkey|authkey|torrent_pass|apikey|api_key|` +
+			`license_key|token|key|pk|uid|secret)=)[^&#\s"']+`,
+	)
+	// secretPath matches a passkey given as the path segment before
+	// announce or scrape.
+	secretPath = regexp.MustCompile(
+		`(?i)(://[^/\s"']+/)[a-z0-9]{16,}(/(?:announce|scrape))`,
+	)
+	// secretUser matches the password in a URL's user info.
+	secretUser = regexp.MustCompile(`(://[^/\s"'@:]+:)[^/\s"'@]+@`)
+
+	ipv4 = regexp.MustCompile(`\b(?:\d{1,3}\.){3}\d{1,3}\b`)
+	ipv6 = regexp.MustCompile(`\[[0-9A-Fa-f:.]+(?:%[^\]]*)?\]`)
+)
+
+type RedactOptions struct {
+	// Keys are patterns, as path.Match has them, for attribute keys whose
+	// values are replaced whatever they are. Matching ignores case.
+	// DefaultRedactKeys if nil.
+	Keys []string
+	// PeerAddrs, for privacy, hides every IP address logged.
+	PeerAddrs bool
+}
+
+// RedactHandler hides secrets, such as passkeys in announce URLs and API
+// keys, from the records it passes to the handler it wraps, so that they
+// never reach a console, file or the log viewer.
+type RedactHandler struct {
+	next slog.Handler
+	opts RedactOptions
+}
+
+func NewRedactHandler(
+	next slog.Handler,
+	opts *RedactOptions,
+) *RedactHandler {
+	var o RedactOptions
+	if opts != nil {
+		o = *opts
+	}
+	if o.Keys == nil {
+		o.Keys = DefaultRedactKeys
+	}
+	keys := make([]string, len(o.Keys))
+	for i, key := range o.Keys {
+		keys[i] = strings.ToLower(key)
+	}
+	o.Keys = keys
+
+	return &RedactHandler{next: next, opts: o}
+}
+
+func (h *RedactHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *RedactHandler) Handle(ctx context.Context, r slog.Record) error {
+	clean := slog.NewRecord(r.Time, r.Level, h.scrub(r.Message), r.PC)
+	r.Attrs(func(a slog.Attr) bool {
+		clean.AddAttrs(h.attr(a))
+		return true
+	})
+	return h.next.Handle(ctx, clean)
+}
+
+func (h *RedactHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+
+	clean := make([]slog.Attr, len(attrs))
+	for i, a := range attrs {
+		clean[i] = h.attr(a)
+	}
+	return &RedactHandler{next: h.next.WithAttrs(clean), opts: h.opts}
+}
+
+func (h *RedactHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return &RedactHandler{next: h.next.WithGroup(name), opts: h.opts}
+}
+
+func (h *RedactHandler) attr(a slog.Attr) slog.Attr {
+	if h.sensitive(a.Key) {
+		return slog.String(a.Key, redacted)
+	}
+
+	v := a.Value.Resolve()
+	switch v.Kind() {
+	case slog.KindGroup:
+		group := v.Group()
+		clean := make([]slog.Attr, len(group))
+		for i, ga := range group {
+			clean[i] = h.attr(ga)
+		}
+		return slog.Attr{Key: a.Key, Value: slog.GroupValue(clean...)}
+	case slog.KindString:
+		return slog.String(a.Key, h.scrub(v.String()))
+	case slog.KindAny:
+		// Errors and addresses are logged as text; only those that give
+		// something away are replaced by their scrubbed text.
+		var text string
+		switch x := v.Any().(type) {
+		case error:
+			text = x.Error()
+		case fmt.Stringer:
+			text = x.String()
+		default:
+			return slog.Attr{Key: a.Key, Value: v}
+		}
+		if clean := h.scrub(text); clean != text {
+			return slog.String(a.Key, clean)
+		}
+	}
+	return slog.Attr{Key: a.Key, Value: v}
+}
+
+func (h *RedactHandler) sensitive(key string) bool {
+	key = strings.ToLower(key)
+	for _, pattern := range h.opts.Keys {
+		if ok, _ := path.Match(pattern, key); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// scrub hides the secrets in URLs in s and, in privacy mode, any IP
+// address.
+func (h *RedactHandler) scrub(s string) string {
+	if strings.Contains(s, "://") || strings.Contains(s, "=") {
+		s = secretParam.ReplaceAllString(s, "${1}"+redacted)
+		s = secretPath.ReplaceAllString(s, "${1}"+redacted+"${2}")
+		s = secretUser.ReplaceAllString(s, "${1}"+redacted+"@")
+	}
+	if !h.opts.PeerAddrs {
+		return s
+	}
+
+	// A bare IPv6 address, without the brackets it has beside a port.
+	if addr, err := netip.ParseAddr(s); err == nil && addr.Is6() {
+		return redacted
+	}
+	s = ipv6.ReplaceAllString(s, redacted)
+	return ipv4.ReplaceAllString(s, redacted)
+}
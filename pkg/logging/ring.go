@@ -0,0 +1,292 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultRingSize is how many entries a RingHandler keeps unless told
+// otherwise.
+const DefaultRingSize = 1000
+
+// Entry is a log record as the ring keeps it. Attributes in groups are
+// keyed by their dotted path. Module is the package that logged it; see
+// SetLevel.
+type Entry struct {
+	Time    time.Time         `json:"time"`
+	Level   string            `json:"level"`
+	Module  string            `json:"module,omitempty"`
+	Message string            `json:"message"`
+	Attrs   map[string]string `json:"attrs,omitempty"`
+
+	level slog.Level
+}
+
+type RingOptions struct {
+	// Size caps the entries kept; the oldest go first.
+	Size int
+	// Level is the least severe level kept, Info if nil.
+	Level slog.Leveler
+}
+
+// RingHandler keeps the most recent records in memory, for showing them
+// in the app, and passes every record on to the handler it wraps, if any.
+type RingHandler struct {
+	next   slog.Handler
+	ring   *ring
+	level  slog.Leveler
+	prefix string
+	attrs  []slog.Attr
+}
+
+// ring is shared by a RingHandler and the handlers derived from it.
+type ring struct {
+	mu      sync.Mutex
+	entries []Entry
+	next    int
+	full    bool
+
+	subsMu sync.RWMutex
+	subs   map[int]func(Entry)
+	nextID int
+}
+
+func NewRingHandler(next slog.Handler, opts *RingOptions) *RingHandler {
+	var o RingOptions
+	if opts != nil {
+		o = *opts
+	}
+	if o.Size <= 0 {
+		o.Size = DefaultRingSize
+	}
+	if o.Level == nil {
+		o.Level = slog.LevelInfo
+	}
+
+	return &RingHandler{
+		next:  next,
+		level: o.Level,
+		ring: &ring{
+			entries: make([]Entry, o.Size),
+			subs:    make(map[int]func(Entry)),
+		},
+	}
+}
+
+func (h *RingHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= h.level.Level() ||
+		(h.next != nil && h.next.Enabled(ctx, level))
+}
+
+func (h *RingHandler) Handle(ctx context.Context, r slog.Record) error {
+	if !allowed(r) {
+		return nil
+	}
+	if r.Level >= h.level.Level() {
+		h.ring.add(h.entry(r))
+	}
+	if h.next == nil || !h.next.Enabled(ctx, r.Level) {
+		return nil
+	}
+	return h.next.Handle(ctx, r)
+}
+
+func (h *RingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+
+	clone := *h
+	if h.next != nil {
+		clone.next = h.next.WithAttrs(attrs)
+	}
+	clone.attrs = slices.Clone(h.attrs)
+	for _, a := range attrs {
+		a.Key = h.prefix + a.Key
+		clone.attrs = append(clone.attrs, a)
+	}
+	return &clone
+}
+
+func (h *RingHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+
+	clone := *h
+	if h.next != nil {
+		clone.next = h.next.WithGroup(name)
+	}
+	clone.prefix = h.prefix + name + "."
+	return &clone
+}
+
+func (h *RingHandler) entry(r slog.Record) Entry {
+	e := Entry{
+		Time:    r.Time,
+		Level:   r.Level.String(),
+		Module:  Module(r.PC),
+		Message: r.Message,
+		level:   r.Level,
+	}
+
+	attrs := make(map[string]string)
+	for _, a := range h.attrs {
+		addAttr(attrs, "", a)
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		addAttr(attrs, h.prefix, a)
+		return true
+	})
+	if len(attrs) > 0 {
+		e.Attrs = attrs
+	}
+	return e
+}
+
+func addAttr(attrs map[string]string, prefix string, a slog.Attr) {
+	v := a.Value.Resolve()
+	if v.Kind() == slog.KindGroup {
+		for _, ga := range v.Group() {
+			addAttr(attrs, prefix+a.Key+".", ga)
+		}
+		return
+	}
+	if a.Key == "" {
+		return
+	}
+	attrs[prefix+a.Key] = v.String()
+}
+
+func (r *ring) add(e Entry) {
+	r.mu.Lock()
+	r.entries[r.next] = e
+	r.next = (r.next + 1) % len(r.entries)
+	if r.next == 0 {
+		r.full = true
+	}
+	r.mu.Unlock()
+
+	r.subsMu.RLock()
+	defer r.subsMu.RUnlock()
+
+	for _, fn := range r.subs {
+		fn(e)
+	}
+}
+
+// Query picks out entries kept by a RingHandler.
+type Query struct {
+	// Level is the least severe level returned.
+	Level slog.Level `json:"level"`
+	// Modules, if any are given, are the only ones returned.
+	Modules []string `json:"modules"`
+	// Text, if set, must be in the message or an attribute's value,
+	// ignoring case.
+	Text string `json:"text"`
+	// Limit caps the entries returned, keeping the latest; zero or less
+	// returns every one that matches.
+	Limit int `json:"limit"`
+}
+
+func (q Query) match(e Entry) bool {
+	if e.level < q.Level {
+		return false
+	}
+	if len(q.Modules) > 0 && !slices.Contains(q.Modules, e.Module) {
+		return false
+	}
+	if q.Text == "" {
+		return true
+	}
+	text := strings.ToLower(q.Text)
+	if strings.Contains(strings.ToLower(e.Message), text) {
+		return true
+	}
+	for _, v := range e.Attrs {
+		if strings.Contains(strings.ToLower(v), text) {
+			return true
+		}
+	}
+	return false
+}
+
+// Recent returns up to limit of the latest entries at level or above,
+// oldest first. A limit of zero or less returns every one kept.
+func (h *RingHandler) Recent(level slog.Level, limit int) []Entry {
+	return h.Query(Query{Level: level, Limit: limit})
+}
+
+// Query returns the latest entries that match q, oldest first.
+func (h *RingHandler) Query(q Query) []Entry {
+	r := h.ring
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	kept := r.entries[:r.next]
+	if r.full {
+		kept = slices.Concat(r.entries[r.next:], kept)
+	}
+
+	var out []Entry
+	for i := len(kept) - 1; i >= 0; i-- {
+		if q.Limit > 0 && len(out) == q.Limit {
+			break
+		}
+		if q.match(kept[i]) {
+			out = append(out, kept[i])
+		}
+	}
+	slices.Reverse(out)
+	return out
+}
+
+// Modules returns the modules of the entries kept, sorted.
+func (h *RingHandler) Modules() []string {
+	r := h.ring
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	seen := make(map[string]bool)
+	for _, e := range r.entries {
+		if e.Module != "" {
+			seen[e.Module] = true
+		}
+	}
+	return slices.Sorted(maps.Keys(seen))
+}
+
+// Subscribe calls fn with every entry the ring keeps from now on, from
+// the goroutine that logged it; fn must not log. The returned function
+// unsubscribes.
+func (h *RingHandler) Subscribe(fn func(Entry)) func() {
+	r := h.ring
+	r.subsMu.Lock()
+	defer r.subsMu.Unlock()
+
+	id := r.nextID
+	r.nextID++
+	r.subs[id] = fn
+
+	return func() {
+		r.subsMu.Lock()
+		defer r.subsMu.Unlock()
+
+		delete(r.subs, id)
+	}
+}
+
+// ParseLevel reads a level name such as "warn" or "ERROR"; empty is Info.
+func ParseLevel(s string) (slog.Level, error) {
+	var l slog.Level
+	if strings.TrimSpace(s) == "" {
+		return slog.LevelInfo, nil
+	}
+	err := l.UnmarshalText([]byte(s))
+	return l, err
+}
@@ -0,0 +1,167 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// maxSampled bounds how many distinct messages a SamplingHandler keeps
+// count of; past it, those whose interval is over are forgotten.
+const maxSampled = 1024
+
+type SampleOptions struct {
+	// Burst is how many records with the same level and message pass in
+	// each Interval; the rest are dropped and counted. 10 if zero.
+	Burst int
+	// Interval is 1s if zero.
+	Interval time.Duration
+	// Level is the least severe level never sampled, Warn if nil.
+	Level slog.Leveler
+}
+
+// SamplingHandler keeps a message logged on a hot path, such as one per
+// peer message, from swamping the handler it wraps. Once a message has
+// been logged Burst times in an interval, the rest are dropped until the
+// next, which starts with a record saying how many were.
+type SamplingHandler struct {
+	next  slog.Handler
+	opts  SampleOptions
+	state *sampler
+}
+
+// sampler is shared by a SamplingHandler and the handlers derived from
+// it.
+type sampler struct {
+	mu      sync.Mutex
+	windows map[sampleKey]*sampleWindow
+}
+
+type sampleKey struct {
+	level   slog.Level
+	message string
+}
+
+type sampleWindow struct {
+	start      time.Time
+	count      int
+	suppressed int
+}
+
+func NewSamplingHandler(
+	next slog.Handler,
+	opts *SampleOptions,
+) *SamplingHandler {
+	var o SampleOptions
+	if opts != nil {
+		o = *opts
+	}
+	if o.Burst <= 0 {
+		o.Burst = 10
+	}
+	if o.Interval <= 0 {
+		o.Interval = time.Second
+	}
+	if o.Level == nil {
+		o.Level = slog.LevelWarn
+	}
+
+	return &SamplingHandler{
+		next:  next,
+		opts:  o,
+		state: &sampler{windows: make(map[sampleKey]*sampleWindow)},
+	}
+}
+
+func (h *SamplingHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *SamplingHandler) Handle(ctx context.Context, r slog.Record) error {
+	if r.Level >= h.opts.Level.Level() {
+		return h.next.Handle(ctx, r)
+	}
+
+	pass, suppressed := h.state.note(r, h.opts)
+	if suppressed > 0 {
+		summary := slog.NewRecord(
+			r.Time,
+			r.Level,
+			"suppressed similar records",
+			r.PC,
+		)
+		summary.AddAttrs(
+			slog.String("message", r.Message),
+			slog.Int("suppressed", suppressed),
+		)
+		if err := h.next.Handle(ctx, summary); err != nil {
+			return err
+		}
+	}
+	if !pass {
+		return nil
+	}
+	return h.next.Handle(ctx, r)
+}
+
+// note counts r against its message's interval. It returns whether r
+// passes and, when r starts a new interval, how many records the last
+// one dropped.
+func (s *sampler) note(r slog.Record, opts SampleOptions) (bool, int) {
+	now := r.Time
+	if now.IsZero() {
+		now = time.Now()
+	}
+	key := sampleKey{level: r.Level, message: r.Message}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	suppressed := 0
+	w := s.windows[key]
+	if w == nil || now.Sub(w.start) >= opts.Interval {
+		if w != nil {
+			suppressed = w.suppressed
+		}
+		if w == nil && len(s.windows) >= maxSampled {
+			s.prune(now, opts.Interval)
+		}
+		w = &sampleWindow{start: now}
+		s.windows[key] = w
+	}
+	w.count++
+	if w.count > opts.Burst {
+		w.suppressed++
+		return false, suppressed
+	}
+	return true, suppressed
+}
+
+func (s *sampler) prune(now time.Time, interval time.Duration) {
+	for key, w := range s.windows {
+		if now.Sub(w.start) >= interval {
+			delete(s.windows, key)
+		}
+	}
+}
+
+func (h *SamplingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+
+	clone := *h
+	clone.next = h.next.WithAttrs(attrs)
+	return &clone
+}
+
+func (h *SamplingHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+
+	clone := *h
+	clone.next = h.next.WithGroup(name)
+	return &clone
+}
@@ -1,27 +1,23 @@
 package logging
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
 	"fmt"
 	"io"
 	"log/slog"
+	"os"
 	"path/filepath"
 	"runtime"
+	"slices"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/fatih/color"
+	"github.com/mattn/go-isatty"
 )
 
-var bufPool = sync.Pool{
-	New: func() interface{} {
-		return new(bytes.Buffer)
-	},
-}
-
 type PrettyHandlerOptions struct {
 	SlogOpts          slog.HandlerOptions
 	UseColor          bool
@@ -60,8 +56,14 @@ type PrettyHandler struct {
 	opts   PrettyHandlerOptions
 	writer io.Writer
 	mu     *sync.Mutex
-	groups []string
-	attrs  []slog.Attr
+
+	// pre holds the attributes given to WithAttrs already encoded, inside
+	// open groups still to be closed. pending are the groups named since,
+	// opened only once something goes in them.
+	pre       []byte
+	preFields bool
+	open      int
+	pending   []string
 
 	colorTime    func(...any) string
 	colorLevel   map[slog.Level]func(...any) string
@@ -71,6 +73,9 @@ type PrettyHandler struct {
 	colorError   func(...any) string
 }
 
+// NewPrettyHandler returns a handler writing to w. Colors, if UseColor
+// asks for them, are only used when w is a terminal, unless NO_COLOR or
+// FORCE_COLOR is set.
 func NewPrettyHandler(w io.Writer, opts *PrettyHandlerOptions) *PrettyHandler {
 	if opts == nil {
 		defaultOpts := DefaultOptions()
@@ -91,9 +96,8 @@ func NewPrettyHandler(w io.Writer, opts *PrettyHandlerOptions) *PrettyHandler {
 		opts:   *opts,
 		writer: w,
 		mu:     &sync.Mutex{},
-		groups: make([]string, 0),
-		attrs:  make([]slog.Attr, 0),
 	}
+	h.opts.UseColor = useColor(w, opts.UseColor)
 	h.initColorFuncs()
 
 	return h
@@ -116,18 +120,46 @@ func (h *PrettyHandler) initColorFuncs() {
 		return
 	}
 
-	h.colorTime = color.New(color.FgHiBlack).SprintFunc()
-	h.colorMessage = color.New(color.FgCyan).SprintFunc()
-	h.colorSource = color.New(color.FgHiBlack).SprintFunc()
-	h.colorFields = color.New(color.FgWhite).SprintFunc()
-	h.colorError = color.New(color.FgRed, color.Bold).SprintFunc()
+	h.colorTime = colorFunc(color.FgHiBlack)
+	h.colorMessage = colorFunc(color.FgCyan)
+	h.colorSource = colorFunc(color.FgHiBlack)
+	h.colorFields = colorFunc(color.FgWhite)
+	h.colorError = colorFunc(color.FgRed, color.Bold)
 
 	h.colorLevel = map[slog.Level]func(...any) string{
-		slog.LevelDebug: color.New(color.FgMagenta).SprintFunc(),
-		slog.LevelInfo:  color.New(color.FgBlue).SprintFunc(),
-		slog.LevelWarn:  color.New(color.FgYellow).SprintFunc(),
-		slog.LevelError: color.New(color.FgRed).SprintFunc(),
+		slog.LevelDebug: colorFunc(color.FgMagenta),
+		slog.LevelInfo:  colorFunc(color.FgBlue),
+		slog.LevelWarn:  colorFunc(color.FgYellow),
+		slog.LevelError: colorFunc(color.FgRed),
+	}
+}
+
+// colorFunc colors regardless of color.NoColor, which only looks at
+// stdout; useColor has already decided for the handler's writer.
+func colorFunc(attrs ...color.Attribute) func(...any) string {
+	c := color.New(attrs...)
+	c.EnableColor()
+	return c.SprintFunc()
+}
+
+// useColor reports whether to color output to w when want asks for it:
+// never if NO_COLOR is set, always if FORCE_COLOR is, and otherwise only
+// when w is a terminal.
+func useColor(w io.Writer, want bool) bool {
+	if !want || os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	switch os.Getenv("FORCE_COLOR") {
+	case "", "0", "false":
+	default:
+		return true
+	}
+
+	f, ok := w.(interface{ Fd() uintptr })
+	if !ok {
+		return false
 	}
+	return isatty.IsTerminal(f.Fd()) || isatty.IsCygwinTerminal(f.Fd())
 }
 
 func (h *PrettyHandler) Enabled(_ context.Context, level slog.Level) bool {
@@ -135,50 +167,59 @@ func (h *PrettyHandler) Enabled(_ context.Context, level slog.Level) bool {
 }
 
 func (h *PrettyHandler) Handle(ctx context.Context, r slog.Record) error {
-	buf := bufPool.Get().(*bytes.Buffer)
-	defer func() {
-		buf.Reset()
-		bufPool.Put(buf)
-	}()
+	if !allowed(r) {
+		return nil
+	}
 
-	h.mu.Lock()
-	defer h.mu.Unlock()
+	bufp := getBuffer()
+	defer putBuffer(bufp)
+	buf := *bufp
 
 	if !h.opts.DisableTimestamp {
-		timestamp := r.Time.Format(h.opts.TimeFormat)
-		buf.WriteString(h.colorTime(timestamp))
-		buf.WriteString(h.opts.FieldSeparator)
+		buf = h.appendTime(buf, r.Time)
+		buf = append(buf, h.opts.FieldSeparator...)
 	}
 
-	level := h.formatLevel(r.Level)
-	buf.WriteString(level)
-	buf.WriteString(h.opts.FieldSeparator)
+	buf = h.appendLevel(buf, r.Level)
+	buf = append(buf, h.opts.FieldSeparator...)
 
 	if h.opts.ShowSource {
-		source := h.extractSource(r.PC)
-		if source != "" {
-			buf.WriteString(h.colorSource(source))
-			buf.WriteString(h.opts.FieldSeparator)
+		n := len(buf)
+		if buf = h.appendSource(buf, r.PC); len(buf) > n {
+			buf = append(buf, h.opts.FieldSeparator...)
 		}
 	}
 
-	buf.WriteString(h.colorMessage(r.Message))
+	if h.opts.UseColor {
+		buf = append(buf, h.colorMessage(r.Message)...)
+	} else {
+		buf = append(buf, r.Message...)
+	}
 
-	attrs := h.collectAttributes(r)
-	if len(attrs) > 0 {
-		buf.WriteString(h.opts.FieldSeparator)
-		if err := h.formatAttributes(buf, attrs); err != nil {
-			buf.WriteString(
-				fmt.Sprintf(
-					"(error formatting attributes: %v)",
-					err,
-				),
-			)
+	mark := len(buf)
+	buf = append(buf, h.opts.FieldSeparator...)
+	start := len(buf)
+	buf = append(buf, '{')
+	if buf = h.appendAttrs(buf, r); len(buf) == start+1 {
+		buf = buf[:mark]
+	} else {
+		if !h.opts.CompactJSON {
+			buf = append(buf, '\n')
+		}
+		buf = append(buf, '}')
+		if h.opts.UseColor {
+			fields := h.colorFields(string(buf[start:]))
+			buf = append(buf[:start], fields...)
 		}
 	}
 
-	buf.WriteByte('\n')
-	_, err := h.writer.Write(buf.Bytes())
+	buf = append(buf, '\n')
+	*bufp = buf
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	_, err := h.writer.Write(buf)
 	return err
 }
 
@@ -187,19 +228,22 @@ func (h *PrettyHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
 		return h
 	}
 
-	h.mu.Lock()
-	defer h.mu.Unlock()
-
-	newHandler := &PrettyHandler{
-		opts:   h.opts,
-		writer: h.writer,
-		mu:     &sync.Mutex{},
-		groups: append([]string(nil), h.groups...),
-		attrs:  append(append([]slog.Attr(nil), h.attrs...), attrs...),
+	e := h.encoder()
+	pre := slices.Clone(h.pre)
+	for _, group := range h.pending {
+		pre = e.openGroup(pre, group)
+	}
+	start := len(pre)
+	if pre = e.attrs(pre, attrs); len(pre) == start {
+		return h
 	}
-	newHandler.initColorFuncs()
 
-	return newHandler
+	clone := *h
+	clone.pre = pre
+	clone.preFields = true
+	clone.open = h.open + len(h.pending)
+	clone.pending = nil
+	return &clone
 }
 
 func (h *PrettyHandler) WithGroup(name string) slog.Handler {
@@ -207,48 +251,102 @@ func (h *PrettyHandler) WithGroup(name string) slog.Handler {
 		return h
 	}
 
-	h.mu.Lock()
-	defer h.mu.Unlock()
+	clone := *h
+	clone.pending = append(slices.Clip(h.pending), name)
+	return &clone
+}
 
-	newHandler := &PrettyHandler{
-		opts:   h.opts,
-		writer: h.writer,
-		mu:     &sync.Mutex{},
-		groups: append(append([]string(nil), h.groups...), name),
-		attrs:  append([]slog.Attr(nil), h.attrs...),
+func (h *PrettyHandler) encoder() attrEncoder {
+	return attrEncoder{
+		compact:    h.opts.CompactJSON,
+		escapeHTML: !h.opts.DisableHTMLEscape,
+		sortKeys:   h.opts.SortKeys,
+		maxLength:  h.opts.MaxFieldLength,
+		timeFormat: h.opts.TimeFormat,
+		depth:      1 + h.open,
+		first:      !h.preFields,
 	}
-	newHandler.initColorFuncs()
-
-	return newHandler
 }
 
-func (h *PrettyHandler) formatLevel(level slog.Level) string {
-	levelStr := strings.ToUpper(level.String())
+// appendAttrs appends the attributes from WithAttrs and then r's, inside
+// their groups, as the fields of an object without its braces.
+func (h *PrettyHandler) appendAttrs(buf []byte, r slog.Record) []byte {
+	e := h.encoder()
+	buf = append(buf, h.pre...)
 
-	if h.opts.LevelWidth > 0 {
-		levelStr = fmt.Sprintf("%-*s", h.opts.LevelWidth, levelStr)
+	if r.NumAttrs() > 0 {
+		mark, first := len(buf), e.first
+		for _, group := range h.pending {
+			buf = e.openGroup(buf, group)
+		}
+		start := len(buf)
+		if e.sortKeys {
+			attrs := make([]slog.Attr, 0, r.NumAttrs())
+			r.Attrs(func(a slog.Attr) bool {
+				attrs = append(attrs, a)
+				return true
+			})
+			buf = e.attrs(buf, attrs)
+		} else {
+			r.Attrs(func(a slog.Attr) bool {
+				buf = e.attr(buf, a)
+				return true
+			})
+		}
+		if len(buf) == start {
+			buf = buf[:mark]
+			e.depth -= len(h.pending)
+			e.first = first
+		} else {
+			for range h.pending {
+				buf = e.closeGroup(buf)
+			}
+		}
 	}
 
-	if colorFunc, ok := h.colorLevel[level]; ok {
-		return colorFunc(levelStr)
+	for range h.open {
+		buf = e.closeGroup(buf)
 	}
+	return buf
+}
 
-	if level > slog.LevelError {
-		return h.colorError(levelStr)
+func (h *PrettyHandler) appendTime(buf []byte, t time.Time) []byte {
+	if h.opts.UseColor {
+		return append(buf, h.colorTime(t.Format(h.opts.TimeFormat))...)
 	}
-	return levelStr
+	return t.AppendFormat(buf, h.opts.TimeFormat)
 }
 
-func (h *PrettyHandler) extractSource(pc uintptr) string {
+func (h *PrettyHandler) appendLevel(buf []byte, level slog.Level) []byte {
+	start := len(buf)
+	buf = append(buf, level.String()...)
+	for len(buf)-start < h.opts.LevelWidth {
+		buf = append(buf, ' ')
+	}
+	if !h.opts.UseColor {
+		return buf
+	}
+
+	colorFunc, ok := h.colorLevel[level]
+	if !ok {
+		if level <= slog.LevelError {
+			return buf
+		}
+		colorFunc = h.colorError
+	}
+	return append(buf[:start], colorFunc(string(buf[start:]))...)
+}
+
+func (h *PrettyHandler) appendSource(buf []byte, pc uintptr) []byte {
 	if pc == 0 {
-		return ""
+		return buf
 	}
 
 	frames := runtime.CallersFrames([]uintptr{pc})
 	frame, _ := frames.Next()
 
 	if frame.Function == "" {
-		return ""
+		return buf
 	}
 
 	file := frame.File
@@ -256,127 +354,23 @@ func (h *PrettyHandler) extractSource(pc uintptr) string {
 		file = filepath.Base(file)
 	}
 
-	source := fmt.Sprintf("%s:%d", file, frame.Line)
+	start := len(buf)
+	buf = append(buf, file...)
+	buf = append(buf, ':')
+	buf = strconv.AppendInt(buf, int64(frame.Line), 10)
 
 	if h.opts.SlogOpts.AddSource {
 		funcName := frame.Function
 		if idx := strings.LastIndex(funcName, "."); idx >= 0 {
 			funcName = funcName[idx+1:]
 		}
-		source = fmt.Sprintf("%s:%s", source, funcName)
-	}
-
-	return source
-}
-
-func (h *PrettyHandler) collectAttributes(
-	r slog.Record,
-) map[string]any {
-	attrs := make(map[string]any)
-
-	current := attrs
-	for _, group := range h.groups {
-		nested := make(map[string]any)
-		current[group] = nested
-		current = nested
-	}
-
-	for _, attr := range h.attrs {
-		h.addAttribute(current, attr)
-	}
-
-	r.Attrs(func(attr slog.Attr) bool {
-		h.addAttribute(current, attr)
-		return true
-	})
-
-	h.cleanEmptyGroups(attrs)
-
-	return attrs
-}
-
-func (h *PrettyHandler) addAttribute(
-	attrs map[string]any,
-	attr slog.Attr,
-) {
-	value := attr.Value.Resolve()
-
-	if value.Kind() == slog.KindGroup {
-		group := make(map[string]any)
-		for _, groupAttr := range value.Group() {
-			h.addAttribute(group, groupAttr)
-		}
-		if len(group) > 0 {
-			attrs[attr.Key] = group
-		}
-		return
-	}
-
-	var v any
-	switch value.Kind() {
-	case slog.KindTime:
-		v = value.Time().Format(h.opts.TimeFormat)
-	case slog.KindDuration:
-		v = value.Duration().String()
-	case slog.KindAny:
-		v = value.Any()
-		if h.opts.MaxFieldLength > 0 {
-			if str, ok := v.(string); ok &&
-				len(str) > h.opts.MaxFieldLength {
-				v = str[:h.opts.MaxFieldLength] + "..."
-			}
-		}
-	default:
-		v = value.Any()
-	}
-
-	attrs[attr.Key] = v
-}
-
-func (h *PrettyHandler) cleanEmptyGroups(attrs map[string]any) {
-	for key, value := range attrs {
-		if nested, ok := value.(map[string]any); ok {
-			h.cleanEmptyGroups(nested)
-			if len(nested) == 0 {
-				delete(attrs, key)
-			}
-		}
+		buf = append(buf, ':')
+		buf = append(buf, funcName...)
 	}
-}
 
-func (h *PrettyHandler) formatAttributes(
-	buf *bytes.Buffer,
-	attrs map[string]any,
-) error {
-	if len(attrs) == 0 {
-		return nil
+	if h.opts.UseColor {
+		source := h.colorSource(string(buf[start:]))
+		buf = append(buf[:start], source...)
 	}
-
-	encoder := json.NewEncoder(buf)
-	encoder.SetEscapeHTML(!h.opts.DisableHTMLEscape)
-
-	if h.opts.CompactJSON {
-		encoder.SetIndent("", "")
-	} else {
-		encoder.SetIndent("", "  ")
-	}
-
-	var jsonBuf bytes.Buffer
-	encoder = json.NewEncoder(&jsonBuf)
-	encoder.SetEscapeHTML(!h.opts.DisableHTMLEscape)
-	if h.opts.CompactJSON {
-		encoder.SetIndent("", "")
-	} else {
-		encoder.SetIndent("", "  ")
-	}
-
-	if err := encoder.Encode(attrs); err != nil {
-		return err
-	}
-
-	result := bytes.TrimRight(jsonBuf.Bytes(), "\n")
-
-	buf.WriteString(h.colorFields(string(result)))
-
-	return nil
+	return buf
 }
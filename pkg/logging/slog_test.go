@@ -0,0 +1,45 @@
+package logging
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+const benchInfoHash = "c12fe1c06bba254a9dc9f519b335aa7c1367a88a"
+
+func benchmarkPretty(b *testing.B, opts PrettyHandlerOptions) {
+	logger := slog.New(NewPrettyHandler(io.Discard, &opts)).With(
+		slog.String("info_hash", benchInfoHash),
+		slog.String("peer", "203.0.113.7:6881"),
+	)
+	err := errors.New("connection reset by peer")
+
+	b.ReportAllocs()
+	for b.Loop() {
+		logger.Info(
+			"received message",
+			slog.String("type", "piece"),
+			slog.Int("index", 1234),
+			slog.Int("length", 16384),
+			slog.Duration("rtt", 42*time.Millisecond),
+			slog.Group("rate", slog.Float64("down", 1.5e6)),
+			slog.Any("error", err),
+		)
+	}
+}
+
+func BenchmarkPrettyHandler(b *testing.B) {
+	opts := DefaultOptions()
+	opts.UseColor = false
+	benchmarkPretty(b, opts)
+}
+
+func BenchmarkPrettyHandlerCompact(b *testing.B) {
+	opts := DefaultOptions()
+	opts.UseColor = false
+	opts.CompactJSON = true
+	benchmarkPretty(b, opts)
+}